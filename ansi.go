@@ -0,0 +1,54 @@
+package gad
+
+import (
+	"strconv"
+	"strings"
+)
+
+// ansiCodes maps the style names of Colorize to their SGR codes. The color
+// codes are foreground codes, background codes are 10 more.
+var ansiCodes = map[string]int{
+	"bold":      1,
+	"dim":       2,
+	"italic":    3,
+	"underline": 4,
+	"blink":     5,
+	"reverse":   7,
+	"strike":    9,
+	"black":     30,
+	"red":       31,
+	"green":     32,
+	"yellow":    33,
+	"blue":      34,
+	"magenta":   35,
+	"cyan":      36,
+	"white":     37,
+	"gray":      90,
+}
+
+// Colorize returns s wrapped by the ANSI escape codes of the styles. color is
+// a space separated list of a color and text styles like "bold red", bg is a
+// background color. Colors are black, red, green, yellow, blue, magenta,
+// cyan, white and gray; text styles are bold, dim, italic, underline, blink,
+// reverse and strike. s is returned as is if there is no style.
+func Colorize(s, color, bg string) (string, error) {
+	var codes []string
+	for _, name := range strings.Fields(color) {
+		code, ok := ansiCodes[name]
+		if !ok {
+			return "", ErrUnexpectedArgValue.NewError("color: unknown style " + strconv.Quote(name))
+		}
+		codes = append(codes, strconv.Itoa(code))
+	}
+	if bg != "" {
+		code, ok := ansiCodes[bg]
+		if !ok || code < 30 {
+			return "", ErrUnexpectedArgValue.NewError("bg: unknown color " + strconv.Quote(bg))
+		}
+		codes = append(codes, strconv.Itoa(code+10))
+	}
+	if len(codes) == 0 {
+		return s, nil
+	}
+	return "\x1b[" + strings.Join(codes, ";") + "m" + s + "\x1b[0m", nil
+}
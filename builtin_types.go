@@ -5,9 +5,11 @@ type BuiltinObjType struct {
 	Value     CallableFunc
 	getters   Dict
 	setters   Dict
-	methods   Dict
+	methods   TypeMethods
 }
 
+var _ TypeMethodsGetter = (*BuiltinObjType)(nil)
+
 func (b *BuiltinObjType) Fields() Dict {
 	return nil
 }
@@ -21,7 +23,12 @@ func (b *BuiltinObjType) Setters() Dict {
 }
 
 func (b *BuiltinObjType) Methods() Dict {
-	return b.methods
+	return b.methods.Dict()
+}
+
+// TypeMethods returns the methods registered to the type.
+func (b *BuiltinObjType) TypeMethods() *TypeMethods {
+	return &b.methods
 }
 
 func (b *BuiltinObjType) IsChildOf(t ObjectType) bool {
@@ -86,6 +93,8 @@ var (
 	TSyncDict,
 	TKeyValue,
 	TKeyValueArray,
+	TIntArray,
+	TFloatArray,
 	TRegexp,
 	TRegexpStrsResult,
 	TRegexpStrsSliceResult,
@@ -135,6 +144,60 @@ var (
 	TIndexGetProxy = &BuiltinObjType{
 		NameValue: "indexGetProxy",
 	}
+	TTaskGroup = &BuiltinObjType{
+		NameValue: "taskGroup",
+	}
+	TMutex = &BuiltinObjType{
+		NameValue: "mutex",
+	}
+	TRWMutex = &BuiltinObjType{
+		NameValue: "rwmutex",
+	}
+	TWaitGroup = &BuiltinObjType{
+		NameValue: "waitgroup",
+	}
+	TOnce = &BuiltinObjType{
+		NameValue: "once",
+	}
+	TTimer = &BuiltinObjType{
+		NameValue: "timer",
+	}
+	TEventEmitter = &BuiltinObjType{
+		NameValue: "events",
+	}
+	TMock = &BuiltinObjType{
+		NameValue: "mock",
+	}
+	TProgress = &BuiltinObjType{
+		NameValue: "progress",
+	}
+	TPluginRegistry = &BuiltinObjType{
+		NameValue: "pluginRegistry",
+	}
+	TCOWDict = &BuiltinObjType{
+		NameValue: "cowDict",
+	}
+	TArrayView = &BuiltinObjType{
+		NameValue: "arrayView",
+	}
+	TSortedArray = &BuiltinObjType{
+		NameValue: "sortedArray",
+	}
+	THeap = &BuiltinObjType{
+		NameValue: "heap",
+	}
+	TDeque = &BuiltinObjType{
+		NameValue: "deque",
+	}
+	TBytesView = &BuiltinObjType{
+		NameValue: "bytesView",
+	}
+	TModuleFactory = &BuiltinObjType{
+		NameValue: "moduleFactory",
+	}
+	TBuiltinNamespace = &BuiltinObjType{
+		NameValue: "builtinNamespace",
+	}
 )
 
 func init() {
@@ -150,13 +213,13 @@ func init() {
 	TStr = RegisterBuiltinType(BuiltinStr, "str", Str(""), BuiltinStringFunc)
 	TBytes = RegisterBuiltinType(BuiltinBytes, "bytes", Bytes{}, BuiltinBytesFunc)
 	TBuffer = RegisterBuiltinType(BuiltinBuffer, "buffer", Buffer{}, BuiltinBufferFunc)
-	TArray = RegisterBuiltinType(BuiltinArray, "array", Array{}, func(c Call) (ret Object, err error) {
-		return c.Args.Values(), nil
-	})
+	TArray = RegisterBuiltinType(BuiltinArray, "array", Array{}, BuiltinArrayFunc)
 	TDict = RegisterBuiltinType(BuiltinDict, "dict", Dict{}, BuiltinDictFunc)
 	TSyncDict = RegisterBuiltinType(BuiltinSyncDic, "syncDict", SyncDict{}, BuiltinSyncDictFunc)
 	TKeyValue = RegisterBuiltinType(BuiltinKeyValue, "keyValue", KeyValue{}, BuiltinKeyValueFunc)
 	TKeyValueArray = RegisterBuiltinType(BuiltinKeyValueArray, "keyValueArray", KeyValueArray{}, BuiltinKeyValueArrayFunc)
+	TIntArray = RegisterBuiltinType(BuiltinIntArray, "intarray", IntArray{}, BuiltinIntArrayFunc)
+	TFloatArray = RegisterBuiltinType(BuiltinFloatArray, "floatarray", FloatArray{}, BuiltinFloatArrayFunc)
 	TRegexp = RegisterBuiltinType(BuiltinRegexp, "regexp", Regexp{}, BuiltinRegexpFunc)
 	TRegexpStrsResult = RegisterBuiltinType(BuiltinRegexpStrsResult, "regexpStrsResult", RegexpStrsResult{}, nil)
 	TRegexpStrsSliceResult = RegisterBuiltinType(BuiltinRegexpStrsSliceResult, "regexpStrsSliceResult", RegexpStrsSliceResult{}, nil)
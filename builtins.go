@@ -37,6 +37,8 @@ const (
 	BuiltinSyncDic
 	BuiltinKeyValue
 	BuiltinKeyValueArray
+	BuiltinIntArray
+	BuiltinFloatArray
 	BuiltinError
 	BuiltinBuffer
 	BuiltinRegexp
@@ -61,10 +63,20 @@ const (
 	BuiltinLen
 	BuiltinSort
 	BuiltinSortReverse
+	BuiltinBisect
+	BuiltinInsertSorted
+	BuiltinSortedArray
+	BuiltinHeap
+	BuiltinDeque
 	BuiltinFilter
 	BuiltinMap
 	BuiltinEach
 	BuiltinReduce
+	BuiltinReduceRight
+	BuiltinScan
+	BuiltinLines
+	BuiltinStop
+	BuiltinExit
 	BuiltinTypeName
 	BuiltinChars
 	BuiltinClose
@@ -73,12 +85,40 @@ const (
 	BuiltinPrint
 	BuiltinPrintf
 	BuiltinPrintln
+	BuiltinPrintTable
+	BuiltinProgress
 	BuiltinSprintf
+	BuiltinFormat
 	BuiltinGlobals
+	BuiltinSuspend
+	BuiltinRecover
+	BuiltinRetry
+	BuiltinGroup
+	BuiltinMutex
+	BuiltinRWMutex
+	BuiltinWaitGroup
+	BuiltinOnce
+	BuiltinSleep
+	BuiltinTicker
+	BuiltinAfter
+	BuiltinEvents
+	BuiltinMock
+	BuiltinDeepEqual
+	BuiltinDiff
+	BuiltinMerge
+	BuiltinMergePatch
+	BuiltinGetPath
+	BuiltinSetPath
 	BuiltinStdIO
 	BuiltinWrap
 	BuiltinStruct
 	BuiltinNew
+	BuiltinGetAttr
+	BuiltinSetAttr
+	BuiltinCallMissing
+	BuiltinEncode
+	BuiltinDecode
+	BuiltinFormatValue
 	BuiltinTypeOf
 	BuiltinAddCallMethod
 	BuiltinRawCaller
@@ -90,6 +130,19 @@ const (
 	BuiltinItems
 	BuiltinCollect
 	BuiltinEnumerate
+	BuiltinZipLongest
+	BuiltinProduct
+	BuiltinPermutations
+	BuiltinCombinations
+	BuiltinCounter
+	BuiltinCycle
+	BuiltinRepeatIter
+	BuiltinTake
+	BuiltinTakeWhile
+	BuiltinWithProgress
+	BuiltinView
+	BuiltinTee
+	BuiltinCached
 	BuiltinIteratorInput
 	BuiltinVMPushWriter
 	BuiltinVMPopWriter
@@ -98,6 +151,8 @@ const (
 	BuiltinFlush
 	BuiltinUserData
 	BuiltinNamedParamTypeCheck
+	BuiltinVarTypeCheck
+	BuiltinReturnTypeCheck
 
 	BuiltinIs
 	BuiltinIsError
@@ -188,10 +243,20 @@ var BuiltinsMap = map[string]BuiltinType{
 	"len":                 BuiltinLen,
 	"sort":                BuiltinSort,
 	"sortReverse":         BuiltinSortReverse,
+	"bisect":              BuiltinBisect,
+	"insertSorted":        BuiltinInsertSorted,
+	"sortedArray":         BuiltinSortedArray,
+	"heap":                BuiltinHeap,
+	"deque":               BuiltinDeque,
 	"filter":              BuiltinFilter,
 	"map":                 BuiltinMap,
 	"each":                BuiltinEach,
 	"reduce":              BuiltinReduce,
+	"reduceRight":         BuiltinReduceRight,
+	"scan":                BuiltinScan,
+	"lines":               BuiltinLines,
+	"stop":                BuiltinStop,
+	"exit":                BuiltinExit,
 	"typeName":            BuiltinTypeName,
 	"chars":               BuiltinChars,
 	"close":               BuiltinClose,
@@ -200,18 +265,48 @@ var BuiltinsMap = map[string]BuiltinType{
 	"print":               BuiltinPrint,
 	"printf":              BuiltinPrintf,
 	"println":             BuiltinPrintln,
+	"printTable":          BuiltinPrintTable,
+	"progress":            BuiltinProgress,
 	"sprintf":             BuiltinSprintf,
+	"format":              BuiltinFormat,
 	"globals":             BuiltinGlobals,
+	"suspend":             BuiltinSuspend,
+	"recover":             BuiltinRecover,
+	"retry":               BuiltinRetry,
+	"group":               BuiltinGroup,
+	"mutex":               BuiltinMutex,
+	"rwmutex":             BuiltinRWMutex,
+	"waitgroup":           BuiltinWaitGroup,
+	"once":                BuiltinOnce,
+	"sleep":               BuiltinSleep,
+	"ticker":              BuiltinTicker,
+	"after":               BuiltinAfter,
+	"events":              BuiltinEvents,
+	"mock":                BuiltinMock,
+	"deepEqual":           BuiltinDeepEqual,
+	"diff":                BuiltinDiff,
+	"merge":               BuiltinMerge,
+	"mergePatch":          BuiltinMergePatch,
+	"getPath":             BuiltinGetPath,
+	"setPath":             BuiltinSetPath,
 	"stdio":               BuiltinStdIO,
 	"wrap":                BuiltinWrap,
 	"struct":              BuiltinStruct,
 	"new":                 BuiltinNew,
+	"__getattr__":         BuiltinGetAttr,
+	"__setattr__":         BuiltinSetAttr,
+	"__call_missing__":    BuiltinCallMissing,
+	"__encode__":          BuiltinEncode,
+	"__decode__":          BuiltinDecode,
+	"__format__":          BuiltinFormatValue,
 	"typeof":              BuiltinTypeOf,
 	"addCallMethod":       BuiltinAddCallMethod,
 	"rawCaller":           BuiltinRawCaller,
 	"repr":                BuiltinRepr,
 	"userData":            BuiltinUserData,
 	"namedParamTypeCheck": BuiltinNamedParamTypeCheck,
+	":varTypeCheck":       BuiltinVarTypeCheck,
+	":returnTypeCheck":    BuiltinReturnTypeCheck,
 
 	"is":         BuiltinIs,
 	"isError":    BuiltinIsError,
@@ -255,6 +350,19 @@ var BuiltinsMap = map[string]BuiltinType{
 	"iterator":      BuiltinIterator,
 	"iteratorInput": BuiltinIteratorInput,
 	"zip":           BuiltinZipIterator,
+	"zipLongest":    BuiltinZipLongest,
+	"product":       BuiltinProduct,
+	"permutations":  BuiltinPermutations,
+	"combinations":  BuiltinCombinations,
+	"counter":       BuiltinCounter,
+	"cycle":         BuiltinCycle,
+	"repeatIter":    BuiltinRepeatIter,
+	"take":          BuiltinTake,
+	"takeWhile":     BuiltinTakeWhile,
+	"withProgress":  BuiltinWithProgress,
+	"view":          BuiltinView,
+	"tee":           BuiltinTee,
+	"cached":        BuiltinCached,
 	"keyValue":      BuiltinKeyValue,
 	"keyValueArray": BuiltinKeyValueArray,
 
@@ -266,73 +374,6 @@ var BuiltinsMap = map[string]BuiltinType{
 	"DISCARD_WRITER": BuiltinDiscardWriter,
 }
 
-type Builtins struct {
-	Objects BuiltinObjectsMap
-	Map     map[string]BuiltinType
-	last    BuiltinType
-}
-
-func NewBuiltins() *Builtins {
-	return &Builtins{Objects: BuiltinObjects, Map: BuiltinsMap, last: NewBuiltinType()}
-}
-
-func (s *Builtins) SetType(typ ObjectType) *Builtins {
-	return s.Set(typ.Name(), typ)
-}
-
-func (s *Builtins) Set(name string, obj Object) *Builtins {
-	if s.last == lastBuiltinType {
-		newObjects := make(BuiltinObjectsMap, len(s.Objects))
-		newMap := make(map[string]BuiltinType, len(s.Objects))
-		for t, o := range s.Objects {
-			newObjects[t] = o
-		}
-		for name, t := range s.Map {
-			newMap[name] = t
-		}
-		s.Objects = newObjects
-		s.Map = newMap
-	}
-	s.last++
-	s.Map[name] = s.last
-	s.Objects[s.last] = obj
-	return s
-}
-
-func (s *Builtins) Call(t BuiltinType, c Call) (Object, error) {
-	return DoCall(s.Objects[t].(CallerObject), c)
-}
-
-func (s *Builtins) Caller(t BuiltinType) CallerObject {
-	return s.Objects[t].(CallerObject)
-}
-
-func (s *Builtins) Invoker(t BuiltinType, c Call) func() (Object, error) {
-	caller := s.Objects[t].(CallerObject)
-	return func() (Object, error) {
-		return caller.Call(c)
-	}
-}
-
-func (s *Builtins) ArgsInvoker(t BuiltinType, c Call) func(arg ...Object) (Object, error) {
-	caller := s.Objects[t].(CallerObject)
-	c.Args = Args{nil}
-	return func(arg ...Object) (Object, error) {
-		c.Args[0] = arg
-		return Val(caller.Call(c))
-	}
-}
-
-func (s *Builtins) Get(t BuiltinType) Object {
-	return s.Objects[t]
-}
-
-func (s *Builtins) AppendMap(m map[string]Object) {
-	for name, o := range m {
-		s.Set(name, o)
-	}
-}
-
 type BuiltinObjectsMap map[BuiltinType]Object
 
 func (m BuiltinObjectsMap) Build() BuiltinObjectsMap {
@@ -420,10 +461,6 @@ var BuiltinObjects = BuiltinObjectsMap{
 		Name:  "cap",
 		Value: funcPORO(BuiltinCapFunc),
 	},
-	BuiltinSort: &BuiltinFunction{
-		Name:  "sort",
-		Value: funcPpVM_OCo_less_ROe(BuiltinSortFunc),
-	},
 	BuiltinSortReverse: &BuiltinFunction{
 		Name:  "sortReverse",
 		Value: funcPpVM_OCo_less_ROe(BuiltinSortReverseFunc),
@@ -445,16 +482,95 @@ var BuiltinObjects = BuiltinObjectsMap{
 		Name:  "println",
 		Value: BuiltinPrintlnFunc,
 	},
+	BuiltinPrintTable: &BuiltinFunction{
+		Name:  "printTable",
+		Value: BuiltinPrintTableFunc,
+	},
+	BuiltinProgress: &BuiltinFunction{
+		Name:  "progress",
+		Value: BuiltinProgressFunc,
+	},
 	BuiltinSprintf: &BuiltinFunction{
 		Name:                  "sprintf",
 		Value:                 BuiltinSprintfFunc,
 		AcceptMethodsDisabled: true,
 	},
+	BuiltinFormat: &BuiltinFunction{
+		Name:                  "format",
+		Value:                 BuiltinFormatFunc,
+		AcceptMethodsDisabled: true,
+	},
 	BuiltinGlobals: &BuiltinFunction{
 		Name:                  "globals",
 		Value:                 BuiltinGlobalsFunc,
 		AcceptMethodsDisabled: true,
 	},
+	BuiltinSuspend: &BuiltinFunction{
+		Name:                  "suspend",
+		Value:                 BuiltinSuspendFunc,
+		AcceptMethodsDisabled: true,
+	},
+	BuiltinRecover: &BuiltinFunction{
+		Name:                  "recover",
+		Value:                 BuiltinRecoverFunc,
+		AcceptMethodsDisabled: true,
+	},
+	BuiltinSleep: &BuiltinFunction{
+		Name:  "sleep",
+		Value: BuiltinSleepFunc,
+	},
+	BuiltinTicker: &BuiltinFunction{
+		Name:  "ticker",
+		Value: BuiltinTickerFunc,
+	},
+	BuiltinEvents: &BuiltinFunction{
+		Name:  "events",
+		Value: BuiltinEventsFunc,
+	},
+	BuiltinMock: &BuiltinFunction{
+		Name:  "mock",
+		Value: BuiltinMockFunc,
+	},
+	BuiltinDeepEqual: &BuiltinFunction{
+		Name:  "deepEqual",
+		Value: BuiltinDeepEqualFunc,
+	},
+	BuiltinDiff: &BuiltinFunction{
+		Name:  "diff",
+		Value: BuiltinDiffFunc,
+	},
+	BuiltinMerge: &BuiltinFunction{
+		Name:  "merge",
+		Value: BuiltinMergeFunc,
+	},
+	BuiltinMergePatch: &BuiltinFunction{
+		Name:  "mergePatch",
+		Value: BuiltinMergePatchFunc,
+	},
+	BuiltinGetPath: &BuiltinFunction{
+		Name:  "getPath",
+		Value: BuiltinGetPathFunc,
+	},
+	BuiltinSetPath: &BuiltinFunction{
+		Name:  "setPath",
+		Value: BuiltinSetPathFunc,
+	},
+	BuiltinMutex: &BuiltinFunction{
+		Name:  "mutex",
+		Value: BuiltinMutexFunc,
+	},
+	BuiltinRWMutex: &BuiltinFunction{
+		Name:  "rwmutex",
+		Value: BuiltinRWMutexFunc,
+	},
+	BuiltinWaitGroup: &BuiltinFunction{
+		Name:  "waitgroup",
+		Value: BuiltinWaitGroupFunc,
+	},
+	BuiltinOnce: &BuiltinFunction{
+		Name:  "once",
+		Value: BuiltinOnceFunc,
+	},
 	BuiltinRepr: &BuiltinFunction{
 		Name:  "repr",
 		Value: BuiltinReprFunc,
@@ -464,6 +580,16 @@ var BuiltinObjects = BuiltinObjectsMap{
 		Value:                 BuiltinNamedParamTypeCheckFunc,
 		AcceptMethodsDisabled: true,
 	},
+	BuiltinVarTypeCheck: &BuiltinFunction{
+		Name:                  ":varTypeCheck",
+		Value:                 BuiltinVarTypeCheckFunc,
+		AcceptMethodsDisabled: true,
+	},
+	BuiltinReturnTypeCheck: &BuiltinFunction{
+		Name:                  ":returnTypeCheck",
+		Value:                 BuiltinReturnTypeCheckFunc,
+		AcceptMethodsDisabled: true,
+	},
 	BuiltinIs: &BuiltinFunction{
 		Name:                  "is",
 		Value:                 BuiltinIsFunc,
@@ -568,6 +694,30 @@ var BuiltinObjects = BuiltinObjectsMap{
 		Name:  "new",
 		Value: BuiltinNewFunc,
 	},
+	BuiltinGetAttr: &BuiltinFunction{
+		Name:  "__getattr__",
+		Value: BuiltinGetAttrFunc,
+	},
+	BuiltinSetAttr: &BuiltinFunction{
+		Name:  "__setattr__",
+		Value: BuiltinSetAttrFunc,
+	},
+	BuiltinCallMissing: &BuiltinFunction{
+		Name:  "__call_missing__",
+		Value: BuiltinCallMissingFunc,
+	},
+	BuiltinEncode: &BuiltinFunction{
+		Name:  "__encode__",
+		Value: BuiltinEncodeFunc,
+	},
+	BuiltinDecode: &BuiltinFunction{
+		Name:  "__decode__",
+		Value: BuiltinDecodeFunc,
+	},
+	BuiltinFormatValue: &BuiltinFunction{
+		Name:  "__format__",
+		Value: BuiltinFormatValueFunc,
+	},
 	BuiltinTypeOf: &BuiltinFunction{
 		Name:                  "typeof",
 		Value:                 BuiltinTypeOfFunc,
@@ -629,6 +779,18 @@ var BuiltinObjects = BuiltinObjectsMap{
 }
 
 func init() {
+	BuiltinObjects[BuiltinRetry] = &BuiltinFunction{
+		Name:  "retry",
+		Value: BuiltinRetryFunc,
+	}
+	BuiltinObjects[BuiltinAfter] = &BuiltinFunction{
+		Name:  "after",
+		Value: BuiltinAfterFunc,
+	}
+	BuiltinObjects[BuiltinGroup] = &BuiltinFunction{
+		Name:  "group",
+		Value: BuiltinGroupFunc,
+	}
 	BuiltinObjects[BuiltinRead] = &BuiltinFunction{
 		Name:  "read",
 		Value: BuiltinReadFunc,
@@ -637,6 +799,30 @@ func init() {
 		Name:  "write",
 		Value: BuiltinWriteFunc,
 	}
+	BuiltinObjects[BuiltinSort] = &BuiltinFunction{
+		Name:  "sort",
+		Value: BuiltinSortFunc,
+	}
+	BuiltinObjects[BuiltinBisect] = &BuiltinFunction{
+		Name:  "bisect",
+		Value: BuiltinBisectFunc,
+	}
+	BuiltinObjects[BuiltinInsertSorted] = &BuiltinFunction{
+		Name:  "insertSorted",
+		Value: BuiltinInsertSortedFunc,
+	}
+	BuiltinObjects[BuiltinSortedArray] = &BuiltinFunction{
+		Name:  "sortedArray",
+		Value: BuiltinSortedArrayFunc,
+	}
+	BuiltinObjects[BuiltinHeap] = &BuiltinFunction{
+		Name:  "heap",
+		Value: BuiltinHeapFunc,
+	}
+	BuiltinObjects[BuiltinDeque] = &BuiltinFunction{
+		Name:  "deque",
+		Value: BuiltinDequeFunc,
+	}
 	BuiltinObjects[BuiltinFilter] = &BuiltinFunction{
 		Name:  "filter",
 		Value: BuiltinFilterFunc,
@@ -653,6 +839,26 @@ func init() {
 		Name:  "reduce",
 		Value: BuiltinReduceFunc,
 	}
+	BuiltinObjects[BuiltinReduceRight] = &BuiltinFunction{
+		Name:  "reduceRight",
+		Value: BuiltinReduceRightFunc,
+	}
+	BuiltinObjects[BuiltinScan] = &BuiltinFunction{
+		Name:  "scan",
+		Value: BuiltinScanFunc,
+	}
+	BuiltinObjects[BuiltinLines] = &BuiltinFunction{
+		Name:  "lines",
+		Value: BuiltinLinesFunc,
+	}
+	BuiltinObjects[BuiltinStop] = &BuiltinFunction{
+		Name:  "stop",
+		Value: BuiltinStopFunc,
+	}
+	BuiltinObjects[BuiltinExit] = &BuiltinFunction{
+		Name:  "exit",
+		Value: BuiltinExitFunc,
+	}
 	BuiltinObjects[BuiltinEach] = &BuiltinFunction{
 		Name:  "each",
 		Value: BuiltinEachFunc,
@@ -682,6 +888,58 @@ func init() {
 		Name:  "enumerate",
 		Value: BuiltinEnumerateFunc,
 	}
+	BuiltinObjects[BuiltinZipLongest] = &BuiltinFunction{
+		Name:  "zipLongest",
+		Value: BuiltinZipLongestFunc,
+	}
+	BuiltinObjects[BuiltinProduct] = &BuiltinFunction{
+		Name:  "product",
+		Value: BuiltinProductFunc,
+	}
+	BuiltinObjects[BuiltinPermutations] = &BuiltinFunction{
+		Name:  "permutations",
+		Value: BuiltinPermutationsFunc,
+	}
+	BuiltinObjects[BuiltinCombinations] = &BuiltinFunction{
+		Name:  "combinations",
+		Value: BuiltinCombinationsFunc,
+	}
+	BuiltinObjects[BuiltinCounter] = &BuiltinFunction{
+		Name:  "counter",
+		Value: BuiltinCounterFunc,
+	}
+	BuiltinObjects[BuiltinCycle] = &BuiltinFunction{
+		Name:  "cycle",
+		Value: BuiltinCycleFunc,
+	}
+	BuiltinObjects[BuiltinRepeatIter] = &BuiltinFunction{
+		Name:  "repeatIter",
+		Value: BuiltinRepeatIterFunc,
+	}
+	BuiltinObjects[BuiltinTake] = &BuiltinFunction{
+		Name:  "take",
+		Value: BuiltinTakeFunc,
+	}
+	BuiltinObjects[BuiltinTakeWhile] = &BuiltinFunction{
+		Name:  "takeWhile",
+		Value: BuiltinTakeWhileFunc,
+	}
+	BuiltinObjects[BuiltinWithProgress] = &BuiltinFunction{
+		Name:  "withProgress",
+		Value: BuiltinWithProgressFunc,
+	}
+	BuiltinObjects[BuiltinView] = &BuiltinFunction{
+		Name:  "view",
+		Value: BuiltinViewFunc,
+	}
+	BuiltinObjects[BuiltinTee] = &BuiltinFunction{
+		Name:  "tee",
+		Value: BuiltinTeeFunc,
+	}
+	BuiltinObjects[BuiltinCached] = &BuiltinFunction{
+		Name:  "cached",
+		Value: BuiltinCachedFunc,
+	}
 	BuiltinObjects[BuiltinIterator] = TIterator
 	BuiltinObjects[BuiltinZipIterator] = TZipIterator
 	BuiltinObjects[BuiltinIteratorInput] = &BuiltinFunction{
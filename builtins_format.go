@@ -0,0 +1,369 @@
+package gad
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// BuiltinFormatFunc implements `format(template, ...args; **namedArgs)` which
+// replaces the fields of template by the formatted arguments. A field is
+// `{}` for the next argument, `{0}` for an argument by index or `{name}` for a
+// named argument, optionally followed by a spec like `{n:>08.2f}`. `{{` and
+// `}}` are the braces. The arguments are formatted by `__format__(value,
+// spec)`, so methods of `__format__` defined for struct types customize them.
+func BuiltinFormatFunc(c Call) (_ Object, err error) {
+	if err = c.Args.CheckMinLen(1); err != nil {
+		return
+	}
+	tpl, ok := c.Args.GetOnly(0).(Str)
+	if !ok {
+		return nil, NewArgumentTypeError("1st", "str", c.Args.GetOnly(0).Type().Name())
+	}
+
+	var (
+		named = c.NamedArgs.Dict()
+		next  int
+		sb    strings.Builder
+		s     = string(tpl)
+	)
+	for len(s) > 0 {
+		i := strings.IndexAny(s, "{}")
+		if i < 0 {
+			sb.WriteString(s)
+			break
+		}
+		sb.WriteString(s[:i])
+		if i+1 < len(s) && s[i+1] == s[i] {
+			sb.WriteByte(s[i])
+			s = s[i+2:]
+			continue
+		}
+		if s[i] == '}' {
+			return nil, ErrUnexpectedArgValue.NewError("single '}' in format template")
+		}
+		end := strings.IndexByte(s[i:], '}')
+		if end < 0 {
+			return nil, ErrUnexpectedArgValue.NewError("unclosed '{' in format template")
+		}
+		field := s[i+1 : i+end]
+		s = s[i+end+1:]
+
+		var spec string
+		if j := strings.IndexByte(field, ':'); j >= 0 {
+			field, spec = field[:j], field[j+1:]
+		}
+
+		var v Object
+		switch {
+		case field == "":
+			if next >= c.Args.Length()-1 {
+				return nil, ErrIndexOutOfBounds.NewError("format argument " + strconv.Itoa(next))
+			}
+			v = c.Args.GetOnly(next + 1)
+			next++
+		case field[0] >= '0' && field[0] <= '9':
+			var n int
+			if n, err = strconv.Atoi(field); err != nil {
+				return nil, ErrUnexpectedArgValue.NewError("invalid format field " + strconv.Quote(field))
+			}
+			if n >= c.Args.Length()-1 {
+				return nil, ErrIndexOutOfBounds.NewError("format argument " + field)
+			}
+			v = c.Args.GetOnly(n + 1)
+		default:
+			if v, ok = named[field]; !ok {
+				return nil, ErrInvalidIndex.NewError("format argument " + strconv.Quote(field))
+			}
+		}
+
+		if v, err = Val(c.VM.Builtins.Call(BuiltinFormatValue, Call{VM: c.VM, Args: Args{Array{v, Str(spec)}}})); err != nil {
+			return
+		}
+		sb.WriteString(v.ToString())
+	}
+	return Str(sb.String()), nil
+}
+
+// BuiltinFormatValueFunc implements `__format__(value, spec)` which returns the
+// string of value formatted by spec `[[fill]align][sign][0][width][.precision][type]`.
+// align is `<`, `>` or `^`, sign is `+` or ` ` and 0 pads the numbers with
+// zeros after the sign. precision is the number of the digits after the point
+// of the floats or the maximum length of the strings. type is `s` for str, `r`
+// for repr, `d`, `x`, `X`, `o` or `b` for the integers, `f`, `e`, `g` or `%`
+// for the numbers.
+func BuiltinFormatValueFunc(c Call) (_ Object, err error) {
+	if err = c.Args.CheckLen(2); err != nil {
+		return
+	}
+	var (
+		v       = c.Args.GetOnly(0)
+		spec    formatSpec
+		s       string
+		numeric bool
+	)
+	if spec, err = parseFormatSpec(c.Args.GetOnly(1).ToString()); err != nil {
+		return
+	}
+
+	switch spec.typ {
+	case 0, 's':
+		switch v.(type) {
+		case Int, Uint, Float, Decimal:
+			numeric = spec.typ == 0
+		}
+		if numeric && spec.prec >= 0 {
+			s, err = spec.float(v, 'f')
+			break
+		}
+		var str Str
+		if str, err = ToStr(c.VM, v); err != nil {
+			return
+		}
+		s = string(str)
+		if !numeric && spec.prec >= 0 && utf8.RuneCountInString(s) > spec.prec {
+			s = string([]rune(s)[:spec.prec])
+		}
+	case 'r':
+		var str Str
+		if str, err = ToRepr(c.VM, v); err != nil {
+			return
+		}
+		s = string(str)
+	case 'd', 'x', 'X', 'o', 'b':
+		numeric = true
+		base := map[byte]int{'d': 10, 'x': 16, 'X': 16, 'o': 8, 'b': 2}[spec.typ]
+		switch t := v.(type) {
+		case Int:
+			s = strconv.FormatInt(int64(t), base)
+		case Uint:
+			s = strconv.FormatUint(uint64(t), base)
+		case Char:
+			s = strconv.FormatInt(int64(t), base)
+		default:
+			return nil, NewArgumentTypeError("1st", "int|uint|char", v.Type().Name())
+		}
+		if spec.typ == 'X' {
+			s = strings.ToUpper(s)
+		}
+	case 'f', 'e', 'g', '%':
+		numeric = true
+		s, err = spec.float(v, spec.typ)
+	default:
+		return nil, ErrUnexpectedArgValue.NewError("unknown format type " + strconv.QuoteRune(rune(spec.typ)))
+	}
+	if err != nil {
+		return
+	}
+	return Str(spec.pad(s, numeric)), nil
+}
+
+// formatSpec is the parsed spec of `__format__`.
+type formatSpec struct {
+	fill  rune
+	align byte
+	sign  byte
+	zero  bool
+	width int
+	prec  int
+	typ   byte
+}
+
+func parseFormatSpec(spec string) (f formatSpec, err error) {
+	f.fill, f.prec = ' ', -1
+	s := spec
+	isAlign := func(c byte) bool { return c == '<' || c == '>' || c == '^' }
+	if r, n := utf8.DecodeRuneInString(s); n > 0 && n < len(s) && isAlign(s[n]) {
+		f.fill, f.align, s = r, s[n], s[n+1:]
+	} else if len(s) > 0 && isAlign(s[0]) {
+		f.align, s = s[0], s[1:]
+	}
+	if len(s) > 0 && (s[0] == '+' || s[0] == ' ' || s[0] == '-') {
+		f.sign, s = s[0], s[1:]
+	}
+	if len(s) > 0 && s[0] == '0' {
+		f.zero, s = true, s[1:]
+	}
+	digits := func() (n int) {
+		i := 0
+		for i < len(s) && s[i] >= '0' && s[i] <= '9' {
+			i++
+		}
+		n, _ = strconv.Atoi(s[:i])
+		s = s[i:]
+		return
+	}
+	f.width = digits()
+	if len(s) > 0 && s[0] == '.' {
+		s = s[1:]
+		if len(s) == 0 || s[0] < '0' || s[0] > '9' {
+			return f, ErrUnexpectedArgValue.NewError("invalid format spec " + strconv.Quote(spec))
+		}
+		f.prec = digits()
+	}
+	if len(s) == 1 {
+		f.typ, s = s[0], s[1:]
+	}
+	if len(s) > 0 {
+		return f, ErrUnexpectedArgValue.NewError("invalid format spec " + strconv.Quote(spec))
+	}
+	return
+}
+
+// float formats the number v in the format of strconv.FormatFloat, or as a
+// percentage if format is '%'.
+func (f formatSpec) float(v Object, format byte) (string, error) {
+	prec := f.prec
+	if prec < 0 && format != 'g' {
+		prec = 6
+	}
+	switch t := v.(type) {
+	case Decimal:
+		if format == 'f' {
+			return t.Go().StringFixed(int32(prec)), nil
+		}
+	case Int, Uint, Float:
+	default:
+		return "", NewArgumentTypeError("1st", "int|uint|float|decimal", v.Type().Name())
+	}
+	x, _ := ToGoFloat64(v)
+	if format == '%' {
+		return strconv.FormatFloat(x*100, 'f', prec, 64) + "%", nil
+	}
+	return strconv.FormatFloat(x, format, prec, 64), nil
+}
+
+// pad returns s with the sign and padded to the width of the spec.
+func (f formatSpec) pad(s string, numeric bool) string {
+	var sign string
+	if numeric {
+		if strings.HasPrefix(s, "-") {
+			sign, s = "-", s[1:]
+		} else if f.sign == '+' || f.sign == ' ' {
+			sign = string(f.sign)
+		}
+	}
+	n := f.width - utf8.RuneCountInString(sign+s)
+	if n <= 0 {
+		return sign + s
+	}
+	if f.zero && numeric && f.align == 0 {
+		return sign + strings.Repeat("0", n) + s
+	}
+	align := f.align
+	if align == 0 {
+		if numeric {
+			align = '>'
+		} else {
+			align = '<'
+		}
+	}
+	fill := string(f.fill)
+	if f.zero && f.align != 0 && f.fill == ' ' {
+		fill = "0"
+	}
+	switch align {
+	case '<':
+		return sign + s + strings.Repeat(fill, n)
+	case '^':
+		return strings.Repeat(fill, n/2) + sign + s + strings.Repeat(fill, n-n/2)
+	}
+	return strings.Repeat(fill, n) + sign + s
+}
+
+// fmtSpec returns the spec of `__format__` for the verb and the flags of the
+// Go fmt state.
+func fmtSpec(s fmt.State, verb rune) (f formatSpec) {
+	f.fill, f.prec = ' ', -1
+	if s.Flag('-') {
+		f.align = '<'
+	}
+	if s.Flag('+') {
+		f.sign = '+'
+	} else if s.Flag(' ') {
+		f.sign = ' '
+	}
+	f.zero = s.Flag('0')
+	f.width, _ = s.Width()
+	if p, ok := s.Precision(); ok {
+		f.prec = p
+	}
+	switch verb {
+	case 'v':
+	case 'q':
+		f.typ = 'r'
+	default:
+		if verb < utf8.RuneSelf {
+			f.typ = byte(verb)
+		}
+	}
+	return
+}
+
+// String returns the spec in the syntax of `__format__`.
+func (f formatSpec) String() string {
+	var sb strings.Builder
+	if f.align != 0 {
+		if f.fill != ' ' {
+			sb.WriteRune(f.fill)
+		}
+		sb.WriteByte(f.align)
+	}
+	if f.sign != 0 {
+		sb.WriteByte(f.sign)
+	}
+	if f.zero {
+		sb.WriteByte('0')
+	}
+	if f.width > 0 {
+		sb.WriteString(strconv.Itoa(f.width))
+	}
+	if f.prec >= 0 {
+		sb.WriteByte('.')
+		sb.WriteString(strconv.Itoa(f.prec))
+	}
+	if f.typ != 0 {
+		sb.WriteByte(f.typ)
+	}
+	return sb.String()
+}
+
+// fmtArgs returns the arguments of the Go fmt functions from args. The
+// arguments whose types define a method of `__format__` are formatted by it
+// with the spec of the verb, the first error of the methods is stored in err
+// after the formatting.
+func fmtArgs(vm *VM, args []Object) (vargs []any, err *error) {
+	err = new(error)
+	vargs = make([]any, len(args))
+	for i, o := range args {
+		if m := serialMethod(vm, BuiltinFormatValue, o.Type(), TStr); m != nil {
+			vargs[i] = &fmtFormatter{vm: vm, m: m, o: o, err: err}
+		} else {
+			vargs[i] = o
+		}
+	}
+	return
+}
+
+// fmtFormatter formats an argument of the Go fmt functions by the method of
+// `__format__`.
+type fmtFormatter struct {
+	vm  *VM
+	m   CallerObject
+	o   Object
+	err *error
+}
+
+func (f *fmtFormatter) Format(s fmt.State, verb rune) {
+	if *f.err != nil {
+		return
+	}
+	ret, err := DoCall(f.m, Call{VM: f.vm, Args: Args{Array{f.o, Str(fmtSpec(s, verb).String())}}})
+	if err != nil {
+		*f.err = err
+		return
+	}
+	_, _ = io.WriteString(s, ret.ToString())
+}
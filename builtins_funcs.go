@@ -1,14 +1,17 @@
 package gad
 
 import (
+	"bufio"
 	"bytes"
 	"errors"
 	"fmt"
 	"io"
+	"math/rand"
 	"regexp"
 	"sort"
 	"strconv"
 	"strings"
+	"time"
 	"unicode/utf8"
 
 	"github.com/gad-lang/gad/repr"
@@ -16,6 +19,15 @@ import (
 	"github.com/shopspring/decimal"
 )
 
+const (
+	// maxCapacity is the maximum length and capacity of the arrays and dicts
+	// allocated in advance by array and dict builtins.
+	maxCapacity = 1 << 24
+	// maxPrealloc is the maximum number of entries allocated in advance by
+	// collect builtin for the estimated length of an iterator.
+	maxPrealloc = 1 << 16
+)
+
 func BuiltinMakeArrayFunc(n int, arg Object) (Object, error) {
 	if n <= 0 {
 		return arg, nil
@@ -290,7 +302,9 @@ func BuiltinCapFunc(arg Object) Object {
 	return Int(n)
 }
 
-func BuiltinSortFunc(vm *VM, arg Object, less CallerObject) (ret Object, err error) {
+// sortObject sorts arg by its Sorter implementation or by the values of the
+// strings and the bytes.
+func sortObject(vm *VM, arg Object, less CallerObject) (ret Object, err error) {
 	switch obj := arg.(type) {
 	case Sorter:
 		ret, err = obj.Sort(vm, less)
@@ -512,6 +526,313 @@ func BuiltinEnumerateFunc(c Call) (_ Object, err error) {
 	})), nil
 }
 
+func BuiltinZipLongestFunc(c Call) (_ Object, err error) {
+	var (
+		fill = c.NamedArgs.GetValue("fill")
+		its  = make([]Iterator, c.Args.Length())
+	)
+	c.Args.Walk(func(i int, arg Object) any {
+		if _, its[i], err = ToIterator(c.VM, arg, &c.NamedArgs); err != nil {
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return
+	}
+	return IteratorObject(ZipLongestIterator(fill, its...)), nil
+}
+
+func BuiltinProductFunc(c Call) (_ Object, err error) {
+	var (
+		repeat = &NamedArgVar{
+			Name:          "repeat",
+			Value:         Int(1),
+			TypeAssertion: TypeAssertionFromTypes(TInt),
+		}
+		pools = make([]Array, c.Args.Length())
+	)
+
+	if err = c.NamedArgs.Get(repeat); err != nil {
+		return
+	}
+
+	c.Args.Walk(func(i int, arg Object) any {
+		if pools[i], err = ValuesOf(c.VM, arg, &NamedArgs{}); err != nil {
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return
+	}
+
+	if n := int(repeat.Value.(Int)); n != 1 {
+		if n < 0 {
+			return nil, NewArgumentTypeError("repeat", "non-negative integer", "negative integer")
+		}
+		repeated := make([]Array, 0, len(pools)*n)
+		for i := 0; i < n; i++ {
+			repeated = append(repeated, pools...)
+		}
+		pools = repeated
+	}
+	return IteratorObject(ProductIterator(pools...)), nil
+}
+
+func builtinPoolArgs(c Call, rRequired bool) (pool Array, r int, err error) {
+	var (
+		iterable = &Arg{
+			Name: "iterable",
+			TypeAssertion: NewTypeAssertion(TypeAssertionHandlers{
+				"iterable": func(v Object) bool {
+					return Iterable(c.VM, v)
+				},
+			}),
+		}
+		k = &Arg{
+			Name:          "k",
+			TypeAssertion: TypeAssertionFromTypes(TInt),
+		}
+	)
+
+	r = -1
+
+	if c.Args.Length() == 1 && !rRequired {
+		err = c.Args.Destructure(iterable)
+	} else if err = c.Args.Destructure(iterable, k); err == nil {
+		if r = int(k.Value.(Int)); r < 0 {
+			err = NewArgumentTypeError("2nd (k)", "non-negative integer", "negative integer")
+		}
+	}
+
+	if err == nil {
+		pool, err = ValuesOf(c.VM, iterable.Value, &c.NamedArgs)
+	}
+	return
+}
+
+func BuiltinPermutationsFunc(c Call) (_ Object, err error) {
+	var (
+		pool Array
+		r    int
+	)
+	if pool, r, err = builtinPoolArgs(c, false); err != nil {
+		return
+	}
+	return IteratorObject(PermutationsIterator(pool, r)), nil
+}
+
+func BuiltinCombinationsFunc(c Call) (_ Object, err error) {
+	var (
+		pool Array
+		r    int
+	)
+	if pool, r, err = builtinPoolArgs(c, true); err != nil {
+		return
+	}
+	return IteratorObject(CombinationsIterator(pool, r)), nil
+}
+
+func BuiltinCounterFunc(c Call) (_ Object, err error) {
+	if err = c.Args.CheckMaxLen(2); err != nil {
+		return
+	}
+	return IteratorObject(CounterIterator(c.Args.GetDefault(0, Int(0)), c.Args.GetDefault(1, Int(1)))), nil
+}
+
+func BuiltinCycleFunc(c Call) (_ Object, err error) {
+	if err = c.Args.CheckLen(1); err != nil {
+		return
+	}
+	var it Iterator
+	if _, it, err = ToIterator(c.VM, c.Args.GetOnly(0), &c.NamedArgs); err != nil {
+		return
+	}
+	return IteratorObject(CycleIterator(it)), nil
+}
+
+func BuiltinRepeatIterFunc(c Call) (_ Object, err error) {
+	var (
+		value = &Arg{Name: "value"}
+		times = &Arg{
+			Name:          "times",
+			TypeAssertion: TypeAssertionFromTypes(TInt),
+		}
+	)
+	if c.Args.Length() == 2 {
+		if err = c.Args.Destructure(value, times); err != nil {
+			return
+		}
+		if times.Value.(Int) < 0 {
+			return nil, NewArgumentTypeError("2nd (times)", "non-negative integer", "negative integer")
+		}
+		return IteratorObject(RepeatIterator(value.Value, int(times.Value.(Int)))), nil
+	}
+	if err = c.Args.Destructure(value); err != nil {
+		return
+	}
+	return IteratorObject(RepeatIterator(value.Value, -1)), nil
+}
+
+func BuiltinTakeFunc(c Call) (_ Object, err error) {
+	var (
+		iterable = &Arg{
+			Name: "iterable",
+			TypeAssertion: NewTypeAssertion(TypeAssertionHandlers{
+				"iterable": func(v Object) bool {
+					return Iterable(c.VM, v)
+				},
+			}),
+		}
+		n = &Arg{
+			Name:          "n",
+			TypeAssertion: TypeAssertionFromTypes(TInt),
+		}
+		it Iterator
+	)
+	if err = c.Args.Destructure(iterable, n); err != nil {
+		return
+	}
+	if _, it, err = ToIterator(c.VM, iterable.Value, &c.NamedArgs); err != nil {
+		return
+	}
+	return IteratorObject(TakeIterator(it, int(n.Value.(Int)))), nil
+}
+
+// BuiltinViewFunc returns a copy-on-write view of array[low:high] sharing the
+// storage of the array. Negative indexes are relative to the end of the array.
+func BuiltinViewFunc(c Call) (_ Object, err error) {
+	if err = c.Args.CheckRangeLen(1, 3); err != nil {
+		return
+	}
+
+	var arr Array
+	switch t := c.Args.Get(0).(type) {
+	case Array:
+		arr = t
+	case *ArrayView:
+		arr = t.share()
+	default:
+		return nil, NewArgumentTypeError("1st", "array|arrayView", t.Type().Name())
+	}
+
+	bounds := [2]int{0, len(arr)}
+	for i := 1; i < c.Args.Length(); i++ {
+		switch v := c.Args.Get(i).(type) {
+		case *NilType:
+		case Int:
+			if bounds[i-1] = int(v); v < 0 {
+				bounds[i-1] += len(arr)
+			}
+		default:
+			return nil, NewArgumentTypeError(strconv.Itoa(i+1), "int", v.Type().Name())
+		}
+	}
+
+	low, high := bounds[0], bounds[1]
+	if low > high {
+		return nil, ErrInvalidIndex.NewError(fmt.Sprintf("[%d:%d]", low, high))
+	}
+	if low < 0 || high > len(arr) {
+		return nil, ErrIndexOutOfBounds.NewError(fmt.Sprintf("[%d:%d]", low, high))
+	}
+	return NewArrayView(arr, low, high), nil
+}
+
+func BuiltinTakeWhileFunc(c Call) (_ Object, err error) {
+	var (
+		iterable = &Arg{
+			Name: "iterable",
+			TypeAssertion: NewTypeAssertion(TypeAssertionHandlers{
+				"iterable": func(v Object) bool {
+					return Iterable(c.VM, v)
+				},
+			}),
+		}
+		callback = &Arg{
+			Name: "callback",
+			TypeAssertion: NewTypeAssertion(TypeAssertionHandlers{
+				"callable": Callable,
+			}),
+		}
+		args   = Array{Nil, Nil}
+		caller VMCaller
+		it     Iterator
+	)
+
+	if err = c.Args.Destructure(iterable, callback); err != nil {
+		return
+	}
+
+	if caller, err = NewInvoker(c.VM, callback.Value).Caller(Args{args}, &c.NamedArgs); err != nil {
+		return
+	}
+
+	if _, it, err = ToIterator(c.VM, iterable.Value, &c.NamedArgs); err != nil {
+		return
+	}
+
+	return IteratorObject(NewPipedInvokeIterator(it, args, 0, caller).
+		SetType(TTakeWhileIterator).
+		SetPostCall(func(state *IteratorState, ret Object) error {
+			if ret.IsFalsy() {
+				state.Mode = IteratorStateModeDone
+			}
+			return nil
+		})), nil
+}
+
+func BuiltinTeeFunc(c Call) (_ Object, err error) {
+	var (
+		iterable = &Arg{
+			Name: "iterable",
+			TypeAssertion: NewTypeAssertion(TypeAssertionHandlers{
+				"iterable": func(v Object) bool {
+					return Iterable(c.VM, v)
+				},
+			}),
+		}
+		n = &Arg{
+			Name:          "n",
+			TypeAssertion: TypeAssertionFromTypes(TInt),
+		}
+		it Iterator
+	)
+	if c.Args.Length() == 1 {
+		n.Value = Int(2)
+		err = c.Args.Destructure(iterable)
+	} else {
+		err = c.Args.Destructure(iterable, n)
+	}
+	if err != nil {
+		return
+	}
+	if n.Value.(Int) < 0 {
+		return nil, NewArgumentTypeError("2nd (n)", "non-negative integer", "negative integer")
+	}
+	if _, it, err = ToIterator(c.VM, iterable.Value, &c.NamedArgs); err != nil {
+		return
+	}
+	its := TeeIterators(it, int(n.Value.(Int)))
+	ret := make(Array, len(its))
+	for i, it := range its {
+		ret[i] = IteratorObject(it)
+	}
+	return ret, nil
+}
+
+func BuiltinCachedFunc(c Call) (_ Object, err error) {
+	if err = c.Args.CheckLen(1); err != nil {
+		return
+	}
+	var it Iterator
+	if _, it, err = ToIterator(c.VM, c.Args.GetOnly(0), &c.NamedArgs); err != nil {
+		return
+	}
+	return IteratorObject(CachedIterator(it)), nil
+}
+
 func BuiltinEachFunc(c Call) (_ Object, err error) {
 	var (
 		iterabler = &Arg{
@@ -563,12 +884,14 @@ func BuiltinEachFunc(c Call) (_ Object, err error) {
 	return iterabler.Value, err
 }
 
-func BuiltinReduceFunc(c Call) (_ Object, err error) {
+// reduceArgs parses (iterable, callback[, initial]) arguments of reduce like
+// builtins and returns the callback caller. Caller arguments are accumulator,
+// value and key.
+func reduceArgs(c Call, reducable bool) (iterable Object, args Array, caller VMCaller, hasInit bool, err error) {
 	var (
 		iterabler = &Arg{
 			Name: "iterable",
 			TypeAssertion: NewTypeAssertion(TypeAssertionHandlers{
-				"reducable": Reducable,
 				"iterable": func(v Object) bool {
 					return Iterable(c.VM, v)
 				},
@@ -581,37 +904,62 @@ func BuiltinReduceFunc(c Call) (_ Object, err error) {
 				"callable": Callable,
 			}),
 		}
-
-		args   = Array{Nil, Nil, Nil}
-		caller VMCaller
 	)
 
+	if reducable {
+		iterabler.TypeAssertion.AcceptHandler("reducable", Reducable)
+	}
+
+	args = Array{Nil, Nil, Nil}
+
 	if c.Args.Length() == 3 {
 		initialArg := &Arg{}
 		if err = c.Args.Destructure(iterabler, callback, initialArg); err != nil {
 			return
 		}
 		args[0] = initialArg.Value
+		hasInit = true
 	} else {
 		if err = c.Args.Destructure(iterabler, callback); err != nil {
 			return
 		}
 	}
 
-	if caller, err = NewInvoker(c.VM, callback.Value).Caller(Args{args}, &c.NamedArgs); err != nil {
+	iterable = iterabler.Value
+	caller, err = NewInvoker(c.VM, callback.Value).Caller(Args{args}, &c.NamedArgs)
+	return
+}
+
+func BuiltinReduceFunc(c Call) (_ Object, err error) {
+	var (
+		iterable Object
+		args     Array
+		caller   VMCaller
+	)
+
+	if iterable, args, caller, _, err = reduceArgs(c, true); err != nil {
 		return
 	}
 
-	if Reducable(iterabler.Value) {
-		return iterabler.Value.(Reducer).Reduce(c.VM, args[0], args, caller)
+	if Reducable(iterable) {
+		return iterable.(Reducer).Reduce(c.VM, args[0], args, caller)
 	}
 
 	var it Iterator
-	if _, it, err = ToIterator(c.VM, iterabler.Value, &c.NamedArgs); err != nil {
+	if _, it, err = ToIterator(c.VM, iterable, &c.NamedArgs); err != nil {
 		return
 	}
 
-	fe := NewPipedInvokeIterator(it, args, 1, caller)
+	fe := NewPipedInvokeIterator(it, args, 1, caller).
+		SetPostCall(func(state *IteratorState, ret Object) error {
+			if s, _ := ret.(*IterationStopValue); s != nil {
+				args[0] = s.Value
+				state.Mode = IteratorStateModeDone
+			} else {
+				state.Entry.V = ret
+			}
+			return nil
+		})
 
 	if args[0] == Nil {
 		fe.preCall = func(k, v Object) (Object, error) {
@@ -627,48 +975,185 @@ func BuiltinReduceFunc(c Call) (_ Object, err error) {
 	return args[0], err
 }
 
-func BuiltinErrorFunc(arg Object) Object {
-	return &Error{Name: "error", Message: arg.ToString()}
-}
-
-func BuiltinRegexpFunc(c Call) (_ Object, err error) {
+func BuiltinReduceRightFunc(c Call) (_ Object, err error) {
 	var (
-		input = Arg{
-			Name:          "input",
-			TypeAssertion: TypeAssertionFromTypes(TStr, TRawStr),
-		}
+		iterable Object
+		args     Array
+		caller   VMCaller
+		hasInit  bool
+		items    KeyValueArray
 	)
 
-	if err = c.Args.Destructure(&input); err != nil {
+	if iterable, args, caller, hasInit, err = reduceArgs(c, false); err != nil {
 		return
 	}
 
-	var re *regexp.Regexp
-	if re, err = regexp.Compile(input.Value.ToString()); err != nil {
+	if items, err = ItemsOf(c.VM, iterable, &c.NamedArgs); err != nil {
 		return
 	}
 
-	return (*Regexp)(re), err
-}
-
-func BuiltinTypeNameFunc(arg Object) Object { return Str(arg.Type().Name()) }
+	i := len(items) - 1
+	if !hasInit && i >= 0 {
+		args[0] = items[i].V
+		i--
+	}
 
-func BuiltinBoolFunc(arg Object) Object { return Bool(!arg.IsFalsy()) }
+	var ret Object
+	for ; i >= 0; i-- {
+		args[1] = items[i].V
+		args[2] = items[i].K
+		if ret, err = caller.Call(); err != nil {
+			return
+		}
+		if s, _ := ret.(*IterationStopValue); s != nil {
+			return s.Value, nil
+		}
+		args[0] = ret
+	}
+	return args[0], nil
+}
 
-func BuiltinFlagFunc(arg Object) Object { return Flag(!arg.IsFalsy()) }
+func BuiltinScanFunc(c Call) (_ Object, err error) {
+	var (
+		iterable Object
+		args     Array
+		caller   VMCaller
+		hasInit  bool
+		it       Iterator
+	)
 
-func BuiltinIntFunc(v int64) Object { return Int(v) }
+	if c.Args.Length() == 1 {
+		if r := readableFrom(c.Args.Get(0)); r != nil {
+			return scanReader(c, r, Str("lines"))
+		}
+	}
 
-func BuiltinUintFunc(v uint64) Object { return Uint(v) }
+	if iterable, args, caller, hasInit, err = reduceArgs(c, false); err != nil {
+		return
+	}
 
-func BuiltinFloatFunc(v float64) Object { return Float(v) }
+	if _, it, err = ToIterator(c.VM, iterable, &c.NamedArgs); err != nil {
+		return
+	}
 
-func BuiltinDecimalFunc(vm *VM, v Object) (Object, error) {
-	return Decimal(decimal.Zero).BinaryOp(vm, token.Add, v)
+	return IteratorObject(ScanIterator(it, args, caller, args[0], hasInit)), nil
 }
 
-func BuiltinCharFunc(arg Object) (Object, error) {
-	v, ok := ToChar(arg)
+// BuiltinLinesFunc returns an iterator which lazily reads the lines of the
+// reader, string or bytes argument.
+func BuiltinLinesFunc(c Call) (_ Object, err error) {
+	var readable = &Arg{
+		Name: "readable",
+		TypeAssertion: NewTypeAssertion(TypeAssertionHandlers{
+			"reader|str|bytes": func(v Object) bool {
+				return readableFrom(v) != nil
+			},
+		}),
+	}
+	if err = c.Args.Destructure(readable); err != nil {
+		return
+	}
+	return scanReader(c, readableFrom(readable.Value), nil)
+}
+
+// scanReader returns an iterator of the tokens of r. If split is not nil, the
+// split named argument selects the tokens, otherwise the tokens are lines.
+func scanReader(c Call, r io.Reader, split Object) (_ Object, err error) {
+	var (
+		splitArg = &NamedArgVar{
+			Name:  "split",
+			Value: Str("lines"),
+			TypeAssertion: NewTypeAssertion(TypeAssertionHandlers{
+				"str":      func(v Object) bool { _, ok := v.(Str); return ok },
+				"callable": Callable,
+			}),
+		}
+		maxLen = &NamedArgVar{
+			Name:          "maxLen",
+			Value:         Int(0),
+			TypeAssertion: TypeAssertionFromTypes(TInt),
+		}
+		splitFunc = bufio.ScanLines
+	)
+
+	vars := []*NamedArgVar{maxLen}
+	if split != nil {
+		vars = append(vars, splitArg)
+	}
+	if err = c.NamedArgs.Get(vars...); err != nil {
+		return
+	}
+	if split != nil {
+		if splitFunc, err = scanSplitFunc(c.VM, splitArg.Value); err != nil {
+			return
+		}
+	}
+	return IteratorObject(ReaderScanIterator(c.Args.Get(0), r, splitFunc, int(maxLen.Value.(Int)))), nil
+}
+
+func BuiltinStopFunc(c Call) (_ Object, err error) {
+	if err = c.Args.CheckMaxLen(1); err != nil {
+		return
+	}
+	return &IterationStopValue{Value: c.Args.GetDefault(0, Nil)}, nil
+}
+
+// BuiltinExitFunc raises an ErrExit error with the exit code argument which
+// defaults to 0. See ExitError.
+func BuiltinExitFunc(c Call) (_ Object, err error) {
+	if err = c.Args.CheckMaxLen(1); err != nil {
+		return
+	}
+	code, ok := ToGoInt(c.Args.GetDefault(0, Int(0)))
+	if !ok {
+		return nil, NewArgumentTypeError("1st", "int", c.Args.Get(0).Type().Name())
+	}
+	exit := &ExitError{Code: code}
+	return nil, &Error{Name: ErrExit.Name, Message: exit.Error(), Cause: exit}
+}
+
+func BuiltinErrorFunc(arg Object) Object {
+	return &Error{Name: "error", Message: arg.ToString()}
+}
+
+func BuiltinRegexpFunc(c Call) (_ Object, err error) {
+	var (
+		input = Arg{
+			Name:          "input",
+			TypeAssertion: TypeAssertionFromTypes(TStr, TRawStr),
+		}
+	)
+
+	if err = c.Args.Destructure(&input); err != nil {
+		return
+	}
+
+	var re *regexp.Regexp
+	if re, err = regexp.Compile(input.Value.ToString()); err != nil {
+		return
+	}
+
+	return (*Regexp)(re), err
+}
+
+func BuiltinTypeNameFunc(arg Object) Object { return Str(arg.Type().Name()) }
+
+func BuiltinBoolFunc(arg Object) Object { return Bool(!arg.IsFalsy()) }
+
+func BuiltinFlagFunc(arg Object) Object { return Flag(!arg.IsFalsy()) }
+
+func BuiltinIntFunc(v int64) Object { return Int(v) }
+
+func BuiltinUintFunc(v uint64) Object { return Uint(v) }
+
+func BuiltinFloatFunc(v float64) Object { return Float(v) }
+
+func BuiltinDecimalFunc(vm *VM, v Object) (Object, error) {
+	return Decimal(decimal.Zero).BinaryOp(vm, token.Add, v)
+}
+
+func BuiltinCharFunc(arg Object) (Object, error) {
+	v, ok := ToChar(arg)
 	if ok && v != utf8.RuneError {
 		return v, nil
 	}
@@ -713,8 +1198,50 @@ func BuiltinStringFunc(c Call) (ret Object, err error) {
 
 	switch c.Args.Length() {
 	case 1:
+		var (
+			color = &NamedArgVar{
+				Name:          "color",
+				TypeAssertion: TypeAssertionFromTypes(TStr),
+			}
+			bg = &NamedArgVar{
+				Name:          "bg",
+				TypeAssertion: TypeAssertionFromTypes(TStr),
+			}
+			sortKeys = &NamedArgVar{
+				Name:          "sortKeys",
+				TypeAssertion: TypeAssertionFromTypes(TBool),
+			}
+			canonical = &NamedArgVar{
+				Name:          "canonical",
+				TypeAssertion: TypeAssertionFromTypes(TBool),
+			}
+		)
+		if err = c.NamedArgs.GetOne(color, bg, sortKeys, canonical); err != nil {
+			return
+		}
 		o := c.Args.GetOnly(0)
-		ret = Str(o.ToString())
+		if canonical.Value == True || sortKeys.Value == True {
+			var s string
+			if s, err = StableString(c.VM, o, canonical.Value == True); err != nil {
+				return
+			}
+			ret = Str(s)
+		} else {
+			ret = Str(o.ToString())
+		}
+		if color.Value != nil || bg.Value != nil {
+			var styles [2]string
+			for i, v := range []Object{color.Value, bg.Value} {
+				if v != nil {
+					styles[i] = string(v.(Str))
+				}
+			}
+			var s string
+			if s, err = Colorize(string(ret.(Str)), styles[0], styles[1]); err != nil {
+				return nil, err
+			}
+			ret = Str(s)
+		}
 	default:
 		var (
 			b          strings.Builder
@@ -842,11 +1369,10 @@ func BuiltinPrintfFunc(c Call) (_ Object, err error) {
 		n, err = fmt.Fprint(w, c.Args.Get(0).ToString())
 	default:
 		format, _ := c.Args.ShiftOk()
-		vargs := make([]any, 0, size-1)
-		for i := 0; i < size-1; i++ {
-			vargs = append(vargs, c.Args.Get(i))
+		vargs, ferr := fmtArgs(c.VM, c.Args.Values())
+		if n, err = fmt.Fprintf(w, format.ToString(), vargs...); err == nil {
+			err = *ferr
 		}
-		n, err = fmt.Fprintf(w, format.ToString(), vargs...)
 	}
 	return Int(n), err
 }
@@ -1054,8 +1580,86 @@ func BuiltinBufferFunc(c Call) (ret Object, err error) {
 	return w, err
 }
 
+// BuiltinArrayFunc returns the arguments as an array. If the length named
+// argument is greater than the number of the arguments, the array is filled up
+// to length with the fill named argument. The cap named argument sets the
+// capacity of the array to append values without growing it.
+func BuiltinArrayFunc(c Call) (_ Object, err error) {
+	if c.NamedArgs.IsFalsy() {
+		return c.Args.Values(), nil
+	}
+
+	var (
+		length = &NamedArgVar{
+			Name:          "length",
+			Value:         Int(-1),
+			TypeAssertion: TypeAssertionFromTypes(TInt),
+		}
+		capacity = &NamedArgVar{
+			Name:          "cap",
+			Value:         Int(0),
+			TypeAssertion: TypeAssertionFromTypes(TInt),
+		}
+		fill = &NamedArgVar{
+			Name:  "fill",
+			Value: Nil,
+		}
+	)
+
+	if err = c.NamedArgs.Get(length, capacity, fill); err != nil {
+		return
+	}
+
+	n := c.Args.Length()
+	l := int(length.Value.(Int))
+	if l < 0 {
+		l = n
+	} else if l < n {
+		return nil, ErrUnexpectedArgValue.NewError(
+			fmt.Sprintf("length(%d) is less than the number of values(%d)", l, n))
+	} else if l > maxCapacity {
+		return nil, ErrUnexpectedArgValue.NewError(
+			fmt.Sprintf("length(%d) is greater than %d", l, maxCapacity))
+	}
+
+	cp := int(capacity.Value.(Int))
+	if cp < 0 || cp > maxCapacity {
+		return nil, ErrUnexpectedArgValue.NewError(
+			fmt.Sprintf("cap(%d) is not in range [0, %d]", cp, maxCapacity))
+	}
+
+	arr := make(Array, 0, max(l, cp))
+	c.Args.Walk(func(_ int, arg Object) any {
+		arr = append(arr, arg)
+		return nil
+	})
+	for len(arr) < l {
+		arr = append(arr, fill.Value)
+	}
+	return arr, nil
+}
+
+// BuiltinDictFunc returns a dict of the entries of the arguments and the named
+// arguments. The cap named argument is not an entry but the number of the
+// entries to allocate space for.
 func BuiltinDictFunc(c Call) (ret Object, err error) {
-	d := Dict{}
+	capacity := &NamedArgVar{
+		Name:          "cap",
+		Value:         Int(0),
+		TypeAssertion: TypeAssertionFromTypes(TInt),
+	}
+
+	var named Dict
+	if named, err = c.NamedArgs.GetVar(capacity); err != nil {
+		return
+	}
+
+	n := int(capacity.Value.(Int))
+	if n < 0 || n > maxCapacity {
+		return nil, ErrUnexpectedArgValue.NewError(
+			fmt.Sprintf("cap(%d) is not in range [0, %d]", n, maxCapacity))
+	}
+	d := make(Dict, n)
 	c.Args.Walk(func(_ int, arg Object) any {
 		switch t := arg.(type) {
 		case KeyValueArray:
@@ -1082,117 +1686,651 @@ func BuiltinDictFunc(c Call) (ret Object, err error) {
 	if err != nil {
 		return
 	}
-	if len(d) == 0 {
-		d = c.NamedArgs.AllDict()
-	} else {
-		for k, v := range c.NamedArgs.AllDict() {
-			d[k] = v
+	if len(d) == 0 && n == 0 {
+		d = named
+	} else {
+		for k, v := range named {
+			d[k] = v
+		}
+	}
+	return d, nil
+}
+
+func BuiltinPrintFunc(c Call) (_ Object, err error) {
+	var (
+		w     io.Writer = c.VM.StdOut
+		total Int
+		n     int
+	)
+
+	if err = c.Args.CheckMinLen(1); err != nil {
+		return
+	}
+
+	arg := c.Args.Get(0)
+	if w2, ok := arg.(Writer); ok {
+		w = w2
+		c.Args.Shift()
+	}
+
+	switch size := c.Args.Length(); size {
+	case 0:
+	default:
+		vargs := make([]any, 0, size)
+		for i := 0; i < size; i++ {
+			vargs = append(vargs, c.Args.Get(i))
+		}
+		n, err = fmt.Fprint(w, vargs...)
+		return Int(n), err
+	}
+
+	return total, err
+}
+
+// BuiltinPrintTableFunc implements `printTable(rows, columns=nil,
+// align="left", maxWidth=0, out=stdout)` which prints the rows aligned in
+// columns, see WriteTable. align is an alignment of all columns, an array of
+// the alignments of the columns or a dict of the column names to their
+// alignments.
+func BuiltinPrintTableFunc(c Call) (_ Object, err error) {
+	var (
+		rows = &Arg{
+			Name:          "rows",
+			TypeAssertion: TypeAssertionFromTypes(TArray),
+		}
+		columns = &NamedArgVar{
+			Name:          "columns",
+			TypeAssertion: TypeAssertionFromTypes(TArray),
+		}
+		align = &NamedArgVar{
+			Name:          "align",
+			TypeAssertion: TypeAssertionFromTypes(TStr, TArray, TDict),
+		}
+		maxWidth = &NamedArgVar{
+			Name:          "maxWidth",
+			Value:         Int(0),
+			TypeAssertion: TypeAssertionFromTypes(TInt),
+		}
+		out = &NamedArgVar{
+			Name:  "out",
+			Value: c.VM.StdOut,
+			TypeAssertion: NewTypeAssertion(TypeAssertionHandlers{
+				"writer": func(v Object) bool {
+					return WriterFrom(v) != nil
+				},
+			}),
+		}
+		opts TableOptions
+	)
+	if err = c.NamedArgs.Get(columns, align, maxWidth, out); err != nil {
+		return
+	}
+	if err = c.Args.Destructure(rows); err != nil {
+		return
+	}
+
+	if columns.Value != nil {
+		for _, col := range columns.Value.(Array) {
+			opts.Columns = append(opts.Columns, col.ToString())
+		}
+	}
+	if len(opts.Columns) == 0 {
+		opts.Columns = tableColumns(rows.Value.(Array))
+	}
+	switch v := align.Value.(type) {
+	case Str:
+		opts.DefaultAlign = string(v)
+	case Array:
+		for _, a := range v {
+			opts.Align = append(opts.Align, a.ToString())
+		}
+	case Dict:
+		opts.Align = make([]string, len(opts.Columns))
+		for i, col := range opts.Columns {
+			if a, ok := v[col]; ok {
+				opts.Align[i] = a.ToString()
+			}
+		}
+	}
+	for _, a := range append(opts.Align, opts.DefaultAlign) {
+		switch a {
+		case "", AlignLeft, AlignRight, AlignCenter:
+		default:
+			return nil, ErrUnexpectedArgValue.NewError("align: unknown alignment " + strconv.Quote(a))
+		}
+	}
+	opts.MaxWidth = int(maxWidth.Value.(Int))
+
+	n, err := WriteTable(WriterFrom(out.Value), rows.Value.(Array), opts)
+	return Int(n), err
+}
+
+// BuiltinProgressFunc implements `progress(total=0, label="", out=stderr)`
+// which returns a Progress of total steps, a spinner if total is not
+// positive.
+func BuiltinProgressFunc(c Call) (_ Object, err error) {
+	if err = c.Args.CheckRangeLen(0, 1); err != nil {
+		return
+	}
+	var (
+		total      Int
+		label, out = progressArgs(c)
+	)
+	if err = c.NamedArgs.Get(label, out); err != nil {
+		return
+	}
+	if c.Args.Length() == 1 {
+		var ok bool
+		if total, ok = c.Args.GetOnly(0).(Int); !ok {
+			return nil, NewArgumentTypeError("1st (total)", "int", c.Args.GetOnly(0).Type().Name())
+		}
+	}
+	return NewProgress(WriterFrom(out.Value), int(total), string(label.Value.(Str))), nil
+}
+
+// BuiltinWithProgressFunc implements `withProgress(iterable, total=nil,
+// label="", out=stderr)` which returns an iterator of the entries of iterable
+// rendering the progress of the iteration. total defaults to the length of
+// iterable if it is known.
+func BuiltinWithProgressFunc(c Call) (_ Object, err error) {
+	var (
+		iterable = &Arg{
+			Name: "iterable",
+			TypeAssertion: NewTypeAssertion(TypeAssertionHandlers{
+				"iterable": func(v Object) bool {
+					return Iterable(c.VM, v)
+				},
+			}),
+		}
+		total = &NamedArgVar{
+			Name:          "total",
+			TypeAssertion: TypeAssertionFromTypes(TInt),
+		}
+		label, out = progressArgs(c)
+		it         Iterator
+		l          int
+	)
+	if err = c.NamedArgs.Get(total, label, out); err != nil {
+		return
+	}
+	if err = c.Args.Destructure(iterable); err != nil {
+		return
+	}
+	if l, it, err = ToIterator(c.VM, iterable.Value, &NamedArgs{}); err != nil {
+		return
+	}
+	if total.Value != nil {
+		l = int(total.Value.(Int))
+	} else if l < 0 {
+		l = IteratorLengthHint(it)
+	}
+	p := NewProgress(WriterFrom(out.Value), l, string(label.Value.(Str)))
+	return IteratorObject(ProgressIterator(it, p)), nil
+}
+
+func progressArgs(c Call) (label, out *NamedArgVar) {
+	label = &NamedArgVar{
+		Name:          "label",
+		Value:         Str(""),
+		TypeAssertion: TypeAssertionFromTypes(TStr),
+	}
+	out = &NamedArgVar{
+		Name:  "out",
+		Value: c.VM.StdErr,
+		TypeAssertion: NewTypeAssertion(TypeAssertionHandlers{
+			"writer": func(v Object) bool {
+				return WriterFrom(v) != nil
+			},
+		}),
+	}
+	return
+}
+
+func BuiltinPrintlnFunc(c Call) (ret Object, err error) {
+	var (
+		w io.Writer = c.VM.StdOut
+		n int
+	)
+
+	switch size := c.Args.Length(); size {
+	case 0:
+		n, err = w.Write([]byte("\n"))
+	default:
+		arg := c.Args.Get(0)
+		if w2, ok := arg.(Writer); ok {
+			w = w2
+			c.Args.Shift()
+			size--
+		}
+
+		var (
+			callerArgs = Array{nil}
+			caller     = NewArgCaller(c.VM, c.VM.Builtins.Objects[BuiltinStr].(CallerObject), callerArgs, c.NamedArgs)
+			s          Object
+			n2         int
+		)
+
+		c.Args.Walk(func(i int, arg Object) any {
+			callerArgs[0] = arg
+			if s, err = caller(); err != nil {
+				return err
+			}
+			n2, err = w.Write([]byte(s.ToString()))
+			n += n2
+
+			if i < size-1 {
+				n2, err = w.Write([]byte(" "))
+				n += n2
+			}
+
+			return err
+		})
+
+		if err != nil {
+			return
+		}
+
+		n2, err = w.Write([]byte("\n"))
+		n += n2
+	}
+	return Int(n), err
+}
+
+func BuiltinSprintfFunc(c Call) (ret Object, err error) {
+	ret = Nil
+	switch size := c.Args.Length(); size {
+	case 0:
+		err = ErrWrongNumArguments.NewError("want>=1 got=0")
+	case 1:
+		ret = Str(c.Args.Get(0).ToString())
+	default:
+		format, _ := c.Args.ShiftOk()
+		vargs, ferr := fmtArgs(c.VM, c.Args.Values())
+		if ret = Str(fmt.Sprintf(format.ToString(), vargs...)); *ferr != nil {
+			return Nil, *ferr
+		}
+	}
+	return
+}
+
+func BuiltinGlobalsFunc(c Call) (Object, error) {
+	return c.VM.GetGlobals(), nil
+}
+
+func BuiltinSuspendFunc(c Call) (_ Object, err error) {
+	if err = c.Args.CheckMaxLen(1); err != nil {
+		return
+	}
+	if err = c.VM.suspend(c.Args.GetDefault(0, Nil)); err != nil {
+		return
+	}
+	return Nil, nil
+}
+
+func BuiltinRecoverFunc(c Call) (_ Object, err error) {
+	if err = c.Args.CheckLen(0); err != nil {
+		return
+	}
+	if e := c.VM.recover(); e != nil {
+		return e, nil
+	}
+	return Nil, nil
+}
+
+// BuiltinRetryFunc calls fn until it does not throw an error. Between attempts
+// it waits for backoff duration which is multiplied by factor after every
+// attempt. If all attempts fail, the last error is thrown. The errors which
+// cannot be caught, like the exit, are thrown without retrying.
+func BuiltinRetryFunc(c Call) (ret Object, err error) {
+	var (
+		fn = &Arg{
+			Name: "fn",
+			TypeAssertion: NewTypeAssertion(TypeAssertionHandlers{
+				"callable": Callable,
+			}),
+		}
+		attempts = &NamedArgVar{
+			Name:          "attempts",
+			Value:         Int(3),
+			TypeAssertion: TypeAssertionFromTypes(TInt),
+		}
+		backoff = &NamedArgVar{
+			Name:          "backoff",
+			Value:         Int(0),
+			TypeAssertion: TypeAssertionFromTypes(TInt),
+		}
+		maxBackoff = &NamedArgVar{
+			Name:          "maxBackoff",
+			Value:         Int(0),
+			TypeAssertion: TypeAssertionFromTypes(TInt),
+		}
+		factor = &NamedArgVar{
+			Name:          "factor",
+			Value:         Float(2),
+			TypeAssertion: TypeAssertionFromTypes(TFloat, TInt),
+		}
+		jitter = &NamedArgVar{
+			Name:          "jitter",
+			Value:         Float(0),
+			TypeAssertion: TypeAssertionFromTypes(TFloat, TInt),
+		}
+		retryIf = &NamedArgVar{
+			Name: "retryIf",
+			TypeAssertion: NewTypeAssertion(TypeAssertionHandlers{
+				"callable": Callable,
+			}),
+		}
+	)
+
+	if err = c.NamedArgs.Get(attempts, backoff, maxBackoff, factor, jitter, retryIf); err != nil {
+		return
+	}
+
+	if err = c.Args.Destructure(fn); err != nil {
+		return
+	}
+
+	n := int(attempts.Value.(Int))
+	if n < 1 {
+		return nil, NewArgumentTypeError("attempts", "positive integer", "non-positive integer")
+	}
+
+	var (
+		delay    = float64(backoff.Value.(Int))
+		maxDelay = float64(maxBackoff.Value.(Int))
+		mul, _   = ToGoFloat64(factor.Value)
+		jit, _   = ToGoFloat64(jitter.Value)
+		caller   VMCaller
+		ifCaller VMCaller
+		ifArgs   = Array{Nil}
+	)
+
+	if caller, err = NewInvoker(c.VM, fn.Value).Caller(Args{}, nil); err != nil {
+		return
+	}
+	defer caller.Close()
+
+	if retryIf.Value != nil && retryIf.Value != Nil {
+		if ifCaller, err = NewInvoker(c.VM, retryIf.Value).Caller(Args{ifArgs}, nil); err != nil {
+			return
+		}
+		defer ifCaller.Close()
+	}
+
+	for i := 1; ; i++ {
+		if ret, err = caller.Call(); err == nil {
+			return
+		}
+
+		// exit, abort, cancellation and budget errors cannot be caught, so
+		// they are not retried
+		var budgetErr *BudgetExceededError
+		if i == n || c.VM.Aborted() || c.VM.Context.Err() != nil || errors.Is(err, ErrVMAborted) ||
+			isExitError(err) || errors.As(err, &budgetErr) {
+			return
+		}
+
+		if ifCaller != nil {
+			ifArgs[0] = c.VM.newErrorFromError(err)
+			if ok, ifErr := ifCaller.Call(); ifErr != nil {
+				return nil, ifErr
+			} else if ok.IsFalsy() {
+				return
+			}
+		}
+
+		d := delay
+		if jit > 0 {
+			d += d * jit * (rand.Float64()*2 - 1)
+		}
+		if err = c.VM.sleep(time.Duration(d)); err != nil {
+			return
+		}
+
+		if delay *= mul; maxDelay > 0 && delay > maxDelay {
+			delay = maxDelay
+		}
+	}
+}
+
+func BuiltinGroupFunc(c Call) (_ Object, err error) {
+	limit := &NamedArgVar{
+		Name:          "limit",
+		Value:         Int(0),
+		TypeAssertion: TypeAssertionFromTypes(TInt),
+	}
+	if err = c.NamedArgs.Get(limit); err != nil {
+		return
+	}
+	if err = c.Args.CheckLen(0); err != nil {
+		return
+	}
+	return NewTaskGroup(c.VM, int(limit.Value.(Int))), nil
+}
+
+func BuiltinSleepFunc(c Call) (_ Object, err error) {
+	if err = c.Args.CheckLen(1); err != nil {
+		return
+	}
+	var d time.Duration
+	if d, err = durationArg("1st", c.Args.GetOnly(0)); err != nil {
+		return
+	}
+	return Nil, c.VM.sleep(d)
+}
+
+func BuiltinTickerFunc(c Call) (_ Object, err error) {
+	if err = c.Args.CheckLen(1); err != nil {
+		return
+	}
+	var d time.Duration
+	if d, err = durationArg("1st", c.Args.GetOnly(0)); err != nil {
+		return
+	}
+	if d <= 0 {
+		return nil, NewArgumentTypeError("1st", "positive duration", "non-positive duration")
+	}
+	return IteratorObject(TickerIterator(d)), nil
+}
+
+func BuiltinAfterFunc(c Call) (_ Object, err error) {
+	if err = c.Args.CheckMinLen(2); err != nil {
+		return
+	}
+	var d time.Duration
+	if d, err = durationArg("1st", c.Args.GetOnly(0)); err != nil {
+		return
+	}
+	fn := c.Args.GetOnly(1)
+	if !Callable(fn) {
+		return nil, NewArgumentTypeError("2nd", "callable", fn.Type().Name())
+	}
+	args := append(Array{}, c.Args.Values()[2:]...)
+	return AfterFunc(c.VM, d, fn, Args{args}, c.NamedArgs.Copy().(*NamedArgs)), nil
+}
+
+func BuiltinEventsFunc(c Call) (Object, error) {
+	if err := c.Args.CheckLen(0); err != nil {
+		return nil, err
+	}
+	return NewEventEmitter(), nil
+}
+
+func BuiltinMockFunc(c Call) (_ Object, err error) {
+	if err = c.Args.CheckLen(0); err != nil {
+		return
+	}
+	var (
+		returns = &NamedArgVar{
+			Name:          "returns",
+			TypeAssertion: TypeAssertionFromTypes(TDict),
+		}
+		calls = &NamedArgVar{
+			Name: "calls",
+			TypeAssertion: NewTypeAssertion(TypeAssertionHandlers{
+				"callable": Callable,
+			}),
 		}
+	)
+	if err = c.NamedArgs.Get(returns, calls); err != nil {
+		return
 	}
-	return d, nil
-}
 
-func BuiltinPrintFunc(c Call) (_ Object, err error) {
 	var (
-		w     io.Writer = c.VM.StdOut
-		total Int
-		n     int
+		d        Dict
+		recorder CallerObject
 	)
+	if returns.Value != nil {
+		d = returns.Value.(Dict).Copy().(Dict)
+	}
+	if calls.Value != nil {
+		recorder = calls.Value.(CallerObject)
+	}
+	return NewMock(d, recorder), nil
+}
 
-	if err = c.Args.CheckMinLen(1); err != nil {
+// BuiltinDeepEqualFunc implements `deepEqual(a, b, tolerance=0.0)` which
+// reports whether a and b are deeply equal, see DeepEqual.
+func BuiltinDeepEqualFunc(c Call) (_ Object, err error) {
+	a, b, tolerance, err := compareArgs(c)
+	if err != nil {
 		return
 	}
+	return Bool(DeepEqual(a, b, tolerance)), nil
+}
 
-	arg := c.Args.Get(0)
-	if w2, ok := arg.(Writer); ok {
-		w = w2
-		c.Args.Shift()
+// BuiltinDiffFunc implements `diff(a, b, tolerance=0.0)` which returns the
+// differences between a and b, see Diff.
+func BuiltinDiffFunc(c Call) (_ Object, err error) {
+	a, b, tolerance, err := compareArgs(c)
+	if err != nil {
+		return
 	}
+	return Diff(a, b, tolerance), nil
+}
 
-	switch size := c.Args.Length(); size {
-	case 0:
-	default:
-		vargs := make([]any, 0, size)
-		for i := 0; i < size; i++ {
-			vargs = append(vargs, c.Args.Get(i))
+// BuiltinMergeFunc implements `merge(a, b, deep=false, arrays="replace")`
+// which returns a new dict of a merged with b, see Merge.
+func BuiltinMergeFunc(c Call) (_ Object, err error) {
+	var (
+		a = &Arg{
+			Name:          "a",
+			TypeAssertion: TypeAssertionFromTypes(TDict),
 		}
-		n, err = fmt.Fprint(w, vargs...)
-		return Int(n), err
+		b = &Arg{
+			Name:          "b",
+			TypeAssertion: TypeAssertionFromTypes(TDict),
+		}
+		deep = &NamedArgVar{
+			Name:          "deep",
+			Value:         False,
+			TypeAssertion: TypeAssertionFromTypes(TBool),
+		}
+		arrays = &NamedArgVar{
+			Name:          "arrays",
+			Value:         Str(MergeArraysReplace),
+			TypeAssertion: TypeAssertionFromTypes(TStr),
+		}
+	)
+	if err = c.NamedArgs.Get(deep, arrays); err != nil {
+		return
+	}
+	if err = c.Args.Destructure(a, b); err != nil {
+		return
 	}
+	mode := string(arrays.Value.(Str))
+	if mode != MergeArraysReplace && mode != MergeArraysConcat {
+		return nil, ErrUnexpectedArgValue.NewError("arrays: want " +
+			strconv.Quote(MergeArraysReplace) + " or " + strconv.Quote(MergeArraysConcat) +
+			", got " + strconv.Quote(mode))
+	}
+	return Merge(a.Value.(Dict), b.Value.(Dict), !deep.Value.IsFalsy(), mode), nil
+}
 
-	return total, err
+// BuiltinMergePatchFunc implements `mergePatch(doc, patch)` which returns doc
+// patched by the JSON merge patch, see MergePatch.
+func BuiltinMergePatchFunc(c Call) (_ Object, err error) {
+	if err = c.Args.CheckLen(2); err != nil {
+		return
+	}
+	return MergePatch(c.Args.GetOnly(0), c.Args.GetOnly(1)), nil
 }
 
-func BuiltinPrintlnFunc(c Call) (ret Object, err error) {
+// BuiltinGetPathFunc implements `getPath(doc, pointer)` which returns the
+// value of doc at the JSON pointer, see GetPath.
+func BuiltinGetPathFunc(c Call) (_ Object, err error) {
 	var (
-		w io.Writer = c.VM.StdOut
-		n int
+		doc     = &Arg{}
+		pointer = &Arg{
+			Name:          "pointer",
+			TypeAssertion: TypeAssertionFromTypes(TStr),
+		}
 	)
+	if err = c.Args.Destructure(doc, pointer); err != nil {
+		return
+	}
+	return GetPath(doc.Value, string(pointer.Value.(Str)))
+}
 
-	switch size := c.Args.Length(); size {
-	case 0:
-		n, err = w.Write([]byte("\n"))
-	default:
-		arg := c.Args.Get(0)
-		if w2, ok := arg.(Writer); ok {
-			w = w2
-			c.Args.Shift()
-			size--
+// BuiltinSetPathFunc implements `setPath(doc, pointer, value)` which sets the
+// value of doc at the JSON pointer and returns doc, see SetPath.
+func BuiltinSetPathFunc(c Call) (_ Object, err error) {
+	var (
+		doc     = &Arg{}
+		pointer = &Arg{
+			Name:          "pointer",
+			TypeAssertion: TypeAssertionFromTypes(TStr),
 		}
+		value = &Arg{}
+	)
+	if err = c.Args.Destructure(doc, pointer, value); err != nil {
+		return
+	}
+	return SetPath(doc.Value, string(pointer.Value.(Str)), value.Value)
+}
 
-		var (
-			callerArgs = Array{nil}
-			caller     = NewArgCaller(c.VM, c.VM.Builtins.Objects[BuiltinStr].(CallerObject), callerArgs, c.NamedArgs)
-			s          Object
-			n2         int
-		)
-
-		c.Args.Walk(func(i int, arg Object) any {
-			callerArgs[0] = arg
-			if s, err = caller(); err != nil {
-				return err
-			}
-			n2, err = w.Write([]byte(s.ToString()))
-			n += n2
-
-			if i < size-1 {
-				n2, err = w.Write([]byte(" "))
-				n += n2
-			}
-
-			return err
-		})
+func compareArgs(c Call) (a, b Object, tolerance float64, err error) {
+	if err = c.Args.CheckLen(2); err != nil {
+		return
+	}
+	t := &NamedArgVar{
+		Name:          "tolerance",
+		Value:         Float(0),
+		TypeAssertion: TypeAssertionFromTypes(TFloat, TInt),
+	}
+	if err = c.NamedArgs.Get(t); err != nil {
+		return
+	}
+	tolerance, _ = ToGoFloat64(t.Value)
+	return c.Args.GetOnly(0), c.Args.GetOnly(1), tolerance, nil
+}
 
-		if err != nil {
-			return
-		}
+func BuiltinMutexFunc(c Call) (Object, error) {
+	if err := c.Args.CheckLen(0); err != nil {
+		return nil, err
+	}
+	return &Mutex{}, nil
+}
 
-		n2, err = w.Write([]byte("\n"))
-		n += n2
+func BuiltinRWMutexFunc(c Call) (Object, error) {
+	if err := c.Args.CheckLen(0); err != nil {
+		return nil, err
 	}
-	return Int(n), err
+	return &RWMutex{}, nil
 }
 
-func BuiltinSprintfFunc(c Call) (ret Object, err error) {
-	ret = Nil
-	switch size := c.Args.Length(); size {
-	case 0:
-		err = ErrWrongNumArguments.NewError("want>=1 got=0")
-	case 1:
-		ret = Str(c.Args.Get(0).ToString())
-	default:
-		format, _ := c.Args.ShiftOk()
-		vargs := make([]any, 0, size-1)
-		for i := 0; i < size-1; i++ {
-			vargs = append(vargs, c.Args.Get(i))
-		}
-		ret = Str(fmt.Sprintf(format.ToString(), vargs...))
+func BuiltinWaitGroupFunc(c Call) (Object, error) {
+	if err := c.Args.CheckLen(0); err != nil {
+		return nil, err
 	}
-	return
+	return &WaitGroup{}, nil
 }
 
-func BuiltinGlobalsFunc(c Call) (Object, error) {
-	return c.VM.GetGlobals(), nil
+func BuiltinOnceFunc(c Call) (Object, error) {
+	if err := c.Args.CheckLen(0); err != nil {
+		return nil, err
+	}
+	return &Once{}, nil
 }
 
 func BuiltinIsFunc(c Call) (ok Object, err error) {
@@ -1279,53 +2417,138 @@ func BuiltinNamedParamTypeCheckFunc(c Call) (val Object, err error) {
 }
 
 func NamedParamTypeCheck(name string, typeso, value Object) (badTypes string, err error) {
-	var (
-		types     []ObjectType
-		assertion = &TypeAssertion{
-			Handlers: map[string]TypeAssertionHandler{
-				"ObjectType|[]ObjectType": func(v Object) bool {
-					switch t := v.(type) {
-					case ObjectType:
-						types = append(types, t)
-						return true
-					case Array:
-						for _, object := range t {
-						try:
-							switch t2 := object.(type) {
-							case ObjectType:
-								types = append(types, t2)
-							case *CallerObjectWithMethods:
-								object = t2.CallerObject
-								goto try
-							default:
-								return false
-							}
-						}
-						return true
-					default:
-					try2:
-						switch t2 := t.(type) {
+	var types []ObjectType
+	if types, badTypes = ObjectTypesOf(typeso); badTypes != "" {
+		return
+	}
+
+	err = NamedParamTypeCheckAssertion(name, TypeAssertionFromTypes(types...), value)
+	return
+}
+
+// ObjectTypesOf returns types of typeso, which is an ObjectType or an Array of
+// ObjectType. If typeso is not valid, badTypes is the expected types names.
+func ObjectTypesOf(typeso Object) (types []ObjectType, badTypes string) {
+	assertion := &TypeAssertion{
+		Handlers: map[string]TypeAssertionHandler{
+			"ObjectType|[]ObjectType": func(v Object) bool {
+				switch t := v.(type) {
+				case ObjectType:
+					types = append(types, t)
+					return true
+				case Array:
+					for _, object := range t {
+					try:
+						switch t2 := object.(type) {
 						case ObjectType:
 							types = append(types, t2)
-							return true
 						case *CallerObjectWithMethods:
-							t = t2.CallerObject
-							goto try2
+							object = t2.CallerObject
+							goto try
 						default:
 							return false
 						}
 					}
-				},
+					return true
+				default:
+				try2:
+					switch t2 := t.(type) {
+					case ObjectType:
+						types = append(types, t2)
+						return true
+					case *CallerObjectWithMethods:
+						t = t2.CallerObject
+						goto try2
+					default:
+						return false
+					}
+				}
 			},
+		},
+	}
+
+	badTypes = assertion.Accept(typeso)
+	return
+}
+
+func BuiltinVarTypeCheckFunc(c Call) (val Object, err error) {
+	var (
+		nameArg = &Arg{
+			Name:          "name",
+			TypeAssertion: TypeAssertionFromTypes(TStr),
+		}
+
+		typesArg = &Arg{
+			Name: "types",
+		}
+
+		valueArg = &Arg{
+			Name: "value",
 		}
+
+		types    []ObjectType
+		badTypes string
 	)
+	if err = c.Args.Destructure(nameArg, typesArg, valueArg); err != nil {
+		return
+	}
+
+	if types, badTypes = ObjectTypesOf(typesArg.Value); badTypes != "" {
+		return nil, NewArgumentTypeError(
+			"2nd (types)",
+			badTypes,
+			typesArg.Value.Type().Name(),
+		)
+	}
+
+	if expectedNames := TypeAssertionFromTypes(types...).Accept(valueArg.Value); expectedNames != "" {
+		return nil, NewVariableTypeError(
+			string(nameArg.Value.(Str)),
+			expectedNames,
+			valueArg.Value.Type().Name(),
+		)
+	}
+	return Nil, nil
+}
+
+func BuiltinReturnTypeCheckFunc(c Call) (val Object, err error) {
+	var (
+		nameArg = &Arg{
+			Name:          "name",
+			TypeAssertion: TypeAssertionFromTypes(TStr),
+		}
 
-	if badTypes = assertion.Accept(typeso); badTypes != "" {
+		typesArg = &Arg{
+			Name: "types",
+		}
+
+		valueArg = &Arg{
+			Name: "value",
+		}
+
+		types    []ObjectType
+		badTypes string
+	)
+	if err = c.Args.Destructure(nameArg, typesArg, valueArg); err != nil {
 		return
 	}
 
-	err = NamedParamTypeCheckAssertion(name, TypeAssertionFromTypes(types...), value)
-	return
+	if types, badTypes = ObjectTypesOf(typesArg.Value); badTypes != "" {
+		return nil, NewArgumentTypeError(
+			"2nd (types)",
+			badTypes,
+			typesArg.Value.Type().Name(),
+		)
+	}
+
+	if expectedNames := TypeAssertionFromTypes(types...).Accept(valueArg.Value); expectedNames != "" {
+		return nil, NewReturnTypeError(
+			string(nameArg.Value.(Str)),
+			expectedNames,
+			valueArg.Value.Type().Name(),
+		)
+	}
+	return valueArg.Value, nil
 }
 
 func NamedParamTypeCheckAssertion(name string, assertion *TypeAssertion, value Object) (err error) {
@@ -1463,6 +2686,28 @@ func BuiltinIsIteratorFunc(arg Object) Object {
 }
 
 func BuiltinIterateFunc(c Call) (_ Object, err error) {
+	if c.Args.Length() == 2 {
+		// iterate(fn, seed) generates seed, fn(seed), fn(fn(seed))...
+		var (
+			callback = &Arg{
+				Name: "callback",
+				TypeAssertion: NewTypeAssertion(TypeAssertionHandlers{
+					"callable": Callable,
+				}),
+			}
+			seed   = &Arg{Name: "seed"}
+			args   = Array{Nil}
+			caller VMCaller
+		)
+		if err = c.Args.Destructure(callback, seed); err != nil {
+			return
+		}
+		if caller, err = NewInvoker(c.VM, callback.Value).Caller(Args{args}, &c.NamedArgs); err != nil {
+			return
+		}
+		return IteratorObject(GeneratorIterator(seed.Value, args, caller)), nil
+	}
+
 	if err := c.Args.CheckLen(1); err != nil {
 		return nil, err
 	}
@@ -1540,6 +2785,7 @@ func BuiltinCollectFunc(c Call) (_ Object, err error) {
 		o   = c.Args.Get(0)
 		dst = Array{}
 		h   func(e *KeyValue) Object
+		it  Iterator
 	)
 
 	if oi, _ := o.(ObjectIterator); oi != nil {
@@ -1548,7 +2794,17 @@ func BuiltinCollectFunc(c Call) (_ Object, err error) {
 		}
 	}
 
-	err = IterateObject(c.VM, o, &c.NamedArgs, func(state *IteratorState) error {
+	if _, it, err = ToIterator(c.VM, o, &c.NamedArgs); err != nil {
+		return
+	} else if it == nil {
+		return nil, ErrNotIterable.NewError(o.Type().Name())
+	}
+
+	if l := IteratorLengthHint(it); l > 0 {
+		dst = make(Array, 0, min(l, maxPrealloc))
+	}
+
+	err = Iterate(c.VM, it, func(state *IteratorState) error {
 		switch state.CollectMode {
 		case IteratorStateCollectModeKeys:
 			h = func(e *KeyValue) Object {
@@ -1918,6 +3174,78 @@ func BuiltinStructFunc(c Call) (ret Object, err error) {
 	return t, nil
 }
 
+// BuiltinGetAttrFunc implements `__getattr__(obj, name)` which returns the
+// attribute of obj like `obj[name]`. Methods of `__getattr__` defined for
+// struct types, e.g. `func __getattr__(r Row, name)`, are called when an
+// attribute which is neither a field nor a getter is read.
+func BuiltinGetAttrFunc(c Call) (ret Object, err error) {
+	if err = c.Args.CheckLen(2); err != nil {
+		return
+	}
+	obj := c.Args.GetOnly(0)
+	if ig, _ := obj.(IndexGetter); ig != nil {
+		return ig.IndexGet(c.VM, c.Args.GetOnly(1))
+	}
+	return nil, ErrNotIndexable.NewError(obj.Type().Name())
+}
+
+// BuiltinSetAttrFunc implements `__setattr__(obj, name, value)` which sets
+// the attribute of obj like `obj[name] = value`. Methods of `__setattr__`
+// defined for struct types, e.g. `func __setattr__(r Row, name, value)`, are
+// called when an attribute which is neither a field nor a setter is written.
+func BuiltinSetAttrFunc(c Call) (ret Object, err error) {
+	if err = c.Args.CheckLen(3); err != nil {
+		return
+	}
+	obj := c.Args.GetOnly(0)
+	if is, _ := obj.(IndexSetter); is != nil {
+		return Nil, is.IndexSet(c.VM, c.Args.GetOnly(1), c.Args.GetOnly(2))
+	}
+	return nil, ErrNotIndexAssignable.NewError(obj.Type().Name())
+}
+
+// BuiltinCallMissingFunc implements `__call_missing__(obj, name, args,
+// namedArgs)` which throws NotCallableError. Methods of `__call_missing__`
+// defined for struct types, e.g. `func __call_missing__(c Client, name, args,
+// namedArgs)`, are called by `obj.name(args)` if the type has neither a
+// method nor an attribute with the name. args is an array and namedArgs is a
+// dict.
+func BuiltinCallMissingFunc(c Call) (ret Object, err error) {
+	if err = c.Args.CheckLen(4); err != nil {
+		return
+	}
+	return nil, ErrNotCallable.NewError("func " + strconv.Quote(c.Args.GetOnly(1).ToString()) +
+		" of type " + c.Args.GetOnly(0).Type().Name())
+}
+
+// BuiltinEncodeFunc implements `__encode__(obj)` which returns the
+// serializable form of obj: the fields of a struct instance with its type name
+// under TypeTagKey, other objects as is. Methods of `__encode__` defined for
+// struct types, e.g. `func __encode__(p Point)`, are called by the
+// serialization modules like json.
+func BuiltinEncodeFunc(c Call) (ret Object, err error) {
+	if err = c.Args.CheckLen(1); err != nil {
+		return
+	}
+	return encodeObject(c.Args.GetOnly(0)), nil
+}
+
+// BuiltinDecodeFunc implements `__decode__(type, value)` which returns the
+// instance of type decoded from value: a struct instance with the fields of
+// the value dict, otherwise the result of calling type with value. Methods of
+// `__decode__` defined for struct types, e.g. `func __decode__(t Point, v)`,
+// are called by the serialization modules like json.
+func BuiltinDecodeFunc(c Call) (ret Object, err error) {
+	if err = c.Args.CheckLen(2); err != nil {
+		return
+	}
+	t, ok := c.Args.GetOnly(0).(ObjectType)
+	if !ok {
+		return nil, NewArgumentTypeError("1st", "type", c.Args.GetOnly(0).Type().Name())
+	}
+	return decodeObject(c.VM, t, c.Args.GetOnly(1))
+}
+
 func BuiltinNewFunc(c Call) (ret Object, err error) {
 	if err = c.Args.CheckLen(1); err != nil {
 		return
@@ -0,0 +1,373 @@
+package gad
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// BuiltinKind is the kind of a builtin listed by Builtins.List.
+type BuiltinKind string
+
+// Builtin kinds
+const (
+	BuiltinKindFunction  BuiltinKind = "function"
+	BuiltinKindType      BuiltinKind = "type"
+	BuiltinKindError     BuiltinKind = "error"
+	BuiltinKindNamespace BuiltinKind = "namespace"
+	BuiltinKindValue     BuiltinKind = "value"
+)
+
+// BuiltinInfo describes a builtin of Builtins for the REPL and tools.
+type BuiltinInfo struct {
+	// Name is the name of the builtin, namespace members are named like
+	// "namespace.member".
+	Name string
+	// Type is the type of the builtin or of the namespace of the member.
+	Type   BuiltinType
+	Kind   BuiltinKind
+	Object Object
+	Doc    string
+}
+
+func newBuiltinInfo(name string, t BuiltinType, obj Object, doc string) BuiltinInfo {
+	info := BuiltinInfo{Name: name, Type: t, Object: obj, Doc: doc}
+	switch obj.(type) {
+	case *BuiltinNamespace:
+		info.Kind = BuiltinKindNamespace
+	case ObjectType:
+		info.Kind = BuiltinKindType
+	case *Error:
+		info.Kind = BuiltinKindError
+	default:
+		if Callable(obj) {
+			info.Kind = BuiltinKindFunction
+		} else {
+			info.Kind = BuiltinKindValue
+		}
+	}
+	return info
+}
+
+// Builtins is the registry of the builtins resolved by the compiler and used
+// by the VM. The same registry, or an unmodified overlay of it, must be given
+// to the compiler (NewSymbolTable) and to the VM (SetupOpts.Builtins) because
+// the compiled bytecode refers to the builtins by their types.
+//
+// Builtins returned by NewBuiltins shares the default builtins until it is
+// modified, modifications never change BuiltinObjects and BuiltinsMap.
+type Builtins struct {
+	Objects BuiltinObjectsMap
+	Map     map[string]BuiltinType
+	docs    map[string]string
+	last    BuiltinType
+	owned   bool
+}
+
+// NewBuiltins returns a registry of the default builtins.
+func NewBuiltins() *Builtins {
+	return &Builtins{Objects: BuiltinObjects, Map: BuiltinsMap}
+}
+
+// Overlay returns a copy of the registry which can be extended, e.g. per VM,
+// without changing s. Bytecode compiled with s can be run with the overlay.
+func (s *Builtins) Overlay() *Builtins {
+	o := *s
+	o.owned = false
+	o.own()
+	return &o
+}
+
+// own copies the shared maps before the first modification.
+func (s *Builtins) own() {
+	if s.owned {
+		return
+	}
+	objects := make(BuiltinObjectsMap, len(s.Objects)+1)
+	for t, o := range s.Objects {
+		objects[t] = o
+	}
+	m := make(map[string]BuiltinType, len(s.Map)+1)
+	for name, t := range s.Map {
+		m[name] = t
+	}
+	docs := make(map[string]string, len(s.docs))
+	for name, doc := range s.docs {
+		docs[name] = doc
+	}
+	s.Objects, s.Map, s.docs, s.owned = objects, m, docs, true
+}
+
+// next returns a type which is not used by the registry and by the types
+// allocated with NewBuiltinType so far.
+func (s *Builtins) next() BuiltinType {
+	if s.last == 0 {
+		lastBuiltinMux.Lock()
+		s.last = lastBuiltinType
+		lastBuiltinMux.Unlock()
+		for t := range s.Objects {
+			if t > s.last {
+				s.last = t
+			}
+		}
+	}
+	s.last++
+	return s.last
+}
+
+// splitBuiltinName splits the namespaced name like "mycorp.fetch" into its
+// namespace and member. Namespace is empty for the names without a dot.
+func splitBuiltinName(name string) (namespace, member string, err error) {
+	namespace, member, ok := strings.Cut(name, ".")
+	if !ok {
+		namespace, member = "", name
+	}
+	if member == "" || (ok && namespace == "") || strings.Contains(member, ".") {
+		err = ErrBuiltinConflict.NewError(fmt.Sprintf("invalid builtin name %q", name))
+	}
+	return
+}
+
+// Register adds the builtin object with the name. Namespaced names like
+// "mycorp.fetch" add the member to the namespace builtin "mycorp", which is
+// created if it does not exist, and scripts access it as `mycorp.fetch`.
+// Returned error wraps ErrBuiltinConflict if the name is invalid or already
+// registered, use Override to replace a builtin.
+func (s *Builtins) Register(name string, obj Object) error {
+	return s.RegisterDoc(name, obj, "")
+}
+
+// RegisterDoc is like Register and sets the documentation of the builtin which
+// is returned by Lookup and List.
+func (s *Builtins) RegisterDoc(name string, obj Object, doc string) error {
+	namespace, member, err := splitBuiltinName(name)
+	if err != nil {
+		return err
+	}
+	if namespace == "" {
+		if _, ok := s.Map[name]; ok {
+			return ErrBuiltinConflict.NewError(fmt.Sprintf("builtin %q is already registered", name))
+		}
+		s.own()
+		t := s.next()
+		s.Map[name] = t
+		s.Objects[t] = obj
+	} else {
+		var ns *BuiltinNamespace
+		if t, ok := s.Map[namespace]; ok {
+			if ns, ok = s.Objects[t].(*BuiltinNamespace); !ok {
+				return ErrBuiltinConflict.NewError(fmt.Sprintf(
+					"builtin %q is not a namespace to register %q", namespace, name))
+			}
+			if _, ok = ns.Members[member]; ok {
+				return ErrBuiltinConflict.NewError(fmt.Sprintf("builtin %q is already registered", name))
+			}
+		}
+		s.setMember(namespace, ns, member, obj)
+	}
+	if doc != "" {
+		s.docs[name] = doc
+	}
+	return nil
+}
+
+// setMember sets the member of a copy of the namespace ns which replaces ns
+// so that the namespaces shared with other registries are not modified. ns is
+// nil if the namespace does not exist.
+func (s *Builtins) setMember(namespace string, ns *BuiltinNamespace, member string, obj Object) {
+	s.own()
+	cp := &BuiltinNamespace{Name: namespace, Members: Dict{}}
+	if ns != nil {
+		for k, v := range ns.Members {
+			cp.Members[k] = v
+		}
+	}
+	cp.Members[member] = obj
+
+	t, ok := s.Map[namespace]
+	if !ok {
+		t = s.next()
+		s.Map[namespace] = t
+	}
+	s.Objects[t] = cp
+}
+
+// Override replaces the object of the registered builtin keeping its type, so
+// bytecode compiled with the registry remains valid. Returned error wraps
+// ErrBuiltinConflict if the builtin is not registered or it is a namespace.
+func (s *Builtins) Override(name string, obj Object) error {
+	namespace, member, err := splitBuiltinName(name)
+	if err != nil {
+		return err
+	}
+	notRegistered := ErrBuiltinConflict.NewError(fmt.Sprintf("builtin %q is not registered", name))
+	if namespace == "" {
+		t, ok := s.Map[name]
+		if !ok {
+			return notRegistered
+		}
+		if _, ok = s.Objects[t].(*BuiltinNamespace); ok {
+			return ErrBuiltinConflict.NewError(fmt.Sprintf("builtin %q is a namespace", name))
+		}
+		s.own()
+		s.Objects[t] = obj
+		return nil
+	}
+
+	t, ok := s.Map[namespace]
+	if !ok {
+		return notRegistered
+	}
+	ns, ok := s.Objects[t].(*BuiltinNamespace)
+	if !ok {
+		return notRegistered
+	}
+	if _, ok = ns.Members[member]; !ok {
+		return notRegistered
+	}
+	s.setMember(namespace, ns, member, obj)
+	return nil
+}
+
+// Lookup returns the info of the builtin or the namespace member.
+func (s *Builtins) Lookup(name string) (info BuiltinInfo, ok bool) {
+	namespace, member, err := splitBuiltinName(name)
+	if err != nil {
+		return
+	}
+	if namespace == "" {
+		var t BuiltinType
+		if t, ok = s.Map[name]; ok {
+			info = newBuiltinInfo(name, t, s.Objects[t], s.docs[name])
+		}
+		return
+	}
+	t, ok := s.Map[namespace]
+	if !ok {
+		return
+	}
+	ns, ok := s.Objects[t].(*BuiltinNamespace)
+	if !ok {
+		return
+	}
+	var obj Object
+	if obj, ok = ns.Members[member]; ok {
+		info = newBuiltinInfo(name, t, obj, s.docs[name])
+	}
+	return
+}
+
+// List returns the infos of the builtins and the namespace members sorted by
+// name. Private builtins whose names start with ':' are not listed.
+func (s *Builtins) List() []BuiltinInfo {
+	infos := make([]BuiltinInfo, 0, len(s.Map))
+	for name, t := range s.Map {
+		if strings.HasPrefix(name, ":") {
+			continue
+		}
+		obj := s.Objects[t]
+		infos = append(infos, newBuiltinInfo(name, t, obj, s.docs[name]))
+		if ns, ok := obj.(*BuiltinNamespace); ok {
+			for member, obj := range ns.Members {
+				name := name + "." + member
+				infos = append(infos, newBuiltinInfo(name, t, obj, s.docs[name]))
+			}
+		}
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].Name < infos[j].Name
+	})
+	return infos
+}
+
+func (s *Builtins) SetType(typ ObjectType) *Builtins {
+	return s.Set(typ.Name(), typ)
+}
+
+// Set registers the builtin or overrides it if it is already registered. It
+// panics if the name is invalid or conflicts with a namespace. Prefer
+// Register which reports the conflicts.
+func (s *Builtins) Set(name string, obj Object) *Builtins {
+	if _, ok := s.Lookup(name); ok {
+		if err := s.Override(name, obj); err != nil {
+			panic(err)
+		}
+	} else if err := s.Register(name, obj); err != nil {
+		panic(err)
+	}
+	return s
+}
+
+func (s *Builtins) Call(t BuiltinType, c Call) (Object, error) {
+	return DoCall(s.Objects[t].(CallerObject), c)
+}
+
+func (s *Builtins) Caller(t BuiltinType) CallerObject {
+	return s.Objects[t].(CallerObject)
+}
+
+func (s *Builtins) Invoker(t BuiltinType, c Call) func() (Object, error) {
+	caller := s.Objects[t].(CallerObject)
+	return func() (Object, error) {
+		return caller.Call(c)
+	}
+}
+
+func (s *Builtins) ArgsInvoker(t BuiltinType, c Call) func(arg ...Object) (Object, error) {
+	caller := s.Objects[t].(CallerObject)
+	c.Args = Args{nil}
+	return func(arg ...Object) (Object, error) {
+		c.Args[0] = arg
+		return Val(caller.Call(c))
+	}
+}
+
+func (s *Builtins) Get(t BuiltinType) Object {
+	return s.Objects[t]
+}
+
+func (s *Builtins) AppendMap(m map[string]Object) {
+	for name, o := range m {
+		s.Set(name, o)
+	}
+}
+
+// BuiltinNamespace is the builtin which groups the builtins registered with
+// namespaced names like "mycorp.fetch". Scripts cannot modify namespaces.
+type BuiltinNamespace struct {
+	Name    string
+	Members Dict
+}
+
+var (
+	_ IndexGetter = (*BuiltinNamespace)(nil)
+	_ KeysGetter  = (*BuiltinNamespace)(nil)
+)
+
+func (o *BuiltinNamespace) Type() ObjectType {
+	return TBuiltinNamespace
+}
+
+func (o *BuiltinNamespace) ToString() string {
+	return ReprQuote("builtinNamespace:" + o.Name)
+}
+
+// IsFalsy implements Object interface.
+func (o *BuiltinNamespace) IsFalsy() bool {
+	return false
+}
+
+// Equal implements Object interface.
+func (o *BuiltinNamespace) Equal(right Object) bool {
+	v, ok := right.(*BuiltinNamespace)
+	return ok && v == o
+}
+
+// IndexGet implements Object interface.
+func (o *BuiltinNamespace) IndexGet(vm *VM, index Object) (Object, error) {
+	return o.Members.IndexGet(vm, index)
+}
+
+func (o *BuiltinNamespace) Keys() Array {
+	return o.Members.Keys()
+}
@@ -0,0 +1,452 @@
+package gad
+
+import (
+	"sort"
+	"unicode/utf8"
+
+	"github.com/gad-lang/gad/token"
+)
+
+// BuiltinSortFunc implements `sort(object; less, key, cmp, reverse=false,
+// stable=false, natural=false)` which sorts object in ascending order. less
+// reports whether its first argument is before the second one and cmp returns
+// a negative, zero or positive int if its first argument is before, equal to
+// or after the second one. key returns the values which are compared instead
+// of the elements, it is called once for each element. natural compares the
+// digits of the strings by the values of the numbers, so "file2.txt" is
+// before "file10.txt". stable keeps the order of the equal elements.
+func BuiltinSortFunc(c Call) (ret Object, err error) {
+	if err = c.Args.CheckLen(1); err != nil {
+		return Nil, err
+	}
+
+	opts, err := parseSortOptions(&c.NamedArgs, true)
+	if err != nil {
+		return Nil, err
+	}
+
+	arg := c.Args.GetOnly(0)
+	if opts.key == nil && opts.cmp == nil && !opts.reverse && !opts.stable && !opts.natural {
+		return sortObject(c.VM, arg, opts.less)
+	}
+
+	switch obj := arg.(type) {
+	case Array:
+		return obj, opts.sort(c.VM, obj)
+	case *ArrayView:
+		obj.own()
+		return obj, opts.sort(c.VM, obj.arr)
+	case KeyValueArray:
+		arr := make(Array, len(obj))
+		for i, kv := range obj {
+			arr[i] = kv
+		}
+		if err = opts.sort(c.VM, arr); err != nil {
+			return
+		}
+		for i, kv := range arr {
+			obj[i] = kv.(*KeyValue)
+		}
+		return obj, nil
+	case Str:
+		arr := make(Array, 0, utf8.RuneCountInString(string(obj)))
+		for _, r := range obj {
+			arr = append(arr, Char(r))
+		}
+		if err = opts.sort(c.VM, arr); err != nil {
+			return
+		}
+		s := make([]rune, len(arr))
+		for i, v := range arr {
+			s[i] = rune(v.(Char))
+		}
+		return Str(s), nil
+	case Bytes:
+		arr := make(Array, len(obj))
+		for i, b := range obj {
+			arr[i] = Int(b)
+		}
+		if err = opts.sort(c.VM, arr); err != nil {
+			return
+		}
+		for i, v := range arr {
+			obj[i] = byte(v.(Int))
+		}
+		return obj, nil
+	case *NilType:
+		return Nil, nil
+	}
+	return Nil, NewArgumentTypeError(
+		"1st",
+		"array|keyValueArray|string|bytes",
+		arg.Type().Name(),
+	)
+}
+
+// BuiltinBisectFunc implements `bisect(array, x; less, key, cmp, reverse=false,
+// natural=false, right=false)` which returns the index of the first element of
+// the sorted array which is not before x, or after x if right is true. The
+// options are the options of sort which sorted the array, key is not called
+// with x.
+func BuiltinBisectFunc(c Call) (_ Object, err error) {
+	if err = c.Args.CheckLen(2); err != nil {
+		return
+	}
+	var (
+		right = &NamedArgVar{Name: "right", Value: False}
+		opts  sortOptions
+		arr   Array
+		i     int
+	)
+	if opts, err = parseSortOptions(&c.NamedArgs, false, right); err != nil {
+		return
+	}
+	if arr, err = sortedArrayArg(c.Args.GetOnly(0)); err != nil {
+		return
+	}
+	if i, err = opts.bisect(c.VM, arr, c.Args.GetOnly(1), !right.Value.IsFalsy()); err != nil {
+		return
+	}
+	return Int(i), nil
+}
+
+// BuiltinInsertSortedFunc implements `insertSorted(array, x; less, key, cmp,
+// reverse=false, natural=false)` which inserts x into the sorted array after
+// the elements equal to it and returns a new array, the array is not changed.
+func BuiltinInsertSortedFunc(c Call) (_ Object, err error) {
+	if err = c.Args.CheckLen(2); err != nil {
+		return
+	}
+	var (
+		opts sortOptions
+		arr  Array
+	)
+	if opts, err = parseSortOptions(&c.NamedArgs, false); err != nil {
+		return
+	}
+	if arr, err = sortedArrayArg(c.Args.GetOnly(0)); err != nil {
+		return
+	}
+	arr, _, err = opts.insert(c.VM, arr, c.Args.GetOnly(1))
+	return arr, err
+}
+
+func sortedArrayArg(o Object) (Array, error) {
+	switch t := o.(type) {
+	case Array:
+		return t, nil
+	case *ArrayView:
+		return t.arr, nil
+	}
+	return nil, NewArgumentTypeError("1st", "array|arrayView", o.Type().Name())
+}
+
+// sortOptions are the named arguments of sort.
+type sortOptions struct {
+	less    CallerObject
+	key     CallerObject
+	cmp     CallerObject
+	reverse bool
+	stable  bool
+	natural bool
+}
+
+// parseSortOptions returns the sort options of the named arguments less, key,
+// cmp, reverse, natural and stable if stable is true. extra are the other
+// named arguments of the caller.
+func parseSortOptions(na *NamedArgs, stable bool, extra ...*NamedArgVar) (opts sortOptions, err error) {
+	callers := func(name string, v *CallerObject) *NamedArgVar {
+		return &NamedArgVar{
+			Name: name,
+			TypeAssertion: NewTypeAssertion(TypeAssertionHandlers{
+				"CallerObject": func(o Object) (ok bool) {
+					*v, ok = o.(CallerObject)
+					return
+				},
+			}),
+		}
+	}
+	var (
+		reverse = &NamedArgVar{Name: "reverse", Value: False}
+		natural = &NamedArgVar{Name: "natural", Value: False}
+		stable_ = &NamedArgVar{Name: "stable", Value: False}
+		vars    = append([]*NamedArgVar{
+			callers("less", &opts.less),
+			callers("key", &opts.key),
+			callers("cmp", &opts.cmp),
+			reverse,
+			natural,
+		}, extra...)
+	)
+	if stable {
+		vars = append(vars, stable_)
+	}
+	if err = na.Get(vars...); err != nil {
+		return
+	}
+	if opts.less != nil && opts.cmp != nil {
+		err = ErrUnexpectedArgValue.NewError("less and cmp cannot be used together")
+		return
+	}
+	opts.reverse = !reverse.Value.IsFalsy()
+	opts.stable = !stable_.Value.IsFalsy()
+	opts.natural = !natural.Value.IsFalsy()
+	return
+}
+
+// keyFunc returns the function which returns the compared value of an element.
+func (o *sortOptions) keyFunc(vm *VM) (func(v Object) (Object, error), error) {
+	if o.key == nil {
+		return func(v Object) (Object, error) {
+			return v, nil
+		}, nil
+	}
+	args := Array{Nil}
+	caller, err := NewInvoker(vm, o.key).Caller(Args{args}, nil)
+	if err != nil {
+		return nil, err
+	}
+	return func(v Object) (Object, error) {
+		args[0] = v
+		return caller.Call()
+	}, nil
+}
+
+// lessFunc returns the function which reports whether the compared value a
+// is before b. The first error of its calls is stored in callErr and the
+// function returns false after it.
+func (o *sortOptions) lessFunc(vm *VM, callErr *error) (_ func(a, b Object) bool, err error) {
+	var (
+		args   = Array{Nil, Nil}
+		caller VMCaller
+	)
+	if fn := o.less; fn != nil || o.cmp != nil {
+		if fn == nil {
+			fn = o.cmp
+		}
+		if caller, err = NewInvoker(vm, fn).Caller(Args{args}, nil); err != nil {
+			return
+		}
+	}
+
+	return func(a, b Object) bool {
+		if *callErr != nil {
+			return false
+		}
+		if o.reverse {
+			a, b = b, a
+		}
+		if caller == nil {
+			return o.defaultLess(vm, a, b, callErr)
+		}
+		args[0], args[1] = a, b
+		ret, err := caller.Call()
+		if err != nil {
+			*callErr = err
+			return false
+		}
+		if o.cmp == nil {
+			return !ret.IsFalsy()
+		}
+		switch t := ret.(type) {
+		case Int:
+			return t < 0
+		case Uint:
+			return false
+		case Float:
+			return t < 0
+		}
+		*callErr = ErrType.NewError("cmp must return int|uint|float, found " + ret.Type().Name())
+		return false
+	}, nil
+}
+
+// sort sorts arr in place, the first error of the calls is returned.
+func (o *sortOptions) sort(vm *VM, arr Array) (err error) {
+	s := &objectSorter{arr: arr, keys: arr}
+	if o.key != nil {
+		var key func(Object) (Object, error)
+		if key, err = o.keyFunc(vm); err != nil {
+			return
+		}
+		s.keys, s.keyed = make(Array, len(arr)), true
+		for i, v := range arr {
+			if s.keys[i], err = key(v); err != nil {
+				return
+			}
+		}
+	}
+	var callErr error
+	if s.less, err = o.lessFunc(vm, &callErr); err != nil {
+		return
+	}
+	if o.stable {
+		sort.Stable(s)
+	} else {
+		sort.Sort(s)
+	}
+	return callErr
+}
+
+// bisect returns the index of the first element of the sorted arr whose
+// compared value is not before x, or after x if right is true.
+func (o *sortOptions) bisect(vm *VM, arr Array, x Object, right bool) (i int, err error) {
+	var (
+		key     func(Object) (Object, error)
+		less    func(a, b Object) bool
+		callErr error
+	)
+	if key, err = o.keyFunc(vm); err != nil {
+		return
+	}
+	if less, err = o.lessFunc(vm, &callErr); err != nil {
+		return
+	}
+	i = sort.Search(len(arr), func(i int) bool {
+		if callErr != nil {
+			return true
+		}
+		k, err := key(arr[i])
+		if err != nil {
+			callErr = err
+			return true
+		}
+		if right {
+			return less(x, k)
+		}
+		return !less(k, x)
+	})
+	return i, callErr
+}
+
+// insert inserts x into the sorted arr after the elements equal to it and
+// returns a new array and the index of x.
+func (o *sortOptions) insert(vm *VM, arr Array, x Object) (_ Array, i int, err error) {
+	var (
+		key func(Object) (Object, error)
+		k   Object
+	)
+	if key, err = o.keyFunc(vm); err != nil {
+		return
+	}
+	if k, err = key(x); err != nil {
+		return
+	}
+	if i, err = o.bisect(vm, arr, k, true); err != nil {
+		return
+	}
+	// the backing array of arr may be shared with the caller
+	ret := make(Array, len(arr)+1)
+	copy(ret, arr[:i])
+	ret[i] = x
+	copy(ret[i+1:], arr[i:])
+	return ret, i, nil
+}
+
+// defaultLess compares a and b by the less operator, or the strings naturally
+// if natural is set.
+func (o *sortOptions) defaultLess(vm *VM, a, b Object, err *error) bool {
+	if o.natural {
+		sa, okA := naturalString(a)
+		sb, okB := naturalString(b)
+		if okA && okB {
+			return NaturalLess(sa, sb)
+		}
+	}
+	if bo, _ := a.(BinaryOperatorHandler); bo != nil {
+		v, e := bo.BinaryOp(vm, token.Less, b)
+		if e != nil {
+			*err = e
+			return false
+		}
+		return v != nil && !v.IsFalsy()
+	}
+	return false
+}
+
+func naturalString(o Object) (string, bool) {
+	switch t := o.(type) {
+	case Str:
+		return string(t), true
+	case RawStr:
+		return string(t), true
+	}
+	return "", false
+}
+
+// objectSorter sorts arr by keys which are arr or the values of the key
+// function of the same length.
+type objectSorter struct {
+	arr  Array
+	keys Array
+	// keyed reports whether keys are the values of the key function
+	keyed bool
+	less  func(a, b Object) bool
+}
+
+func (s *objectSorter) Len() int {
+	return len(s.arr)
+}
+
+func (s *objectSorter) Less(i, j int) bool {
+	return s.less(s.keys[i], s.keys[j])
+}
+
+func (s *objectSorter) Swap(i, j int) {
+	s.arr[i], s.arr[j] = s.arr[j], s.arr[i]
+	if s.keyed {
+		s.keys[i], s.keys[j] = s.keys[j], s.keys[i]
+	}
+}
+
+// NaturalLess reports whether a is before b comparing their digits by the
+// values of the numbers, so "file2.txt" is before "file10.txt". The numbers
+// of the same value are ordered by the count of their leading zeros.
+func NaturalLess(a, b string) bool {
+	var zeros int
+	for a != "" && b != "" {
+		if isDigit(a[0]) && isDigit(b[0]) {
+			na, nb := digitsLen(a), digitsLen(b)
+			da, db := trimZeros(a[:na]), trimZeros(b[:nb])
+			if len(da) != len(db) {
+				return len(da) < len(db)
+			}
+			if da != db {
+				return da < db
+			}
+			if zeros == 0 {
+				zeros = (na - len(da)) - (nb - len(db))
+			}
+			a, b = a[na:], b[nb:]
+			continue
+		}
+		ra, sa := utf8.DecodeRuneInString(a)
+		rb, sb := utf8.DecodeRuneInString(b)
+		if ra != rb {
+			return ra < rb
+		}
+		a, b = a[sa:], b[sb:]
+	}
+	if len(a) != len(b) {
+		return len(a) < len(b)
+	}
+	return zeros < 0
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func digitsLen(s string) (n int) {
+	for n < len(s) && isDigit(s[n]) {
+		n++
+	}
+	return
+}
+
+func trimZeros(s string) string {
+	for len(s) > 1 && s[0] == '0' {
+		s = s[1:]
+	}
+	return s
+}
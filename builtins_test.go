@@ -1,8 +1,11 @@
 package gad_test
 
 import (
+	"errors"
 	"testing"
 
+	"github.com/stretchr/testify/require"
+
 	. "github.com/gad-lang/gad"
 )
 
@@ -31,3 +34,57 @@ func TestBuiltinTypes(t *testing.T) {
 		t.Fatal("builtin 'global' is not *BuiltinFunction type")
 	}
 }
+
+func TestBuiltinsRegistry(t *testing.T) {
+	fn := func(ret Object) *Function {
+		return &Function{Name: "fetch", Value: func(Call) (Object, error) {
+			return ret, nil
+		}}
+	}
+
+	b := NewBuiltins()
+	require.NoError(t, b.RegisterDoc("mycorp.fetch", fn(Int(1)), "fetches a URL"))
+	require.NoError(t, b.Register("mycorp.limit", Int(10)))
+	require.NoError(t, b.Register("LIMIT", Int(10)))
+
+	for _, name := range []string{"mycorp.fetch", "LIMIT", "len", "len.x", "a..b", ".a", "a.", ""} {
+		err := b.Register(name, Nil)
+		require.Truef(t, errors.Is(err, ErrBuiltinConflict), "%s: %v", name, err)
+	}
+	require.True(t, errors.Is(b.Override("mycorp.other", Nil), ErrBuiltinConflict))
+	require.True(t, errors.Is(b.Override("mycorp", Nil), ErrBuiltinConflict))
+	require.NotContains(t, BuiltinsMap, "mycorp")
+
+	info, ok := b.Lookup("mycorp.fetch")
+	require.True(t, ok)
+	require.Equal(t, BuiltinKindFunction, info.Kind)
+	require.Equal(t, "fetches a URL", info.Doc)
+	require.Equal(t, b.Map["mycorp"], info.Type)
+
+	kinds := map[string]BuiltinKind{}
+	for _, info := range b.List() {
+		kinds[info.Name] = info.Kind
+	}
+	require.Equal(t, BuiltinKindNamespace, kinds["mycorp"])
+	require.Equal(t, BuiltinKindValue, kinds["mycorp.limit"])
+	require.Equal(t, BuiltinKindType, kinds["int"])
+	require.Equal(t, BuiltinKindError, kinds["TypeError"])
+	require.NotContains(t, kinds, ":makeArray")
+
+	c, err := Compile([]byte(`return [mycorp.fetch(), mycorp.limit, LIMIT, len([1])]`),
+		CompileOptions{CompilerOptions: CompilerOptions{SymbolTable: NewSymbolTable(b)}})
+	require.NoError(t, err)
+
+	run := func(builtins *Builtins) Object {
+		ret, err := NewVM(c).Setup(SetupOpts{Builtins: builtins}).Run(nil)
+		require.NoError(t, err)
+		return ret
+	}
+
+	overlay := b.Overlay()
+	require.NoError(t, overlay.Override("mycorp.fetch", fn(Int(2))))
+	require.NoError(t, overlay.Override("len", fn(Int(3))))
+	require.Equal(t, Array{Int(2), Int(10), Int(10), Int(3)}, run(overlay))
+	require.Equal(t, Array{Int(1), Int(10), Int(10), Int(1)}, run(b))
+	require.Equal(t, TBuiltinFunction, BuiltinObjects[BuiltinLen].Type())
+}
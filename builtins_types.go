@@ -35,6 +35,23 @@ var (
 	TMapIterator            = &Type{Parent: TIterator, TypeName: "MapIterator"}
 	TFilterIterator         = &Type{Parent: TIterator, TypeName: "FilterIterator"}
 	TZipIterator            = &Type{Parent: TIterator, TypeName: "ZipIterator"}
+	TZipLongestIterator     = &Type{Parent: TIterator, TypeName: "ZipLongestIterator"}
+	TProductIterator        = &Type{Parent: TIterator, TypeName: "ProductIterator"}
+	TPermutationsIterator   = &Type{Parent: TIterator, TypeName: "PermutationsIterator"}
+	TCombinationsIterator   = &Type{Parent: TIterator, TypeName: "CombinationsIterator"}
+	TCounterIterator        = &Type{Parent: TIterator, TypeName: "CounterIterator"}
+	TTickerIterator         = &Type{Parent: TIterator, TypeName: "TickerIterator"}
+	TCycleIterator          = &Type{Parent: TIterator, TypeName: "CycleIterator"}
+	TRepeatIterator         = &Type{Parent: TIterator, TypeName: "RepeatIterator"}
+	TGeneratorIterator      = &Type{Parent: TIterator, TypeName: "GeneratorIterator"}
+	TTakeIterator           = &Type{Parent: TIterator, TypeName: "TakeIterator"}
+	TProgressIterator       = &Type{Parent: TIterator, TypeName: "ProgressIterator"}
+	TTakeWhileIterator      = &Type{Parent: TIterator, TypeName: "TakeWhileIterator"}
+	TCachedIterator         = &Type{Parent: TIterator, TypeName: "CachedIterator"}
+	TTeeIterator            = &Type{Parent: TIterator, TypeName: "TeeIterator"}
+	TScanIterator           = &Type{Parent: TIterator, TypeName: "ScanIterator"}
+	TChanIterator           = &Type{Parent: TIterator, TypeName: "ChanIterator"}
+	TReaderScanIterator     = &Type{Parent: TIterator, TypeName: "ReaderScanIterator"}
 	TPipedInvokeIterator    = &Type{Parent: TIterator, TypeName: "PipedInvokeIterator"}
 )
 
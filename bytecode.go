@@ -21,6 +21,8 @@ type Bytecode struct {
 	Main       *CompiledFunction
 	Constants  []Object
 	NumModules int
+	// ModuleNames holds the names of the imported modules by module index.
+	ModuleNames []string
 }
 
 // Fprint writes constants and instructions to given Writer in a human readable form.
@@ -80,7 +82,10 @@ type CompiledFunction struct {
 
 	AllowMethods bool
 	// number of local variabls including parameters NumLocals>=NumParams
-	NumLocals    int
+	NumLocals int
+	// LocalNames holds the names of the local variables by index for
+	// debuggers.
+	LocalNames   []string
 	Instructions []byte
 	Free         []*ObjectPtr
 	// SourceMap holds the index of instruction and token's position.
@@ -90,6 +95,10 @@ type CompiledFunction struct {
 
 	NamedParams NamedParams
 
+	// ReturnType is the declared types of returned value, which are checked
+	// on return.
+	ReturnType ParamType
+
 	// NamedParamsMap is a map of NamedParams with index
 	// this value allow to perform named args validation.
 	NamedParamsMap map[string]int
@@ -125,6 +134,9 @@ func (o *CompiledFunction) ToString() string {
 		params = append(params, o.NamedParams.String())
 	}
 	s = append(s, "("+strings.Join(params, ", ")+")")
+	if len(o.ReturnType) > 0 {
+		s = append(s, " -> "+o.ReturnType.String())
+	}
 	return ReprQuote("compiledFunction" + strings.Join(s, ""))
 }
 
@@ -141,6 +153,9 @@ func (o *CompiledFunction) Repr(*VM) (_ string, err error) {
 		params = append(params, o.NamedParams.String())
 	}
 	s = append(s, "("+strings.Join(params, ", ")+")")
+	if len(o.ReturnType) > 0 {
+		s = append(s, " -> "+o.ReturnType.String())
+	}
 	return ReprQuote("compiledFunction" + strings.Join(s, "")), nil
 }
 
@@ -175,11 +190,31 @@ func (o *CompiledFunction) Copy() Object {
 
 	return &CompiledFunction{
 		NumLocals:    o.NumLocals,
+		LocalNames:   o.LocalNames,
 		Instructions: insts,
 		Free:         free,
 		SourceMap:    sourceMap,
 		Params:       o.Params,
 		NamedParams:  o.NamedParams,
+		ReturnType:   o.ReturnType,
+	}
+}
+
+// Closure returns a new function sharing the instructions of the function
+// with given free variables.
+func (o *CompiledFunction) Closure(free []*ObjectPtr) *CompiledFunction {
+	return &CompiledFunction{
+		Name:         o.Name,
+		Instructions: o.Instructions,
+		NumLocals:    o.NumLocals,
+		LocalNames:   o.LocalNames,
+		SourceMap:    o.SourceMap,
+		Free:         free,
+		Params:       o.Params,
+		NamedParams:  o.NamedParams,
+		ReturnType:   o.ReturnType,
+		sourceFile:   o.sourceFile,
+		module:       o.module,
 	}
 }
 
@@ -241,6 +276,9 @@ func (o *CompiledFunction) Fprint(w io.Writer) {
 	_, _ = fmt.Fprintf(w, "Locals: %d\n", o.NumLocals)
 	_, _ = fmt.Fprintf(w, "Params: %s\n", o.Params.String())
 	_, _ = fmt.Fprintf(w, "NamedParams: %s\n", o.NamedParams.String())
+	if len(o.ReturnType) > 0 {
+		_, _ = fmt.Fprintf(w, "ReturnType: %s\n", o.ReturnType.String())
+	}
 	_, _ = fmt.Fprintf(w, "Instructions:\n")
 
 	i := 0
@@ -0,0 +1,599 @@
+// Package checker implements an advisory static type checker for Gad source
+// files. It walks the AST using declared variable, parameter and return types
+// and the types of builtin constructors to report type mismatches before the
+// program runs. Checking has no effect on compilation or runtime behavior.
+package checker
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/gad-lang/gad/parser"
+	"github.com/gad-lang/gad/parser/node"
+	"github.com/gad-lang/gad/parser/source"
+	"github.com/gad-lang/gad/token"
+)
+
+// Diagnostic represents a type mismatch found by the checker.
+type Diagnostic struct {
+	Pos     parser.SourceFilePos
+	Message string
+}
+
+func (d *Diagnostic) String() string {
+	return d.Pos.String() + ": " + d.Message
+}
+
+// builtinTypes is the set of builtin type names which can be inferred
+// statically. Declared types out of this set, such as user defined types,
+// disable the check since their relations are known at runtime only.
+var builtinTypes = map[string]bool{
+	"nil":     true,
+	"flag":    true,
+	"bool":    true,
+	"int":     true,
+	"uint":    true,
+	"float":   true,
+	"decimal": true,
+	"char":    true,
+	"rawstr":  true,
+	"str":     true,
+	"bytes":   true,
+	"buffer":  true,
+	"array":   true,
+	"dict":    true,
+	"error":   true,
+}
+
+// builtinReturnTypes holds return types of builtin functions whose result
+// type does not depend on arguments.
+var builtinReturnTypes = map[string]string{
+	"len":        "int",
+	"cap":        "int",
+	"repr":       "str",
+	"sprintf":    "str",
+	"typeName":   "str",
+	"chars":      "array",
+	"collect":    "array",
+	"contains":   "bool",
+	"isError":    "bool",
+	"isInt":      "bool",
+	"isUint":     "bool",
+	"isFloat":    "bool",
+	"isChar":     "bool",
+	"isBool":     "bool",
+	"isStr":      "bool",
+	"isRawStr":   "bool",
+	"isBytes":    "bool",
+	"isDict":     "bool",
+	"isSyncDict": "bool",
+	"isArray":    "bool",
+	"isNil":      "bool",
+	"isFunction": "bool",
+	"isCallable": "bool",
+	"isIterable": "bool",
+	"isIterator": "bool",
+}
+
+type variable struct {
+	types []string
+	funcs []*function
+}
+
+type function struct {
+	name       string
+	params     [][]string
+	variadic   bool
+	returnType []string
+}
+
+type scope struct {
+	parent *scope
+	vars   map[string]*variable
+}
+
+func (s *scope) lookup(name string) *variable {
+	for ; s != nil; s = s.parent {
+		if v, ok := s.vars[name]; ok {
+			return v
+		}
+	}
+	return nil
+}
+
+func (s *scope) define(name string) *variable {
+	v := &variable{}
+	s.vars[name] = v
+	return v
+}
+
+// Checker walks a parsed file and collects diagnostics.
+type Checker struct {
+	file        *parser.SourceFile
+	scope       *scope
+	funcs       []*function
+	diagnostics []*Diagnostic
+}
+
+// Check checks the parsed file and returns diagnostics sorted by position.
+func Check(file *parser.File) []*Diagnostic {
+	c := &Checker{
+		file:  file.InputFile,
+		scope: &scope{vars: map[string]*variable{}},
+	}
+	c.stmts(file.Stmts)
+	sort.SliceStable(c.diagnostics, func(i, j int) bool {
+		return c.diagnostics[i].Pos.Offset < c.diagnostics[j].Pos.Offset
+	})
+	return c.diagnostics
+}
+
+// CheckSource parses the source and checks it. Parse errors are returned as
+// error.
+func CheckSource(name string, src []byte) ([]*Diagnostic, error) {
+	fileSet := parser.NewFileSet()
+	srcFile := fileSet.AddFile(name, -1, len(src))
+	p := parser.NewParser(srcFile, src, nil)
+	file, err := p.ParseFile()
+	if err != nil {
+		return nil, err
+	}
+	return Check(file), nil
+}
+
+func (c *Checker) report(pos source.Pos, format string, args ...any) {
+	c.diagnostics = append(c.diagnostics, &Diagnostic{
+		Pos:     c.file.Position(pos),
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+func (c *Checker) openScope() {
+	c.scope = &scope{parent: c.scope, vars: map[string]*variable{}}
+}
+
+func (c *Checker) closeScope() {
+	c.scope = c.scope.parent
+}
+
+func (c *Checker) stmts(stmts []node.Stmt) {
+	for _, stmt := range stmts {
+		c.stmt(stmt)
+	}
+}
+
+func (c *Checker) block(b *node.BlockStmt) {
+	if b == nil {
+		return
+	}
+	c.openScope()
+	c.stmts(b.Stmts)
+	c.closeScope()
+}
+
+func (c *Checker) stmt(stmt node.Stmt) {
+	switch s := stmt.(type) {
+	case *node.ExprStmt:
+		if f, _ := s.Expr.(*node.FuncLit); f != nil && f.Type.Ident != nil {
+			v := c.scope.lookup(f.Type.Ident.Name)
+			if v == nil {
+				v = c.scope.define(f.Type.Ident.Name)
+			}
+			v.funcs = append(v.funcs, c.function(f.Type))
+		}
+		c.expr(s.Expr)
+	case *node.BlockStmt:
+		c.block(s)
+	case *node.DeclStmt:
+		c.decl(s.Decl.(*node.GenDecl))
+	case *node.AssignStmt:
+		c.assign(s)
+	case *node.IncDecStmt:
+		c.expr(s.Expr)
+	case *node.ReturnStmt:
+		c.ret(&s.Return)
+	case *node.IfStmt:
+		c.openScope()
+		if s.Init != nil {
+			c.stmt(s.Init)
+		}
+		c.expr(s.Cond)
+		c.block(s.Body)
+		if s.Else != nil {
+			c.stmt(s.Else)
+		}
+		c.closeScope()
+	case *node.ForStmt:
+		c.openScope()
+		if s.Init != nil {
+			c.stmt(s.Init)
+		}
+		c.expr(s.Cond)
+		if s.Post != nil {
+			c.stmt(s.Post)
+		}
+		c.block(s.Body)
+		c.closeScope()
+	case *node.ForInStmt:
+		c.expr(s.Iterable)
+		c.openScope()
+		if s.Key != nil {
+			c.scope.define(s.Key.Name)
+		}
+		if s.Value != nil {
+			c.scope.define(s.Value.Name)
+		}
+		c.block(s.Body)
+		c.closeScope()
+		c.block(s.Else)
+	case *node.TryStmt:
+		c.block(s.Body)
+		if s.Catch != nil {
+			c.openScope()
+			if s.Catch.Ident != nil {
+				c.scope.define(s.Catch.Ident.Name)
+			}
+			c.block(s.Catch.Body)
+			c.closeScope()
+		}
+		if s.Finally != nil {
+			c.block(s.Finally.Body)
+		}
+	case *node.ThrowStmt:
+		c.expr(s.Expr)
+	case *node.ExprToTextStmt:
+		c.expr(s.Expr)
+	}
+}
+
+func (c *Checker) decl(d *node.GenDecl) {
+	for _, spec := range d.Specs {
+		switch s := spec.(type) {
+		case *node.ValueSpec:
+			for i, ident := range s.Idents {
+				var value node.Expr
+				if i < len(s.Values) {
+					value = s.Values[i]
+				}
+				c.expr(value)
+				v := c.scope.define(ident.Name)
+				v.types = identNames(s.TypedIdent(i).Type)
+				if value != nil {
+					c.checkAssignable(value, v.types, "declaration of '%s'", ident.Name)
+				}
+			}
+		case *node.ParamSpec:
+			c.scope.define(s.Ident.Ident.Name).types = identNames(s.Ident.Type)
+		case *node.NamedParamSpec:
+			c.expr(s.Value)
+			v := c.scope.define(s.Ident.Ident.Name)
+			v.types = identNames(s.Ident.Type)
+			if s.Value != nil {
+				c.checkAssignable(s.Value, v.types, "declaration of '%s'", s.Ident.Ident.Name)
+			}
+		}
+	}
+}
+
+func (c *Checker) assign(s *node.AssignStmt) {
+	for _, expr := range s.RHS {
+		c.expr(expr)
+	}
+
+	if s.Token == token.Define {
+		for i, expr := range s.LHS {
+			if ident, _ := expr.(*node.Ident); ident != nil {
+				v := c.scope.define(ident.Name)
+				if len(s.LHS) == len(s.RHS) {
+					switch f := s.RHS[i].(type) {
+					case *node.FuncLit:
+						v.funcs = append(v.funcs, c.function(f.Type))
+					case *node.ClosureLit:
+						v.funcs = append(v.funcs, c.function(f.Type))
+					}
+				}
+			}
+		}
+		return
+	}
+
+	for i, expr := range s.LHS {
+		ident, _ := expr.(*node.Ident)
+		if ident == nil {
+			c.expr(expr)
+			continue
+		}
+		if v := c.scope.lookup(ident.Name); v != nil {
+			// the tracked functions are replaced only if the variable is
+			// assigned in its scope, so it is not assigned conditionally
+			var fn *function
+			if s.Token == token.Assign && len(s.LHS) == len(s.RHS) && c.scope.vars[ident.Name] == v {
+				switch f := s.RHS[i].(type) {
+				case *node.FuncLit:
+					fn = c.function(f.Type)
+				case *node.ClosureLit:
+					fn = c.function(f.Type)
+				}
+			}
+			v.funcs = nil
+			if fn != nil {
+				v.funcs = []*function{fn}
+			}
+		}
+	}
+
+	if s.Token != token.Assign || len(s.LHS) != 1 || len(s.RHS) != 1 {
+		return
+	}
+
+	if ident, _ := s.LHS[0].(*node.Ident); ident != nil {
+		if v := c.scope.lookup(ident.Name); v != nil {
+			c.checkAssignable(s.RHS[0], v.types, "assignment to '%s'", ident.Name)
+		}
+	}
+}
+
+func (c *Checker) ret(r *node.Return) {
+	var result node.Expr = &node.NilLit{TokenPos: r.ReturnPos}
+	if r.Result != nil {
+		result = r.Result
+		c.expr(result)
+	}
+	if l := len(c.funcs); l > 0 {
+		f := c.funcs[l-1]
+		if f.name == "" {
+			c.checkAssignable(result, f.returnType, "return of function")
+		} else {
+			c.checkAssignable(result, f.returnType, "return of function '%s'", f.name)
+		}
+	}
+}
+
+func (c *Checker) function(typ *node.FuncType) *function {
+	f := &function{
+		returnType: identNames(typ.ReturnType),
+		variadic:   typ.Params.Args.Var != nil,
+	}
+	if typ.Ident != nil {
+		f.name = typ.Ident.Name
+	}
+	for _, param := range typ.Params.Args.Values {
+		f.params = append(f.params, identNames(param.Type))
+	}
+	return f
+}
+
+func (c *Checker) funcBody(typ *node.FuncType, body func()) {
+	c.funcs = append(c.funcs, c.function(typ))
+	c.openScope()
+
+	for _, param := range typ.Params.Args.Values {
+		c.scope.define(param.Ident.Name).types = identNames(param.Type)
+	}
+	if param := typ.Params.Args.Var; param != nil {
+		c.scope.define(param.Ident.Name).types = []string{"array"}
+	}
+	for i, param := range typ.Params.NamedArgs.Names {
+		c.expr(typ.Params.NamedArgs.Values[i])
+		c.scope.define(param.Ident.Name).types = identNames(param.Type)
+	}
+	if param := typ.Params.NamedArgs.Var; param != nil {
+		c.scope.define(param.Ident.Name)
+	}
+
+	body()
+
+	c.closeScope()
+	c.funcs = c.funcs[:len(c.funcs)-1]
+}
+
+func (c *Checker) expr(expr node.Expr) {
+	switch e := expr.(type) {
+	case nil:
+	case *node.FuncLit:
+		c.funcBody(e.Type, func() {
+			c.stmts(e.Body.Stmts)
+		})
+	case *node.ClosureLit:
+		c.funcBody(e.Type, func() {
+			if b, ok := e.Body.(*node.BlockExpr); ok {
+				c.stmts(b.Stmts)
+			} else {
+				c.ret(&node.Return{ReturnPos: e.Body.Pos(), Result: e.Body})
+			}
+		})
+	case *node.CallExpr:
+		c.expr(e.Func)
+		for _, arg := range e.Args.Values {
+			c.expr(arg)
+		}
+		for _, arg := range e.NamedArgs.Values {
+			c.expr(arg)
+		}
+		c.call(e)
+	case *node.BinaryExpr:
+		c.expr(e.LHS)
+		c.expr(e.RHS)
+	case *node.UnaryExpr:
+		c.expr(e.Expr)
+	case *node.ParenExpr:
+		c.expr(e.Expr)
+	case *node.MultiParenExpr:
+		for _, expr := range e.Exprs {
+			c.expr(expr)
+		}
+	case *node.CondExpr:
+		c.expr(e.Cond)
+		c.expr(e.True)
+		c.expr(e.False)
+	case *node.IndexExpr:
+		c.expr(e.Expr)
+		c.expr(e.Index)
+	case *node.SliceExpr:
+		c.expr(e.Expr)
+		c.expr(e.Low)
+		c.expr(e.High)
+	case *node.SelectorExpr:
+		c.expr(e.Expr)
+	case *node.NullishSelectorExpr:
+		c.expr(e.Expr)
+	case *node.ArrayLit:
+		for _, elem := range e.Elements {
+			c.expr(elem)
+		}
+	case *node.DictLit:
+		for _, elem := range e.Elements {
+			c.expr(elem.Value)
+		}
+	case *node.KeyValueLit:
+		c.expr(e.Key)
+		c.expr(e.Value)
+	case *node.BlockExpr:
+		c.block(e.BlockStmt)
+	case *node.StmtsExpr:
+		c.stmts(e.Stmts)
+	case *node.ReturnExpr:
+		c.ret(&e.Return)
+	case *node.ThrowExpr:
+		c.expr(e.Expr)
+	}
+}
+
+func (c *Checker) call(e *node.CallExpr) {
+	ident, _ := e.Func.(*node.Ident)
+	if ident == nil {
+		return
+	}
+
+	v := c.scope.lookup(ident.Name)
+	if v == nil || len(v.funcs) == 0 {
+		return
+	}
+
+	var (
+		args   = e.Args.Values
+		spread = e.Args.Var != nil
+		msgs   []string
+	)
+
+funcs:
+	for _, f := range v.funcs {
+		if !spread && (len(args) < len(f.params) || len(args) > len(f.params) && !f.variadic) {
+			msgs = append(msgs, fmt.Sprintf("wrong number of arguments in call to '%s': want=%d got=%d",
+				ident.Name, len(f.params), len(args)))
+			continue
+		}
+		for i, param := range f.params {
+			if i >= len(args) {
+				break
+			}
+			if typ := c.typeOf(args[i]); !assignable(typ, param) {
+				msgs = append(msgs, fmt.Sprintf("cannot use %s value as %s in argument #%d of '%s'",
+					typ, strings.Join(param, "|"), i+1, ident.Name))
+				continue funcs
+			}
+		}
+		// at least one of the methods accepts arguments
+		return
+	}
+
+	// none of the methods accepts arguments
+	c.report(e.Pos(), "%s", strings.Join(msgs, "; "))
+}
+
+func (c *Checker) checkAssignable(expr node.Expr, types []string, format string, args ...any) {
+	if typ := c.typeOf(expr); !assignable(typ, types) {
+		c.report(expr.Pos(), "cannot use %s value as %s in %s",
+			typ, strings.Join(types, "|"), fmt.Sprintf(format, args...))
+	}
+}
+
+// typeOf returns the statically known type name of expression or an empty
+// string if the type is unknown.
+func (c *Checker) typeOf(expr node.Expr) string {
+	switch e := expr.(type) {
+	case *node.IntLit:
+		return "int"
+	case *node.UintLit:
+		return "uint"
+	case *node.FloatLit:
+		return "float"
+	case *node.DecimalLit:
+		return "decimal"
+	case *node.CharLit:
+		return "char"
+	case *node.StringLit:
+		return "str"
+	case *node.RawStringLit:
+		return "rawstr"
+	case *node.BoolLit:
+		return "bool"
+	case *node.FlagLit:
+		return "flag"
+	case *node.NilLit:
+		return "nil"
+	case *node.ArrayLit:
+		return "array"
+	case *node.DictLit:
+		return "dict"
+	case *node.ParenExpr:
+		return c.typeOf(e.Expr)
+	case *node.UnaryExpr:
+		if e.Token == token.Not {
+			return "bool"
+		}
+	case *node.BinaryExpr:
+		switch e.Token {
+		case token.Equal, token.NotEqual, token.Less, token.LessEq, token.Greater, token.GreaterEq:
+			return "bool"
+		}
+	case *node.Ident:
+		if v := c.scope.lookup(e.Name); v != nil {
+			if len(v.types) == 1 && builtinTypes[v.types[0]] {
+				return v.types[0]
+			}
+		}
+	case *node.CallExpr:
+		ident, _ := e.Func.(*node.Ident)
+		if ident == nil {
+			return ""
+		}
+		if v := c.scope.lookup(ident.Name); v != nil {
+			// all methods must return the same type
+			var typ string
+			for i, f := range v.funcs {
+				if len(f.returnType) != 1 || !builtinTypes[f.returnType[0]] ||
+					i > 0 && typ != f.returnType[0] {
+					return ""
+				}
+				typ = f.returnType[0]
+			}
+			return typ
+		}
+		if builtinTypes[ident.Name] && ident.Name != "nil" {
+			return ident.Name
+		}
+		return builtinReturnTypes[ident.Name]
+	}
+	return ""
+}
+
+func assignable(typ string, types []string) bool {
+	if typ == "" || len(types) == 0 {
+		return true
+	}
+	for _, t := range types {
+		if t == typ || !builtinTypes[t] {
+			return true
+		}
+	}
+	return false
+}
+
+func identNames(idents []*node.Ident) (names []string) {
+	for _, ident := range idents {
+		names = append(names, ident.Name)
+	}
+	return
+}
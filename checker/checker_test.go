@@ -0,0 +1,70 @@
+package checker_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/gad-lang/gad/checker"
+)
+
+func expectDiagnostics(t *testing.T, src string, expected ...string) {
+	t.Helper()
+	diagnostics, err := checker.CheckSource("test", []byte(src))
+	require.NoError(t, err)
+	var got []string
+	for _, d := range diagnostics {
+		got = append(got, d.String())
+	}
+	require.Equal(t, expected, got, src)
+}
+
+func TestCheck(t *testing.T) {
+	expectDiagnostics(t, `var a int = 1; a = 2; var b str|int = "x"; b = 3`)
+	expectDiagnostics(t, `var a int = "x"`,
+		`test:1:13: cannot use str value as int in declaration of 'a'`)
+	expectDiagnostics(t, "var a int\na = 1.5",
+		`test:2:5: cannot use float value as int in assignment to 'a'`)
+	expectDiagnostics(t, `var a int; a = str(1)`,
+		`test:1:16: cannot use str value as int in assignment to 'a'`)
+	expectDiagnostics(t, `var a int; a = len("x")`)
+	expectDiagnostics(t, `var a MyType = 1`)
+	expectDiagnostics(t, `var a int = x`)
+
+	expectDiagnostics(t, `func f(a int, b str) {}; f(1, "x"); f(1, 2)`,
+		`test:1:37: cannot use int value as str in argument #2 of 'f'`)
+	expectDiagnostics(t, `f := func(a int) {}; f(1, 2)`,
+		`test:1:22: wrong number of arguments in call to 'f': want=1 got=2`)
+	expectDiagnostics(t, `f := func(a int, *b) {}; f(1, 2, 3); f(*[1])`)
+	expectDiagnostics(t, `func f(a int) {}; func f(a str) {}; f(1); f("x")`)
+	expectDiagnostics(t, `func f(a int) {}; func f(a str) {}; f(1.1)`,
+		`test:1:37: cannot use float value as int in argument #1 of 'f'; `+
+			`cannot use float value as str in argument #1 of 'f'`)
+
+	expectDiagnostics(t, `func f() -> str { return "x" }; var a str = f()`)
+	expectDiagnostics(t, `func f() -> int { return "x" }; var a str = f()`,
+		`test:1:26: cannot use str value as int in return of function 'f'`,
+		`test:1:45: cannot use int value as str in declaration of 'a'`)
+	expectDiagnostics(t, `f := func() -> int { return }`,
+		`test:1:22: cannot use nil value as int in return of function`)
+	expectDiagnostics(t, `f := func(x int) -> int => x; g := func(x str) -> int => x`,
+		`test:1:58: cannot use str value as int in return of function`)
+
+	expectDiagnostics(t, `f := func(a int) {}; f = func(a str) {}; f("x")`)
+	expectDiagnostics(t, `f := func(a int) {}; f = func(a str) {}; f(1, 2)`,
+		`test:1:42: wrong number of arguments in call to 'f': want=1 got=2`)
+	expectDiagnostics(t, `f := func(a int) {}; f = g; f("x", 2)`)
+	expectDiagnostics(t, `f := func(a int) {}; if x { f = func(a str) {} }; f("x"); f(1)`)
+	expectDiagnostics(t, `func f(a int) {}; f = func(a, b) {}; f(1, 2)`)
+
+	expectDiagnostics(t, `var a int; if true { var a str; a = "x" }; a = 1`)
+	expectDiagnostics(t, `var a int; for i := 0; i < 1; i++ { a = i > 0 }`,
+		`test:1:41: cannot use bool value as int in assignment to 'a'`)
+}
+
+func TestCheckSourceError(t *testing.T) {
+	_, err := checker.CheckSource("test", []byte(`var a int =`))
+	require.Error(t, err)
+	require.True(t, strings.Contains(err.Error(), "test:1"), err.Error())
+}
@@ -0,0 +1,149 @@
+//go:build !js
+// +build !js
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/gad-lang/gad"
+	"github.com/gad-lang/gad/checker"
+	"github.com/gad-lang/gad/importers"
+	"github.com/gad-lang/gad/parser"
+)
+
+// errCheckFailed is returned by `gad check` if an error is found, the
+// diagnostics are already printed.
+var errCheckFailed = errors.New("check failed")
+
+// diagnostic is a record of `gad check` output.
+type diagnostic struct {
+	File     string `json:"file"`
+	Line     int    `json:"line"`
+	Col      int    `json:"col"`
+	Severity string `json:"severity"`
+	Code     string `json:"code"`
+	Message  string `json:"message"`
+}
+
+func (d *diagnostic) String() string {
+	return fmt.Sprintf("%s:%d:%d: %s: %s [%s]", d.File, d.Line, d.Col, d.Severity, d.Message, d.Code)
+}
+
+func newDiagnostic(pos parser.SourceFilePos, severity, code, msg string) *diagnostic {
+	return &diagnostic{
+		File:     pos.Filename,
+		Line:     pos.Line,
+		Col:      pos.Column,
+		Severity: severity,
+		Code:     code,
+		Message:  msg,
+	}
+}
+
+// runCheck runs `gad check` command which reports the parse and compile errors
+// and the type mismatch warnings of the script files without running them.
+func runCheck(args []string, out io.Writer) error {
+	var (
+		flagset = flag.NewFlagSet("check", flag.ContinueOnError)
+		asJSON  bool
+		strict  bool
+	)
+	flagset.SetOutput(out)
+	flagset.BoolVar(&asJSON, "json", false,
+		`Print diagnostics as JSON records, one per line: {"file", "line", "col", "severity", "code", "message"}`)
+	flagset.BoolVar(&strict, "strict", false, `Fail on warnings`)
+	flagset.Usage = func() {
+		_, _ = fmt.Fprint(flagset.Output(),
+			"Usage: gad check [flags] SCRIPT_FILE...\n\n",
+			"Parses and compiles the script files without running them and reports the errors\n",
+			"and the type mismatch warnings. Exit status is 1 if an error is found.\n\n",
+			"Flags:\n",
+		)
+		flagset.PrintDefaults()
+	}
+	if err := flagset.Parse(args); err != nil {
+		return err
+	}
+	if flagset.NArg() == 0 {
+		flagset.Usage()
+		return fmt.Errorf("check: SCRIPT_FILE is required")
+	}
+
+	var (
+		enc    = json.NewEncoder(out)
+		failed bool
+	)
+	for _, file := range flagset.Args() {
+		diagnostics, err := checkFile(file)
+		if err != nil {
+			return err
+		}
+		for _, d := range diagnostics {
+			if d.Severity == "error" || strict {
+				failed = true
+			}
+			if asJSON {
+				err = enc.Encode(d)
+			} else {
+				_, err = fmt.Fprintln(out, d)
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+	if failed {
+		return errCheckFailed
+	}
+	return nil
+}
+
+// checkFile returns the diagnostics of the script file. Error is returned if
+// the file cannot be read.
+func checkFile(file string) ([]*diagnostic, error) {
+	script, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	importers.Shebang2Slashes(script)
+
+	warnings, err := checker.CheckSource(file, script)
+	if err != nil {
+		var (
+			errList     parser.ErrorList
+			diagnostics []*diagnostic
+		)
+		if !errors.As(err, &errList) {
+			return nil, err
+		}
+		for _, e := range errList {
+			diagnostics = append(diagnostics, newDiagnostic(e.Pos, "error", "parse", e.Msg))
+		}
+		return diagnostics, nil
+	}
+
+	var diagnostics []*diagnostic
+	opts := gad.CompileOptions{CompilerOptions: gad.DefaultCompilerOptions}
+	opts.SymbolTable = defaultSymbolTable()
+	opts.ModuleMap = DefaultModuleMap(filepath.Dir(file), &sourcePath)
+	opts.Module = &gad.ModuleInfo{Name: file, File: "file:" + file}
+	if _, err = gad.Compile(script, opts); err != nil {
+		code, msg, pos, _ := sourceError(err)
+		if !pos.IsValid() {
+			code, msg, pos = "compile", err.Error(), parser.SourceFilePos{Filename: file}
+		}
+		diagnostics = append(diagnostics, newDiagnostic(pos, "error", code, msg))
+	}
+
+	for _, w := range warnings {
+		diagnostics = append(diagnostics, newDiagnostic(w.Pos, "warning", "type-mismatch", w.Message))
+	}
+	return diagnostics, nil
+}
@@ -0,0 +1,287 @@
+//go:build !js
+// +build !js
+
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gad-lang/gad"
+	"github.com/gad-lang/gad/importers"
+	"github.com/gad-lang/gad/parser"
+	"github.com/gad-lang/gad/stdlib/term"
+	"github.com/peterh/liner"
+)
+
+const debugPrompt = "(debug) "
+
+// errDebugQuit stops the debugged script when quit command is entered.
+var errDebugQuit = errors.New("quit")
+
+type debugMode int
+
+const (
+	debugContinue debugMode = iota
+	debugStep
+	debugNext
+)
+
+type breakpoint struct {
+	file string
+	line int
+}
+
+// debugger implements gad.Debugger and reads its commands with prompt.
+type debugger struct {
+	out     io.Writer
+	prompt  func(string) (string, error)
+	main    string
+	sources map[string][]string
+	breaks  map[breakpoint]bool
+	mode    debugMode
+	depth   int
+	lastCmd string
+}
+
+// runDebug runs `gad debug` command which runs a script file under an
+// interactive debugger.
+func runDebug(args []string, in io.Reader, out io.Writer) error {
+	flagset := flag.NewFlagSet("debug", flag.ContinueOnError)
+	flagset.SetOutput(out)
+	flagset.Usage = func() {
+		_, _ = fmt.Fprint(flagset.Output(),
+			"Usage: gad debug SCRIPT_FILE [ARGS...]\n\n",
+			"Runs the script file under the debugger. It stops before the first line,\n",
+			"enter help to list the commands.\n",
+		)
+		flagset.PrintDefaults()
+	}
+	if err := flagset.Parse(args); err != nil {
+		return err
+	}
+	if flagset.NArg() < 1 {
+		flagset.Usage()
+		return fmt.Errorf("debug: SCRIPT_FILE is required")
+	}
+
+	file := flagset.Arg(0)
+	script, err := os.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	importers.Shebang2Slashes(script)
+
+	s := newScript(context.Background(), file, filepath.Dir(file), script, out)
+	s.args = flagset.Args()[1:]
+	opts := s.compileOptions()
+	opts.Module = &gad.ModuleInfo{Name: file, File: "file:" + file}
+	bc, err := gad.Compile(script, opts)
+	if err != nil {
+		return err
+	}
+
+	d := &debugger{
+		out:     out,
+		main:    file,
+		sources: map[string][]string{file: strings.Split(string(script), "\n")},
+		breaks:  map[breakpoint]bool{},
+		mode:    debugStep,
+	}
+
+	if f, ok := in.(*os.File); ok && term.IsTerminal(f) {
+		line := liner.NewLiner()
+		defer line.Close()
+		d.prompt = func(p string) (string, error) {
+			s, err := line.Prompt(p)
+			if s = strings.TrimSpace(s); s != "" {
+				line.AppendHistory(s)
+			}
+			return s, err
+		}
+	} else {
+		scanner := bufio.NewScanner(in)
+		d.prompt = func(p string) (string, error) {
+			_, _ = fmt.Fprint(out, p)
+			if !scanner.Scan() {
+				if err := scanner.Err(); err != nil {
+					return "", err
+				}
+				return "", io.EOF
+			}
+			_, _ = fmt.Fprintln(out, scanner.Text())
+			return scanner.Text(), nil
+		}
+	}
+
+	scriptArgs, namedArgs := s.parseArgs()
+	ret, err := gad.NewVM(bc).SetRecover(true).Setup(gad.SetupOpts{Debugger: d}).RunOpts(&gad.RunOpts{
+		Globals:   scriptGlobals,
+		Args:      gad.Args{scriptArgs},
+		NamedArgs: gad.NewNamedArgs(namedArgs.ToKeyValueArray()),
+		StdOut:    out,
+	})
+	switch {
+	case errors.Is(err, errDebugQuit):
+		return nil
+	case err != nil:
+		return err
+	}
+	_, _ = fmt.Fprintf(out, "exited: %s\n", ret.ToString())
+	return nil
+}
+
+// Line implements gad.Debugger.
+func (d *debugger) Line(vm *gad.VM, pos parser.SourceFilePos) error {
+	switch {
+	case d.breaks[breakpoint{file: pos.Filename, line: pos.Line}]:
+	case d.mode == debugStep:
+	case d.mode == debugNext && vm.CallDepth() <= d.depth:
+	default:
+		return nil
+	}
+
+	d.printLine(pos)
+
+	for {
+		line, err := d.prompt(debugPrompt)
+		if err != nil {
+			if err == io.EOF {
+				return errDebugQuit
+			}
+			return err
+		}
+		if line = strings.TrimSpace(line); line == "" {
+			line = d.lastCmd
+		}
+		d.lastCmd = line
+
+		cmd, arg, _ := strings.Cut(line, " ")
+		arg = strings.TrimSpace(arg)
+		switch cmd {
+		case "":
+		case "b", "break":
+			if bp, ok := d.parseBreakpoint(arg); ok {
+				d.breaks[bp] = true
+				_, _ = fmt.Fprintf(d.out, "breakpoint set at %s:%d\n", bp.file, bp.line)
+			}
+		case "clear":
+			if bp, ok := d.parseBreakpoint(arg); ok {
+				delete(d.breaks, bp)
+			}
+		case "breaks":
+			d.printBreakpoints()
+		case "s", "step":
+			d.mode = debugStep
+			return nil
+		case "n", "next":
+			d.mode, d.depth = debugNext, vm.CallDepth()
+			return nil
+		case "c", "continue":
+			d.mode = debugContinue
+			return nil
+		case "p", "print":
+			ret, err := vm.DebugEval(0, arg)
+			if err != nil {
+				_, _ = fmt.Fprintf(d.out, "!   %v\n", err)
+			} else {
+				_, _ = fmt.Fprintf(d.out, "⇦   %s\n", resultString(ret))
+			}
+		case "l", "locals":
+			d.printLocals(vm)
+		case "bt":
+			for i, f := range vm.DebugFrames() {
+				name := f.Fn.Name
+				if name == "" {
+					name = "<main>"
+				}
+				_, _ = fmt.Fprintf(d.out, "#%d  %s at %s:%d\n", i, name, f.Pos.Filename, f.Pos.Line)
+			}
+		case "q", "quit":
+			return errDebugQuit
+		case "h", "help":
+			d.printHelp()
+		default:
+			_, _ = fmt.Fprintf(d.out, "unknown command %q, enter help to list the commands\n", cmd)
+		}
+	}
+}
+
+// parseBreakpoint parses [FILE:]LINE argument, FILE defaults to the script
+// file.
+func (d *debugger) parseBreakpoint(arg string) (bp breakpoint, ok bool) {
+	bp.file = d.main
+	if i := strings.LastIndexByte(arg, ':'); i >= 0 {
+		bp.file, arg = arg[:i], arg[i+1:]
+	}
+	var err error
+	if bp.line, err = strconv.Atoi(arg); err != nil || bp.line < 1 {
+		_, _ = fmt.Fprintf(d.out, "invalid breakpoint %q, want [FILE:]LINE\n", arg)
+		return bp, false
+	}
+	return bp, true
+}
+
+func (d *debugger) printLine(pos parser.SourceFilePos) {
+	lines, ok := d.sources[pos.Filename]
+	if !ok {
+		if src, _, err := importers.ShebangReadFile(pos.Filename); err == nil {
+			lines = strings.Split(string(src), "\n")
+		}
+		d.sources[pos.Filename] = lines
+	}
+	if pos.Line <= len(lines) {
+		_, _ = fmt.Fprintf(d.out, "%s:%d\n  %d | %s\n", pos.Filename, pos.Line, pos.Line,
+			strings.TrimRight(lines[pos.Line-1], "\r"))
+		return
+	}
+	_, _ = fmt.Fprintf(d.out, "%s:%d\n", pos.Filename, pos.Line)
+}
+
+func (d *debugger) printLocals(vm *gad.VM) {
+	locals := vm.DebugFrames()[0].Locals()
+	for _, k := range locals.SortedKeys() {
+		_, _ = fmt.Fprintf(d.out, "%s = %s\n", k, resultString(locals[k.ToString()]))
+	}
+}
+
+func (d *debugger) printBreakpoints() {
+	bps := make([]breakpoint, 0, len(d.breaks))
+	for bp := range d.breaks {
+		bps = append(bps, bp)
+	}
+	sort.Slice(bps, func(i, j int) bool {
+		if bps[i].file != bps[j].file {
+			return bps[i].file < bps[j].file
+		}
+		return bps[i].line < bps[j].line
+	})
+	for _, bp := range bps {
+		_, _ = fmt.Fprintf(d.out, "%s:%d\n", bp.file, bp.line)
+	}
+}
+
+func (d *debugger) printHelp() {
+	_, _ = fmt.Fprint(d.out,
+		"break, b [FILE:]LINE  set a breakpoint, FILE defaults to the script file\n",
+		"clear [FILE:]LINE     delete a breakpoint\n",
+		"breaks                list the breakpoints\n",
+		"step, s               run to the next line, stepping into calls\n",
+		"next, n               run to the next line of the current function\n",
+		"continue, c           run to the next breakpoint\n",
+		"print, p EXPR         evaluate the expression in the current frame\n",
+		"locals, l             print the local variables of the current frame\n",
+		"bt                    print the call stack\n",
+		"quit, q               stop the script\n",
+		"An empty line repeats the last command.\n",
+	)
+}
@@ -0,0 +1,84 @@
+//go:build !js
+// +build !js
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/gad-lang/gad"
+	"github.com/gad-lang/gad/importers"
+)
+
+// runDisasm runs `gad disasm` command which prints the bytecode listing of a
+// script file.
+func runDisasm(args []string, out io.Writer) error {
+	var (
+		flagset  = flag.NewFlagSet("disasm", flag.ContinueOnError)
+		noSource bool
+		passList string
+	)
+	flagset.SetOutput(out)
+	flagset.BoolVar(&noSource, "no-source", false, `Do not interleave source lines`)
+	flagset.StringVar(&passList, "passes", strings.Join(gad.OptimizerPassNames, ","),
+		`Comma separated optimizer passes to run`)
+	flagset.Usage = func() {
+		_, _ = fmt.Fprint(flagset.Output(),
+			"Usage: gad disasm [flags] SCRIPT_FILE\n\n",
+			"Compiles the script file and prints its bytecode listing.\n\n",
+			"Flags:\n",
+		)
+		flagset.PrintDefaults()
+	}
+	if err := flagset.Parse(args); err != nil {
+		return err
+	}
+	if flagset.NArg() != 1 {
+		flagset.Usage()
+		return fmt.Errorf("disasm: SCRIPT_FILE is required")
+	}
+
+	passes, err := gad.ParseOptimizerPasses(passList)
+	if err != nil {
+		return err
+	}
+
+	file := flagset.Arg(0)
+	script, err := os.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	importers.Shebang2Slashes(script)
+
+	opts := gad.CompileOptions{CompilerOptions: gad.DefaultCompilerOptions}
+	opts.OptimizeConst = !noOptimizer
+	opts.OptimizeExpr = !noOptimizer
+	if noOptimizer {
+		passes = []string{}
+	}
+	opts.OptimizerPasses = passes
+	opts.SymbolTable = defaultSymbolTable()
+	opts.ModuleMap = DefaultModuleMap(filepath.Dir(file), &sourcePath)
+	opts.Module = &gad.ModuleInfo{Name: file, File: "file:" + file}
+	bc, err := gad.Compile(script, opts)
+	if err != nil {
+		return err
+	}
+
+	var dopts gad.DisassembleOptions
+	if !noSource {
+		dopts.ReadSource = func(name string) ([]byte, error) {
+			if name == file {
+				return script, nil
+			}
+			src, _, err := importers.ShebangReadFile(name)
+			return src, err
+		}
+	}
+	return gad.Disassemble(bc, out, dopts)
+}
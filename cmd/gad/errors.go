@@ -0,0 +1,158 @@
+//go:build !js
+// +build !js
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/gad-lang/gad"
+	"github.com/gad-lang/gad/importers"
+	"github.com/gad-lang/gad/parser"
+	"github.com/gad-lang/gad/stdlib/term"
+)
+
+// errPrinter prints the errors of main to stderr.
+var errPrinter = &errorPrinter{sources: map[string][]byte{}}
+
+// errorPrinter prints the parse, compile and runtime errors of the scripts
+// with the source line of the error position, a caret pointing the column and
+// the stack trace.
+type errorPrinter struct {
+	// color enables ANSI colors.
+	color bool
+	// sources holds the sources of the scripts which cannot be read from file
+	// system by name like stdin.
+	sources map[string][]byte
+}
+
+// print prints err to w. Errors without source position are printed with
+// their stack traces if any.
+func (p *errorPrinter) print(w io.Writer, err error) {
+	code, msg, pos, trace := sourceError(err)
+	switch code {
+	case "parse":
+		msg = "Parse Error: " + msg
+	case "compile":
+		msg = "Compile Error: " + msg
+	case "optimizer":
+		msg = "Optimizer Error: " + msg
+	}
+
+	if !pos.IsValid() {
+		_, _ = fmt.Fprintf(w, "%+v\n", err)
+		return
+	}
+
+	var (
+		num = strconv.Itoa(pos.Line)
+		pad = strings.Repeat(" ", len(num))
+	)
+	_, _ = fmt.Fprintln(w, p.paint(term.Bold+term.Red, msg))
+	_, _ = fmt.Fprintf(w, "%s%s %s\n", pad, p.paint(term.Blue, "-->"), pos)
+
+	if line, ok := p.sourceLine(pos); ok {
+		_, _ = fmt.Fprintf(w, "%s %s\n", pad, p.paint(term.Blue, "|"))
+		_, _ = fmt.Fprintf(w, "%s %s %s\n", p.paint(term.Blue, num), p.paint(term.Blue, "|"), line)
+		if pos.Column > 0 {
+			_, _ = fmt.Fprintf(w, "%s %s %s%s\n", pad, p.paint(term.Blue, "|"),
+				caretIndent(line, pos.Column-1), p.paint(term.Bold+term.Red, "^"))
+		}
+	}
+
+	for i, f := range trace {
+		at := "   "
+		if i == 0 {
+			at = "at "
+		}
+		_, _ = fmt.Fprintf(w, "\t%s%s\n", at, p.paint(term.Cyan, f.String()))
+	}
+}
+
+// sourceError returns the kind of the error as code, its message without
+// position and the position it is raised at. The stack trace of runtime errors
+// is also returned. Position is invalid if err has no source position.
+func sourceError(err error) (code, msg string, pos parser.SourceFilePos, trace gad.StackTrace) {
+	var (
+		errList  parser.ErrorList
+		parseErr *parser.Error
+		compErr  *gad.CompilerError
+		optErr   *gad.OptimizerError
+		rtErr    *gad.RuntimeError
+	)
+
+	switch {
+	case errors.As(err, &errList) && len(errList) > 0:
+		code, msg, pos = "parse", errList[0].Msg, errList[0].Pos
+		if len(errList) > 1 {
+			msg += fmt.Sprintf(" (and %d more errors)", len(errList)-1)
+		}
+	case errors.As(err, &parseErr):
+		code, msg, pos = "parse", parseErr.Msg, parseErr.Pos
+	case errors.As(err, &compErr) && compErr.FileSet != nil && compErr.Node != nil:
+		code, msg, pos = "compile", compErr.Err.Error(), compErr.FileSet.Position(compErr.Node.Pos())
+	case errors.As(err, &optErr):
+		code, msg, pos = "optimizer", optErr.Err.Error(), optErr.FilePos
+	case errors.As(err, &rtErr):
+		code, msg, trace = "runtime", rtErr.Error(), rtErr.StackTrace()
+		if len(trace) > 0 {
+			// the innermost frame is the last one
+			pos = trace[len(trace)-1]
+		}
+	}
+	return
+}
+
+// sourceLine returns the source line of the position without line break.
+func (p *errorPrinter) sourceLine(pos parser.SourceFilePos) (string, bool) {
+	src, ok := p.sources[pos.Filename]
+	if !ok {
+		var err error
+		if src, _, err = importers.ShebangReadFile(pos.Filename); err != nil {
+			return "", false
+		}
+		p.sources[pos.Filename] = src
+	}
+
+	lines := strings.Split(string(src), "\n")
+	if pos.Line > len(lines) {
+		return "", false
+	}
+	return strings.TrimRight(lines[pos.Line-1], "\r"), true
+}
+
+func (p *errorPrinter) paint(color, s string) string {
+	if !p.color {
+		return s
+	}
+	return color + s + term.Reset
+}
+
+// caretIndent returns the blanks to align a caret under the byte offset of
+// the line, tabs are kept to align with the tabs of the line.
+func caretIndent(line string, offset int) string {
+	if offset > len(line) {
+		offset = len(line)
+	}
+	var b strings.Builder
+	for _, c := range line[:offset] {
+		if c == '\t' {
+			b.WriteByte('\t')
+		} else {
+			b.WriteByte(' ')
+		}
+	}
+	return b.String()
+}
+
+// useColor reports whether the errors are colored. Colors are disabled by
+// -no-color flag, NO_COLOR environment variable or if stderr is not a
+// terminal.
+func useColor(noColor bool) bool {
+	return !noColor && os.Getenv("NO_COLOR") == "" && term.IsTerminal(os.Stderr)
+}
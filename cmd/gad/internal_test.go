@@ -6,10 +6,18 @@ package main
 import (
 	"bytes"
 	"context"
+	"errors"
 	"flag"
 	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"strings"
+	"sync"
+	"syscall"
 	"testing"
+	"time"
 
 	"github.com/gad-lang/gad"
 	"github.com/gad-lang/gad/repr"
@@ -69,7 +77,7 @@ func TestREPL(t *testing.T) {
 	t.Run("globals", func(t *testing.T) {
 		require.NoError(t, r.execute(".globals"))
 		testHasPrefix(t, string(cw.consume()), `{Gosched: `+repr.Quote("function:Gosched")+`, SOURCE_PATH: `+
-			repr.Quote("reflectSlice:github.com/gad-lang/gad/importers.PathList"+repr.Quote("&[]"))+`}`)
+			repr.Quote("reflectSlice:github.com/gad-lang/gad/importers.PathList"+repr.Quote("&[]"))+`, stderr: writer of `)
 	})
 	t.Run("globals plus", func(t *testing.T) {
 		require.NoError(t, r.execute(".globals+"))
@@ -179,6 +187,74 @@ func TestREPL(t *testing.T) {
 		require.NoError(t, r.execute(".memory_stats"))
 		testHasPrefix(t, string(cw.consume()), "ToInterface Memory Stats")
 	})
+	t.Run("time", func(t *testing.T) {
+		r := newREPL(ctx, cw)
+		require.NoError(t, r.execute("a := [1, 2, 3]"))
+		cw.consume()
+		require.NoError(t, r.execute(".time -n 10 a[0] + a[1]"))
+		require.Regexp(t, `^10 runs\t\S+\t\S+/op\n$`, string(cw.consume()))
+		require.NoError(t, r.execute(".time x := 1"))
+		require.Regexp(t, `^1000 runs\t`, string(cw.consume()))
+		require.NoError(t, r.execute(".time -n 0 a"))
+		testHasPrefix(t, string(cw.consume()), `!   invalid number of runs "0"`)
+		require.NoError(t, r.execute(".time"))
+		testHasPrefix(t, string(cw.consume()), "!   code is required")
+		require.NoError(t, r.execute("a"))
+		testHasPrefix(t, string(cw.consume()), "\n⇦   [1, 2, 3]\n")
+	})
+	t.Run("mem", func(t *testing.T) {
+		r := newREPL(ctx, cw)
+		require.NoError(t, r.execute(".mem -n 100 [1, 2, 3]"))
+		require.Regexp(t, `^100 runs\t.+\t\d+ B/op\t\d+ allocs/op\n$`, string(cw.consume()))
+		require.NoError(t, r.execute(".mem undefined_var"))
+		testHasPrefix(t, string(cw.consume()), "!   Compile Error: unresolved reference")
+	})
+	t.Run("complete", func(t *testing.T) {
+		r := newREPL(ctx, cw)
+		require.NoError(t, r.execute(`Point := struct("Point", fields={x: 0, y: 0}, methods={len: (p) => 2})`))
+		require.NoError(t, r.execute(`d := {abc: 1, abd: {x: 1}, "not ident": 2}; p := Point(x=1)`))
+		cw.consume()
+		require.Equal(t, []string{"d.abc", "d.abd"}, r.complete("d.ab"))
+		require.Equal(t, []string{"x := d.abd.x"}, r.complete("x := d.abd."))
+		require.Equal(t, []string{"p.__methods__", "p.len", "p.x", "p.y"}, r.complete("p."))
+		require.NoError(t, r.eval.DefineGlobal("g", gad.Dict{"name": gad.Str("gad")}))
+		require.Equal(t, []string{"g.name"}, r.complete("g."))
+		require.Empty(t, r.complete("unknown."))
+		require.Empty(t, r.complete("d.abc.x"))
+		require.Equal(t, []string{".time"}, r.complete(".tim"))
+	})
+	t.Run("multiline", func(t *testing.T) {
+		r := newREPL(ctx, cw)
+		require.NoError(t, r.execute("sum := func(*a) {"))
+		require.Equal(t, indentUnit, r.indentation())
+		require.NoError(t, r.execute("total := 0"))
+		require.NoError(t, r.execute("      for v in a {"))
+		require.Equal(t, indentUnit+indentUnit, r.indentation())
+		require.NoError(t, r.execute("total += v"))
+		require.NoError(t, r.execute("}"))
+		require.NoError(t, r.execute("    return total"))
+		require.Empty(t, cw.consume())
+		require.NoError(t, r.execute("}"))
+		require.Equal(t, "", r.indentation())
+		testHasPrefix(t, string(cw.consume()), "\n⇦   nil\n")
+		require.NoError(t, r.execute("sum(1, 2, 3)"))
+		testHasPrefix(t, string(cw.consume()), "\n⇦   6\n")
+
+		require.NoError(t, r.execute("s := `a {"))
+		require.NoError(t, r.execute("  b`"))
+		testHasPrefix(t, string(cw.consume()), "\n⇦   nil\n")
+		require.NoError(t, r.execute("s"))
+		testHasPrefix(t, string(cw.consume()), "\n⇦   a {\n  b\n")
+
+		require.NoError(t, r.execute("f(("))
+		require.NoError(t, r.execute(""))
+		require.Empty(t, cw.consume())
+		require.NoError(t, r.execute(""))
+		testHasPrefix(t, string(cw.consume()), "\n!   Parse Error")
+		require.NoError(t, r.execute("1 + \\"))
+		require.NoError(t, r.execute("2"))
+		testHasPrefix(t, string(cw.consume()), "\n⇦   3\n")
+	})
 	t.Run("reset", func(t *testing.T) {
 		r := newREPL(ctx, cw)
 		require.NoError(t, r.execute("test := 1"))
@@ -194,7 +270,7 @@ func TestREPL(t *testing.T) {
 		cw.consume()
 		require.NoError(t, r.execute("str(int)"))
 		require.Equal(t, "⇦   \""+repr.Quote("builtinType int")+" with 1 methods:\\n  "+
-			"1. "+repr.Quote("compiledFunction #9(p Point)")+"\"",
+			"1. "+repr.Quote("compiledFunction #12(p Point)")+"\"",
 			strings.TrimSpace(string(cw.consume())))
 		require.NoError(t, r.execute("int(Point(2,8))"))
 		require.Equal(t, "⇦   16", strings.TrimSpace(string(cw.consume())))
@@ -240,7 +316,38 @@ func TestFlags(t *testing.T) {
 		})
 	}
 
-	fs := flag.NewFlagSet("script file", flag.ExitOnError)
+	fs := flag.NewFlagSet("optimizer passes", flag.ContinueOnError)
+	_, _, _, err := parseFlags(fs, []string{"-trace", "compiler,optimizer:dce", "-passes", "constfold,dce"})
+	require.NoError(t, err)
+	require.True(t, traceOptimizer)
+	require.True(t, traceCompiler)
+	require.Equal(t, []string{gad.PassDCE}, tracePasses)
+	require.Equal(t, []string{gad.PassConstFold, gad.PassDCE}, passes)
+
+	resetGlobals()
+
+	fs = flag.NewFlagSet("default optimizer passes", flag.ContinueOnError)
+	_, _, _, err = parseFlags(fs, nil)
+	require.NoError(t, err)
+	require.Equal(t, gad.OptimizerPassNames, passes)
+
+	resetGlobals()
+
+	fs = flag.NewFlagSet("no optimizer", flag.ContinueOnError)
+	_, _, _, err = parseFlags(fs, []string{"-no-optimizer"})
+	require.NoError(t, err)
+	require.NotNil(t, passes)
+	require.Empty(t, passes)
+
+	resetGlobals()
+
+	fs = flag.NewFlagSet("unknown optimizer pass", flag.ContinueOnError)
+	_, _, _, err = parseFlags(fs, []string{"-trace", "optimizer:foo"})
+	require.Error(t, err)
+
+	resetGlobals()
+
+	fs = flag.NewFlagSet("script file", flag.ExitOnError)
 	fp, to, _, err := parseFlags(fs, []string{"testdata/fibtc.gad"})
 	require.NoError(t, err)
 	require.Empty(t, to)
@@ -259,6 +366,15 @@ func TestFlags(t *testing.T) {
 	fs = flag.NewFlagSet("file does not exist", flag.ExitOnError)
 	_, _, _, err = parseFlags(fs, []string{"testdata/doesnotexist"})
 	require.Error(t, err)
+
+	resetGlobals()
+
+	fs = flag.NewFlagSet("expr", flag.ExitOnError)
+	fp, _, params, err := parseFlags(fs, []string{"-e", "1 + 2", "a", "--b=c"})
+	require.NoError(t, err)
+	require.Empty(t, fp)
+	require.Equal(t, "1 + 2", evalExpr)
+	require.Equal(t, []string{"a", "--b=c"}, params)
 }
 
 func resetGlobals() {
@@ -267,6 +383,10 @@ func resetGlobals() {
 	traceParser = false
 	traceOptimizer = false
 	traceCompiler = false
+	tracePasses = nil
+	passes = nil
+	evalExpr = ""
+	noColor = false
 }
 
 func TestExecuteScript(t *testing.T) {
@@ -297,6 +417,26 @@ func TestExecuteScript(t *testing.T) {
 	}
 }
 
+func TestEvalExpr(t *testing.T) {
+	ctx := context.Background()
+	var buf bytes.Buffer
+
+	s := newScript(ctx, "(expr)", ".", []byte(`param (*args, sep=","); args[0] + sep + args[1]`), nil)
+	s.args = []string{"a", "b", "--sep=+"}
+	require.NoError(t, s.eval(&buf))
+	require.Equal(t, "a+b\n", buf.String())
+
+	buf.Reset()
+	require.NoError(t, newScript(ctx, "(expr)", ".", []byte(`x := 1`), nil).eval(&buf))
+	require.Empty(t, buf.String())
+
+	require.NoError(t, newScript(ctx, "(expr)", ".",
+		[]byte(`[typeName(stdin), typeName(stdout), typeName(stderr)]`), nil).eval(&buf))
+	require.Equal(t, `["reader", "writer", "writer"]`+"\n", buf.String())
+
+	require.Error(t, newScript(ctx, "(expr)", ".", []byte(`throw "x"`), nil).eval(&buf))
+}
+
 func testHasPrefix(t *testing.T, s, pref string) {
 	t.Helper()
 	v := strings.HasPrefix(s, pref)
@@ -318,3 +458,288 @@ func (c *console) consume() []byte {
 func (c *console) Write(p []byte) (int, error) {
 	return c.buf.Write(p)
 }
+
+func TestPack(t *testing.T) {
+	dir := t.TempDir()
+	libDir := filepath.Join(dir, "mylib")
+	require.NoError(t, os.MkdirAll(libDir, 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(libDir, "index.gad"),
+		[]byte(`return {x: import("strings").ToUpper("x")}`), 0o644))
+
+	output := filepath.Join(dir, "mylib.gadpkg")
+	var buf bytes.Buffer
+	require.NoError(t, runPack([]string{"-o", output, "-version", "1.0.0", libDir}, &buf))
+	require.Equal(t, "mylib: 1 modules packed into "+output+"\n", buf.String())
+
+	var err error
+	packages, err = loadPackages([]string{output})
+	require.NoError(t, err)
+	defer func() { packages = nil }()
+
+	scr := []byte(`if import("mylib").x != "X" { throw "unexpected" }`)
+	require.NoError(t, newScript(context.Background(), "(pack)", dir, scr, nil).execute())
+
+	require.Error(t, runPack(nil, &buf))
+}
+
+func TestModTidy(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "lib@1.0.0.gad"), []byte(`return 1`), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "lib@1.1.0.gad"), []byte(`return 2`), 0o644))
+	script := filepath.Join(dir, "main.gad")
+	require.NoError(t, os.WriteFile(script, []byte(`import("lib@^1")`), 0o644))
+
+	lock := filepath.Join(dir, "gad.lock")
+	var buf bytes.Buffer
+	require.NoError(t, runMod([]string{"tidy", "-lock", lock, script}, &buf))
+	require.Equal(t, "1 versioned imports locked in "+lock+"\n", buf.String())
+
+	data, err := os.ReadFile(lock)
+	require.NoError(t, err)
+	require.Contains(t, string(data), "lib@^1 1.1.0\n")
+
+	require.Error(t, runMod([]string{"vendor"}, &buf))
+}
+
+func TestDisasm(t *testing.T) {
+	dir := t.TempDir()
+	script := filepath.Join(dir, "main.gad")
+	require.NoError(t, os.WriteFile(script, []byte("x := 1\nreturn x"), 0o644))
+
+	var buf bytes.Buffer
+	require.NoError(t, runDisasm([]string{script}, &buf))
+	require.Contains(t, buf.String(), "    ; "+script+":1: x := 1\n  0000  CONSTANT     5               ; 1\n")
+
+	buf.Reset()
+	require.NoError(t, runDisasm([]string{"-no-source", script}, &buf))
+	require.NotContains(t, buf.String(), script)
+
+	require.Error(t, runDisasm(nil, &buf))
+}
+
+func TestJSON(t *testing.T) {
+	var buf bytes.Buffer
+	in := strings.NewReader(`{"name": "gad", "tags": ["a", "b"]}`)
+	require.NoError(t, runJSON([]string{"{n: it.name, count: len(it.tags)}"}, in, &buf))
+	require.Equal(t, "{\n  \"count\": 2,\n  \"n\": \"gad\"\n}\n", buf.String())
+
+	buf.Reset()
+	in = strings.NewReader("{\"x\": 1}\n\n{\"x\": \"y\"}\n")
+	require.NoError(t, runJSON([]string{"-lines", "-c", "-r", "it.x"}, in, &buf))
+	require.Equal(t, "1\ny\n", buf.String())
+
+	require.Error(t, runJSON([]string{"x := 1"}, strings.NewReader(`1`), &buf))
+	require.Error(t, runJSON([]string{"it"}, strings.NewReader(`{`), &buf))
+	require.Error(t, runJSON(nil, strings.NewReader(`1`), &buf))
+}
+
+func TestDebug(t *testing.T) {
+	dir := t.TempDir()
+	script := filepath.Join(dir, "main.gad")
+	require.NoError(t, os.WriteFile(script, []byte(`add := func(a, b) {
+	c := a + b
+	return c
+}
+x := add(1, 2)
+println(x)
+return x * 2`), 0o644))
+
+	var buf bytes.Buffer
+	in := strings.NewReader("break 3\nc\np c * 10\nbt\nlocals\nn\nn\n\n")
+	require.NoError(t, runDebug([]string{script}, in, &buf))
+	out := buf.String()
+	require.Contains(t, out, script+":1\n  1 | add := func(a, b) {\n")
+	require.Contains(t, out, "breakpoint set at "+script+":3\n")
+	require.Contains(t, out, script+":3\n  3 | \treturn c\n")
+	require.Contains(t, out, "(debug) p c * 10\n⇦   30\n")
+	require.Contains(t, out, "#1  <main> at "+script+":5\n")
+	require.Contains(t, out, "a = 1\nb = 2\nc = 3\n")
+	require.Contains(t, out, "  6 | println(x)\n(debug) n\n3\n")
+	require.Contains(t, out, "exited: 6\n")
+
+	buf.Reset()
+	require.NoError(t, runDebug([]string{script}, strings.NewReader("s\ns\nq\n"), &buf))
+	require.Contains(t, buf.String(), "  2 | \tc := a + b\n(debug) q\n")
+	require.NotContains(t, buf.String(), "exited")
+
+	require.Error(t, runDebug(nil, strings.NewReader(""), &buf))
+}
+
+func TestErrorPrinter(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	p := &errorPrinter{sources: map[string][]byte{}}
+	run := func(src string) string {
+		p.sources["(test)"] = []byte(src)
+		err := newScript(ctx, "(test)", ".", []byte(src), nil).execute()
+		require.Error(t, err)
+		var buf bytes.Buffer
+		p.print(&buf, err)
+		return buf.String()
+	}
+
+	require.Equal(t, "ZeroDivisionError: \n"+
+		" --> (test):2:9\n"+
+		"  |\n"+
+		"2 | \treturn a / 0\n"+
+		"  | \t       ^\n"+
+		"\tat (test):4:1\n"+
+		"\t   (test):2:9\n",
+		run("f := func(a) {\n\treturn a / 0\n}\nf(1)"))
+	require.Equal(t, "Parse Error: expected ')', found 'EOF'\n"+
+		" --> (test):1:8\n"+
+		"  |\n"+
+		"1 | x := (1\n"+
+		"  |        ^\n",
+		run("x := (1"))
+	require.Equal(t, "Compile Error: unresolved reference \"y\"\n"+
+		" --> (test):1:1\n"+
+		"  |\n"+
+		"1 | y\n"+
+		"  | ^\n",
+		run("y"))
+
+	p.color = true
+	require.Contains(t, run("y"), "\x1b[1m\x1b[31mCompile Error: unresolved reference \"y\"\x1b[0m\n")
+
+	var buf bytes.Buffer
+	p.print(&buf, errors.New("no position"))
+	require.Equal(t, "no position\n", buf.String())
+
+	t.Setenv("NO_COLOR", "1")
+	require.False(t, useColor(false))
+}
+
+func TestCheck(t *testing.T) {
+	dir := t.TempDir()
+	ok := filepath.Join(dir, "ok.gad")
+	require.NoError(t, os.WriteFile(ok, []byte("var (x int = 1)\nreturn x"), 0o644))
+	warn := filepath.Join(dir, "warn.gad")
+	require.NoError(t, os.WriteFile(warn, []byte("var (x int = \"a\")\nreturn x"), 0o644))
+	compile := filepath.Join(dir, "compile.gad")
+	require.NoError(t, os.WriteFile(compile, []byte("x := 1\nreturn y"), 0o644))
+	parse := filepath.Join(dir, "parse.gad")
+	require.NoError(t, os.WriteFile(parse, []byte("x := (1\ny := 2"), 0o644))
+
+	var buf bytes.Buffer
+	require.NoError(t, runCheck([]string{ok}, &buf))
+	require.Empty(t, buf.String())
+
+	require.NoError(t, runCheck([]string{"-json", warn}, &buf))
+	require.Equal(t, `{"file":"`+warn+`","line":1,"col":14,"severity":"warning","code":"type-mismatch",`+
+		`"message":"cannot use str value as int in declaration of 'x'"}`+"\n", buf.String())
+
+	buf.Reset()
+	require.ErrorIs(t, runCheck([]string{"-strict", warn}, &buf), errCheckFailed)
+	require.Equal(t, warn+":1:14: warning: cannot use str value as int in declaration of 'x' [type-mismatch]\n", buf.String())
+
+	buf.Reset()
+	require.ErrorIs(t, runCheck([]string{"-json", ok, compile, parse}, &buf), errCheckFailed)
+	require.Equal(t, `{"file":"`+compile+`","line":2,"col":8,"severity":"error","code":"compile",`+
+		`"message":"unresolved reference \"y\""}`+"\n", strings.SplitAfter(buf.String(), "\n")[0])
+	require.Equal(t, `{"file":"`+parse+`","line":2,"col":1,"severity":"error","code":"parse",`+
+		`"message":"expected ')', found y"}`+"\n", strings.SplitAfter(buf.String(), "\n")[1])
+
+	require.Error(t, runCheck([]string{filepath.Join(dir, "none.gad")}, &buf))
+	require.Error(t, runCheck(nil, &buf))
+}
+
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestServe(t *testing.T) {
+	dir := t.TempDir()
+	config := filepath.Join(dir, "config.json")
+	require.NoError(t, os.WriteFile(config, []byte(`{"name": "a"}`), 0o644))
+	app := filepath.Join(dir, "app.gad")
+	require.NoError(t, os.WriteFile(app, []byte(`
+state := {healthy: true}
+return {
+	start: func(ctx) {
+		println("start", ctx.config().name)
+		ctx.wait()
+		println("done", ctx.done())
+	},
+	stop: func() { println("stop") },
+	reload: func(config) {
+		println("reload", config.name)
+		state.healthy = false
+	},
+	health: func() { return state.healthy },
+}`), 0o644))
+
+	var out syncBuffer
+	sv, err := newService(app, nil, config, &out)
+	require.NoError(t, err)
+
+	health := func() int {
+		w := httptest.NewRecorder()
+		sv.ServeHTTP(w, httptest.NewRequest("GET", "/health", nil))
+		return w.Code
+	}
+
+	sigs := make(chan os.Signal)
+	ret := make(chan error)
+	go func() { ret <- sv.run(sigs) }()
+	require.Eventually(t, func() bool { return out.String() == "start a\n" }, time.Second, time.Millisecond)
+	require.Equal(t, http.StatusOK, health())
+
+	require.NoError(t, os.WriteFile(config, []byte(`{"name": "b"}`), 0o644))
+	sigs <- syscall.SIGHUP
+	require.Eventually(t, func() bool { return out.String() == "start a\nreload b\n" }, time.Second, time.Millisecond)
+	require.Equal(t, http.StatusServiceUnavailable, health())
+	sigs <- syscall.SIGTERM
+	require.NoError(t, <-ret)
+	require.Equal(t, "start a\nreload b\nstop\ndone true\n", out.String())
+	require.Equal(t, http.StatusServiceUnavailable, health())
+
+	require.NoError(t, os.WriteFile(app, []byte(`return {stop: func() {}}`), 0o644))
+	_, err = newService(app, nil, "", &out)
+	require.EqualError(t, err, "serve: script must return a dict with start function, got dict")
+
+	require.NoError(t, os.WriteFile(app, []byte(`return {start: func(ctx) { throw "failed" }}`), 0o644))
+	sv, err = newService(app, nil, "", &out)
+	require.NoError(t, err)
+	require.ErrorContains(t, sv.run(sigs), "failed")
+
+	require.Error(t, runServe(nil, &out))
+}
+
+func TestLint(t *testing.T) {
+	dir := t.TempDir()
+	app := filepath.Join(dir, "app.gad")
+	require.NoError(t, os.WriteFile(app, []byte("a := 1\nreturn 1 == \"1\""), 0o644))
+
+	var buf bytes.Buffer
+	require.NoError(t, runLint([]string{app}, &buf))
+	require.Equal(t, app+":1:1: warning: 'a' declared and not used [unused-var]\n"+
+		app+":2:10: warning: suspicious == comparison of int and str values [mixed-equal]\n", buf.String())
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, ".gadlint"), []byte("mixed-equal off\nunused-var error"), 0o644))
+	buf.Reset()
+	require.ErrorIs(t, runLint([]string{"-json", app}, &buf), errLintFailed)
+	require.Equal(t, `{"file":"`+app+`","line":1,"col":1,"severity":"error","code":"unused-var",`+
+		`"message":"'a' declared and not used"}`+"\n", buf.String())
+
+	require.NoError(t, os.WriteFile(app, []byte("a := (1"), 0o644))
+	buf.Reset()
+	require.ErrorIs(t, runLint([]string{app}, &buf), errLintFailed)
+	require.Contains(t, buf.String(), "error: expected ')', found 'EOF' [parse]")
+
+	require.Error(t, runLint(nil, &buf))
+}
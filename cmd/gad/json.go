@@ -0,0 +1,107 @@
+//go:build !js
+// +build !js
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+
+	"github.com/gad-lang/gad"
+	"github.com/gad-lang/gad/stdlib/json"
+)
+
+// runJSON runs `gad json` command which decodes the JSON input into the
+// variable it, evaluates an expression and prints the result as JSON.
+func runJSON(args []string, in io.Reader, out io.Writer) error {
+	var (
+		flagset = flag.NewFlagSet("json", flag.ContinueOnError)
+		compact bool
+		raw     bool
+		lines   bool
+	)
+	flagset.SetOutput(out)
+	flagset.BoolVar(&compact, "c", false, `Print compact JSON instead of indented JSON`)
+	flagset.BoolVar(&raw, "r", false, `Print string results without quotes`)
+	flagset.BoolVar(&lines, "lines", false, `Evaluate the expression for each line of newline delimited JSON input`)
+	flagset.Usage = func() {
+		_, _ = fmt.Fprint(flagset.Output(),
+			"Usage: gad json [flags] EXPR\n",
+			"       gad -json [flags] EXPR\n\n",
+			"Decodes the JSON read from stdin into the variable it, evaluates the expression\n",
+			"and prints its result encoded as JSON.\n\n",
+			"    curl -s https://api.github.com/users/gad-lang | gad json 'it.name'\n",
+			"    cat users.json | gad json -c 'collect(values(filter(it, (u, _, _) => u.age >= 18)))'\n\n",
+			"Flags:\n",
+		)
+		flagset.PrintDefaults()
+	}
+	if err := flagset.Parse(args); err != nil {
+		return err
+	}
+	if flagset.NArg() != 1 {
+		flagset.Usage()
+		return fmt.Errorf("json: EXPR is required")
+	}
+
+	bc, err := gad.CompileExpr([]byte(flagset.Arg(0)), gad.CompileOptions{
+		CompilerOptions: gad.CompilerOptions{
+			Module: &gad.ModuleInfo{Name: "(json)"},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	vm := gad.NewVM(bc).SetRecover(true)
+	eval := func(data []byte) error {
+		it, err := json.Unmarshal(data, json.NewDecodeOptions())
+		if err != nil {
+			return err
+		}
+
+		ret, err := vm.RunOpts(&gad.RunOpts{Globals: gad.Dict{"it": it}})
+		if err != nil {
+			return err
+		}
+
+		if s, ok := ret.(gad.Str); ok && raw {
+			_, err = fmt.Fprintln(out, string(s))
+			return err
+		}
+
+		var b []byte
+		if compact {
+			b, err = json.Marshal(vm, ret)
+		} else {
+			b, err = json.MarshalIndent(vm, ret, "", "  ")
+		}
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(out, "%s\n", b)
+		return err
+	}
+
+	if !lines {
+		data, err := io.ReadAll(in)
+		if err != nil {
+			return err
+		}
+		return eval(data)
+	}
+
+	scanner := bufio.NewScanner(in)
+	scanner.Buffer(nil, 64*1024*1024)
+	for scanner.Scan() {
+		if line := bytes.TrimSpace(scanner.Bytes()); len(line) > 0 {
+			if err = eval(line); err != nil {
+				return err
+			}
+		}
+	}
+	return scanner.Err()
+}
@@ -0,0 +1,121 @@
+//go:build !js
+// +build !js
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/gad-lang/gad/importers"
+	"github.com/gad-lang/gad/lint"
+	"github.com/gad-lang/gad/parser"
+)
+
+// errLintFailed is returned by `gad lint` if a diagnostic with error severity
+// is found, the diagnostics are already printed.
+var errLintFailed = errors.New("lint failed")
+
+// runLint runs `gad lint` command which reports the rule violations of the
+// script files.
+func runLint(args []string, out io.Writer) error {
+	var (
+		flagset    = flag.NewFlagSet("lint", flag.ContinueOnError)
+		configFile string
+		asJSON     bool
+	)
+	flagset.SetOutput(out)
+	flagset.StringVar(&configFile, "config", "",
+		`Config file, defaults to the nearest `+lint.ConfigFile+` file in the directory of the script or its parents`)
+	flagset.BoolVar(&asJSON, "json", false,
+		`Print diagnostics as JSON records, one per line: {"file", "line", "col", "severity", "code", "message"}`)
+	flagset.Usage = func() {
+		_, _ = fmt.Fprint(flagset.Output(),
+			"Usage: gad lint [flags] SCRIPT_FILE...\n\n",
+			"Reports unused variables, shadowed builtins, suspicious comparisons, unreachable\n",
+			"code, deep nesting and names not matching the naming convention. Exit status is 1\n",
+			"if a rule with error severity is violated.\n\n",
+			"Flags:\n",
+		)
+		flagset.PrintDefaults()
+	}
+	if err := flagset.Parse(args); err != nil {
+		return err
+	}
+	if flagset.NArg() == 0 {
+		flagset.Usage()
+		return fmt.Errorf("lint: SCRIPT_FILE is required")
+	}
+
+	var (
+		enc    = json.NewEncoder(out)
+		failed bool
+	)
+	for _, file := range flagset.Args() {
+		path := configFile
+		if path == "" {
+			path = lint.FindConfig(filepath.Dir(file))
+		}
+		config := lint.DefaultConfig()
+		if path != "" {
+			var err error
+			if config, err = lint.LoadConfig(path); err != nil {
+				return err
+			}
+		}
+
+		diagnostics, err := lintFile(file, config)
+		if err != nil {
+			return err
+		}
+		for _, d := range diagnostics {
+			if d.Severity == string(lint.Error) {
+				failed = true
+			}
+			if asJSON {
+				err = enc.Encode(d)
+			} else {
+				_, err = fmt.Fprintln(out, d)
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+	if failed {
+		return errLintFailed
+	}
+	return nil
+}
+
+// lintFile returns the diagnostics of the script file. Parse errors are
+// returned as diagnostics with error severity.
+func lintFile(file string, config *lint.Config) ([]*diagnostic, error) {
+	script, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	importers.Shebang2Slashes(script)
+
+	var diagnostics []*diagnostic
+	lints, err := lint.LintSource(file, script, config)
+	if err != nil {
+		var errList parser.ErrorList
+		if !errors.As(err, &errList) {
+			return nil, err
+		}
+		for _, e := range errList {
+			diagnostics = append(diagnostics, newDiagnostic(e.Pos, "error", "parse", e.Msg))
+		}
+		return diagnostics, nil
+	}
+	for _, d := range lints {
+		diagnostics = append(diagnostics, newDiagnostic(d.Pos, string(d.Severity), d.Rule, d.Message))
+	}
+	return diagnostics, nil
+}
@@ -23,10 +23,16 @@ import (
 	"strconv"
 	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/gad-lang/gad"
+	"github.com/gad-lang/gad/checker"
+	"github.com/gad-lang/gad/gadpkg"
+	"github.com/gad-lang/gad/parser"
 	"github.com/gad-lang/gad/runehelper"
 	"github.com/gad-lang/gad/stdlib/helper"
+	"github.com/gad-lang/gad/stdlib/humanize"
+	"github.com/gad-lang/gad/stdlib/term"
 	"github.com/peterh/liner"
 
 	"github.com/gad-lang/gad/importers"
@@ -37,6 +43,7 @@ const (
 	title         = "Gad"
 	promptPrefix  = ">>> "
 	promptPrefix2 = "... "
+	indentUnit    = "    "
 )
 
 var (
@@ -45,8 +52,15 @@ var (
 	traceParser     bool
 	traceOptimizer  bool
 	traceCompiler   bool
+	tracePasses     []string
+	passes          []string
 	safe            bool
+	vet             bool
 	disabledModules map[string]bool
+	packages        []*gadpkg.Package
+	cacheDir        string
+	evalExpr        string
+	noColor         bool
 )
 
 var suggestions []suggest
@@ -80,6 +94,9 @@ var (
 				}
 				return v
 			}(),
+			"stdin":  gad.NewReader(os.Stdin),
+			"stdout": gad.NewWriter(os.Stdout),
+			"stderr": gad.NewWriter(os.Stderr),
 		},
 	}
 )
@@ -99,6 +116,12 @@ type repl struct {
 	lastBytecode *gad.Bytecode
 	lastResult   gad.Object
 	isMultiline  bool
+	// indent is the number of the unclosed brackets of the multiline input.
+	indent int
+	// inRaw reports whether the multiline input ends in a raw string or a
+	// comment whose lines are kept as they are.
+	inRaw     bool
+	lastBlank bool
 }
 
 func newREPL(ctx context.Context, stdout io.Writer) *repl {
@@ -114,6 +137,8 @@ func newREPL(ctx context.Context, stdout io.Writer) *repl {
 		TraceCompiler:     traceCompiler,
 		OptimizeConst:     !noOptimizer,
 		OptimizeExpr:      !noOptimizer,
+		OptimizerPasses:   passes,
+		TracePasses:       tracePasses,
 	}}
 
 	if stdout == nil {
@@ -148,6 +173,8 @@ func newREPL(ctx context.Context, stdout io.Writer) *repl {
 		".symbols+":      r.cmdSymbolsVerbose,
 		".modules_cache": r.cmdModulesCache,
 		".memory_stats":  r.cmdMemoryStats,
+		".time":          r.cmdTime,
+		".mem":           r.cmdMem,
 		".reset":         func(string) error { return errReset },
 		".exit":          func(string) error { return errExit },
 	}
@@ -285,6 +312,67 @@ func (r *repl) cmdMemoryStats(_ string) error {
 	return nil
 }
 
+// defaultBenchRuns is the number of runs of .time and .mem commands if it is
+// not given.
+const defaultBenchRuns = 1000
+
+// cmdTime runs `.time [-n N] CODE` command which runs the code N times and
+// prints the wall time.
+func (r *repl) cmdTime(line string) error {
+	n, elapsed, _, err := r.bench(line)
+	if err != nil {
+		r.writeString(fmt.Sprintf("!   %+v", err))
+		return nil
+	}
+	_, _ = fmt.Fprintf(r.out, "%d runs\t%v\t%v/op\n", n, elapsed, elapsed/time.Duration(n))
+	return nil
+}
+
+// cmdMem runs `.mem [-n N] CODE` command which runs the code N times and
+// prints the memory allocated.
+func (r *repl) cmdMem(line string) error {
+	n, _, m, err := r.bench(line)
+	if err != nil {
+		r.writeString(fmt.Sprintf("!   %+v", err))
+		return nil
+	}
+	_, _ = fmt.Fprintf(r.out, "%d runs\t%s\t%d B/op\t%d allocs/op\n",
+		n, humanFriendlySize(m.TotalAlloc), m.TotalAlloc/uint64(n), m.Mallocs/uint64(n))
+	return nil
+}
+
+// bench runs the code of `.CMD [-n N] CODE` line N times in a function which
+// can access the variables of the session. It returns the number of runs, the
+// wall time and the delta of the memory stats.
+func (r *repl) bench(line string) (n int, elapsed time.Duration, m runtime.MemStats, err error) {
+	_, code, _ := strings.Cut(strings.TrimSpace(line), " ")
+	code = strings.TrimSpace(code)
+	n = defaultBenchRuns
+	if rest, ok := strings.CutPrefix(code, "-n "); ok {
+		runs, rest, _ := strings.Cut(strings.TrimSpace(rest), " ")
+		if n, err = strconv.Atoi(runs); err != nil || n < 1 {
+			return 0, 0, m, fmt.Errorf("invalid number of runs %q", runs)
+		}
+		code = strings.TrimSpace(rest)
+	}
+	if code == "" {
+		return 0, 0, m, errors.New("code is required: .time|.mem [-n N] CODE")
+	}
+
+	script := fmt.Sprintf("(func() {\n\tf := func() {\n%s\n\t}\n\tfor i := 0; i < %d; i++ { f() }\n})()", code, n)
+
+	var before runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+	start := time.Now()
+	_, _, err = r.eval.Run(r.ctx, []byte(script))
+	elapsed = time.Since(start)
+	runtime.ReadMemStats(&m)
+	m.TotalAlloc -= before.TotalAlloc
+	m.Mallocs -= before.Mallocs
+	return
+}
+
 func (r *repl) cmdModulesCache(_ string) error {
 	_, _ = fmt.Fprintf(r.out, "%v\n", r.eval.ModulesCache)
 	return nil
@@ -311,16 +399,78 @@ func (r *repl) execute(line string) error {
 		return nil
 	}
 
+	// Input is continued while it has unclosed brackets, raw strings or
+	// comments. Two blank lines run the incomplete input to report its error.
+	blank := strings.TrimSpace(line) == ""
+	force := r.isMultiline && blank && r.lastBlank
+	r.lastBlank = blank
+	if r.isMultiline && !r.inRaw {
+		line = r.indentLine(line)
+	}
 	r.script.WriteString(line)
 
+	if !force {
+		if r.indent, r.inRaw = inputDepth(r.script.Bytes()); r.indent > 0 || r.inRaw {
+			r.isMultiline = true
+			r.script.WriteString("\n")
+			return nil
+		}
+	}
+
 	r.executeScript()
 
 	r.isMultiline = false
+	r.indent, r.inRaw, r.lastBlank = 0, false, false
 	r.setSymbolSuggestions()
 	r.script.Reset()
 	return nil
 }
 
+// indentLine replaces the indentation of the continuation line with the
+// indentation of the unclosed brackets.
+func (r *repl) indentLine(line string) string {
+	line = strings.TrimLeft(line, " \t")
+	if line == "" {
+		return line
+	}
+	depth := r.indent
+	if strings.IndexByte("}])", line[0]) >= 0 {
+		depth--
+	}
+	return strings.Repeat(indentUnit, max(depth, 0)) + line
+}
+
+// indentation returns the indentation to suggest for the next line.
+func (r *repl) indentation() string {
+	if !r.isMultiline || r.inRaw {
+		return ""
+	}
+	return strings.Repeat(indentUnit, max(r.indent, 0))
+}
+
+// inputDepth returns the number of the unclosed brackets of src and whether
+// it ends in an unterminated raw string or comment.
+func inputDepth(src []byte) (depth int, inRaw bool) {
+	file := parser.NewFileSet().AddFile("", -1, len(src))
+	s := parser.NewScanner(file, src, nil)
+	s.ErrorHandler(func(_ parser.SourceFilePos, msg string) {
+		switch msg {
+		case "raw string literal not terminated", "comment not terminated":
+			inRaw = true
+		}
+	})
+	for {
+		switch s.Scan().Token {
+		case token.EOF:
+			return
+		case token.LBrace, token.LParen, token.LBrack:
+			depth++
+		case token.RBrace, token.RParen, token.RBrack:
+			depth--
+		}
+	}
+}
+
 func (r *repl) executeScript() {
 	var err error
 
@@ -330,15 +480,20 @@ func (r *repl) executeScript() {
 		return
 	}
 
-	switch v := r.lastResult.(type) {
+	r.writeString("\n⇦   " + resultString(r.lastResult))
+}
+
+// resultString formats the result of an evaluation for the terminal.
+func resultString(o gad.Object) string {
+	switch v := o.(type) {
 	case gad.Str:
-		r.writeString(fmt.Sprintf("\n⇦   %q", string(v)))
+		return strconv.Quote(string(v))
 	case gad.Char:
-		r.writeString(fmt.Sprintf("\n⇦   %q", rune(v)))
+		return strconv.QuoteRune(rune(v))
 	case gad.Bytes:
-		r.writeString(fmt.Sprintf("\n⇦   %v", []byte(v)))
+		return fmt.Sprint([]byte(v))
 	default:
-		r.writeString(fmt.Sprintf("\n⇦   %v", r.lastResult))
+		return fmt.Sprint(o)
 	}
 }
 
@@ -379,8 +534,14 @@ func (r *repl) run(history io.Reader) error {
 	line := liner.NewLiner()
 	defer line.Close()
 
+	defer func() {
+		if err := r.eval.Close(); err != nil {
+			r.writeString(fmt.Sprintf("\n!   %+v", err))
+		}
+	}()
+
 	line.SetMultiLineMode(true)
-	line.SetCompleter(complete)
+	line.SetCompleter(r.complete)
 	_, err := line.ReadHistory(history)
 	if err != nil {
 		err = &gad.Error{Message: "failed history read", Cause: err}
@@ -391,7 +552,11 @@ func (r *repl) run(history io.Reader) error {
 	var str string
 
 	for err == nil {
-		str, err = line.Prompt(r.prefix())
+		if indent := r.indentation(); indent != "" {
+			str, err = line.PromptWithSuggestion(r.prefix(), indent, -1)
+		} else {
+			str, err = line.Prompt(r.prefix())
+		}
 		if err != nil {
 			if err == io.EOF {
 				err = nil
@@ -412,6 +577,117 @@ func (r *repl) run(history io.Reader) error {
 	return err
 }
 
+// complete completes the member selector at the end of the line with the keys,
+// fields, getters and methods of the value of the session it selects, the
+// other lines are completed with the symbols.
+func (r *repl) complete(line string) (completions []string) {
+	start := len(line)
+	for start > 0 {
+		c, size := utf8.DecodeLastRuneInString(line[:start])
+		if c != '.' && !runehelper.IsIdentifier(c) {
+			break
+		}
+		start -= size
+	}
+
+	path, prefix, ok := cutLast(line[start:], ".")
+	if !ok || path == "" {
+		return complete(line)
+	}
+
+	v := r.selectorValue(path)
+	if v == nil {
+		return nil
+	}
+
+	for _, name := range memberNames(v) {
+		if strings.HasPrefix(name, prefix) {
+			completions = append(completions, line[:start]+path+"."+name)
+		}
+	}
+	return
+}
+
+// selectorValue returns the value of the session selected by the path like
+// `a.b.c` or nil if it is not found. Only the keys of dictionaries and the
+// fields of objects are selected to not run script code.
+func (r *repl) selectorValue(path string) (v gad.Object) {
+	names := strings.Split(path, ".")
+	for _, s := range r.eval.Opts.SymbolTable.Symbols() {
+		if s.Name != names[0] {
+			continue
+		}
+		switch s.Scope {
+		case gad.ScopeLocal:
+			if s.Index < len(r.eval.Locals) {
+				v = r.eval.Locals[s.Index]
+			}
+		case gad.ScopeGlobal:
+			v, _ = r.eval.Globals.IndexGet(r.eval.VM, gad.Str(s.Name))
+		}
+	}
+	if p, ok := v.(*gad.ObjectPtr); ok && p.Value != nil {
+		v = *p.Value
+	}
+
+	for _, name := range names[1:] {
+		switch t := v.(type) {
+		case gad.Dict:
+			v = t[name]
+		case *gad.SyncDict:
+			v, _ = t.Get(name)
+		case *gad.Obj:
+			v = t.Fields()[name]
+		default:
+			return nil
+		}
+	}
+	return
+}
+
+// memberNames returns the sorted identifier names which can be selected from
+// the value.
+func memberNames(v gad.Object) []string {
+	set := map[string]struct{}{}
+	add := func(d gad.Dict) {
+		for k := range d {
+			set[k] = struct{}{}
+		}
+	}
+	if kg, ok := v.(gad.KeysGetter); ok {
+		for _, k := range kg.Keys() {
+			if s, ok := k.(gad.Str); ok {
+				set[string(s)] = struct{}{}
+			}
+		}
+	}
+	if t := v.Type(); t != nil {
+		if _, ok := v.(*gad.Obj); ok {
+			add(t.Fields())
+			set[gad.ObjectMethodsGetterFieldName] = struct{}{}
+		}
+		add(t.Getters())
+		add(t.Methods())
+	}
+
+	names := make([]string, 0, len(set))
+	for name := range set {
+		if name != "" && strings.IndexFunc(name, func(r rune) bool { return !runehelper.IsIdentifier(r) }) < 0 {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// cutLast slices s around the last instance of sep.
+func cutLast(s, sep string) (before, after string, found bool) {
+	if i := strings.LastIndex(s, sep); i >= 0 {
+		return s[:i], s[i+len(sep):], true
+	}
+	return s, "", false
+}
+
 func complete(line string) (completions []string) {
 	var contains []string
 	for _, v := range suggestions {
@@ -427,7 +703,7 @@ func complete(line string) (completions []string) {
 
 func defaultSymbolTable() *gad.SymbolTable {
 	table := gad.NewSymbolTable(gad.NewBuiltins())
-	_, err := table.DefineGlobals([]string{"Gosched", "SOURCE_PATH"})
+	_, err := table.DefineGlobals([]string{"Gosched", "SOURCE_PATH", "stdin", "stdout", "stderr"})
 	if err != nil {
 		panic(&gad.Error{Message: "global symbol define error", Cause: err})
 	}
@@ -438,26 +714,20 @@ func DefaultModuleMap(workdir string, sourcePath *importers.PathList) *gad.Modul
 	mb := helper.NewModuleMapBuilder()
 	mb.Safe = safe
 	mb.Disabled = disabledModules
-	return mb.Build().
-		SetExtImporter(&importers.FileImporter{
-			WorkDir:      workdir,
-			FileReader:   importers.ShebangReadFile,
-			NameResolver: importers.OsDirsNameResolverPtr(sourcePath),
-		})
+	mm := mb.Build()
+	for _, pkg := range packages {
+		pkg.AddTo(mm)
+	}
+	return mm.SetExtImporter(&importers.FileImporter{
+		WorkDir:      workdir,
+		FileReader:   importers.ShebangReadFile,
+		NameResolver: importers.OsDirsNameResolverPtr(sourcePath),
+		Lock:         moduleLock,
+	})
 }
 
 func humanFriendlySize(b uint64) string {
-	if b < 1024 {
-		return fmt.Sprint(strconv.FormatUint(b, 10), " bytes")
-	}
-
-	if b >= 1024 && b < 1024*1024 {
-		return fmt.Sprint(strconv.FormatFloat(
-			float64(b)/1024, 'f', 1, 64), " KiB")
-	}
-
-	return fmt.Sprint(strconv.FormatFloat(
-		float64(b)/1024/1024, 'f', 1, 64), " MiB")
+	return humanize.Bytes(float64(b), false)
 }
 
 func initSuggestions() {
@@ -475,6 +745,8 @@ func initSuggestions() {
 		{text: ".return+", description: "Print Last Return Result (verbose)"},
 		{text: ".modules_cache", description: "Print Modules Cache"},
 		{text: ".memory_stats", description: "Print Memory Stats"},
+		{text: ".time", description: "Run code N times and print wall time: .time [-n N] CODE"},
+		{text: ".mem", description: "Run code N times and print allocations: .mem [-n N] CODE"},
 		{text: ".gc", description: "Run Garbage Collector"},
 		{text: ".symbols", description: "Print Symbols"},
 		{text: ".symbols+", description: "Print Symbols (verbose)"},
@@ -483,22 +755,26 @@ func initSuggestions() {
 	}
 
 	// add builtins to suggestions
-	for k, id := range gad.BuiltinsMap {
+	for _, info := range gad.NewBuiltins().List() {
 		var desc string
-		o := gad.BuiltinObjects[id]
-		switch o.(type) {
-		case *gad.BuiltinFunction:
+		switch info.Kind {
+		case gad.BuiltinKindFunction:
 			desc = "Builtin Function"
-		case *gad.BuiltinObjType:
+		case gad.BuiltinKindType:
 			desc = "Builtin Object Type"
-		case *gad.Error:
+		case gad.BuiltinKindError:
 			desc = "Builtin Error"
+		case gad.BuiltinKindNamespace:
+			desc = "Builtin Namespace"
 		default:
 			desc = "Builtin"
 		}
+		if info.Doc != "" {
+			desc = info.Doc
+		}
 		suggestions = append(suggestions,
 			suggest{
-				text:        k,
+				text:        info.Name,
 				description: desc,
 				typ:         "builtin",
 			},
@@ -522,17 +798,31 @@ func parseFlags(
 ) (filePath string, timeout time.Duration, params []string, err error) {
 	var (
 		trace    string
+		passList string
 		disabled string
+		pkgs     string
 		module   bool
 	)
 	flagset.StringVar(&trace, "trace", "",
-		`Comma separated units: -trace parser,optimizer,compiler`)
+		`Comma separated units: -trace parser,optimizer,compiler. `+
+			`Trace an optimizer pass with optimizer:PASS: -trace optimizer:dce,optimizer:peephole`)
 	flagset.BoolVar(&noOptimizer, "no-optimizer", false, `Disable optimization`)
-	flagset.BoolVar(&safe, "safe", false, `Disable al external acess modules: "http", "os" and "filepath"`)
+	flagset.StringVar(&passList, "passes", strings.Join(gad.OptimizerPassNames, ","),
+		`Comma separated optimizer passes to run`)
+	flagset.BoolVar(&vet, "vet", false, `Report type mismatches of SCRIPT_FILE without running it`)
+	flagset.BoolVar(&safe, "safe", false, `Disable al external acess modules: "http", "os", "filepath", "term", "ask" and "blob"`)
 	flagset.BoolVar(&module, "module", false, `if SCRIPT_FILE does not exists, check exists in GADPATH`)
 	flagset.StringVar(&disabled, "disabled-modules", "", `Disable external acess modules by comma separated units: -disabled-modules http,os`)
+	flagset.StringVar(&pkgs, "pkg", "", `Import modules from comma separated package archives: -pkg mylib.gadpkg,other.gadpkg`)
+	flagset.StringVar(&cacheDir, "cache", os.Getenv("GADCACHE"),
+		"Directory to cache compiled scripts, defaults to $GADCACHE. Caching is disabled if it is empty")
+	flagset.StringVar(&evalExpr, "e", "",
+		"Run the script EXPR instead of SCRIPT_FILE and print its result if it is not nil. "+
+			"All arguments are passed to the script")
+	flagset.BoolVar(&noColor, "no-color", false,
+		"Disable colors of error output. Colors are disabled if NO_COLOR environment variable is set")
 	flagset.DurationVar(&timeout, "timeout", 0,
-		"Program timeout. It is applicable if a script file is provided and "+
+		"Program timeout. It is applicable if a script file or an expression is provided and "+
 			"must be non-zero duration")
 
 	disabledModules = map[string]bool{}
@@ -545,7 +835,16 @@ func parseFlags(
 
 	flagset.Usage = func() {
 		_, _ = fmt.Fprint(flagset.Output(),
-			"Usage: gad [flags] [SCRIPT_FILE [ARGS...]]\n\n",
+			"Usage: gad [flags] [SCRIPT_FILE [ARGS...]]\n",
+			"       gad [flags] -e EXPR [ARGS...]\n",
+			"       gad pack [flags] DIR\n",
+			"       gad mod tidy [flags] SCRIPT_FILE...\n",
+			"       gad disasm [flags] SCRIPT_FILE\n",
+			"       gad json [flags] EXPR\n",
+			"       gad debug SCRIPT_FILE [ARGS...]\n",
+			"       gad check [flags] SCRIPT_FILE...\n",
+			"       gad lint [flags] SCRIPT_FILE...\n",
+			"       gad serve [flags] SCRIPT_FILE [ARGS...]\n\n",
 			"If script file is not provided, REPL terminal application is started.\n\n",
 			"If script file is provided, pass named params with '--NAME=VALUE' named flags '--NAME'.\n",
 			"  Script example for join arguments:\n\n",
@@ -556,6 +855,9 @@ func parseFlags(
 			"    param (*args, sep=\",\", ln=no)\n",
 			"    if !args { return }\n    for _, arg in args[:-1] { print(arg, sep) }\n    print(args[-1])\n    if ln { println() }\n\n",
 			"Use - to read from stdin\n\n",
+			"The globals stdin, stdout and stderr are the standard streams, so scripts can be used in pipelines:\n\n",
+			"    cat app.log | gad -e 'for l in lines(stdin) { if contains(l, \"ERROR\") { println(l) } }'\n",
+			"    seq 10 | gad -e 'reduce(lines(stdin), (s, l, i) => s + int(l), 0)'\n\n",
 			"\nFlags:\n",
 		)
 		flagset.PrintDefaults()
@@ -565,20 +867,46 @@ func parseFlags(
 		return
 	}
 
+	if pkgs != "" {
+		if packages, err = loadPackages(strings.Split(pkgs, ",")); err != nil {
+			return
+		}
+	}
+
+	if noOptimizer {
+		passes = []string{}
+	} else if passes, err = gad.ParseOptimizerPasses(passList); err != nil {
+		return
+	}
+
 	if trace != "" {
 		traceEnabled = true
-		trace = "," + trace + ","
-		if strings.Contains(trace, ",parser,") {
-			traceParser = true
-		}
-		if strings.Contains(trace, ",optimizer,") {
-			traceOptimizer = true
-		}
-		if strings.Contains(trace, ",compiler,") {
-			traceCompiler = true
+		for _, unit := range strings.Split(trace, ",") {
+			switch unit = strings.TrimSpace(unit); unit {
+			case "parser":
+				traceParser = true
+			case "optimizer":
+				traceOptimizer = true
+			case "compiler":
+				traceCompiler = true
+			default:
+				if pass, ok := strings.CutPrefix(unit, "optimizer:"); ok {
+					var p []string
+					if p, err = gad.ParseOptimizerPasses(pass); err != nil {
+						return
+					}
+					traceOptimizer = true
+					tracePasses = append(tracePasses, p...)
+				}
+			}
 		}
 	}
 
+	if evalExpr != "" {
+		params = flagset.Args()
+		return
+	}
+
 	if flagset.NArg() < 1 {
 		return
 	}
@@ -620,7 +948,7 @@ func newScript(ctx context.Context, modulePath string, workdir string, script []
 	return &Script{ctx: ctx, modulePath: modulePath, workdir: workdir, script: script, traceOut: traceOut, sourcePath: &sourcePath}
 }
 
-func (s *Script) execute() error {
+func (s *Script) compileOptions() gad.CompileOptions {
 	opts := gad.CompileOptions{
 		CompilerOptions: gad.DefaultCompilerOptions,
 	}
@@ -636,15 +964,17 @@ func (s *Script) execute() error {
 		opts.TraceParser = traceParser
 		opts.TraceCompiler = traceCompiler
 		opts.TraceOptimizer = traceOptimizer
+		opts.TracePasses = tracePasses
 	}
+	opts.OptimizerPasses = passes
+	return opts
+}
 
-	bc, err := gad.Compile(s.script, opts)
-	if err != nil {
-		return err
-	}
-
-	namedArgs := make(gad.Dict)
-	args := make(gad.Array, 0)
+// parseArgs returns the positional arguments and the '--NAME=VALUE' and
+// '--NAME' named arguments of the script.
+func (s *Script) parseArgs() (args gad.Array, namedArgs gad.Dict) {
+	namedArgs = make(gad.Dict)
+	args = make(gad.Array, 0)
 
 	if numArgs := len(s.args); numArgs > 0 {
 		var newArgs []gad.Object
@@ -674,6 +1004,16 @@ func (s *Script) execute() error {
 		}
 		args = newArgs
 	}
+	return
+}
+
+func (s *Script) execute() error {
+	bc, err := gad.CachedCompile(s.script, s.compileOptions(), cacheDir)
+	if err != nil {
+		return err
+	}
+
+	args, namedArgs := s.parseArgs()
 
 	if requiredParams := bc.Main.Params.RequiredCount(); requiredParams > 0 {
 		if len(args) < requiredParams {
@@ -715,12 +1055,27 @@ func (s *Script) execute() error {
 	return err
 }
 
-func hasMode(f *os.File, m os.FileMode) bool {
-	info, err := f.Stat()
-	if err != nil {
-		return false
+// eval runs the script as an expression and writes its result to out if it is
+// not nil.
+func (s *Script) eval(out io.Writer) (err error) {
+	args, namedArgs := s.parseArgs()
+	e := gad.NewEval(s.compileOptions(), &gad.RunOpts{
+		Globals:   scriptGlobals,
+		Args:      gad.Args{args},
+		NamedArgs: gad.NewNamedArgs(namedArgs.ToKeyValueArray()),
+	})
+	defer func() {
+		if err2 := e.Close(); err == nil {
+			err = err2
+		}
+	}()
+
+	ret, _, err := e.Run(s.ctx, s.script)
+	if err != nil || ret == nil || ret == gad.Nil {
+		return
 	}
-	return info.Mode()&m == m
+	_, err = fmt.Fprintln(out, ret.ToString())
+	return
 }
 
 func hasInputRedirection() bool {
@@ -746,12 +1101,67 @@ func setTerminalTitle(title string) {
 }
 
 func main() {
+	errPrinter.color = useColor(false)
+	if len(os.Args) > 1 && os.Args[1] == "pack" {
+		checkErr(runPack(os.Args[2:], os.Stdout), nil)
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "mod" {
+		checkErr(runMod(os.Args[2:], os.Stdout), nil)
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "disasm" {
+		checkErr(runDisasm(os.Args[2:], os.Stdout), nil)
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "check" {
+		err := runCheck(os.Args[2:], os.Stdout)
+		if errors.Is(err, errCheckFailed) {
+			os.Exit(1)
+		}
+		checkErr(err, nil)
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "lint" {
+		err := runLint(os.Args[2:], os.Stdout)
+		if errors.Is(err, errLintFailed) {
+			os.Exit(1)
+		}
+		checkErr(err, nil)
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "serve" {
+		checkErr(runServe(os.Args[2:], os.Stdout), nil)
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "debug" {
+		checkErr(runDebug(os.Args[2:], os.Stdin, os.Stdout), nil)
+		return
+	}
+	if len(os.Args) > 1 && (os.Args[1] == "json" || os.Args[1] == "-json") {
+		checkErr(runJSON(os.Args[2:], os.Stdin, os.Stdout), nil)
+		return
+	}
+
 	filePath, timeout, args, err := parseFlags(flag.CommandLine, os.Args[1:])
 	checkErr(err, nil)
+	errPrinter.color = useColor(noColor)
 
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
+	if evalExpr != "" {
+		if timeout > 0 {
+			ctx, cancel = context.WithTimeout(ctx, timeout)
+			defer cancel()
+		}
+		errPrinter.sources["(expr)"] = []byte(evalExpr)
+		s := newScript(ctx, "(expr)", ".", []byte(evalExpr), os.Stdout)
+		s.args = args
+		checkErr(s.eval(os.Stdout), cancel)
+		return
+	}
+
 	if len(filePath) == 0 && hasInputRedirection() {
 		filePath = "-"
 	}
@@ -777,6 +1187,25 @@ func main() {
 		importers.Shebang2Slashes(script)
 
 		checkErr(err, cancel)
+		errPrinter.sources[path.Clean(modulePath)] = script
+
+		moduleLock, err = importers.LoadLock(filepath.Join(workdir, importers.LockFile))
+		checkErr(err, cancel)
+
+		if vet {
+			var diagnostics []*checker.Diagnostic
+			diagnostics, err = checker.CheckSource(modulePath, script)
+			checkErr(err, cancel)
+			for _, d := range diagnostics {
+				_, _ = fmt.Fprintln(os.Stderr, d)
+			}
+			if len(diagnostics) > 0 {
+				cancel()
+				os.Exit(1)
+			}
+			return
+		}
+
 		s := newScript(ctx, modulePath, workdir, script, os.Stdout)
 		s.args = args
 		err = s.execute()
@@ -784,7 +1213,7 @@ func main() {
 		return
 	}
 
-	if !hasMode(os.Stdout, os.ModeCharDevice) {
+	if !term.IsTerminal(os.Stdout) {
 		_, _ = fmt.Fprintln(os.Stderr, "not a terminal")
 		os.Exit(1)
 	}
@@ -825,8 +1254,16 @@ func checkErr(err error, fn func()) {
 		return
 	}
 
+	var exit *gad.ExitError
+	if errors.As(err, &exit) {
+		if fn != nil {
+			fn()
+		}
+		os.Exit(exit.Code)
+	}
+
 	defer os.Exit(1)
-	_, _ = fmt.Fprintf(os.Stderr, "%+v\n", err)
+	errPrinter.print(os.Stderr, err)
 	if fn != nil {
 		fn()
 	}
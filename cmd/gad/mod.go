@@ -0,0 +1,74 @@
+//go:build !js
+// +build !js
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/gad-lang/gad"
+	"github.com/gad-lang/gad/importers"
+)
+
+// moduleLock is the lock of versioned imports used by the file importer.
+var moduleLock *importers.Lock
+
+// runMod runs `gad mod` commands.
+func runMod(args []string, out io.Writer) error {
+	var (
+		flagset = flag.NewFlagSet("mod", flag.ContinueOnError)
+		lock    string
+	)
+	flagset.SetOutput(out)
+	flagset.StringVar(&lock, "lock", importers.LockFile, `Lock file`)
+	flagset.Usage = func() {
+		_, _ = fmt.Fprint(flagset.Output(),
+			"Usage: gad mod tidy [flags] SCRIPT_FILE...\n\n",
+			"Resolves versioned imports like import(\"lib@^1.2\") of the scripts and\n",
+			"writes the resolved versions to the lock file.\n\n",
+			"Flags:\n",
+		)
+		flagset.PrintDefaults()
+	}
+	if len(args) == 0 || args[0] != "tidy" {
+		flagset.Usage()
+		return fmt.Errorf("mod: unknown command")
+	}
+	if err := flagset.Parse(args[1:]); err != nil {
+		return err
+	}
+	if flagset.NArg() == 0 {
+		flagset.Usage()
+		return fmt.Errorf("mod: SCRIPT_FILE is required")
+	}
+
+	old := moduleLock
+	defer func() { moduleLock = old }()
+	moduleLock = importers.NewLock()
+
+	for _, file := range flagset.Args() {
+		script, err := os.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		importers.Shebang2Slashes(script)
+
+		opts := gad.CompileOptions{CompilerOptions: gad.DefaultCompilerOptions}
+		opts.SymbolTable = defaultSymbolTable()
+		opts.ModuleMap = DefaultModuleMap(filepath.Dir(file), &sourcePath)
+		opts.Module = &gad.ModuleInfo{Name: file, File: "file:" + file}
+		if _, err = gad.Compile(script, opts); err != nil {
+			return err
+		}
+	}
+
+	if err := moduleLock.Save(lock); err != nil {
+		return err
+	}
+	_, err := fmt.Fprintf(out, "%d versioned imports locked in %s\n", len(moduleLock.Names()), lock)
+	return err
+}
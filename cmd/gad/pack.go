@@ -0,0 +1,85 @@
+//go:build !js
+// +build !js
+
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/gad-lang/gad"
+	"github.com/gad-lang/gad/gadpkg"
+)
+
+// runPack runs `gad pack` command which builds a package archive from the
+// script files of a directory.
+func runPack(args []string, out io.Writer) error {
+	var (
+		flagset = flag.NewFlagSet("pack", flag.ContinueOnError)
+		output  string
+		name    string
+		version string
+	)
+	flagset.SetOutput(out)
+	flagset.StringVar(&output, "o", "", `Output file (default: NAME`+gadpkg.Ext+`)`)
+	flagset.StringVar(&name, "name", "", `Package name (default: base name of DIR)`)
+	flagset.StringVar(&version, "version", "", `Package version`)
+	flagset.Usage = func() {
+		_, _ = fmt.Fprint(flagset.Output(),
+			"Usage: gad pack [flags] DIR\n\n",
+			"Compiles the .gad files of DIR into a package archive.\n\n",
+			"Flags:\n",
+		)
+		flagset.PrintDefaults()
+	}
+	if err := flagset.Parse(args); err != nil {
+		return err
+	}
+	if flagset.NArg() != 1 {
+		flagset.Usage()
+		return fmt.Errorf("pack: DIR is required")
+	}
+
+	dir := flagset.Arg(0)
+	if name == "" {
+		abs, err := filepath.Abs(dir)
+		if err != nil {
+			return err
+		}
+		name = filepath.Base(abs)
+	}
+	if output == "" {
+		output = name + gadpkg.Ext
+	}
+
+	var buf bytes.Buffer
+	m, err := gadpkg.Pack(&buf, dir, name, version, gad.CompilerOptions{
+		ModuleMap:     DefaultModuleMap(dir, &sourcePath),
+		OptimizeConst: !noOptimizer,
+		OptimizeExpr:  !noOptimizer,
+	})
+	if err != nil {
+		return err
+	}
+	if err = os.WriteFile(output, buf.Bytes(), 0o644); err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(out, "%s: %d modules packed into %s\n", m.Name, len(m.Modules), output)
+	return err
+}
+
+// loadPackages opens the package archives of paths.
+func loadPackages(paths []string) (pkgs []*gadpkg.Package, err error) {
+	for _, p := range paths {
+		var pkg *gadpkg.Package
+		if pkg, err = gadpkg.Open(p); err != nil {
+			return nil, err
+		}
+		pkgs = append(pkgs, pkg)
+	}
+	return
+}
@@ -0,0 +1,305 @@
+//go:build !js
+// +build !js
+
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/gad-lang/gad"
+	"github.com/gad-lang/gad/importers"
+	"github.com/gad-lang/gad/stdlib/json"
+)
+
+// service runs the lifecycle hooks exported by a `gad serve` script.
+type service struct {
+	vm         *gad.VM
+	hooks      gad.Dict
+	out        io.Writer
+	configFile string
+	timeout    time.Duration
+	done       chan struct{}
+	started    chan error
+	// hookMu serializes the calls of the hooks except start.
+	hookMu sync.Mutex
+
+	mu       sync.Mutex
+	config   gad.Object
+	stopping bool
+}
+
+// runServe runs `gad serve` command which runs a script as a long-running
+// service until SIGINT or SIGTERM is received.
+func runServe(args []string, out io.Writer) error {
+	var (
+		flagset    = flag.NewFlagSet("serve", flag.ContinueOnError)
+		configFile string
+		healthAddr string
+		timeout    time.Duration
+	)
+	flagset.SetOutput(out)
+	flagset.StringVar(&configFile, "config", "",
+		`JSON config file passed to the script, it is read again on SIGHUP`)
+	flagset.StringVar(&healthAddr, "health", "",
+		`Serve health endpoint GET /health at the address, e.g. ":8081"`)
+	flagset.DurationVar(&timeout, "timeout", 10*time.Second,
+		`Time to wait for start function to return after stop, then the script is aborted`)
+	flagset.Usage = func() {
+		_, _ = fmt.Fprint(flagset.Output(),
+			"Usage: gad serve [flags] SCRIPT_FILE [ARGS...]\n\n",
+			"Runs the script as a service. The script must return a dict with start(ctx)\n",
+			"function and optional stop(), reload(config) and health() functions.\n",
+			"start is called once, stop is called on SIGINT or SIGTERM and reload on SIGHUP.\n\n",
+			"Flags:\n",
+		)
+		flagset.PrintDefaults()
+	}
+	if err := flagset.Parse(args); err != nil {
+		return err
+	}
+	if flagset.NArg() < 1 {
+		flagset.Usage()
+		return fmt.Errorf("serve: SCRIPT_FILE is required")
+	}
+
+	sv, err := newService(flagset.Arg(0), flagset.Args()[1:], configFile, out)
+	if err != nil {
+		return err
+	}
+	sv.timeout = timeout
+
+	if healthAddr != "" {
+		srv := &http.Server{Addr: healthAddr, Handler: sv}
+		go func() {
+			if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				_, _ = fmt.Fprintf(out, "serve: health: %v\n", err)
+			}
+		}()
+		defer srv.Close()
+	}
+
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
+	defer signal.Stop(sigs)
+	return sv.run(sigs)
+}
+
+// newService runs the script file and returns the service of the dict it
+// returns.
+func newService(file string, args []string, configFile string, out io.Writer) (*service, error) {
+	script, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	importers.Shebang2Slashes(script)
+
+	s := newScript(context.Background(), file, filepath.Dir(file), script, out)
+	s.args = args
+	opts := s.compileOptions()
+	opts.Module = &gad.ModuleInfo{Name: file, File: "file:" + file}
+	bc, err := gad.Compile(script, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	sv := &service{
+		vm:         gad.NewVM(bc).SetRecover(true),
+		out:        out,
+		configFile: configFile,
+		timeout:    10 * time.Second,
+		done:       make(chan struct{}),
+		started:    make(chan error, 1),
+		config:     gad.Nil,
+	}
+	if sv.config, err = sv.readConfig(); err != nil {
+		return nil, err
+	}
+
+	scriptArgs, namedArgs := s.parseArgs()
+	ret, err := sv.vm.RunOpts(&gad.RunOpts{
+		Globals:   scriptGlobals,
+		Args:      gad.Args{scriptArgs},
+		NamedArgs: gad.NewNamedArgs(namedArgs.ToKeyValueArray()),
+		StdOut:    out,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	hooks, ok := ret.(gad.Dict)
+	if !ok || !gad.Callable(hooks["start"]) {
+		return nil, fmt.Errorf("serve: script must return a dict with start function, got %s",
+			ret.Type().Name())
+	}
+	for _, name := range []string{"stop", "reload", "health"} {
+		if fn, ok := hooks[name]; ok && !gad.Callable(fn) {
+			return nil, fmt.Errorf("serve: %s must be a function, got %s", name, fn.Type().Name())
+		}
+	}
+	sv.hooks = hooks
+	return sv, nil
+}
+
+// run calls start function and handles the signals until the service is
+// stopped.
+func (sv *service) run(sigs <-chan os.Signal) error {
+	defer sv.vm.Close()
+
+	go func() {
+		_, err := sv.call("start", sv.context())
+		sv.started <- err
+	}()
+
+	for {
+		select {
+		case err := <-sv.started:
+			// start function may return immediately after starting its
+			// workers, the service runs until it is stopped.
+			sv.started = nil
+			if err != nil {
+				return err
+			}
+		case sig := <-sigs:
+			if sig == syscall.SIGHUP {
+				if err := sv.reload(); err != nil {
+					_, _ = fmt.Fprintf(sv.out, "serve: reload: %v\n", err)
+				}
+				continue
+			}
+			return sv.stop()
+		}
+	}
+}
+
+// stop calls stop function and waits for start function to return until the
+// timeout, then the script is aborted.
+func (sv *service) stop() error {
+	sv.mu.Lock()
+	sv.stopping = true
+	sv.mu.Unlock()
+	close(sv.done)
+
+	_, err := sv.call("stop")
+	if sv.started == nil {
+		return err
+	}
+
+	select {
+	case startErr := <-sv.started:
+		if err == nil {
+			err = startErr
+		}
+	case <-time.After(sv.timeout):
+		sv.vm.Abort()
+	}
+	return err
+}
+
+// reload reads the config file again and calls reload function with it.
+func (sv *service) reload() error {
+	config, err := sv.readConfig()
+	if err != nil {
+		return err
+	}
+	sv.mu.Lock()
+	sv.config = config
+	sv.mu.Unlock()
+	_, err = sv.call("reload", config)
+	return err
+}
+
+// ServeHTTP implements http.Handler for the health endpoint. The service is
+// healthy if it is not stopping and health function, if any, returns a truthy
+// value.
+func (sv *service) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/health" {
+		http.NotFound(w, r)
+		return
+	}
+
+	sv.mu.Lock()
+	stopping := sv.stopping
+	sv.mu.Unlock()
+	if stopping {
+		http.Error(w, "stopping", http.StatusServiceUnavailable)
+		return
+	}
+
+	ret, err := sv.call("health")
+	switch {
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+	case ret != nil && ret.IsFalsy():
+		http.Error(w, "unhealthy", http.StatusServiceUnavailable)
+	default:
+		_, _ = fmt.Fprintln(w, "ok")
+	}
+}
+
+// context returns the ctx argument of start function.
+func (sv *service) context() gad.Dict {
+	return gad.Dict{
+		"config": &gad.Function{
+			Name: "config",
+			Value: func(gad.Call) (gad.Object, error) {
+				sv.mu.Lock()
+				defer sv.mu.Unlock()
+				return sv.config, nil
+			},
+		},
+		"done": &gad.Function{
+			Name: "done",
+			Value: func(gad.Call) (gad.Object, error) {
+				select {
+				case <-sv.done:
+					return gad.True, nil
+				default:
+					return gad.False, nil
+				}
+			},
+		},
+		"wait": &gad.Function{
+			Name: "wait",
+			Value: func(gad.Call) (gad.Object, error) {
+				<-sv.done
+				return gad.Nil, nil
+			},
+		},
+	}
+}
+
+// call calls the hook function if it is exported, nil object is returned
+// otherwise. Only start function runs concurrently with the other hooks.
+func (sv *service) call(name string, args ...gad.Object) (gad.Object, error) {
+	fn, ok := sv.hooks[name]
+	if !ok {
+		return nil, nil
+	}
+	if name != "start" {
+		sv.hookMu.Lock()
+		defer sv.hookMu.Unlock()
+	}
+	return gad.NewInvoker(sv.vm, fn).Invoke(gad.Args{args}, nil)
+}
+
+func (sv *service) readConfig() (gad.Object, error) {
+	if sv.configFile == "" {
+		return gad.Nil, nil
+	}
+	data, err := os.ReadFile(sv.configFile)
+	if err != nil {
+		return nil, err
+	}
+	return json.Unmarshal(data, json.NewDecodeOptions())
+}
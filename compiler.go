@@ -11,6 +11,7 @@ import (
 	"io"
 	"os"
 	"reflect"
+	"sort"
 
 	"github.com/gad-lang/gad/parser"
 	"github.com/gad-lang/gad/parser/ast"
@@ -63,12 +64,17 @@ type (
 		moduleMap      *ModuleMap
 		moduleStore    *moduleStore
 		module         *ModuleInfo
+		importIndex    int
+		captures       []localCapture
 		variadic       bool
 		varNamedParams bool
 		loops          []*loopStmts
 		loopIndex      int
 		tryCatchIndex  int
 		iotaVal        int
+		funcName       string
+		moduleMain     bool
+		returnType     []*SymbolInfo
 		opts           CompilerOptions
 		trace          io.Writer
 		indent         int
@@ -78,23 +84,38 @@ type (
 
 	// CompilerOptions represents customizable options for Compile().
 	CompilerOptions struct {
-		Context             context.Context
-		ModuleMap           *ModuleMap
-		Module              *ModuleInfo
-		ModuleFile          string
-		Constants           []Object
-		SymbolTable         *SymbolTable
-		Trace               io.Writer
-		TraceParser         bool
-		TraceCompiler       bool
-		TraceOptimizer      bool
-		OptimizerMaxCycle   int
-		OptimizeConst       bool
-		OptimizeExpr        bool
+		Context           context.Context
+		ModuleMap         *ModuleMap
+		Module            *ModuleInfo
+		ModuleFile        string
+		Constants         []Object
+		SymbolTable       *SymbolTable
+		Trace             io.Writer
+		TraceParser       bool
+		TraceCompiler     bool
+		TraceOptimizer    bool
+		OptimizerMaxCycle int
+		OptimizeConst     bool
+		OptimizeExpr      bool
+		// ParallelImports compiles the imported source modules concurrently
+		// before the script, see Compile. The imports are found by scanning
+		// the sources, so modules which are not imported like the ones in dead
+		// code are also read by their importers.
+		ParallelImports     bool
+		OptimizerPasses     []string
+		TracePasses         []string
 		MixedWriteFunction  node.Expr
 		MixedExprToTextFunc node.Expr
-		moduleStore         *moduleStore
-		constsCache         map[Object]int
+		// Deprecations are the deprecated builtins and module members which
+		// are warned by Warn when they are used.
+		Deprecations Deprecations
+		// Warn is called for every compiler warning if it is not nil.
+		Warn        func(w *CompilerWarning)
+		moduleStore *moduleStore
+		constsCache map[Object]int
+		// exprMode is set by CompileExpr. It disables imports and resolves
+		// unknown identifiers as globals.
+		exprMode bool
 	}
 
 	// CompilerError represents a compiler error.
@@ -109,15 +130,33 @@ type (
 		typ           int
 		constantIndex int
 		moduleIndex   int
+		importIndex   int
 		name          string
 	}
 
+	// moduleImport records an import of a module to validate the cached
+	// bytecode, see CachedCompile.
+	moduleImport struct {
+		// Parent is the index of the import whose module imports this module
+		// or -1 if it is imported by the main module.
+		Parent   int    `json:"parent"`
+		Name     string `json:"name"`
+		Resolved string `json:"resolved"`
+		// Sum is the hex encoded SHA-256 digest of the module source. It is
+		// empty if the module is an Object or it was imported before.
+		Sum string `json:"sha256,omitempty"`
+	}
+
 	// moduleStore represents modules indexes and total count that are defined
 	// while compiling.
 	moduleStore struct {
-		count int
-		store map[string]*moduleStoreItem
-		items []*moduleStoreItem
+		count   int
+		store   map[string]*moduleStoreItem
+		items   []*moduleStoreItem
+		imports []moduleImport
+		// precompiled are the modules read and compiled before compiling
+		// the script by name, see precompileImports.
+		precompiled map[string]*precompiledModule
 	}
 
 	// loopStmts represents a loopStmts construct that the compiler uses to
@@ -180,6 +219,7 @@ func NewCompiler(file *parser.SourceFile, opts CompilerOptions) *Compiler {
 		moduleMap:     opts.ModuleMap,
 		moduleStore:   opts.moduleStore,
 		module:        opts.Module,
+		importIndex:   -1,
 		loopIndex:     -1,
 		tryCatchIndex: -1,
 		iotaVal:       -1,
@@ -194,8 +234,19 @@ type CompileOptions struct {
 	ScannerOptions parser.ScannerOptions
 }
 
-// Compile compiles given script to Bytecode.
+// Compile compiles given script to Bytecode. If opts.ParallelImports is set,
+// the imported source modules which do not import other source modules are
+// compiled concurrently before compiling the script.
 func Compile(script []byte, opts CompileOptions) (*Bytecode, error) {
+	srcFile, pf, err := parseScript(script, &opts)
+	if err != nil {
+		return nil, err
+	}
+	bc, _, err := compileFileImports(script, srcFile, pf, opts)
+	return bc, err
+}
+
+func parseScript(script []byte, opts *CompileOptions) (*parser.SourceFile, *parser.File, error) {
 	var (
 		fileSet    = parser.NewFileSet()
 		moduleName string
@@ -217,42 +268,78 @@ func Compile(script []byte, opts CompileOptions) (*Bytecode, error) {
 	p := parser.NewParserWithOptions(srcFile, script, &opts.ParserOptions, &opts.ScannerOptions)
 	pf, err := p.ParseFile()
 	if err != nil {
-		return nil, err
+		return nil, nil, err
+	}
+	return srcFile, pf, nil
+}
+
+func compileFile(srcFile *parser.SourceFile, pf *parser.File, opts CompileOptions) (*Bytecode, error) {
+	bc, _, err := compileFileImports(nil, srcFile, pf, opts)
+	return bc, err
+}
+
+// compileFileImports compiles the file parsed from script and also returns the
+// imports of the modules.
+func compileFileImports(
+	script []byte,
+	srcFile *parser.SourceFile,
+	pf *parser.File,
+	opts CompileOptions,
+) (*Bytecode, []moduleImport, error) {
+	if err := checkOptimizerPasses(opts.OptimizerPasses); err != nil {
+		return nil, nil, err
+	}
+	if err := checkOptimizerPasses(opts.TracePasses); err != nil {
+		return nil, nil, err
 	}
 
 	compiler := NewCompiler(srcFile, opts.CompilerOptions)
 	compiler.SetGlobalSymbolsIndex()
 
-	if opts.OptimizeConst || opts.OptimizeExpr {
+	if opts.ParallelImports && script != nil && !opts.TraceParser &&
+		!opts.TraceOptimizer && !opts.TraceCompiler {
+		compiler.precompileImports(script, &opts.ScannerOptions)
+	}
+
+	if opts.passEnabled(PassConstFold) {
 		err := compiler.optimize(pf)
 		if err != nil && err != errSkip {
-			return nil, err
+			return nil, nil, err
 		}
 	}
 
 	if err := compiler.Compile(pf); err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	bc := compiler.Bytecode()
 	if bc.Main.NumLocals > 256 {
-		return nil, ErrSymbolLimit
+		return nil, nil, ErrSymbolLimit
 	}
-	return bc, nil
+	runBytecodePasses(bc, len(opts.Constants), &opts.CompilerOptions)
+	return bc, compiler.moduleStore.imports, nil
 }
 
 // SetGlobalSymbolsIndex sets index of a global symbol. This is only required
 // when a global symbol is defined in SymbolTable and provided to compiler.
 // Otherwise, caller needs to append the constant to Constants, set the symbol
 // index and provide it to the Compiler. This should be called before
-// Compiler.Compile call.
+// Compiler.Compile call. Indexes are set in the order of symbol names.
 func (c *Compiler) SetGlobalSymbolsIndex() {
-	symbols := c.symbolTable.Symbols()
-	for _, s := range symbols {
+	var symbols []*Symbol
+	for _, s := range c.symbolTable.Symbols() {
 		if s.Scope == ScopeGlobal && s.Index == -1 {
-			s.Index = c.addConstant(Str(s.Name))
+			symbols = append(symbols, s)
 		}
 	}
+
+	sort.Slice(symbols, func(i, j int) bool {
+		return symbols[i].Name < symbols[j].Name
+	})
+
+	for _, s := range symbols {
+		s.Index = c.addConstant(Str(s.Name))
+	}
 }
 
 // optimize runs the Optimizer and returns Optimizer object and error from Optimizer.
@@ -300,6 +387,7 @@ func (c *Compiler) Bytecode() *Bytecode {
 	}
 
 	if lastOp != OpReturn || len(jumpPos) > 0 {
+		c.emitModuleHooks(nil)
 		c.emit(nil, OpReturn, 0)
 	}
 
@@ -307,17 +395,20 @@ func (c *Compiler) Bytecode() *Bytecode {
 		Params:       c.symbolTable.params,
 		NamedParams:  c.symbolTable.namedParams,
 		NumLocals:    c.symbolTable.maxDefinition,
+		LocalNames:   c.symbolTable.LocalNames(),
 		Instructions: c.instructions,
 		SourceMap:    c.sourceMap,
+		ReturnType:   c.returnType,
 		sourceFile:   c.file,
 		module:       c.module,
 	}
 
 	return &Bytecode{
-		FileSet:    c.file.Set(),
-		Constants:  c.constants,
-		Main:       cf,
-		NumModules: c.moduleStore.count,
+		FileSet:     c.file.Set(),
+		Constants:   c.constants,
+		Main:        cf,
+		NumModules:  c.moduleStore.count,
+		ModuleNames: c.moduleStore.names(),
 	}
 }
 
@@ -552,6 +643,9 @@ func (c *Compiler) Compile(nd ast.Node) error {
 		if nt.Options.ExprToTextFunc != nil {
 			c.opts.MixedExprToTextFunc = nt.Options.ExprToTextFunc
 		}
+		if len(nt.Options.ImportPaths) > 0 {
+			c.moduleMap = c.moduleMap.WithImportPaths(nt.Options.ImportPaths...)
+		}
 	case nil:
 	default:
 		return c.errorf(nt, `%[1]T "%[1]v" not implemented`, nt)
@@ -592,6 +686,10 @@ func (c *Compiler) addConstant(obj Object) (index int) {
 		}
 	}()
 
+	if s, ok := obj.(Str); ok {
+		obj = Str(Intern(string(s)))
+	}
+
 	switch obj.(type) {
 	case Int, Uint, Str, RawStr, Bool, Flag, Float, Char, *NilType, Decimal:
 		i, ok := c.constsCache[obj]
@@ -679,15 +777,6 @@ func (c *Compiler) addInstruction(b []byte) int {
 	return posNewIns
 }
 
-func (c *Compiler) checkCyclicImports(nd ast.Node, modulePath string) error {
-	if c.module.Name == modulePath {
-		return c.errorf(nd, "cyclic module import: %s", modulePath)
-	} else if c.parent != nil {
-		return c.parent.checkCyclicImports(nd, modulePath)
-	}
-	return nil
-}
-
 func (c *Compiler) addModule(name string, typ, constantIndex int) *moduleStoreItem {
 	moduleIndex := c.moduleStore.count
 	c.moduleStore.count++
@@ -702,6 +791,22 @@ func (c *Compiler) addModule(name string, typ, constantIndex int) *moduleStoreIt
 	return item
 }
 
+// addImport records the import of the module resolved to name and returns
+// the index of the record.
+func (c *Compiler) addImport(importName, name string) int {
+	for i, imp := range c.moduleStore.imports {
+		if imp.Parent == c.importIndex && imp.Name == importName {
+			return i
+		}
+	}
+	c.moduleStore.imports = append(c.moduleStore.imports, moduleImport{
+		Parent:   c.importIndex,
+		Name:     importName,
+		Resolved: name,
+	})
+	return len(c.moduleStore.imports) - 1
+}
+
 func (c *Compiler) getModule(name string) (*moduleStoreItem, bool) {
 	indexes, ok := c.moduleStore.store[name]
 	return indexes, ok
@@ -744,6 +849,10 @@ func (c *Compiler) CompileModule(
 		DisableBuiltin(c.symbolTable.DisabledBuiltins()...)
 
 	fork := c.fork(modFile, module, moduleMap, symbolTable)
+	fork.moduleMain = true
+	if item, ok := c.moduleStore.store[module.Name]; ok {
+		fork.importIndex = item.importIndex
+	}
 	err = fork.optimize(file)
 	if err != nil && err != errSkip {
 		err = c.error(nd, err)
@@ -757,34 +866,39 @@ func (c *Compiler) CompileModule(
 	return
 }
 
+// compileModule compiles the module and sets its main function to the
+// constant at index which is reserved for the module.
 func (c *Compiler) compileModule(
 	nd ast.Node,
 	importable Importable,
 	module *ModuleInfo,
 	moduleMap *ModuleMap,
 	src []byte,
-) (int, error) {
-	var err error
-	if err = c.checkCyclicImports(nd, module.Name); err != nil {
-		return 0, err
-	}
-
-	var bc *Bytecode
+	index int,
+) (err error) {
+	var (
+		bc     *Bytecode
+		linked bool
+	)
 	if cimp, ok := importable.(CompilableImporter); ok {
 		if bc, err = cimp.CompileModule(c, nd, module, moduleMap, src); err != nil {
-			return 0, err
+			return
+		}
+	} else if bc, linked, err = c.linkPrecompiled(module, src); err != nil {
+		return
+	} else if !linked {
+		if bc, err = c.CompileModule(nd, module, moduleMap, src, nil, nil); err != nil {
+			return
 		}
-	} else if bc, err = c.CompileModule(nd, module, moduleMap, src, nil, nil); err != nil {
-		return 0, err
 	}
 
 	if bc.Main.NumLocals > 256 {
-		return 0, c.error(nd, ErrSymbolLimit)
+		return c.error(nd, ErrSymbolLimit)
 	}
 
 	c.constants = bc.Constants
-	index := c.addConstant(bc.Main)
-	return index, nil
+	c.constants[index] = bc.Main
+	return
 }
 
 func (c *Compiler) enterLoop() *loopStmts {
@@ -819,25 +933,15 @@ func (c *Compiler) fork(
 	moduleMap *ModuleMap,
 	symbolTable *SymbolTable,
 ) *Compiler {
-	child := NewCompiler(file, CompilerOptions{
-		Context:           c.opts.Context,
-		ModuleMap:         moduleMap,
-		Module:            module,
-		Constants:         c.constants,
-		SymbolTable:       symbolTable,
-		Trace:             c.trace,
-		TraceParser:       c.opts.TraceParser,
-		TraceCompiler:     c.opts.TraceCompiler,
-		TraceOptimizer:    c.opts.TraceOptimizer,
-		OptimizerMaxCycle: c.opts.OptimizerMaxCycle,
-		OptimizeConst:     c.opts.OptimizeConst,
-		OptimizeExpr:      c.opts.OptimizeExpr,
-		moduleStore:       c.moduleStore,
-		constsCache:       c.constsCache,
-	})
+	opts := c.moduleOptions(module, moduleMap, symbolTable)
+	opts.Constants = c.constants
+	opts.moduleStore = c.moduleStore
+	opts.constsCache = c.constsCache
+	child := NewCompiler(file, opts)
 
 	child.parent = c
 	child.cfuncCache = c.cfuncCache
+	child.importIndex = c.importIndex
 
 	if module.Name == c.module.Name {
 		child.indent = c.indent
@@ -845,6 +949,20 @@ func (c *Compiler) fork(
 	return child
 }
 
+// moduleOptions returns the options of the compiler of a module, which are
+// the options of c with the module, its module map and symbol table.
+func (c *Compiler) moduleOptions(module *ModuleInfo, moduleMap *ModuleMap, symbolTable *SymbolTable) CompilerOptions {
+	opts := c.opts
+	opts.ModuleMap = moduleMap
+	opts.Module = module
+	opts.ModuleFile = ""
+	opts.Constants = nil
+	opts.SymbolTable = symbolTable
+	opts.moduleStore = nil
+	opts.constsCache = nil
+	return opts
+}
+
 func (c *Compiler) error(nd ast.Node, err error) error {
 	return &CompilerError{
 		FileSet: c.file.Set(),
@@ -943,7 +1061,8 @@ func MakeInstruction(buf []byte, op Opcode, args ...int) ([]byte, error) {
 	case OpEqual, OpNotEqual, OpNil, OpTrue, OpFalse, OpYes, OpNo, OpPop, OpSliceIndex,
 		OpSetIndex, OpIterInit, OpIterNext, OpIterKey, OpIterValue,
 		OpSetupCatch, OpSetupFinally, OpNoOp, OpCallee, OpArgs, OpNamedArgs,
-		OpStdIn, OpStdOut, OpStdErr, OpIsNil, OpNotIsNil, OpDotName, OpDotFile, OpIsModule:
+		OpStdIn, OpStdOut, OpStdErr, OpIsNil, OpNotIsNil, OpDotName, OpDotFile, OpIsModule,
+		OpModuleHooks:
 		return buf, nil
 	default:
 		return buf, &Error{
@@ -1004,8 +1123,21 @@ func newModuleStore() *moduleStore {
 	}
 }
 
+func (ms *moduleStore) names() []string {
+	if ms.count == 0 {
+		return nil
+	}
+	names := make([]string, ms.count)
+	for _, item := range ms.items {
+		names[item.moduleIndex] = item.name
+	}
+	return names
+}
+
 func (ms *moduleStore) reset() *moduleStore {
 	ms.count = 0
+	ms.items = nil
+	ms.imports = nil
 	for k := range ms.store {
 		delete(ms.store, k)
 	}
@@ -0,0 +1,323 @@
+package gad
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// BytecodeCodec encodes and decodes Bytecode for CachedCompile.
+type BytecodeCodec interface {
+	// Version returns the version of the encoding format. Cached bytecode
+	// encoded with another version is not used.
+	Version() string
+	EncodeBytecode(bc *Bytecode, w io.Writer) error
+	DecodeBytecode(r io.Reader, modules *ModuleMap) (*Bytecode, error)
+}
+
+// DefaultBytecodeCodec is the codec used by CachedCompile. It is set by
+// importing the encoder package. If it is nil, CachedCompile does not cache.
+var DefaultBytecodeCodec BytecodeCodec
+
+// compileCacheVersion is changed if the compiler output changes to ignore
+// the entries written by the older versions.
+const compileCacheVersion = "gad-compile-cache-1"
+
+// compileCacheEntry is the header of a compile cache file which is followed by
+// the encoded bytecode.
+type compileCacheEntry struct {
+	Imports []moduleImport `json:"imports,omitempty"`
+	// Modules are the modules of the functions, Funcs are the indexes of the
+	// modules of the main function and the constants, -1 for no module.
+	Modules []ModuleInfo `json:"modules,omitempty"`
+	Funcs   []int        `json:"funcs,omitempty"`
+	// Sum is the hex encoded SHA-256 digest of the encoded bytecode.
+	Sum string `json:"sha256"`
+}
+
+// CachedCompile is like Compile but it stores the compiled bytecode in
+// cacheDir and returns the cached bytecode if the script, the options and the
+// sources of the imported modules are not changed, skipping parsing,
+// optimization and compilation.
+//
+// Bytecode is cached only if DefaultBytecodeCodec is set, cacheDir is not
+// empty, opts has no Constants, tracing is disabled and deprecations are not
+// warned; otherwise it calls
+// Compile. Unreadable or invalid cache entries are ignored and errors writing
+// the cache are not reported. On cache hits opts.SymbolTable is not updated.
+func CachedCompile(src []byte, opts CompileOptions, cacheDir string) (*Bytecode, error) {
+	codec := DefaultBytecodeCodec
+	if codec == nil || cacheDir == "" || len(opts.Constants) > 0 ||
+		opts.TraceParser || opts.TraceOptimizer || opts.TraceCompiler ||
+		(opts.Warn != nil && len(opts.Deprecations) > 0) {
+		return Compile(src, opts)
+	}
+
+	key := compileCacheKey(codec, src, &opts)
+	file := filepath.Join(cacheDir, key[:2], key)
+	if bc := readCompileCache(codec, file, &opts); bc != nil {
+		return bc, nil
+	}
+
+	srcFile, pf, err := parseScript(src, &opts)
+	if err != nil {
+		return nil, err
+	}
+	bc, imports, err := compileFileImports(src, srcFile, pf, opts)
+	if err != nil {
+		return nil, err
+	}
+	_ = writeCompileCache(codec, file, bc, imports)
+	return bc, nil
+}
+
+// compileCacheKey returns the hex encoded digest of the inputs of the
+// compiler.
+func compileCacheKey(codec BytecodeCodec, src []byte, opts *CompileOptions) string {
+	h := sha256.New()
+	writeString := func(s string) {
+		_ = binary.Write(h, binary.LittleEndian, int64(len(s)))
+		h.Write([]byte(s))
+	}
+	writeInt := func(v int64) {
+		_ = binary.Write(h, binary.LittleEndian, v)
+	}
+
+	writeString(compileCacheVersion)
+	writeString(codec.Version())
+	writeString(string(src))
+	if opts.Module != nil {
+		writeString(opts.Module.Name)
+		writeString(opts.Module.File)
+	} else {
+		writeString("")
+		writeString("")
+	}
+	writeInt(int64(opts.OptimizerMaxCycle))
+	writeInt(int64(boolToInt(opts.OptimizeConst)<<1 | boolToInt(opts.OptimizeExpr)))
+	for _, name := range OptimizerPassNames {
+		writeInt(int64(boolToInt(opts.passEnabled(name))))
+	}
+	writeInt(int64(opts.ParserOptions.Mode))
+	writeInt(int64(opts.ScannerOptions.Mode))
+	writeInt(int64(opts.ScannerOptions.MixedExprRune))
+
+	if st := opts.SymbolTable; st != nil {
+		for _, s := range st.Symbols() {
+			writeString(s.Name)
+			writeInt(int64(s.Scope))
+			writeInt(int64(s.Index))
+		}
+		disabled := st.DisabledBuiltins()
+		sort.Strings(disabled)
+		writeInt(int64(len(disabled)))
+		for _, name := range disabled {
+			writeString(name)
+		}
+		if st.builtins != nil {
+			hashBuiltins(st.builtins, writeString, writeInt)
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func hashBuiltins(b *Builtins, writeString func(string), writeInt func(int64)) {
+	names := make([]string, 0, len(b.Map))
+	for name := range b.Map {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	writeInt(int64(len(names)))
+	for _, name := range names {
+		writeString(name)
+		writeInt(int64(b.Map[name]))
+	}
+}
+
+func boolToInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// readCompileCache returns the bytecode cached in file or nil if it does not
+// exist or is out of date.
+func readCompileCache(codec BytecodeCodec, file string, opts *CompileOptions) *Bytecode {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil
+	}
+	i := bytes.IndexByte(data, '\n')
+	if i < 0 {
+		return nil
+	}
+
+	var entry compileCacheEntry
+	if err = json.Unmarshal(data[:i], &entry); err != nil {
+		return nil
+	}
+	data = data[i+1:]
+	if sum := sha256.Sum256(data); hex.EncodeToString(sum[:]) != entry.Sum {
+		return nil
+	}
+	if !validImports(entry.Imports, opts) {
+		return nil
+	}
+
+	bc, err := codec.DecodeBytecode(bytes.NewReader(data), opts.ModuleMap)
+	if err != nil || bc.Main == nil || len(entry.Funcs) != len(bc.Constants)+1 {
+		return nil
+	}
+	for i, fn := range compiledFunctions(bc) {
+		if fn == nil {
+			continue
+		}
+		if m := entry.Funcs[i]; m >= 0 && m < len(entry.Modules) {
+			fn.module = &entry.Modules[m]
+		}
+	}
+	return bc
+}
+
+// validImports reports whether the modules of imports are resolved to the
+// same modules having the same sources.
+func validImports(imports []moduleImport, opts *CompileOptions) bool {
+	maps := make([]*ModuleMap, len(imports))
+	for i, imp := range imports {
+		moduleMap := opts.ModuleMap
+		if imp.Parent >= i {
+			return false
+		} else if imp.Parent >= 0 {
+			moduleMap = maps[imp.Parent]
+		}
+
+		importer := moduleMap.Get(imp.Name)
+		if importer == nil {
+			return false
+		}
+
+		name := imp.Name
+		maps[i] = opts.ModuleMap
+		extImp, isExt := importer.(ExtImporter)
+		if isExt {
+			if resolved, _ := extImp.Name(); resolved != "" {
+				name = resolved
+			}
+			maps[i] = moduleMap.Fork(name)
+		}
+		if name != imp.Resolved {
+			return false
+		}
+		if imp.Sum == "" {
+			continue
+		}
+
+		mod, _, err := importer.Import(opts.Context, name)
+		if err != nil {
+			return false
+		}
+		src, ok := mod.([]byte)
+		if !ok {
+			return false
+		}
+		if sum := sha256.Sum256(src); hex.EncodeToString(sum[:]) != imp.Sum {
+			return false
+		}
+		if moduleMap.verifyChecksum(src, isExt, name, imp.Name) != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// writeCompileCache writes bc to the cache file atomically.
+func writeCompileCache(
+	codec BytecodeCodec,
+	file string,
+	bc *Bytecode,
+	imports []moduleImport,
+) (err error) {
+	entry := compileCacheEntry{Imports: imports}
+
+	// encoder clears module info of the functions, so copies are encoded
+	cp := *bc
+	cp.Constants = make([]Object, len(bc.Constants))
+	copy(cp.Constants, bc.Constants)
+	if bc.Main != nil {
+		cp.Main = bc.Main.Copy().(*CompiledFunction)
+	}
+	for i, o := range cp.Constants {
+		if fn, ok := o.(*CompiledFunction); ok {
+			cp.Constants[i] = fn.Copy()
+		}
+	}
+
+	modules := make(map[*ModuleInfo]int)
+	for _, fn := range compiledFunctions(bc) {
+		m := -1
+		if fn != nil && fn.module != nil {
+			var ok bool
+			if m, ok = modules[fn.module]; !ok {
+				m = len(entry.Modules)
+				modules[fn.module] = m
+				entry.Modules = append(entry.Modules, *fn.module)
+			}
+		}
+		entry.Funcs = append(entry.Funcs, m)
+	}
+
+	var buf bytes.Buffer
+	if err = codec.EncodeBytecode(&cp, &buf); err != nil {
+		return
+	}
+	sum := sha256.Sum256(buf.Bytes())
+	entry.Sum = hex.EncodeToString(sum[:])
+
+	if err = os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+		return
+	}
+	f, err := os.CreateTemp(filepath.Dir(file), "tmp-*")
+	if err != nil {
+		return
+	}
+	defer func() {
+		if err != nil {
+			_ = f.Close()
+			_ = os.Remove(f.Name())
+		}
+	}()
+
+	w := bufio.NewWriter(f)
+	if err = json.NewEncoder(w).Encode(&entry); err != nil {
+		return
+	}
+	if _, err = buf.WriteTo(w); err != nil {
+		return
+	}
+	if err = w.Flush(); err != nil {
+		return
+	}
+	if err = f.Close(); err != nil {
+		return
+	}
+	return os.Rename(f.Name(), file)
+}
+
+// compiledFunctions returns the main function followed by the constants of bc
+// which are compiled functions or nil.
+func compiledFunctions(bc *Bytecode) []*CompiledFunction {
+	fns := make([]*CompiledFunction, len(bc.Constants)+1)
+	fns[0] = bc.Main
+	for i, o := range bc.Constants {
+		fns[i+1], _ = o.(*CompiledFunction)
+	}
+	return fns
+}
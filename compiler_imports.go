@@ -0,0 +1,217 @@
+package gad
+
+import (
+	"bytes"
+	"runtime"
+	"strconv"
+	"sync"
+
+	"github.com/gad-lang/gad/parser"
+	"github.com/gad-lang/gad/token"
+)
+
+// precompiledModule is a module imported before compiling the importing
+// script. The result of the importer is kept, so the importer is called once
+// for every module. If bc is not nil, the source module is compiled separately
+// and linked into the compiler when it is imported.
+type precompiledModule struct {
+	mod       any
+	err       error
+	src       []byte
+	info      *ModuleInfo
+	moduleMap *ModuleMap
+	leaf      bool
+	bc        *Bytecode
+	imports   []moduleImport
+	// warnings are the warnings of compiling the module, they are reported
+	// when the module is linked.
+	warnings []*CompilerWarning
+}
+
+// precompileImports compiles the source modules imported by script and its
+// modules concurrently if they do not import other source modules. Modules
+// importing source modules are compiled by the compiler as usual to share the
+// modules imported by different modules. Imports are resolved and modules are
+// read in the calling goroutine because importers are not safe for concurrent
+// use. Modules which fail to compile are compiled again by the compiler to
+// report the errors.
+func (c *Compiler) precompileImports(script []byte, scannerOptions *parser.ScannerOptions) {
+	var (
+		modules  []*precompiledModule
+		imported = make(map[string]*precompiledModule)
+	)
+
+	// resolveImports returns the modules imported by src and reports whether
+	// src imports only Objects like builtin modules.
+	resolveImports := func(src []byte, moduleMap *ModuleMap, opts *parser.ScannerOptions) bool {
+		leaf := true
+		for _, name := range scanImports(src, opts) {
+			importer := moduleMap.Get(name)
+			if importer == nil {
+				leaf = false
+				continue
+			}
+			if _, ok := importer.(CompilableImporter); ok {
+				leaf = false
+				continue
+			}
+
+			forked := c.moduleMap
+			if extImp, ok := importer.(ExtImporter); ok {
+				leaf = false
+				resolved, err := extImp.Name()
+				if err != nil {
+					continue
+				} else if resolved != "" {
+					name = resolved
+				}
+				forked = moduleMap.Fork(name)
+			}
+
+			if m := imported[name]; m != nil {
+				if m.err != nil || m.src != nil {
+					leaf = false
+				}
+				continue
+			}
+			mod, url, err := importer.Import(c.opts.Context, name)
+			m := &precompiledModule{
+				mod:       mod,
+				err:       err,
+				info:      &ModuleInfo{Name: name, File: url},
+				moduleMap: forked,
+			}
+			imported[name] = m
+			if err != nil {
+				leaf = false
+				continue
+			}
+			if m.src, _ = mod.([]byte); m.src == nil {
+				continue
+			}
+			leaf = false
+			modules = append(modules, m)
+		}
+		return leaf
+	}
+
+	resolveImports(script, c.moduleMap, scannerOptions)
+	for i := 0; i < len(modules); i++ {
+		m := modules[i]
+		m.leaf = resolveImports(m.src, m.moduleMap, nil)
+	}
+
+	if len(imported) > 0 {
+		c.moduleStore.precompiled = imported
+	}
+
+	var (
+		wg  sync.WaitGroup
+		sem = make(chan struct{}, runtime.GOMAXPROCS(0))
+	)
+	for _, m := range modules {
+		if !m.leaf {
+			continue
+		}
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(m *precompiledModule) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			c.precompileModule(m)
+		}(m)
+	}
+	wg.Wait()
+}
+
+// importModule imports the module resolved to name. Modules imported by
+// precompileImports are not imported again.
+func (c *Compiler) importModule(importer Importable, name string) (any, string, error) {
+	if m := c.moduleStore.precompiled[name]; m != nil {
+		return m.mod, m.info.File, m.err
+	}
+	return importer.Import(c.opts.Context, name)
+}
+
+// precompileModule compiles the module like CompileModule but with its own
+// constants and modules. It sets m.bc if the module is compiled.
+func (c *Compiler) precompileModule(m *precompiledModule) {
+	fileSet := parser.NewFileSet()
+	file := fileSet.AddFile(m.info.Name, -1, len(m.src))
+	pf, err := parser.NewParserWithOptions(file, m.src, &parser.ParserOptions{}, nil).ParseFile()
+	if err != nil {
+		return
+	}
+
+	opts := c.moduleOptions(m.info, m.moduleMap, NewSymbolTable(c.symbolTable.builtins).
+		DisableBuiltin(c.symbolTable.DisabledBuiltins()...))
+	if opts.Warn != nil {
+		// Warn may not be safe for concurrent use
+		opts.Warn = func(w *CompilerWarning) {
+			m.warnings = append(m.warnings, w)
+		}
+	}
+	compiler := NewCompiler(file, opts)
+	compiler.moduleMain = true
+	// the builtin modules imported before are not imported again, the map is
+	// not changed while the modules are compiled
+	compiler.moduleStore.precompiled = c.moduleStore.precompiled
+	if err = compiler.optimize(pf); err != nil && err != errSkip {
+		return
+	}
+	if err = compiler.Compile(pf); err != nil {
+		return
+	}
+	m.bc = compiler.Bytecode()
+	m.imports = compiler.moduleStore.imports
+}
+
+// linkPrecompiled returns the precompiled bytecode of the module linked into
+// the compiler if the module with same source is precompiled.
+func (c *Compiler) linkPrecompiled(module *ModuleInfo, src []byte) (*Bytecode, bool, error) {
+	m := c.moduleStore.precompiled[module.Name]
+	if m == nil || m.bc == nil || !bytes.Equal(m.src, src) {
+		return nil, false, nil
+	}
+
+	importIndex := -1
+	if item, ok := c.moduleStore.store[module.Name]; ok {
+		importIndex = item.importIndex
+	}
+	for _, imp := range m.imports {
+		imp.Parent = importIndex
+		c.moduleStore.imports = append(c.moduleStore.imports, imp)
+	}
+
+	if c.opts.Warn != nil {
+		for _, w := range m.warnings {
+			c.opts.Warn(w)
+		}
+	}
+	bc, err := c.linkModule(m.bc, true)
+	return bc, true, err
+}
+
+// scanImports returns the names of the modules imported by src. It may return
+// names which are not imported like the ones in dead code.
+func scanImports(src []byte, opts *parser.ScannerOptions) (names []string) {
+	file := parser.NewFileSet().AddFile("", -1, len(src))
+	s := parser.NewScanner(file, src, opts)
+
+	var prev [2]parser.Token
+	for {
+		t := s.Scan()
+		if t.Token == token.EOF {
+			return
+		}
+		if t.Token == token.String && prev[1].Token == token.LParen &&
+			prev[0].Token == token.Import {
+			if name, err := strconv.Unquote(t.Literal); err == nil && name != "" {
+				names = append(names, name)
+			}
+		}
+		prev[0], prev[1] = prev[1], t
+	}
+}
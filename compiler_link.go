@@ -0,0 +1,186 @@
+package gad
+
+import (
+	"fmt"
+
+	"github.com/gad-lang/gad/parser"
+	"github.com/gad-lang/gad/parser/source"
+)
+
+// LinkModule links the module bytecode bc, which is compiled separately like
+// a module loaded from a package archive, into the compiler. Constants,
+// imported modules and source files of bc are appended to the compiler's ones
+// and instructions are relocated. Returned Bytecode can be returned by
+// CompilableImporter.CompileModule and its Main is the module function.
+func (c *Compiler) LinkModule(bc *Bytecode) (*Bytecode, error) {
+	return c.linkModule(bc, false)
+}
+
+// linkModule links bc into the compiler. If shared is true, modules of bc are
+// shared with the modules of the compiler having the same name.
+func (c *Compiler) linkModule(bc *Bytecode, shared bool) (*Bytecode, error) {
+	l := &linker{
+		constOffset:  len(c.constants),
+		moduleOffset: c.moduleStore.count,
+		files:        make(map[*parser.SourceFile]*parser.SourceFile),
+	}
+	if shared {
+		l.modules = make(map[int]int, bc.NumModules)
+		l.module = func(index, constantIndex int) int {
+			if m, ok := l.modules[index]; ok {
+				return m
+			}
+			var name string
+			if index < len(bc.ModuleNames) {
+				name = bc.ModuleNames[index]
+			}
+			item, ok := c.getModule(name)
+			if !ok {
+				item = c.addModule(name, 2, constantIndex)
+			}
+			l.modules[index] = item.moduleIndex
+			return item.moduleIndex
+		}
+	}
+
+	if bc.FileSet != nil && c.file != nil {
+		l.fileSet = bc.FileSet
+		for _, f := range bc.FileSet.Files {
+			nf := c.file.Set().AddFile(f.Name, -1, f.Size)
+			nf.Lines = append([]int{}, f.Lines...)
+			l.files[f] = nf
+		}
+	}
+
+	constants := c.constants
+	for _, o := range bc.Constants {
+		switch v := o.(type) {
+		case *CompiledFunction:
+			var err error
+			if o, err = l.function(v); err != nil {
+				return nil, err
+			}
+		case *CallerObjectWithMethods:
+			if fn, ok := v.CallerObject.(*CompiledFunction); ok {
+				linked, err := l.function(fn)
+				if err != nil {
+					return nil, err
+				}
+				cp := *v
+				cp.CallerObject = linked
+				o = &cp
+			}
+		}
+		constants = append(constants, o)
+	}
+
+	main, err := l.function(bc.Main)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := 0; i < bc.NumModules && !shared; i++ {
+		var name string
+		if i < len(bc.ModuleNames) {
+			name = bc.ModuleNames[i]
+		}
+		// modules of bc are not shared with the modules of the compiler
+		// which may have the same name but different content.
+		c.moduleStore.items = append(c.moduleStore.items, &moduleStoreItem{
+			moduleIndex: c.moduleStore.count,
+			name:        name,
+		})
+		c.moduleStore.count++
+	}
+
+	linked := &Bytecode{
+		Main:       main,
+		Constants:  constants,
+		NumModules: c.moduleStore.count,
+	}
+	if c.file != nil {
+		linked.FileSet = c.file.Set()
+	}
+	return linked, nil
+}
+
+type linker struct {
+	constOffset  int
+	moduleOffset int
+	fileSet      *parser.SourceFileSet
+	files        map[*parser.SourceFile]*parser.SourceFile
+	// modules maps the shared modules to the modules of the compiler, see
+	// linkModule.
+	modules map[int]int
+	module  func(index, constantIndex int) int
+}
+
+func (l *linker) pos(p int) int {
+	if l.fileSet == nil {
+		return p
+	}
+	if f := l.fileSet.File(source.Pos(p)); f != nil {
+		if nf := l.files[f]; nf != nil {
+			return p - f.Base + nf.Base
+		}
+	}
+	return p
+}
+
+func (l *linker) function(fn *CompiledFunction) (*CompiledFunction, error) {
+	cp := *fn
+	cp.Instructions = make([]byte, len(fn.Instructions))
+	copy(cp.Instructions, fn.Instructions)
+
+	var (
+		operands = make([]int, 0, 4)
+		inst     = make([]byte, 0, 8)
+		offset   int
+		err      error
+	)
+	for i := 0; i < len(cp.Instructions); i += offset + 1 {
+		op := Opcode(cp.Instructions[i])
+		operands, offset = ReadOperands(OpcodeOperands[op], cp.Instructions[i+1:], operands)
+
+		switch op {
+		case OpConstant, OpGetGlobal, OpSetGlobal, OpClosure:
+			operands[0] += l.constOffset
+		case OpLoadModule:
+			operands[0] += l.constOffset
+			if l.module != nil {
+				operands[1] = l.module(operands[1], operands[0])
+			} else {
+				operands[1] += l.moduleOffset
+			}
+		case OpStoreModule:
+			if l.module != nil {
+				operands[0] = l.module(operands[0], -1)
+			} else {
+				operands[0] += l.moduleOffset
+			}
+		default:
+			continue
+		}
+
+		if operands[0] > 1<<16-1 || (op == OpLoadModule && operands[1] > 1<<16-1) {
+			return nil, fmt.Errorf("link %s: %w", fn.Name, ErrSymbolLimit)
+		}
+		if inst, err = MakeInstruction(inst[:0], op, operands...); err != nil {
+			return nil, err
+		}
+		copy(cp.Instructions[i:], inst)
+	}
+
+	if fn.SourceMap != nil {
+		cp.SourceMap = make(map[int]int, len(fn.SourceMap))
+		for k, v := range fn.SourceMap {
+			cp.SourceMap[k] = l.pos(v)
+		}
+	}
+	if fn.sourceFile != nil {
+		if nf := l.files[fn.sourceFile]; nf != nil {
+			cp.sourceFile = nf
+		}
+	}
+	return &cp, nil
+}
@@ -5,6 +5,8 @@
 package gad
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"strconv"
 	"strings"
@@ -136,6 +138,7 @@ func (c *Compiler) compileTryStmt(nd *node.TryStmt) error {
 			symbol, exists := c.symbolTable.DefineLocal(nd.Catch.Ident.Name)
 			if exists {
 				c.emit(nd, OpSetLocal, symbol.Index)
+				symbol.mutated = true
 			} else {
 				c.emit(nd, OpDefineLocal, symbol.Index)
 			}
@@ -173,6 +176,7 @@ func (c *Compiler) compileCatchStmt(nd *node.CatchStmt) error {
 		symbol, exists := c.symbolTable.DefineLocal(nd.Ident.Name)
 		if exists {
 			c.emit(nd, OpSetLocal, symbol.Index)
+			symbol.mutated = true
 		} else {
 			c.emit(nd, OpDefineLocal, symbol.Index)
 		}
@@ -346,6 +350,15 @@ func (c *Compiler) compileDeclGlobal(nd *node.GenDecl) error {
 
 		idx := c.addConstant(Str(spec.Ident.Ident.Name))
 		symbol.Index = idx
+
+		if len(spec.Ident.Type) > 0 {
+			if _, symbol.Type, err = c.nameSymbolsOfTypedIdent(nd, spec.Ident); err != nil {
+				return err
+			}
+			if err = c.compileVarTypeCheck(spec.Ident.Ident, symbol.Type); err != nil {
+				return err
+			}
+		}
 	}
 	return nil
 }
@@ -391,11 +404,113 @@ func (c *Compiler) compileDeclValue(nd *node.GenDecl) error {
 			if err != nil {
 				return err
 			}
+
+			if isConst && ident.Name != "_" && c.opts.passEnabled(PassInline) {
+				if value, ok := literalValue(v); ok {
+					symbol, _ := c.symbolTable.Resolve(ident.Name)
+					symbol.value = value
+				}
+			}
+
+			if ti := spec.TypedIdent(i); len(ti.Type) > 0 {
+				symbol, _ := c.symbolTable.Resolve(ident.Name)
+				if _, symbol.Type, err = c.nameSymbolsOfTypedIdent(nd, ti); err != nil {
+					return err
+				}
+				// a declaration without value is nil until first assignment
+				if i < len(spec.Values) && spec.Values[i] != nil {
+					if err = c.compileVarTypeCheck(ident, symbol.Type); err != nil {
+						return err
+					}
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// compileAssignTypeCheck emits type checks of assigned variables which have
+// declared types.
+func (c *Compiler) compileAssignTypeCheck(lhs []node.Expr) error {
+	for _, expr := range lhs {
+		ident, _ := expr.(*node.Ident)
+		if ident == nil {
+			continue
+		}
+		symbol, ok := c.symbolTable.Resolve(ident.Name)
+		if !ok {
+			continue
+		}
+		for symbol.Original != nil {
+			symbol = symbol.Original
+		}
+		if len(symbol.Type) > 0 {
+			if err := c.compileVarTypeCheck(ident, symbol.Type); err != nil {
+				return err
+			}
 		}
 	}
 	return nil
 }
 
+// returnTypeCheckExpr wraps result into a call which checks returned value
+// against declared return types of the function.
+func (c *Compiler) returnTypeCheckExpr(result node.Expr) node.Expr {
+	var typesElements = make([]node.Expr, len(c.returnType))
+	for i, symbol := range c.returnType {
+		typesElements[i] = &node.Ident{NamePos: result.Pos(), Name: symbol.Name}
+	}
+
+	return &node.CallExpr{
+		Func: &node.Ident{
+			NamePos: result.Pos(),
+			Name:    BuiltinReturnTypeCheck.String(),
+		},
+		CallArgs: node.CallArgs{
+			Args: node.CallExprArgs{
+				Values: []node.Expr{
+					&node.StringLit{Value: c.funcName},
+					&node.ArrayLit{Elements: typesElements},
+					result,
+				},
+			},
+		},
+	}
+}
+
+func (c *Compiler) compileVarTypeCheck(ident *node.Ident, types []*SymbolInfo) error {
+	var typesArg node.Expr = &node.Ident{
+		NamePos: ident.Pos(),
+		Name:    types[0].Name,
+	}
+
+	if len(types) > 1 {
+		var typesElements = make([]node.Expr, len(types))
+		for i, symbol := range types {
+			typesElements[i] = &node.Ident{NamePos: ident.Pos(), Name: symbol.Name}
+		}
+		typesArg = &node.ArrayLit{Elements: typesElements}
+	}
+
+	return c.Compile(&node.ExprStmt{
+		Expr: &node.CallExpr{
+			Func: &node.Ident{
+				NamePos: ident.Pos(),
+				Name:    BuiltinVarTypeCheck.String(),
+			},
+			CallArgs: node.CallArgs{
+				Args: node.CallExprArgs{
+					Values: []node.Expr{
+						&node.StringLit{Value: ident.Name},
+						typesArg,
+						ident,
+					},
+				},
+			},
+		},
+	})
+}
+
 func (c *Compiler) checkAssignment(
 	nd ast.Node,
 	lhs []node.Expr,
@@ -493,6 +608,9 @@ func (c *Compiler) compileAssignStmt(
 		if err := c.compileDefineAssign(nd, lhs[0], keyword, token.Assign, false); err != nil {
 			return err
 		}
+		if err := c.compileAssignTypeCheck(lhs); err != nil {
+			return err
+		}
 		c.changeOperand(jumpPos, len(c.instructions))
 		return nil
 	}
@@ -505,13 +623,22 @@ func (c *Compiler) compileAssignStmt(
 	}
 
 	if isArrDestruct {
-		return c.compileDestructuring(nd, lhs, tempArrSymbol, keyword, op)
+		if err := c.compileDestructuring(nd, lhs, tempArrSymbol, keyword, op); err != nil {
+			return err
+		}
+	} else {
+		if op != token.Assign && op != token.Define {
+			c.compileCompoundAssignment(nd, op)
+		}
+		if err := c.compileDefineAssign(nd, lhs[0], keyword, op, false); err != nil {
+			return err
+		}
+		if op == token.Define && len(rhs) == 1 {
+			c.defineModule(lhs[0], rhs[0])
+		}
 	}
 
-	if op != token.Assign && op != token.Define {
-		c.compileCompoundAssignment(nd, op)
-	}
-	return c.compileDefineAssign(nd, lhs[0], keyword, op, false)
+	return c.compileAssignTypeCheck(lhs)
 }
 
 func (c *Compiler) compileCompoundAssignment(
@@ -622,6 +749,7 @@ func (c *Compiler) compileAssign(
 	case ScopeLocal:
 		c.emit(nd, OpSetLocal, symbol.Index)
 		symbol.Assigned = true
+		symbol.mutated = true
 	case ScopeFree:
 		c.emit(nd, OpSetFree, symbol.Index)
 		symbol.Assigned = true
@@ -629,6 +757,7 @@ func (c *Compiler) compileAssign(
 		for s != nil {
 			if s.Original != nil && s.Original.Scope == ScopeLocal {
 				s.Original.Assigned = true
+				s.Original.mutated = true
 			}
 			s = s.Original
 		}
@@ -758,10 +887,22 @@ func (c *Compiler) compileBlockStmt(nd *node.BlockStmt) error {
 }
 
 func (c *Compiler) compileReturn(nd *node.Return) error {
+	if len(c.returnType) > 0 {
+		var result node.Expr = &node.NilLit{TokenPos: nd.Pos()}
+		if nd.Result != nil {
+			result = nd.Result
+		}
+		nd = &node.Return{
+			ReturnPos: nd.ReturnPos,
+			Result:    c.returnTypeCheckExpr(result),
+		}
+	}
+
 	if nd.Result == nil {
 		if c.tryCatchIndex > -1 {
 			c.emit(nd, OpFinalizer, 0)
 		}
+		c.emitModuleHooks(nd)
 		c.emit(nd, OpReturn, 0)
 		return nil
 	}
@@ -774,10 +915,43 @@ func (c *Compiler) compileReturn(nd *node.Return) error {
 		c.emit(nd, OpFinalizer, 0)
 	}
 
+	c.emitModuleHooks(nd)
 	c.emit(nd, OpReturn, 1)
 	return nil
 }
 
+// emitModuleHooks emits OpModuleHooks with the __init__ and __cleanup__
+// functions of the module if the main function of a source module is
+// compiled and one of them is defined.
+func (c *Compiler) emitModuleHooks(nd ast.Node) {
+	if !c.moduleMain {
+		return
+	}
+
+	var (
+		symbols [2]*Symbol
+		found   bool
+	)
+	for i, name := range [...]string{"__init__", "__cleanup__"} {
+		if symbol, ok := c.symbolTable.Resolve(name); ok && symbol.Scope == ScopeLocal {
+			symbols[i] = symbol
+			found = true
+		}
+	}
+	if !found {
+		return
+	}
+
+	for _, symbol := range symbols {
+		if symbol != nil {
+			c.emit(nd, OpGetLocal, symbol.Index)
+		} else {
+			c.emit(nd, OpNil)
+		}
+	}
+	c.emit(nd, OpModuleHooks)
+}
+
 func (c *Compiler) compileForStmt(stmt *node.ForStmt) error {
 	c.symbolTable = c.symbolTable.Fork(true)
 	defer func() {
@@ -1124,14 +1298,31 @@ func (c *Compiler) compileFunc(nd ast.Node, typ *node.FuncType, body *node.Block
 
 	fork := c.fork(c.file, c.module, c.moduleMap, symbolTable)
 	fork.variadic = typ.Params.Args.Var != nil
+
+	if len(typ.ReturnType) > 0 {
+		if fork.returnType, err = c.typeSymbols(nd, typ.ReturnType); err != nil {
+			return
+		}
+		if typ.Ident != nil {
+			fork.funcName = typ.Ident.Name
+		}
+		// falling off the end of function returns nil, which must be checked too
+		body = &node.BlockStmt{
+			Stmts:  append(body.Stmts, &node.ReturnStmt{Return: node.Return{ReturnPos: body.RBrace}}),
+			LBrace: body.LBrace,
+			RBrace: body.RBrace,
+		}
+	}
 	if err := fork.Compile(body); err != nil {
 		return err
 	}
+	fork.captureByValue()
 	freeSymbols := fork.symbolTable.FreeSymbols()
 	for _, s := range freeSymbols {
 		switch s.Scope {
 		case ScopeLocal:
-			c.emit(nd, OpGetLocalPtr, s.Index)
+			pos := c.emit(nd, OpGetLocalPtr, s.Index)
+			c.captures = append(c.captures, localCapture{pos: pos, symbol: s})
 		case ScopeFree:
 			c.emit(nd, OpGetFreePtr, s.Index)
 		}
@@ -1252,6 +1443,7 @@ func (c *Compiler) compileUnaryExpr(nd *node.UnaryExpr) error {
 func (c *Compiler) compileSelectorExpr(nd *node.SelectorExpr) error {
 	defer c.pushSelector()()
 	expr, selectors := resolveSelectorExprs(nd)
+	c.warnDeprecatedMember(expr, selectors[0])
 
 	if err := c.Compile(expr); err != nil {
 		return err
@@ -1299,6 +1491,7 @@ func (c *Compiler) compileNullishSelectorExpr(nd *node.NullishSelectorExpr) erro
 	defer c.pushSelector()()
 
 	expr, selectors := resolveSelectorExprs(nd)
+	c.warnDeprecatedMember(expr, selectors[0])
 
 	var jumpPos int
 
@@ -1399,6 +1592,7 @@ func (c *Compiler) compileCallExpr(nd *node.CallExpr) error {
 		selExpr, isSelector = nd.Func.(*node.SelectorExpr)
 	}
 	if isSelector {
+		c.warnDeprecatedMember(selExpr.Expr, selExpr.Sel)
 		if err := c.Compile(selExpr.Expr); err != nil {
 			return err
 		}
@@ -1464,6 +1658,10 @@ func (c *Compiler) compileImportExpr(nd *node.ImportExpr) error {
 		return c.errorf(nd, "empty module name")
 	}
 
+	if c.opts.exprMode {
+		return c.errorf(nd, "import is not allowed in expression")
+	}
+
 	importer := c.moduleMap.Get(moduleName)
 	if importer == nil {
 		return c.errorf(nd, "module '%s' not found", moduleName)
@@ -1478,14 +1676,19 @@ func (c *Compiler) compileImportExpr(nd *node.ImportExpr) error {
 		}
 	}
 
+	importIndex := c.addImport(nd.ModuleName, moduleName)
 	module, exists := c.getModule(moduleName)
 	if !exists {
-		mod, url, err := importer.Import(c.opts.Context, moduleName)
+		mod, url, err := c.importModule(importer, moduleName)
 		if err != nil {
 			return c.error(nd, err)
 		}
 		switch v := mod.(type) {
 		case []byte:
+			if err = c.moduleMap.verifyChecksum(v, isExt, moduleName, nd.ModuleName); err != nil {
+				return c.error(nd, err)
+			}
+
 			var moduleMap *ModuleMap
 			if isExt {
 				moduleMap = c.moduleMap.Fork(moduleName)
@@ -1495,11 +1698,16 @@ func (c *Compiler) compileImportExpr(nd *node.ImportExpr) error {
 
 			moduleInfo := &ModuleInfo{moduleName, url}
 
-			cidx, err := c.compileModule(nd, importer, moduleInfo, moduleMap, v)
-			if err != nil {
+			// module is added before compiling to let the module and its
+			// imports import it cyclically, see OpLoadModule.
+			c.constants = append(c.constants, Nil)
+			module = c.addModule(moduleName, 1, len(c.constants)-1)
+			module.importIndex = importIndex
+			sum := sha256.Sum256(v)
+			c.moduleStore.imports[importIndex].Sum = hex.EncodeToString(sum[:])
+			if err = c.compileModule(nd, importer, moduleInfo, moduleMap, v, module.constantIndex); err != nil {
 				return err
 			}
-			module = c.addModule(moduleName, 1, cidx)
 			for _, cnt := range c.constants {
 				if fn, ok := cnt.(*CompiledFunction); ok {
 					fn.module = moduleInfo
@@ -1593,8 +1801,22 @@ func (c *Compiler) compileCondExpr(nd *node.CondExpr) error {
 }
 
 func (c *Compiler) compileIdent(nd *node.Ident) error {
+	if c.opts.passEnabled(PassInline) {
+		if v := c.symbolTable.constValue(nd.Name); v != nil {
+			if w := c.opts.passTrace(PassInline); w != nil {
+				_, _ = fmt.Fprintf(w, "%s: %s: %s\n", PassInline, nd.Name, ToCode(v))
+			}
+			c.emitValue(nd, v)
+			return nil
+		}
+	}
+
 	symbol, ok := c.symbolTable.Resolve(nd.Name)
 	if !ok {
+		if c.opts.exprMode {
+			c.emit(nd, OpGetGlobal, c.addConstant(Str(nd.Name)))
+			return nil
+		}
 		if c.iotaVal < 0 || nd.Name != "iota" {
 			return c.errorf(nd, "unresolved reference %q", nd.Name)
 		}
@@ -1608,6 +1830,7 @@ func (c *Compiler) compileIdent(nd *node.Ident) error {
 	case ScopeLocal:
 		c.emit(nd, OpGetLocal, symbol.Index)
 	case ScopeBuiltin:
+		c.warnDeprecated(nd, nd.Name)
 		c.emit(nd, OpGetBuiltin, symbol.Index)
 	case ScopeFree:
 		c.emit(nd, OpGetFree, symbol.Index)
@@ -1615,6 +1838,43 @@ func (c *Compiler) compileIdent(nd *node.Ident) error {
 	return nil
 }
 
+// defineModule records the module name of the variable defined by an import
+// expression to warn about its deprecated members.
+func (c *Compiler) defineModule(lhs, rhs node.Expr) {
+	ident, ok := lhs.(*node.Ident)
+	if !ok {
+		return
+	}
+	if imp, ok := rhs.(*node.ImportExpr); ok {
+		if symbol, ok := c.symbolTable.find(ident.Name); ok {
+			symbol.module = imp.ModuleName
+		}
+	}
+}
+
+// warnDeprecatedMember warns if the selected member of a module imported
+// directly or by a variable is deprecated.
+func (c *Compiler) warnDeprecatedMember(expr, sel node.Expr) {
+	if len(c.opts.Deprecations) == 0 {
+		return
+	}
+	member, ok := sel.(*node.StringLit)
+	if !ok {
+		return
+	}
+
+	var module string
+	switch e := expr.(type) {
+	case *node.ImportExpr:
+		module = e.ModuleName
+	case *node.Ident:
+		module = c.symbolTable.moduleOf(e.Name)
+	}
+	if module != "" {
+		c.warnDeprecated(sel, module+"."+member.Value)
+	}
+}
+
 func (c *Compiler) compileArrayLit(nd *node.ArrayLit) error {
 	for _, elem := range nd.Elements {
 		if err := c.Compile(elem); err != nil {
@@ -1777,15 +2037,20 @@ func (c *Compiler) helperBuildKwargsIfUndefinedStmts(count int, get func(index i
 
 func (c *Compiler) nameSymbolsOfTypedIdent(nd ast.Node, ti *node.TypedIdent) (name string, symbols []*SymbolInfo, err error) {
 	name = ti.Ident.Name
-	if len(ti.Type) > 0 {
-		symbols = make([]*SymbolInfo, len(ti.Type))
-		for i2, tname := range ti.Type {
+	symbols, err = c.typeSymbols(nd, ti.Type)
+	return
+}
+
+func (c *Compiler) typeSymbols(nd ast.Node, types []*node.Ident) (symbols []*SymbolInfo, err error) {
+	if len(types) > 0 {
+		symbols = make([]*SymbolInfo, len(types))
+		for i, tname := range types {
 			symbol, ok := c.symbolTable.Resolve(tname.Name)
 			if !ok {
 				err = c.errorf(nd, "unresolved reference %q", tname)
 				return
 			}
-			symbols[i2] = &symbol.SymbolInfo
+			symbols[i] = &symbol.SymbolInfo
 		}
 	}
 	return
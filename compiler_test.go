@@ -1,6 +1,9 @@
 package gad_test
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
 	"testing"
 
 	"github.com/gad-lang/gad/parser"
@@ -3113,3 +3116,25 @@ func expectCompileWithOpts(t *testing.T,
 	require.NoError(t, err)
 	TestBytecodesEqual(t, expected, got, expected.Main.SourceMap != nil)
 }
+
+func TestCompilerModuleChecksums(t *testing.T) {
+	src := []byte(`return 1`)
+	sum := sha256.Sum256(src)
+	digest := hex.EncodeToString(sum[:])
+
+	compile := func(mm *ModuleMap) error {
+		_, err := Compile([]byte(`import("mod")`),
+			CompileOptions{CompilerOptions: CompilerOptions{ModuleMap: mm}})
+		return err
+	}
+
+	mm := NewModuleMap().AddSourceModule("mod", src)
+	require.NoError(t, compile(mm.Copy().SetChecksums(map[string]string{"mod": digest}, true)))
+	require.NoError(t, compile(mm.Copy().SetChecksums(map[string]string{"mod": strings.ToUpper(digest)}, false)))
+	// only ExtImporter modules are required to have a checksum
+	require.NoError(t, compile(mm.Copy().SetChecksums(nil, true)))
+
+	err := compile(mm.Copy().SetChecksums(map[string]string{"mod": "00"}, false))
+	require.ErrorIs(t, err, ErrModuleChecksum)
+	require.Contains(t, err.Error(), "module 'mod' checksum mismatch: want 00, got "+digest)
+}
@@ -0,0 +1,161 @@
+package gad
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+
+	"github.com/gad-lang/gad/parser"
+	"github.com/gad-lang/gad/parser/ast"
+)
+
+// Deprecations maps the names of the deprecated builtins and module members
+// to their replacement hints. Module members are named as "module.member".
+// It is given to CompilerOptions to warn about the deprecated names at compile
+// time and to SetupOpts to report their uses at runtime.
+type Deprecations map[string]string
+
+// Deprecate marks the builtin or the module member deprecated. Hint is the
+// replacement hint like "use strings.Fields instead", it may be empty.
+func (d Deprecations) Deprecate(name, hint string) Deprecations {
+	d[name] = hint
+	return d
+}
+
+// Message returns the deprecation message of the name and reports whether the
+// name is deprecated.
+func (d Deprecations) Message(name string) (string, bool) {
+	hint, ok := d[name]
+	if !ok {
+		return "", false
+	}
+	if hint == "" {
+		return fmt.Sprintf("'%s' is deprecated", name), true
+	}
+	return fmt.Sprintf("'%s' is deprecated: %s", name, hint), true
+}
+
+// CompilerWarning represents a compiler warning which does not stop the
+// compilation, see CompilerOptions.Warn.
+type CompilerWarning struct {
+	FileSet *parser.SourceFileSet
+	Node    ast.Node
+	Message string
+}
+
+// Pos returns the source position of the warning.
+func (w *CompilerWarning) Pos() parser.SourceFilePos {
+	return w.FileSet.Position(w.Node.Pos())
+}
+
+func (w *CompilerWarning) String() string {
+	return w.Pos().String() + ": " + w.Message
+}
+
+func (c *Compiler) warnf(nd ast.Node, format string, args ...any) {
+	if c.opts.Warn == nil {
+		return
+	}
+	c.opts.Warn(&CompilerWarning{
+		FileSet: c.file.Set(),
+		Node:    nd,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+// warnDeprecated warns if the builtin or the module member is deprecated.
+func (c *Compiler) warnDeprecated(nd ast.Node, name string) {
+	if msg, ok := c.opts.Deprecations.Message(name); ok {
+		c.warnf(nd, "%s", msg)
+	}
+}
+
+// DeprecationEvent describes the first use of a deprecated builtin or module
+// function at runtime, see SetupOpts.OnDeprecated.
+type DeprecationEvent struct {
+	Name    string
+	Message string
+	Pos     parser.SourceFilePos
+}
+
+// deprecationState is shared by a VM and its child VMs to resolve the
+// deprecated builtins and module functions and to report them once.
+type deprecationState struct {
+	mu       sync.Mutex
+	builtins map[BuiltinType]string
+	funcs    map[Object]string
+	reported map[string]bool
+}
+
+func (vm *VM) initDeprecations() {
+	s := &deprecationState{
+		builtins: map[BuiltinType]string{},
+		funcs:    map[Object]string{},
+		reported: map[string]bool{},
+	}
+	for name, t := range vm.Builtins.Map {
+		if _, ok := vm.Deprecations[name]; ok {
+			s.builtins[t] = name
+		}
+	}
+	vm.deprecations = s
+}
+
+// deprecatedModule registers the deprecated functions of a builtin module to
+// be reported when they are called.
+func (vm *VM) deprecatedModule(module Object) {
+	d, ok := module.(Dict)
+	if !ok {
+		return
+	}
+	name, ok := d[AttrModuleName]
+	if !ok {
+		return
+	}
+
+	vm.deprecations.mu.Lock()
+	defer vm.deprecations.mu.Unlock()
+
+	for k, v := range d {
+		member := name.ToString() + "." + k
+		if _, ok := vm.Deprecations[member]; !ok || !Callable(v) || !reflect.TypeOf(v).Comparable() {
+			continue
+		}
+		vm.deprecations.funcs[v] = member
+	}
+}
+
+func (vm *VM) deprecatedBuiltin(t BuiltinType) {
+	if name, ok := vm.deprecations.builtins[t]; ok {
+		vm.deprecated(name)
+	}
+}
+
+func (vm *VM) deprecatedCall(fn Object) {
+	if !reflect.TypeOf(fn).Comparable() {
+		return
+	}
+	vm.deprecations.mu.Lock()
+	name, ok := vm.deprecations.funcs[fn]
+	vm.deprecations.mu.Unlock()
+	if ok {
+		vm.deprecated(name)
+	}
+}
+
+func (vm *VM) deprecated(name string) {
+	vm.deprecations.mu.Lock()
+	reported := vm.deprecations.reported[name]
+	vm.deprecations.reported[name] = true
+	vm.deprecations.mu.Unlock()
+	if reported {
+		return
+	}
+
+	e := DeprecationEvent{Name: name}
+	e.Message, _ = vm.Deprecations.Message(name)
+	if vm.bytecode.FileSet != nil {
+		e.Pos = vm.bytecode.FileSet.Position(vm.getSourcePos())
+	}
+	vm.OnDeprecated(e)
+}
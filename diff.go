@@ -0,0 +1,186 @@
+package gad
+
+import (
+	"math"
+	"sort"
+)
+
+// Kinds of the differences returned by Diff.
+const (
+	DiffAdded   = "added"
+	DiffRemoved = "removed"
+	DiffChanged = "changed"
+)
+
+// DeepEqual reports whether a and b are deeply equal. Dicts, arrays and the
+// fields of the struct instances are compared recursively, numbers are equal
+// if their difference is not greater than tolerance and other objects are
+// compared by their Equal methods. Cyclic values are equal if their cycles
+// are walked together.
+func DeepEqual(a, b Object, tolerance float64) bool {
+	d := differ{tolerance: tolerance, first: true}
+	d.walk(nil, a, b)
+	return len(d.diffs) == 0
+}
+
+// Diff returns the differences between a and b which are compared like
+// DeepEqual. A difference is a dict with keys:
+//   - path: array of the dict keys and the array indexes to the value,
+//   - kind: DiffAdded, DiffRemoved or DiffChanged,
+//   - a: the value of a, missing if kind is DiffAdded,
+//   - b: the value of b, missing if kind is DiffRemoved.
+//
+// Dict keys are walked in sorted order.
+func Diff(a, b Object, tolerance float64) Array {
+	d := differ{tolerance: tolerance}
+	d.walk(nil, a, b)
+	if d.diffs == nil {
+		return Array{}
+	}
+	return d.diffs
+}
+
+type differ struct {
+	tolerance float64
+	// first stops at the first difference.
+	first bool
+	diffs Array
+	// visited are the pairs of the containers being walked, a pair walked
+	// again through a cycle is equal.
+	visited map[[2]any]struct{}
+}
+
+// visit reports whether the containers identified by a and b are already
+// being walked, otherwise it records them until the returned function is
+// called.
+func (d *differ) visit(a, b any) (visited bool, leave func()) {
+	if a == nil || b == nil {
+		return false, func() {}
+	}
+	key := [2]any{a, b}
+	if _, visited = d.visited[key]; visited {
+		return
+	}
+	if d.visited == nil {
+		d.visited = map[[2]any]struct{}{}
+	}
+	d.visited[key] = struct{}{}
+	return false, func() { delete(d.visited, key) }
+}
+
+func (d *differ) add(path Array, kind string, a, b Object) {
+	diff := Dict{
+		"path": append(Array{}, path...),
+		"kind": Str(kind),
+	}
+	if a != nil {
+		diff["a"] = a
+	}
+	if b != nil {
+		diff["b"] = b
+	}
+	d.diffs = append(d.diffs, diff)
+}
+
+func (d *differ) done() bool {
+	return d.first && len(d.diffs) > 0
+}
+
+func (d *differ) walk(path Array, a, b Object) {
+	switch av := a.(type) {
+	case Dict:
+		if bv, ok := b.(Dict); ok {
+			if visited, leave := d.visit(av.identity(), bv.identity()); !visited {
+				d.walkDict(path, av, bv)
+				leave()
+			}
+			return
+		}
+	case Array:
+		if bv, ok := b.(Array); ok {
+			if visited, leave := d.visit(av.identity(), bv.identity()); !visited {
+				d.walkArray(path, av, bv)
+				leave()
+			}
+			return
+		}
+	case *Obj:
+		if bv, ok := b.(*Obj); ok && av.typ.Equal(bv.typ) {
+			if visited, leave := d.visit(av, bv); !visited {
+				d.walkDict(path, av.fields, bv.fields)
+				leave()
+			}
+			return
+		}
+	default:
+		if d.numbersEqual(a, b) {
+			return
+		}
+	}
+	if !a.Equal(b) {
+		d.add(path, DiffChanged, a, b)
+	}
+}
+
+func (d *differ) walkDict(path Array, a, b Dict) {
+	for _, key := range sortedKeys(a) {
+		if d.done() {
+			return
+		}
+		if bv, ok := b[key]; ok {
+			d.walk(append(path, Str(key)), a[key], bv)
+		} else {
+			d.add(append(path, Str(key)), DiffRemoved, a[key], nil)
+		}
+	}
+	for _, key := range sortedKeys(b) {
+		if d.done() {
+			return
+		}
+		if _, ok := a[key]; !ok {
+			d.add(append(path, Str(key)), DiffAdded, nil, b[key])
+		}
+	}
+}
+
+func sortedKeys(d Dict) []string {
+	keys := make([]string, 0, len(d))
+	for k := range d {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (d *differ) walkArray(path Array, a, b Array) {
+	for i := 0; i < len(a) || i < len(b); i++ {
+		if d.done() {
+			return
+		}
+		switch {
+		case i >= len(b):
+			d.add(append(path, Int(i)), DiffRemoved, a[i], nil)
+		case i >= len(a):
+			d.add(append(path, Int(i)), DiffAdded, nil, b[i])
+		default:
+			d.walk(append(path, Int(i)), a[i], b[i])
+		}
+	}
+}
+
+func (d *differ) numbersEqual(a, b Object) bool {
+	if d.tolerance <= 0 || !isNumber(a) || !isNumber(b) {
+		return false
+	}
+	af, _ := ToGoFloat64(a)
+	bf, _ := ToGoFloat64(b)
+	return math.Abs(af-bf) <= d.tolerance
+}
+
+func isNumber(o Object) bool {
+	switch o.(type) {
+	case Int, Uint, Float, Decimal:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,253 @@
+package gad
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/gad-lang/gad/parser/source"
+	"github.com/gad-lang/gad/token"
+)
+
+// DisassembleOptions are the options of Disassemble.
+type DisassembleOptions struct {
+	// ReadSource returns the source of the file with given name. If it is not
+	// nil, source lines are interleaved with the instructions compiled from
+	// them. Files which can not be read are listed without source lines.
+	ReadSource func(filename string) ([]byte, error)
+	// MaxValueLen is the maximum length of the constant values shown next to
+	// the instructions. Longer values are truncated. Zero means 40.
+	MaxValueLen int
+}
+
+// Disassemble writes a human readable listing of bc to w. Main function and
+// the compiled function constants are listed with the values of referenced
+// constants, globals, builtins and modules resolved, and jump targets shown as
+// labels like "L1".
+func Disassemble(bc *Bytecode, w io.Writer, opts DisassembleOptions) error {
+	if opts.MaxValueLen <= 0 {
+		opts.MaxValueLen = 40
+	}
+	d := &disassembler{
+		bc:      bc,
+		opts:    opts,
+		w:       w,
+		sources: make(map[string][]string),
+	}
+
+	d.printf("; modules: %d, constants: %d\n", bc.NumModules, len(bc.Constants))
+	d.function(-1, bc.Main)
+	for i, o := range bc.Constants {
+		if fn, ok := o.(*CompiledFunction); ok {
+			d.function(i, fn)
+		}
+	}
+	return d.err
+}
+
+type disassembler struct {
+	bc       *Bytecode
+	opts     DisassembleOptions
+	w        io.Writer
+	err      error
+	sources  map[string][]string
+	builtins map[BuiltinType]string
+}
+
+func (d *disassembler) printf(format string, args ...any) {
+	if d.err == nil {
+		_, d.err = fmt.Fprintf(d.w, format, args...)
+	}
+}
+
+func (d *disassembler) function(index int, fn *CompiledFunction) {
+	name := fn.Name
+	switch {
+	case index < 0 && name == "":
+		d.printf("\nfunc main")
+	case index < 0:
+		d.printf("\nfunc %s", name)
+	case name == "":
+		d.printf("\nfunc <anonymous> [constant %d]", index)
+	default:
+		d.printf("\nfunc %s [constant %d]", name, index)
+	}
+	d.printf(" params=(%s) locals=%d", fn.Params.String(), fn.NumLocals)
+	if len(fn.NamedParams.Params) > 0 {
+		d.printf(" named=%s", fn.NamedParams.String())
+	}
+	d.printf("\n")
+
+	labels := jumpLabels(fn.Instructions)
+
+	var (
+		operands []int
+		offset   int
+		lastFile string
+		lastLine int
+	)
+	for ip := 0; ip < len(fn.Instructions); ip += offset + 1 {
+		if label, ok := labels[ip]; ok {
+			d.printf("%s:\n", label)
+		}
+		if file, line, text, ok := d.sourceLine(fn, ip); ok && (file != lastFile || line != lastLine) {
+			lastFile, lastLine = file, line
+			d.printf("    ; %s:%d: %s\n", file, line, text)
+		}
+
+		op := fn.Instructions[ip]
+		operands, offset = ReadOperands(OpcodeOperands[op], fn.Instructions[ip+1:], operands)
+
+		args := make([]string, len(operands))
+		for i, v := range operands {
+			if isJumpOperand(Opcode(op), i) {
+				args[i] = labels[v]
+			} else {
+				args[i] = fmt.Sprint(v)
+			}
+		}
+
+		line := fmt.Sprintf("  %04d  %-12s %s", ip, OpcodeNames[op], strings.Join(args, " "))
+		if comment := d.comment(Opcode(op), operands); comment != "" {
+			line = fmt.Sprintf("%-36s ; %s", line, comment)
+		}
+		d.printf("%s\n", strings.TrimRight(line, " "))
+	}
+	if label, ok := labels[len(fn.Instructions)]; ok {
+		d.printf("%s:\n", label)
+	}
+}
+
+// comment returns the description of the operands of the instruction.
+func (d *disassembler) comment(op Opcode, operands []int) string {
+	switch op {
+	case OpConstant:
+		return d.constant(operands[0])
+	case OpGetGlobal, OpSetGlobal:
+		if c := d.constantObject(operands[0]); c != nil {
+			return c.ToString()
+		}
+	case OpClosure:
+		return d.constant(operands[0])
+	case OpLoadModule, OpStoreModule:
+		idx := operands[len(operands)-1]
+		if idx < len(d.bc.ModuleNames) && d.bc.ModuleNames[idx] != "" {
+			return fmt.Sprintf("module %q", d.bc.ModuleNames[idx])
+		}
+	case OpGetBuiltin:
+		return d.builtin(BuiltinType(operands[0]))
+	case OpBinaryOp, OpUnary:
+		return token.Token(operands[0]).String()
+	case OpCall, OpCallName:
+		return fmt.Sprintf("args=%d flags=%d", operands[0], operands[1])
+	}
+	return ""
+}
+
+func (d *disassembler) constantObject(index int) Object {
+	if index < 0 || index >= len(d.bc.Constants) {
+		return nil
+	}
+	return d.bc.Constants[index]
+}
+
+func (d *disassembler) constant(index int) string {
+	switch c := d.constantObject(index).(type) {
+	case nil:
+		return "<invalid constant>"
+	case *CompiledFunction:
+		if c.Name == "" {
+			return "func <anonymous>"
+		}
+		return "func " + c.Name
+	default:
+		s := ToCode(c)
+		if len(s) > d.opts.MaxValueLen {
+			s = s[:d.opts.MaxValueLen] + "..."
+		}
+		return s
+	}
+}
+
+func (d *disassembler) builtin(t BuiltinType) string {
+	if d.builtins == nil {
+		d.builtins = make(map[BuiltinType]string, len(BuiltinsMap))
+		for name, bt := range BuiltinsMap {
+			// prefer the shortest name if a builtin has aliases
+			if old, ok := d.builtins[bt]; !ok || len(name) < len(old) ||
+				(len(name) == len(old) && name < old) {
+				d.builtins[bt] = name
+			}
+		}
+	}
+	return d.builtins[t]
+}
+
+// sourceLine returns the source line of the instruction at ip.
+func (d *disassembler) sourceLine(fn *CompiledFunction, ip int) (file string, line int, text string, ok bool) {
+	if d.opts.ReadSource == nil || d.bc.FileSet == nil {
+		return
+	}
+	pos, exists := fn.SourceMap[ip]
+	if !exists || pos <= 0 {
+		return
+	}
+	p := d.bc.FileSet.Position(source.Pos(pos))
+	if p.Line <= 0 {
+		return
+	}
+
+	lines, exists := d.sources[p.Filename]
+	if !exists {
+		if src, err := d.opts.ReadSource(p.Filename); err == nil {
+			lines = strings.Split(string(bytes.ReplaceAll(src, []byte("\r\n"), []byte("\n"))), "\n")
+		}
+		d.sources[p.Filename] = lines
+	}
+	if p.Line > len(lines) {
+		return
+	}
+	return p.Filename, p.Line, strings.TrimSpace(lines[p.Line-1]), true
+}
+
+// isJumpOperand reports whether the i'th operand of op is an instruction
+// position.
+func isJumpOperand(op Opcode, i int) bool {
+	switch op {
+	case OpJump, OpJumpFalsy, OpAndJump, OpOrJump, OpJumpNil, OpJumpNotNil:
+		return i == 0
+	case OpIterNextElse, OpSetupTry:
+		return i < 2
+	}
+	return false
+}
+
+// jumpLabels returns the labels of the jump targets of insts in order of
+// their positions.
+func jumpLabels(insts []byte) map[int]string {
+	var (
+		targets  []int
+		seen     = make(map[int]bool)
+		operands []int
+		offset   int
+	)
+	for ip := 0; ip < len(insts); ip += offset + 1 {
+		op := Opcode(insts[ip])
+		operands, offset = ReadOperands(OpcodeOperands[op], insts[ip+1:], operands)
+		for i, v := range operands {
+			if isJumpOperand(op, i) && !seen[v] {
+				seen[v] = true
+				targets = append(targets, v)
+			}
+		}
+	}
+	sort.Ints(targets)
+
+	labels := make(map[int]string, len(targets))
+	for i, pos := range targets {
+		labels[pos] = fmt.Sprintf("L%d", i+1)
+	}
+	return labels
+}
@@ -0,0 +1,66 @@
+package gad_test
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	. "github.com/gad-lang/gad"
+)
+
+func TestDisassemble(t *testing.T) {
+	src := []byte(`x := 1
+f := func(a) {
+	if a > 0 { return "pos" }
+	return len([a])
+}
+for i := 0; i < 2; i++ { x += f(i) }
+global g
+g = import("mod")
+return x`)
+
+	opts := CompileOptions{CompilerOptions: DefaultCompilerOptions}
+	opts.ModuleMap = NewModuleMap().AddSourceModule("mod", []byte(`return 1`))
+	opts.Module = &ModuleInfo{Name: "main.gad"}
+	bc, err := Compile(src, opts)
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, Disassemble(bc, &buf, DisassembleOptions{
+		ReadSource: func(name string) ([]byte, error) {
+			if name == "main.gad" {
+				return src, nil
+			}
+			return nil, errors.New("not found")
+		},
+	}))
+	out := buf.String()
+
+	for _, s := range []string{
+		"\nfunc main params=() locals=3\n",
+		"    ; main.gad:1: x := 1\n  0000  CONSTANT     0               ; 1\n",
+		"\nL1:\n  0015  GETLOCAL     2\n",
+		"JUMPFALSY    L2\n",
+		"JUMP         L1\n",
+		"; <\n",
+		"; module \"mod\"\n",
+		"SETGLOBAL    5               ; g\n",
+		"params=(a) locals=1\n",
+		`; "pos"`,
+		"    ; main.gad:4: return len([a])\n",
+		"GETBUILTIN   ",
+		"; len\n",
+		"; args=1 flags=0\n",
+	} {
+		require.Contains(t, out, s)
+	}
+	require.NotContains(t, out, "mod:1")
+
+	buf.Reset()
+	require.NoError(t, Disassemble(bc, &buf, DisassembleOptions{}))
+	require.NotContains(t, buf.String(), "main.gad:")
+	require.Equal(t, strings.Count(out, "\nfunc "), strings.Count(buf.String(), "\nfunc "))
+}
@@ -10,7 +10,8 @@ import (
 	"github.com/gad-lang/gad"
 )
 
-// EncodeBytecodeTo encodes given bc to w io.Writer.
+// EncodeBytecodeTo encodes given bc to w io.Writer. Encoding is deterministic,
+// bytecode compiled from the same input is encoded to the same bytes.
 func EncodeBytecodeTo(bc *gad.Bytecode, w io.Writer) error {
 	return (*Bytecode)(bc).Encode(w)
 }
@@ -22,6 +23,45 @@ func DecodeBytecodeFrom(r io.Reader, modules *gad.ModuleMap) (*gad.Bytecode, err
 	return (*gad.Bytecode)(&bc), err
 }
 
+// DecodeVerifiedBytecodeFrom decodes *gad.Bytecode from given r io.Reader and
+// verifies it with gad.Bytecode.Verify. Use it to load bytecode from untrusted
+// sources. If builtins is nil, default builtins are used.
+func DecodeVerifiedBytecodeFrom(
+	r io.Reader,
+	modules *gad.ModuleMap,
+	builtins *gad.Builtins,
+) (*gad.Bytecode, error) {
+	bc, err := DecodeBytecodeFrom(r, modules)
+	if err != nil {
+		return nil, err
+	}
+	if err = bc.Verify(builtins); err != nil {
+		return nil, err
+	}
+	return bc, nil
+}
+
+// Codec implements gad.BytecodeCodec. It is set as gad.DefaultBytecodeCodec to
+// let gad.CachedCompile cache bytecode.
+type Codec struct{}
+
+var _ gad.BytecodeCodec = Codec{}
+
+// Version implements gad.BytecodeCodec.
+func (Codec) Version() string {
+	return fmt.Sprintf("%x/%d", BytecodeSignature, BytecodeVersion)
+}
+
+// EncodeBytecode implements gad.BytecodeCodec.
+func (Codec) EncodeBytecode(bc *gad.Bytecode, w io.Writer) error {
+	return EncodeBytecodeTo(bc, w)
+}
+
+// DecodeBytecode implements gad.BytecodeCodec.
+func (Codec) DecodeBytecode(r io.Reader, modules *gad.ModuleMap) (*gad.Bytecode, error) {
+	return DecodeBytecodeFrom(r, modules)
+}
+
 // Encode writes encoded data of Bytecode to writer.
 func (bc *Bytecode) Encode(w io.Writer) error {
 	data, err := bc.MarshalBinary()
@@ -5,12 +5,14 @@ import (
 	"io"
 	"io/ioutil"
 	"os"
+	"path/filepath"
 	"testing"
 	gotime "time"
 
 	"github.com/gad-lang/gad"
 	"github.com/stretchr/testify/require"
 
+	"github.com/gad-lang/gad/importers"
 	"github.com/gad-lang/gad/stdlib/fmt"
 	"github.com/gad-lang/gad/stdlib/json"
 	"github.com/gad-lang/gad/stdlib/strings"
@@ -81,6 +83,113 @@ func TestBytecode_file(t *testing.T) {
 	testBytecodesEqual(t, bc, got)
 }
 
+func TestDecodeVerifiedBytecodeFrom(t *testing.T) {
+	bc, err := gad.Compile([]byte(`a := 1; return a + 1`), gad.DefaultCompileOptions)
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, EncodeBytecodeTo(bc, &buf))
+	got, err := DecodeVerifiedBytecodeFrom(bytes.NewReader(buf.Bytes()), nil, nil)
+	require.NoError(t, err)
+	ret, err := gad.NewVM(got).Run(nil)
+	require.NoError(t, err)
+	require.Equal(t, gad.Int(2), ret)
+
+	buf.Reset()
+	bc.Main.Instructions = append([]byte{}, bc.Main.Instructions...)
+	bc.Main.Instructions[0] = 255
+	require.NoError(t, EncodeBytecodeTo(bc, &buf))
+	_, err = DecodeVerifiedBytecodeFrom(bytes.NewReader(buf.Bytes()), nil, nil)
+	require.ErrorIs(t, err, gad.ErrInvalidBytecode)
+}
+
+func TestBytecode_deterministic(t *testing.T) {
+	src := []byte(`
+const (a = 1, b = "x", c)
+const cd = {x: 1, y: 2, z: [1, {a: 2, b: 3}]}
+x := {a: 1, b: 2, c: 3, d: {e: 4, f: 5}, "g h": [1, 2]}
+f := func(p, *q; r=1, s=2, **t) { return p + r + s }
+y := func() { return x.a + f(1; s=3) }
+strings := import("strings")
+json := import("json")
+srcmod := import("srcmod")
+global (g1, g2)
+g1 = 1; g2 = 2
+try { throw "x" } catch e { g2 = e } finally { g1++ }
+for k, v in x { g1 += v }
+return [x, y(), srcmod.z, strings.ToUpper("a"), json.Marshal(cd), __name__]
+`)
+
+	compile := func() []byte {
+		opts := gad.DefaultCompilerOptions
+		opts.ModuleMap = gad.NewModuleMap().
+			AddBuiltinModule("strings", strings.Module).
+			AddBuiltinModule("json", json.Module).
+			AddSourceModule("srcmod", []byte(`return {z: {m: 1, n: func() { return 2 }}}`))
+		bc, err := gad.Compile(src, gad.CompileOptions{CompilerOptions: opts})
+		require.NoError(t, err)
+
+		var buf bytes.Buffer
+		require.NoError(t, EncodeBytecodeTo(bc, &buf))
+		return buf.Bytes()
+	}
+
+	want := compile()
+	for i := 0; i < 20; i++ {
+		require.Equal(t, want, compile())
+	}
+}
+
+func TestCachedCompile(t *testing.T) {
+	dir := t.TempDir()
+	cacheDir := filepath.Join(dir, "cache")
+	lib := filepath.Join(dir, "lib.gad")
+	src := []byte(`lib := import("./lib.gad"); return [lib.v, lib.name, __name__]`)
+
+	compile := func() (gad.Object, bool) {
+		st := gad.NewSymbolTable(gad.NewBuiltins())
+		opts := gad.CompilerOptions{SymbolTable: st}
+		opts.ModuleMap = gad.NewModuleMap().
+			SetExtImporter(&importers.FileImporter{WorkDir: dir})
+		bc, err := gad.CachedCompile(src, gad.CompileOptions{CompilerOptions: opts}, cacheDir)
+		require.NoError(t, err)
+		ret, err := gad.NewVM(bc).Run(nil)
+		require.NoError(t, err)
+		// symbols are defined only if the script is compiled
+		return ret, len(st.Symbols()) > 0
+	}
+	entries := func() int {
+		files, err := filepath.Glob(filepath.Join(cacheDir, "*", "*"))
+		require.NoError(t, err)
+		return len(files)
+	}
+
+	require.NoError(t, os.WriteFile(lib, []byte(`return {v: 1, name: __name__}`), 0o644))
+	want := gad.Array{gad.Int(1), gad.Str(lib), gad.Str(gad.MainName)}
+	ret, compiled := compile()
+	require.Equal(t, want, ret)
+	require.True(t, compiled)
+	require.Equal(t, 1, entries())
+
+	ret, compiled = compile()
+	require.Equal(t, want, ret)
+	require.False(t, compiled)
+
+	// changing an imported module invalidates the cached bytecode
+	require.NoError(t, os.WriteFile(lib, []byte(`return {v: 2, name: __name__}`), 0o644))
+	ret, compiled = compile()
+	require.Equal(t, gad.Array{gad.Int(2), gad.Str(lib), gad.Str(gad.MainName)}, ret)
+	require.True(t, compiled)
+	require.Equal(t, 1, entries())
+
+	// corrupted entries are ignored
+	files, _ := filepath.Glob(filepath.Join(cacheDir, "*", "*"))
+	require.NoError(t, os.WriteFile(files[0], []byte("{}\ngarbage"), 0o644))
+	ret, compiled = compile()
+	require.Equal(t, gad.Int(2), ret.(gad.Array)[0])
+	require.True(t, compiled)
+}
+
 func TestBytecode_full(t *testing.T) {
 	src := `
 fmt := import("fmt")
@@ -178,6 +287,7 @@ func testBytecodesEqual(t *testing.T, want, got *gad.Bytecode) {
 		"expected:%s\nactual:%s", tests.Sdump(want.Constants), tests.Sdump(want.Constants))
 	testBytecodeConstants(t, gad.NewVM(got).Init(), want.Constants, got.Constants)
 	require.Equal(t, want.NumModules, got.NumModules)
+	require.Equal(t, want.ModuleNames, got.ModuleNames)
 }
 
 func logmicros(t *testing.T, format string, f func()) {
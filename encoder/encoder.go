@@ -104,6 +104,8 @@ func init() {
 	gob.Register((*json.RawMessage)(nil))
 	gob.Register((*gad.SymbolInfo)(nil))
 	gob.Register(([]*gad.SymbolInfo)(nil))
+
+	gad.DefaultBytecodeCodec = Codec{}
 }
 
 // MarshalBinary implements encoding.BinaryMarshaler
@@ -228,6 +230,22 @@ func (bc *Bytecode) bytecodeV1Encoder(w io.Writer) (err error) {
 			return
 		}
 	}
+
+	// ModuleNames, field #4
+	if len(bc.ModuleNames) > 0 {
+		_ = writeByteTo(w, 4)
+		names := make(Array, len(bc.ModuleNames))
+		for i, name := range bc.ModuleNames {
+			names[i] = gad.Str(name)
+		}
+		var data []byte
+		if data, err = names.MarshalBinary(); err != nil {
+			return
+		}
+		if _, err = w.Write(data); err != nil {
+			return
+		}
+	}
 	return nil
 }
 
@@ -248,7 +266,10 @@ func (bc *Bytecode) bytecodeV1Decoder(r *bytes.Buffer) error {
 				return err
 			}
 
-			sz := obj.(gad.Int)
+			sz, ok := obj.(gad.Int)
+			if !ok || sz > gad.Int(r.Len()) {
+				return errors.New("invalid file set size")
+			}
 			if sz <= 0 {
 				continue
 			}
@@ -269,21 +290,51 @@ func (bc *Bytecode) bytecodeV1Decoder(r *bytes.Buffer) error {
 				return err
 			}
 
-			bc.Main = f.(*gad.CompiledFunction)
+			main, ok := f.(*gad.CompiledFunction)
+			if !ok {
+				return errors.New("invalid main function")
+			}
+			bc.Main = main
 		case 2:
 			obj, err := DecodeObject(r)
 			if err != nil {
 				return err
 			}
 
-			bc.Constants = obj.(gad.Array)
+			constants, ok := obj.(gad.Array)
+			if !ok {
+				return errors.New("invalid constants")
+			}
+			bc.Constants = constants
 		case 3:
 			num, err := DecodeObject(r)
 			if err != nil {
 				return err
 			}
 
-			bc.NumModules = int(num.(gad.Int))
+			n, ok := num.(gad.Int)
+			if !ok {
+				return errors.New("invalid number of modules")
+			}
+			bc.NumModules = int(n)
+		case 4:
+			obj, err := DecodeObject(r)
+			if err != nil {
+				return err
+			}
+
+			names, ok := obj.(gad.Array)
+			if !ok {
+				return errors.New("invalid module names")
+			}
+			bc.ModuleNames = make([]string, len(names))
+			for i, name := range names {
+				s, ok := name.(gad.Str)
+				if !ok {
+					return errors.New("invalid module names")
+				}
+				bc.ModuleNames[i] = string(s)
+			}
 		default:
 			return errors.New("unknown field:" + strconv.Itoa(int(field)))
 		}
@@ -442,7 +493,7 @@ func DecodeObject(r io.Reader) (gad.Object, error) {
 			if err := v.UnmarshalBinary(buf); err != nil {
 				return nil, err
 			}
-			return gad.Str(v), nil
+			return gad.Str(gad.Intern(string(v))), nil
 		case binMapV1:
 			var v = Map{}
 			if err := v.UnmarshalBinary(buf); err != nil {
@@ -366,6 +366,12 @@ func TestEncDecObjects(t *testing.T) {
 		compFunc(nil,
 			withSourceMap(map[int]int{0: 1, 3: 1, 5: 1}),
 		),
+		compFunc(nil,
+			withReturnType(
+				&gad.SymbolInfo{Name: "int", Index: int(gad.BuiltinInt), Scope: gad.ScopeBuiltin},
+				&gad.SymbolInfo{Name: "str", Index: int(gad.BuiltinStr), Scope: gad.ScopeBuiltin},
+			),
+		),
 		compFunc(concatInsts(
 			makeInst(gad.OpConstant, 0),
 			makeInst(gad.OpConstant, 1),
@@ -557,6 +563,7 @@ func testDecodedBytecodeEqual(t *testing.T, actual, decoded *gad.Bytecode) {
 	testBytecodeConstants(t, gad.NewVM(actual).Init(), actual.Constants, decoded.Constants)
 	require.Equal(t, actual.Main, decoded.Main, msg)
 	require.Equal(t, actual.NumModules, decoded.NumModules, msg)
+	require.Equal(t, actual.ModuleNames, decoded.ModuleNames, msg)
 	if actual.FileSet == nil {
 		require.Nil(t, decoded.FileSet, msg)
 	} else {
@@ -665,6 +672,12 @@ func withLocals(numLocals int) funcOpt {
 	}
 }
 
+func withReturnType(types ...*gad.SymbolInfo) funcOpt {
+	return func(cf *gad.CompiledFunction) {
+		cf.ReturnType = types
+	}
+}
+
 func withSourceMap(m map[int]int) funcOpt {
 	return func(cf *gad.CompiledFunction) {
 		cf.SourceMap = m
@@ -774,3 +787,9 @@ func randStringWithCharset(length int, charset string) string {
 func randString(length int) string {
 	return randStringWithCharset(length, charset)
 }
+
+func TestEncDecRandomObjects(t *testing.T) {
+	gad.TestRandObjects(t, 1, 200, 3, func(t *testing.T, o gad.Object) {
+		gad.TestEncodeRoundTrip(t, Codec{}, o)
+	})
+}
@@ -5,6 +5,7 @@ import (
 	"encoding/binary"
 	"encoding/gob"
 	"math"
+	"sort"
 
 	"github.com/gad-lang/gad"
 	"github.com/shopspring/decimal"
@@ -187,7 +188,15 @@ func (o Map) MarshalBinary() ([]byte, error) {
 	var tmpBuf bytes.Buffer
 	var vi varintConv
 
-	for k, v := range o {
+	// keys are sorted to encode same map to same bytes
+	keys := make([]string, 0, len(o))
+	for k := range o {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		v := o[k]
 		b := vi.toBytes(int64(len(k)))
 		tmpBuf.Write(b)
 		tmpBuf.WriteString(k)
@@ -316,6 +325,27 @@ func (o *CompiledFunction) MarshalBinary() ([]byte, error) {
 		}
 	}
 
+	if len(o.ReturnType) > 0 {
+		// ReturnType field #9
+		tmpBuf.WriteByte(9)
+		symbols := make(gad.Array, len(o.ReturnType))
+		for i, info := range o.ReturnType {
+			symbols[i] = info
+		}
+		b, _ := Array(symbols).MarshalBinary()
+		tmpBuf.Write(b)
+	}
+
+	if len(o.LocalNames) > 0 {
+		// LocalNames field #10
+		tmpBuf.WriteByte(10)
+		tmpBuf.Write(vi.toBytes(int64(len(o.LocalNames))))
+		for _, name := range o.LocalNames {
+			b, _ := String(name).MarshalBinary()
+			tmpBuf.Write(b)
+		}
+	}
+
 	// Ignore Free variables, doesn't make sense
 
 	if o.SourceMap != nil {
@@ -323,10 +353,15 @@ func (o *CompiledFunction) MarshalBinary() ([]byte, error) {
 		tmpBuf.WriteByte(8)
 		b := vi.toBytes(int64(len(o.SourceMap) * 2))
 		tmpBuf.Write(b)
-		for key, value := range o.SourceMap {
+		keys := make([]int, 0, len(o.SourceMap))
+		for key := range o.SourceMap {
+			keys = append(keys, key)
+		}
+		sort.Ints(keys)
+		for _, key := range keys {
 			b = vi.toBytes(int64(key))
 			tmpBuf.Write(b)
-			b = vi.toBytes(int64(value))
+			b = vi.toBytes(int64(o.SourceMap[key]))
 			tmpBuf.Write(b)
 		}
 	}
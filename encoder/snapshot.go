@@ -0,0 +1,503 @@
+package encoder
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/gad-lang/gad"
+)
+
+// Snapshot signature and version are written to the header of encoded
+// Snapshot.
+const (
+	SnapshotSignature uint32 = 0x75474F53
+	SnapshotVersion   uint16 = 1
+)
+
+const (
+	slotNilV1 byte = iota
+	slotObjectV1
+	slotPtrV1
+	slotFuncV1
+	slotNamedArgsV1
+)
+
+// Function references of encoded Snapshot. Other references are constant
+// indexes of Bytecode.
+const (
+	funcRefMain   = -1
+	funcRefInline = -2
+)
+
+// EncodeSnapshotTo encodes the snapshot of a VM suspended while running bc to
+// w io.Writer. Compiled functions are encoded as references to bc, so the
+// snapshot must be decoded with the same Bytecode.
+// Variables shared by closures are kept shared, except closures nested in
+// containers like arrays and dicts which are encoded without their free
+// variables.
+func EncodeSnapshotTo(bc *gad.Bytecode, s *gad.Snapshot, w io.Writer) error {
+	e := &snapshotEncoder{bc: bc, ptrs: map[*gad.ObjectPtr]int{}}
+	e.collect(s.Value)
+	for _, o := range s.Stack {
+		e.collect(o)
+	}
+	for _, f := range s.Frames {
+		e.collect(f.Fn)
+		for _, p := range f.Free {
+			e.addPtr(p)
+		}
+		for _, arr := range f.Args {
+			for _, o := range arr {
+				e.collect(o)
+			}
+		}
+	}
+
+	sig := make([]byte, 6)
+	binary.BigEndian.PutUint32(sig, SnapshotSignature)
+	binary.BigEndian.PutUint16(sig[4:], SnapshotVersion)
+	e.buf.Write(sig)
+
+	e.writeInt(len(e.list))
+	for _, p := range e.list {
+		if err := e.writeSlot(*p.Value); err != nil {
+			return err
+		}
+	}
+
+	if err := e.writeSlot(s.Value); err != nil {
+		return err
+	}
+
+	e.writeInt(len(s.Stack))
+	for _, o := range s.Stack {
+		if err := e.writeSlot(o); err != nil {
+			return err
+		}
+	}
+
+	e.writeInt(len(s.Frames))
+	for _, f := range s.Frames {
+		if err := e.writeFrame(f); err != nil {
+			return err
+		}
+	}
+
+	_, err := w.Write(e.buf.Bytes())
+	return err
+}
+
+// DecodeSnapshotFrom decodes *gad.Snapshot from given r io.Reader. Bytecode
+// must be the one used to encode the snapshot.
+func DecodeSnapshotFrom(bc *gad.Bytecode, r io.Reader) (*gad.Snapshot, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(data) < 6 {
+		return nil, errors.New("invalid snapshot data")
+	}
+
+	if binary.BigEndian.Uint32(data[0:4]) != SnapshotSignature {
+		return nil, errors.New("snapshot signature mismatch")
+	}
+
+	if v := binary.BigEndian.Uint16(data[4:6]); v != SnapshotVersion {
+		return nil, fmt.Errorf("unsupported snapshot version: %d", v)
+	}
+
+	d := &snapshotDecoder{bc: bc}
+	d.vi.reader = bytes.NewReader(data[6:])
+
+	var n int
+	if n, err = d.readLen(); err != nil {
+		return nil, err
+	}
+
+	d.ptrs = make([]*gad.ObjectPtr, n)
+	for i := range d.ptrs {
+		var v gad.Object = gad.Nil
+		d.ptrs[i] = &gad.ObjectPtr{Value: &v}
+	}
+
+	for _, p := range d.ptrs {
+		if *p.Value, err = d.readSlot(); err != nil {
+			return nil, err
+		}
+	}
+
+	s := &gad.Snapshot{}
+	if s.Value, err = d.readSlot(); err != nil {
+		return nil, err
+	}
+
+	if n, err = d.readLen(); err != nil {
+		return nil, err
+	}
+
+	s.Stack = make([]gad.Object, n)
+	for i := range s.Stack {
+		if s.Stack[i], err = d.readSlot(); err != nil {
+			return nil, err
+		}
+	}
+
+	if n, err = d.readLen(); err != nil {
+		return nil, err
+	}
+
+	s.Frames = make([]*gad.SnapshotFrame, n)
+	for i := range s.Frames {
+		if s.Frames[i], err = d.readFrame(); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+type snapshotEncoder struct {
+	bc   *gad.Bytecode
+	ptrs map[*gad.ObjectPtr]int
+	list []*gad.ObjectPtr
+	buf  bytes.Buffer
+	vi   varintConv
+}
+
+func (e *snapshotEncoder) addPtr(p *gad.ObjectPtr) {
+	if _, ok := e.ptrs[p]; ok {
+		return
+	}
+	e.ptrs[p] = len(e.list)
+	e.list = append(e.list, p)
+	e.collect(*p.Value)
+}
+
+func (e *snapshotEncoder) collect(o gad.Object) {
+	switch v := o.(type) {
+	case *gad.ObjectPtr:
+		e.addPtr(v)
+	case *gad.CompiledFunction:
+		for _, p := range v.Free {
+			e.addPtr(p)
+		}
+	case *gad.NamedArgs:
+		for _, kv := range v.Join() {
+			e.collect(kv.V)
+		}
+	}
+}
+
+func (e *snapshotEncoder) writeInt(v int) {
+	e.buf.Write(e.vi.toBytes(int64(v)))
+}
+
+func (e *snapshotEncoder) writeSlot(o gad.Object) error {
+	switch v := o.(type) {
+	case nil:
+		e.buf.WriteByte(slotNilV1)
+	case *gad.ObjectPtr:
+		e.buf.WriteByte(slotPtrV1)
+		e.writeInt(e.ptrs[v])
+	case *gad.CompiledFunction:
+		e.buf.WriteByte(slotFuncV1)
+		if err := e.writeFunc(v); err != nil {
+			return err
+		}
+		e.writePtrs(v.Free)
+	case *gad.NamedArgs:
+		e.buf.WriteByte(slotNamedArgsV1)
+		pairs := v.Join()
+		e.writeInt(len(pairs))
+		for _, kv := range pairs {
+			if err := e.writeSlot(kv.K); err != nil {
+				return err
+			}
+			if err := e.writeSlot(kv.V); err != nil {
+				return err
+			}
+		}
+	default:
+		e.buf.WriteByte(slotObjectV1)
+		data, err := Array{o}.MarshalBinary()
+		if err != nil {
+			return err
+		}
+		e.buf.Write(data)
+	}
+	return nil
+}
+
+func (e *snapshotEncoder) writePtrs(ptrs []*gad.ObjectPtr) {
+	e.writeInt(len(ptrs))
+	for _, p := range ptrs {
+		e.writeInt(e.ptrs[p])
+	}
+}
+
+func (e *snapshotEncoder) writeFunc(f *gad.CompiledFunction) error {
+	if sameFunc(f, e.bc.Main) {
+		e.writeInt(funcRefMain)
+		return nil
+	}
+
+	for i, c := range e.bc.Constants {
+		if c, _ := c.(*gad.CompiledFunction); c != nil && sameFunc(f, c) {
+			e.writeInt(i)
+			return nil
+		}
+	}
+
+	e.writeInt(funcRefInline)
+	f = f.Copy().(*gad.CompiledFunction)
+	data, err := (*CompiledFunction)(f).MarshalBinary()
+	if err != nil {
+		return err
+	}
+	e.buf.Write(data)
+	return nil
+}
+
+func (e *snapshotEncoder) writeFrame(f *gad.SnapshotFrame) error {
+	if err := e.writeFunc(f.Fn); err != nil {
+		return err
+	}
+	e.writePtrs(f.Free)
+	e.writeInt(f.IP)
+	e.writeInt(f.BasePointer)
+
+	e.writeInt(len(f.Args))
+	for _, arr := range f.Args {
+		e.writeInt(len(arr))
+		for _, o := range arr {
+			if err := e.writeSlot(o); err != nil {
+				return err
+			}
+		}
+	}
+
+	var namedArgs gad.Object
+	if f.NamedArgs != nil {
+		namedArgs = f.NamedArgs
+	}
+	if err := e.writeSlot(namedArgs); err != nil {
+		return err
+	}
+
+	e.writeInt(len(f.Handlers))
+	for _, h := range f.Handlers {
+		e.writeInt(h.SP)
+		e.writeInt(h.Catch)
+		e.writeInt(h.Finally)
+		e.writeInt(h.ReturnTo)
+	}
+	return nil
+}
+
+type snapshotDecoder struct {
+	bc   *gad.Bytecode
+	ptrs []*gad.ObjectPtr
+	vi   varintConv
+}
+
+func (d *snapshotDecoder) readInt() (int, error) {
+	v, err := d.vi.read()
+	return int(v), err
+}
+
+// readLen reads the length of a list whose items are encoded at least in one
+// byte, so it cannot exceed the remaining data.
+func (d *snapshotDecoder) readLen() (int, error) {
+	n, err := d.readInt()
+	if err == nil && (n < 0 || n > d.vi.reader.Len()) {
+		err = fmt.Errorf("invalid snapshot length: %d", n)
+	}
+	return n, err
+}
+
+func (d *snapshotDecoder) readSlot() (gad.Object, error) {
+	typ, err := d.vi.reader.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+
+	switch typ {
+	case slotNilV1:
+		return nil, nil
+	case slotPtrV1:
+		return d.readPtr()
+	case slotFuncV1:
+		f, err := d.readFunc()
+		if err != nil {
+			return nil, err
+		}
+		free, err := d.readPtrs()
+		if err != nil {
+			return nil, err
+		}
+		if free != nil {
+			f = f.Closure(free)
+		}
+		return f, nil
+	case slotNamedArgsV1:
+		n, err := d.readLen()
+		if err != nil {
+			return nil, err
+		}
+		pairs := make(gad.KeyValueArray, n)
+		for i := range pairs {
+			kv := &gad.KeyValue{}
+			if kv.K, err = d.readSlot(); err != nil {
+				return nil, err
+			}
+			if kv.V, err = d.readSlot(); err != nil {
+				return nil, err
+			}
+			pairs[i] = kv
+		}
+		return gad.NewNamedArgs(pairs), nil
+	case slotObjectV1:
+		o, err := DecodeObject(d.vi.reader)
+		if err != nil {
+			return nil, err
+		}
+		arr, ok := o.(gad.Array)
+		if !ok || len(arr) != 1 {
+			return nil, errors.New("invalid snapshot object")
+		}
+		return arr[0], nil
+	default:
+		return nil, fmt.Errorf("invalid snapshot slot type: %d", typ)
+	}
+}
+
+func (d *snapshotDecoder) readPtr() (*gad.ObjectPtr, error) {
+	i, err := d.readInt()
+	if err != nil {
+		return nil, err
+	}
+	if i < 0 || i >= len(d.ptrs) {
+		return nil, fmt.Errorf("invalid snapshot pointer: %d", i)
+	}
+	return d.ptrs[i], nil
+}
+
+func (d *snapshotDecoder) readPtrs() (ptrs []*gad.ObjectPtr, err error) {
+	var n int
+	if n, err = d.readLen(); err != nil || n == 0 {
+		return
+	}
+
+	ptrs = make([]*gad.ObjectPtr, n)
+	for i := range ptrs {
+		if ptrs[i], err = d.readPtr(); err != nil {
+			return
+		}
+	}
+	return
+}
+
+func (d *snapshotDecoder) readFunc() (*gad.CompiledFunction, error) {
+	ref, err := d.readInt()
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case ref == funcRefMain:
+		return d.bc.Main, nil
+	case ref == funcRefInline:
+		o, err := DecodeObject(d.vi.reader)
+		if err != nil {
+			return nil, err
+		}
+		if f, ok := o.(*gad.CompiledFunction); ok {
+			return f, nil
+		}
+	case ref >= 0 && ref < len(d.bc.Constants):
+		if f, ok := d.bc.Constants[ref].(*gad.CompiledFunction); ok {
+			return f, nil
+		}
+	}
+	return nil, fmt.Errorf("invalid snapshot function reference: %d", ref)
+}
+
+func (d *snapshotDecoder) readFrame() (f *gad.SnapshotFrame, err error) {
+	f = &gad.SnapshotFrame{}
+	if f.Fn, err = d.readFunc(); err != nil {
+		return
+	}
+	if f.Free, err = d.readPtrs(); err != nil {
+		return
+	}
+	if len(f.Free) > 0 {
+		f.Fn = f.Fn.Closure(f.Free)
+	}
+	if f.IP, err = d.readInt(); err != nil {
+		return
+	}
+	if f.BasePointer, err = d.readInt(); err != nil {
+		return
+	}
+
+	var n int
+	if n, err = d.readLen(); err != nil {
+		return
+	}
+
+	if n > 0 {
+		f.Args = make(gad.Args, n)
+		for i := range f.Args {
+			var l int
+			if l, err = d.readLen(); err != nil {
+				return
+			}
+			f.Args[i] = make(gad.Array, l)
+			for j := range f.Args[i] {
+				if f.Args[i][j], err = d.readSlot(); err != nil {
+					return
+				}
+			}
+		}
+	}
+
+	var namedArgs gad.Object
+	if namedArgs, err = d.readSlot(); err != nil {
+		return
+	}
+	f.NamedArgs, _ = namedArgs.(*gad.NamedArgs)
+
+	if n, err = d.readLen(); err != nil {
+		return
+	}
+
+	f.Handlers = make([]gad.SnapshotErrHandler, n)
+	for i := range f.Handlers {
+		h := &f.Handlers[i]
+		if h.SP, err = d.readInt(); err != nil {
+			return
+		}
+		if h.Catch, err = d.readInt(); err != nil {
+			return
+		}
+		if h.Finally, err = d.readInt(); err != nil {
+			return
+		}
+		if h.ReturnTo, err = d.readInt(); err != nil {
+			return
+		}
+	}
+	return
+}
+
+// sameFunc reports whether f is a or a closure of a.
+func sameFunc(f, a *gad.CompiledFunction) bool {
+	if f == a {
+		return true
+	}
+	return a != nil && len(f.Instructions) > 0 && len(a.Instructions) > 0 &&
+		&f.Instructions[0] == &a.Instructions[0]
+}
@@ -0,0 +1,88 @@
+package encoder_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/gad-lang/gad"
+	. "github.com/gad-lang/gad/encoder"
+)
+
+func TestSnapshot(t *testing.T) {
+	bc, err := gad.Compile([]byte(`
+param (start, **opts)
+counter := 0
+inc := func(n) { counter += n; return counter }
+wait := func(name) {
+	try {
+		return suspend({name: name, counter: counter})
+	} finally {
+		inc(1)
+	}
+}
+inc(start)
+a := wait("first")
+b := wait("second")
+return [a, b, counter, inc(100), opts.x]`), gad.CompileOptions{})
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, EncodeBytecodeTo(bc, &buf))
+
+	vm := gad.NewVM(bc)
+	_, err = vm.RunOpts(&gad.RunOpts{
+		Args:      gad.Args{gad.Array{gad.Int(5)}},
+		NamedArgs: gad.NewNamedArgs(gad.KeyValueArray{&gad.KeyValue{K: gad.Str("x"), V: gad.Str("y")}}),
+	})
+	require.ErrorIs(t, err, gad.ErrVMSuspended)
+
+	// resume the snapshot twice in "another process" with decoded Bytecode
+	resume := func(s *gad.Snapshot, value gad.Object) (*gad.VM, gad.Object, error) {
+		t.Helper()
+		bc2, err := DecodeBytecodeFrom(bytes.NewReader(buf.Bytes()), nil)
+		require.NoError(t, err)
+
+		var sbuf bytes.Buffer
+		require.NoError(t, EncodeSnapshotTo(bc, s, &sbuf))
+		s2, err := DecodeSnapshotFrom(bc2, &sbuf)
+		require.NoError(t, err)
+
+		vm := gad.NewVM(bc2)
+		ret, err := vm.Resume(s2, value, nil)
+		return vm, ret, err
+	}
+
+	s := vm.Snapshot()
+	require.Equal(t, gad.Dict{"name": gad.Str("first"), "counter": gad.Int(5)}, s.Value)
+
+	vm, _, err = resume(s, gad.Int(1))
+	require.ErrorIs(t, err, gad.ErrVMSuspended)
+	s = vm.Snapshot()
+	require.Equal(t, gad.Dict{"name": gad.Str("second"), "counter": gad.Int(6)}, s.Value)
+
+	// the snapshot is still valid after encoding
+	_, ret, err := resume(s, gad.Int(2))
+	require.NoError(t, err)
+	require.Equal(t, gad.Array{gad.Int(1), gad.Int(2), gad.Int(7), gad.Int(107), gad.Str("y")}, ret)
+	_, ret, err = resume(s, gad.Int(3))
+	require.NoError(t, err)
+	require.Equal(t, gad.Array{gad.Int(1), gad.Int(3), gad.Int(7), gad.Int(107), gad.Str("y")}, ret)
+
+	_, err = DecodeSnapshotFrom(bc, bytes.NewReader([]byte{1, 2, 3, 4, 5, 6}))
+	require.Error(t, err)
+
+	// truncated and malformed snapshots return errors
+	var sbuf bytes.Buffer
+	require.NoError(t, EncodeSnapshotTo(bc, s, &sbuf))
+	data := sbuf.Bytes()
+	for i := 6; i < len(data); i++ {
+		_, err = DecodeSnapshotFrom(bc, bytes.NewReader(data[:i]))
+		require.Error(t, err, i)
+	}
+	// a huge number of pointers
+	huge := append(append([]byte{}, data[:6]...), 9, 0xfe, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x7f)
+	_, err = DecodeSnapshotFrom(bc, bytes.NewReader(huge))
+	require.ErrorContains(t, err, "invalid snapshot length")
+}
@@ -470,6 +470,29 @@ func (o *CompiledFunction) UnmarshalBinary(data []byte) error {
 				}
 				o.SourceMap[int(key)] = int(value)
 			}
+		case 9:
+			types, err := DecodeObject(rd)
+			if err != nil {
+				return err
+			}
+			typesArr := types.(gad.Array)
+			o.ReturnType = make(gad.ParamType, len(typesArr))
+			for i, object := range typesArr {
+				o.ReturnType[i] = object.(*gad.SymbolInfo)
+			}
+		case 10:
+			v, err := vi.read()
+			if err != nil {
+				return err
+			}
+			o.LocalNames = make([]string, v)
+			for i := range o.LocalNames {
+				name, err := DecodeObject(rd)
+				if err != nil {
+					return err
+				}
+				o.LocalNames[i] = string(name.(gad.Str))
+			}
 		default:
 			return errors.New("unknown field:" + strconv.Itoa(int(field)))
 		}
@@ -5,7 +5,9 @@
 package gad
 
 import (
+	"errors"
 	"fmt"
+	"strconv"
 	"strings"
 )
 
@@ -24,6 +26,9 @@ var (
 	// ErrVMAborted represents a VM aborted error.
 	ErrVMAborted = &Error{Name: "VMAbortedError"}
 
+	// ErrVMSuspended represents a VM suspended by suspend builtin.
+	ErrVMSuspended = &Error{Name: "VMSuspendedError"}
+
 	// ErrWrongNumArguments represents a wrong number of arguments error.
 	ErrWrongNumArguments = &Error{Name: "WrongNumberOfArgumentsError"}
 
@@ -57,6 +62,33 @@ var (
 	// ErrZeroDivision is an error where divisor is zero.
 	ErrZeroDivision = &Error{Name: "ZeroDivisionError"}
 
+	// ErrDeadlock represents a deadlock detected by synchronization objects.
+	ErrDeadlock = &Error{Name: "DeadlockError"}
+
+	// ErrPanic represents a Go panic recovered by VM.
+	ErrPanic = &Error{Name: "PanicError"}
+
+	// ErrCyclicImport represents an error where a module is imported while it
+	// is being initialized, e.g. a module imported by it imports it at top
+	// level.
+	ErrCyclicImport = &Error{Name: "CyclicImportError"}
+
+	// ErrModuleChecksum represents an error where the source of an imported
+	// module does not match the expected checksum.
+	ErrModuleChecksum = &Error{Name: "ModuleChecksumError"}
+
+	// ErrBuiltinConflict represents an error where a builtin registered to
+	// Builtins conflicts with a registered one or has an invalid name.
+	ErrBuiltinConflict = &Error{Name: "BuiltinConflictError"}
+
+	// ErrInvalidBytecode represents an error where a bytecode loaded from an
+	// untrusted source fails verification.
+	ErrInvalidBytecode = &Error{Name: "InvalidBytecodeError"}
+
+	// ErrViewInvalidated represents an error where a view of a host buffer is
+	// used after the host invalidated it.
+	ErrViewInvalidated = &Error{Name: "ViewInvalidatedError"}
+
 	// ErrUnexpectedNamedArg is an error where unexpected kwarg.
 	ErrUnexpectedNamedArg = &Error{Name: "ErrUnexpectedNamedArg"}
 
@@ -86,8 +118,90 @@ var (
 
 	// ErrNotWriteable represents a not writeable type error.
 	ErrNotWriteable = &Error{Name: "ErrNotWriteable"}
+
+	// ErrExit represents the error raised by exit builtin whose cause is an
+	// ExitError holding the exit code.
+	ErrExit = &Error{Name: "ExitError"}
+
+	// ErrTaint represents a tainted value passed to a sink, see TaintPolicy.
+	ErrTaint = &Error{Name: "TaintError"}
+
+	// ErrNotTransferable represents a value which cannot be handed to another
+	// VM, see Transfer.
+	ErrNotTransferable = &Error{Name: "NotTransferableError"}
 )
 
+// panicErrors are the errors caused by programming errors rather than thrown
+// values.
+var panicErrors = []*Error{
+	ErrPanic,
+	ErrStackOverflow,
+	ErrWrongNumArguments,
+	ErrInvalidOperator,
+	ErrIndexOutOfBounds,
+	ErrInvalidIndex,
+	ErrNotIterable,
+	ErrNotIndexable,
+	ErrNotIndexAssignable,
+	ErrNotIndexDeletable,
+	ErrNotCallable,
+	ErrNotImplemented,
+	ErrZeroDivision,
+	ErrUnexpectedNamedArg,
+	ErrType,
+	ErrDeadlock,
+}
+
+// IsPanicError reports whether err is caused by a programming error such as
+// index out of bounds, type errors or a Go panic.
+func IsPanicError(err error) bool {
+	var re *RuntimeError
+	if errors.As(err, &re) {
+		return re.Panic
+	}
+	for _, e := range panicErrors {
+		if errors.Is(err, e) {
+			return true
+		}
+	}
+	return false
+}
+
+// GoPanic is the cause of ErrPanic errors which holds the recovered value and
+// the stack of the panicking goroutine.
+type GoPanic struct {
+	Value any
+	Stack []byte
+}
+
+// Error implements error interface.
+func (p *GoPanic) Error() string {
+	return fmt.Sprint(p.Value)
+}
+
+// Unwrap returns ErrPanic.
+func (p *GoPanic) Unwrap() error {
+	return ErrPanic
+}
+
+// ExitError is the cause of ErrExit errors raised by exit builtin to stop the
+// script with an exit code. VM runs the finally blocks and the deferred
+// functions while unwinding the call stack but skips the catch blocks, so the
+// error is returned by VM run methods. Use errors.As to get the exit code.
+type ExitError struct {
+	Code int
+}
+
+// Error implements error interface.
+func (e *ExitError) Error() string {
+	return "exit status " + strconv.Itoa(e.Code)
+}
+
+// Unwrap returns ErrExit.
+func (e *ExitError) Unwrap() error {
+	return ErrExit
+}
+
 // NewOperandTypeError creates a new Error from ErrType.
 func NewOperandTypeError(token, leftType, rightType string) *Error {
 	return ErrType.NewError(
@@ -109,6 +223,25 @@ func NewNamedArgumentTypeError(name, expectType, foundType string) *Error {
 			name, expectType, foundType))
 }
 
+// NewVariableTypeError creates a new Error from ErrType.
+func NewVariableTypeError(name, expectType, foundType string) *Error {
+	return ErrType.NewError(
+		fmt.Sprintf("invalid type for variable '%s': expected %s, found %s",
+			name, expectType, foundType))
+}
+
+// NewReturnTypeError creates a new Error from ErrType.
+func NewReturnTypeError(funcName, expectType, foundType string) *Error {
+	if funcName == "" {
+		funcName = "function"
+	} else {
+		funcName = "function '" + funcName + "'"
+	}
+	return ErrType.NewError(
+		fmt.Sprintf("invalid return type of %s: expected %s, found %s",
+			funcName, expectType, foundType))
+}
+
 // NewIndexTypeError creates a new Error from ErrType.
 func NewIndexTypeError(expectType, foundType string) *Error {
 	return ErrType.NewError(
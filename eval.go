@@ -6,6 +6,7 @@ package gad
 
 import (
 	"context"
+	"fmt"
 )
 
 // Eval compiles and runs scripts within same scope.
@@ -72,6 +73,72 @@ func (r *Eval) Run(ctx context.Context, script []byte) (Object, *Bytecode, error
 	return ret, bytecode, nil
 }
 
+// Close calls the __cleanup__ functions of the source modules imported in the
+// session and removes modules cache.
+func (r *Eval) Close() error {
+	r.ModulesCache = nil
+	return r.VM.Close()
+}
+
+// DefineGlobal declares a global variable in the session and sets its value
+// in Globals, so next Run calls can use it without a global statement.
+func (r *Eval) DefineGlobal(name string, value Object) error {
+	r.undefineBuiltin(name)
+	if _, err := r.Opts.SymbolTable.DefineGlobal(name); err != nil {
+		return err
+	}
+	return r.Globals.IndexSet(r.VM, Str(name), value)
+}
+
+// DefineLocal declares a local variable in the session if it is not declared
+// yet and sets its value.
+func (r *Eval) DefineLocal(name string, value Object) error {
+	r.undefineBuiltin(name)
+	symbol, _ := r.Opts.SymbolTable.DefineLocal(name)
+	if symbol.Scope != ScopeLocal {
+		return fmt.Errorf("%q redeclared in this block", name)
+	}
+
+	if l := symbol.Index + 1; len(r.Locals) < l {
+		r.Locals = append(r.Locals, make([]Object, l-len(r.Locals))...)
+	}
+	if value == nil {
+		value = Nil
+	}
+	r.Locals[symbol.Index] = value
+	return nil
+}
+
+// Undefine removes the variable declared in the session. Value of a local
+// variable is released and a global variable is deleted from Globals if it
+// supports deletion. It returns false if variable is not declared.
+func (r *Eval) Undefine(name string) (bool, error) {
+	symbol, ok := r.Opts.SymbolTable.Undefine(name)
+	if !ok {
+		return false, nil
+	}
+
+	switch symbol.Scope {
+	case ScopeLocal:
+		if symbol.Index < len(r.Locals) {
+			r.Locals[symbol.Index] = nil
+		}
+	case ScopeGlobal:
+		if d, ok := r.Globals.(IndexDeleter); ok {
+			return true, d.IndexDelete(r.VM, Str(name))
+		}
+	}
+	return true, nil
+}
+
+// undefineBuiltin removes the builtin symbol cached by symbol table to allow
+// shadowing it.
+func (r *Eval) undefineBuiltin(name string) {
+	if _, ok := r.Opts.SymbolTable.find(name, ScopeBuiltin); ok {
+		r.Opts.SymbolTable.Undefine(name)
+	}
+}
+
 func (r *Eval) run(ctx context.Context) (ret Object, err error) {
 	ret = Nil
 	doneCh := make(chan struct{})
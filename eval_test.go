@@ -4,6 +4,7 @@ import (
 	"context"
 	"runtime"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 
@@ -237,3 +238,155 @@ func TestEval(t *testing.T) {
 			`Parse Error: expected statement, found '.'`)
 	})
 }
+
+func TestEvalDefine(t *testing.T) {
+	eval := NewEval(CompileOptions{})
+	ctx := context.Background()
+
+	run := func(script string) Object {
+		t.Helper()
+		ret, _, err := eval.Run(ctx, []byte(script))
+		require.NoError(t, err)
+		return ret
+	}
+
+	require.NoError(t, eval.DefineGlobal("g", Int(1)))
+	require.Equal(t, Int(1), run(`g`))
+	require.NoError(t, eval.DefineLocal("a", Int(2)))
+	require.Equal(t, Int(3), run(`a + g`))
+	require.Equal(t, Int(3), run(`b := a + 1; b`))
+	require.NoError(t, eval.DefineLocal("b", Int(10)))
+	require.Equal(t, Int(12), run(`a + b`))
+	require.NoError(t, eval.DefineLocal("c", nil))
+	require.Equal(t, Nil, run(`c`))
+
+	// shadow builtins resolved by previous runs
+	require.Equal(t, Int(1), run(`len([1])`))
+	require.NoError(t, eval.DefineLocal("len", Int(5)))
+	require.Equal(t, Int(5), run(`len`))
+
+	require.Error(t, eval.DefineGlobal("a", Int(1)))
+	require.Error(t, eval.DefineLocal("g", Int(1)))
+
+	ok, err := eval.Undefine("g")
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, Dict{}, eval.Globals)
+	ok, err = eval.Undefine("a")
+	require.NoError(t, err)
+	require.True(t, ok)
+	ok, err = eval.Undefine("a")
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	_, _, err = eval.Run(ctx, []byte(`a`))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), `unresolved reference "a"`)
+	require.Equal(t, Int(10), run(`b`))
+	require.Equal(t, Int(1), run(`a := 1; a`))
+}
+
+func TestEvalClose(t *testing.T) {
+	var n int
+	mm := NewModuleMap().
+		AddBuiltinModule("counter", map[string]Object{
+			"inc": &Function{Name: "inc", Value: func(Call) (Object, error) {
+				n++
+				return Nil, nil
+			}},
+		}).
+		AddSourceModule("mod", []byte(`c := import("counter"); func __cleanup__() { c.inc() }; return 1`))
+	eval := NewEval(CompileOptions{CompilerOptions: CompilerOptions{ModuleMap: mm}})
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		ret, _, err := eval.Run(ctx, []byte(`import("mod")`))
+		require.NoError(t, err)
+		require.Equal(t, Int(1), ret)
+	}
+	require.Equal(t, 0, n)
+	require.NoError(t, eval.Close())
+	require.Equal(t, 1, n)
+	require.Nil(t, eval.ModulesCache)
+}
+
+func TestCompileExpr(t *testing.T) {
+	opts := CompileOptions{CompilerOptions: CompilerOptions{
+		ModuleMap: NewModuleMap().AddBuiltinModule("time", gadtime.Module),
+	}}
+
+	bc, err := CompileExpr([]byte(`age >= 18 && country == "TR"`), opts)
+	require.NoError(t, err)
+	ret, err := NewVM(bc).RunOpts(&RunOpts{Globals: Dict{"age": Int(20), "country": Str("TR")}})
+	require.NoError(t, err)
+	require.Equal(t, True, ret)
+	ret, err = NewVM(bc).RunOpts(&RunOpts{Globals: Dict{"age": Int(10), "country": Str("TR")}})
+	require.NoError(t, err)
+	require.Equal(t, False, ret)
+
+	bc, err = CompileExpr([]byte(`collect(map(list, (v, _) => v * factor))`), opts)
+	require.NoError(t, err)
+	ret, err = NewVM(bc).RunOpts(&RunOpts{Globals: Dict{"list": Array{Int(1), Int(2)}, "factor": Int(3)}})
+	require.NoError(t, err)
+	require.Equal(t, Array{Int(3), Int(6)}, ret)
+
+	bc, err = CompileExpr([]byte(`missing`), opts)
+	require.NoError(t, err)
+	ret, err = NewVM(bc).RunOpts(&RunOpts{Globals: Dict{}})
+	require.NoError(t, err)
+	require.Equal(t, Nil, ret)
+
+	for _, src := range []string{``, `a := 1`, `1; 2`, `return 1`, `if a { b }`} {
+		_, err = CompileExpr([]byte(src), opts)
+		require.ErrorIs(t, err, ErrNotExpression, src)
+	}
+
+	_, err = CompileExpr([]byte(`import("time").Second`), opts)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "import is not allowed in expression")
+
+	_, err = CompileExpr([]byte(`a +`), opts)
+	require.Error(t, err)
+}
+
+func TestExprCache(t *testing.T) {
+	cache := NewExprCache(CompileOptions{})
+
+	bc, err := cache.Compile(`a + b`)
+	require.NoError(t, err)
+	bc2, err := cache.Compile(`a + b`)
+	require.NoError(t, err)
+	require.Same(t, bc, bc2)
+	require.Equal(t, 1, cache.Len())
+
+	_, err = cache.Compile(`a +`)
+	require.Error(t, err)
+	require.Equal(t, 1, cache.Len())
+
+	ret, err := cache.Eval(context.Background(), `a + b`, Dict{"a": Int(1), "b": Int(2)})
+	require.NoError(t, err)
+	require.Equal(t, Int(3), ret)
+	ret, err = cache.Eval(nil, `a + b`, Dict{"a": Str("x"), "b": Str("y")})
+	require.NoError(t, err)
+	require.Equal(t, Str("xy"), ret)
+	ret, err = cache.Eval(nil, `a == nil`, nil)
+	require.NoError(t, err)
+	require.Equal(t, True, ret)
+	require.Equal(t, 2, cache.Len())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = cache.Eval(ctx, `a`, nil)
+	require.ErrorIs(t, err, context.Canceled)
+
+	ctx, cancel = context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = cache.Eval(ctx, `func() { for {} }()`, nil)
+	require.ErrorIs(t, err, ErrVMAborted)
+
+	require.Equal(t, 4, cache.Len())
+	cache.Delete(`a + b`)
+	require.Equal(t, 3, cache.Len())
+	cache.Clear()
+	require.Equal(t, 0, cache.Len())
+}
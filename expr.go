@@ -0,0 +1,148 @@
+package gad
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/gad-lang/gad/parser/node"
+)
+
+// ErrNotExpression is returned by CompileExpr if source is not a single
+// expression.
+var ErrNotExpression = errors.New("source is not a single expression")
+
+// CompileExpr compiles given source which must contain a single expression to
+// Bytecode returning the value of expression. Statements and imports are not
+// allowed. Identifiers which are not resolved by compiler are read from
+// RunOpts.Globals at runtime, so the Bytecode can be run against record Dicts:
+//
+//	bc, _ := CompileExpr([]byte(`age >= 18 && country == "TR"`), CompileOptions{})
+//	ret, _ := NewVM(bc).RunOpts(&RunOpts{Globals: Dict{"age": Int(20), "country": Str("TR")}})
+func CompileExpr(src []byte, opts CompileOptions) (*Bytecode, error) {
+	srcFile, pf, err := parseScript(src, &opts)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(pf.Stmts) != 1 {
+		return nil, ErrNotExpression
+	}
+
+	stmt, ok := pf.Stmts[0].(*node.ExprStmt)
+	if !ok {
+		return nil, ErrNotExpression
+	}
+
+	pf.Stmts[0] = &node.ReturnStmt{Return: node.Return{
+		ReturnPos: stmt.Pos(),
+		Result:    stmt.Expr,
+	}}
+
+	opts.exprMode = true
+	return compileFile(srcFile, pf, opts)
+}
+
+// ExprCache compiles expressions with CompileExpr and caches the Bytecode by
+// the source. It is safe to use concurrently.
+type ExprCache struct {
+	opts  CompileOptions
+	mu    sync.RWMutex
+	items map[string]*Bytecode
+}
+
+// NewExprCache returns new ExprCache which compiles expressions with given
+// options.
+func NewExprCache(opts CompileOptions) *ExprCache {
+	return &ExprCache{
+		opts:  opts,
+		items: make(map[string]*Bytecode),
+	}
+}
+
+// Compile returns cached Bytecode of expression or compiles and caches it.
+// Compile errors are not cached.
+func (c *ExprCache) Compile(src string) (*Bytecode, error) {
+	c.mu.RLock()
+	bc := c.items[src]
+	c.mu.RUnlock()
+	if bc != nil {
+		return bc, nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if bc = c.items[src]; bc != nil {
+		return bc, nil
+	}
+
+	bc, err := CompileExpr([]byte(src), c.opts)
+	if err != nil {
+		return nil, err
+	}
+	c.items[src] = bc
+	return bc, nil
+}
+
+// Eval compiles expression if it is not cached and runs it with given globals
+// in a new VM. If context is done before VM stops, VM is aborted.
+func (c *ExprCache) Eval(ctx context.Context, src string, globals IndexGetSetter) (ret Object, err error) {
+	var bc *Bytecode
+	if bc, err = c.Compile(src); err != nil {
+		return
+	}
+
+	if globals == nil {
+		globals = Dict{}
+	}
+
+	vm := NewVM(bc)
+	if ctx == nil {
+		return vm.RunOpts(&RunOpts{Globals: globals})
+	}
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	default:
+	}
+
+	doneCh := make(chan struct{})
+	go func() {
+		defer close(doneCh)
+		ret, err = vm.RunOpts(&RunOpts{Globals: globals})
+	}()
+
+	select {
+	case <-ctx.Done():
+		vm.Abort()
+		<-doneCh
+		if err == nil {
+			err = ctx.Err()
+		}
+	case <-doneCh:
+	}
+	return
+}
+
+// Len returns the number of cached expressions.
+func (c *ExprCache) Len() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return len(c.items)
+}
+
+// Delete removes expression from cache.
+func (c *ExprCache) Delete(src string) {
+	c.mu.Lock()
+	delete(c.items, src)
+	c.mu.Unlock()
+}
+
+// Clear removes all cached expressions.
+func (c *ExprCache) Clear() {
+	c.mu.Lock()
+	c.items = make(map[string]*Bytecode)
+	c.mu.Unlock()
+}
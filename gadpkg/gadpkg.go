@@ -0,0 +1,270 @@
+// Package gadpkg implements package archives (.gadpkg) which bundle
+// precompiled modules of a script library into a single file.
+//
+// An archive is a zip file containing "gadpkg.json" manifest with the name,
+// the version and the checksums of the modules, and the encoded bytecode of
+// every module under "modules/" directory. Modules of an archive are linked
+// into the importing script while compiling it, so their sources are not
+// required to run it.
+package gadpkg
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+
+	"github.com/gad-lang/gad"
+	"github.com/gad-lang/gad/encoder"
+	"github.com/gad-lang/gad/importers"
+	"github.com/gad-lang/gad/parser/ast"
+)
+
+// Ext is the file extension of package archives.
+const Ext = ".gadpkg"
+
+const (
+	manifestName = "gadpkg.json"
+	modulesDir   = "modules/"
+)
+
+// Manifest describes a package archive.
+type Manifest struct {
+	Name    string   `json:"name"`
+	Version string   `json:"version"`
+	Modules []Module `json:"modules"`
+}
+
+// Module is a module of a package archive.
+type Module struct {
+	// Name is the slash separated path of the module source in the package
+	// directory, e.g. "index.gad" or "internal/util.gad".
+	Name string `json:"name"`
+	// Checksum is the hex encoded SHA-256 of the encoded module bytecode.
+	Checksum string `json:"sha256"`
+}
+
+// Package is a package archive loaded into memory.
+type Package struct {
+	Manifest
+	data map[string][]byte
+}
+
+// Pack compiles the ".gad" files of dir with opts and writes the package
+// archive to w. Modules import each other relatively like
+// `import("./util.gad")`.
+func Pack(w io.Writer, dir, name, version string, opts gad.CompilerOptions) (*Manifest, error) {
+	if name == "" {
+		return nil, errors.New("gadpkg: empty package name")
+	}
+
+	m := &Manifest{Name: name, Version: version}
+	data := make(map[string][]byte)
+
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || filepath.Ext(p) != ".gad" {
+			return err
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+
+		src, _, err := importers.ShebangReadFile(p)
+		if err != nil {
+			return err
+		}
+
+		mopts := opts
+		if mopts.ModuleMap == nil {
+			mopts.ModuleMap = gad.NewModuleMap()
+		} else {
+			mopts.ModuleMap = mopts.ModuleMap.Copy()
+		}
+		mopts.ModuleMap.SetExtImporter(&importers.FileImporter{
+			WorkDir:    filepath.Dir(p),
+			FileReader: importers.ShebangReadFile,
+		})
+		mopts.Module = &gad.ModuleInfo{Name: name + "/" + rel}
+
+		bc, err := gad.Compile(src, gad.CompileOptions{CompilerOptions: mopts})
+		if err != nil {
+			return fmt.Errorf("gadpkg: compile %s: %w", rel, err)
+		}
+
+		var buf bytes.Buffer
+		if err = encoder.EncodeBytecodeTo(bc, &buf); err != nil {
+			return fmt.Errorf("gadpkg: encode %s: %w", rel, err)
+		}
+		data[rel] = buf.Bytes()
+		m.Modules = append(m.Modules, Module{Name: rel, Checksum: checksum(buf.Bytes())})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	zw := zip.NewWriter(w)
+	mw, err := zw.Create(manifestName)
+	if err != nil {
+		return nil, err
+	}
+	enc := json.NewEncoder(mw)
+	enc.SetIndent("", "  ")
+	if err = enc.Encode(m); err != nil {
+		return nil, err
+	}
+	for _, mod := range m.Modules {
+		fw, err := zw.Create(modulesDir + mod.Name)
+		if err != nil {
+			return nil, err
+		}
+		if _, err = fw.Write(data[mod.Name]); err != nil {
+			return nil, err
+		}
+	}
+	return m, zw.Close()
+}
+
+// Open reads the package archive file at path.
+func Open(path string) (*Package, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	return Read(f, fi.Size())
+}
+
+// Read reads the package archive from r and verifies the checksums of the
+// modules.
+func Read(r io.ReaderAt, size int64) (*Package, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, fmt.Errorf("gadpkg: %w", err)
+	}
+
+	p := &Package{data: make(map[string][]byte)}
+	if err = readJSON(zr, manifestName, &p.Manifest); err != nil {
+		return nil, err
+	}
+	if p.Name == "" {
+		return nil, errors.New("gadpkg: empty package name")
+	}
+
+	for _, mod := range p.Modules {
+		data, err := readFile(zr, modulesDir+mod.Name)
+		if err != nil {
+			return nil, err
+		}
+		if checksum(data) != mod.Checksum {
+			return nil, fmt.Errorf("gadpkg: checksum mismatch of module %q", mod.Name)
+		}
+		p.data[mod.Name] = data
+	}
+	return p, nil
+}
+
+// Entry returns the name of the entry module which is "index.gad" or the
+// module named as the package with ".gad" extension. It returns empty string
+// if the package has no entry module.
+func (p *Package) Entry() string {
+	for _, name := range []string{"index.gad", path.Base(p.Name) + ".gad"} {
+		if _, ok := p.data[name]; ok {
+			return name
+		}
+	}
+	return ""
+}
+
+// Importable returns the importable module of given name or nil if it does
+// not exist.
+func (p *Package) Importable(name string) gad.Importable {
+	data, ok := p.data[name]
+	if !ok {
+		return nil
+	}
+	return &module{name: p.Name + "/" + name, data: data}
+}
+
+// AddTo adds the modules of the package to m as "<package>/<module>", e.g.
+// "mylib/util.gad". Entry module is also added as "<package>".
+func (p *Package) AddTo(m *gad.ModuleMap) *gad.ModuleMap {
+	for _, mod := range p.Modules {
+		m.Add(p.Name+"/"+mod.Name, p.Importable(mod.Name))
+	}
+	if entry := p.Entry(); entry != "" {
+		m.Add(p.Name, p.Importable(entry))
+	}
+	return m
+}
+
+// module is a precompiled module of a package which is linked into the
+// importing bytecode.
+type module struct {
+	name string
+	data []byte
+}
+
+var _ gad.CompilableImporter = (*module)(nil)
+
+// Import returns the encoded bytecode of the module.
+func (m *module) Import(context.Context, string) (any, string, error) {
+	return m.data, "gadpkg:" + m.name, nil
+}
+
+// CompileModule decodes and verifies the bytecode of the module and links it
+// into the compiler.
+func (m *module) CompileModule(
+	c *gad.Compiler,
+	_ ast.Node,
+	_ *gad.ModuleInfo,
+	moduleMap *gad.ModuleMap,
+	src []byte,
+) (*gad.Bytecode, error) {
+	bc, err := encoder.DecodeVerifiedBytecodeFrom(bytes.NewReader(src), moduleMap, nil)
+	if err != nil {
+		return nil, fmt.Errorf("gadpkg: decode %s: %w", m.name, err)
+	}
+	return c.LinkModule(bc)
+}
+
+func checksum(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func readFile(zr *zip.Reader, name string) ([]byte, error) {
+	f, err := zr.Open(name)
+	if err != nil {
+		return nil, fmt.Errorf("gadpkg: %w", err)
+	}
+	defer f.Close()
+	return io.ReadAll(f)
+}
+
+func readJSON(zr *zip.Reader, name string, v any) error {
+	data, err := readFile(zr, name)
+	if err != nil {
+		return err
+	}
+	if err = json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("gadpkg: %s: %w", name, err)
+	}
+	return nil
+}
@@ -0,0 +1,94 @@
+package gadpkg_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/gad-lang/gad"
+	"github.com/gad-lang/gad/gadpkg"
+	gadstrings "github.com/gad-lang/gad/stdlib/strings"
+)
+
+func TestPack(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"index.gad": `
+util := import("./internal/util.gad")
+counter := 0
+return {
+	greet: func(name) {
+		counter++
+		return util.title(name) + "#" + counter
+	},
+	fail: func() { throw "boom" },
+	version: "1",
+}`,
+		"internal/util.gad": `
+strings := import("strings")
+return {title: func(s) => strings.ToUpper(s[:1]) + s[1:]}`,
+	}
+	for name, src := range files {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		require.NoError(t, os.MkdirAll(filepath.Dir(p), 0o755))
+		require.NoError(t, os.WriteFile(p, []byte(src), 0o644))
+	}
+
+	newModuleMap := func() *gad.ModuleMap {
+		return gad.NewModuleMap().AddBuiltinModule("strings", gadstrings.Module)
+	}
+
+	var buf bytes.Buffer
+	m, err := gadpkg.Pack(&buf, dir, "mylib", "1.0.0",
+		gad.CompilerOptions{ModuleMap: newModuleMap()})
+	require.NoError(t, err)
+	require.Equal(t, "mylib", m.Name)
+	require.Len(t, m.Modules, 2)
+	require.Equal(t, "index.gad", m.Modules[0].Name)
+
+	pkg, err := gadpkg.Read(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	require.NoError(t, err)
+	require.Equal(t, *m, pkg.Manifest)
+	require.Equal(t, "index.gad", pkg.Entry())
+
+	mm := pkg.AddTo(newModuleMap())
+	script := `
+strings := import("strings")
+lib := import("mylib")
+util := import("mylib/internal/util.gad")
+x := "a"
+return [lib.greet("gad"), lib.greet(x), util.title("b"), strings.ToLower("C"), lib.version]`
+	bc, err := gad.Compile([]byte(script),
+		gad.CompileOptions{CompilerOptions: gad.CompilerOptions{ModuleMap: mm}})
+	require.NoError(t, err)
+	ret, err := gad.NewVM(bc).Run()
+	require.NoError(t, err)
+	require.Equal(t, gad.Array{gad.Str("Gad#1"), gad.Str("A#2"), gad.Str("B"), gad.Str("c"), gad.Str("1")}, ret)
+
+	bc, err = gad.Compile([]byte(`import("mylib").fail()`),
+		gad.CompileOptions{CompilerOptions: gad.CompilerOptions{ModuleMap: mm}})
+	require.NoError(t, err)
+	_, err = gad.NewVM(bc).Run()
+	require.Error(t, err)
+	require.Contains(t, fmt.Sprintf("%+v", err), "mylib/index.gad:9")
+}
+
+func TestReadChecksum(t *testing.T) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	w, _ := zw.Create("gadpkg.json")
+	_, _ = w.Write([]byte(`{"name":"x","modules":[{"name":"index.gad","sha256":"00"}]}`))
+	w, _ = zw.Create("modules/index.gad")
+	_, _ = w.Write([]byte("data"))
+	require.NoError(t, zw.Close())
+
+	_, err := gadpkg.Read(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	require.Error(t, err)
+	require.True(t, strings.Contains(err.Error(), `checksum mismatch of module "index.gad"`))
+}
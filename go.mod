@@ -3,10 +3,13 @@ module github.com/gad-lang/gad
 go 1.21
 
 require (
+	github.com/andybalholm/cascadia v1.3.2
 	github.com/peterh/liner v1.2.2
 	github.com/shopspring/decimal v1.3.1
 	github.com/stretchr/testify v1.8.2
 	github.com/unapu-go/cmd-utils v0.0.0-20210819145619-98d5bccf2672
+	golang.org/x/crypto v0.17.0
+	golang.org/x/net v0.19.0
 )
 
 require (
@@ -17,7 +20,7 @@ require (
 	github.com/pkg/errors v0.9.1 // indirect
 	github.com/pmezard/go-difflib v1.0.0 // indirect
 	github.com/rivo/uniseg v0.2.0 // indirect
-	golang.org/x/sys v0.8.0 // indirect
+	golang.org/x/sys v0.15.0 // indirect
 	gopkg.in/yaml.v3 v3.0.1 // indirect
 	mvdan.cc/sh/v3 v3.3.1 // indirect
 )
@@ -92,6 +92,10 @@ try:
 	return
 }
 
+func (f *PipedInvokeIterator) LengthHint() int {
+	return IteratorLengthHint(f.it)
+}
+
 func (f *PipedInvokeIterator) Start(vm *VM) (state *IteratorState, err error) {
 	if state, err = f.it.Start(vm); err != nil {
 		return
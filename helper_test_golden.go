@@ -0,0 +1,49 @@
+package gad
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gad-lang/gad/parser"
+	"github.com/stretchr/testify/require"
+)
+
+// ExpectRenderGolden compiles the mixed mode template file, renders it with
+// the globals and compares the output with the golden file. If the test binary
+// defines the `-update` flag and it is set, the golden file is written with
+// the output instead:
+//
+//	var update = flag.Bool("update", false, "update golden files")
+func ExpectRenderGolden(t *testing.T, templatePath string, globals IndexGetSetter, goldenPath string) {
+	t.Helper()
+	src, err := os.ReadFile(templatePath)
+	require.NoError(t, err)
+
+	opts := CompileOptions{
+		CompilerOptions: CompilerOptions{
+			Module: &ModuleInfo{Name: filepath.Base(templatePath), File: "file:" + templatePath},
+		},
+	}
+	opts.ParserOptions.Mode |= parser.ParseMixed
+	bc, err := Compile(src, opts)
+	require.NoErrorf(t, err, "Template: %s", templatePath)
+
+	var out bytes.Buffer
+	_, err = NewVM(bc).RunOpts(&RunOpts{Globals: globals, StdOut: &out})
+	require.NoErrorf(t, err, "Template: %s", templatePath)
+
+	if f := flag.Lookup("update"); f != nil && f.Value.String() == "true" {
+		require.NoError(t, os.WriteFile(goldenPath, out.Bytes(), 0644))
+	}
+	golden, err := os.ReadFile(goldenPath)
+	require.NoError(t, err)
+	require.Equalf(t,
+		strings.ReplaceAll(string(golden), "\r\n", "\n"),
+		strings.ReplaceAll(out.String(), "\r\n", "\n"),
+		"Template: %s\nGolden: %s", templatePath, goldenPath,
+	)
+}
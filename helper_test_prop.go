@@ -0,0 +1,162 @@
+package gad
+
+import (
+	"bytes"
+	"math/rand"
+	"testing"
+
+	"github.com/gad-lang/gad/parser"
+	"github.com/gad-lang/gad/tests"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+)
+
+// RandObject returns a random Nil, Bool, Int, Uint, Float, Decimal, Char,
+// Str, Bytes, Array or Dict object. Array and Dict values are nested at most
+// depth levels, scalar objects are returned if depth is not positive.
+func RandObject(r *rand.Rand, depth int) Object {
+	n := 9
+	if depth > 0 {
+		n += 2
+	}
+	switch r.Intn(n) {
+	case 0:
+		return Nil
+	case 1:
+		return Bool(r.Intn(2) == 1)
+	case 2:
+		return Int(r.Int63() - r.Int63())
+	case 3:
+		return Uint(r.Uint64())
+	case 4:
+		return Float(r.NormFloat64() * 1e6)
+	case 5:
+		return Decimal(decimal.New(r.Int63n(1e12)-5e11, -int32(r.Intn(6))))
+	case 6:
+		return Char(randRune(r))
+	case 7:
+		return Str(randString(r))
+	case 8:
+		b := make(Bytes, r.Intn(16))
+		r.Read(b)
+		return b
+	case 9:
+		arr := make(Array, r.Intn(5))
+		for i := range arr {
+			arr[i] = RandObject(r, depth-1)
+		}
+		return arr
+	default:
+		dict := make(Dict)
+		for i := r.Intn(5); i > 0; i-- {
+			dict[randString(r)] = RandObject(r, depth-1)
+		}
+		return dict
+	}
+}
+
+func randRune(r *rand.Rand) rune {
+	const chars = "abcxyzABCXYZ019 _-.\"'\\\t\nçğüşıöé€日本語😀"
+	runes := []rune(chars)
+	return runes[r.Intn(len(runes))]
+}
+
+func randString(r *rand.Rand) string {
+	s := make([]rune, r.Intn(12))
+	for i := range s {
+		s[i] = randRune(r)
+	}
+	return string(s)
+}
+
+// TestRandObjects calls f with n random objects of at most depth nested
+// levels generated from seed. The seed and the object are logged if f fails,
+// so the failure can be reproduced.
+func TestRandObjects(t *testing.T, seed int64, n, depth int, f func(t *testing.T, o Object)) {
+	t.Helper()
+	r := rand.New(rand.NewSource(seed))
+	for i := 0; i < n; i++ {
+		o := RandObject(r, depth)
+		if !t.Run("", func(t *testing.T) { f(t, o) }) {
+			t.Fatalf("seed %d, object #%d:\n%s", seed, i, tests.Sdump(o))
+		}
+	}
+}
+
+// TestParseRoundTrip parses the script, parses the string of the parsed file
+// again and checks that both compile to equal bytecode. The strings are not
+// compared as String adds the parentheses of the expressions.
+func TestParseRoundTrip(t *testing.T, script string) {
+	t.Helper()
+	fileSet := parser.NewFileSet()
+	file := fileSet.AddFile("(round-trip)", -1, len(script))
+	f, err := parser.NewParser(file, []byte(script), nil).ParseFile()
+	require.NoErrorf(t, err, "Script:\n%s", script)
+
+	expected, err := Compile([]byte(script), CompileOptions{})
+	require.NoErrorf(t, err, "Script:\n%s", script)
+	got, err := Compile([]byte(f.String()), CompileOptions{})
+	require.NoErrorf(t, err, "Script:\n%s\nString:\n%s", script, f.String())
+	TestBytecodesEqual(t, expected, got, false)
+}
+
+// TestEncodeRoundTrip encodes the bytecode with the object as a constant
+// using codec, decodes it and checks that the decoded constant is equal to the
+// object.
+func TestEncodeRoundTrip(t *testing.T, codec BytecodeCodec, o Object) {
+	t.Helper()
+	bc, err := Compile([]byte(`return nil`), CompileOptions{})
+	require.NoError(t, err)
+	bc.Constants = append(bc.Constants, o)
+
+	var buf bytes.Buffer
+	require.NoError(t, codec.EncodeBytecode(bc, &buf))
+	got, err := codec.DecodeBytecode(&buf, nil)
+	require.NoError(t, err)
+	require.Len(t, got.Constants, len(bc.Constants))
+
+	decoded := got.Constants[len(got.Constants)-1]
+	if !o.Equal(decoded) {
+		t.Fatalf("Objects not equal:\nEncoded:\n%s\nDecoded:\n%s\n",
+			tests.Sdump(o), tests.Sdump(decoded))
+	}
+}
+
+// TestOptimizerEquivalence runs the script compiled with and without the
+// optimizer and checks that both return equal objects and write the same
+// output.
+func TestOptimizerEquivalence(t *testing.T, script string, opts *TestOpts) {
+	t.Helper()
+	if opts == nil {
+		opts = NewTestOpts()
+	}
+	run := func(copts CompilerOptions) (Object, string) {
+		builtins := NewBuiltins()
+		builtins.AppendMap(opts.builtins)
+		copts.SymbolTable = NewSymbolTable(builtins)
+		copts.ModuleMap = opts.moduleMap
+		bc, err := Compile([]byte(script), CompileOptions{CompilerOptions: copts})
+		require.NoErrorf(t, err, "Script:\n%s", script)
+
+		var buf bytes.Buffer
+		ropts := &RunOpts{
+			Globals: opts.globals,
+			Args:    Args{opts.args},
+			StdOut:  &buf,
+		}
+		if opts.namedArgs != nil {
+			ropts.NamedArgs = opts.namedArgs.Copy().(*NamedArgs)
+		}
+		ret, err := NewVM(bc).Setup(SetupOpts{Builtins: builtins}).RunOpts(ropts)
+		require.NoErrorf(t, err, "Script:\n%s", script)
+		return ret, buf.String()
+	}
+
+	optimized, optimizedOut := run(DefaultCompilerOptions)
+	unoptimized, unoptimizedOut := run(CompilerOptions{})
+	if !optimized.Equal(unoptimized) {
+		t.Fatalf("Objects not equal:\nOptimized:\n%s\nUnoptimized:\n%s\nScript:\n%s\n",
+			tests.Sdump(optimized), tests.Sdump(unoptimized), script)
+	}
+	require.Equalf(t, unoptimizedOut, optimizedOut, "Script:\n%s", script)
+}
@@ -150,6 +150,23 @@ func (t *TestOpts) Buffered() *TestOpts {
 	return t
 }
 
+// TestExpectRunErr compiles script with the modules of opts and requires that
+// running it with the globals and the args of opts returns an error matching
+// expected by errors.Is. The error is returned to check it further.
+func TestExpectRunErr(t *testing.T, script string, opts *TestOpts, expected error) error {
+	t.Helper()
+	if opts == nil {
+		opts = NewTestOpts()
+	}
+	c := CompileOptions{CompilerOptions: DefaultCompilerOptions}
+	c.ModuleMap = opts.moduleMap
+	bc, err := Compile([]byte(script), c)
+	require.NoError(t, err)
+	_, err = NewVM(bc).RunOpts(&RunOpts{Globals: opts.globals, Args: Args{opts.args}, NamedArgs: opts.namedArgs})
+	require.ErrorIs(t, err, expected)
+	return err
+}
+
 func TestExpectRun(t *testing.T, script string, opts *TestOpts, expect Object) {
 	t.Helper()
 	if opts == nil {
@@ -14,12 +14,22 @@ import (
 // system. It uses absolute paths of module as import names.
 type FileImporter struct {
 	NameResolver func(cwd, name string) (string, error)
-	WorkDir      string
-	FileReader   func(string) (data []byte, uri string, err error)
-	name         string
+	// WorkDir is the directory of the importing module which relative module
+	// names are resolved from. Fork sets it to the directory of the imported
+	// module.
+	WorkDir    string
+	FileReader func(string) (data []byte, uri string, err error)
+	// Paths are searched if the module is not found by NameResolver or in
+	// WorkDir.
+	Paths PathList
+	// Lock stores the resolved versions of versioned imports like
+	// `import("lib@^1.2")`. If it is nil, the highest matching version is
+	// imported.
+	Lock *Lock
+	name string
 }
 
-var _ gad.ExtImporter = (*FileImporter)(nil)
+var _ gad.PathsExtImporter = (*FileImporter)(nil)
 
 // Get impelements gad.ExtImporter and returns itself if name is not empty.
 func (m *FileImporter) Get(name string) gad.ExtImporter {
@@ -31,23 +41,94 @@ func (m *FileImporter) Get(name string) gad.ExtImporter {
 }
 
 // Name returns the absoule path of the module. A previous Get call is required
-// to get the name of the imported module.
+// to get the name of the imported module. If the path is a directory, the
+// path of its entry module is returned, see PackageEntry. Versioned imports
+// like "lib@^1.2" are resolved to "lib@<version>" files or directories, see
+// ParseConstraint.
 func (m *FileImporter) Name() (string, error) {
 	if m.name == "" {
 		return "", nil
 	}
+
+	if base, constraint, ok := splitVersion(m.name); ok {
+		path, err := m.resolveVersion(base, constraint)
+		if err != nil {
+			return "", err
+		}
+		return PackageEntry(path), nil
+	}
+
+	path, err := m.lookup()
+	if err != nil || path == "" {
+		return path, err
+	}
+	return PackageEntry(path), nil
+}
+
+func (m *FileImporter) lookup() (string, error) {
+	path, err := m.resolve()
+	if len(m.Paths) == 0 || filepath.IsAbs(m.name) {
+		return path, err
+	}
+	if err == nil {
+		if _, err = os.Stat(path); err == nil || !os.IsNotExist(err) {
+			return path, nil
+		}
+	}
+	for _, dir := range m.Paths {
+		p := filepath.Join(dir, filepath.FromSlash(m.name))
+		if _, err2 := os.Stat(p); err2 == nil {
+			return p, nil
+		}
+	}
+	if path != "" {
+		return path, nil
+	}
+	return path, err
+}
+
+// PackageEntry returns the entry module of the directory package at path,
+// which is "index.gad" or the file named as the directory with ".gad"
+// extension in the directory, e.g. "mylib/index.gad" or "mylib/mylib.gad".
+// It returns path if it is not a directory or has no entry module.
+func PackageEntry(path string) string {
+	if fi, err := os.Stat(path); err != nil || !fi.IsDir() {
+		return path
+	}
+	for _, name := range []string{"index.gad", filepath.Base(path) + ".gad"} {
+		p := filepath.Join(path, name)
+		if fi, err := os.Stat(p); err == nil && !fi.IsDir() {
+			return p
+		}
+	}
+	return path
+}
+
+func (m *FileImporter) resolve() (string, error) {
 	if m.NameResolver != nil {
 		return m.NameResolver(m.WorkDir, m.name)
 	}
 
-	path := m.name
-	if !filepath.IsAbs(path) {
-		path = filepath.Join(m.WorkDir, path)
-		if p, err := filepath.Abs(path); err == nil {
-			path = p
+	if filepath.IsAbs(m.name) {
+		return m.name, nil
+	}
+	return absJoin(m.WorkDir, m.name), nil
+}
+
+// WithPaths implements gad.PathsExtImporter and returns a copy of
+// FileImporter which also searches modules in paths. Relative paths are
+// resolved from WorkDir.
+func (m *FileImporter) WithPaths(paths ...string) gad.ExtImporter {
+	c := *m
+	c.name = ""
+	c.Paths = append(PathList{}, m.Paths...)
+	for _, p := range paths {
+		if !filepath.IsAbs(p) {
+			p = filepath.Join(m.WorkDir, p)
 		}
+		c.Paths.Append(filepath.Clean(p))
 	}
-	return path, nil
+	return &c
 }
 
 // Import returns the content of the path determined by Name call. Empty name
@@ -77,6 +158,8 @@ func (m *FileImporter) Fork(moduleName string) gad.ExtImporter {
 		WorkDir:      filepath.Dir(moduleName),
 		FileReader:   m.FileReader,
 		NameResolver: m.NameResolver,
+		Paths:        m.Paths,
+		Lock:         m.Lock,
 	}
 }
 
@@ -106,20 +189,23 @@ func (d *PathList) Remove(count int) {
 }
 
 // OsDirsNameResolverPtr is similar to `OsDirsNameResolver`, but receives ptr of `dirs`.
+// Relative names are resolved from cwd, which is the directory of the
+// importing module, and then from dirs. Resolved names are absolute.
 func OsDirsNameResolverPtr(dirs *PathList) func(cwd, path string) (string, error) {
-	if len(*dirs) == 0 {
-		return func(_, path string) (string, error) {
-			return path, nil
-		}
-	}
 	return func(cwd string, p string) (name string, err error) {
-		p = path.Clean(p)
-		name = filepath.Join(cwd, p)
+		p = filepath.FromSlash(path.Clean(filepath.ToSlash(p)))
+		if filepath.IsAbs(p) {
+			return p, nil
+		}
+		name = absJoin(cwd, p)
+		if len(*dirs) == 0 {
+			return name, nil
+		}
 		if _, err = os.Stat(name); err == nil || !os.IsNotExist(err) {
 			return
 		}
 		for _, dir := range *dirs {
-			name = filepath.Join(dir, p)
+			name = absJoin(dir, p)
 			if _, err = os.Stat(name); err == nil || !os.IsNotExist(err) {
 				return
 			}
@@ -128,6 +214,14 @@ func OsDirsNameResolverPtr(dirs *PathList) func(cwd, path string) (string, error
 	}
 }
 
+func absJoin(dir, name string) string {
+	name = filepath.Join(dir, name)
+	if p, err := filepath.Abs(name); err == nil {
+		return p
+	}
+	return name
+}
+
 // ShebangReadFile reads given path and returns the content of the file. If file
 // starts with Shebang #! , it is replaced with //.
 // This function can be used as ReadFile callback in FileImporter.
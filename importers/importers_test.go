@@ -2,6 +2,8 @@ package importers_test
 
 import (
 	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
 	"os"
 	"path/filepath"
 	"strings"
@@ -170,6 +172,95 @@ println("test8")
 		)
 	})
 
+	t.Run("relative", func(t *testing.T) {
+		buf.Reset()
+
+		tempDir := t.TempDir()
+		createModules(t, tempDir, map[string]string{
+			"./app/main.gad": `
+import("./sibling.gad")
+import("../lib/util.gad")
+`,
+			"./app/sibling.gad": `println("sibling")`,
+			"./lib/util.gad":    `import("./helper.gad"); println("util")`,
+			"./lib/helper.gad":  `println("helper")`,
+		})
+
+		opts := gad.DefaultCompilerOptions
+		opts.ModuleMap = moduleMap.Copy()
+		opts.ModuleMap.SetExtImporter(&importers.FileImporter{
+			WorkDir:      filepath.Join(tempDir, "app"),
+			NameResolver: importers.OsDirsNameResolver(nil),
+		})
+
+		script, err := os.ReadFile(filepath.Join(tempDir, "app", "main.gad"))
+		require.NoError(t, err)
+		bc, err := gad.Compile(script, gad.CompileOptions{CompilerOptions: opts})
+		require.NoError(t, err)
+		_, err = gad.NewVM(bc).RunOpts(&gad.RunOpts{
+			StdOut: gad.NewWriter(buf),
+		})
+		require.NoError(t, err)
+		require.Equal(t, "sibling\nhelper\nutil\n", strings.ReplaceAll(buf.String(), "\r", ""))
+	})
+
+	t.Run("package", func(t *testing.T) {
+		buf.Reset()
+
+		tempDir := t.TempDir()
+		createModules(t, tempDir, map[string]string{
+			"./mylib/index.gad":    `return {name: import("./internal.gad")}`,
+			"./mylib/internal.gad": `return "mylib"`,
+			"./other/other.gad":    `return "other"`,
+		})
+
+		opts := gad.DefaultCompilerOptions
+		opts.ModuleMap = moduleMap.Copy()
+		opts.ModuleMap.SetExtImporter(&importers.FileImporter{WorkDir: tempDir})
+
+		bc, err := gad.Compile([]byte(`println(import("mylib").name, import("./other"))`),
+			gad.CompileOptions{CompilerOptions: opts})
+		require.NoError(t, err)
+		_, err = gad.NewVM(bc).RunOpts(&gad.RunOpts{
+			StdOut: gad.NewWriter(buf),
+		})
+		require.NoError(t, err)
+		require.Equal(t, "mylib other\n", strings.ReplaceAll(buf.String(), "\r", ""))
+	})
+
+	t.Run("config_path", func(t *testing.T) {
+		buf.Reset()
+
+		tempDir := t.TempDir()
+		createModules(t, tempDir, map[string]string{
+			"./lib/util.gad": `
+import("helper.gad") as h
+return {name: "util:" + h.name}
+`,
+			"./vendor/helper.gad": `return {name: "helper"}`,
+		})
+
+		opts := gad.DefaultCompilerOptions
+		opts.ModuleMap = moduleMap.Copy()
+		im := &importers.FileImporter{WorkDir: tempDir}
+		opts.ModuleMap.SetExtImporter(im)
+
+		script := `# gad: path="./lib` + string(filepath.ListSeparator) + `./vendor"
+import("util.gad") as u
+println(u.name)
+`
+		bc, err := gad.Compile([]byte(script), gad.CompileOptions{CompilerOptions: opts})
+		require.NoError(t, err)
+		require.Empty(t, im.Paths)
+		_, err = gad.NewVM(bc).RunOpts(&gad.RunOpts{
+			StdOut: gad.NewWriter(buf),
+		})
+		require.NoError(t, err)
+		require.Equal(t, "util:helper\n", strings.ReplaceAll(buf.String(), "\r", ""))
+
+		_, err = gad.Compile([]byte(`import("util.gad")`), gad.CompileOptions{CompilerOptions: opts})
+		require.Error(t, err)
+	})
 }
 
 func createModules(t *testing.T, baseDir string, files map[string]string) {
@@ -181,3 +272,48 @@ func createModules(t *testing.T, baseDir string, files map[string]string) {
 		require.NoError(t, err)
 	}
 }
+
+func TestOsDirsNameResolver(t *testing.T) {
+	tempDir := t.TempDir()
+	createModules(t, tempDir, map[string]string{
+		"./lib/a.gad": ``,
+	})
+	cwd := filepath.Join(tempDir, "app")
+
+	name, err := importers.OsDirsNameResolver(nil)(cwd, "./x/../b.gad")
+	require.NoError(t, err)
+	require.Equal(t, filepath.Join(cwd, "b.gad"), name)
+
+	resolve := importers.OsDirsNameResolver([]string{filepath.Join(tempDir, "lib")})
+	name, err = resolve(cwd, "a.gad")
+	require.NoError(t, err)
+	require.Equal(t, filepath.Join(tempDir, "lib", "a.gad"), name)
+
+	_, err = resolve(cwd, "c.gad")
+	require.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestFileImporterChecksums(t *testing.T) {
+	tempDir := t.TempDir()
+	createModules(t, tempDir, map[string]string{
+		"./a.gad": `return "a"`,
+		"./b.gad": `return "b"`,
+	})
+	sum := sha256.Sum256([]byte(`return "a"`))
+
+	mm := gad.NewModuleMap().
+		SetExtImporter(&importers.FileImporter{WorkDir: tempDir}).
+		SetChecksums(map[string]string{
+			filepath.Join(tempDir, "a.gad"): hex.EncodeToString(sum[:]),
+		}, true)
+	compile := func(script string) error {
+		_, err := gad.Compile([]byte(script),
+			gad.CompileOptions{CompilerOptions: gad.CompilerOptions{ModuleMap: mm}})
+		return err
+	}
+
+	require.NoError(t, compile(`import("a.gad")`))
+	err := compile(`import("b.gad")`)
+	require.ErrorIs(t, err, gad.ErrModuleChecksum)
+	require.Contains(t, err.Error(), "b.gad' has no checksum")
+}
@@ -0,0 +1,365 @@
+package importers
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// LockFile is the name of the lock file which stores resolved versions of
+// versioned imports like `import("lib@^1.2")`.
+const LockFile = "gad.lock"
+
+// Version is a semantic version like "1.2.3" or "1.2.3-beta".
+type Version struct {
+	Major, Minor, Patch int
+	Pre                 string
+}
+
+// ParseVersion parses s as a Version. Leading "v" is optional and missing
+// minor and patch numbers are zero.
+func ParseVersion(s string) (v Version, err error) {
+	v, _, err = parseVersion(s)
+	return
+}
+
+// parseVersion also returns the number of the given numeric parts.
+func parseVersion(s string) (v Version, parts int, err error) {
+	str := strings.TrimPrefix(s, "v")
+	if i := strings.IndexByte(str, '-'); i >= 0 {
+		v.Pre = str[i+1:]
+		str = str[:i]
+	}
+	nums := strings.Split(str, ".")
+	if str == "" || len(nums) > 3 {
+		return v, 0, fmt.Errorf("invalid version %q", s)
+	}
+	for i, n := range nums {
+		var d int
+		if d, err = strconv.Atoi(n); err != nil || d < 0 {
+			return v, 0, fmt.Errorf("invalid version %q", s)
+		}
+		switch i {
+		case 0:
+			v.Major = d
+		case 1:
+			v.Minor = d
+		case 2:
+			v.Patch = d
+		}
+	}
+	return v, len(nums), nil
+}
+
+// Compare returns -1, 0 or 1 if v is less than, equal to or greater than o.
+// Pre-release versions are less than their release versions.
+func (v Version) Compare(o Version) int {
+	for _, d := range [...]int{v.Major - o.Major, v.Minor - o.Minor, v.Patch - o.Patch} {
+		if d < 0 {
+			return -1
+		} else if d > 0 {
+			return 1
+		}
+	}
+	switch {
+	case v.Pre == o.Pre:
+		return 0
+	case v.Pre == "":
+		return 1
+	case o.Pre == "":
+		return -1
+	case v.Pre < o.Pre:
+		return -1
+	}
+	return 1
+}
+
+func (v Version) String() string {
+	s := fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+	if v.Pre != "" {
+		s += "-" + v.Pre
+	}
+	return s
+}
+
+// Constraint is a version constraint of a versioned import.
+type Constraint struct {
+	min, max         Version
+	hasMin, hasMax   bool
+	minExcl, maxIncl bool
+	src              string
+}
+
+// ParseConstraint parses a version constraint. Supported forms are:
+//
+//   - any version
+//     1.2.3     exactly 1.2.3, partial versions like 1.2 match 1.2.x
+//     ^1.2.3    compatible versions >=1.2.3 <2.0.0 (<0.3.0 for ^0.2.3)
+//     ~1.2.3    patch versions >=1.2.3 <1.3.0
+//     >=1.2 >1.2 <=1.2 <1.2
+//
+// Pre-release versions only match if the constraint has a pre-release.
+func ParseConstraint(s string) (c Constraint, err error) {
+	c.src = s
+	if s == "" || s == "*" || s == "latest" {
+		return
+	}
+
+	var op string
+	for _, o := range []string{">=", "<=", ">", "<", "=", "^", "~"} {
+		if strings.HasPrefix(s, o) {
+			op = o
+			break
+		}
+	}
+
+	v, parts, err := parseVersion(s[len(op):])
+	if err != nil {
+		return c, err
+	}
+
+	switch op {
+	case ">=":
+		c.min, c.hasMin = v, true
+	case ">":
+		c.min, c.hasMin, c.minExcl = v, true, true
+	case "<=":
+		c.max, c.hasMax, c.maxIncl = v, true, true
+		if parts < 3 {
+			c.max, c.maxIncl = nextVersion(v, parts), false
+		}
+	case "<":
+		c.max, c.hasMax = v, true
+	case "^":
+		c.min, c.hasMin, c.hasMax = v, true, true
+		switch {
+		case v.Major > 0 || parts == 1:
+			c.max = Version{Major: v.Major + 1}
+		case v.Minor > 0 || parts == 2:
+			c.max = Version{Minor: v.Minor + 1}
+		default:
+			c.max = Version{Patch: v.Patch + 1}
+		}
+	case "~":
+		c.min, c.hasMin, c.hasMax = v, true, true
+		c.max = nextVersion(v, min(parts, 2))
+	default:
+		c.min, c.hasMin = v, true
+		c.max, c.hasMax, c.maxIncl = v, true, true
+		if parts < 3 {
+			c.max, c.maxIncl = nextVersion(v, parts), false
+		}
+	}
+	return
+}
+
+// nextVersion returns the smallest version greater than all versions
+// matching the partial version v having 1 or 2 parts.
+func nextVersion(v Version, parts int) Version {
+	if parts == 1 {
+		return Version{Major: v.Major + 1}
+	}
+	return Version{Major: v.Major, Minor: v.Minor + 1}
+}
+
+// Check reports whether v satisfies the constraint.
+func (c Constraint) Check(v Version) bool {
+	if v.Pre != "" && c.min.Pre == "" && c.max.Pre == "" {
+		return false
+	}
+	if c.hasMin {
+		if d := v.Compare(c.min); d < 0 || (d == 0 && c.minExcl) {
+			return false
+		}
+	}
+	if c.hasMax {
+		if d := v.Compare(c.max); d > 0 || (d == 0 && !c.maxIncl) {
+			return false
+		}
+	}
+	return true
+}
+
+func (c Constraint) String() string {
+	return c.src
+}
+
+// Lock maps versioned imports like "lib@^1.2" to their resolved versions so
+// they are resolved reproducibly. It is safe to use concurrently.
+type Lock struct {
+	mu       sync.Mutex
+	versions map[string]string
+}
+
+// NewLock creates an empty Lock.
+func NewLock() *Lock {
+	return &Lock{versions: make(map[string]string)}
+}
+
+// LoadLock reads the lock file at path. It returns an empty Lock if the file
+// does not exist.
+func LoadLock(path string) (*Lock, error) {
+	l := NewLock()
+	f, err := os.Open(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return l, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+	return l, l.read(f)
+}
+
+func (l *Lock) read(r io.Reader) error {
+	s := bufio.NewScanner(r)
+	for line := 1; s.Scan(); line++ {
+		text := strings.TrimSpace(s.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		fields := strings.Fields(text)
+		if len(fields) != 2 {
+			return fmt.Errorf("%s:%d: invalid line", LockFile, line)
+		}
+		if _, err := ParseVersion(fields[1]); err != nil {
+			return fmt.Errorf("%s:%d: %w", LockFile, line, err)
+		}
+		l.versions[fields[0]] = fields[1]
+	}
+	return s.Err()
+}
+
+// Get returns the locked version of the import.
+func (l *Lock) Get(name string) (version string, ok bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	version, ok = l.versions[name]
+	return
+}
+
+// Set locks the version of the import.
+func (l *Lock) Set(name, version string) {
+	l.mu.Lock()
+	l.versions[name] = version
+	l.mu.Unlock()
+}
+
+// Names returns the sorted names of the locked imports.
+func (l *Lock) Names() []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	names := make([]string, 0, len(l.versions))
+	for name := range l.versions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// WriteTo writes the lock file content to w.
+func (l *Lock) WriteTo(w io.Writer) (int64, error) {
+	var buf bytes.Buffer
+	buf.WriteString("# Code generated by gad mod tidy. DO NOT EDIT.\n")
+	for _, name := range l.Names() {
+		v, _ := l.Get(name)
+		fmt.Fprintf(&buf, "%s %s\n", name, v)
+	}
+	return buf.WriteTo(w)
+}
+
+// Save writes the lock file to path.
+func (l *Lock) Save(path string) error {
+	var buf bytes.Buffer
+	_, _ = l.WriteTo(&buf)
+	return os.WriteFile(path, buf.Bytes(), 0o644)
+}
+
+// splitVersion splits a versioned import name like "lib@^1.2".
+func splitVersion(name string) (base, constraint string, ok bool) {
+	i := strings.LastIndexByte(name, '@')
+	if i <= 0 || strings.ContainsAny(name[i+1:], `/\`) {
+		return name, "", false
+	}
+	return name[:i], name[i+1:], true
+}
+
+// resolveVersion returns the path of the module version matching the
+// constraint of a versioned import. Versions are files or directories named
+// like "lib@1.2.3.gad" or "lib@1.2.3" in WorkDir and Paths.
+func (m *FileImporter) resolveVersion(base, constraint string) (string, error) {
+	c, err := ParseConstraint(constraint)
+	if err != nil {
+		return "", fmt.Errorf("module %q: %w", m.name, err)
+	}
+
+	var locked string
+	if m.Lock != nil {
+		locked, _ = m.Lock.Get(m.name)
+	}
+
+	dirs := []string{m.WorkDir}
+	if filepath.IsAbs(base) {
+		dirs = []string{""}
+	} else {
+		dirs = append(dirs, m.Paths...)
+	}
+
+	var (
+		found    string
+		foundVer Version
+		prefix   = filepath.Base(base) + "@"
+	)
+	for _, dir := range dirs {
+		dir = filepath.Join(dir, filepath.Dir(filepath.FromSlash(base)))
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, e := range entries {
+			name := e.Name()
+			if !strings.HasPrefix(name, prefix) {
+				continue
+			}
+			vs := strings.TrimPrefix(name, prefix)
+			if !e.IsDir() {
+				vs = strings.TrimSuffix(vs, ".gad")
+			}
+			v, err := ParseVersion(vs)
+			if err != nil || !c.Check(v) {
+				continue
+			}
+			if locked != "" {
+				if v.String() == locked || vs == locked {
+					return absJoin(dir, name), nil
+				}
+				continue
+			}
+			if found == "" || v.Compare(foundVer) > 0 {
+				found, foundVer = absJoin(dir, name), v
+			}
+		}
+		if found != "" {
+			break
+		}
+	}
+
+	if locked != "" {
+		return "", fmt.Errorf("module %q: locked version %s not found", m.name, locked)
+	}
+	if found == "" {
+		return "", fmt.Errorf("module %q: no version matching %q found", m.name, constraint)
+	}
+	if m.Lock != nil {
+		m.Lock.Set(m.name, foundVer.String())
+	}
+	return found, nil
+}
@@ -0,0 +1,104 @@
+package importers_test
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/gad-lang/gad"
+	"github.com/gad-lang/gad/importers"
+)
+
+func TestConstraint(t *testing.T) {
+	tests := []struct {
+		constraint string
+		match      []string
+		noMatch    []string
+	}{
+		{"*", []string{"0.0.1", "1.2.3"}, []string{"1.0.0-beta"}},
+		{"1.2.3", []string{"1.2.3", "v1.2.3"}, []string{"1.2.4", "1.2.2"}},
+		{"1.2", []string{"1.2.0", "1.2.9"}, []string{"1.3.0", "1.1.9"}},
+		{"^1.2", []string{"1.2.0", "1.9.0"}, []string{"1.1.9", "2.0.0", "2.0.0-beta", "1.3.0-rc"}},
+		{"^0.2.3", []string{"0.2.3", "0.2.9"}, []string{"0.3.0", "0.2.2"}},
+		{"^0.0.3", []string{"0.0.3"}, []string{"0.0.4"}},
+		{"~1.2.3", []string{"1.2.3", "1.2.9"}, []string{"1.3.0", "1.2.2"}},
+		{"~1", []string{"1.0.0", "1.9.0"}, []string{"2.0.0"}},
+		{">=1.2", []string{"1.2.0", "3.0.0"}, []string{"1.1.9"}},
+		{">1.2.0", []string{"1.2.1"}, []string{"1.2.0"}},
+		{"<=1.2", []string{"1.2.9", "0.1.0"}, []string{"1.3.0"}},
+		{"<1.2.0", []string{"1.1.9"}, []string{"1.2.0"}},
+		{">=1.0.0-beta", []string{"1.0.0-beta", "1.0.0"}, []string{"1.0.0-alpha"}},
+	}
+	for _, tt := range tests {
+		c, err := importers.ParseConstraint(tt.constraint)
+		require.NoError(t, err, tt.constraint)
+		for _, s := range tt.match {
+			v, err := importers.ParseVersion(s)
+			require.NoError(t, err)
+			require.True(t, c.Check(v), "%s must match %s", tt.constraint, s)
+		}
+		for _, s := range tt.noMatch {
+			v, err := importers.ParseVersion(s)
+			require.NoError(t, err)
+			require.False(t, c.Check(v), "%s must not match %s", tt.constraint, s)
+		}
+	}
+
+	_, err := importers.ParseConstraint("^x")
+	require.Error(t, err)
+	_, err = importers.ParseVersion("1.2.3.4")
+	require.Error(t, err)
+}
+
+func TestVersionedImport(t *testing.T) {
+	tempDir := t.TempDir()
+	createModules(t, tempDir, map[string]string{
+		"./lib@1.2.0.gad":         `return "1.2.0"`,
+		"./lib@1.3.1/index.gad":   `return "1.3.1"`,
+		"./lib@2.0.0.gad":         `return "2.0.0"`,
+		"./vendor/util@0.1.0.gad": `return "util"`,
+	})
+
+	run := func(lock *importers.Lock, script string) (gad.Object, error) {
+		opts := gad.DefaultCompilerOptions
+		opts.ModuleMap = gad.NewModuleMap()
+		opts.ModuleMap.SetExtImporter(&importers.FileImporter{WorkDir: tempDir, Lock: lock})
+		bc, err := gad.Compile([]byte(script), gad.CompileOptions{CompilerOptions: opts})
+		if err != nil {
+			return nil, err
+		}
+		return gad.NewVM(bc).Run()
+	}
+
+	lock := importers.NewLock()
+	ret, err := run(lock, `return [import("lib@^1.2"), import("lib@1.2"), import("vendor/util@*")]`)
+	require.NoError(t, err)
+	require.Equal(t, gad.Array{gad.Str("1.3.1"), gad.Str("1.2.0"), gad.Str("util")}, ret)
+	require.Equal(t, []string{"lib@1.2", "lib@^1.2", "vendor/util@*"}, lock.Names())
+
+	var buf bytes.Buffer
+	_, err = lock.WriteTo(&buf)
+	require.NoError(t, err)
+	require.Contains(t, buf.String(), "lib@^1.2 1.3.1\n")
+
+	path := filepath.Join(tempDir, importers.LockFile)
+	lock.Set("lib@^1.2", "1.2.0")
+	require.NoError(t, lock.Save(path))
+	lock, err = importers.LoadLock(path)
+	require.NoError(t, err)
+	ret, err = run(lock, `return import("lib@^1.2")`)
+	require.NoError(t, err)
+	require.Equal(t, gad.Str("1.2.0"), ret)
+
+	lock.Set("lib@^1.2", "1.4.0")
+	_, err = run(lock, `return import("lib@^1.2")`)
+	require.Error(t, err)
+	require.True(t, strings.Contains(err.Error(), "locked version 1.4.0 not found"))
+
+	_, err = run(nil, `return import("lib@^3")`)
+	require.Error(t, err)
+	require.True(t, strings.Contains(err.Error(), `no version matching "^3" found`))
+}
@@ -0,0 +1,67 @@
+package gad
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+const (
+	// internMaxLen is the maximum length of the interned strings.
+	internMaxLen = 32
+	// internMaxCount is the maximum number of the interned strings. Strings
+	// are not interned after the limit is reached to bound the memory used by
+	// the keys created at runtime.
+	internMaxCount = 1 << 16
+)
+
+var (
+	internTable sync.Map
+	internCount atomic.Int64
+)
+
+// Intern returns the canonical copy of s if it is a short identifier-like
+// string such as a dict key or a field name, otherwise s. Interned strings
+// share their memory and comparing them is a pointer check. It is safe for
+// concurrent use.
+func Intern(s string) string {
+	if !isInternable(s) {
+		return s
+	}
+	if v, ok := internTable.Load(s); ok {
+		return v.(string)
+	}
+	if internCount.Load() >= internMaxCount {
+		return s
+	}
+	v, loaded := internTable.LoadOrStore(s, s)
+	if !loaded {
+		internCount.Add(1)
+	}
+	return v.(string)
+}
+
+// isInternable reports whether s is a non-empty string of at most
+// internMaxLen ASCII letters, digits, '_', '-', '.' or '$'.
+func isInternable(s string) bool {
+	if s == "" || len(s) > internMaxLen {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9',
+			c == '_', c == '-', c == '.', c == '$':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// internKey returns the interned key to store in the dict if it does not
+// have the key yet. Existing keys are kept by the map.
+func (o Dict) internKey(key string) string {
+	if _, ok := o[key]; ok {
+		return key
+	}
+	return Intern(key)
+}
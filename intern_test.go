@@ -0,0 +1,46 @@
+package gad
+
+import (
+	"strings"
+	"testing"
+	"unsafe"
+
+	"github.com/stretchr/testify/require"
+)
+
+func sameString(a, b string) bool {
+	return len(a) == len(b) && unsafe.StringData(a) == unsafe.StringData(b)
+}
+
+func TestIntern(t *testing.T) {
+	a := strings.Repeat("field_", 2)
+	b := strings.Repeat("field_", 2)
+	require.False(t, sameString(a, b))
+	require.True(t, sameString(Intern(a), Intern(b)))
+	require.True(t, sameString(Intern(a), Intern(strings.Clone(a))))
+
+	for _, s := range []string{"", "a b", "ü", strings.Repeat("x", internMaxLen+1)} {
+		require.True(t, sameString(s, Intern(s)), s)
+	}
+
+	bc, err := Compile([]byte(`return {name: 1}`), DefaultCompileOptions)
+	require.NoError(t, err)
+	bc2, err := Compile([]byte(`x := "name"; return x`), DefaultCompileOptions)
+	require.NoError(t, err)
+	key := func(bc *Bytecode) string {
+		for _, c := range bc.Constants {
+			if s, ok := c.(Str); ok && s == "name" {
+				return string(s)
+			}
+		}
+		t.Fatal("constant not found")
+		return ""
+	}
+	require.True(t, sameString(key(bc), key(bc2)))
+
+	d := Dict{}
+	require.NoError(t, d.IndexSet(nil, Str(strings.Clone("name")), Int(1)))
+	for k := range d {
+		require.True(t, sameString(k, key(bc)))
+	}
+}
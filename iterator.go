@@ -11,6 +11,8 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+
+	"github.com/gad-lang/gad/token"
 )
 
 type IteratorStateMode uint8
@@ -85,6 +87,28 @@ type LengthIterator interface {
 	Length() int
 }
 
+// LengthHintIterator is implemented by the iterators which can estimate the
+// number of their entries, like the iterators mapping or filtering other
+// iterators. LengthHint returns the estimated number of entries or a negative
+// value if it is unknown. The hint is used to preallocate collected values, so
+// it may differ from the actual number of entries.
+type LengthHintIterator interface {
+	Iterator
+	LengthHint() int
+}
+
+// IteratorLengthHint returns the length of it if it is a LengthIterator, its
+// length hint if it is a LengthHintIterator, otherwise -1.
+func IteratorLengthHint(it Iterator) int {
+	switch t := it.(type) {
+	case LengthIterator:
+		return t.Length()
+	case LengthHintIterator:
+		return t.LengthHint()
+	}
+	return -1
+}
+
 type CollectableIterator interface {
 	Iterator
 	Collect(vm *VM) (Object, error)
@@ -364,6 +388,785 @@ func (it *zipIterator) Next(vm *VM, state *IteratorState) (err error) {
 	return
 }
 
+type zipLongestIterator struct {
+	Iterators []Iterator
+	Fill      Object
+	states    []*IteratorState
+}
+
+// ZipLongestIterator returns an iterator that aggregates the values of each
+// iterator into arrays until all of them are exhausted. Values of exhausted
+// iterators are replaced by fill.
+func ZipLongestIterator(fill Object, its ...Iterator) Iterator {
+	if fill == nil {
+		fill = Nil
+	}
+	return &zipLongestIterator{Iterators: its, Fill: fill}
+}
+
+func (it *zipLongestIterator) Type() ObjectType {
+	return TZipLongestIterator
+}
+
+func (it *zipLongestIterator) Repr(vm *VM) (_ string, err error) {
+	var s = make([]string, len(it.Iterators))
+	for i := range s {
+		if s[i], err = it.Iterators[i].Repr(vm); err != nil {
+			return
+		}
+	}
+	return ToReprTypedRS(vm, it.Type(), strings.Join(s, ", "))
+}
+
+func (it *zipLongestIterator) Input() Object {
+	its := make(Array, len(it.Iterators))
+	for i, it := range it.Iterators {
+		its[i] = IteratorObject(it)
+	}
+	return its
+}
+
+func (it *zipLongestIterator) Start(vm *VM) (state *IteratorState, err error) {
+	it.states = make([]*IteratorState, len(it.Iterators))
+	for i, iterator := range it.Iterators {
+		if it.states[i], err = iterator.Start(vm); err != nil {
+			return
+		}
+		if err = IteratorStateCheck(vm, iterator, it.states[i]); err != nil {
+			return
+		}
+	}
+	state = &IteratorState{Value: Int(0)}
+	it.read(state)
+	return
+}
+
+func (it *zipLongestIterator) Next(vm *VM, state *IteratorState) (err error) {
+	state.Mode = IteratorStateModeEntry
+	for i, iterator := range it.Iterators {
+		s := it.states[i]
+		if s.Mode == IteratorStateModeDone {
+			continue
+		}
+		s.Mode = IteratorStateModeEntry
+		if err = iterator.Next(vm, s); err != nil {
+			return
+		}
+		if err = IteratorStateCheck(vm, iterator, s); err != nil {
+			return
+		}
+	}
+	if i, ok := state.Value.(Int); ok {
+		state.Value = i + 1
+	}
+	it.read(state)
+	return
+}
+
+func (it *zipLongestIterator) read(state *IteratorState) {
+	var (
+		values = make(Array, len(it.states))
+		done   = true
+	)
+	for i, s := range it.states {
+		if s.Mode == IteratorStateModeDone {
+			values[i] = it.Fill
+		} else {
+			values[i] = s.Entry.V
+			done = false
+		}
+	}
+	if done {
+		state.Mode = IteratorStateModeDone
+		return
+	}
+	state.Entry.K = state.Value
+	state.Entry.V = values
+}
+
+// poolsIterator is the base of combinatoric iterators. It yields arrays of
+// pools values selected by indices.
+type poolsIterator struct {
+	typ     ObjectType
+	Pools   []Array
+	indices []int
+}
+
+func (it *poolsIterator) Type() ObjectType {
+	return it.typ
+}
+
+func (it *poolsIterator) Repr(vm *VM) (string, error) {
+	return ToReprTypedRS(vm, it.typ, it.Input().ToString())
+}
+
+func (it *poolsIterator) Input() Object {
+	arr := make(Array, len(it.Pools))
+	for i, pool := range it.Pools {
+		arr[i] = pool
+	}
+	return arr
+}
+
+// emit sets state entry to the next tuple. The value of state is the
+// sequential index of tuple.
+func (it *poolsIterator) emit(state *IteratorState, tuple Array) {
+	if i, ok := state.Value.(Int); ok {
+		state.Value = i + 1
+	} else {
+		state.Value = Int(0)
+	}
+	state.Mode = IteratorStateModeEntry
+	state.Entry.K = state.Value
+	state.Entry.V = tuple
+}
+
+type productIterator struct {
+	poolsIterator
+}
+
+// ProductIterator returns an iterator of the cartesian product of pools.
+func ProductIterator(pools ...Array) Iterator {
+	return &productIterator{poolsIterator{typ: TProductIterator, Pools: pools}}
+}
+
+func (it *productIterator) tuple() Array {
+	tuple := make(Array, len(it.Pools))
+	for i, pool := range it.Pools {
+		tuple[i] = pool[it.indices[i]]
+	}
+	return tuple
+}
+
+func (it *productIterator) Start(*VM) (state *IteratorState, err error) {
+	state = &IteratorState{}
+	for _, pool := range it.Pools {
+		if len(pool) == 0 {
+			state.Mode = IteratorStateModeDone
+			return
+		}
+	}
+	it.indices = make([]int, len(it.Pools))
+	it.emit(state, it.tuple())
+	return
+}
+
+func (it *productIterator) Next(_ *VM, state *IteratorState) (err error) {
+	for i := len(it.indices) - 1; i >= 0; i-- {
+		if it.indices[i]++; it.indices[i] < len(it.Pools[i]) {
+			it.emit(state, it.tuple())
+			return
+		}
+		it.indices[i] = 0
+	}
+	state.Mode = IteratorStateModeDone
+	return
+}
+
+type permutationsIterator struct {
+	poolsIterator
+	r      int
+	cycles []int
+}
+
+// PermutationsIterator returns an iterator of successive r length permutations
+// of pool values. If r is negative, the pool length is used.
+func PermutationsIterator(pool Array, r int) Iterator {
+	if r < 0 {
+		r = len(pool)
+	}
+	return &permutationsIterator{poolsIterator: poolsIterator{typ: TPermutationsIterator, Pools: []Array{pool}}, r: r}
+}
+
+func (it *permutationsIterator) tuple() Array {
+	tuple := make(Array, it.r)
+	for i, j := range it.indices[:it.r] {
+		tuple[i] = it.Pools[0][j]
+	}
+	return tuple
+}
+
+func (it *permutationsIterator) Start(*VM) (state *IteratorState, err error) {
+	state = &IteratorState{}
+	n := len(it.Pools[0])
+	if it.r > n {
+		state.Mode = IteratorStateModeDone
+		return
+	}
+	it.indices = make([]int, n)
+	for i := range it.indices {
+		it.indices[i] = i
+	}
+	it.cycles = make([]int, it.r)
+	for i := range it.cycles {
+		it.cycles[i] = n - i
+	}
+	it.emit(state, it.tuple())
+	return
+}
+
+func (it *permutationsIterator) Next(_ *VM, state *IteratorState) (err error) {
+	n := len(it.indices)
+	for i := it.r - 1; i >= 0; i-- {
+		if it.cycles[i]--; it.cycles[i] == 0 {
+			first := it.indices[i]
+			copy(it.indices[i:], it.indices[i+1:])
+			it.indices[n-1] = first
+			it.cycles[i] = n - i
+		} else {
+			j := n - it.cycles[i]
+			it.indices[i], it.indices[j] = it.indices[j], it.indices[i]
+			it.emit(state, it.tuple())
+			return
+		}
+	}
+	state.Mode = IteratorStateModeDone
+	return
+}
+
+type combinationsIterator struct {
+	poolsIterator
+	r int
+}
+
+// CombinationsIterator returns an iterator of r length subsequences of pool
+// values in the pool order.
+func CombinationsIterator(pool Array, r int) Iterator {
+	return &combinationsIterator{poolsIterator: poolsIterator{typ: TCombinationsIterator, Pools: []Array{pool}}, r: r}
+}
+
+func (it *combinationsIterator) tuple() Array {
+	tuple := make(Array, it.r)
+	for i, j := range it.indices {
+		tuple[i] = it.Pools[0][j]
+	}
+	return tuple
+}
+
+func (it *combinationsIterator) Start(*VM) (state *IteratorState, err error) {
+	state = &IteratorState{}
+	if it.r < 0 || it.r > len(it.Pools[0]) {
+		state.Mode = IteratorStateModeDone
+		return
+	}
+	it.indices = make([]int, it.r)
+	for i := range it.indices {
+		it.indices[i] = i
+	}
+	it.emit(state, it.tuple())
+	return
+}
+
+func (it *combinationsIterator) Next(_ *VM, state *IteratorState) (err error) {
+	var (
+		n = len(it.Pools[0])
+		i = it.r - 1
+	)
+	for ; i >= 0 && it.indices[i] == i+n-it.r; i-- {
+	}
+	if i < 0 {
+		state.Mode = IteratorStateModeDone
+		return
+	}
+	it.indices[i]++
+	for j := i + 1; j < it.r; j++ {
+		it.indices[j] = it.indices[j-1] + 1
+	}
+	it.emit(state, it.tuple())
+	return
+}
+
+type counterIterator struct {
+	start, step Object
+	cur         Object
+}
+
+// CounterIterator returns an infinite iterator of values starting from start
+// incremented by step.
+func CounterIterator(start, step Object) Iterator {
+	return &counterIterator{start: start, step: step}
+}
+
+func (it *counterIterator) Type() ObjectType {
+	return TCounterIterator
+}
+
+func (it *counterIterator) Repr(vm *VM) (string, error) {
+	return ToReprTypedRS(vm, it.Type(), it.start.ToString()+", "+it.step.ToString())
+}
+
+func (it *counterIterator) Input() Object {
+	return Array{it.start, it.step}
+}
+
+func (it *counterIterator) Start(*VM) (state *IteratorState, err error) {
+	it.cur = it.start
+	state = &IteratorState{Value: Int(0), Entry: KeyValue{K: Int(0), V: it.cur}}
+	return
+}
+
+func (it *counterIterator) Next(vm *VM, state *IteratorState) (err error) {
+	if it.cur, err = BinaryOp(vm, token.Add, it.cur, it.step); err != nil {
+		return
+	}
+	i := state.Value.(Int) + 1
+	state.Value = i
+	state.Entry.K = i
+	state.Entry.V = it.cur
+	return
+}
+
+type cycleIterator struct {
+	Iterator
+	saved Array
+	pos   int
+	i     Int
+}
+
+// CycleIterator returns an infinite iterator which repeats the values of
+// iterator. Values are saved on first pass, so iterator is consumed only once.
+func CycleIterator(it Iterator) Iterator {
+	return &cycleIterator{Iterator: it}
+}
+
+func (it *cycleIterator) Type() ObjectType {
+	return TCycleIterator
+}
+
+func (it *cycleIterator) Repr(vm *VM) (string, error) {
+	return ToReprTypedRS(vm, it.Type(), it.Iterator)
+}
+
+func (it *cycleIterator) Input() Object {
+	return IteratorObject(it.Iterator)
+}
+
+func (it *cycleIterator) Start(vm *VM) (state *IteratorState, err error) {
+	it.saved = nil
+	it.pos = -1
+	it.i = 0
+	if state, err = it.Iterator.Start(vm); err != nil {
+		return
+	}
+	if err = IteratorStateCheck(vm, it.Iterator, state); err != nil || state.Mode == IteratorStateModeDone {
+		return
+	}
+	it.saved = append(it.saved, state.Entry.V)
+	state.Entry.K = it.i
+	return
+}
+
+func (it *cycleIterator) Next(vm *VM, state *IteratorState) (err error) {
+	it.i++
+	if it.pos < 0 {
+		if err = it.Iterator.Next(vm, state); err != nil {
+			return
+		}
+		if err = IteratorStateCheck(vm, it.Iterator, state); err != nil {
+			return
+		}
+		if state.Mode != IteratorStateModeDone {
+			it.saved = append(it.saved, state.Entry.V)
+			state.Entry.K = it.i
+			return
+		}
+		it.pos = 0
+	} else if it.pos++; it.pos == len(it.saved) {
+		it.pos = 0
+	}
+	state.Mode = IteratorStateModeEntry
+	state.Entry.K = it.i
+	state.Entry.V = it.saved[it.pos]
+	return
+}
+
+type repeatIterator struct {
+	value Object
+	times int
+}
+
+// RepeatIterator returns an iterator which yields value times. If times is
+// negative, the iterator is infinite.
+func RepeatIterator(value Object, times int) Iterator {
+	return &repeatIterator{value: value, times: times}
+}
+
+func (it *repeatIterator) Type() ObjectType {
+	return TRepeatIterator
+}
+
+func (it *repeatIterator) Repr(vm *VM) (string, error) {
+	s := it.value.ToString()
+	if it.times >= 0 {
+		s += ", " + strconv.Itoa(it.times)
+	}
+	return ToReprTypedRS(vm, it.Type(), s)
+}
+
+func (it *repeatIterator) Input() Object {
+	return it.value
+}
+
+func (it *repeatIterator) Start(*VM) (state *IteratorState, err error) {
+	state = &IteratorState{Value: Int(0), Entry: KeyValue{K: Int(0), V: it.value}}
+	if it.times == 0 {
+		state.Mode = IteratorStateModeDone
+	}
+	return
+}
+
+func (it *repeatIterator) Next(_ *VM, state *IteratorState) (err error) {
+	i := state.Value.(Int) + 1
+	if it.times >= 0 && int(i) >= it.times {
+		state.Mode = IteratorStateModeDone
+		return
+	}
+	state.Value = i
+	state.Entry.K = i
+	state.Entry.V = it.value
+	return
+}
+
+type generatorIterator struct {
+	seed   Object
+	cur    Object
+	caller VMCaller
+	args   Array
+}
+
+// GeneratorIterator returns an infinite iterator which yields seed, fn(seed),
+// fn(fn(seed)) and so on. The caller must be created with args as arguments.
+func GeneratorIterator(seed Object, args Array, caller VMCaller) Iterator {
+	return &generatorIterator{seed: seed, args: args, caller: caller}
+}
+
+func (it *generatorIterator) Type() ObjectType {
+	return TGeneratorIterator
+}
+
+func (it *generatorIterator) Repr(vm *VM) (s string, err error) {
+	var callee Str
+	if callee, err = ToRepr(vm, it.caller.Callee()); err != nil {
+		return
+	}
+	return ToReprTypedRS(vm, it.Type(), string(callee)+", "+it.seed.ToString())
+}
+
+func (it *generatorIterator) Input() Object {
+	return it.seed
+}
+
+func (it *generatorIterator) Start(*VM) (state *IteratorState, err error) {
+	it.cur = it.seed
+	state = &IteratorState{Value: Int(0), Entry: KeyValue{K: Int(0), V: it.cur}}
+	return
+}
+
+func (it *generatorIterator) Next(_ *VM, state *IteratorState) (err error) {
+	it.args[0] = it.cur
+	if it.cur, err = it.caller.Call(); err != nil {
+		return
+	}
+	i := state.Value.(Int) + 1
+	state.Value = i
+	state.Entry.K = i
+	state.Entry.V = it.cur
+	return
+}
+
+type takeIterator struct {
+	Iterator
+	n     int
+	count int
+}
+
+// TakeIterator returns an iterator which yields only the first n entries of
+// iterator.
+func TakeIterator(it Iterator, n int) Iterator {
+	return &takeIterator{Iterator: it, n: n}
+}
+
+func (it *takeIterator) Type() ObjectType {
+	return TTakeIterator
+}
+
+func (it *takeIterator) Repr(vm *VM) (s string, err error) {
+	if s, err = it.Iterator.Repr(vm); err != nil {
+		return
+	}
+	return ToReprTypedRS(vm, it.Type(), s+", "+strconv.Itoa(it.n))
+}
+
+func (it *takeIterator) LengthHint() int {
+	l := IteratorLengthHint(it.Iterator)
+	if l < 0 {
+		// n may be far more than the entries of the iterator
+		return -1
+	}
+	return min(l, max(it.n, 0))
+}
+
+func (it *takeIterator) Start(vm *VM) (state *IteratorState, err error) {
+	if it.count = 0; it.n <= 0 {
+		return &IteratorState{Mode: IteratorStateModeDone}, nil
+	}
+	if state, err = it.Iterator.Start(vm); err == nil {
+		err = IteratorStateCheck(vm, it.Iterator, state)
+		it.count++
+	}
+	return
+}
+
+func (it *takeIterator) Next(vm *VM, state *IteratorState) (err error) {
+	if it.count >= it.n {
+		state.Mode = IteratorStateModeDone
+		return
+	}
+	if err = it.Iterator.Next(vm, state); err == nil {
+		err = IteratorStateCheck(vm, it.Iterator, state)
+		it.count++
+	}
+	return
+}
+
+// iteratorCache memoizes entries of iterator, so they can be consumed by many
+// readers while the source iterator is consumed only once.
+type iteratorCache struct {
+	it      Iterator
+	state   *IteratorState
+	entries []KeyValue
+	done    bool
+}
+
+func (c *iteratorCache) get(vm *VM, i int) (e *KeyValue, err error) {
+	for i >= len(c.entries) && !c.done {
+		if c.state == nil {
+			c.state, err = c.it.Start(vm)
+		} else {
+			err = c.it.Next(vm, c.state)
+		}
+		if err == nil {
+			err = IteratorStateCheck(vm, c.it, c.state)
+		}
+		if err != nil {
+			return
+		}
+		if c.state.Mode == IteratorStateModeDone {
+			c.done = true
+		} else {
+			c.entries = append(c.entries, c.state.Entry)
+		}
+	}
+	if i < len(c.entries) {
+		e = &c.entries[i]
+	}
+	return
+}
+
+type cachedIterator struct {
+	typ   ObjectType
+	cache *iteratorCache
+}
+
+// CachedIterator returns an iterator which memoizes entries of iterator, so
+// it can be iterated many times while iterator is consumed only once.
+func CachedIterator(it Iterator) Iterator {
+	return &cachedIterator{typ: TCachedIterator, cache: &iteratorCache{it: it}}
+}
+
+// TeeIterators returns n independent iterators over entries of iterator.
+// Iterator is consumed only once and consumed entries are shared.
+func TeeIterators(it Iterator, n int) []Iterator {
+	var (
+		cache = &iteratorCache{it: it}
+		its   = make([]Iterator, n)
+	)
+	for i := range its {
+		its[i] = &cachedIterator{typ: TTeeIterator, cache: cache}
+	}
+	return its
+}
+
+func (it *cachedIterator) Type() ObjectType {
+	return it.typ
+}
+
+func (it *cachedIterator) Repr(vm *VM) (string, error) {
+	return ToReprTypedRS(vm, it.Type(), it.cache.it)
+}
+
+func (it *cachedIterator) Input() Object {
+	return IteratorObject(it.cache.it)
+}
+
+func (it *cachedIterator) Start(vm *VM) (state *IteratorState, err error) {
+	state = &IteratorState{Value: Int(-1)}
+	err = it.Next(vm, state)
+	return
+}
+
+func (it *cachedIterator) Next(vm *VM, state *IteratorState) (err error) {
+	var (
+		i = state.Value.(Int) + 1
+		e *KeyValue
+	)
+	if e, err = it.cache.get(vm, int(i)); err != nil {
+		return
+	}
+	if e == nil {
+		state.Mode = IteratorStateModeDone
+		return
+	}
+	state.Value = i
+	state.Entry = *e
+	return
+}
+
+// IterationStopValue is returned by reducer callbacks to stop iteration early
+// with Value as result.
+type IterationStopValue struct {
+	Value Object
+}
+
+var _ Object = (*IterationStopValue)(nil)
+
+func (o *IterationStopValue) IsFalsy() bool {
+	return false
+}
+
+func (o *IterationStopValue) Type() ObjectType {
+	return IterationStop
+}
+
+func (o *IterationStopValue) ToString() string {
+	return "stop(" + o.Value.ToString() + ")"
+}
+
+func (o *IterationStopValue) Equal(right Object) bool {
+	if v, ok := right.(*IterationStopValue); ok {
+		return o.Value.Equal(v.Value)
+	}
+	return false
+}
+
+type scanIterator struct {
+	Iterator
+	caller  VMCaller
+	args    Array
+	init    Object
+	hasInit bool
+}
+
+// ScanIterator returns an iterator which yields running accumulations of
+// iterator values. The caller must be created with args as arguments, which
+// are set to accumulator, value and key before each call. If hasInit is false,
+// the first value is used as the initial accumulator.
+func ScanIterator(it Iterator, args Array, caller VMCaller, init Object, hasInit bool) Iterator {
+	return &scanIterator{Iterator: it, args: args, caller: caller, init: init, hasInit: hasInit}
+}
+
+func (it *scanIterator) Type() ObjectType {
+	return TScanIterator
+}
+
+func (it *scanIterator) Repr(vm *VM) (s string, err error) {
+	if s, err = it.Iterator.Repr(vm); err != nil {
+		return
+	}
+	var s2 Str
+	if s2, err = ToRepr(vm, it.caller.Callee()); err != nil {
+		return
+	}
+	return ToReprTypedRS(vm, it.Type(), s+" → "+string(s2))
+}
+
+func (it *scanIterator) Input() Object {
+	return IteratorObject(it.Iterator)
+}
+
+func (it *scanIterator) Start(vm *VM) (state *IteratorState, err error) {
+	if state, err = it.Iterator.Start(vm); err != nil {
+		return
+	}
+	if err = IteratorStateCheck(vm, it.Iterator, state); err != nil || state.Mode == IteratorStateModeDone {
+		return
+	}
+	if !it.hasInit {
+		it.args[0] = state.Entry.V
+		return
+	}
+	it.args[0] = it.init
+	err = it.call(state)
+	return
+}
+
+func (it *scanIterator) Next(vm *VM, state *IteratorState) (err error) {
+	if err = it.Iterator.Next(vm, state); err != nil {
+		return
+	}
+	if err = IteratorStateCheck(vm, it.Iterator, state); err != nil || state.Mode == IteratorStateModeDone {
+		return
+	}
+	return it.call(state)
+}
+
+func (it *scanIterator) call(state *IteratorState) (err error) {
+	it.args[1] = state.Entry.V
+	it.args[2] = state.Entry.K
+	var ret Object
+	if ret, err = it.caller.Call(); err == nil {
+		it.args[0] = ret
+		state.Entry.V = ret
+	}
+	return
+}
+
+type chanIterator struct {
+	ch <-chan Object
+}
+
+// ChanIterator returns an iterator which yields values received from ch until
+// ch is closed, the VM is aborted or its Context is done. Receiving blocks the
+// VM, so the sender must close ch when it is done.
+func ChanIterator(ch <-chan Object) Iterator {
+	return &chanIterator{ch: ch}
+}
+
+func (it *chanIterator) Type() ObjectType {
+	return TChanIterator
+}
+
+func (it *chanIterator) Repr(vm *VM) (string, error) {
+	return ToReprTypedRS(vm, it.Type(), fmt.Sprintf("%p", it.ch))
+}
+
+func (it *chanIterator) Input() Object {
+	return Nil
+}
+
+func (it *chanIterator) Start(vm *VM) (state *IteratorState, err error) {
+	state = &IteratorState{Value: Int(-1)}
+	err = it.Next(vm, state)
+	return
+}
+
+func (it *chanIterator) Next(vm *VM, state *IteratorState) (err error) {
+	if vm.Aborted() {
+		state.Mode = IteratorStateModeDone
+		return ErrVMAborted
+	}
+	v, ok, err := vm.receive(it.ch)
+	if !ok {
+		state.Mode = IteratorStateModeDone
+		return
+	}
+	i := state.Value.(Int) + 1
+	state.Value = i
+	state.Entry.K = i
+	state.Entry.V = v
+	return
+}
+
 var _ Object = (*iteratorObject)(nil)
 
 // iteratorObject is used in VM to make an iterable Object.
@@ -402,6 +1205,10 @@ func (o *iteratorObject) GetIterator() Iterator {
 	return o.Iterator
 }
 
+func (o *iteratorObject) LengthHint() int {
+	return IteratorLengthHint(o.Iterator)
+}
+
 func (o *iteratorObject) ToString() string {
 	return "iteratorObject of " + o.Input().ToString()
 }
@@ -543,6 +1350,10 @@ func (s *StateIteratorObject) GetIterator() Iterator {
 	return s.Iterator
 }
 
+func (s *StateIteratorObject) LengthHint() int {
+	return IteratorLengthHint(s.Iterator)
+}
+
 func (s *StateIteratorObject) Read() (_ bool, err error) {
 	if s.State == nil {
 		if s.State, err = s.Start(s.VM); err != nil {
@@ -659,6 +1470,10 @@ func (o *SyncDict) Iterate(_ *VM, na *NamedArgs) Iterator {
 	return o.Value.Iterate(nil, na)
 }
 
+func (o *COWDict) Iterate(_ *VM, na *NamedArgs) Iterator {
+	return o.Load().Iterate(nil, na)
+}
+
 func (o *Buffer) Iterate(_ *VM, na *NamedArgs) Iterator {
 	return Bytes(o.Bytes()).Iterate(nil, na)
 }
@@ -745,6 +1560,10 @@ try:
 	return
 }
 
+func (f *wrapIterator) LengthHint() int {
+	return IteratorLengthHint(f.Iterator)
+}
+
 func (f *wrapIterator) Start(vm *VM) (state *IteratorState, err error) {
 	if state, err = f.Iterator.Start(vm); err != nil {
 		return
@@ -776,6 +1595,10 @@ func CollectModeIterator(iterator Iterator, mode IteratorStateCollectMode) Itera
 	return &collectModeIterator{Iterator: iterator, mode: mode}
 }
 
+func (f *collectModeIterator) LengthHint() int {
+	return IteratorLengthHint(f.Iterator)
+}
+
 func (f *collectModeIterator) Start(vm *VM) (state *IteratorState, err error) {
 	if state, err = f.Iterator.Start(vm); err != nil {
 		return
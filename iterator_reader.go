@@ -0,0 +1,163 @@
+package gad
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"strings"
+)
+
+type readerScanIterator struct {
+	input   Object
+	r       io.Reader
+	split   bufio.SplitFunc
+	maxLen  int
+	scanner *bufio.Scanner
+}
+
+// ReaderScanIterator returns an iterator which lazily reads r and yields the
+// tokens split by split as strings keyed by their indexes. If maxLen is
+// greater than 0, it is the maximum length of a token, otherwise
+// bufio.MaxScanTokenSize is used. r is read once, so the iterator cannot be
+// restarted.
+func ReaderScanIterator(input Object, r io.Reader, split bufio.SplitFunc, maxLen int) Iterator {
+	return &readerScanIterator{input: input, r: r, split: split, maxLen: maxLen}
+}
+
+func (it *readerScanIterator) Type() ObjectType {
+	return TReaderScanIterator
+}
+
+func (it *readerScanIterator) Repr(vm *VM) (string, error) {
+	return ToReprTypedRS(vm, it.Type(), it.input)
+}
+
+func (it *readerScanIterator) Input() Object {
+	return it.input
+}
+
+func (it *readerScanIterator) Start(vm *VM) (state *IteratorState, err error) {
+	if it.scanner == nil {
+		it.scanner = bufio.NewScanner(it.r)
+		if it.maxLen > 0 {
+			it.scanner.Buffer(nil, it.maxLen)
+		}
+		it.scanner.Split(it.split)
+	}
+	state = &IteratorState{Value: Int(-1)}
+	err = it.Next(vm, state)
+	return
+}
+
+func (it *readerScanIterator) Next(vm *VM, state *IteratorState) (err error) {
+	if vm.Aborted() {
+		state.Mode = IteratorStateModeDone
+		return ErrVMAborted
+	}
+	if !it.scanner.Scan() {
+		state.Mode = IteratorStateModeDone
+		return it.scanner.Err()
+	}
+	i := state.Value.(Int) + 1
+	state.Value = i
+	state.Entry.K = i
+	state.Entry.V = Str(it.scanner.Text())
+	return
+}
+
+// readableFrom returns the reader of o or the reader of the contents of o if
+// it is a string or bytes.
+func readableFrom(o Object) io.Reader {
+	switch v := o.(type) {
+	case Str:
+		return strings.NewReader(string(v))
+	case RawStr:
+		return strings.NewReader(string(v))
+	case Bytes:
+		return bytes.NewReader(v)
+	}
+	if r := ReaderFrom(o); r != nil {
+		return r
+	}
+	return nil
+}
+
+// scanSplitFunc returns the bufio.SplitFunc of split which is the name of a
+// bufio split function or a callable. The callable is called with the unread
+// data and whether the end of the input is reached. It returns nil to read
+// more data or an array of the number of bytes to advance and the token which
+// is nil to skip the data. A token must advance the data, except an empty
+// token which ends the scan at the end of the input.
+func scanSplitFunc(vm *VM, split Object) (_ bufio.SplitFunc, err error) {
+	switch v := split.(type) {
+	case Str:
+		switch v {
+		case "lines":
+			return bufio.ScanLines, nil
+		case "words":
+			return bufio.ScanWords, nil
+		case "runes":
+			return bufio.ScanRunes, nil
+		case "bytes":
+			return bufio.ScanBytes, nil
+		}
+		return nil, ErrUnexpectedArgValue.NewError(
+			"split: " + string(v) + `, expected "lines", "words", "runes", "bytes" or callable`)
+	}
+
+	if !Callable(split) {
+		return nil, NewArgumentTypeError("split", "str|callable", split.Type().Name())
+	}
+
+	var (
+		args   = Array{Nil, Nil}
+		caller VMCaller
+	)
+	if caller, err = NewInvoker(vm, split).Caller(Args{args}, nil); err != nil {
+		return
+	}
+
+	return func(data []byte, atEOF bool) (advance int, token []byte, err error) {
+		// data is the buffer of the scanner which is overwritten later
+		args[0] = append(Bytes{}, data...)
+		args[1] = Bool(atEOF)
+
+		var ret Object
+		if ret, err = caller.Call(); err != nil {
+			return
+		}
+
+		switch t := ret.(type) {
+		case *NilType:
+			return
+		case Array:
+			if len(t) == 2 {
+				if n, ok := t[0].(Int); ok && n >= 0 && int(n) <= len(data) {
+					advance = int(n)
+					switch tok := t[1].(type) {
+					case *NilType:
+						return
+					case Str:
+						token = []byte(tok)
+					case Bytes:
+						token = tok
+					default:
+						goto invalid
+					}
+					if advance > 0 {
+						return
+					}
+					if atEOF && len(token) == 0 {
+						return 0, nil, bufio.ErrFinalToken
+					}
+					return 0, nil, ErrType.NewError("split result token does not advance the data: " +
+						ret.ToString())
+				}
+			}
+		}
+	invalid:
+		err = ErrType.NewError("split result expected nil or [advance int, token str|bytes|nil], found " +
+			ret.ToString())
+		return
+	}, nil
+}
@@ -0,0 +1,133 @@
+package lint
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ConfigFile is the name of the lint config file.
+const ConfigFile = ".gadlint"
+
+// DefaultMaxDepth is the default maximum nesting depth of deep-nesting rule.
+const DefaultMaxDepth = 5
+
+// DefaultNaming is the default pattern of the declared names of naming rule.
+// Names are camel case and may start with an underscore.
+var DefaultNaming = regexp.MustCompile(`^(_|_?[a-zA-Z][a-zA-Z0-9]*)$`)
+
+// Config configures the rules of the linter.
+//
+// Config file consists of lines of a rule name followed by its severity (off,
+// warning or error) or its option, lines starting with '#' are comments:
+//
+//	unused-var error
+//	shadowed-builtin off
+//	deep-nesting 4
+//	naming ^[a-z][a-zA-Z0-9]*$
+type Config struct {
+	// Rules maps the rule names to their severities. Missing rules are
+	// reported as warnings.
+	Rules map[string]Severity
+	// MaxDepth is the maximum nesting depth of deep-nesting rule.
+	MaxDepth int
+	// Naming is the pattern of the declared names of naming rule.
+	Naming *regexp.Regexp
+}
+
+// DefaultConfig returns a Config which reports all rules as warnings.
+func DefaultConfig() *Config {
+	return &Config{
+		Rules:    map[string]Severity{},
+		MaxDepth: DefaultMaxDepth,
+		Naming:   DefaultNaming,
+	}
+}
+
+// Severity returns the severity of the rule.
+func (c *Config) Severity(rule string) Severity {
+	if s, ok := c.Rules[rule]; ok {
+		return s
+	}
+	return Warning
+}
+
+// LoadConfig reads the config file at path on top of the default config.
+func LoadConfig(path string) (*Config, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	c := DefaultConfig()
+	return c, c.read(path, f)
+}
+
+// FindConfig returns the path of the nearest config file in dir or its
+// parents. An empty string is returned if there is none.
+func FindConfig(dir string) string {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return ""
+	}
+	for {
+		path := filepath.Join(dir, ConfigFile)
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+func (c *Config) read(name string, r io.Reader) error {
+	s := bufio.NewScanner(r)
+	for line := 1; s.Scan(); line++ {
+		text := strings.TrimSpace(s.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		rule, value, _ := strings.Cut(text, " ")
+		if err := c.set(rule, strings.TrimSpace(value)); err != nil {
+			return fmt.Errorf("%s:%d: %w", name, line, err)
+		}
+	}
+	return s.Err()
+}
+
+func (c *Config) set(rule, value string) (err error) {
+	if !knownRule(rule) {
+		return fmt.Errorf("unknown rule %q", rule)
+	}
+
+	switch s := Severity(value); s {
+	case Off, Warning, Error:
+		c.Rules[rule] = s
+		return nil
+	}
+
+	switch rule {
+	case RuleDeepNesting:
+		if c.MaxDepth, err = strconv.Atoi(value); err == nil && c.MaxDepth < 1 {
+			err = errors.New("depth must be positive")
+		}
+	case RuleNaming:
+		c.Naming, err = regexp.Compile(value)
+	default:
+		err = fmt.Errorf("invalid severity %q, want off, warning or error", value)
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", rule, err)
+	}
+	return nil
+}
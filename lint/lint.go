@@ -0,0 +1,494 @@
+// Package lint implements a linter for Gad source files. Its rules report
+// unused variables, shadowed builtins, suspicious comparisons, unreachable
+// code, deep nesting and names not matching the naming convention. Rules are
+// configured with a Config which is read from a .gadlint file.
+package lint
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/gad-lang/gad"
+	"github.com/gad-lang/gad/parser"
+	"github.com/gad-lang/gad/parser/node"
+	"github.com/gad-lang/gad/parser/source"
+	"github.com/gad-lang/gad/token"
+)
+
+// Rule names.
+const (
+	RuleUnusedVar       = "unused-var"
+	RuleShadowedBuiltin = "shadowed-builtin"
+	RuleMixedEqual      = "mixed-equal"
+	RuleUnreachable     = "unreachable"
+	RuleDeepNesting     = "deep-nesting"
+	RuleNaming          = "naming"
+)
+
+// Rules is the list of the rule names.
+var Rules = []string{
+	RuleUnusedVar,
+	RuleShadowedBuiltin,
+	RuleMixedEqual,
+	RuleUnreachable,
+	RuleDeepNesting,
+	RuleNaming,
+}
+
+func knownRule(name string) bool {
+	for _, r := range Rules {
+		if r == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Severity is the severity of a rule.
+type Severity string
+
+const (
+	Off     Severity = "off"
+	Warning Severity = "warning"
+	Error   Severity = "error"
+)
+
+// Diagnostic represents a rule violation found by the linter.
+type Diagnostic struct {
+	Pos      parser.SourceFilePos
+	Rule     string
+	Severity Severity
+	Message  string
+}
+
+func (d *Diagnostic) String() string {
+	return fmt.Sprintf("%s: %s: %s [%s]", d.Pos, d.Severity, d.Message, d.Rule)
+}
+
+type variable struct {
+	name  string
+	pos   source.Pos
+	used  bool
+	param bool
+}
+
+type scope struct {
+	parent *scope
+	vars   []*variable
+}
+
+func (s *scope) lookup(name string) *variable {
+	for ; s != nil; s = s.parent {
+		for i := len(s.vars) - 1; i >= 0; i-- {
+			if s.vars[i].name == name {
+				return s.vars[i]
+			}
+		}
+	}
+	return nil
+}
+
+// Linter walks a parsed file and collects diagnostics.
+type Linter struct {
+	config      *Config
+	file        *parser.SourceFile
+	scope       *scope
+	depth       int
+	diagnostics []*Diagnostic
+}
+
+// Lint lints the parsed file and returns diagnostics sorted by position. If
+// config is nil, DefaultConfig is used.
+func Lint(file *parser.File, config *Config) []*Diagnostic {
+	if config == nil {
+		config = DefaultConfig()
+	}
+	l := &Linter{
+		config: config,
+		file:   file.InputFile,
+		scope:  &scope{},
+	}
+	l.stmts(file.Stmts)
+	l.closeScope()
+	sort.SliceStable(l.diagnostics, func(i, j int) bool {
+		return l.diagnostics[i].Pos.Offset < l.diagnostics[j].Pos.Offset
+	})
+	return l.diagnostics
+}
+
+// LintSource parses the source and lints it. Parse errors are returned as
+// error.
+func LintSource(name string, src []byte, config *Config) ([]*Diagnostic, error) {
+	fileSet := parser.NewFileSet()
+	srcFile := fileSet.AddFile(name, -1, len(src))
+	p := parser.NewParser(srcFile, src, nil)
+	file, err := p.ParseFile()
+	if err != nil {
+		return nil, err
+	}
+	return Lint(file, config), nil
+}
+
+func (l *Linter) report(rule string, pos source.Pos, format string, args ...any) {
+	severity := l.config.Severity(rule)
+	if severity == Off {
+		return
+	}
+	l.diagnostics = append(l.diagnostics, &Diagnostic{
+		Pos:      l.file.Position(pos),
+		Rule:     rule,
+		Severity: severity,
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+func (l *Linter) openScope() {
+	l.scope = &scope{parent: l.scope}
+}
+
+// closeScope reports the unused variables of the current scope.
+func (l *Linter) closeScope() {
+	for _, v := range l.scope.vars {
+		if !v.used && !v.param && v.name != "_" {
+			l.report(RuleUnusedVar, v.pos, "'%s' declared and not used", v.name)
+		}
+	}
+	l.scope = l.scope.parent
+}
+
+func (l *Linter) define(ident *node.Ident, param bool) {
+	if ident == nil {
+		return
+	}
+	if _, ok := gad.BuiltinsMap[ident.Name]; ok {
+		l.report(RuleShadowedBuiltin, ident.NamePos, "'%s' shadows the builtin", ident.Name)
+	}
+	if l.config.Naming != nil && !l.config.Naming.MatchString(ident.Name) {
+		l.report(RuleNaming, ident.NamePos, "name '%s' does not match %s", ident.Name, l.config.Naming)
+	}
+	l.scope.vars = append(l.scope.vars, &variable{name: ident.Name, pos: ident.NamePos, param: param})
+}
+
+func (l *Linter) use(name string) {
+	if v := l.scope.lookup(name); v != nil {
+		v.used = true
+	}
+}
+
+// nested walks the body of a control statement one level deeper and reports
+// the statement if it exceeds the maximum depth.
+func (l *Linter) nested(pos source.Pos, body func()) {
+	l.depth++
+	if l.depth == l.config.MaxDepth+1 {
+		l.report(RuleDeepNesting, pos, "nesting depth %d exceeds %d", l.depth, l.config.MaxDepth)
+	}
+	body()
+	l.depth--
+}
+
+func (l *Linter) stmts(stmts []node.Stmt) {
+	var terminated bool
+	for _, stmt := range stmts {
+		if _, empty := stmt.(*node.EmptyStmt); terminated && !empty {
+			l.report(RuleUnreachable, stmt.Pos(), "unreachable code")
+			terminated = false
+		}
+		l.stmt(stmt)
+		switch stmt.(type) {
+		case *node.ReturnStmt, *node.ThrowStmt, *node.BranchStmt:
+			terminated = true
+		}
+	}
+}
+
+func (l *Linter) block(b *node.BlockStmt) {
+	if b == nil {
+		return
+	}
+	l.openScope()
+	l.stmts(b.Stmts)
+	l.closeScope()
+}
+
+func (l *Linter) stmt(stmt node.Stmt) {
+	switch s := stmt.(type) {
+	case *node.ExprStmt:
+		if f, _ := s.Expr.(*node.FuncLit); f != nil && f.Type.Ident != nil {
+			if v := l.scope.lookup(f.Type.Ident.Name); v == nil {
+				l.define(f.Type.Ident, false)
+			}
+		}
+		l.expr(s.Expr)
+	case *node.BlockStmt:
+		l.block(s)
+	case *node.DeclStmt:
+		l.decl(s.Decl.(*node.GenDecl))
+	case *node.AssignStmt:
+		l.assign(s)
+	case *node.IncDecStmt:
+		l.expr(s.Expr)
+	case *node.ReturnStmt:
+		l.expr(s.Result)
+	case *node.IfStmt:
+		l.openScope()
+		if s.Init != nil {
+			l.stmt(s.Init)
+		}
+		l.expr(s.Cond)
+		l.nested(s.Pos(), func() { l.block(s.Body) })
+		if s.Else != nil {
+			// else if is at the same depth
+			l.stmt(s.Else)
+		}
+		l.closeScope()
+	case *node.ForStmt:
+		l.openScope()
+		if s.Init != nil {
+			l.stmt(s.Init)
+		}
+		l.expr(s.Cond)
+		if s.Post != nil {
+			l.stmt(s.Post)
+		}
+		l.nested(s.Pos(), func() { l.block(s.Body) })
+		l.closeScope()
+	case *node.ForInStmt:
+		l.expr(s.Iterable)
+		l.openScope()
+		l.define(s.Key, false)
+		l.define(s.Value, false)
+		l.nested(s.Pos(), func() { l.block(s.Body) })
+		l.closeScope()
+		l.block(s.Else)
+	case *node.TryStmt:
+		l.nested(s.Pos(), func() {
+			l.block(s.Body)
+			if s.Catch != nil {
+				l.openScope()
+				// catch variable is not required to be used
+				l.define(s.Catch.Ident, true)
+				l.block(s.Catch.Body)
+				l.closeScope()
+			}
+			if s.Finally != nil {
+				l.block(s.Finally.Body)
+			}
+		})
+	case *node.ThrowStmt:
+		l.expr(s.Expr)
+	case *node.ExprToTextStmt:
+		l.expr(s.Expr)
+	}
+}
+
+func (l *Linter) decl(d *node.GenDecl) {
+	for _, spec := range d.Specs {
+		switch s := spec.(type) {
+		case *node.ValueSpec:
+			for i, ident := range s.Idents {
+				if i < len(s.Values) {
+					l.expr(s.Values[i])
+				}
+				if d.Tok != token.Global {
+					l.define(ident, false)
+				}
+			}
+		case *node.ParamSpec:
+			l.define(s.Ident.Ident, true)
+		case *node.NamedParamSpec:
+			l.expr(s.Value)
+			l.define(s.Ident.Ident, true)
+		}
+	}
+}
+
+func (l *Linter) assign(s *node.AssignStmt) {
+	for _, expr := range s.RHS {
+		l.expr(expr)
+	}
+
+	for _, expr := range s.LHS {
+		ident, _ := expr.(*node.Ident)
+		switch {
+		case ident == nil:
+			l.expr(expr)
+		case s.Token == token.Define:
+			l.define(ident, false)
+		case s.Token != token.Assign:
+			// compound assignment reads the variable
+			l.use(ident.Name)
+		}
+	}
+}
+
+func (l *Linter) funcBody(typ *node.FuncType, body func()) {
+	depth := l.depth
+	l.depth = 0
+	l.openScope()
+
+	for _, param := range typ.Params.Args.Values {
+		l.define(param.Ident, true)
+	}
+	if param := typ.Params.Args.Var; param != nil {
+		l.define(param.Ident, true)
+	}
+	for i, param := range typ.Params.NamedArgs.Names {
+		l.expr(typ.Params.NamedArgs.Values[i])
+		l.define(param.Ident, true)
+	}
+	if param := typ.Params.NamedArgs.Var; param != nil {
+		l.define(param.Ident, true)
+	}
+
+	body()
+
+	l.closeScope()
+	l.depth = depth
+}
+
+func (l *Linter) expr(expr node.Expr) {
+	switch e := expr.(type) {
+	case nil:
+	case *node.Ident:
+		l.use(e.Name)
+	case *node.FuncLit:
+		l.funcBody(e.Type, func() {
+			l.stmts(e.Body.Stmts)
+		})
+	case *node.ClosureLit:
+		l.funcBody(e.Type, func() {
+			if b, ok := e.Body.(*node.BlockExpr); ok {
+				l.stmts(b.Stmts)
+			} else {
+				l.expr(e.Body)
+			}
+		})
+	case *node.CallExpr:
+		l.expr(e.Func)
+		for _, arg := range e.Args.Values {
+			l.expr(arg)
+		}
+		if e.Args.Var != nil {
+			l.expr(e.Args.Var.Value)
+		}
+		for _, arg := range e.NamedArgs.Values {
+			l.expr(arg)
+		}
+		if e.NamedArgs.Var != nil {
+			l.expr(e.NamedArgs.Var.Value)
+		}
+	case *node.BinaryExpr:
+		l.expr(e.LHS)
+		l.expr(e.RHS)
+		if e.Token == token.Equal || e.Token == token.NotEqual {
+			l.compare(e)
+		}
+	case *node.UnaryExpr:
+		l.expr(e.Expr)
+	case *node.ParenExpr:
+		l.expr(e.Expr)
+	case *node.MultiParenExpr:
+		for _, expr := range e.Exprs {
+			l.expr(expr)
+		}
+	case *node.CondExpr:
+		l.expr(e.Cond)
+		l.expr(e.True)
+		l.expr(e.False)
+	case *node.IndexExpr:
+		l.expr(e.Expr)
+		l.expr(e.Index)
+	case *node.SliceExpr:
+		l.expr(e.Expr)
+		l.expr(e.Low)
+		l.expr(e.High)
+	case *node.SelectorExpr:
+		l.expr(e.Expr)
+	case *node.NullishSelectorExpr:
+		l.expr(e.Expr)
+	case *node.ArrayLit:
+		for _, elem := range e.Elements {
+			l.expr(elem)
+		}
+	case *node.DictLit:
+		for _, elem := range e.Elements {
+			l.expr(elem.Value)
+		}
+	case *node.KeyValueLit:
+		if _, ok := e.Key.(*node.Ident); !ok {
+			l.expr(e.Key)
+		}
+		l.expr(e.Value)
+	case *node.KeyValueArrayLit:
+		for _, elem := range e.Elements {
+			l.expr(elem)
+		}
+	case *node.ArgVarLit:
+		l.expr(e.Value)
+	case *node.NamedArgVarLit:
+		l.expr(e.Value)
+	case *node.BlockExpr:
+		l.block(e.BlockStmt)
+	case *node.StmtsExpr:
+		l.stmts(e.Stmts)
+	case *node.ReturnExpr:
+		l.expr(e.Result)
+	case *node.ThrowExpr:
+		l.expr(e.Expr)
+	}
+}
+
+// compare reports == and != comparisons of values of different types which
+// are known statically, such comparisons are always false or always true.
+func (l *Linter) compare(e *node.BinaryExpr) {
+	lt, rt := typeOf(e.LHS), typeOf(e.RHS)
+	if lt == "" || rt == "" || lt == rt || numeric[lt] && numeric[rt] {
+		return
+	}
+	l.report(RuleMixedEqual, e.TokenPos, "suspicious %s comparison of %s and %s values", e.Token, lt, rt)
+}
+
+var numeric = map[string]bool{
+	"int":     true,
+	"uint":    true,
+	"float":   true,
+	"decimal": true,
+	"char":    true,
+}
+
+// typeOf returns the statically known type name of expression or an empty
+// string if the type is unknown. Nil is unknown as it is compared to any type.
+func typeOf(expr node.Expr) string {
+	switch e := expr.(type) {
+	case *node.IntLit:
+		return "int"
+	case *node.UintLit:
+		return "uint"
+	case *node.FloatLit:
+		return "float"
+	case *node.DecimalLit:
+		return "decimal"
+	case *node.CharLit:
+		return "char"
+	case *node.StringLit, *node.RawStringLit:
+		return "str"
+	case *node.BoolLit:
+		return "bool"
+	case *node.ArrayLit:
+		return "array"
+	case *node.DictLit:
+		return "dict"
+	case *node.ParenExpr:
+		return typeOf(e.Expr)
+	case *node.UnaryExpr:
+		if e.Token == token.Not {
+			return "bool"
+		}
+	case *node.BinaryExpr:
+		switch e.Token {
+		case token.Equal, token.NotEqual, token.Less, token.LessEq, token.Greater, token.GreaterEq:
+			return "bool"
+		}
+	}
+	return ""
+}
@@ -0,0 +1,107 @@
+package lint_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/gad-lang/gad/lint"
+)
+
+func expectDiagnostics(t *testing.T, config *lint.Config, src string, expected ...string) {
+	t.Helper()
+	diagnostics, err := lint.LintSource("test", []byte(src), config)
+	require.NoError(t, err)
+	var got []string
+	for _, d := range diagnostics {
+		got = append(got, d.String())
+	}
+	require.Equal(t, expected, got, src)
+}
+
+func TestLint(t *testing.T) {
+	expectDiagnostics(t, nil, `a := 1; b := func(x, *y; z=a) { return x }; return b(a)`)
+	expectDiagnostics(t, nil, `a := 1; b := 2; b = 3; return`,
+		`test:1:1: warning: 'a' declared and not used [unused-var]`,
+		`test:1:9: warning: 'b' declared and not used [unused-var]`)
+	expectDiagnostics(t, nil, `a := 1; a += 2; var (c, d = 1); for k, v in {} { println(v) }; try {} catch err {}`,
+		`test:1:22: warning: 'c' declared and not used [unused-var]`,
+		`test:1:25: warning: 'd' declared and not used [unused-var]`,
+		`test:1:37: warning: 'k' declared and not used [unused-var]`)
+	expectDiagnostics(t, nil, `func f() {}; global g; param p; f()`)
+
+	expectDiagnostics(t, nil, `len := 1; return func(str) { return str }(len)`,
+		`test:1:1: warning: 'len' shadows the builtin [shadowed-builtin]`,
+		`test:1:23: warning: 'str' shadows the builtin [shadowed-builtin]`)
+
+	expectDiagnostics(t, nil, `return [1 == "1", 1 != 1.0, "a" == 'a', [] == {}, 1 == nil, (1 < 2) == "x"]`,
+		`test:1:11: warning: suspicious == comparison of int and str values [mixed-equal]`,
+		`test:1:33: warning: suspicious == comparison of str and char values [mixed-equal]`,
+		`test:1:44: warning: suspicious == comparison of array and dict values [mixed-equal]`,
+		`test:1:69: warning: suspicious == comparison of bool and str values [mixed-equal]`)
+
+	expectDiagnostics(t, nil, "f := func() {\n\treturn 1\n\tprintln(2)\n\tprintln(3)\n}\nf()",
+		`test:3:2: warning: unreachable code [unreachable]`)
+	expectDiagnostics(t, nil, "for {\n\tbreak\n\tprintln(1)\n}\nthrow 1\nreturn",
+		`test:3:2: warning: unreachable code [unreachable]`,
+		`test:6:1: warning: unreachable code [unreachable]`)
+
+	expectDiagnostics(t, &lint.Config{MaxDepth: 2},
+		"if true {\n\tfor {\n\t\tif true {\n\t\t\tif true {}\n\t\t}\n\t}\n} else if true {\n\tfor {}\n}",
+		`test:3:3: warning: nesting depth 3 exceeds 2 [deep-nesting]`)
+	expectDiagnostics(t, &lint.Config{MaxDepth: 2},
+		"for {\n\tfor {\n\t\tf := func() {\n\t\t\tfor { for {} }\n\t\t}\n\t\tf()\n\t}\n}")
+
+	expectDiagnostics(t, nil, `my_var := 1; _x := 2; MyType := 3; _ := 4; return [my_var, _x, MyType]`,
+		`test:1:1: warning: name 'my_var' does not match ^(_|_?[a-zA-Z][a-zA-Z0-9]*)$ [naming]`)
+}
+
+func TestLintConfig(t *testing.T) {
+	dir := t.TempDir()
+	sub := filepath.Join(dir, "sub")
+	require.NoError(t, os.Mkdir(sub, 0o755))
+	require.Equal(t, "", lint.FindConfig(sub))
+
+	path := filepath.Join(dir, lint.ConfigFile)
+	require.NoError(t, os.WriteFile(path, []byte(`
+# lint rules
+unused-var error
+shadowed-builtin off
+deep-nesting 1
+naming ^[a-z]+$
+`), 0o644))
+	require.Equal(t, path, lint.FindConfig(sub))
+
+	config, err := lint.LoadConfig(path)
+	require.NoError(t, err)
+	expectDiagnostics(t, config, "len := 1\nif true { if true { aB := 1 } }",
+		`test:1:1: error: 'len' declared and not used [unused-var]`,
+		`test:2:11: warning: nesting depth 2 exceeds 1 [deep-nesting]`,
+		`test:2:21: warning: name 'aB' does not match ^[a-z]+$ [naming]`,
+		`test:2:21: error: 'aB' declared and not used [unused-var]`)
+
+	for src, msg := range map[string]string{
+		"unknown off":       `:1: unknown rule "unknown"`,
+		"unused-var 1":      `:1: unused-var: invalid severity "1", want off, warning or error`,
+		"deep-nesting 0":    `:1: deep-nesting: depth must be positive`,
+		"# c\nnaming (":     `:2: naming: error parsing regexp`,
+		"mixed-equal error": "",
+	} {
+		require.NoError(t, os.WriteFile(path, []byte(src), 0o644))
+		_, err = lint.LoadConfig(path)
+		if msg == "" {
+			require.NoError(t, err)
+		} else {
+			require.Error(t, err)
+			require.True(t, strings.HasPrefix(err.Error(), path+msg), err.Error())
+		}
+	}
+}
+
+func TestLintSourceError(t *testing.T) {
+	_, err := lint.LintSource("test", []byte(`a :=`), nil)
+	require.Error(t, err)
+}
@@ -0,0 +1,175 @@
+package gad
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Array merge modes of Merge.
+const (
+	MergeArraysReplace = "replace"
+	MergeArraysConcat  = "concat"
+)
+
+// Merge returns a new dict with the entries of a overridden by the entries of
+// b. If deep is true, the dicts in both a and b are merged recursively. Arrays
+// in both a and b are replaced by the arrays of b or concatenated by the
+// arrays mode, MergeArraysReplace or MergeArraysConcat. a and b are not
+// modified.
+func Merge(a, b Dict, deep bool, arrays string) Dict {
+	r := make(Dict, len(a)+len(b))
+	for k, v := range a {
+		r[k] = v
+	}
+	for k, bv := range b {
+		switch av := r[k].(type) {
+		case Dict:
+			if bd, ok := bv.(Dict); ok && deep {
+				bv = Merge(av, bd, deep, arrays)
+			}
+		case Array:
+			if ba, ok := bv.(Array); ok && arrays == MergeArraysConcat {
+				bv = append(append(make(Array, 0, len(av)+len(ba)), av...), ba...)
+			}
+		}
+		r[k] = bv
+	}
+	return r
+}
+
+// MergePatch returns the result of applying the JSON merge patch to doc as
+// described in RFC 7386: a nil value in the patch removes the key, a dict is
+// merged recursively and other values replace the values of doc. doc is not
+// modified.
+func MergePatch(doc, patch Object) Object {
+	p, ok := patch.(Dict)
+	if !ok {
+		return patch
+	}
+	d, _ := doc.(Dict)
+	r := make(Dict, len(d)+len(p))
+	for k, v := range d {
+		r[k] = v
+	}
+	for k, v := range p {
+		if v == Nil {
+			delete(r, k)
+		} else if cur, ok := r[k]; ok {
+			r[k] = MergePatch(cur, v)
+		} else {
+			r[k] = MergePatch(nil, v)
+		}
+	}
+	return r
+}
+
+// ParsePointer returns the reference tokens of the JSON pointer described in
+// RFC 6901, e.g. "/a/b/0". The empty pointer refers to the whole document.
+func ParsePointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return nil, nil
+	}
+	if pointer[0] != '/' {
+		return nil, ErrInvalidIndex.NewError("invalid JSON pointer " + strconv.Quote(pointer))
+	}
+	tokens := strings.Split(pointer[1:], "/")
+	for i, t := range tokens {
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(t, "~1", "/"), "~0", "~")
+	}
+	return tokens, nil
+}
+
+// GetPath returns the value of doc at the JSON pointer, see ParsePointer. Nil
+// is returned if the value does not exist.
+func GetPath(doc Object, pointer string) (Object, error) {
+	tokens, err := ParsePointer(pointer)
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range tokens {
+		switch v := doc.(type) {
+		case Dict:
+			var ok bool
+			if doc, ok = v[t]; !ok {
+				return Nil, nil
+			}
+		case Array:
+			i, ok := pointerIndex(t, len(v))
+			if !ok || i == len(v) {
+				return Nil, nil
+			}
+			doc = v[i]
+		default:
+			return Nil, nil
+		}
+	}
+	return doc, nil
+}
+
+// SetPath sets the value of doc at the JSON pointer, see ParsePointer, and
+// returns doc. Missing dicts on the path are created and "-" token or the
+// length of an array appends the value to the array. doc is modified, the
+// returned value must be used if doc is an array which the value is appended
+// to.
+func SetPath(doc Object, pointer string, value Object) (Object, error) {
+	tokens, err := ParsePointer(pointer)
+	if err != nil {
+		return nil, err
+	}
+	return setPath(doc, tokens, value)
+}
+
+func setPath(doc Object, tokens []string, value Object) (_ Object, err error) {
+	if len(tokens) == 0 {
+		return value, nil
+	}
+	t := tokens[0]
+	switch v := doc.(type) {
+	case Dict:
+		cur, ok := v[t]
+		if !ok {
+			cur = Dict{}
+		}
+		if cur, err = setPath(cur, tokens[1:], value); err != nil {
+			return
+		}
+		v[t] = cur
+		return v, nil
+	case Array:
+		i, ok := pointerIndex(t, len(v))
+		if !ok {
+			return nil, ErrIndexOutOfBounds.NewError(t)
+		}
+		var elem Object
+		if i == len(v) {
+			if elem, err = setPath(Dict{}, tokens[1:], value); err != nil {
+				return
+			}
+			return append(v, elem), nil
+		}
+		if elem, err = setPath(v[i], tokens[1:], value); err != nil {
+			return
+		}
+		v[i] = elem
+		return v, nil
+	case *NilType:
+		return setPath(Dict{}, tokens, value)
+	}
+	return nil, ErrNotIndexAssignable.NewError(doc.Type().Name())
+}
+
+// pointerIndex returns the array index of the JSON pointer token, "-" refers
+// to the length of the array.
+func pointerIndex(t string, length int) (int, bool) {
+	if t == "-" {
+		return length, true
+	}
+	if len(t) > 1 && t[0] == '0' {
+		return 0, false
+	}
+	i, err := strconv.Atoi(t)
+	if err != nil || i < 0 || i > length {
+		return 0, false
+	}
+	return i, true
+}
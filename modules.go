@@ -6,7 +6,11 @@ package gad
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
+	"fmt"
+	"strings"
 
 	"github.com/gad-lang/gad/parser/ast"
 )
@@ -33,6 +37,15 @@ type ExtImporter interface {
 	Fork(moduleName string) ExtImporter
 }
 
+// PathsExtImporter is an ExtImporter which can search modules in additional
+// paths set by `# gad: path="..."` config of the importing script.
+type PathsExtImporter interface {
+	ExtImporter
+	// WithPaths returns a copy of the importer which also searches modules in
+	// paths after its own resolution fails.
+	WithPaths(paths ...string) ExtImporter
+}
+
 type CompilableImporter interface {
 	Importable
 	CompileModule(compiler *Compiler, nd ast.Node, module *ModuleInfo, moduleMap *ModuleMap, src []byte) (bc *Bytecode, err error)
@@ -41,8 +54,15 @@ type CompilableImporter interface {
 // ModuleMap represents a set of named modules. Use NewModuleMap to create a
 // new module map.
 type ModuleMap struct {
-	m  map[string]Importable
-	im ExtImporter
+	m         map[string]Importable
+	im        ExtImporter
+	checksums *moduleChecksums
+}
+
+// moduleChecksums holds expected SHA-256 digests of module sources.
+type moduleChecksums struct {
+	sums   map[string]string
+	strict bool
 }
 
 // NewModuleMap creates a new module map.
@@ -65,11 +85,61 @@ func (m *ModuleMap) Fork(moduleName string) *ModuleMap {
 	}
 	if m.im != nil {
 		fork := m.im.Fork(moduleName)
-		return &ModuleMap{m: m.m, im: fork}
+		return &ModuleMap{m: m.m, im: fork, checksums: m.checksums}
+	}
+	return m
+}
+
+// WithImportPaths returns a copy of ModuleMap whose ExtImporter also searches
+// modules in paths. It returns m if ExtImporter is not a PathsExtImporter.
+func (m *ModuleMap) WithImportPaths(paths ...string) *ModuleMap {
+	if m == nil || len(paths) == 0 {
+		return m
+	}
+	if im, ok := m.im.(PathsExtImporter); ok {
+		return &ModuleMap{m: m.m, im: im.WithPaths(paths...), checksums: m.checksums}
+	}
+	return m
+}
+
+// SetChecksums sets the expected hex encoded SHA-256 digests of the module
+// sources by module name. A name is either the name given to import or the
+// name resolved by ExtImporter like the absolute path of a file. Compiling an
+// import fails with ErrModuleChecksum if the digest of a module does not
+// match. If strict is true, modules imported by ExtImporter without a digest
+// are rejected too. Modules importing Objects like builtin modules are not
+// checked.
+func (m *ModuleMap) SetChecksums(sums map[string]string, strict bool) *ModuleMap {
+	cs := &moduleChecksums{sums: make(map[string]string, len(sums)), strict: strict}
+	for name, sum := range sums {
+		cs.sums[name] = strings.ToLower(sum)
 	}
+	m.checksums = cs
 	return m
 }
 
+// verifyChecksum checks the digest of the module source data imported with
+// names.
+func (m *ModuleMap) verifyChecksum(data []byte, ext bool, names ...string) error {
+	if m == nil || m.checksums == nil {
+		return nil
+	}
+	for _, name := range names {
+		if want, ok := m.checksums.sums[name]; ok {
+			sum := sha256.Sum256(data)
+			if got := hex.EncodeToString(sum[:]); got != want {
+				return ErrModuleChecksum.NewError(fmt.Sprintf(
+					"module '%s' checksum mismatch: want %s, got %s", name, want, got))
+			}
+			return nil
+		}
+	}
+	if ext && m.checksums.strict {
+		return ErrModuleChecksum.NewError(fmt.Sprintf("module '%s' has no checksum", names[0]))
+	}
+	return nil
+}
+
 // Add adds an importable module.
 func (m *ModuleMap) Add(name string, module Importable) *ModuleMap {
 	m.m[name] = module
@@ -91,6 +161,16 @@ func (m *ModuleMap) AddSourceModule(name string, src []byte) *ModuleMap {
 	return m
 }
 
+// AddFactoryModule adds a module whose attributes are created by fn for
+// every VM importing it.
+func (m *ModuleMap) AddFactoryModule(
+	name string,
+	fn func(vm *VM) (map[string]Object, error),
+) *ModuleMap {
+	m.m[name] = &FactoryModule{New: fn}
+	return m
+}
+
 // Remove removes a named module.
 func (m *ModuleMap) Remove(name string) {
 	delete(m.m, name)
@@ -112,7 +192,7 @@ func (m *ModuleMap) Get(name string) Importable {
 
 // Copy creates a copy of the module map.
 func (m *ModuleMap) Copy() *ModuleMap {
-	c := &ModuleMap{m: make(map[string]Importable), im: m.im}
+	c := &ModuleMap{m: make(map[string]Importable), im: m.im, checksums: m.checksums}
 
 	for name, mod := range m.m {
 		c.m[name] = mod
@@ -145,3 +225,56 @@ func (m *BuiltinModule) Import(_ context.Context, moduleName string) (any, strin
 	cp.(Dict)[AttrModuleName] = Str(moduleName)
 	return cp, "builtin:" + moduleName, nil
 }
+
+// FactoryModule is an importable module whose attributes are created by New
+// when a VM imports it first time. It lets every VM get a module instance
+// configured for it, e.g. an http client restricted to the hosts of the tenant
+// which is read from VM's Context.
+type FactoryModule struct {
+	New func(vm *VM) (map[string]Object, error)
+}
+
+// Import returns a module factory object which is called by VM.
+func (m *FactoryModule) Import(_ context.Context, moduleName string) (any, string, error) {
+	if m.New == nil {
+		return nil, "", errors.New("module factory not set")
+	}
+	return &moduleFactory{name: moduleName, new: m.New}, "factory:" + moduleName, nil
+}
+
+// moduleFactory is the constant of a FactoryModule import which is replaced
+// with the module created for the VM.
+type moduleFactory struct {
+	name string
+	new  func(vm *VM) (map[string]Object, error)
+}
+
+func (f *moduleFactory) Type() ObjectType {
+	return TModuleFactory
+}
+
+func (f *moduleFactory) ToString() string {
+	return ReprQuote("moduleFactory:" + f.name)
+}
+
+func (f *moduleFactory) IsFalsy() bool {
+	return false
+}
+
+func (f *moduleFactory) Equal(right Object) bool {
+	v, ok := right.(*moduleFactory)
+	return ok && v == f
+}
+
+func (f *moduleFactory) instance(vm *VM) (Object, error) {
+	attrs, err := f.new(vm)
+	if err != nil {
+		return nil, err
+	}
+	d := make(Dict, len(attrs)+1)
+	for k, v := range attrs {
+		d[k] = v
+	}
+	d[AttrModuleName] = Str(f.name)
+	return d, nil
+}
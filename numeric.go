@@ -6,6 +6,7 @@ package gad
 
 import (
 	"fmt"
+	"io"
 	"math/big"
 	"strconv"
 	"strings"
@@ -118,6 +119,8 @@ func (o Int) BinaryOp(vm *VM, tok token.Token, right Object) (Object, error) {
 			right = Int(0)
 		}
 		return o.BinaryOp(vm, tok, right)
+	case IntArray, FloatArray:
+		return numArrayBinaryOp(tok, o, right)
 	case *NilType:
 		switch tok {
 		case token.Less, token.LessEq:
@@ -340,6 +343,8 @@ func (o Float) BinaryOp(vm *VM, tok token.Token, right Object) (Object, error) {
 			right = Float(0)
 		}
 		return o.BinaryOp(vm, tok, right)
+	case IntArray, FloatArray:
+		return numArrayBinaryOp(tok, o, right)
 	case *NilType:
 		switch tok {
 		case token.Less, token.LessEq:
@@ -477,10 +482,30 @@ func (o Decimal) BinaryOp(vm *VM, tok token.Token, right Object) (Object, error)
 	)
 }
 
-// Format implements fmt.Formatter interface.
+// Format implements fmt.Formatter interface. The f verb writes the exact
+// digits of the precision, the e and g verbs format the float64 value.
 func (o Decimal) Format(s fmt.State, verb rune) {
-	format := compat.FmtFormatString(s, verb)
-	fmt.Fprintf(s, format, o.Go())
+	var str string
+	switch verb {
+	case 'f', 'F':
+		if p, ok := s.Precision(); ok {
+			str = o.Go().StringFixed(int32(p))
+		} else {
+			str = o.Go().String()
+		}
+	case 'd':
+		str = o.Go().Truncate(0).String()
+	case 'e', 'E', 'g', 'G':
+		f, _ := o.Go().Float64()
+		fmt.Fprintf(s, compat.FmtFormatString(s, verb), f)
+		return
+	case 's', 'v':
+		str = o.Go().String()
+	default:
+		fmt.Fprintf(s, compat.FmtFormatString(s, verb), o.Go().String())
+		return
+	}
+	_, _ = io.WriteString(s, fmtSpec(s, verb).pad(str, true))
 }
 
 func (o Decimal) ToBytes() (b Bytes, err error) {
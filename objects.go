@@ -393,12 +393,12 @@ func (o Str) Equal(right Object) bool {
 func (o Str) IsFalsy() bool { return len(o) == 0 }
 
 // BinaryOp implements Object interface.
-func (o Str) BinaryOp(_ *VM, tok token.Token, right Object) (Object, error) {
+func (o Str) BinaryOp(vm *VM, tok token.Token, right Object) (Object, error) {
 	switch v := right.(type) {
 	case Str:
 		switch tok {
 		case token.Add:
-			return o + v, nil
+			return Str(vm.concatStr(string(o), string(v))), nil
 		case token.Less:
 			return Bool(o < v), nil
 		case token.LessEq:
@@ -434,7 +434,7 @@ func (o Str) BinaryOp(_ *VM, tok token.Token, right Object) (Object, error) {
 	}
 
 	if tok == token.Add {
-		return o + Str(right.ToString()), nil
+		return Str(vm.concatStr(string(o), right.ToString())), nil
 	}
 
 	return nil, NewOperandTypeError(
@@ -537,6 +537,10 @@ func (o Bytes) Equal(right Object) bool {
 	if v, ok := right.(Str); ok {
 		return string(o) == string(v)
 	}
+
+	if v, ok := right.(*HostBytes); ok {
+		return v.Equal(o)
+	}
 	return false
 }
 
@@ -795,7 +799,12 @@ func (o Array) Copy() Object {
 
 // DeepCopy implements DeepCopier interface.
 func (o Array) DeepCopy(vm *VM) (_ Object, err error) {
+	defer vm.beginDeepCopy()()
+	if cp, ok := vm.deepCopyOf(o.identity()); ok {
+		return cp, nil
+	}
 	cp := make(Array, len(o))
+	vm.addDeepCopy(o.identity(), cp)
 	for i, v := range o {
 		if v, err = DeepCopy(vm, v); err != nil {
 			return
@@ -855,6 +864,9 @@ func (o Array) IndexGet(_ *VM, index Object) (Object, error) {
 func (o Array) Equal(right Object) bool {
 	v, ok := right.(Array)
 	if !ok {
+		if view, _ := right.(*ArrayView); view != nil {
+			return o.Equal(view.arr)
+		}
 		return false
 	}
 
@@ -1194,7 +1206,12 @@ func (o Dict) Copy() Object {
 
 // DeepCopy implements DeepCopier interface.
 func (o Dict) DeepCopy(vm *VM) (_ Object, err error) {
+	defer vm.beginDeepCopy()()
+	if cp, ok := vm.deepCopyOf(o.identity()); ok {
+		return cp, nil
+	}
 	cp := make(Dict, len(o))
+	vm.addDeepCopy(o.identity(), cp)
 	for k, v := range o {
 		if cp[k], err = DeepCopy(vm, v); err != nil {
 			return
@@ -1205,7 +1222,7 @@ func (o Dict) DeepCopy(vm *VM) (_ Object, err error) {
 
 // IndexSet implements Object interface.
 func (o Dict) IndexSet(_ *VM, index, value Object) error {
-	o[index.ToString()] = value
+	o[o.internKey(index.ToString())] = value
 	return nil
 }
 
@@ -1257,7 +1274,7 @@ func (o Dict) BinaryOp(vm *VM, tok token.Token, right Object) (_ Object, err err
 		switch tok {
 		case token.Add:
 			err = IterateObject(vm, right, &NamedArgs{}, nil, func(e *KeyValue) error {
-				o[e.K.ToString()] = e.V
+				o[o.internKey(e.K.ToString())] = e.V
 				return nil
 			})
 			return o, err
@@ -1430,16 +1447,21 @@ func (o *SyncDict) Copy() Object {
 
 // DeepCopy implements DeepCopier interface.
 func (o *SyncDict) DeepCopy(vm *VM) (v Object, err error) {
+	defer vm.beginDeepCopy()()
+	if v, ok := vm.deepCopyOf(o); ok {
+		return v, nil
+	}
+	cp := &SyncDict{}
+	vm.addDeepCopy(o, cp)
+
 	o.mu.RLock()
 	defer o.mu.RUnlock()
 
 	if v, err = o.Value.DeepCopy(vm); err != nil {
 		return
 	}
-
-	return &SyncDict{
-		Value: v.(Dict),
-	}, nil
+	cp.Value = v.(Dict)
+	return cp, nil
 }
 
 // IndexSet implements Object interface.
@@ -1601,6 +1623,14 @@ func (o *Error) IndexGet(_ *VM, index Object) (Object, error) {
 		return Str(o.Message), nil
 	}
 
+	if s == "GoStack" {
+		var p *GoPanic
+		if errors.As(o, &p) {
+			return Str(p.Stack), nil
+		}
+		return Nil, nil
+	}
+
 	if s == "New" {
 		return &Function{
 			Name: "New",
@@ -1637,6 +1667,9 @@ type RuntimeError struct {
 	Err     *Error
 	fileSet *parser.SourceFileSet
 	Trace   []source.Pos
+	// Panic reports whether error is raised by VM or a function due to a
+	// programming error instead of a throw statement.
+	Panic bool
 }
 
 var (
@@ -1679,6 +1712,7 @@ func (o *RuntimeError) Copy() Object {
 		Err:     err,
 		fileSet: o.fileSet,
 		Trace:   append([]source.Pos{}, o.Trace...),
+		Panic:   o.Panic,
 	}
 }
 
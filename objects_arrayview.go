@@ -0,0 +1,169 @@
+package gad
+
+import (
+	"fmt"
+
+	"github.com/gad-lang/gad/token"
+)
+
+// ArrayView is a copy-on-write view of a part of an Array. Views share the
+// storage of the viewed array, so slicing large arrays does not copy them.
+// The first write to a view copies the viewed part which is owned by the view
+// after that. Writes to the viewed array are visible in the views until they
+// are written. Appending to a view returns a new Array.
+type ArrayView struct {
+	arr   Array
+	owned bool
+}
+
+var (
+	_ Object                = (*ArrayView)(nil)
+	_ Copier                = (*ArrayView)(nil)
+	_ DeepCopier            = (*ArrayView)(nil)
+	_ IndexGetSetter        = (*ArrayView)(nil)
+	_ Slicer                = (*ArrayView)(nil)
+	_ Iterabler             = (*ArrayView)(nil)
+	_ KeysGetter            = (*ArrayView)(nil)
+	_ ValuesGetter          = (*ArrayView)(nil)
+	_ ItemsGetter           = (*ArrayView)(nil)
+	_ Sorter                = (*ArrayView)(nil)
+	_ ReverseSorter         = (*ArrayView)(nil)
+	_ Appender              = (*ArrayView)(nil)
+	_ ToArrayAppenderObject = (*ArrayView)(nil)
+	_ ToIterfaceVMConverter = (*ArrayView)(nil)
+)
+
+// NewArrayView returns a view of arr[low:high]. It panics if the indexes are
+// out of range like slicing.
+func NewArrayView(arr Array, low, high int) *ArrayView {
+	return &ArrayView{arr: arr[low:high:high]}
+}
+
+// own copies the viewed part if it is shared.
+func (o *ArrayView) own() {
+	if !o.owned {
+		o.arr = o.arr.Copy().(Array)
+		o.owned = true
+	}
+}
+
+// share returns the viewed part which must not be modified. o is copied on the
+// next write.
+func (o *ArrayView) share() Array {
+	o.owned = false
+	return o.arr
+}
+
+func (o *ArrayView) Type() ObjectType {
+	return TArrayView
+}
+
+func (o *ArrayView) Format(f fmt.State, verb rune) {
+	o.arr.Format(f, verb)
+}
+
+func (o *ArrayView) ToString() string {
+	return o.arr.ToString()
+}
+
+func (o *ArrayView) Repr(vm *VM) (string, error) {
+	return ArrayRepr(o.Type().Name(), vm, len(o.arr), func(i int) Object {
+		return o.arr[i]
+	})
+}
+
+func (o *ArrayView) ToInterface(vm *VM) any {
+	return o.arr.ToAnyArray(vm)
+}
+
+// Copy implements Copier interface. Copy shares the viewed part until one of
+// them is written.
+func (o *ArrayView) Copy() Object {
+	return &ArrayView{arr: o.share()}
+}
+
+// DeepCopy implements DeepCopier interface.
+func (o *ArrayView) DeepCopy(vm *VM) (Object, error) {
+	return o.arr.DeepCopy(vm)
+}
+
+// IndexGet implements Object interface.
+func (o *ArrayView) IndexGet(vm *VM, index Object) (Object, error) {
+	return o.arr.IndexGet(vm, index)
+}
+
+// IndexSet implements Object interface.
+func (o *ArrayView) IndexSet(vm *VM, index, value Object) error {
+	if _, err := o.arr.IndexGet(vm, index); err != nil {
+		return err
+	}
+	o.own()
+	return o.arr.IndexSet(vm, index, value)
+}
+
+// Equal implements Object interface.
+func (o *ArrayView) Equal(right Object) bool {
+	if v, ok := right.(*ArrayView); ok {
+		return o.arr.Equal(v.arr)
+	}
+	return o.arr.Equal(right)
+}
+
+// IsFalsy implements Object interface.
+func (o *ArrayView) IsFalsy() bool {
+	return len(o.arr) == 0
+}
+
+// BinaryOp implements Object interface.
+func (o *ArrayView) BinaryOp(vm *VM, tok token.Token, right Object) (Object, error) {
+	return o.arr.BinaryOp(vm, tok, right)
+}
+
+// Length implements LengthGetter interface.
+func (o *ArrayView) Length() int {
+	return len(o.arr)
+}
+
+// Slice implements Slicer interface. The returned view shares the viewed part
+// until one of them is written.
+func (o *ArrayView) Slice(low, high int) Object {
+	return NewArrayView(o.share(), low, high)
+}
+
+func (o *ArrayView) Iterate(vm *VM, na *NamedArgs) Iterator {
+	return o.arr.Iterate(vm, na)
+}
+
+func (o *ArrayView) Keys() Array {
+	return o.arr.Keys()
+}
+
+// Values returns a copy of the viewed part.
+func (o *ArrayView) Values() Array {
+	return o.arr.Copy().(Array)
+}
+
+func (o *ArrayView) Items(vm *VM) (KeyValueArray, error) {
+	return o.arr.Items(vm)
+}
+
+func (o *ArrayView) Sort(vm *VM, less CallerObject) (_ Object, err error) {
+	o.own()
+	_, err = o.arr.Sort(vm, less)
+	return o, err
+}
+
+func (o *ArrayView) SortReverse(vm *VM) (_ Object, err error) {
+	o.own()
+	_, err = o.arr.SortReverse(vm)
+	return o, err
+}
+
+// Append implements Appender interface. It returns a new Array.
+func (o *ArrayView) Append(vm *VM, items ...Object) (Object, error) {
+	return o.arr.Append(vm, items...)
+}
+
+func (o *ArrayView) AppendToArray(arr *Array) {
+	*arr = append(*arr, o.arr...)
+}
@@ -0,0 +1,156 @@
+package gad
+
+import (
+	"sync/atomic"
+
+	"github.com/gad-lang/gad/token"
+)
+
+// COWDict is a copy-on-write Dict container which can be used as globals of
+// VMs. Reads are lock-free. Writes copy the current Dict and atomically
+// replace it, so Dicts returned by Load are never modified. Host can swap in a
+// new configuration with Store between script invocations and running pools of
+// VMs see it without restart.
+type COWDict struct {
+	p atomic.Pointer[Dict]
+}
+
+var (
+	_ Object       = (*COWDict)(nil)
+	_ Copier       = (*COWDict)(nil)
+	_ IndexDeleter = (*COWDict)(nil)
+	_ LengthGetter = (*COWDict)(nil)
+	_ KeysGetter   = (*COWDict)(nil)
+	_ ValuesGetter = (*COWDict)(nil)
+	_ ItemsGetter  = (*COWDict)(nil)
+)
+
+// NewCOWDict creates a new COWDict holding d. d must not be modified after
+// this call.
+func NewCOWDict(d Dict) *COWDict {
+	o := &COWDict{}
+	o.Store(d)
+	return o
+}
+
+// Load returns the current Dict which must not be modified.
+func (o *COWDict) Load() Dict {
+	if p := o.p.Load(); p != nil {
+		return *p
+	}
+	return nil
+}
+
+// Store atomically replaces the current Dict with d. d must not be modified
+// after this call.
+func (o *COWDict) Store(d Dict) {
+	o.p.Store(&d)
+}
+
+// Swap atomically replaces the current Dict with d and returns the old one.
+func (o *COWDict) Swap(d Dict) Dict {
+	if p := o.p.Swap(&d); p != nil {
+		return *p
+	}
+	return nil
+}
+
+// Update atomically replaces the current Dict with the copy modified by fn.
+// fn may be called more than once if another write happens concurrently.
+func (o *COWDict) Update(fn func(d Dict) error) error {
+	for {
+		old := o.p.Load()
+		var d Dict
+		if old != nil {
+			d = make(Dict, len(*old)+1)
+			for k, v := range *old {
+				d[k] = v
+			}
+		} else {
+			d = Dict{}
+		}
+		if err := fn(d); err != nil {
+			return err
+		}
+		if o.p.CompareAndSwap(old, &d) {
+			return nil
+		}
+	}
+}
+
+func (o *COWDict) Type() ObjectType {
+	return TCOWDict
+}
+
+func (o *COWDict) ToString() string {
+	return o.Load().ToString()
+}
+
+// Copy implements Copier interface. Copy shares the current Dict until one of
+// them is written.
+func (o *COWDict) Copy() Object {
+	return NewCOWDict(o.Load())
+}
+
+// IndexGet implements Object interface.
+func (o *COWDict) IndexGet(vm *VM, index Object) (Object, error) {
+	return o.Load().IndexGet(vm, index)
+}
+
+// IndexSet implements Object interface.
+func (o *COWDict) IndexSet(vm *VM, index, value Object) error {
+	return o.Update(func(d Dict) error {
+		return d.IndexSet(vm, index, value)
+	})
+}
+
+// IndexDelete implements IndexDeleter interface.
+func (o *COWDict) IndexDelete(vm *VM, key Object) error {
+	if _, ok := o.Load()[key.ToString()]; !ok {
+		return nil
+	}
+	return o.Update(func(d Dict) error {
+		return d.IndexDelete(vm, key)
+	})
+}
+
+// Equal implements Object interface.
+func (o *COWDict) Equal(right Object) bool {
+	if v, ok := right.(*COWDict); ok {
+		return o.Load().Equal(v.Load())
+	}
+	return o.Load().Equal(right)
+}
+
+// IsFalsy implements Object interface.
+func (o *COWDict) IsFalsy() bool {
+	return len(o.Load()) == 0
+}
+
+// BinaryOp implements Object interface.
+func (o *COWDict) BinaryOp(vm *VM, tok token.Token, right Object) (Object, error) {
+	return o.Load().BinaryOp(vm, tok, right)
+}
+
+// Get returns Object in map if exists.
+func (o *COWDict) Get(index string) (value Object, exists bool) {
+	value, exists = o.Load()[index]
+	return
+}
+
+// Length returns the number of items in the dict.
+func (o *COWDict) Length() int {
+	return len(o.Load())
+}
+
+func (o *COWDict) Items(vm *VM) (KeyValueArray, error) {
+	return o.Load().Items(vm)
+}
+
+func (o *COWDict) Keys() Array {
+	return o.Load().Keys()
+}
+
+func (o *COWDict) Values() Array {
+	return o.Load().Values()
+}
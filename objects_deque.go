@@ -0,0 +1,265 @@
+package gad
+
+import (
+	"fmt"
+)
+
+// Deque is a double-ended queue in a ring buffer. Pushing and popping at both
+// ends and indexing are O(1). If maxLen is positive, pushing to a full deque
+// drops the element at the other end, so it is a sliding window.
+type Deque struct {
+	buf    Array
+	head   int
+	n      int
+	maxLen int
+}
+
+var (
+	_ Object                = (*Deque)(nil)
+	_ Copier                = (*Deque)(nil)
+	_ IndexGetSetter        = (*Deque)(nil)
+	_ LengthGetter          = (*Deque)(nil)
+	_ Iterabler             = (*Deque)(nil)
+	_ ValuesGetter          = (*Deque)(nil)
+	_ NameCallerObject      = (*Deque)(nil)
+	_ ToArrayAppenderObject = (*Deque)(nil)
+	_ ToIterfaceVMConverter = (*Deque)(nil)
+)
+
+// NewDeque returns a new Deque of the values. If maxLen is positive, only the
+// last maxLen values are kept.
+func NewDeque(maxLen int, values ...Object) *Deque {
+	d := &Deque{maxLen: maxLen}
+	d.PushBack(values...)
+	return d
+}
+
+func (o *Deque) Type() ObjectType {
+	return TDeque
+}
+
+func (o *Deque) ToString() string {
+	return o.Values().ToString()
+}
+
+func (o *Deque) Format(f fmt.State, verb rune) {
+	o.Values().Format(f, verb)
+}
+
+func (o *Deque) Repr(vm *VM) (string, error) {
+	return ArrayRepr(o.Type().Name(), vm, o.n, o.at)
+}
+
+func (o *Deque) ToInterface(vm *VM) any {
+	return o.Values().ToAnyArray(vm)
+}
+
+// Copy implements Copier interface.
+func (o *Deque) Copy() Object {
+	return NewDeque(o.maxLen, o.Values()...)
+}
+
+// Equal implements Object interface.
+func (o *Deque) Equal(right Object) bool {
+	if v, ok := right.(*Deque); ok {
+		return o.Values().Equal(v.Values())
+	}
+	return false
+}
+
+// IsFalsy implements Object interface.
+func (o *Deque) IsFalsy() bool {
+	return o.n == 0
+}
+
+// Length implements LengthGetter interface.
+func (o *Deque) Length() int {
+	return o.n
+}
+
+func (o *Deque) Iterate(vm *VM, na *NamedArgs) Iterator {
+	return o.Values().Iterate(vm, na)
+}
+
+// Values returns the elements from the front to the back.
+func (o *Deque) Values() Array {
+	arr := make(Array, o.n)
+	for i := range arr {
+		arr[i] = o.at(i)
+	}
+	return arr
+}
+
+func (o *Deque) AppendToArray(arr *Array) {
+	*arr = append(*arr, o.Values()...)
+}
+
+func (o *Deque) at(i int) Object {
+	return o.buf[(o.head+i)%len(o.buf)]
+}
+
+func (o *Deque) index(index Object) (int, error) {
+	i, ok := index.(Int)
+	if !ok {
+		return 0, NewIndexTypeError("int", index.Type().Name())
+	}
+	n := int(i)
+	if n < 0 {
+		n += o.n
+	}
+	if n < 0 || n >= o.n {
+		return 0, ErrIndexOutOfBounds.NewError(index.ToString())
+	}
+	return n, nil
+}
+
+// IndexGet implements IndexGetter interface. Negative indexes are relative
+// to the back.
+func (o *Deque) IndexGet(_ *VM, index Object) (Object, error) {
+	i, err := o.index(index)
+	if err != nil {
+		return nil, err
+	}
+	return o.at(i), nil
+}
+
+// IndexSet implements IndexSetter interface.
+func (o *Deque) IndexSet(_ *VM, index, value Object) error {
+	i, err := o.index(index)
+	if err != nil {
+		return err
+	}
+	o.buf[(o.head+i)%len(o.buf)] = value
+	return nil
+}
+
+// grow doubles the buffer if it is full.
+func (o *Deque) grow() {
+	if o.n < len(o.buf) {
+		return
+	}
+	buf := make(Array, max(2*len(o.buf), 8))
+	for i := 0; i < o.n; i++ {
+		buf[i] = o.at(i)
+	}
+	o.buf, o.head = buf, 0
+}
+
+// PushBack pushes the values to the back.
+func (o *Deque) PushBack(values ...Object) {
+	for _, v := range values {
+		if o.maxLen > 0 && o.n == o.maxLen {
+			o.PopFront()
+		}
+		o.grow()
+		o.buf[(o.head+o.n)%len(o.buf)] = v
+		o.n++
+	}
+}
+
+// PushFront pushes the values to the front, so the last value is the first
+// element.
+func (o *Deque) PushFront(values ...Object) {
+	for _, v := range values {
+		if o.maxLen > 0 && o.n == o.maxLen {
+			o.PopBack()
+		}
+		o.grow()
+		o.head = (o.head - 1 + len(o.buf)) % len(o.buf)
+		o.buf[o.head] = v
+		o.n++
+	}
+}
+
+// PopBack removes the last element and returns it, or nil if o is empty.
+func (o *Deque) PopBack() Object {
+	if o.n == 0 {
+		return nil
+	}
+	i := (o.head + o.n - 1) % len(o.buf)
+	v := o.buf[i]
+	o.buf[i] = nil
+	o.n--
+	return v
+}
+
+// PopFront removes the first element and returns it, or nil if o is empty.
+func (o *Deque) PopFront() Object {
+	if o.n == 0 {
+		return nil
+	}
+	v := o.buf[o.head]
+	o.buf[o.head] = nil
+	o.head = (o.head + 1) % len(o.buf)
+	o.n--
+	return v
+}
+
+// CallName implements NameCallerObject interface.
+func (o *Deque) CallName(name string, c Call) (_ Object, err error) {
+	switch name {
+	case "push", "pushLeft":
+		if err = c.Args.CheckMinLen(1); err != nil {
+			return
+		}
+		if name == "push" {
+			o.PushBack(c.Args.Values()...)
+		} else {
+			o.PushFront(c.Args.Values()...)
+		}
+		return Nil, nil
+	case "pop", "popLeft", "peek", "peekLeft":
+		if err = c.Args.CheckLen(0); err != nil {
+			return
+		}
+		if o.n == 0 {
+			return nil, ErrIndexOutOfBounds.NewError(name + " from empty deque")
+		}
+		switch name {
+		case "pop":
+			return o.PopBack(), nil
+		case "popLeft":
+			return o.PopFront(), nil
+		case "peek":
+			return o.at(o.n - 1), nil
+		}
+		return o.at(0), nil
+	case "clear":
+		if err = c.Args.CheckLen(0); err != nil {
+			return
+		}
+		o.buf, o.head, o.n = nil, 0, 0
+		return Nil, nil
+	}
+	return nil, ErrInvalidIndex.NewError(name)
+}
+
+// BuiltinDequeFunc implements `deque(iterable=nil; maxLen=0)` which returns a
+// new deque of the values of iterable. If maxLen is positive, pushing to a
+// full deque drops the element at the other end.
+func BuiltinDequeFunc(c Call) (_ Object, err error) {
+	if err = c.Args.CheckRangeLen(0, 1); err != nil {
+		return
+	}
+	maxLen := &NamedArgVar{
+		Name:          "maxLen",
+		Value:         Int(0),
+		TypeAssertion: TypeAssertionFromTypes(TInt),
+	}
+	if err = c.NamedArgs.Get(maxLen); err != nil {
+		return
+	}
+	d := NewDeque(int(maxLen.Value.(Int)))
+	if c.Args.Length() == 1 && c.Args.GetOnly(0) != Nil {
+		var v Object
+		if v, err = Val(c.VM.Builtins.Call(BuiltinCollect, Call{VM: c.VM, Args: Args{Array{c.Args.GetOnly(0)}}})); err != nil {
+			return
+		}
+		arr, ok := v.(Array)
+		if !ok {
+			return nil, NewArgumentTypeError("1st", "iterable", c.Args.GetOnly(0).Type().Name())
+		}
+		d.PushBack(arr...)
+	}
+	return d, nil
+}
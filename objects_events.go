@@ -0,0 +1,231 @@
+package gad
+
+import (
+	"sort"
+	"sync"
+)
+
+// EventEmitter calls the functions registered for an event name when the
+// event is emitted. Functions are called synchronously in registration order
+// unless async delivery is requested. It is safe to use concurrently.
+type EventEmitter struct {
+	mu       sync.RWMutex
+	handlers map[string][]*eventHandler
+}
+
+type eventHandler struct {
+	fn   Object
+	once bool
+}
+
+var (
+	_ Object           = (*EventEmitter)(nil)
+	_ NameCallerObject = (*EventEmitter)(nil)
+)
+
+// NewEventEmitter creates a new EventEmitter.
+func NewEventEmitter() *EventEmitter {
+	return &EventEmitter{handlers: make(map[string][]*eventHandler)}
+}
+
+func (e *EventEmitter) Type() ObjectType {
+	return TEventEmitter
+}
+
+func (e *EventEmitter) ToString() string {
+	return ReprQuote("events")
+}
+
+func (e *EventEmitter) IsFalsy() bool {
+	return false
+}
+
+func (e *EventEmitter) Equal(right Object) bool {
+	v, ok := right.(*EventEmitter)
+	return ok && v == e
+}
+
+// On registers fn for the event. If once is true, fn is removed before it is
+// called first time.
+func (e *EventEmitter) On(name string, fn Object, once bool) {
+	e.mu.Lock()
+	e.handlers[name] = append(e.handlers[name], &eventHandler{fn: fn, once: once})
+	e.mu.Unlock()
+}
+
+// Off removes fn from the event and returns the number of removed handlers.
+// If fn is nil, all handlers of the event are removed.
+func (e *EventEmitter) Off(name string, fn Object) (n int) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if fn == nil {
+		n = len(e.handlers[name])
+		delete(e.handlers, name)
+		return
+	}
+
+	handlers := e.handlers[name][:0]
+	for _, h := range e.handlers[name] {
+		if h.fn == fn || h.fn.Equal(fn) {
+			n++
+			continue
+		}
+		handlers = append(handlers, h)
+	}
+
+	if len(handlers) == 0 {
+		delete(e.handlers, name)
+	} else {
+		e.handlers[name] = handlers
+	}
+	return
+}
+
+// Listeners returns the functions registered for the event.
+func (e *EventEmitter) Listeners(name string) Array {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	ret := make(Array, len(e.handlers[name]))
+	for i, h := range e.handlers[name] {
+		ret[i] = h.fn
+	}
+	return ret
+}
+
+// Names returns the sorted names of events having handlers.
+func (e *EventEmitter) Names() Array {
+	e.mu.RLock()
+	names := make([]string, 0, len(e.handlers))
+	for name := range e.handlers {
+		names = append(names, name)
+	}
+	e.mu.RUnlock()
+
+	sort.Strings(names)
+	ret := make(Array, len(names))
+	for i, name := range names {
+		ret[i] = Str(name)
+	}
+	return ret
+}
+
+// take returns the handlers of the event to be called and removes the ones
+// registered with once.
+func (e *EventEmitter) take(name string) []*eventHandler {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	handlers := append([]*eventHandler{}, e.handlers[name]...)
+	kept := e.handlers[name][:0]
+	for _, h := range e.handlers[name] {
+		if !h.once {
+			kept = append(kept, h)
+		}
+	}
+	if len(kept) == 0 {
+		delete(e.handlers, name)
+	} else {
+		e.handlers[name] = kept
+	}
+	return handlers
+}
+
+// Emit calls the handlers of the event with given arguments and returns the
+// number of called handlers. The first error stops the delivery.
+func (e *EventEmitter) Emit(vm *VM, name string, args Args, namedArgs *NamedArgs) (int, error) {
+	handlers := e.take(name)
+	for _, h := range handlers {
+		if _, err := NewInvoker(vm, h.fn).Invoke(args, namedArgs.Copy().(*NamedArgs)); err != nil {
+			return 0, err
+		}
+	}
+	return len(handlers), nil
+}
+
+// EmitAsync calls every handler of the event in its own goroutine and returns
+// the TaskGroup of the calls.
+func (e *EventEmitter) EmitAsync(vm *VM, name string, args Array, namedArgs *NamedArgs) (*TaskGroup, error) {
+	g := NewTaskGroup(vm, 0)
+	for _, h := range e.take(name) {
+		if err := g.Go(h.fn, args, namedArgs.Copy().(*NamedArgs)); err != nil {
+			return nil, err
+		}
+	}
+	return g, nil
+}
+
+func eventName(pos string, o Object) (string, error) {
+	switch o.(type) {
+	case Str, RawStr:
+		return o.ToString(), nil
+	}
+	return "", NewArgumentTypeError(pos, "str", o.Type().Name())
+}
+
+func (e *EventEmitter) CallName(name string, c Call) (_ Object, err error) {
+	var event string
+
+	switch name {
+	case "on", "once":
+		if err = c.Args.CheckLen(2); err != nil {
+			return
+		}
+		if event, err = eventName("1st", c.Args.GetOnly(0)); err != nil {
+			return
+		}
+		fn := c.Args.GetOnly(1)
+		if !Callable(fn) {
+			return nil, NewArgumentTypeError("2nd", "callable", fn.Type().Name())
+		}
+		e.On(event, fn, name == "once")
+		return Nil, nil
+	case "off":
+		if err = c.Args.CheckRangeLen(1, 2); err != nil {
+			return
+		}
+		if event, err = eventName("1st", c.Args.GetOnly(0)); err != nil {
+			return
+		}
+		var fn Object
+		if c.Args.Length() == 2 {
+			fn = c.Args.GetOnly(1)
+		}
+		return Int(e.Off(event, fn)), nil
+	case "emit":
+		if err = c.Args.CheckMinLen(1); err != nil {
+			return
+		}
+		if event, err = eventName("1st", c.Args.GetOnly(0)); err != nil {
+			return
+		}
+		var (
+			async     = !c.NamedArgs.GetValue("async").IsFalsy()
+			args      = append(Array{}, c.Args.Values()[1:]...)
+			namedArgs = NewNamedArgs(c.NamedArgs.UnreadPairs())
+		)
+		if async {
+			return e.EmitAsync(c.VM, event, args, namedArgs)
+		}
+		var n int
+		if n, err = e.Emit(c.VM, event, Args{args}, namedArgs); err != nil {
+			return
+		}
+		return Int(n), nil
+	case "listeners":
+		if err = c.Args.CheckLen(1); err != nil {
+			return
+		}
+		if event, err = eventName("1st", c.Args.GetOnly(0)); err != nil {
+			return
+		}
+		return e.Listeners(event), nil
+	case "names":
+		if err = c.Args.CheckLen(0); err != nil {
+			return
+		}
+		return e.Names(), nil
+	}
+	return nil, ErrInvalidIndex.NewError(name)
+}
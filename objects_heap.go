@@ -0,0 +1,216 @@
+package gad
+
+import (
+	"container/heap"
+	"fmt"
+)
+
+// Heap is a priority queue whose first element is the least one by the
+// options of sort, or the greatest one if it is a max heap. Pushing and
+// popping are O(log n).
+type Heap struct {
+	items Array
+	// keys are the values of the key function of items
+	keys Array
+	opts sortOptions
+}
+
+var (
+	_ Object                = (*Heap)(nil)
+	_ Copier                = (*Heap)(nil)
+	_ LengthGetter          = (*Heap)(nil)
+	_ Iterabler             = (*Heap)(nil)
+	_ ValuesGetter          = (*Heap)(nil)
+	_ NameCallerObject      = (*Heap)(nil)
+	_ ToIterfaceVMConverter = (*Heap)(nil)
+)
+
+func (o *Heap) Type() ObjectType {
+	return THeap
+}
+
+func (o *Heap) ToString() string {
+	return o.items.ToString()
+}
+
+func (o *Heap) Repr(vm *VM) (string, error) {
+	return ArrayRepr(o.Type().Name(), vm, len(o.items), func(i int) Object {
+		return o.items[i]
+	})
+}
+
+func (o *Heap) ToInterface(vm *VM) any {
+	return o.items.ToAnyArray(vm)
+}
+
+// Copy implements Copier interface.
+func (o *Heap) Copy() Object {
+	return &Heap{items: o.items.Copy().(Array), keys: o.keys.Copy().(Array), opts: o.opts}
+}
+
+// Equal implements Object interface.
+func (o *Heap) Equal(right Object) bool {
+	return o == right
+}
+
+// IsFalsy implements Object interface.
+func (o *Heap) IsFalsy() bool {
+	return len(o.items) == 0
+}
+
+// Length implements LengthGetter interface.
+func (o *Heap) Length() int {
+	return len(o.items)
+}
+
+// Iterate iterates the elements in the order of the heap which is not sorted.
+func (o *Heap) Iterate(vm *VM, na *NamedArgs) Iterator {
+	return o.Values().Iterate(vm, na)
+}
+
+// Values returns a copy of the elements in the order of the heap.
+func (o *Heap) Values() Array {
+	return o.items.Copy().(Array)
+}
+
+// heapItem is an element of Heap and the value of its key.
+type heapItem struct {
+	item, key Object
+}
+
+// heapInterface implements heap.Interface for the elements of Heap.
+type heapInterface struct {
+	*Heap
+	less func(a, b Object) bool
+}
+
+func (h heapInterface) Len() int {
+	return len(h.items)
+}
+
+func (h heapInterface) Less(i, j int) bool {
+	return h.less(h.keys[i], h.keys[j])
+}
+
+func (h heapInterface) Swap(i, j int) {
+	h.items[i], h.items[j] = h.items[j], h.items[i]
+	h.keys[i], h.keys[j] = h.keys[j], h.keys[i]
+}
+
+func (h heapInterface) Push(x any) {
+	v := x.(heapItem)
+	h.items = append(h.items, v.item)
+	h.keys = append(h.keys, v.key)
+}
+
+func (h heapInterface) Pop() any {
+	n := len(h.items) - 1
+	v := h.items[n]
+	h.items[n], h.keys[n] = nil, nil
+	h.items, h.keys = h.items[:n], h.keys[:n]
+	return v
+}
+
+// Push pushes the values.
+func (o *Heap) Push(vm *VM, values ...Object) (err error) {
+	var (
+		key     func(Object) (Object, error)
+		k       Object
+		callErr error
+		h       = heapInterface{Heap: o}
+	)
+	if key, err = o.opts.keyFunc(vm); err != nil {
+		return
+	}
+	if h.less, err = o.opts.lessFunc(vm, &callErr); err != nil {
+		return
+	}
+	for _, v := range values {
+		if k, err = key(v); err != nil {
+			return
+		}
+		heap.Push(h, heapItem{item: v, key: k})
+		if callErr != nil {
+			return callErr
+		}
+	}
+	return
+}
+
+// Pop removes the first element and returns it.
+func (o *Heap) Pop(vm *VM) (_ Object, err error) {
+	if len(o.items) == 0 {
+		return nil, ErrIndexOutOfBounds.NewError("pop from empty heap")
+	}
+	var (
+		callErr error
+		h       = heapInterface{Heap: o}
+	)
+	if h.less, err = o.opts.lessFunc(vm, &callErr); err != nil {
+		return
+	}
+	v := heap.Pop(h).(Object)
+	return v, callErr
+}
+
+// CallName implements NameCallerObject interface.
+func (o *Heap) CallName(name string, c Call) (_ Object, err error) {
+	switch name {
+	case "push":
+		if err = c.Args.CheckMinLen(1); err != nil {
+			return
+		}
+		return Nil, o.Push(c.VM, c.Args.Values()...)
+	case "pop":
+		if err = c.Args.CheckLen(0); err != nil {
+			return
+		}
+		return o.Pop(c.VM)
+	case "peek":
+		if err = c.Args.CheckLen(0); err != nil {
+			return
+		}
+		if len(o.items) == 0 {
+			return nil, ErrIndexOutOfBounds.NewError("peek from empty heap")
+		}
+		return o.items[0], nil
+	}
+	return nil, ErrInvalidIndex.NewError(name)
+}
+
+// Format implements fmt.Formatter interface.
+func (o *Heap) Format(f fmt.State, verb rune) {
+	o.items.Format(f, verb)
+}
+
+// BuiltinHeapFunc implements `heap(iterable=nil; less, key, cmp, natural=false,
+// max=false)` which returns a new heap of the values of iterable. The first
+// element of the heap is the least one by the options of sort, or the
+// greatest one if max is true.
+func BuiltinHeapFunc(c Call) (_ Object, err error) {
+	if err = c.Args.CheckRangeLen(0, 1); err != nil {
+		return
+	}
+	var (
+		maxHeap = &NamedArgVar{Name: "max", Value: False}
+		o       = &Heap{}
+	)
+	if o.opts, err = parseSortOptions(&c.NamedArgs, false, maxHeap); err != nil {
+		return
+	}
+	o.opts.reverse = o.opts.reverse || !maxHeap.Value.IsFalsy()
+	if c.Args.Length() == 1 && c.Args.GetOnly(0) != Nil {
+		var v Object
+		if v, err = Val(c.VM.Builtins.Call(BuiltinCollect, Call{VM: c.VM, Args: Args{Array{c.Args.GetOnly(0)}}})); err != nil {
+			return
+		}
+		arr, ok := v.(Array)
+		if !ok {
+			return nil, NewArgumentTypeError("1st", "iterable", c.Args.GetOnly(0).Type().Name())
+		}
+		if err = o.Push(c.VM, arr...); err != nil {
+			return
+		}
+	}
+	return o, nil
+}
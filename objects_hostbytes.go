@@ -0,0 +1,209 @@
+package gad
+
+import (
+	"sync"
+
+	"github.com/gad-lang/gad/token"
+)
+
+// HostBytes is a view of a byte slice owned by the host which is exposed to
+// scripts without copying it. Values read from the view like slices, strings
+// and converted bytes are copies, so scripts cannot keep references to the
+// slice. After the view is invalidated, the slice is not accessed anymore and
+// using the view returns ErrViewInvalidated.
+type HostBytes struct {
+	b        []byte
+	readOnly bool
+	state    *hostBytesState
+}
+
+// hostBytesState is shared by a view and the views sliced from it.
+type hostBytesState struct {
+	mu          sync.RWMutex
+	invalidated bool
+}
+
+var (
+	_ Object         = (*HostBytes)(nil)
+	_ Copier         = (*HostBytes)(nil)
+	_ IndexGetSetter = (*HostBytes)(nil)
+	_ Slicer         = (*HostBytes)(nil)
+	_ Iterabler      = (*HostBytes)(nil)
+	_ BytesConverter = (*HostBytes)(nil)
+)
+
+// BytesView returns a view of b which is valid until the returned function is
+// called. If readOnly is true, scripts cannot modify b. The host must not
+// modify b while a script may access the view. Invalidation waits for the
+// accesses in progress, so b can be reused after it returns.
+func BytesView(b []byte, readOnly bool) (*HostBytes, func()) {
+	o := &HostBytes{
+		b:        b[:len(b):len(b)],
+		readOnly: readOnly,
+		state:    &hostBytesState{},
+	}
+	return o, o.Invalidate
+}
+
+// Invalidate invalidates the view and the views sliced from it.
+func (o *HostBytes) Invalidate() {
+	o.state.mu.Lock()
+	o.state.invalidated = true
+	o.b = nil
+	o.state.mu.Unlock()
+}
+
+// Valid reports whether the view is not invalidated.
+func (o *HostBytes) Valid() bool {
+	o.state.mu.RLock()
+	defer o.state.mu.RUnlock()
+	return !o.state.invalidated
+}
+
+// ReadOnly reports whether scripts cannot modify the viewed bytes.
+func (o *HostBytes) ReadOnly() bool {
+	return o.readOnly
+}
+
+// read calls fn with the viewed bytes which must not be retained by fn.
+func (o *HostBytes) read(fn func(b Bytes) error) error {
+	o.state.mu.RLock()
+	defer o.state.mu.RUnlock()
+	if o.state.invalidated {
+		return ErrViewInvalidated
+	}
+	return fn(o.b)
+}
+
+// copyBytes returns a copy of the viewed bytes.
+func (o *HostBytes) copyBytes() (cp Bytes, err error) {
+	err = o.read(func(b Bytes) error {
+		cp = make(Bytes, len(b))
+		copy(cp, b)
+		return nil
+	})
+	return
+}
+
+func (o *HostBytes) Type() ObjectType {
+	return TBytesView
+}
+
+func (o *HostBytes) ToString() string {
+	var s string
+	_ = o.read(func(b Bytes) error {
+		s = string(b)
+		return nil
+	})
+	return s
+}
+
+// ToBytes implements BytesConverter interface. It returns a copy of the viewed
+// bytes.
+func (o *HostBytes) ToBytes() (Bytes, error) {
+	return o.copyBytes()
+}
+
+// Copy implements Copier interface. It returns the copy of the viewed bytes
+// or nil if the view is invalidated.
+func (o *HostBytes) Copy() Object {
+	cp, err := o.copyBytes()
+	if err != nil {
+		return Nil
+	}
+	return cp
+}
+
+// IndexGet implements Object interface.
+func (o *HostBytes) IndexGet(vm *VM, index Object) (ret Object, err error) {
+	err = o.read(func(b Bytes) (err error) {
+		ret, err = b.IndexGet(vm, index)
+		return
+	})
+	return
+}
+
+// IndexSet implements Object interface.
+func (o *HostBytes) IndexSet(vm *VM, index, value Object) error {
+	if o.readOnly {
+		return ErrNotIndexAssignable.NewError("read-only " + o.Type().Name())
+	}
+	return o.read(func(b Bytes) error {
+		return b.IndexSet(vm, index, value)
+	})
+}
+
+// Equal implements Object interface.
+func (o *HostBytes) Equal(right Object) (eq bool) {
+	if v, ok := right.(*HostBytes); ok {
+		if right, ok = v.Copy().(Bytes); !ok {
+			return false
+		}
+	}
+	_ = o.read(func(b Bytes) error {
+		eq = b.Equal(right)
+		return nil
+	})
+	return
+}
+
+// IsFalsy implements Object interface.
+func (o *HostBytes) IsFalsy() bool {
+	return o.Length() == 0
+}
+
+// BinaryOp implements Object interface.
+func (o *HostBytes) BinaryOp(vm *VM, tok token.Token, right Object) (ret Object, err error) {
+	if v, ok := right.(*HostBytes); ok {
+		if right, err = v.copyBytes(); err != nil {
+			return
+		}
+	}
+	if tok == token.Add {
+		var cp Bytes
+		if cp, err = o.copyBytes(); err != nil {
+			return
+		}
+		return cp.BinaryOp(vm, tok, right)
+	}
+	err = o.read(func(b Bytes) (err error) {
+		ret, err = b.BinaryOp(vm, tok, right)
+		return
+	})
+	return
+}
+
+// Length implements LengthGetter interface. It returns 0 if the view is
+// invalidated.
+func (o *HostBytes) Length() (n int) {
+	_ = o.read(func(b Bytes) error {
+		n = len(b)
+		return nil
+	})
+	return
+}
+
+// Slice implements Slicer interface. The returned view shares the viewed bytes
+// and it is invalidated with o.
+func (o *HostBytes) Slice(low, high int) Object {
+	o.state.mu.RLock()
+	defer o.state.mu.RUnlock()
+	if o.state.invalidated {
+		return &HostBytes{readOnly: o.readOnly, state: o.state}
+	}
+	return &HostBytes{b: o.b[low:high:high], readOnly: o.readOnly, state: o.state}
+}
+
+// Iterate implements Iterabler interface.
+func (o *HostBytes) Iterate(_ *VM, na *NamedArgs) Iterator {
+	return NewRangeIteration(TBytesIterator, o, o.Length(), func(e *KeyValue, i int) error {
+		return o.read(func(b Bytes) error {
+			if i >= len(b) {
+				return ErrIndexOutOfBounds
+			}
+			e.K = Int(i)
+			e.V = Int(b[i])
+			return nil
+		})
+	}).ParseNamedArgs(na)
+}
@@ -0,0 +1,118 @@
+package gad
+
+import (
+	"sync"
+)
+
+// MockCallsFieldName is the attribute of Mock objects which returns the
+// recorded calls.
+const MockCallsFieldName = "__calls__"
+
+// MockCallName is the name of the calls recorded when a Mock is called as a
+// function.
+const MockCallName = "__call__"
+
+// Mock is a test double which accepts calls of any method, records them and
+// returns the programmed values. A call of a method whose programmed value is
+// callable returns the result of calling the value with the same arguments,
+// other methods return their value or nil. Calls are recorded as dicts with
+// name, args and namedArgs keys. It is safe to use concurrently.
+type Mock struct {
+	mu       sync.Mutex
+	returns  Dict
+	recorder CallerObject
+	calls    Array
+}
+
+var (
+	_ Object           = (*Mock)(nil)
+	_ CallerObject     = (*Mock)(nil)
+	_ NameCallerObject = (*Mock)(nil)
+	_ IndexGetter      = (*Mock)(nil)
+)
+
+// NewMock creates a new Mock returning the values of returns. If recorder is
+// not nil, it is called with every recorded call, e.g. to record the calls of
+// several mocks in order.
+func NewMock(returns Dict, recorder CallerObject) *Mock {
+	if returns == nil {
+		returns = Dict{}
+	}
+	return &Mock{returns: returns, recorder: recorder}
+}
+
+func (m *Mock) Type() ObjectType {
+	return TMock
+}
+
+func (m *Mock) ToString() string {
+	return ReprQuote("mock")
+}
+
+func (m *Mock) IsFalsy() bool {
+	return false
+}
+
+func (m *Mock) Equal(right Object) bool {
+	v, ok := right.(*Mock)
+	return ok && v == m
+}
+
+// Calls returns a copy of the recorded calls.
+func (m *Mock) Calls() Array {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append(Array{}, m.calls...)
+}
+
+// Call implements CallerObject interface. It records the call as
+// MockCallName method.
+func (m *Mock) Call(c Call) (Object, error) {
+	return m.CallName(MockCallName, c)
+}
+
+// CallName implements NameCallerObject interface.
+func (m *Mock) CallName(name string, c Call) (_ Object, err error) {
+	call := Dict{
+		"name":      Str(name),
+		"args":      append(Array{}, c.Args.Values()...),
+		"namedArgs": c.NamedArgs.Dict(),
+	}
+	if call["namedArgs"].(Dict) == nil {
+		call["namedArgs"] = Dict{}
+	}
+
+	m.mu.Lock()
+	m.calls = append(m.calls, call)
+	ret, ok := m.returns[name]
+	m.mu.Unlock()
+
+	if m.recorder != nil {
+		if _, err = DoCall(m.recorder, Call{VM: c.VM, Args: Args{Array{call}}}); err != nil {
+			return
+		}
+	}
+	if !ok {
+		return Nil, nil
+	}
+	if Callable(ret) {
+		return YieldCall(ret.(CallerObject), &c), nil
+	}
+	return ret, nil
+}
+
+// IndexGet implements IndexGetter interface. It returns the recorded calls for
+// MockCallsFieldName, otherwise the method with the name which records its
+// calls.
+func (m *Mock) IndexGet(_ *VM, index Object) (Object, error) {
+	name := index.ToString()
+	if name == MockCallsFieldName {
+		return m.Calls(), nil
+	}
+	return &Function{
+		Name: name,
+		Value: func(c Call) (Object, error) {
+			return m.CallName(name, c)
+		},
+	}, nil
+}
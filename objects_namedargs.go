@@ -7,6 +7,7 @@ import (
 	"strconv"
 	"strings"
 
+	"github.com/gad-lang/gad/internal/compat"
 	"github.com/gad-lang/gad/repr"
 	"github.com/gad-lang/gad/runehelper"
 	"github.com/gad-lang/gad/token"
@@ -151,6 +152,11 @@ func (o *KeyValue) Type() ObjectType {
 	return DetectTypeOf(o)
 }
 
+// Format implements fmt.Formatter interface.
+func (o *KeyValue) Format(s fmt.State, verb rune) {
+	fmt.Fprintf(s, compat.FmtFormatString(s, verb), o.ToString())
+}
+
 func (o *KeyValue) ToString() string {
 	var sb strings.Builder
 	switch t := o.K.(type) {
@@ -209,7 +215,7 @@ func (o *KeyValue) Repr(vm *VM) (_ string, err error) {
 
 // DeepCopy implements DeepCopier interface.
 func (o KeyValue) DeepCopy(vm *VM) (_ Object, err error) {
-	if o.V, err = DeepCopy(nil, o.V); err != nil {
+	if o.V, err = DeepCopy(vm, o.V); err != nil {
 		return
 	}
 	return &o, nil
@@ -363,6 +369,11 @@ func (o KeyValueArray) Dict() (ret Dict) {
 	return
 }
 
+// Format implements fmt.Formatter interface.
+func (o KeyValueArray) Format(s fmt.State, verb rune) {
+	fmt.Fprintf(s, compat.FmtFormatString(s, verb), o.ToString())
+}
+
 func (o KeyValueArray) ToString() string {
 	var sb strings.Builder
 	sb.WriteString("(;")
@@ -0,0 +1,656 @@
+package gad
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+
+	"github.com/gad-lang/gad/token"
+)
+
+// IntArray is a packed array of ints. Arithmetic operators are applied to the
+// elements in Go loops without boxing them, so numeric scripts run much faster
+// than with arrays of ints. The operands of the operators are packed arrays of
+// the same length or numbers, which are applied to each element like arrays of
+// length 1.
+type IntArray []Int
+
+// FloatArray is a packed array of floats like IntArray. Operations of an
+// IntArray and floats return FloatArrays.
+type FloatArray []Float
+
+var (
+	_ Object                = IntArray(nil)
+	_ Copier                = IntArray(nil)
+	_ IndexGetSetter        = IntArray(nil)
+	_ Slicer                = IntArray(nil)
+	_ Iterabler             = IntArray(nil)
+	_ ValuesGetter          = IntArray(nil)
+	_ Appender              = IntArray(nil)
+	_ ToIterfaceVMConverter = IntArray(nil)
+
+	_ Object                = FloatArray(nil)
+	_ Copier                = FloatArray(nil)
+	_ IndexGetSetter        = FloatArray(nil)
+	_ Slicer                = FloatArray(nil)
+	_ Iterabler             = FloatArray(nil)
+	_ ValuesGetter          = FloatArray(nil)
+	_ Appender              = FloatArray(nil)
+	_ ToIterfaceVMConverter = FloatArray(nil)
+)
+
+func (o IntArray) Type() ObjectType {
+	return TIntArray
+}
+
+func (o IntArray) ToString() string {
+	return ArrayToString(len(o), func(i int) Object {
+		return o[i]
+	})
+}
+
+func (o IntArray) Repr(vm *VM) (string, error) {
+	return ArrayRepr(o.Type().Name(), vm, len(o), func(i int) Object {
+		return o[i]
+	})
+}
+
+func (o IntArray) ToInterface(*VM) any {
+	v := make([]int64, len(o))
+	for i, e := range o {
+		v[i] = int64(e)
+	}
+	return v
+}
+
+// Copy implements Copier interface.
+func (o IntArray) Copy() Object {
+	cp := make(IntArray, len(o))
+	copy(cp, o)
+	return cp
+}
+
+// IndexGet implements Object interface. Selectors return the methods sum,
+// mean, min, max and toArray.
+func (o IntArray) IndexGet(_ *VM, index Object) (Object, error) {
+	if s, ok := index.(Str); ok {
+		return numArrayMethod(o, string(s))
+	}
+	i, err := numArrayIndex(index, len(o))
+	if err != nil {
+		return nil, err
+	}
+	return o[i], nil
+}
+
+// IndexSet implements Object interface.
+func (o IntArray) IndexSet(_ *VM, index, value Object) error {
+	i, err := numArrayIndex(index, len(o))
+	if err != nil {
+		return err
+	}
+	v, ok := toIntElem(value)
+	if !ok {
+		return NewIndexValueTypeError("int|uint|char|bool", value.Type().Name())
+	}
+	o[i] = v
+	return nil
+}
+
+// Equal implements Object interface.
+func (o IntArray) Equal(right Object) bool {
+	v, ok := right.(IntArray)
+	if !ok || len(o) != len(v) {
+		return false
+	}
+	for i := range o {
+		if o[i] != v[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// IsFalsy implements Object interface.
+func (o IntArray) IsFalsy() bool { return len(o) == 0 }
+
+// BinaryOp implements Object interface.
+func (o IntArray) BinaryOp(_ *VM, tok token.Token, right Object) (Object, error) {
+	return numArrayBinaryOp(tok, o, right)
+}
+
+// Length implements LengthGetter interface.
+func (o IntArray) Length() int {
+	return len(o)
+}
+
+// Slice implements Slicer interface. The slice shares the elements of o.
+func (o IntArray) Slice(low, high int) Object {
+	return o[low:high:high]
+}
+
+func (o IntArray) Iterate(_ *VM, na *NamedArgs) Iterator {
+	return SliceIteration(TArrayIterator, o, o, func(e *KeyValue, i Int, v Int) error {
+		e.K = i
+		e.V = v
+		return nil
+	}).ParseNamedArgs(na)
+}
+
+// Values implements ValuesGetter interface.
+func (o IntArray) Values() Array {
+	arr := make(Array, len(o))
+	for i, v := range o {
+		arr[i] = v
+	}
+	return arr
+}
+
+// Append implements Appender interface.
+func (o IntArray) Append(_ *VM, items ...Object) (Object, error) {
+	arr := make(IntArray, len(o), len(o)+len(items))
+	copy(arr, o)
+	for i, item := range items {
+		v, ok := toIntElem(item)
+		if !ok {
+			return nil, NewArgumentTypeError(strconv.Itoa(i+2), "int|uint|char|bool", item.Type().Name())
+		}
+		arr = append(arr, v)
+	}
+	return arr, nil
+}
+
+func (o FloatArray) Type() ObjectType {
+	return TFloatArray
+}
+
+func (o FloatArray) ToString() string {
+	return ArrayToString(len(o), func(i int) Object {
+		return o[i]
+	})
+}
+
+func (o FloatArray) Repr(vm *VM) (string, error) {
+	return ArrayRepr(o.Type().Name(), vm, len(o), func(i int) Object {
+		return o[i]
+	})
+}
+
+func (o FloatArray) ToInterface(*VM) any {
+	v := make([]float64, len(o))
+	for i, e := range o {
+		v[i] = float64(e)
+	}
+	return v
+}
+
+// Copy implements Copier interface.
+func (o FloatArray) Copy() Object {
+	cp := make(FloatArray, len(o))
+	copy(cp, o)
+	return cp
+}
+
+// IndexGet implements Object interface. Selectors return the methods sum,
+// mean, min, max and toArray.
+func (o FloatArray) IndexGet(_ *VM, index Object) (Object, error) {
+	if s, ok := index.(Str); ok {
+		return numArrayMethod(o, string(s))
+	}
+	i, err := numArrayIndex(index, len(o))
+	if err != nil {
+		return nil, err
+	}
+	return o[i], nil
+}
+
+// IndexSet implements Object interface.
+func (o FloatArray) IndexSet(_ *VM, index, value Object) error {
+	i, err := numArrayIndex(index, len(o))
+	if err != nil {
+		return err
+	}
+	v, ok := toFloatElem(value)
+	if !ok {
+		return NewIndexValueTypeError("float|int|uint", value.Type().Name())
+	}
+	o[i] = v
+	return nil
+}
+
+// Equal implements Object interface.
+func (o FloatArray) Equal(right Object) bool {
+	v, ok := right.(FloatArray)
+	if !ok || len(o) != len(v) {
+		return false
+	}
+	for i := range o {
+		if o[i] != v[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// IsFalsy implements Object interface.
+func (o FloatArray) IsFalsy() bool { return len(o) == 0 }
+
+// BinaryOp implements Object interface.
+func (o FloatArray) BinaryOp(_ *VM, tok token.Token, right Object) (Object, error) {
+	return numArrayBinaryOp(tok, o, right)
+}
+
+// Length implements LengthGetter interface.
+func (o FloatArray) Length() int {
+	return len(o)
+}
+
+// Slice implements Slicer interface. The slice shares the elements of o.
+func (o FloatArray) Slice(low, high int) Object {
+	return o[low:high:high]
+}
+
+func (o FloatArray) Iterate(_ *VM, na *NamedArgs) Iterator {
+	return SliceIteration(TArrayIterator, o, o, func(e *KeyValue, i Int, v Float) error {
+		e.K = i
+		e.V = v
+		return nil
+	}).ParseNamedArgs(na)
+}
+
+// Values implements ValuesGetter interface.
+func (o FloatArray) Values() Array {
+	arr := make(Array, len(o))
+	for i, v := range o {
+		arr[i] = v
+	}
+	return arr
+}
+
+// Append implements Appender interface.
+func (o FloatArray) Append(_ *VM, items ...Object) (Object, error) {
+	arr := make(FloatArray, len(o), len(o)+len(items))
+	copy(arr, o)
+	for i, item := range items {
+		v, ok := toFloatElem(item)
+		if !ok {
+			return nil, NewArgumentTypeError(strconv.Itoa(i+2), "float|int|uint", item.Type().Name())
+		}
+		arr = append(arr, v)
+	}
+	return arr, nil
+}
+
+// BuiltinIntArrayFunc returns an IntArray of the arguments. Iterable arguments
+// are expanded. If there is no argument, the array has length named argument
+// zeros.
+func BuiltinIntArrayFunc(c Call) (Object, error) {
+	var arr IntArray
+	err := collectNumArray(c, func(n int) {
+		arr = make(IntArray, n)
+	}, func(n int) {
+		arr = slices.Grow(arr, n)
+	}, func(o Object) bool {
+		switch v := o.(type) {
+		case IntArray:
+			arr = append(arr, v...)
+		case FloatArray:
+			for _, f := range v {
+				arr = append(arr, Int(f))
+			}
+		default:
+			i, ok := toIntElem(o)
+			if !ok {
+				return false
+			}
+			arr = append(arr, i)
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	if arr == nil {
+		arr = IntArray{}
+	}
+	return arr, nil
+}
+
+// BuiltinFloatArrayFunc returns a FloatArray of the arguments like
+// BuiltinIntArrayFunc.
+func BuiltinFloatArrayFunc(c Call) (Object, error) {
+	var arr FloatArray
+	err := collectNumArray(c, func(n int) {
+		arr = make(FloatArray, n)
+	}, func(n int) {
+		arr = slices.Grow(arr, n)
+	}, func(o Object) bool {
+		switch v := o.(type) {
+		case FloatArray:
+			arr = append(arr, v...)
+		case IntArray:
+			for _, i := range v {
+				arr = append(arr, Float(i))
+			}
+		default:
+			f, ok := toFloatElem(o)
+			if !ok {
+				return false
+			}
+			arr = append(arr, f)
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	if arr == nil {
+		arr = FloatArray{}
+	}
+	return arr, nil
+}
+
+// collectNumArray calls init with the length named argument if there is no
+// argument, otherwise it calls add with the numbers and the packed arrays in
+// the arguments and the values of the other iterable arguments. grow is called
+// with the number of the values to be added.
+func collectNumArray(c Call, init, grow func(n int), add func(o Object) bool) (err error) {
+	length := &NamedArgVar{
+		Name:          "length",
+		Value:         Int(0),
+		TypeAssertion: TypeAssertionFromTypes(TInt),
+	}
+	if err = c.NamedArgs.Get(length); err != nil {
+		return
+	}
+
+	if c.Args.Length() == 0 {
+		init(max(int(length.Value.(Int)), 0))
+		return
+	}
+
+	grow(c.Args.Length())
+	c.Args.Walk(func(i int, arg Object) any {
+		if add(arg) {
+			return nil
+		}
+		if !Iterable(c.VM, arg) {
+			err = NewArgumentTypeError(strconv.Itoa(i+1), "number|iterable", arg.Type().Name())
+			return err
+		}
+		var values Array
+		if values, err = ValuesOf(c.VM, arg, &NamedArgs{}); err != nil {
+			return err
+		}
+		grow(len(values))
+		for j, v := range values {
+			if !add(v) {
+				err = NewArgumentTypeError(strconv.Itoa(i+1)+"["+strconv.Itoa(j)+"]",
+					"number", v.Type().Name())
+				return err
+			}
+		}
+		return nil
+	})
+	return
+}
+
+func toIntElem(o Object) (Int, bool) {
+	switch v := o.(type) {
+	case Int:
+		return v, true
+	case Uint:
+		return Int(v), true
+	case Char:
+		return Int(v), true
+	case Bool:
+		if v {
+			return 1, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
+
+func toFloatElem(o Object) (Float, bool) {
+	switch v := o.(type) {
+	case Float:
+		return v, true
+	case Int:
+		return Float(v), true
+	case Uint:
+		return Float(v), true
+	}
+	return 0, false
+}
+
+// numArrayIndex returns the index of the element of an array of length n.
+func numArrayIndex(index Object, n int) (int, error) {
+	var i int
+	switch v := index.(type) {
+	case Int:
+		if i = int(v); i < 0 {
+			i += n
+		}
+	case Uint:
+		i = int(v)
+	default:
+		return 0, NewIndexTypeError("int|uint", index.Type().Name())
+	}
+	if i < 0 || i >= n {
+		return 0, ErrIndexOutOfBounds
+	}
+	return i, nil
+}
+
+// numArrayOperand returns the elements of a packed array or a number as an
+// array of length 1.
+func numArrayOperand(o Object) (ints []Int, floats []Float, ok bool) {
+	switch v := o.(type) {
+	case IntArray:
+		return v, nil, true
+	case FloatArray:
+		return nil, v, true
+	case Int:
+		return []Int{v}, nil, true
+	case Uint:
+		return []Int{Int(v)}, nil, true
+	case Float:
+		return nil, []Float{v}, true
+	}
+	return nil, nil, false
+}
+
+func numArrayBinaryOp(tok token.Token, left, right Object) (Object, error) {
+	li, lf, lok := numArrayOperand(left)
+	ri, rf, rok := numArrayOperand(right)
+	if lok && rok {
+		if lf == nil && rf == nil {
+			if r, ok, err := intArrayOp(tok, li, ri); ok {
+				if err != nil {
+					return nil, err
+				}
+				return IntArray(r), nil
+			}
+		} else {
+			if lf == nil {
+				lf = intsToFloats(li)
+			}
+			if rf == nil {
+				rf = intsToFloats(ri)
+			}
+			if r, ok, err := numArrayOp(tok, lf, rf); ok {
+				if err != nil {
+					return nil, err
+				}
+				return FloatArray(r), nil
+			}
+		}
+	}
+	return nil, NewOperandTypeError(
+		tok.String(),
+		left.Type().Name(),
+		right.Type().Name())
+}
+
+func intsToFloats(ints []Int) []Float {
+	floats := make([]Float, len(ints))
+	for i, v := range ints {
+		floats[i] = Float(v)
+	}
+	return floats
+}
+
+// numArrayLen returns the length of the result of an operation of x and y and
+// the strides of them which are 0 for the arrays of length 1.
+func numArrayLen(nx, ny int) (n, sx, sy int, err error) {
+	n, sx, sy = nx, 1, 1
+	switch {
+	case nx == ny:
+	case nx == 1:
+		n, sx = ny, 0
+	case ny == 1:
+		sy = 0
+	default:
+		err = ErrInvalidOperator.NewError(fmt.Sprintf("length mismatch: %d and %d", nx, ny))
+	}
+	return
+}
+
+// numArrayOp applies the arithmetic operator tok to the elements of x and y.
+// It reports whether tok is supported.
+func numArrayOp[T Int | Float](tok token.Token, x, y []T) (out []T, ok bool, err error) {
+	n, sx, sy, err := numArrayLen(len(x), len(y))
+	if err != nil {
+		return nil, true, err
+	}
+
+	out = make([]T, n)
+	switch tok {
+	case token.Add:
+		for i := range out {
+			out[i] = x[i*sx] + y[i*sy]
+		}
+	case token.Sub:
+		for i := range out {
+			out[i] = x[i*sx] - y[i*sy]
+		}
+	case token.Mul:
+		for i := range out {
+			out[i] = x[i*sx] * y[i*sy]
+		}
+	case token.Quo:
+		for i := range out {
+			d := y[i*sy]
+			if d == 0 {
+				return nil, true, ErrZeroDivision
+			}
+			out[i] = x[i*sx] / d
+		}
+	default:
+		return nil, false, nil
+	}
+	return out, true, nil
+}
+
+// intArrayOp is like numArrayOp with the integer operators.
+func intArrayOp(tok token.Token, x, y []Int) (out []Int, ok bool, err error) {
+	switch tok {
+	case token.Rem, token.And, token.Or, token.Xor, token.AndNot, token.Shl, token.Shr:
+	default:
+		return numArrayOp(tok, x, y)
+	}
+
+	n, sx, sy, err := numArrayLen(len(x), len(y))
+	if err != nil {
+		return nil, true, err
+	}
+
+	out = make([]Int, n)
+	for i := range out {
+		a, b := x[i*sx], y[i*sy]
+		switch tok {
+		case token.Rem:
+			if b == 0 {
+				return nil, true, ErrZeroDivision
+			}
+			out[i] = a % b
+		case token.And:
+			out[i] = a & b
+		case token.Or:
+			out[i] = a | b
+		case token.Xor:
+			out[i] = a ^ b
+		case token.AndNot:
+			out[i] = a &^ b
+		case token.Shl:
+			out[i] = a << b
+		case token.Shr:
+			out[i] = a >> b
+		}
+	}
+	return out, true, nil
+}
+
+// numArrayMethod returns the method name of the packed array arr.
+func numArrayMethod[T Int | Float](arr []T, name string) (Object, error) {
+	var fn func() Object
+	switch name {
+	case "sum":
+		fn = func() Object {
+			var sum T
+			for _, v := range arr {
+				sum += v
+			}
+			return numObject(sum)
+		}
+	case "mean":
+		fn = func() Object {
+			if len(arr) == 0 {
+				return Nil
+			}
+			var sum float64
+			for _, v := range arr {
+				sum += float64(v)
+			}
+			return Float(sum / float64(len(arr)))
+		}
+	case "min", "max":
+		isMin := name == "min"
+		fn = func() Object {
+			if len(arr) == 0 {
+				return Nil
+			}
+			m := arr[0]
+			for _, v := range arr[1:] {
+				if isMin && v < m || !isMin && v > m {
+					m = v
+				}
+			}
+			return numObject(m)
+		}
+	case "toArray":
+		fn = func() Object {
+			values := make(Array, len(arr))
+			for i, v := range arr {
+				values[i] = numObject(v)
+			}
+			return values
+		}
+	default:
+		return nil, ErrInvalidIndex.NewError(name)
+	}
+	return &Function{
+		Name: name,
+		Value: func(c Call) (Object, error) {
+			if err := c.Args.CheckLen(0); err != nil {
+				return nil, err
+			}
+			return fn(), nil
+		},
+	}, nil
+}
+
+func numObject[T Int | Float](v T) Object {
+	return any(v).(Object)
+}
@@ -99,9 +99,13 @@ type ReflectType struct {
 	FieldsNames  []string
 	RFields      map[string]*ReflectField
 	formatMethod *ReflectMethod
+	methods      TypeMethods
 }
 
-var _ ObjectType = (*ReflectType)(nil)
+var (
+	_ ObjectType        = (*ReflectType)(nil)
+	_ TypeMethodsGetter = (*ReflectType)(nil)
+)
 
 var (
 	reflectTypeCache   = map[reflect.Type]*ReflectType{}
@@ -297,9 +301,19 @@ func (r *ReflectType) Methods() (m Dict) {
 	for key := range r.RMethods {
 		m[key] = Nil
 	}
+	for key, fn := range r.methods.Dict() {
+		m[key] = fn
+	}
 	return m
 }
 
+// TypeMethods returns the methods registered to the type in addition to the
+// methods of the Go type. Types are cached, so the methods are registered for
+// all values of the Go type.
+func (r *ReflectType) TypeMethods() *TypeMethods {
+	return &r.methods
+}
+
 func (r *ReflectType) Fields() (fields Dict) {
 	fields = Dict{}
 	for _, f := range r.RFields {
@@ -461,6 +475,7 @@ func (r *ReflectValue) Init() {
 			names[i] = Str(name)
 			i++
 		}
+
 		sort.Slice(names, func(i, j int) bool {
 			return names[i].(Str) < names[j].(Str)
 		})
@@ -1216,6 +1231,9 @@ func (s *ReflectStruct) IndexGetS(vm *VM, index string) (value Object, err error
 	}
 
 	if !handled {
+		if m := TypeMethodOf(s, index); m != nil {
+			return m, nil
+		}
 		return s.methodsGetter.GetIndex(vm, Str(index))
 	}
 
@@ -2,6 +2,7 @@ package gad
 
 import (
 	"fmt"
+	"reflect"
 	"strings"
 	"testing"
 
@@ -545,3 +546,52 @@ func checkError(t *testing.T, label string, want, got error) bool {
 	}
 	return true
 }
+
+type typeMethodsObject struct {
+	ObjectImpl
+	v Int
+}
+
+var typeMethodsObjectType = NewBuiltinObjType("typeMethodsObject", nil)
+
+func (o *typeMethodsObject) Type() ObjectType {
+	return typeMethodsObjectType
+}
+
+func (o *typeMethodsObject) ToString() string {
+	return o.v.ToString()
+}
+
+func TestTypeMethods(t *testing.T) {
+	type point struct {
+		X int
+	}
+
+	rt := NewReflectType(reflect.TypeOf(point{}))
+	rt.TypeMethods().Set("scale", &Function{Value: func(c Call) (Object, error) {
+		p := c.Args.Get(0).(*ReflectStruct).Value().Interface().(*point)
+		p.X *= int(c.Args.Get(1).(Int))
+		return Int(p.X), nil
+	}})
+	typeMethodsObjectType.TypeMethods().Set("add", &Function{Value: func(c Call) (Object, error) {
+		return c.Args.Get(0).(*typeMethodsObject).v + c.Args.Get(1).(Int), nil
+	}})
+
+	require.Contains(t, rt.Methods(), "scale")
+	require.Equal(t, []string{"add"}, typeMethodsObjectType.TypeMethods().Names())
+
+	c, err := Compile([]byte(`global (p, o)
+	scale := p.scale
+	return [p.scale(2), scale(3), p.X, o.add(1)]`), CompileOptions{})
+	require.NoError(t, err)
+
+	pt := &point{X: 1}
+	rv, err := NewReflectValue(pt)
+	require.NoError(t, err)
+	ret, err := NewVM(c).RunOpts(&RunOpts{Globals: Dict{"p": rv, "o": &typeMethodsObject{v: 10}}})
+	require.NoError(t, err)
+	require.Equal(t, Array{Int(2), Int(6), Int(6), Int(11)}, ret)
+
+	typeMethodsObjectType.TypeMethods().Set("add", nil)
+	require.Empty(t, typeMethodsObjectType.TypeMethods().Names())
+}
@@ -0,0 +1,226 @@
+package gad
+
+import (
+	"fmt"
+)
+
+// SortedArray is an array which keeps its elements sorted by the options of
+// sort. Elements are inserted after the elements equal to them, so the
+// elements inserted earlier are before the equal ones inserted later.
+type SortedArray struct {
+	arr  Array
+	opts sortOptions
+}
+
+var (
+	_ Object                = (*SortedArray)(nil)
+	_ Copier                = (*SortedArray)(nil)
+	_ DeepCopier            = (*SortedArray)(nil)
+	_ IndexGetter           = (*SortedArray)(nil)
+	_ LengthGetter          = (*SortedArray)(nil)
+	_ Iterabler             = (*SortedArray)(nil)
+	_ ValuesGetter          = (*SortedArray)(nil)
+	_ NameCallerObject      = (*SortedArray)(nil)
+	_ ToArrayAppenderObject = (*SortedArray)(nil)
+	_ ToIterfaceVMConverter = (*SortedArray)(nil)
+)
+
+func (o *SortedArray) Type() ObjectType {
+	return TSortedArray
+}
+
+func (o *SortedArray) Format(f fmt.State, verb rune) {
+	o.arr.Format(f, verb)
+}
+
+func (o *SortedArray) ToString() string {
+	return o.arr.ToString()
+}
+
+func (o *SortedArray) Repr(vm *VM) (string, error) {
+	return ArrayRepr(o.Type().Name(), vm, len(o.arr), func(i int) Object {
+		return o.arr[i]
+	})
+}
+
+func (o *SortedArray) ToInterface(vm *VM) any {
+	return o.arr.ToAnyArray(vm)
+}
+
+// Copy implements Copier interface.
+func (o *SortedArray) Copy() Object {
+	return &SortedArray{arr: o.arr.Copy().(Array), opts: o.opts}
+}
+
+// DeepCopy implements DeepCopier interface.
+func (o *SortedArray) DeepCopy(vm *VM) (_ Object, err error) {
+	var arr Object
+	if arr, err = o.arr.DeepCopy(vm); err != nil {
+		return
+	}
+	return &SortedArray{arr: arr.(Array), opts: o.opts}, nil
+}
+
+// IndexGet implements IndexGetter interface.
+func (o *SortedArray) IndexGet(vm *VM, index Object) (Object, error) {
+	return o.arr.IndexGet(vm, index)
+}
+
+// Equal implements Object interface.
+func (o *SortedArray) Equal(right Object) bool {
+	if v, ok := right.(*SortedArray); ok {
+		return o.arr.Equal(v.arr)
+	}
+	return o.arr.Equal(right)
+}
+
+// IsFalsy implements Object interface.
+func (o *SortedArray) IsFalsy() bool {
+	return len(o.arr) == 0
+}
+
+// Length implements LengthGetter interface.
+func (o *SortedArray) Length() int {
+	return len(o.arr)
+}
+
+func (o *SortedArray) Iterate(vm *VM, na *NamedArgs) Iterator {
+	return o.arr.Iterate(vm, na)
+}
+
+// Values returns a copy of the elements.
+func (o *SortedArray) Values() Array {
+	return o.arr.Copy().(Array)
+}
+
+func (o *SortedArray) AppendToArray(arr *Array) {
+	*arr = append(*arr, o.arr...)
+}
+
+// Insert inserts the values after the elements equal to them and returns the
+// index of the last one.
+func (o *SortedArray) Insert(vm *VM, values ...Object) (i int, err error) {
+	for _, v := range values {
+		if o.arr, i, err = o.opts.insert(vm, o.arr, v); err != nil {
+			return
+		}
+	}
+	return
+}
+
+// Remove removes the first element equal to x and reports whether it was
+// found.
+func (o *SortedArray) Remove(vm *VM, x Object) (ok bool, err error) {
+	var (
+		key func(Object) (Object, error)
+		k   Object
+		i   int
+	)
+	if key, err = o.opts.keyFunc(vm); err != nil {
+		return
+	}
+	if k, err = key(x); err != nil {
+		return
+	}
+	if i, err = o.opts.bisect(vm, o.arr, k, false); err != nil {
+		return
+	}
+	var end int
+	if end, err = o.opts.bisect(vm, o.arr, k, true); err != nil {
+		return
+	}
+	for ; i < end; i++ {
+		if o.arr[i].Equal(x) {
+			o.arr = append(o.arr[:i], o.arr[i+1:]...)
+			return true, nil
+		}
+	}
+	return
+}
+
+// CallName implements NameCallerObject interface.
+func (o *SortedArray) CallName(name string, c Call) (_ Object, err error) {
+	switch name {
+	case "insert":
+		if err = c.Args.CheckMinLen(1); err != nil {
+			return
+		}
+		var i int
+		if i, err = o.Insert(c.VM, c.Args.Values()...); err != nil {
+			return
+		}
+		return Int(i), nil
+	case "remove":
+		if err = c.Args.CheckLen(1); err != nil {
+			return
+		}
+		var ok bool
+		if ok, err = o.Remove(c.VM, c.Args.GetOnly(0)); err != nil {
+			return
+		}
+		return Bool(ok), nil
+	case "bisect":
+		if err = c.Args.CheckLen(1); err != nil {
+			return
+		}
+		right := &NamedArgVar{Name: "right", Value: False}
+		if err = c.NamedArgs.Get(right); err != nil {
+			return
+		}
+		var i int
+		if i, err = o.opts.bisect(c.VM, o.arr, c.Args.GetOnly(0), !right.Value.IsFalsy()); err != nil {
+			return
+		}
+		return Int(i), nil
+	case "pop":
+		if err = c.Args.CheckRangeLen(0, 1); err != nil {
+			return
+		}
+		i := len(o.arr) - 1
+		if c.Args.Length() == 1 {
+			n, ok := c.Args.GetOnly(0).(Int)
+			if !ok {
+				return nil, NewArgumentTypeError("1st", "int", c.Args.GetOnly(0).Type().Name())
+			}
+			if i = int(n); i < 0 {
+				i += len(o.arr)
+			}
+		}
+		if i < 0 || i >= len(o.arr) {
+			return nil, ErrIndexOutOfBounds.NewError(fmt.Sprint(i))
+		}
+		v := o.arr[i]
+		o.arr = append(o.arr[:i], o.arr[i+1:]...)
+		return v, nil
+	}
+	return nil, ErrInvalidIndex.NewError(name)
+}
+
+// BuiltinSortedArrayFunc implements `sortedArray(iterable=nil; less, key, cmp,
+// reverse=false, natural=false)` which returns a new sortedArray of the
+// values of iterable.
+func BuiltinSortedArrayFunc(c Call) (_ Object, err error) {
+	if err = c.Args.CheckRangeLen(0, 1); err != nil {
+		return
+	}
+	o := &SortedArray{}
+	if o.opts, err = parseSortOptions(&c.NamedArgs, false); err != nil {
+		return
+	}
+	o.opts.stable = true
+	if c.Args.Length() == 1 && c.Args.GetOnly(0) != Nil {
+		var v Object
+		if v, err = Val(c.VM.Builtins.Call(BuiltinCollect, Call{VM: c.VM, Args: Args{Array{c.Args.GetOnly(0)}}})); err != nil {
+			return
+		}
+		arr, ok := v.(Array)
+		if !ok {
+			return nil, NewArgumentTypeError("1st", "iterable", c.Args.GetOnly(0).Type().Name())
+		}
+		o.arr = arr.Copy().(Array)
+		if err = o.opts.sort(c.VM, o.arr); err != nil {
+			return
+		}
+	}
+	return o, nil
+}
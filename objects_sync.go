@@ -0,0 +1,425 @@
+package gad
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// syncLock is a reader/writer lock whose waiters can be aborted with the VM.
+// Owners are tracked by VM to detect deadlocks if VM's DeadlockTimeout is set.
+type syncLock struct {
+	mu       sync.Mutex
+	writer   *VM
+	readers  map[*VM]int
+	nreaders int
+	changed  chan struct{}
+}
+
+func (l *syncLock) tryAcquire(vm *VM, write bool) bool {
+	if l.writer != nil || write && l.nreaders > 0 {
+		return false
+	}
+	if write {
+		l.writer = vm
+	} else {
+		if l.readers == nil {
+			l.readers = make(map[*VM]int)
+		}
+		l.readers[vm]++
+		l.nreaders++
+	}
+	return true
+}
+
+func (l *syncLock) TryAcquire(vm *VM, write bool) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.tryAcquire(vm, write)
+}
+
+func (l *syncLock) Acquire(vm *VM, write bool) error {
+	var (
+		timeout  = vm.DeadlockTimeout
+		deadline time.Time
+	)
+	if timeout > 0 {
+		deadline = time.Now().Add(timeout)
+	}
+
+	for {
+		l.mu.Lock()
+		if l.tryAcquire(vm, write) {
+			l.mu.Unlock()
+			return nil
+		}
+
+		if timeout > 0 && (l.writer == vm || write && l.readers[vm] > 0) {
+			l.mu.Unlock()
+			return ErrDeadlock.NewError("lock is already held by the same VM")
+		}
+
+		if l.changed == nil {
+			l.changed = make(chan struct{})
+		}
+		ch := l.changed
+		l.mu.Unlock()
+
+		var d time.Duration
+		if timeout > 0 {
+			if d = time.Until(deadline); d <= 0 {
+				return ErrDeadlock.NewError("lock is not acquired in " + timeout.String())
+			}
+		}
+
+		if _, err := vm.wait(ch, d); err != nil {
+			return err
+		}
+	}
+}
+
+func (l *syncLock) Release(vm *VM, write bool) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if write {
+		if l.writer == nil {
+			return errors.New("unlock of unlocked mutex")
+		}
+		l.writer = nil
+	} else {
+		if l.nreaders == 0 {
+			return errors.New("runlock of unlocked mutex")
+		}
+		l.nreaders--
+		if n := l.readers[vm]; n > 1 {
+			l.readers[vm] = n - 1
+		} else {
+			delete(l.readers, vm)
+		}
+	}
+
+	if l.changed != nil {
+		close(l.changed)
+		l.changed = nil
+	}
+	return nil
+}
+
+func (l *syncLock) call(c Call, write bool) (_ Object, err error) {
+	if err = c.Args.CheckMinLen(1); err != nil {
+		return
+	}
+
+	fn := c.Args.GetOnly(0)
+	if !Callable(fn) {
+		return nil, NewArgumentTypeError("1st", "callable", fn.Type().Name())
+	}
+	args := c.Args.Values()[1:]
+
+	if err = l.Acquire(c.VM, write); err != nil {
+		return
+	}
+	defer func() {
+		if rerr := l.Release(c.VM, write); err == nil {
+			err = rerr
+		}
+	}()
+	return NewInvoker(c.VM, fn).Invoke(Args{args}, &c.NamedArgs)
+}
+
+// Mutex is a mutual exclusion lock object for scripts sharing state between
+// concurrently running functions.
+type Mutex struct {
+	l syncLock
+}
+
+var (
+	_ Object           = (*Mutex)(nil)
+	_ NameCallerObject = (*Mutex)(nil)
+)
+
+func (o *Mutex) Type() ObjectType {
+	return TMutex
+}
+
+func (o *Mutex) ToString() string {
+	return ReprQuote("mutex")
+}
+
+func (o *Mutex) IsFalsy() bool {
+	return false
+}
+
+func (o *Mutex) Equal(right Object) bool {
+	v, ok := right.(*Mutex)
+	return ok && v == o
+}
+
+func (o *Mutex) CallName(name string, c Call) (Object, error) {
+	switch name {
+	case "lock", "unlock", "tryLock":
+		if err := c.Args.CheckLen(0); err != nil {
+			return nil, err
+		}
+	}
+
+	switch name {
+	case "lock":
+		return Nil, o.l.Acquire(c.VM, true)
+	case "unlock":
+		return Nil, o.l.Release(c.VM, true)
+	case "tryLock":
+		return Bool(o.l.TryAcquire(c.VM, true)), nil
+	case "call":
+		return o.l.call(c, true)
+	}
+	return nil, ErrInvalidIndex.NewError(name)
+}
+
+// RWMutex is a reader/writer mutual exclusion lock object.
+type RWMutex struct {
+	l syncLock
+}
+
+var (
+	_ Object           = (*RWMutex)(nil)
+	_ NameCallerObject = (*RWMutex)(nil)
+)
+
+func (o *RWMutex) Type() ObjectType {
+	return TRWMutex
+}
+
+func (o *RWMutex) ToString() string {
+	return ReprQuote("rwmutex")
+}
+
+func (o *RWMutex) IsFalsy() bool {
+	return false
+}
+
+func (o *RWMutex) Equal(right Object) bool {
+	v, ok := right.(*RWMutex)
+	return ok && v == o
+}
+
+func (o *RWMutex) CallName(name string, c Call) (Object, error) {
+	switch name {
+	case "lock", "unlock", "tryLock", "rlock", "runlock", "tryRLock":
+		if err := c.Args.CheckLen(0); err != nil {
+			return nil, err
+		}
+	}
+
+	switch name {
+	case "lock":
+		return Nil, o.l.Acquire(c.VM, true)
+	case "unlock":
+		return Nil, o.l.Release(c.VM, true)
+	case "tryLock":
+		return Bool(o.l.TryAcquire(c.VM, true)), nil
+	case "rlock":
+		return Nil, o.l.Acquire(c.VM, false)
+	case "runlock":
+		return Nil, o.l.Release(c.VM, false)
+	case "tryRLock":
+		return Bool(o.l.TryAcquire(c.VM, false)), nil
+	case "call":
+		return o.l.call(c, true)
+	case "rcall":
+		return o.l.call(c, false)
+	}
+	return nil, ErrInvalidIndex.NewError(name)
+}
+
+// WaitGroup waits for a collection of functions to finish.
+type WaitGroup struct {
+	mu   sync.Mutex
+	n    int
+	zero chan struct{}
+}
+
+var (
+	_ Object           = (*WaitGroup)(nil)
+	_ NameCallerObject = (*WaitGroup)(nil)
+)
+
+func (o *WaitGroup) Type() ObjectType {
+	return TWaitGroup
+}
+
+func (o *WaitGroup) ToString() string {
+	return ReprQuote("waitgroup")
+}
+
+func (o *WaitGroup) IsFalsy() bool {
+	return false
+}
+
+func (o *WaitGroup) Equal(right Object) bool {
+	v, ok := right.(*WaitGroup)
+	return ok && v == o
+}
+
+// Add adds delta to the counter. If counter becomes zero, waiters are
+// released.
+func (o *WaitGroup) Add(delta int) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if o.n+delta < 0 {
+		return errors.New("negative waitgroup counter")
+	}
+	o.n += delta
+	if o.n == 0 && o.zero != nil {
+		close(o.zero)
+		o.zero = nil
+	}
+	return nil
+}
+
+// Wait blocks until counter is zero.
+func (o *WaitGroup) Wait(vm *VM) error {
+	o.mu.Lock()
+	if o.n == 0 {
+		o.mu.Unlock()
+		return nil
+	}
+	if o.zero == nil {
+		o.zero = make(chan struct{})
+	}
+	ch := o.zero
+	o.mu.Unlock()
+
+	timedOut, err := vm.wait(ch, vm.DeadlockTimeout)
+	if timedOut {
+		err = ErrDeadlock.NewError("waitgroup is not done in " + vm.DeadlockTimeout.String())
+	}
+	return err
+}
+
+func (o *WaitGroup) CallName(name string, c Call) (_ Object, err error) {
+	switch name {
+	case "add":
+		if err = c.Args.CheckMaxLen(1); err != nil {
+			return
+		}
+		delta := 1
+		if c.Args.Length() == 1 {
+			arg := c.Args.GetOnly(0)
+			var ok bool
+			if delta, ok = ToGoInt(arg); !ok {
+				return nil, NewArgumentTypeError("1st", "int", arg.Type().Name())
+			}
+		}
+		return Nil, o.Add(delta)
+	case "done":
+		if err = c.Args.CheckLen(0); err != nil {
+			return
+		}
+		return Nil, o.Add(-1)
+	case "wait":
+		if err = c.Args.CheckLen(0); err != nil {
+			return
+		}
+		return Nil, o.Wait(c.VM)
+	}
+	return nil, ErrInvalidIndex.NewError(name)
+}
+
+// Once calls a function only once and returns its result to all callers.
+type Once struct {
+	mu      sync.Mutex
+	done    bool
+	running *VM
+	ch      chan struct{}
+	result  Object
+	err     error
+}
+
+var (
+	_ Object           = (*Once)(nil)
+	_ NameCallerObject = (*Once)(nil)
+)
+
+func (o *Once) Type() ObjectType {
+	return TOnce
+}
+
+func (o *Once) ToString() string {
+	return ReprQuote("once")
+}
+
+func (o *Once) IsFalsy() bool {
+	return false
+}
+
+func (o *Once) Equal(right Object) bool {
+	v, ok := right.(*Once)
+	return ok && v == o
+}
+
+// Do calls fn if it is not called before, otherwise waits for the first call
+// to return. The result and the error of the first call is returned.
+func (o *Once) Do(vm *VM, fn Object, args Args, namedArgs *NamedArgs) (Object, error) {
+	o.mu.Lock()
+	if o.done {
+		o.mu.Unlock()
+		return o.result, o.err
+	}
+
+	if o.running != nil {
+		if vm.DeadlockTimeout > 0 && o.running == vm {
+			o.mu.Unlock()
+			return nil, ErrDeadlock.NewError("once is called recursively")
+		}
+		ch := o.ch
+		o.mu.Unlock()
+
+		timedOut, err := vm.wait(ch, vm.DeadlockTimeout)
+		if timedOut {
+			err = ErrDeadlock.NewError("once is not done in " + vm.DeadlockTimeout.String())
+		}
+		if err != nil {
+			return nil, err
+		}
+		return o.result, o.err
+	}
+
+	o.running = vm
+	o.ch = make(chan struct{})
+	o.mu.Unlock()
+
+	ret, err := NewInvoker(vm, fn).Invoke(args, namedArgs)
+
+	o.mu.Lock()
+	o.done = true
+	o.running = nil
+	o.result, o.err = ret, err
+	close(o.ch)
+	o.mu.Unlock()
+	return ret, err
+}
+
+func (o *Once) CallName(name string, c Call) (_ Object, err error) {
+	switch name {
+	case "call":
+		if err = c.Args.CheckMinLen(1); err != nil {
+			return
+		}
+		fn := c.Args.GetOnly(0)
+		if !Callable(fn) {
+			return nil, NewArgumentTypeError("1st", "callable", fn.Type().Name())
+		}
+		return o.Do(c.VM, fn, Args{c.Args.Values()[1:]}, &c.NamedArgs)
+	case "done":
+		if err = c.Args.CheckLen(0); err != nil {
+			return
+		}
+		o.mu.Lock()
+		defer o.mu.Unlock()
+		return Bool(o.done), nil
+	}
+	return nil, ErrInvalidIndex.NewError(name)
+}
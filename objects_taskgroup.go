@@ -0,0 +1,172 @@
+package gad
+
+import (
+	"strconv"
+	"sync"
+)
+
+// TaskGroup runs callables concurrently in their own goroutines like errgroup
+// package of Go. The first error aborts the running tasks and it is thrown by
+// wait method. Objects shared by tasks are not synchronized.
+type TaskGroup struct {
+	vm      *VM
+	wg      sync.WaitGroup
+	sem     chan struct{}
+	mu      sync.Mutex
+	err     error
+	results Array
+	running map[*VM]struct{}
+}
+
+var (
+	_ Object           = (*TaskGroup)(nil)
+	_ NameCallerObject = (*TaskGroup)(nil)
+)
+
+// NewTaskGroup creates a new TaskGroup which runs at most limit tasks at the
+// same time. If limit is not positive, number of tasks is not limited.
+func NewTaskGroup(vm *VM, limit int) *TaskGroup {
+	g := &TaskGroup{vm: vm, running: make(map[*VM]struct{})}
+	if limit > 0 {
+		g.sem = make(chan struct{}, limit)
+	}
+	return g
+}
+
+func (g *TaskGroup) Type() ObjectType {
+	return TTaskGroup
+}
+
+func (g *TaskGroup) ToString() string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return ReprQuote("taskGroup tasks=" + strconv.Itoa(len(g.results)))
+}
+
+func (g *TaskGroup) IsFalsy() bool {
+	return false
+}
+
+func (g *TaskGroup) Equal(right Object) bool {
+	v, ok := right.(*TaskGroup)
+	return ok && v == g
+}
+
+func (g *TaskGroup) CallName(name string, c Call) (Object, error) {
+	switch name {
+	case "go":
+		if err := c.Args.CheckMinLen(1); err != nil {
+			return nil, err
+		}
+		fn := c.Args.GetOnly(0)
+		if !Callable(fn) {
+			return nil, NewArgumentTypeError("1st", "callable", fn.Type().Name())
+		}
+		args := append(Array{}, c.Args.Values()[1:]...)
+		return Nil, g.Go(fn, args, c.NamedArgs.Copy().(*NamedArgs))
+	case "wait":
+		if err := c.Args.CheckLen(0); err != nil {
+			return nil, err
+		}
+		return g.Wait()
+	}
+	return nil, ErrInvalidIndex.NewError(name)
+}
+
+// Go calls fn with given arguments in a new goroutine. If number of tasks is
+// limited, it blocks until a running task returns. Tasks are not started after
+// the first error.
+func (g *TaskGroup) Go(fn Object, args Array, namedArgs *NamedArgs) error {
+	if g.sem != nil {
+		select {
+		case g.sem <- struct{}{}:
+		case <-g.vm.Context.Done():
+			return g.vm.Context.Err()
+		}
+	}
+
+	g.mu.Lock()
+	if g.err != nil {
+		g.mu.Unlock()
+		g.release()
+		return nil
+	}
+	index := len(g.results)
+	g.results = append(g.results, Nil)
+	g.mu.Unlock()
+
+	inv := NewInvoker(g.vm, fn)
+	inv.Acquire()
+	if inv.isCompiled {
+		g.mu.Lock()
+		g.running[inv.child] = struct{}{}
+		g.mu.Unlock()
+	}
+
+	g.wg.Add(1)
+	go func() {
+		var (
+			ret Object
+			err error
+		)
+
+		defer func() {
+			if r := recover(); r != nil {
+				err = newPanicError(r)
+			}
+			g.done(inv, index, ret, err)
+		}()
+
+		if g.failed() {
+			return
+		}
+		ret, err = inv.Invoke(Args{args}, namedArgs)
+	}()
+	return nil
+}
+
+func (g *TaskGroup) done(inv *Invoker, index int, ret Object, err error) {
+	g.mu.Lock()
+	delete(g.running, inv.child)
+	if err != nil {
+		if g.err == nil {
+			g.err = err
+			for vm := range g.running {
+				vm.Abort()
+			}
+		}
+	} else {
+		g.results[index] = ret
+	}
+	g.mu.Unlock()
+
+	inv.Release()
+	g.release()
+	g.wg.Done()
+}
+
+func (g *TaskGroup) failed() bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.err != nil
+}
+
+func (g *TaskGroup) release() {
+	if g.sem != nil {
+		<-g.sem
+	}
+}
+
+// Wait blocks until all tasks return. It returns the results of tasks in the
+// order they are started or the first error.
+func (g *TaskGroup) Wait() (Object, error) {
+	g.wg.Wait()
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.err != nil {
+		return nil, g.err
+	}
+	return append(Array{}, g.results...), nil
+}
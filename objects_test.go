@@ -413,3 +413,174 @@ func TestObjectIndexSet(t *testing.T) {
 	require.Nil(t, err)
 	require.Equal(t, Int(2), v.(*SyncDict).Value["a"])
 }
+
+func TestArrayView(t *testing.T) {
+	arr := Array{Int(1), Int(2), Int(3), Int(4)}
+	v := NewArrayView(arr, 1, 3)
+	require.Equal(t, "arrayView", v.Type().Name())
+	require.Equal(t, 2, v.Length())
+	require.True(t, v.Equal(Array{Int(2), Int(3)}))
+	require.True(t, Array{Int(2), Int(3)}.Equal(v))
+	require.True(t, NewArrayView(arr, 1, 1).IsFalsy())
+
+	// writes to the array are visible until the view is written
+	arr[1] = Int(20)
+	e, err := v.IndexGet(nil, Int(0))
+	require.NoError(t, err)
+	require.Equal(t, Int(20), e)
+
+	sub := v.Slice(1, 2).(*ArrayView)
+	cp := v.Copy().(*ArrayView)
+	require.NoError(t, v.IndexSet(nil, Int(-1), Int(30)))
+	require.Equal(t, Array{Int(1), Int(20), Int(3), Int(4)}, arr)
+	require.True(t, v.Equal(Array{Int(20), Int(30)}))
+	require.True(t, cp.Equal(Array{Int(20), Int(3)}))
+	require.True(t, sub.Equal(Array{Int(3)}))
+	require.ErrorIs(t, v.IndexSet(nil, Int(2), Nil), ErrIndexOutOfBounds)
+
+	appended, err := v.Append(nil, Int(5))
+	require.NoError(t, err)
+	require.Equal(t, Array{Int(20), Int(30), Int(5)}, appended)
+	require.Equal(t, Array{Int(1), Int(20), Int(3), Int(4)}, arr)
+
+	values := cp.Values()
+	values[0] = Nil
+	require.True(t, cp.Equal(Array{Int(20), Int(3)}))
+}
+
+func TestBytesView(t *testing.T) {
+	buf := []byte("hello world")
+	v, invalidate := BytesView(buf[:5], false)
+	require.Equal(t, "bytesView", v.Type().Name())
+	require.True(t, v.Valid())
+	require.Equal(t, 5, v.Length())
+	require.Equal(t, "hello", v.ToString())
+	require.True(t, v.Equal(Bytes("hello")))
+	require.True(t, Bytes("hello").Equal(v))
+
+	c, err := Compile([]byte(`param v
+v[0] = 72
+s := v[1:3]
+return [str(s), len(v), bytes(v), v + "!", collect(s)]`), CompileOptions{})
+	require.NoError(t, err)
+	ret, err := NewVM(c).Run(v)
+	require.NoError(t, err)
+	require.Equal(t, Array{Str("el"), Int(5), Bytes("Hello"), Bytes("Hello!"), Array{Int('e'), Int('l')}}, ret)
+	require.Equal(t, "Hello world", string(buf))
+
+	s := v.Slice(1, 3).(*HostBytes)
+	invalidate()
+	require.False(t, v.Valid())
+	require.False(t, s.Valid())
+	_, err = s.IndexGet(nil, Int(0))
+	require.ErrorIs(t, err, ErrViewInvalidated)
+	require.Equal(t, 0, v.Length())
+
+	_, err = NewVM(c).Run(v)
+	require.ErrorIs(t, err, ErrViewInvalidated)
+
+	ro, _ := BytesView(buf, true)
+	require.True(t, ro.ReadOnly())
+	require.ErrorIs(t, ro.IndexSet(nil, Int(0), Int('x')), ErrNotIndexAssignable)
+}
+
+func TestCOWDict(t *testing.T) {
+	d := NewCOWDict(Dict{"a": Int(1)})
+	first := d.Load()
+	require.Equal(t, "cowDict", d.Type().Name())
+	require.False(t, d.IsFalsy())
+	require.True(t, NewCOWDict(nil).IsFalsy())
+
+	v, err := d.IndexGet(nil, Str("a"))
+	require.NoError(t, err)
+	require.Equal(t, Int(1), v)
+
+	require.NoError(t, d.IndexSet(nil, Str("b"), Int(2)))
+	require.Equal(t, Dict{"a": Int(1)}, first)
+	require.Equal(t, Dict{"a": Int(1), "b": Int(2)}, d.Load())
+	require.NoError(t, d.IndexDelete(nil, Str("a")))
+	require.Equal(t, Dict{"b": Int(2)}, d.Load())
+	require.Equal(t, 1, d.Length())
+
+	cp := d.Copy().(*COWDict)
+	require.NoError(t, cp.IndexSet(nil, Str("c"), Int(3)))
+	require.Equal(t, Dict{"b": Int(2)}, d.Load())
+
+	old := d.Swap(Dict{"x": Str("y")})
+	require.Equal(t, Dict{"b": Int(2)}, old)
+	require.True(t, d.Equal(Dict{"x": Str("y")}))
+
+	c, err := Compile([]byte(`global config; return config.name`), CompileOptions{})
+	require.NoError(t, err)
+	globals := NewCOWDict(Dict{"config": Dict{"name": Str("v1")}})
+	vm := NewVM(c)
+	ret, err := vm.RunOpts(&RunOpts{Globals: globals})
+	require.NoError(t, err)
+	require.Equal(t, Str("v1"), ret)
+
+	globals.Store(Dict{"config": Dict{"name": Str("v2")}})
+	ret, err = vm.RunOpts(&RunOpts{Globals: globals})
+	require.NoError(t, err)
+	require.Equal(t, Str("v2"), ret)
+
+	c, err = Compile([]byte(`global x; x = (x ?? 0) + 1; return collect(keys(globals()))`), CompileOptions{})
+	require.NoError(t, err)
+	globals = NewCOWDict(nil)
+	ret, err = NewVM(c).RunOpts(&RunOpts{Globals: globals})
+	require.NoError(t, err)
+	require.Equal(t, Array{Str("x")}, ret)
+	require.Equal(t, Dict{"x": Int(1)}, globals.Load())
+}
+
+func TestTransfer(t *testing.T) {
+	inner := Array{Int(1), Str("a")}
+	d := Dict{"x": inner, "y": inner, "b": Bytes("b")}
+	d["self"] = d
+	v, err := Transfer(Array{d, Nil, Float(1.5), &KeyValue{K: Str("k"), V: Array{}}})
+	require.NoError(t, err)
+
+	arr := v.(Array)
+	cp := arr[0].(Dict)
+	require.Equal(t, inner, cp["x"])
+	require.Equal(t, Nil, arr[1])
+	require.Equal(t, Float(1.5), arr[2])
+
+	// copies are not shared with the original but keep the references
+	cp["x"].(Array)[0] = Int(2)
+	require.Equal(t, Int(1), inner[0])
+	require.Equal(t, Int(2), cp["y"].(Array)[0])
+	cp["b"].(Bytes)[0] = 'c'
+	require.Equal(t, Bytes("b"), d["b"])
+	cp["z"] = True
+	require.Equal(t, True, cp["self"].(Dict)["z"])
+	require.NotContains(t, d, "z")
+
+	sd := &SyncDict{Value: Dict{"a": Array{Int(1)}}}
+	v, err = Transfer(sd)
+	require.NoError(t, err)
+	require.NotSame(t, sd, v)
+	require.Equal(t, sd.Value, v.(*SyncDict).Value)
+
+	cow := NewCOWDict(Dict{"a": Int(1)})
+	v, err = Transfer(cow)
+	require.NoError(t, err)
+	require.NotSame(t, cow, v)
+	require.Equal(t, Dict{"a": Int(1)}, v.(*COWDict).Load())
+
+	v, err = Transfer(Taint(Array{Int(1)}))
+	require.NoError(t, err)
+	require.True(t, IsTainted(v))
+
+	for _, o := range []Object{
+		&Buffer{},
+		Array{Int(1), &Buffer{}},
+		Dict{"a": &ObjectPtr{}},
+		&CompiledFunction{},
+		MustToObject(struct{ A int }{}),
+		&SyncDict{Value: Dict{"a": &Buffer{}}},
+	} {
+		_, err = Transfer(o)
+		require.Error(t, err, "%T", o)
+		require.ErrorIs(t, err, ErrNotTransferable)
+	}
+}
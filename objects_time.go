@@ -0,0 +1,203 @@
+package gad
+
+import (
+	"sync"
+	"time"
+)
+
+// ToDuration converts int nanoseconds or duration string like "1m30s" to
+// time.Duration.
+func ToDuration(o Object) (d time.Duration, ok bool) {
+	switch v := o.(type) {
+	case Str, RawStr:
+		var err error
+		if d, err = time.ParseDuration(v.ToString()); err != nil {
+			return
+		}
+		return d, true
+	default:
+		var i int64
+		if i, ok = ToGoInt64(o); ok {
+			d = time.Duration(i)
+		}
+		return
+	}
+}
+
+func durationArg(pos string, o Object) (time.Duration, error) {
+	d, ok := ToDuration(o)
+	if !ok {
+		return 0, NewArgumentTypeError(pos, "int|str", o.Type().Name())
+	}
+	return d, nil
+}
+
+type tickerIterator struct {
+	d     time.Duration
+	start time.Time
+}
+
+// TickerIterator returns an infinite iterator which waits for given duration
+// before every entry. Keys are tick indexes and values are durations elapsed
+// since the first call. Waiting is interrupted if VM is aborted or its
+// Context is done.
+func TickerIterator(d time.Duration) Iterator {
+	return &tickerIterator{d: d}
+}
+
+func (it *tickerIterator) Type() ObjectType {
+	return TTickerIterator
+}
+
+func (it *tickerIterator) Repr(vm *VM) (string, error) {
+	return ToReprTypedRS(vm, it.Type(), it.d.String())
+}
+
+func (it *tickerIterator) Input() Object {
+	return Int(it.d)
+}
+
+func (it *tickerIterator) Start(vm *VM) (state *IteratorState, err error) {
+	it.start = time.Now()
+	state = &IteratorState{Value: Int(0), Entry: KeyValue{K: Int(0)}}
+	err = it.tick(vm, state, 0)
+	return
+}
+
+func (it *tickerIterator) Next(vm *VM, state *IteratorState) (err error) {
+	i := state.Value.(Int) + 1
+	state.Value = i
+	state.Entry.K = i
+	return it.tick(vm, state, i)
+}
+
+func (it *tickerIterator) tick(vm *VM, state *IteratorState, i Int) (err error) {
+	next := it.start.Add(it.d * time.Duration(i+1))
+	if err = vm.sleep(time.Until(next)); err != nil {
+		return
+	}
+	state.Entry.V = Int(time.Since(it.start))
+	return
+}
+
+// Timer calls a function once in its own goroutine after a duration unless
+// it is stopped, the VM is aborted or the run of the VM ends.
+type Timer struct {
+	mu      sync.Mutex
+	stopCh  chan struct{}
+	done    chan struct{}
+	stopped bool
+	fired   bool
+	result  Object
+	err     error
+}
+
+var (
+	_ Object           = (*Timer)(nil)
+	_ NameCallerObject = (*Timer)(nil)
+)
+
+// AfterFunc starts a Timer which calls fn with given arguments after d.
+func AfterFunc(vm *VM, d time.Duration, fn Object, args Args, namedArgs *NamedArgs) *Timer {
+	t := &Timer{
+		stopCh: make(chan struct{}),
+		done:   make(chan struct{}),
+		result: Nil,
+	}
+
+	vm.addTimer(t)
+
+	go func() {
+		defer close(t.done)
+
+		var (
+			timedOut = true
+			err      error
+		)
+		if d > 0 {
+			timedOut, err = vm.wait(t.stopCh, d)
+		}
+		vm.removeTimer(t)
+
+		t.mu.Lock()
+		if !timedOut || t.stopped {
+			if err != nil {
+				t.stopped = true
+				t.err = err
+			}
+			t.mu.Unlock()
+			return
+		}
+		t.fired = true
+		t.mu.Unlock()
+
+		inv := NewInvoker(vm, fn)
+		inv.Acquire()
+		defer inv.Release()
+
+		defer func() {
+			if r := recover(); r != nil {
+				t.err = newPanicError(r)
+			}
+		}()
+		t.result, t.err = inv.Invoke(args, namedArgs)
+	}()
+	return t
+}
+
+func (t *Timer) Type() ObjectType {
+	return TTimer
+}
+
+func (t *Timer) ToString() string {
+	return ReprQuote("timer")
+}
+
+func (t *Timer) IsFalsy() bool {
+	return false
+}
+
+func (t *Timer) Equal(right Object) bool {
+	v, ok := right.(*Timer)
+	return ok && v == t
+}
+
+// Stop prevents the Timer from firing. It returns false if the timer has
+// already fired or been stopped.
+func (t *Timer) Stop() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.fired || t.stopped {
+		return false
+	}
+	t.stopped = true
+	close(t.stopCh)
+	return true
+}
+
+// Wait blocks until the function returns and returns its result. If Timer is
+// stopped, it returns nil.
+func (t *Timer) Wait(vm *VM) (Object, error) {
+	if _, err := vm.wait(t.done, 0); err != nil {
+		return nil, err
+	}
+	if t.err != nil {
+		return nil, t.err
+	}
+	return t.result, nil
+}
+
+func (t *Timer) CallName(name string, c Call) (Object, error) {
+	if err := c.Args.CheckLen(0); err != nil {
+		return nil, err
+	}
+
+	switch name {
+	case "stop":
+		return Bool(t.Stop()), nil
+	case "wait":
+		return t.Wait(c.VM)
+	}
+	return nil, ErrInvalidIndex.NewError(name)
+}
@@ -5,6 +5,7 @@ import (
 	"strconv"
 	"strings"
 
+	"github.com/gad-lang/gad/internal/compat"
 	"github.com/gad-lang/gad/token"
 )
 
@@ -42,6 +43,11 @@ func (o *Obj) ToString() string {
 	}).ToString()
 }
 
+// Format implements fmt.Formatter interface.
+func (o *Obj) Format(s fmt.State, verb rune) {
+	fmt.Fprintf(s, compat.FmtFormatString(s, verb), o.ToString())
+}
+
 // Copy implements Copier interface.
 func (o Obj) Copy() Object {
 	o.fields = o.fields.Copy().(Dict)
@@ -49,12 +55,18 @@ func (o Obj) Copy() Object {
 }
 
 // DeepCopy implements DeepCopier interface.
-func (o Obj) DeepCopy(vm *VM) (r Object, err error) {
+func (o *Obj) DeepCopy(vm *VM) (r Object, err error) {
+	defer vm.beginDeepCopy()()
+	if r, ok := vm.deepCopyOf(o); ok {
+		return r, nil
+	}
+	cp := &Obj{typ: o.typ}
+	vm.addDeepCopy(o, cp)
 	if r, err = o.fields.DeepCopy(vm); err != nil {
 		return
 	}
-	o.fields = r.(Dict)
-	return &o, nil
+	cp.fields = r.(Dict)
+	return cp, nil
 }
 
 // IndexSet implements Object interface.
@@ -62,12 +74,42 @@ func (o *Obj) IndexSet(vm *VM, index, value Object) (err error) {
 	name := index.ToString()
 	if s := o.typ.SettersDict[name]; s != nil {
 		_, err = DoCall(s.(CallerObject), Call{VM: vm, Args: Args{Array{o, value}}})
+	} else if m := o.attrMethod(vm, BuiltinSetAttr, name, value); m != nil {
+		_, err = DoCall(m, Call{VM: vm, Args: Args{Array{o, Str(name), value}}})
 	} else {
 		o.fields[name] = value
 	}
 	return
 }
 
+// attrMethod returns the method of __getattr__, __setattr__ or
+// __call_missing__ protocol function defined for the type of o and the
+// arguments following the attribute name if the attribute is neither a field
+// of o nor a field of its type, otherwise nil.
+func (o *Obj) attrMethod(vm *VM, t BuiltinType, name string, args ...Object) CallerObject {
+	if vm == nil || vm.Builtins == nil {
+		return nil
+	}
+	if _, ok := o.fields[name]; ok {
+		return nil
+	}
+	if _, ok := o.typ.FieldsDict[name]; ok {
+		return nil
+	}
+	cwm, _ := vm.Builtins.Objects[t].(*CallerObjectWithMethods)
+	if cwm == nil {
+		return nil
+	}
+	types := []ObjectType{o.typ, TStr}
+	for _, arg := range args {
+		types = append(types, arg.Type())
+	}
+	if m := cwm.Methods.GetMethod(types); m != nil && !m.Default {
+		return m.CallerObject
+	}
+	return nil
+}
+
 func (o *Obj) Methods() *IndexGetProxy {
 	methodsName := o.typ.MethodsDict.SortedKeys
 	return &IndexGetProxy{
@@ -114,6 +156,9 @@ func (o *Obj) IndexGet(vm *VM, index Object) (Object, error) {
 		if ok {
 			return v, nil
 		}
+		if m := o.attrMethod(vm, BuiltinGetAttr, name); m != nil {
+			return YieldCall(m, &Call{VM: vm, Args: Args{Array{o, Str(name)}}}), nil
+		}
 		return Nil, nil
 	}
 }
@@ -163,9 +208,25 @@ func (o *Obj) CallName(name string, c Call) (_ Object, err error) {
 	if v, err = o.IndexGet(c.VM, Str(name)); err != nil {
 		return
 	}
+	if y, ok := v.(*yieldCall); ok {
+		// resolves the getter or __getattr__ to call its result
+		if v, err = DoCall(y.CallerObject, *y.c); err != nil {
+			return
+		}
+	}
 	if Callable(v) {
 		return YieldCall(v.(CallerObject), &c), nil
 	}
+	if _, ok := o.typ.GettersDict[name]; !ok {
+		// args may refer to the VM stack
+		args, namedArgs := append(Array{}, c.Args.Values()...), c.NamedArgs.Dict()
+		if namedArgs == nil {
+			namedArgs = Dict{}
+		}
+		if m := o.attrMethod(c.VM, BuiltinCallMissing, name, args, namedArgs); m != nil {
+			return YieldCall(m, &Call{VM: c.VM, Args: Args{Array{o, Str(name), args, namedArgs}}}), nil
+		}
+	}
 	return nil, ErrNotCallable.NewError("func " + strconv.Quote(name) + " of type " + v.Type().Name())
 }
 
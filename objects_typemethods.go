@@ -0,0 +1,102 @@
+package gad
+
+import (
+	"sort"
+	"sync/atomic"
+)
+
+// TypeMethods is the table of the methods registered to an ObjectType by the
+// host. The methods are callable on the values of the type like
+// `value.method(args)` and they are called with the value as the first
+// argument. Registered methods take precedence over the indexes and the
+// methods of the values. Reads are lock-free; methods should be registered
+// before scripts use the type.
+type TypeMethods struct {
+	p atomic.Pointer[map[string]CallerObject]
+}
+
+// TypeMethodsGetter is implemented by the ObjectTypes which have a
+// TypeMethods table like BuiltinObjType and ReflectType.
+type TypeMethodsGetter interface {
+	ObjectType
+	TypeMethods() *TypeMethods
+}
+
+// Set registers the method with the name, a nil fn removes the method.
+func (m *TypeMethods) Set(name string, fn CallerObject) {
+	for {
+		old := m.p.Load()
+		methods := make(map[string]CallerObject)
+		if old != nil {
+			for k, v := range *old {
+				methods[k] = v
+			}
+		}
+		if fn == nil {
+			delete(methods, name)
+		} else {
+			methods[name] = fn
+		}
+		if m.p.CompareAndSwap(old, &methods) {
+			return
+		}
+	}
+}
+
+// Get returns the method with the name or nil.
+func (m *TypeMethods) Get(name string) CallerObject {
+	if p := m.p.Load(); p != nil {
+		return (*p)[name]
+	}
+	return nil
+}
+
+// Names returns the sorted names of the methods.
+func (m *TypeMethods) Names() []string {
+	p := m.p.Load()
+	if p == nil {
+		return nil
+	}
+	names := make([]string, 0, len(*p))
+	for name := range *p {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Dict returns the methods as a Dict.
+func (m *TypeMethods) Dict() Dict {
+	p := m.p.Load()
+	if p == nil {
+		return nil
+	}
+	d := make(Dict, len(*p))
+	for name, fn := range *p {
+		d[name] = fn
+	}
+	return d
+}
+
+// TypeMethodOf returns the method registered with the name to the type of o
+// bound to o, or nil if there is none.
+func TypeMethodOf(o Object, name string) CallerObject {
+	t, _ := o.Type().(TypeMethodsGetter)
+	if t == nil {
+		return nil
+	}
+	fn := t.TypeMethods().Get(name)
+	if fn == nil {
+		return nil
+	}
+	return &Function{
+		Name: name,
+		ToStr: func() string {
+			return "method of " + t.Name() + "#" + name
+		},
+		Value: func(c Call) (Object, error) {
+			c.Args = append(Args{Array{o}}, c.Args...)
+			return YieldCall(fn, &c), nil
+		},
+	}
+}
@@ -91,6 +91,7 @@ const (
 	OpTextWriter
 	OpIsNil
 	OpNotIsNil
+	OpModuleHooks
 )
 
 // OpcodeNames are string representation of opcodes.
@@ -157,6 +158,7 @@ var OpcodeNames = [...]string{
 	OpNamedArgs:     "NAMEDARGS",
 	OpIsNil:         "ISNIL",
 	OpNotIsNil:      "NOTISNIL",
+	OpModuleHooks:   "MODULEHOOKS",
 }
 
 // OpcodeOperands is the number of operands.
@@ -223,6 +225,7 @@ var OpcodeOperands = [...][]int{
 	OpArgs:          {},
 	OpNamedArgs:     {},
 	OpKeyValue:      {1}, // 0: whitout value, 1: with value
+	OpModuleHooks:   {},
 }
 
 // ReadOperands reads operands from the bytecode. Given operands slice is used to
@@ -91,10 +91,8 @@ func NewOptimizer(
 		disabled = append(disabled, base.ShadowedBuiltins()...)
 	}
 
-	var trace io.Writer
-	if opts.TraceOptimizer {
-		trace = opts.Trace
-	}
+	trace := opts.passTrace(PassConstFold)
+	enabled := opts.passEnabled(PassConstFold)
 
 	var builtins *Builtins
 	if opts.SymbolTable != nil {
@@ -105,8 +103,8 @@ func NewOptimizer(
 		file:             file,
 		vm:               NewVM(nil).SetRecover(true),
 		maxCycle:         opts.OptimizerMaxCycle,
-		optimConsts:      opts.OptimizeConst,
-		optimExpr:        opts.OptimizeExpr,
+		optimConsts:      enabled && opts.OptimizeConst,
+		optimExpr:        enabled && opts.OptimizeExpr,
 		disabledBuiltins: disabled,
 		moduleStore:      newModuleStore(),
 		trace:            trace,
@@ -967,6 +965,10 @@ func (m multipleErr) Errors() []error {
 	return m
 }
 
+func (m multipleErr) Unwrap() []error {
+	return m
+}
+
 func (m multipleErr) Error() string {
 	if len(m) == 0 {
 		return ""
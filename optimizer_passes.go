@@ -0,0 +1,452 @@
+package gad
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/gad-lang/gad/parser/node"
+)
+
+// Names of the optimizer passes which can be selected by
+// CompilerOptions.OptimizerPasses.
+const (
+	// PassConstFold evaluates constant expressions in the AST. What is
+	// evaluated is selected by OptimizeConst and OptimizeExpr options.
+	PassConstFold = "constfold"
+	// PassInline replaces the references to constants declared with literal
+	// values by the values.
+	PassInline = "inline"
+	// PassEscape passes the values of the locals captured by closures in
+	// functions instead of promoting them to ObjectPtr cells if they are not
+	// assigned after their definitions.
+	PassEscape = "escape"
+	// PassDCE removes the instructions which are not reachable.
+	PassDCE = "dce"
+	// PassPeephole removes jumps to the next instruction, threads jumps to
+	// jumps and removes values pushed and popped immediately.
+	PassPeephole = "peephole"
+)
+
+// OptimizerPassNames are the names of the optimizer passes in the order they
+// run.
+var OptimizerPassNames = []string{PassConstFold, PassInline, PassEscape, PassDCE, PassPeephole}
+
+// ParseOptimizerPasses returns the pass names in comma separated list s. It
+// returns an error if a name is not a known pass. Empty s returns an empty
+// non-nil slice which disables all passes.
+func ParseOptimizerPasses(s string) ([]string, error) {
+	passes := []string{}
+	for _, name := range strings.Split(s, ",") {
+		if name = strings.TrimSpace(name); name == "" {
+			continue
+		}
+		passes = append(passes, name)
+	}
+	if err := checkOptimizerPasses(passes); err != nil {
+		return nil, err
+	}
+	return passes, nil
+}
+
+func checkOptimizerPasses(passes []string) error {
+	for _, name := range passes {
+		if !isOptimizerPass(name) {
+			return fmt.Errorf("unknown optimizer pass %q, valid passes are %s",
+				name, strings.Join(OptimizerPassNames, ", "))
+		}
+	}
+	return nil
+}
+
+func isOptimizerPass(name string) bool {
+	for _, pass := range OptimizerPassNames {
+		if pass == name {
+			return true
+		}
+	}
+	return false
+}
+
+// passEnabled reports whether the optimizer pass is enabled. If
+// OptimizerPasses is nil, only constfold pass is enabled if OptimizeConst or
+// OptimizeExpr is set.
+func (o *CompilerOptions) passEnabled(name string) bool {
+	if name == PassConstFold && !o.OptimizeConst && !o.OptimizeExpr {
+		return false
+	}
+	if o.OptimizerPasses == nil {
+		return name == PassConstFold
+	}
+	for _, pass := range o.OptimizerPasses {
+		if pass == name {
+			return true
+		}
+	}
+	return false
+}
+
+// passTrace returns the writer to print the trace output of the pass or nil
+// if it is not traced.
+func (o *CompilerOptions) passTrace(name string) io.Writer {
+	if !o.TraceOptimizer || o.Trace == nil {
+		return nil
+	}
+	if len(o.TracePasses) == 0 {
+		return o.Trace
+	}
+	for _, pass := range o.TracePasses {
+		if pass == name {
+			return o.Trace
+		}
+	}
+	return nil
+}
+
+// literalValue returns the value of a literal which can be inlined.
+func literalValue(expr node.Expr) (Object, bool) {
+	switch v := expr.(type) {
+	case *node.IntLit:
+		return Int(v.Value), true
+	case *node.UintLit:
+		return Uint(v.Value), true
+	case *node.FloatLit:
+		return Float(v.Value), true
+	case *node.DecimalLit:
+		return Decimal(v.Value), true
+	case *node.CharLit:
+		return Char(v.Value), true
+	case *node.StringLit:
+		return Str(v.Value), true
+	case *node.RawStringLit:
+		return RawStr(v.UnquotedValue()), true
+	case *node.BoolLit:
+		return Bool(v.Value), true
+	case *node.FlagLit:
+		return Flag(v.Value), true
+	case *node.NilLit:
+		return Nil, true
+	}
+	return nil, false
+}
+
+// emitValue emits the instruction pushing the inlined value.
+func (c *Compiler) emitValue(nd node.Expr, value Object) {
+	switch value {
+	case Nil:
+		c.emit(nd, OpNil)
+	case True:
+		c.emit(nd, OpTrue)
+	case False:
+		c.emit(nd, OpFalse)
+	case Yes:
+		c.emit(nd, OpYes)
+	case No:
+		c.emit(nd, OpNo)
+	default:
+		c.emit(nd, OpConstant, c.addConstant(value))
+	}
+}
+
+// localCapture is an OpGetLocalPtr instruction emitted to capture a local by
+// a closure.
+type localCapture struct {
+	pos    int
+	symbol *Symbol
+}
+
+// captureByValue changes the instructions capturing the locals which are
+// never assigned after their definitions to push their values. OpClosure
+// creates a new cell for each value, so the locals are not promoted to
+// ObjectPtr cells. It must be called after the function is compiled because
+// the locals may be assigned after they are captured. Main functions are not
+// analyzed because their locals may be assigned by the scripts compiled later
+// with the same symbol table like in REPL.
+func (c *Compiler) captureByValue() {
+	if !c.opts.passEnabled(PassEscape) {
+		return
+	}
+	for _, capture := range c.captures {
+		if capture.symbol.mutated {
+			continue
+		}
+		c.instructions[capture.pos] = byte(OpGetLocal)
+		if w := c.opts.passTrace(PassEscape); w != nil {
+			_, _ = fmt.Fprintf(w, "%s: %s\n", PassEscape, capture.symbol.Name)
+		}
+	}
+}
+
+// passInst is a decoded instruction. Jump operands are the indexes of the
+// target instructions, -1 for no target of OpSetupTry.
+type passInst struct {
+	op       Opcode
+	operands []int
+	pos      int
+	dead     bool
+}
+
+// runBytecodePasses runs the enabled bytecode passes on the functions compiled
+// after the first numConsts constants.
+func runBytecodePasses(bc *Bytecode, numConsts int, opts *CompilerOptions) {
+	dce, peephole := opts.passEnabled(PassDCE), opts.passEnabled(PassPeephole)
+	if !dce && !peephole {
+		return
+	}
+
+	seen := make(map[*CompiledFunction]bool)
+	process := func(fn *CompiledFunction, name string) {
+		if fn == nil || seen[fn] || len(fn.Instructions) == 0 {
+			return
+		}
+		seen[fn] = true
+
+		insts := decodeInsts(fn.Instructions)
+		if dce {
+			removed := eliminateDeadCode(insts)
+			if w := opts.passTrace(PassDCE); w != nil && removed > 0 {
+				_, _ = fmt.Fprintf(w, "%s: %s: removed %d of %d instructions\n",
+					PassDCE, name, removed, len(insts))
+			}
+		}
+		if peephole {
+			removed, threaded := peepholeInsts(insts)
+			if w := opts.passTrace(PassPeephole); w != nil && removed+threaded > 0 {
+				_, _ = fmt.Fprintf(w, "%s: %s: removed %d, threaded %d of %d instructions\n",
+					PassPeephole, name, removed, threaded, len(insts))
+			}
+		}
+		encodeInsts(fn, insts)
+	}
+
+	process(bc.Main, "main")
+	for i := numConsts; i < len(bc.Constants); i++ {
+		var fn *CompiledFunction
+		switch t := bc.Constants[i].(type) {
+		case *CompiledFunction:
+			fn = t
+		case *CallerObjectWithMethods:
+			fn, _ = t.CallerObject.(*CompiledFunction)
+		}
+		if fn != nil {
+			name := fn.Name
+			if name == "" {
+				name = "<anonymous>"
+			}
+			process(fn, fmt.Sprintf("%s [constant %d]", name, i))
+		}
+	}
+}
+
+func decodeInsts(b []byte) []*passInst {
+	var (
+		insts   []*passInst
+		index   = make(map[int]int)
+		offset  int
+		scratch []int
+	)
+	for ip := 0; ip < len(b); ip += offset + 1 {
+		op := Opcode(b[ip])
+		scratch, offset = ReadOperands(OpcodeOperands[op], b[ip+1:], scratch)
+		index[ip] = len(insts)
+		insts = append(insts, &passInst{
+			op:       op,
+			operands: append([]int(nil), scratch...),
+			pos:      ip,
+		})
+	}
+	index[len(b)] = len(insts)
+
+	for _, inst := range insts {
+		for i, v := range inst.operands {
+			if !isJumpOperand(inst.op, i) {
+				continue
+			}
+			if inst.op == OpSetupTry && v == 0 {
+				inst.operands[i] = -1
+			} else {
+				inst.operands[i] = index[v]
+			}
+		}
+	}
+	return insts
+}
+
+// encodeInsts sets the instructions and the source map of fn to the alive
+// instructions. Jumps to dead instructions jump to the next alive one.
+func encodeInsts(fn *CompiledFunction, insts []*passInst) {
+	// positions of alive instructions, dead ones take the next position
+	positions := make([]int, len(insts)+1)
+	pos := 0
+	for i, inst := range insts {
+		positions[i] = pos
+		if !inst.dead {
+			pos++
+			for _, w := range OpcodeOperands[inst.op] {
+				pos += w
+			}
+		}
+	}
+	positions[len(insts)] = pos
+
+	var (
+		out       = make([]byte, 0, pos)
+		sourceMap = make(map[int]int, len(fn.SourceMap))
+		operands  []int
+		buf       = make([]byte, 0, 8)
+	)
+	for i, inst := range insts {
+		if inst.dead {
+			continue
+		}
+		operands = append(operands[:0], inst.operands...)
+		for j, v := range operands {
+			if !isJumpOperand(inst.op, j) {
+				continue
+			}
+			if v < 0 {
+				operands[j] = 0
+			} else {
+				operands[j] = positions[v]
+			}
+		}
+		if p, ok := fn.SourceMap[inst.pos]; ok {
+			sourceMap[positions[i]] = p
+		}
+		var err error
+		if buf, err = MakeInstruction(buf, inst.op, operands...); err != nil {
+			panic(err)
+		}
+		out = append(out, buf...)
+	}
+	fn.Instructions = out
+	fn.SourceMap = sourceMap
+}
+
+// eliminateDeadCode marks the unreachable instructions dead and returns the
+// number of them. The last instruction is kept to end the function with a
+// return.
+func eliminateDeadCode(insts []*passInst) int {
+	if len(insts) == 0 {
+		return 0
+	}
+	reachable := make([]bool, len(insts))
+	work := []int{0}
+	flow := func(to int) {
+		if to >= 0 && to < len(insts) && !reachable[to] {
+			reachable[to] = true
+			work = append(work, to)
+		}
+	}
+	reachable[0] = true
+
+	for len(work) > 0 {
+		i := work[len(work)-1]
+		work = work[:len(work)-1]
+
+		inst := insts[i]
+		switch inst.op {
+		case OpReturn:
+			continue
+		case OpThrow:
+			if inst.operands[0] == 1 {
+				continue
+			}
+		case OpJump:
+			flow(inst.operands[0])
+			continue
+		case OpJumpFalsy, OpJumpNil, OpAndJump, OpOrJump, OpJumpNotNil:
+			flow(inst.operands[0])
+		case OpIterNextElse:
+			flow(inst.operands[0])
+			flow(inst.operands[1])
+			continue
+		case OpSetupTry:
+			flow(inst.operands[0])
+			flow(inst.operands[1])
+		}
+		flow(i + 1)
+	}
+
+	var removed int
+	for i := 0; i < len(insts)-1; i++ {
+		if !reachable[i] && !insts[i].dead {
+			insts[i].dead = true
+			removed++
+		}
+	}
+	return removed
+}
+
+// peepholeInsts threads jumps to unconditional jumps, removes jumps to the
+// next instruction and values popped right after they are pushed. It returns
+// the number of removed instructions and threaded jumps.
+func peepholeInsts(insts []*passInst) (removed, threaded int) {
+	// alive returns the index of the first alive instruction at or after i.
+	alive := func(i int) int {
+		for i < len(insts) && insts[i].dead {
+			i++
+		}
+		return i
+	}
+
+	for _, inst := range insts {
+		if inst.dead {
+			continue
+		}
+		switch inst.op {
+		case OpJump, OpJumpFalsy, OpJumpNil, OpAndJump, OpOrJump, OpJumpNotNil:
+		default:
+			continue
+		}
+		target := alive(inst.operands[0])
+		for hops := 0; hops < len(insts) && target < len(insts) &&
+			insts[target].op == OpJump; hops++ {
+			target = alive(insts[target].operands[0])
+		}
+		if target != alive(inst.operands[0]) {
+			inst.operands[0] = target
+			threaded++
+		}
+	}
+
+	last := len(insts) - 1
+	for i, inst := range insts {
+		if i < last && !inst.dead && inst.op == OpJump && alive(inst.operands[0]) == alive(i+1) {
+			inst.dead = true
+			removed++
+		}
+	}
+
+	targets := make(map[int]bool)
+	for _, inst := range insts {
+		if inst.dead {
+			continue
+		}
+		for i, v := range inst.operands {
+			if isJumpOperand(inst.op, i) && v >= 0 {
+				targets[alive(v)] = true
+			}
+		}
+	}
+	for i, inst := range insts {
+		if inst.dead {
+			continue
+		}
+		switch inst.op {
+		case OpConstant, OpNil, OpTrue, OpFalse, OpYes, OpNo, OpGetLocal, OpGetFree:
+		default:
+			continue
+		}
+		// the value of the last expression statement is kept for Eval which
+		// returns it instead of popping
+		next := alive(i + 1)
+		if next < last && insts[next].op == OpPop && !targets[next] &&
+			alive(next+1) != last {
+			inst.dead = true
+			insts[next].dead = true
+			removed += 2
+		}
+	}
+	return
+}
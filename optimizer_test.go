@@ -1,7 +1,9 @@
 package gad_test
 
 import (
+	"bytes"
 	"fmt"
+	"math/rand"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -747,6 +749,198 @@ func TestOptimizerError(t *testing.T) {
 	}
 }
 
+func TestOptimizerPasses(t *testing.T) {
+	withPasses := func(passes ...string) CompileOptions {
+		opts := DefaultCompileOptions
+		opts.OptimizerPasses = passes
+		return opts
+	}
+
+	expectCompileWithOpts(t, `const x = 1; return x`, withPasses(PassInline),
+		bytecode(
+			Array{Int(1)},
+			compFunc(concatInsts(
+				makeInst(OpConstant, 0),
+				makeInst(OpDefineLocal, 0),
+				makeInst(OpConstant, 0),
+				makeInst(OpReturn, 1),
+			),
+				withLocals(1),
+			),
+		))
+	// inlined constants are not captured by closures
+	expectCompileWithOpts(t, `const x = "a"; return func() { return x }`, withPasses(PassInline),
+		bytecode(
+			Array{
+				Str("a"),
+				compFunc(concatInsts(
+					makeInst(OpConstant, 0),
+					makeInst(OpReturn, 1),
+				)),
+			},
+			compFunc(concatInsts(
+				makeInst(OpConstant, 0),
+				makeInst(OpDefineLocal, 0),
+				makeInst(OpConstant, 1),
+				makeInst(OpReturn, 1),
+			),
+				withLocals(1),
+			),
+		))
+	// locals which are not assigned after definition are captured by value
+	expectCompileWithOpts(t, `return func(a) { b := 1; c := 2; c = 3; return func() { return a + b + c } }`,
+		withPasses(PassEscape),
+		bytecode(
+			Array{
+				Int(1),
+				Int(2),
+				Int(3),
+				compFunc(concatInsts(
+					makeInst(OpGetFree, 0),
+					makeInst(OpGetFree, 1),
+					makeInst(OpBinaryOp, int(token.Add)),
+					makeInst(OpGetFree, 2),
+					makeInst(OpBinaryOp, int(token.Add)),
+					makeInst(OpReturn, 1),
+				)),
+				compFunc(concatInsts(
+					makeInst(OpConstant, 0),
+					makeInst(OpDefineLocal, 1),
+					makeInst(OpConstant, 1),
+					makeInst(OpDefineLocal, 2),
+					makeInst(OpConstant, 2),
+					makeInst(OpSetLocal, 2),
+					makeInst(OpGetLocal, 0),
+					makeInst(OpGetLocal, 1),
+					makeInst(OpGetLocalPtr, 2),
+					makeInst(OpClosure, 3, 3),
+					makeInst(OpReturn, 1),
+				),
+					withParams("a"),
+					withLocals(3),
+				),
+			},
+			compFunc(concatInsts(
+				makeInst(OpConstant, 4),
+				makeInst(OpReturn, 1),
+			)),
+		))
+	expectCompileWithOpts(t, `for { return 1 }`, withPasses(PassDCE),
+		bytecode(
+			Array{Int(1)},
+			compFunc(concatInsts(
+				makeInst(OpConstant, 0),
+				makeInst(OpReturn, 1),
+				makeInst(OpReturn, 0),
+			)),
+		))
+	expectCompileWithOpts(t, `a := 1; a; return a`, withPasses(PassPeephole),
+		bytecode(
+			Array{Int(1)},
+			compFunc(concatInsts(
+				makeInst(OpConstant, 0),
+				makeInst(OpDefineLocal, 0),
+				makeInst(OpGetLocal, 0),
+				makeInst(OpReturn, 1),
+			),
+				withLocals(1),
+			),
+		))
+	// value of the last expression is kept for Eval
+	expectCompileWithOpts(t, `a := 1; a`, withPasses(PassPeephole),
+		bytecode(
+			Array{Int(1)},
+			compFunc(concatInsts(
+				makeInst(OpConstant, 0),
+				makeInst(OpDefineLocal, 0),
+				makeInst(OpGetLocal, 0),
+				makeInst(OpPop),
+				makeInst(OpReturn, 0),
+			),
+				withLocals(1),
+			),
+		))
+	expectCompileWithOpts(t, `a := 1; for { break }; return a`, withPasses(PassDCE, PassPeephole),
+		bytecode(
+			Array{Int(1)},
+			compFunc(concatInsts(
+				makeInst(OpConstant, 0),
+				makeInst(OpDefineLocal, 0),
+				makeInst(OpGetLocal, 0),
+				makeInst(OpReturn, 1),
+				makeInst(OpReturn, 0),
+			),
+				withLocals(1),
+			),
+		))
+	// no pass is run if the list is empty
+	expectCompileWithOpts(t, `return 1 + 2`, withPasses([]string{}...),
+		bytecode(
+			Array{Int(1), Int(2)},
+			compFunc(concatInsts(
+				makeInst(OpConstant, 0),
+				makeInst(OpConstant, 1),
+				makeInst(OpBinaryOp, int(token.Add)),
+				makeInst(OpReturn, 1),
+			)),
+		))
+
+	expectCompileErrorWithOpts(t, `return 1`, withPasses("foo"),
+		`unknown optimizer pass "foo", valid passes are constfold, inline, escape, dce, peephole`)
+
+	passes, err := ParseOptimizerPasses(" dce, peephole,")
+	require.NoError(t, err)
+	require.Equal(t, []string{PassDCE, PassPeephole}, passes)
+	passes, err = ParseOptimizerPasses("")
+	require.NoError(t, err)
+	require.NotNil(t, passes)
+	require.Empty(t, passes)
+	_, err = ParseOptimizerPasses("dce,foo")
+	require.Error(t, err)
+
+	// trace output is limited to the selected passes
+	var buf bytes.Buffer
+	opts := withPasses(OptimizerPassNames...)
+	opts.Trace = &buf
+	opts.TraceOptimizer = true
+	opts.TracePasses = []string{PassInline}
+	_, err = Compile([]byte(`const x = 1; for { return x }`), opts)
+	require.NoError(t, err)
+	require.Equal(t, "inline: x: 1\n", buf.String())
+
+	// locals assigned by closures and main function locals are not captured
+	// by value
+	for _, script := range []string{
+		`f := func() { a := 1; g := func() { a = 2 }; g(); return a }; return f()`,
+		`f := func() { a := 1; g := func() { return a }; a = 2; return g() }; return f()`,
+		`f := func() { a := 1; g := func() { return func() { a = 2 } }; g()(); return a }; return f()`,
+		`a := 1; g := func() { return a }; a = 2; return g()`,
+	} {
+		bc, err := Compile([]byte(script), withPasses(OptimizerPassNames...))
+		require.NoError(t, err)
+		ret, err := NewVM(bc).Run()
+		require.NoError(t, err, script)
+		require.Equal(t, Int(2), ret, script)
+	}
+
+	bc, err := Compile([]byte(`
+	const n = 3
+	total := 0
+	for i := 0; i < n; i++ {
+		try {
+			if i == 1 { continue }
+			total += i
+		} finally {
+			total += 10
+		}
+	}
+	return total`), withPasses(OptimizerPassNames...))
+	require.NoError(t, err)
+	ret, err := NewVM(bc).Run()
+	require.NoError(t, err)
+	require.Equal(t, Int(32), ret)
+}
+
 func expectEval(t *testing.T, script string, expected *Bytecode) {
 	t.Helper()
 	opts := DefaultCompileOptions
@@ -764,3 +958,22 @@ func expectEvalError(t *testing.T, script, errStr string) {
 	opts.OptimizerMaxCycle = 1<<8 - 1
 	expectCompileErrorWithOpts(t, script, opts, errStr)
 }
+
+func TestOptimizerRandomConstants(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 100; i++ {
+		x, y := r.Int63n(2000)-1000, r.Int63n(2000)-1000
+		if y == 0 {
+			y = 1
+		}
+		script := fmt.Sprintf(`
+		x := %[1]s; y := %[2]s
+		println(%[1]s + %[2]s, %[1]s - %[2]s)
+		return [%[1]s * %[2]s, %[1]s / %[2]s, %[1]s %% %[2]s, %[1]s < %[2]s, %[1]s == %[2]s,
+			-%[1]s, !%[1]s, %[1]s & %[2]s, %[1]s | %[2]s, %[1]s ^ %[2]s, float(%[1]s) / %[2]s,
+			str(%[1]s) + "-" + %[2]s, x + y, x > 0 ? %[2]s : -%[2]s]`,
+			fmt.Sprintf("(%d)", x), fmt.Sprintf("(%d)", y))
+		TestOptimizerEquivalence(t, script, nil)
+		TestParseRoundTrip(t, script)
+	}
+}
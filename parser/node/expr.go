@@ -582,6 +582,7 @@ type FuncType struct {
 	FuncPos      source.Pos
 	Ident        *Ident
 	Params       FuncParams
+	ReturnType   []*Ident
 	AllowMethods bool
 }
 
@@ -594,6 +595,9 @@ func (e *FuncType) Pos() source.Pos {
 
 // End returns the position of first character immediately after the node.
 func (e *FuncType) End() source.Pos {
+	if l := len(e.ReturnType); l > 0 {
+		return e.ReturnType[l-1].End()
+	}
 	return e.Params.End()
 }
 
@@ -603,7 +607,15 @@ func (e *FuncType) String() string {
 		s += " "
 		s += e.Ident.String()
 	}
-	return s + e.Params.String()
+	s += e.Params.String()
+	if l := len(e.ReturnType); l > 0 {
+		var types = make([]string, l)
+		for i, ident := range e.ReturnType {
+			types[i] = ident.String()
+		}
+		s += " -> " + strings.Join(types, "|")
+	}
+	return s
 }
 
 // Ident represents an identifier.
@@ -14,6 +14,7 @@ package node
 
 import (
 	"fmt"
+	"path/filepath"
 	"strings"
 
 	"github.com/gad-lang/gad/parser/ast"
@@ -740,6 +741,8 @@ type ConfigOptions struct {
 	NoMixed        bool
 	WriteFunc      Expr
 	ExprToTextFunc Expr
+	// ImportPaths are the module search paths set by `path` key.
+	ImportPaths []string
 }
 
 type ConfigStmt struct {
@@ -794,6 +797,13 @@ func (c *ConfigStmt) ParseElements() {
 			if k.Value != nil {
 				c.Options.ExprToTextFunc = k.Value
 			}
+		case "path":
+			switch v := k.Value.(type) {
+			case *StringLit:
+				c.Options.ImportPaths = append(c.Options.ImportPaths, filepath.SplitList(v.Value)...)
+			case *RawStringLit:
+				c.Options.ImportPaths = append(c.Options.ImportPaths, filepath.SplitList(v.UnquotedValue())...)
+			}
 		}
 	}
 }
@@ -31,9 +31,10 @@ type (
 
 	// A ValueSpec node represents a variable declaration
 	ValueSpec struct {
-		Idents []*Ident // TODO: slice is reserved for tuple assignment
-		Values []Expr   // initial values; or nil
-		Data   any      // iota
+		Idents []*Ident   // TODO: slice is reserved for tuple assignment
+		Types  [][]*Ident // declared types; or nil
+		Values []Expr     // initial values; or nil
+		Data   any        // iota
 	}
 
 	// A ParamSpec node represents a parameter declaration
@@ -120,13 +121,22 @@ func (s *NamedParamSpec) WriteCode(ctx *CodeWriterContext) (err error) {
 	return WriteCode(ctx, s.Value)
 }
 
+// TypedIdent returns the i-th identifier with its declared types.
+func (s *ValueSpec) TypedIdent(i int) *TypedIdent {
+	ti := &TypedIdent{Ident: s.Idents[i]}
+	if i < len(s.Types) {
+		ti.Type = s.Types[i]
+	}
+	return ti
+}
+
 func (s *ValueSpec) String() string {
 	vals := make([]string, 0, len(s.Idents))
 	for i := range s.Idents {
 		if s.Values[i] != nil {
-			vals = append(vals, fmt.Sprintf("%s = %v", s.Idents[i], s.Values[i]))
+			vals = append(vals, fmt.Sprintf("%s = %v", s.TypedIdent(i), s.Values[i]))
 		} else {
-			vals = append(vals, s.Idents[i].String())
+			vals = append(vals, s.TypedIdent(i).String())
 		}
 	}
 	return strings.Join(vals, ", ")
@@ -135,7 +145,7 @@ func (s *ValueSpec) String() string {
 func (s *ValueSpec) WriteCode(ctx *CodeWriterContext) (err error) {
 	last := len(s.Idents) - 1
 	for i := range s.Idents {
-		if err = WriteCode(ctx, s.Idents[i]); err != nil {
+		if err = WriteCode(ctx, s.TypedIdent(i)); err != nil {
 			return
 		}
 		if s.Values[i] != nil {
@@ -1333,11 +1333,21 @@ func (p *Parser) ParseFuncType(parseLambda bool) *node.FuncType {
 	}
 
 	params := p.ParseFuncParams(parseLambda)
+
+	var returnType []*node.Ident
+	if p.Token.Token == token.RArrow {
+		p.Next()
+		if returnType = p.ParseType(); returnType == nil {
+			p.ErrorExpected(p.Token.Pos, "return type")
+		}
+	}
+
 	return &node.FuncType{
 		Token:        tok,
 		FuncPos:      pos,
 		Ident:        ident,
 		Params:       *params,
+		ReturnType:   returnType,
 		AllowMethods: allowMethods,
 	}
 }
@@ -1882,9 +1892,14 @@ func (p *Parser) ParseValueSpec(keyword token.Token, multi bool, _ []node.Spec,
 	}
 	pos := p.Token.Pos
 	var idents []*node.Ident
+	var types [][]*node.Ident
 	var values []node.Expr
 	if p.Token.Token == token.Ident {
 		ident := p.ParseIdent()
+		var typ []*node.Ident
+		if keyword == token.Var {
+			typ = p.ParseType()
+		}
 		var expr node.Expr
 		if p.Token.Token == token.Assign {
 			p.Next()
@@ -1897,6 +1912,9 @@ func (p *Parser) ParseValueSpec(keyword token.Token, multi bool, _ []node.Spec,
 		}
 		idents = append(idents, ident)
 		values = append(values, expr)
+		if typ != nil {
+			types = append(types, typ)
+		}
 		if multi && p.Token.Token == token.Comma {
 			p.Next()
 		} else if multi {
@@ -1909,6 +1927,7 @@ func (p *Parser) ParseValueSpec(keyword token.Token, multi bool, _ []node.Spec,
 	}
 	spec := &node.ValueSpec{
 		Idents: idents,
+		Types:  types,
 		Values: values,
 		Data:   i,
 	}
@@ -2385,6 +2404,20 @@ func (p *Parser) ParseSimpleStmt(forIn bool) node.Stmt {
 
 	x := p.ParseExprList()
 
+	if p.Token.Token == token.Ident && p.Token.Literal == "as" && len(x) == 1 {
+		// import("name") as alias
+		if imp, ok := x[0].(*node.ImportExpr); ok {
+			pos := p.Token.Pos
+			p.Next()
+			return &node.AssignStmt{
+				LHS:      []node.Expr{p.ParseIdent()},
+				RHS:      []node.Expr{imp},
+				Token:    token.Define,
+				TokenPos: pos,
+			}
+		}
+	}
+
 	switch p.Token.Token {
 	case token.Assign, token.Define: // assignment statement
 		pos, tok := p.Token.Pos, p.Token.Token
@@ -583,6 +583,9 @@ b=2)`, func(p pfn) []Stmt {
 	expectParseString(t, "var (x=1,\ny)", "var (x = 1, y)")
 	expectParseString(t, "var (x,\ny = 2)", "var (x, y = 2)")
 	expectParseString(t, "var (x\ny)", "var (x, y)")
+	expectParseString(t, "var x int", "var x int")
+	expectParseString(t, "var x int|uint = 1", "var x int|uint = 1")
+	expectParseString(t, "var (x str,\ny = 2)", "var (x str, y = 2)")
 	expectParseString(t, `var (_, _a, $_a, a, A, $b, $, a1, $1, $b1, $$, ŝ, $ŝ)`,
 		`var (_, _a, $_a, a, A, $b, $, a1, $1, $b1, $$, ŝ, $ŝ)`)
 
@@ -1911,6 +1914,9 @@ func TestParseFunction(t *testing.T) {
 	})
 
 	expectParseString(t, "func(){}", "func() {}")
+	expectParseString(t, "func(a int) -> str {}", "func(a int) -> str {}")
+	expectParseString(t, "func f() -> int|str {}", "func f() -> int|str {}")
+	expectParseString(t, "func() -> int => 1", "func() -> int {return 1}")
 	expectParseString(t, "func(\n){}", "func() {}")
 	expectParseString(t, "func(a,){}", "func(a) {}")
 	expectParseString(t, "func(\na,\n){}", "func(a) {}")
@@ -2228,6 +2234,14 @@ func TestParseImport(t *testing.T) {
 				token.Define, p(1, 3)))
 	})
 
+	expectParse(t, `import("long/path/mod1") as a`, func(p pfn) []Stmt {
+		return stmts(
+			assignStmt(
+				exprs(ident("a", p(1, 29))),
+				exprs(importExpr("long/path/mod1", p(1, 1))),
+				token.Define, p(1, 26)))
+	})
+
 	expectParse(t, `import("mod1").var1`, func(p pfn) []Stmt {
 		return stmts(
 			exprStmt(
@@ -2873,6 +2887,11 @@ a
 		return stmts(
 			config(p(1, 1), kv(ident("mixed", p(1, 8)))))
 	})
+	expectParse(t, `# gad: path="./lib:./vendor"`, func(p pfn) []Stmt {
+		c := config(p(1, 1), kv(ident("path", p(1, 8)), stringLit("./lib:./vendor", p(1, 13))))
+		require.Equal(t, []string{"./lib", "./vendor"}, c.Options.ImportPaths)
+		return stmts(c)
+	})
 }
 
 func TestParseTryThrow(t *testing.T) {
@@ -626,8 +626,10 @@ do:
 				goto do
 			}
 
-			t.Token = s.Switch3(token.Sub, token.SubAssign, '-', token.Dec)
-			if t.Token == token.Dec {
+			if s.Ch == '>' {
+				s.Next()
+				t.Token = token.RArrow
+			} else if t.Token = s.Switch3(token.Sub, token.SubAssign, '-', token.Dec); t.Token == token.Dec {
 				insertSemi = true
 			}
 		case '*':
@@ -0,0 +1,203 @@
+package gad
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+)
+
+// PluginRegistry is an object injected into scripts by the host, so scripts
+// can register callbacks which are enumerated and invoked later by Go code:
+//
+//	reg := NewPluginRegistry()
+//	_, _ = NewVM(bc).RunOpts(&RunOpts{Globals: Dict{"registry": reg}})
+//	for _, h := range reg.Hooks("request.before") {
+//		_, err := h.Call(Str("/"))
+//	}
+//
+// In script, `registry.register("request.before", func(path) {...})` adds a
+// hook. It is safe to use concurrently.
+type PluginRegistry struct {
+	mu    sync.RWMutex
+	hooks map[string][]*PluginHook
+}
+
+// PluginHook is a callback registered to a PluginRegistry.
+type PluginHook struct {
+	Name string
+	Fn   Object
+	vm   *VM
+}
+
+var (
+	_ Object           = (*PluginRegistry)(nil)
+	_ NameCallerObject = (*PluginRegistry)(nil)
+)
+
+// NewPluginRegistry creates a new PluginRegistry.
+func NewPluginRegistry() *PluginRegistry {
+	return &PluginRegistry{hooks: make(map[string][]*PluginHook)}
+}
+
+func (r *PluginRegistry) Type() ObjectType {
+	return TPluginRegistry
+}
+
+func (r *PluginRegistry) ToString() string {
+	return ReprQuote("pluginRegistry")
+}
+
+func (r *PluginRegistry) IsFalsy() bool {
+	return false
+}
+
+func (r *PluginRegistry) Equal(right Object) bool {
+	v, ok := right.(*PluginRegistry)
+	return ok && v == r
+}
+
+// Register adds fn as a hook of name. Hook functions are invoked in the given
+// VM, so VM must not be reused for another Bytecode while hooks are in use.
+func (r *PluginRegistry) Register(vm *VM, name string, fn Object) *PluginHook {
+	h := &PluginHook{Name: name, Fn: fn, vm: vm}
+	r.mu.Lock()
+	r.hooks[name] = append(r.hooks[name], h)
+	r.mu.Unlock()
+	return h
+}
+
+// Unregister removes the hooks of name and returns the number of removed
+// hooks.
+func (r *PluginRegistry) Unregister(name string) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	n := len(r.hooks[name])
+	delete(r.hooks, name)
+	return n
+}
+
+// Names returns the sorted names having hooks.
+func (r *PluginRegistry) Names() []string {
+	r.mu.RLock()
+	names := make([]string, 0, len(r.hooks))
+	for name := range r.hooks {
+		names = append(names, name)
+	}
+	r.mu.RUnlock()
+	sort.Strings(names)
+	return names
+}
+
+// Hooks returns the hooks of name in registration order.
+func (r *PluginRegistry) Hooks(name string) []*PluginHook {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return append([]*PluginHook{}, r.hooks[name]...)
+}
+
+// Invoke calls all hooks of name with given arguments and returns their
+// results. The first error stops the invocation.
+func (r *PluginRegistry) Invoke(name string, args ...Object) (ret Array, err error) {
+	hooks := r.Hooks(name)
+	ret = make(Array, len(hooks))
+	for i, h := range hooks {
+		if ret[i], err = h.Call(args...); err != nil {
+			return nil, err
+		}
+	}
+	return
+}
+
+func (r *PluginRegistry) CallName(name string, c Call) (_ Object, err error) {
+	switch name {
+	case "register":
+		var (
+			hookName = &Arg{
+				Name:          "name",
+				TypeAssertion: TypeAssertionFromTypes(TStr, TRawStr),
+			}
+			fn = &Arg{
+				Name: "fn",
+				TypeAssertion: NewTypeAssertion(TypeAssertionHandlers{
+					"callable": Callable,
+				}),
+			}
+		)
+		if err = c.Args.Destructure(hookName, fn); err != nil {
+			return
+		}
+		r.Register(c.VM, hookName.Value.ToString(), fn.Value)
+		return Nil, nil
+	case "unregister":
+		if err = c.Args.CheckLen(1); err != nil {
+			return
+		}
+		return Int(r.Unregister(c.Args.GetOnly(0).ToString())), nil
+	case "names":
+		if err = c.Args.CheckLen(0); err != nil {
+			return
+		}
+		names := r.Names()
+		ret := make(Array, len(names))
+		for i, n := range names {
+			ret[i] = Str(n)
+		}
+		return ret, nil
+	case "has":
+		if err = c.Args.CheckLen(1); err != nil {
+			return
+		}
+		return Bool(len(r.Hooks(c.Args.GetOnly(0).ToString())) > 0), nil
+	}
+	return nil, ErrInvalidIndex.NewError(name)
+}
+
+// Call invokes the hook function with given arguments.
+func (h *PluginHook) Call(args ...Object) (Object, error) {
+	return NewInvoker(h.vm, h.Fn).Invoke(Args{args}, nil)
+}
+
+// CallGo converts Go arguments to Objects with ToObject and invokes the hook.
+func (h *PluginHook) CallGo(args ...any) (Object, error) {
+	objs := make(Array, len(args))
+	for i, arg := range args {
+		var err error
+		if objs[i], err = ToObject(arg); err != nil {
+			return nil, err
+		}
+	}
+	return h.Call(objs...)
+}
+
+// PluginFunc adapts the hook to a typed Go function. Arguments are converted
+// with ToObject and the result is converted to R with ToInterface if R is not
+// an Object type.
+func PluginFunc[R any](h *PluginHook) func(args ...any) (R, error) {
+	return func(args ...any) (r R, err error) {
+		var ret Object
+		if ret, err = h.CallGo(args...); err != nil {
+			return
+		}
+
+		if v, ok := ret.(R); ok {
+			return v, nil
+		}
+
+		iv := ToInterface(ret)
+		if v, ok := iv.(R); ok {
+			return v, nil
+		}
+
+		rt := reflect.TypeOf(&r).Elem()
+		if rv := reflect.ValueOf(iv); rv.IsValid() && rv.Type().ConvertibleTo(rt) {
+			return rv.Convert(rt).Interface().(R), nil
+		}
+		if iv == nil {
+			return
+		}
+		err = fmt.Errorf("plugin hook %q: cannot convert %s to %s",
+			h.Name, ret.Type().Name(), rt)
+		return
+	}
+}
@@ -0,0 +1,60 @@
+package gad_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	. "github.com/gad-lang/gad"
+)
+
+func TestPluginRegistry(t *testing.T) {
+	c, err := Compile([]byte(`
+	param registry
+	n := 0
+	registry.register("count", func() { n++; return n })
+	registry.register("greet", func(name) => "hello " + name)
+	registry.register("greet", func(name) => "hi " + name)
+	registry.register("fail", func() { throw "failed" })
+	registry.register("tmp", func() {})
+	return [registry.has("greet"), registry.has("none"), registry.unregister("tmp"), registry.names()]
+	`), CompileOptions{})
+	require.NoError(t, err)
+
+	reg := NewPluginRegistry()
+	ret, err := NewVM(c).Run(reg)
+	require.NoError(t, err)
+	require.Equal(t, Array{True, False, Int(1), Array{Str("count"), Str("fail"), Str("greet")}}, ret)
+	require.Equal(t, []string{"count", "fail", "greet"}, reg.Names())
+
+	greets, err := reg.Invoke("greet", Str("gad"))
+	require.NoError(t, err)
+	require.Equal(t, Array{Str("hello gad"), Str("hi gad")}, greets)
+
+	count := PluginFunc[int](reg.Hooks("count")[0])
+	for i := 1; i <= 3; i++ {
+		n, err := count()
+		require.NoError(t, err)
+		require.Equal(t, i, n)
+	}
+
+	greet := PluginFunc[string](reg.Hooks("greet")[1])
+	s, err := greet("go")
+	require.NoError(t, err)
+	require.Equal(t, "hi go", s)
+
+	obj, err := PluginFunc[Object](reg.Hooks("greet")[0])("x")
+	require.NoError(t, err)
+	require.Equal(t, Str("hello x"), obj)
+
+	_, err = PluginFunc[int](reg.Hooks("greet")[0])("x")
+	require.EqualError(t, err, `plugin hook "greet": cannot convert str to int`)
+
+	_, err = reg.Invoke("fail")
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "failed")
+
+	ret, err = reg.Invoke("none")
+	require.NoError(t, err)
+	require.Empty(t, ret)
+}
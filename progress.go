@@ -0,0 +1,264 @@
+package gad
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	progressBarWidth = 30
+	// progressRedraw is the minimum interval of redrawing a spinner.
+	progressRedraw = 100 * time.Millisecond
+)
+
+var progressFrames = []string{"|", "/", "-", "\\"}
+
+// Progress reports the progress of a long-running script. It renders a bar,
+// or a spinner if the total is unknown, which is redrawn in place on a
+// terminal. Other writers get a line on every 10 percent, or only the final
+// line if the total is unknown, to keep the logs readable. It is safe to use
+// concurrently.
+type Progress struct {
+	mu      sync.Mutex
+	w       io.Writer
+	label   string
+	total   int
+	current int
+	term    bool
+	frame   int
+	drawn   time.Time
+	step    int
+	last    string
+	done    bool
+}
+
+var (
+	_ Object           = (*Progress)(nil)
+	_ NameCallerObject = (*Progress)(nil)
+	_ IndexGetter      = (*Progress)(nil)
+)
+
+// NewProgress creates a new Progress of total steps rendering to w. If total
+// is not positive, a spinner is rendered.
+func NewProgress(w io.Writer, total int, label string) *Progress {
+	return &Progress{w: w, total: total, label: label, term: isTerminal(w)}
+}
+
+func (p *Progress) Type() ObjectType {
+	return TProgress
+}
+
+func (p *Progress) ToString() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return ReprQuote("progress " + p.count())
+}
+
+func (p *Progress) IsFalsy() bool {
+	return false
+}
+
+func (p *Progress) Equal(right Object) bool {
+	v, ok := right.(*Progress)
+	return ok && v == p
+}
+
+// IndexGet implements IndexGetter interface.
+func (p *Progress) IndexGet(_ *VM, index Object) (Object, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	switch index.ToString() {
+	case "total":
+		return Int(p.total), nil
+	case "current":
+		return Int(p.current), nil
+	case "label":
+		return Str(p.label), nil
+	}
+	return nil, ErrInvalidIndex.NewError(index.ToString())
+}
+
+// CallName implements NameCallerObject interface.
+func (p *Progress) CallName(name string, c Call) (_ Object, err error) {
+	switch name {
+	case "inc":
+		if err = c.Args.CheckRangeLen(0, 1); err != nil {
+			return
+		}
+		n := 1
+		if c.Args.Length() == 1 {
+			v, ok := c.Args.GetOnly(0).(Int)
+			if !ok {
+				return nil, NewArgumentTypeError("1st", "int", c.Args.GetOnly(0).Type().Name())
+			}
+			n = int(v)
+		}
+		return p, p.Inc(n)
+	case "done":
+		if err = c.Args.CheckLen(0); err != nil {
+			return
+		}
+		return p, p.Done()
+	}
+	return nil, ErrInvalidIndex.NewError(name)
+}
+
+// Inc advances the progress by n steps and renders it.
+func (p *Progress) Inc(n int) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.done {
+		return nil
+	}
+	p.current += n
+
+	switch {
+	case p.term:
+		if p.total <= 0 && time.Since(p.drawn) < progressRedraw {
+			return nil
+		}
+		p.frame++
+		return p.render("\r" + p.line())
+	case p.total > 0:
+		if step := p.percent() / 10; step > p.step {
+			p.step = step
+			return p.render(p.line() + "\n")
+		}
+	}
+	return nil
+}
+
+// Done renders the final state of the progress, further steps are ignored.
+func (p *Progress) Done() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.done {
+		return nil
+	}
+	p.done = true
+
+	if p.term {
+		return p.render("\r" + p.line() + "\n")
+	}
+	if line := p.line() + "\n"; line != p.last {
+		return p.render(line)
+	}
+	return nil
+}
+
+func (p *Progress) render(s string) error {
+	p.drawn = time.Now()
+	p.last = s
+	_, err := io.WriteString(p.w, s)
+	return err
+}
+
+func (p *Progress) percent() int {
+	if p.current >= p.total {
+		return 100
+	}
+	return p.current * 100 / p.total
+}
+
+func (p *Progress) count() string {
+	if p.total <= 0 {
+		return strconv.Itoa(p.current)
+	}
+	return strconv.Itoa(p.current) + "/" + strconv.Itoa(p.total)
+}
+
+func (p *Progress) line() string {
+	var b strings.Builder
+	if p.label != "" {
+		b.WriteString(p.label)
+		b.WriteByte(' ')
+	}
+	switch {
+	case p.total > 0 && p.term:
+		filled := p.percent() * progressBarWidth / 100
+		b.WriteString("[" + strings.Repeat("=", filled) + strings.Repeat(" ", progressBarWidth-filled) + "] ")
+	case p.term && !p.done:
+		b.WriteString(progressFrames[p.frame%len(progressFrames)] + " ")
+	}
+	b.WriteString(p.count())
+	if p.total > 0 {
+		b.WriteString(fmt.Sprintf(" %3d%%", p.percent()))
+	}
+	if p.term {
+		// clears the rest of the previous line
+		b.WriteString("\x1b[K")
+	}
+	return b.String()
+}
+
+// isTerminal reports whether w writes to a terminal.
+func isTerminal(w io.Writer) bool {
+	// unwraps the writer objects like stdout, a few levels are enough
+	for i := 0; i < 4; i++ {
+		switch t := w.(type) {
+		case *os.File:
+			fi, err := t.Stat()
+			return err == nil && fi.Mode()&os.ModeCharDevice != 0
+		case Writer:
+			w = t.GoWriter()
+		default:
+			return false
+		}
+	}
+	return false
+}
+
+type progressIterator struct {
+	Iterator
+	p *Progress
+}
+
+// ProgressIterator returns an iterator which yields the entries of it and
+// advances p by every entry. p is done when the iteration is done.
+func ProgressIterator(it Iterator, p *Progress) Iterator {
+	return &progressIterator{Iterator: it, p: p}
+}
+
+func (it *progressIterator) Type() ObjectType {
+	return TProgressIterator
+}
+
+func (it *progressIterator) Repr(vm *VM) (s string, err error) {
+	if s, err = it.Iterator.Repr(vm); err != nil {
+		return
+	}
+	return ToReprTypedRS(vm, it.Type(), s)
+}
+
+func (it *progressIterator) LengthHint() int {
+	return IteratorLengthHint(it.Iterator)
+}
+
+func (it *progressIterator) Start(vm *VM) (state *IteratorState, err error) {
+	if state, err = it.Iterator.Start(vm); err == nil {
+		err = it.check(vm, state)
+	}
+	return
+}
+
+func (it *progressIterator) Next(vm *VM, state *IteratorState) (err error) {
+	if err = it.p.Inc(1); err != nil {
+		return
+	}
+	if err = it.Iterator.Next(vm, state); err == nil {
+		err = it.check(vm, state)
+	}
+	return
+}
+
+func (it *progressIterator) check(vm *VM, state *IteratorState) (err error) {
+	if err = IteratorStateCheck(vm, it.Iterator, state); err == nil && state.Mode == IteratorStateModeDone {
+		err = it.p.Done()
+	}
+	return
+}
@@ -0,0 +1,121 @@
+package gad
+
+// TypeTagKey is the key of the type name in the encoded dicts of the struct
+// instances, it is used to decode them back into instances of the registered
+// types, see EncodeObject and SerialTypes.
+const TypeTagKey = "__type__"
+
+// EncodeObject returns the serializable form of o which is encoded by the
+// serialization modules like json. The `__encode__` method defined for the
+// type of o is called if any, otherwise the default implementation
+// BuiltinEncodeFunc is used.
+func EncodeObject(vm *VM, o Object) (Object, error) {
+	if m := serialMethod(vm, BuiltinEncode, o.Type()); m != nil {
+		return DoCall(m, Call{VM: vm, Args: Args{Array{o}}})
+	}
+	return encodeObject(o), nil
+}
+
+// DecodeObject returns the instance of type t decoded from the value v which
+// was returned by EncodeObject. The `__decode__` method defined for t is
+// called if any, otherwise the default implementation BuiltinDecodeFunc is
+// used.
+func DecodeObject(vm *VM, t ObjectType, v Object) (Object, error) {
+	if m := serialMethod(vm, BuiltinDecode, t, v.Type()); m != nil {
+		return DoCall(m, Call{VM: vm, Args: Args{Array{t, v}}})
+	}
+	return decodeObject(vm, t, v)
+}
+
+// serialMethod returns the method of __encode__ or __decode__ builtin defined
+// for the types, nil is returned if there is only the default implementation.
+func serialMethod(vm *VM, t BuiltinType, types ...ObjectType) CallerObject {
+	if vm == nil || vm.Builtins == nil {
+		return nil
+	}
+	cwm, _ := vm.Builtins.Objects[t].(*CallerObjectWithMethods)
+	if cwm == nil {
+		return nil
+	}
+	if m := cwm.Methods.GetMethod(types); m != nil && !m.Default {
+		return m.CallerObject
+	}
+	return nil
+}
+
+func encodeObject(o Object) Object {
+	if obj, ok := o.(*Obj); ok {
+		d := make(Dict, len(obj.fields)+1)
+		for k, v := range obj.fields {
+			d[k] = v
+		}
+		d[TypeTagKey] = Str(obj.typ.Name())
+		return d
+	}
+	return o
+}
+
+func decodeObject(vm *VM, t ObjectType, v Object) (Object, error) {
+	if d, ok := v.(Dict); ok {
+		fields := make(Dict, len(d))
+		for k, v := range d {
+			if k != TypeTagKey {
+				fields[k] = v
+			}
+		}
+		if _, ok := t.(*ObjType); ok {
+			return t.New(vm, fields)
+		}
+		v = fields
+	}
+	if co, ok := t.(CallerObject); ok {
+		return DoCall(co, Call{VM: vm, Args: Args{Array{v}}})
+	}
+	return nil, ErrType.NewError("cannot decode type " + t.Name())
+}
+
+// SerialTypes maps the type names to the types whose encoded dicts are
+// decoded back into instances by the serialization modules.
+type SerialTypes map[string]ObjectType
+
+// NewSerialTypes returns the SerialTypes of types which is an array of types
+// or a dict of the type names to types.
+func NewSerialTypes(types Object) (SerialTypes, error) {
+	s := SerialTypes{}
+	switch v := types.(type) {
+	case *NilType:
+	case Array:
+		for _, t := range v {
+			ot, ok := t.(ObjectType)
+			if !ok {
+				return nil, NewArgumentTypeError("types", "array of types", t.Type().Name())
+			}
+			s[ot.Name()] = ot
+		}
+	case Dict:
+		for name, t := range v {
+			ot, ok := t.(ObjectType)
+			if !ok {
+				return nil, NewArgumentTypeError("types", "dict of types", t.Type().Name())
+			}
+			s[name] = ot
+		}
+	default:
+		return nil, NewArgumentTypeError("types", "array|dict", types.Type().Name())
+	}
+	return s, nil
+}
+
+// Decode decodes d if it has the name of a registered type under TypeTagKey,
+// otherwise d is returned.
+func (s SerialTypes) Decode(vm *VM, d Dict) (Object, error) {
+	name, ok := d[TypeTagKey]
+	if !ok {
+		return d, nil
+	}
+	t, ok := s[name.ToString()]
+	if !ok {
+		return d, nil
+	}
+	return DecodeObject(vm, t, d)
+}
@@ -0,0 +1,132 @@
+// Package ask provides ask module for Gad script language to ask the user
+// questions interactively, e.g. in setup scripts. The answers are read by
+// term.Prompt, so the lines are edited by liner on a terminal.
+package ask
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/gad-lang/gad"
+	"github.com/gad-lang/gad/stdlib/term"
+)
+
+// Validator validates the answer of Input. It returns a non-empty message if
+// the answer is rejected.
+type Validator func(answer string) (msg string, err error)
+
+// Asker asks the questions by writing the prompts to Out and reading the
+// answers from In. A question is asked again until a valid answer is given.
+type Asker struct {
+	In  io.Reader
+	Out io.Writer
+}
+
+// Input asks for a line of text. def is returned for an empty answer and is
+// displayed in the prompt if it is not empty.
+func (a Asker) Input(msg, def string, validate Validator) (string, error) {
+	prompt := msg
+	if def != "" {
+		prompt += " [" + def + "]"
+	}
+	prompt += ": "
+	for {
+		answer, err := term.Prompt(a.In, a.Out, prompt, false)
+		if err != nil {
+			return "", err
+		}
+		if answer = strings.TrimSpace(answer); answer == "" {
+			answer = def
+		}
+		if validate == nil {
+			return answer, nil
+		}
+		reject, err := validate(answer)
+		if err != nil {
+			return "", err
+		}
+		if reject == "" {
+			return answer, nil
+		}
+		if err = a.reject(reject); err != nil {
+			return "", err
+		}
+	}
+}
+
+// Confirm asks a yes or no question. def is returned for an empty answer.
+func (a Asker) Confirm(msg string, def bool) (bool, error) {
+	prompt := msg + " [y/N]: "
+	if def {
+		prompt = msg + " [Y/n]: "
+	}
+	for {
+		answer, err := term.Prompt(a.In, a.Out, prompt, false)
+		if err != nil {
+			return false, err
+		}
+		switch strings.ToLower(strings.TrimSpace(answer)) {
+		case "":
+			return def, nil
+		case "y", "yes":
+			return true, nil
+		case "n", "no":
+			return false, nil
+		}
+		if err = a.reject("answer yes or no"); err != nil {
+			return false, err
+		}
+	}
+}
+
+// Select asks to choose one of the choices and returns its index. The choices
+// are listed with their 1-based numbers, the answer is a number or a choice.
+// def is the index of the choice returned for an empty answer, a negative
+// value requires an answer.
+func (a Asker) Select(msg string, choices []string, def int) (int, error) {
+	if len(choices) == 0 {
+		return 0, gad.ErrUnexpectedArgValue.NewError("no choices")
+	}
+	var b strings.Builder
+	b.WriteString(msg + "\n")
+	for i, c := range choices {
+		fmt.Fprintf(&b, "  %d) %s\n", i+1, c)
+	}
+	prompt := "choose [1-" + strconv.Itoa(len(choices)) + "]"
+	if def >= 0 && def < len(choices) {
+		prompt += " (" + strconv.Itoa(def+1) + ")"
+	} else {
+		def = -1
+	}
+	prompt += ": "
+	if _, err := io.WriteString(a.Out, b.String()); err != nil {
+		return 0, err
+	}
+	for {
+		answer, err := term.Prompt(a.In, a.Out, prompt, false)
+		if err != nil {
+			return 0, err
+		}
+		if answer = strings.TrimSpace(answer); answer == "" && def >= 0 {
+			return def, nil
+		}
+		if i, err := strconv.Atoi(answer); err == nil && i >= 1 && i <= len(choices) {
+			return i - 1, nil
+		}
+		for i, c := range choices {
+			if answer == c {
+				return i, nil
+			}
+		}
+		if err = a.reject("invalid choice " + strconv.Quote(answer)); err != nil {
+			return 0, err
+		}
+	}
+}
+
+func (a Asker) reject(msg string) error {
+	_, err := io.WriteString(a.Out, "! "+msg+"\n")
+	return err
+}
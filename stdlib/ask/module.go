@@ -0,0 +1,154 @@
+package ask
+
+import (
+	"github.com/gad-lang/gad"
+)
+
+// Module represents ask module.
+var Module = gad.Dict{
+	// gad:doc
+	// # ask module
+	//
+	// ## Functions
+	// input(msg str; default="", validate=nil) -> str
+	// Asks for a line of text, default is returned for an empty answer. If
+	// validate is given, it is called with the answer which is asked again if
+	// validate returns false, an error or a message string.
+	"input": &gad.Function{
+		Name:  "input",
+		Value: inputFunc,
+	},
+	// gad:doc
+	// confirm(msg str; default=false) -> bool
+	// Asks a yes or no question, default is returned for an empty answer.
+	"confirm": &gad.Function{
+		Name:  "confirm",
+		Value: confirmFunc,
+	},
+	// gad:doc
+	// select(msg str, choices array; default=nil) -> any
+	// Lists the numbered choices and returns the chosen one. The answer is the
+	// number or the string of a choice, default is returned for an empty
+	// answer if it is one of the choices.
+	"select": &gad.Function{
+		Name:  "select",
+		Value: selectFunc,
+	},
+}
+
+func asker(c gad.Call) Asker {
+	return Asker{In: c.VM.StdIn, Out: c.VM.StdOut}
+}
+
+func msgArg() *gad.Arg {
+	return &gad.Arg{
+		Name:          "msg",
+		TypeAssertion: gad.TypeAssertionFromTypes(gad.TStr),
+	}
+}
+
+func inputFunc(c gad.Call) (_ gad.Object, err error) {
+	var (
+		msg = msgArg()
+		def = &gad.NamedArgVar{
+			Name:          "default",
+			Value:         gad.Str(""),
+			TypeAssertion: gad.TypeAssertionFromTypes(gad.TStr),
+		}
+		validate = &gad.NamedArgVar{
+			Name: "validate",
+			TypeAssertion: gad.NewTypeAssertion(gad.TypeAssertionHandlers{
+				"callable": gad.Callable,
+			}),
+		}
+		validator Validator
+	)
+	if err = c.Args.Destructure(msg); err != nil {
+		return
+	}
+	if err = c.NamedArgs.Get(def, validate); err != nil {
+		return
+	}
+	if validate.Value != nil && validate.Value != gad.Nil {
+		inv := gad.NewInvoker(c.VM, validate.Value)
+		validator = func(answer string) (string, error) {
+			ret, err := inv.Invoke(gad.Args{gad.Array{gad.Str(answer)}}, nil)
+			if err != nil {
+				return "", err
+			}
+			switch r := ret.(type) {
+			case gad.Bool:
+				if !r {
+					return "invalid value", nil
+				}
+			case gad.Str:
+				return string(r), nil
+			case *gad.Error:
+				return r.Message, nil
+			}
+			return "", nil
+		}
+	}
+	answer, err := asker(c).Input(msg.Value.ToString(), def.Value.ToString(), validator)
+	if err != nil {
+		return
+	}
+	return gad.Str(answer), nil
+}
+
+func confirmFunc(c gad.Call) (_ gad.Object, err error) {
+	var (
+		msg = msgArg()
+		def = &gad.NamedArgVar{
+			Name:          "default",
+			Value:         gad.False,
+			TypeAssertion: gad.TypeAssertionFromTypes(gad.TBool),
+		}
+	)
+	if err = c.Args.Destructure(msg); err != nil {
+		return
+	}
+	if err = c.NamedArgs.Get(def); err != nil {
+		return
+	}
+	ok, err := asker(c).Confirm(msg.Value.ToString(), bool(def.Value.(gad.Bool)))
+	if err != nil {
+		return
+	}
+	return gad.Bool(ok), nil
+}
+
+func selectFunc(c gad.Call) (_ gad.Object, err error) {
+	var (
+		msg     = msgArg()
+		choices = &gad.Arg{
+			Name:          "choices",
+			TypeAssertion: gad.TypeAssertionFromTypes(gad.TArray),
+		}
+		def = &gad.NamedArgVar{
+			Name:  "default",
+			Value: gad.Nil,
+		}
+	)
+	if err = c.Args.Destructure(msg, choices); err != nil {
+		return
+	}
+	if err = c.NamedArgs.Get(def); err != nil {
+		return
+	}
+	var (
+		items  = choices.Value.(gad.Array)
+		labels = make([]string, len(items))
+		index  = -1
+	)
+	for i, item := range items {
+		labels[i] = item.ToString()
+		if index < 0 && def.Value != gad.Nil && item.Equal(def.Value) {
+			index = i
+		}
+	}
+	if index, err = asker(c).Select(msg.Value.ToString(), labels, index); err != nil {
+		return
+	}
+	return items[index], nil
+}
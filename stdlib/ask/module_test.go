@@ -0,0 +1,81 @@
+package ask_test
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/gad-lang/gad"
+	"github.com/gad-lang/gad/stdlib/ask"
+)
+
+func run(t *testing.T, script, in string) (gad.Object, string, error) {
+	t.Helper()
+	mm := gad.NewModuleMap()
+	mm.AddBuiltinModule("ask", ask.Module)
+	c := gad.CompileOptions{CompilerOptions: gad.DefaultCompilerOptions}
+	c.ModuleMap = mm
+	bc, err := gad.Compile([]byte(`ask := import("ask");`+script), c)
+	require.NoError(t, err)
+	var out bytes.Buffer
+	ret, err := gad.NewVM(bc).RunOpts(&gad.RunOpts{StdIn: strings.NewReader(in), StdOut: &out})
+	return ret, out.String(), err
+}
+
+func TestInput(t *testing.T) {
+	ret, out, err := run(t, `return ask.input("name")`, "alice\n")
+	require.NoError(t, err)
+	require.Equal(t, gad.Str("alice"), ret)
+	require.Equal(t, "name: ", out)
+
+	ret, out, err = run(t, `return ask.input("port"; default="80")`, "\n")
+	require.NoError(t, err)
+	require.Equal(t, gad.Str("80"), ret)
+	require.Equal(t, "port [80]: ", out)
+
+	ret, out, err = run(t, `return ask.input("port"; validate=func(v) {
+		if v == "" { return false }
+		if v == "x" { return "not a number" }
+		return true
+	})`, "\nx\n8080\n")
+	require.NoError(t, err)
+	require.Equal(t, gad.Str("8080"), ret)
+	require.Equal(t, "port: ! invalid value\nport: ! not a number\nport: ", out)
+
+	_, _, err = run(t, `return ask.input("port"; validate=func(v) { throw error("no") })`, "1\n")
+	require.Error(t, err)
+
+	_, _, err = run(t, `return ask.input("name")`, "")
+	require.ErrorIs(t, err, io.EOF)
+}
+
+func TestConfirm(t *testing.T) {
+	ret, out, err := run(t, `return [ask.confirm("ok?"), ask.confirm("ok?"), ask.confirm("ok?"; default=true)]`,
+		"maybe\nYes\nn\n\n")
+	require.NoError(t, err)
+	require.Equal(t, gad.Array{gad.True, gad.False, gad.True}, ret)
+	require.Equal(t, "ok? [y/N]: ! answer yes or no\nok? [y/N]: ok? [y/N]: ok? [Y/n]: ", out)
+}
+
+func TestSelect(t *testing.T) {
+	ret, out, err := run(t, `return ask.select("env", ["dev", "prod"])`, "\n3\nprod\n")
+	require.NoError(t, err)
+	require.Equal(t, gad.Str("prod"), ret)
+	require.Equal(t, "env\n  1) dev\n  2) prod\n"+
+		"choose [1-2]: ! invalid choice \"\"\nchoose [1-2]: ! invalid choice \"3\"\nchoose [1-2]: ", out)
+
+	ret, _, err = run(t, `return ask.select("n", [10, 20, 30])`, "2\n")
+	require.NoError(t, err)
+	require.Equal(t, gad.Int(20), ret)
+
+	ret, out, err = run(t, `return ask.select("env", ["dev", "prod"]; default="prod")`, "\n")
+	require.NoError(t, err)
+	require.Equal(t, gad.Str("prod"), ret)
+	require.Contains(t, out, "choose [1-2] (2): ")
+
+	_, _, err = run(t, `return ask.select("env", [])`, "")
+	require.ErrorIs(t, err, gad.ErrUnexpectedArgValue)
+}
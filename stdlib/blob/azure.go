@@ -0,0 +1,279 @@
+package blob
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/xml"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gad-lang/gad"
+)
+
+const azureVersion = "2020-10-02"
+
+// AzureBackend is a Backend of an Azure Blob Storage container. The requests
+// are authorized by the shared key of the storage account and the signed URLs
+// are service SAS URLs.
+type AzureBackend struct {
+	Account   string
+	Container string
+	// Key is the base64 encoded shared key of the account.
+	Key string
+	// Endpoint is the URL of the blob service like the URL of an emulator,
+	// the container is the first segment of the paths. If empty,
+	// https://<account>.blob.core.windows.net is used.
+	Endpoint string
+	// Client sends the requests, http.DefaultClient is used if nil.
+	Client *http.Client
+
+	// now returns the time of the requests and signatures.
+	now func() time.Time
+}
+
+// openAzure opens az://container?account=a URLs. The account defaults to
+// AZURE_STORAGE_ACCOUNT environment variable, the shared key is read from
+// AZURE_STORAGE_KEY environment variable.
+func openAzure(u *url.URL) (Backend, error) {
+	if u.Host == "" {
+		return nil, gad.ErrUnexpectedArgValue.NewError("missing container in " + u.String())
+	}
+	q := u.Query()
+	b := &AzureBackend{
+		Account:   q.Get("account"),
+		Container: u.Host,
+		Key:       os.Getenv("AZURE_STORAGE_KEY"),
+		Endpoint:  q.Get("endpoint"),
+	}
+	if b.Account == "" {
+		b.Account = os.Getenv("AZURE_STORAGE_ACCOUNT")
+	}
+	if b.Account == "" {
+		return nil, gad.ErrUnexpectedArgValue.NewError("missing account in " + u.String())
+	}
+	return b, nil
+}
+
+// url returns the URL of the blob, or of the container if the key is empty.
+func (b *AzureBackend) url(key string) *url.URL {
+	var u *url.URL
+	if b.Endpoint != "" {
+		u, _ = url.Parse(strings.TrimSuffix(b.Endpoint, "/"))
+	}
+	if u == nil {
+		u = &url.URL{Scheme: "https", Host: b.Account + ".blob.core.windows.net"}
+	}
+	u.Path += "/" + b.Container
+	if key != "" {
+		u.Path += "/" + key
+	}
+	u.RawPath = uriEncode(u.Path, false)
+	return u
+}
+
+// resource returns the path of the blob in the account.
+func (b *AzureBackend) resource(key string) string {
+	r := "/" + b.Account + "/" + b.Container
+	if key != "" {
+		r += "/" + key
+	}
+	return r
+}
+
+// List implements Backend interface.
+func (b *AzureBackend) List(prefix, token string) (objects []Info, next string, err error) {
+	u := b.url("")
+	q := url.Values{"restype": {"container"}, "comp": {"list"}, "prefix": {prefix}}
+	if token != "" {
+		q.Set("marker", token)
+	}
+	u.RawQuery = q.Encode()
+
+	body, err := b.do(http.MethodGet, u, "", nil)
+	if err != nil {
+		return
+	}
+	var result struct {
+		NextMarker string
+		Blobs      struct {
+			Blob []struct {
+				Name       string
+				Properties struct {
+					ContentLength int64  `xml:"Content-Length"`
+					LastModified  string `xml:"Last-Modified"`
+				}
+			}
+		}
+	}
+	if err = xml.Unmarshal(body, &result); err != nil {
+		return
+	}
+	for _, blob := range result.Blobs.Blob {
+		modified, _ := time.Parse(http.TimeFormat, blob.Properties.LastModified)
+		objects = append(objects, Info{Key: blob.Name, Size: blob.Properties.ContentLength, Modified: modified})
+	}
+	return objects, result.NextMarker, nil
+}
+
+// Read implements Backend interface.
+func (b *AzureBackend) Read(key string) ([]byte, error) {
+	return b.do(http.MethodGet, b.url(key), key, nil)
+}
+
+// Write implements Backend interface. The objects are written as block blobs.
+func (b *AzureBackend) Write(key string, data []byte) error {
+	_, err := b.do(http.MethodPut, b.url(key), key, data)
+	return err
+}
+
+// Delete implements Backend interface.
+func (b *AzureBackend) Delete(key string) error {
+	_, err := b.do(http.MethodDelete, b.url(key), key, nil)
+	return err
+}
+
+// SignedURL implements Backend interface. It returns a service SAS URL of
+// the blob with the permission of the method.
+func (b *AzureBackend) SignedURL(key, method string, expires time.Duration) (string, error) {
+	var perm string
+	switch strings.ToUpper(method) {
+	case http.MethodGet, http.MethodHead:
+		perm = "r"
+	case http.MethodPut, http.MethodPost:
+		perm = "cw"
+	case http.MethodDelete:
+		perm = "d"
+	default:
+		return "", gad.ErrUnexpectedArgValue.NewError("unsupported method " + method)
+	}
+	expiry := b.time().Add(expires).Format(time.RFC3339)
+	sig, err := b.sign(strings.Join([]string{
+		perm, "", expiry, "/blob" + b.resource(key), "", "", "https", azureVersion, "b", "",
+		"", "", "", "", "",
+	}, "\n"))
+	if err != nil {
+		return "", err
+	}
+	u := b.url(key)
+	u.RawQuery = url.Values{
+		"sv":  {azureVersion},
+		"sr":  {"b"},
+		"sp":  {perm},
+		"se":  {expiry},
+		"spr": {"https"},
+		"sig": {sig},
+	}.Encode()
+	return u.String(), nil
+}
+
+func (b *AzureBackend) do(method string, u *url.URL, key string, data []byte) ([]byte, error) {
+	header := http.Header{}
+	header.Set("X-Ms-Date", b.time().Format(http.TimeFormat))
+	header.Set("X-Ms-Version", azureVersion)
+	if method == http.MethodPut {
+		header.Set("X-Ms-Blob-Type", "BlockBlob")
+	}
+	auth, err := b.sign(b.stringToSign(method, u, key, header, len(data)))
+	if err != nil {
+		return nil, err
+	}
+	header.Set("Authorization", "SharedKey "+b.Account+":"+auth)
+
+	req, err := http.NewRequest(method, u.String(), bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	req.Header = header
+	client := b.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode/100 != 2 {
+		return nil, azureError(key, resp.StatusCode, body)
+	}
+	return body, nil
+}
+
+func azureError(key string, status int, body []byte) error {
+	var e struct {
+		Code    string
+		Message string
+	}
+	_ = xml.Unmarshal(body, &e)
+	if status == http.StatusNotFound && key != "" && e.Code != "ContainerNotFound" {
+		return ErrNotFound.NewError(key)
+	}
+	msg := strconv.Itoa(status) + " " + http.StatusText(status)
+	if e.Code != "" {
+		msg += ": " + e.Code + ": " + strings.TrimSpace(e.Message)
+	}
+	return &gad.Error{Name: "BlobError", Message: msg}
+}
+
+// stringToSign returns the string to sign of a request authorized by the
+// shared key.
+func (b *AzureBackend) stringToSign(method string, u *url.URL, key string, header http.Header, length int) string {
+	var contentLength string
+	if length > 0 {
+		contentLength = strconv.Itoa(length)
+	}
+	var s strings.Builder
+	// content encoding, language, length, MD5, type, date, conditions and range
+	s.WriteString(method + "\n\n\n" + contentLength + "\n\n\n\n\n\n\n\n\n")
+
+	var names []string
+	for k := range header {
+		if k = strings.ToLower(k); strings.HasPrefix(k, "x-ms-") {
+			names = append(names, k)
+		}
+	}
+	sort.Strings(names)
+	for _, k := range names {
+		s.WriteString(k + ":" + strings.TrimSpace(header.Get(k)) + "\n")
+	}
+
+	s.WriteString(b.resource(uriEncode(key, false)))
+	q := u.Query()
+	params := make([]string, 0, len(q))
+	for k := range q {
+		params = append(params, k)
+	}
+	sort.Strings(params)
+	for _, k := range params {
+		values := append([]string(nil), q[k]...)
+		sort.Strings(values)
+		s.WriteString("\n" + strings.ToLower(k) + ":" + strings.Join(values, ","))
+	}
+	return s.String()
+}
+
+// sign returns the base64 encoded HMAC-SHA256 signature of s by the key.
+func (b *AzureBackend) sign(s string) (string, error) {
+	key, err := base64.StdEncoding.DecodeString(b.Key)
+	if err != nil {
+		return "", gad.ErrUnexpectedArgValue.NewError("invalid Azure storage key: " + err.Error())
+	}
+	return base64.StdEncoding.EncodeToString(hmacSHA256(key, s)), nil
+}
+
+func (b *AzureBackend) time() time.Time {
+	if b.now != nil {
+		return b.now().UTC()
+	}
+	return time.Now().UTC()
+}
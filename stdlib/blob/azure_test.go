@@ -0,0 +1,103 @@
+package blob
+
+import (
+	"encoding/base64"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAzureBackend(t *testing.T) {
+	objects := map[string]string{}
+	b := &AzureBackend{
+		Account:   "account",
+		Container: "container",
+		Key:       base64.StdEncoding.EncodeToString([]byte("secret")),
+		now: func() time.Time {
+			return time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+		},
+	}
+	server := *b
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key, _ := url.PathUnescape(strings.TrimPrefix(r.URL.EscapedPath(), "/container/"))
+		if key == "/container" {
+			key = ""
+		}
+		var length int
+		if r.Method == http.MethodPut {
+			length = int(r.ContentLength)
+		}
+		sig, _ := server.sign(server.stringToSign(r.Method, r.URL, key, r.Header, length))
+		if r.Header.Get("Authorization") != "SharedKey account:"+sig {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		switch {
+		case r.URL.Query().Get("comp") == "list":
+			// one object per page
+			if r.URL.Query().Get("marker") == "" {
+				w.Write([]byte(`<EnumerationResults><Blobs><Blob><Name>a/1</Name><Properties>` +
+					`<Last-Modified>Tue, 02 Jan 2024 03:04:05 GMT</Last-Modified><Content-Length>1</Content-Length>` +
+					`</Properties></Blob></Blobs><NextMarker>m1</NextMarker></EnumerationResults>`))
+			} else {
+				w.Write([]byte(`<EnumerationResults><Blobs><Blob><Name>a/2</Name><Properties>` +
+					`<Content-Length>2</Content-Length></Properties></Blob></Blobs><NextMarker/></EnumerationResults>`))
+			}
+		case r.Method == http.MethodPut:
+			require.Equal(t, "BlockBlob", r.Header.Get("X-Ms-Blob-Type"))
+			b, _ := io.ReadAll(r.Body)
+			objects[key] = string(b)
+			w.WriteHeader(http.StatusCreated)
+		case r.Method == http.MethodGet:
+			v, ok := objects[key]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				w.Write([]byte(`<Error><Code>BlobNotFound</Code><Message>not found</Message></Error>`))
+				return
+			}
+			w.Write([]byte(v))
+		case r.Method == http.MethodDelete:
+			delete(objects, key)
+			w.WriteHeader(http.StatusAccepted)
+		}
+	}))
+	defer srv.Close()
+	b.Endpoint = srv.URL
+
+	require.NoError(t, b.Write("dir/a b.txt", []byte("hello")))
+	require.Equal(t, "hello", objects["dir/a b.txt"])
+	data, err := b.Read("dir/a b.txt")
+	require.NoError(t, err)
+	require.Equal(t, "hello", string(data))
+	require.NoError(t, b.Delete("dir/a b.txt"))
+	_, err = b.Read("dir/a b.txt")
+	require.ErrorIs(t, err, ErrNotFound)
+
+	page, next, err := b.List("a/", "")
+	require.NoError(t, err)
+	require.Equal(t, "m1", next)
+	require.Equal(t, []Info{{Key: "a/1", Size: 1, Modified: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)}}, page)
+	page, next, err = b.List("a/", next)
+	require.NoError(t, err)
+	require.Equal(t, "", next)
+	require.Equal(t, "a/2", page[0].Key)
+
+	b.Endpoint = ""
+	s, err := b.SignedURL("dir/a.txt", "GET", time.Hour)
+	require.NoError(t, err)
+	sig, _ := b.sign("r\n\n2024-01-02T04:04:05Z\n/blob/account/container/dir/a.txt\n\n\nhttps\n2020-10-02\nb\n\n\n\n\n\n")
+	require.Equal(t, "https://account.blob.core.windows.net/container/dir/a.txt?"+
+		"se=2024-01-02T04%3A04%3A05Z&sig="+url.QueryEscape(sig)+"&sp=r&spr=https&sr=b&sv=2020-10-02", s)
+
+	b.Key = base64.StdEncoding.EncodeToString([]byte("other"))
+	b.Endpoint = srv.URL
+	_, err = b.Read("x")
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "403 Forbidden")
+}
@@ -0,0 +1,102 @@
+// Package blob provides blob module for Gad script language to list, read,
+// write and delete the objects of blob storages like S3 buckets or local
+// directories with the same API, so data moving scripts are not bound to a
+// cloud. Backends are opened by URL schemes, file, s3, gs and az schemes are
+// built in and other backends can be registered from Go by Register.
+package blob
+
+import (
+	"net/url"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gad-lang/gad"
+	"github.com/gad-lang/gad/stdlib/vfs"
+)
+
+// ErrNotFound is returned if an object does not exist.
+var ErrNotFound = &gad.Error{Name: "BlobNotFoundError"}
+
+// Info describes an object of a backend.
+type Info struct {
+	Key      string
+	Size     int64
+	Modified time.Time
+}
+
+// Backend is a blob storage. Keys are slash separated paths.
+type Backend interface {
+	// List returns a page of the objects whose keys start with prefix in key
+	// order, the page after the page of token if it is not empty. next is the
+	// token of the next page or empty for the last page.
+	List(prefix, token string) (objects []Info, next string, err error)
+	// Read returns the content of the object or ErrNotFound.
+	Read(key string) ([]byte, error)
+	// Write creates or replaces the object.
+	Write(key string, data []byte) error
+	// Delete deletes the object.
+	Delete(key string) error
+	// SignedURL returns a URL to request the object by method without
+	// credentials until expires.
+	SignedURL(key, method string, expires time.Duration) (string, error)
+}
+
+// Opener opens the backend of u.
+type Opener func(u *url.URL) (Backend, error)
+
+var (
+	openersMu sync.RWMutex
+	openers   = map[string]Opener{
+		"file": openFile,
+		"s3":   openS3,
+		"gs":   openGCS,
+		"az":   openAzure,
+	}
+)
+
+// Register registers the opener of the URL scheme, replacing the previous
+// one. file, s3, gs and az schemes are registered by default.
+func Register(scheme string, open Opener) {
+	openersMu.Lock()
+	defer openersMu.Unlock()
+	openers[scheme] = open
+}
+
+// Schemes returns the sorted registered URL schemes.
+func Schemes() []string {
+	openersMu.RLock()
+	defer openersMu.RUnlock()
+	schemes := make([]string, 0, len(openers))
+	for s := range openers {
+		schemes = append(schemes, s)
+	}
+	sort.Strings(schemes)
+	return schemes
+}
+
+// Open opens the backend of rawURL by the opener of its scheme.
+func Open(rawURL string) (Backend, error) {
+	return OpenFS(nil, rawURL)
+}
+
+// OpenFS is like Open but file URLs are opened in fsys instead of the
+// filesystem of the operating system if fsys is not nil, so the objects of
+// file backends stay in the filesystem given to the scripts.
+func OpenFS(fsys vfs.FS, rawURL string) (Backend, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, gad.ErrUnexpectedArgValue.NewError(err.Error())
+	}
+	if fsys != nil && u.Scheme == "file" {
+		return openFileFS(fsys, u)
+	}
+	openersMu.RLock()
+	open := openers[u.Scheme]
+	openersMu.RUnlock()
+	if open == nil {
+		return nil, gad.ErrUnexpectedArgValue.NewError("unknown blob scheme " + strconv.Quote(u.Scheme))
+	}
+	return open(u)
+}
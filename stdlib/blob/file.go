@@ -0,0 +1,142 @@
+package blob
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gad-lang/gad"
+	"github.com/gad-lang/gad/stdlib/vfs"
+)
+
+// FileBackend is a Backend storing the objects as the files under a directory
+// of a filesystem, keys are the slash separated paths relative to it.
+type FileBackend struct {
+	fsys vfs.FS
+	root string
+}
+
+// NewFileBackend returns a FileBackend storing the objects under root
+// directory of fsys.
+func NewFileBackend(fsys vfs.FS, root string) *FileBackend {
+	return &FileBackend{fsys: fsys, root: filepath.Clean(root)}
+}
+
+// openFile opens file:///dir URLs in the filesystem of the operating system.
+func openFile(u *url.URL) (Backend, error) {
+	return openFileFS(vfs.OS, u)
+}
+
+// openFileFS opens file:///dir URLs in fsys, relative directories are like
+// file://dir.
+func openFileFS(fsys vfs.FS, u *url.URL) (Backend, error) {
+	dir := u.Host + u.Path
+	if dir == "" {
+		return nil, gad.ErrUnexpectedArgValue.NewError("missing directory in " + u.String())
+	}
+	return NewFileBackend(fsys, filepath.FromSlash(dir)), nil
+}
+
+func (b *FileBackend) name(key string) (string, error) {
+	if key == "" || strings.HasPrefix(key, "/") || path.Clean(key) != key || strings.HasPrefix(key, "../") || key == ".." {
+		return "", gad.ErrUnexpectedArgValue.NewError("invalid key " + key)
+	}
+	return filepath.Join(b.root, filepath.FromSlash(key)), nil
+}
+
+// List implements Backend interface. All objects are returned in one page.
+func (b *FileBackend) List(prefix, _ string) (objects []Info, _ string, err error) {
+	err = vfs.Walk(b.fsys, b.root, func(name string, info fs.FileInfo, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(b.root, name)
+		if err != nil {
+			return err
+		}
+		if key := filepath.ToSlash(rel); strings.HasPrefix(key, prefix) {
+			objects = append(objects, Info{Key: key, Size: info.Size(), Modified: info.ModTime()})
+		}
+		return nil
+	})
+	sort.Slice(objects, func(i, j int) bool {
+		return objects[i].Key < objects[j].Key
+	})
+	return
+}
+
+// Read implements Backend interface.
+func (b *FileBackend) Read(key string) ([]byte, error) {
+	name, err := b.name(key)
+	if err != nil {
+		return nil, err
+	}
+	f, err := b.fsys.OpenFile(name, os.O_RDONLY, 0)
+	if err != nil {
+		return nil, fileError(key, err)
+	}
+	defer f.Close()
+	return io.ReadAll(f)
+}
+
+// Write implements Backend interface. The missing directories are created.
+func (b *FileBackend) Write(key string, data []byte) (err error) {
+	name, err := b.name(key)
+	if err != nil {
+		return
+	}
+	if err = b.fsys.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+		return
+	}
+	f, err := b.fsys.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o644)
+	if err != nil {
+		return
+	}
+	if _, err = f.Write(data); err != nil {
+		_ = f.Close()
+		return
+	}
+	return f.Close()
+}
+
+// Delete implements Backend interface.
+func (b *FileBackend) Delete(key string) error {
+	name, err := b.name(key)
+	if err != nil {
+		return err
+	}
+	return fileError(key, b.fsys.Remove(name))
+}
+
+// SignedURL implements Backend interface. It returns the file URL of the
+// object as files are not served.
+func (b *FileBackend) SignedURL(key, _ string, _ time.Duration) (string, error) {
+	name, err := b.name(key)
+	if err != nil {
+		return "", err
+	}
+	if name, err = filepath.Abs(name); err != nil {
+		return "", err
+	}
+	return (&url.URL{Scheme: "file", Path: filepath.ToSlash(name)}).String(), nil
+}
+
+func fileError(key string, err error) error {
+	if errors.Is(err, fs.ErrNotExist) {
+		return ErrNotFound.NewError(key)
+	}
+	return err
+}
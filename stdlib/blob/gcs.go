@@ -0,0 +1,320 @@
+package blob
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gad-lang/gad"
+)
+
+const (
+	gcsAlgorithm  = "GOOG4-RSA-SHA256"
+	gcsHost       = "storage.googleapis.com"
+	gcsScope      = "https://www.googleapis.com/auth/devstorage.read_write"
+	gcsTokenURL   = "https://oauth2.googleapis.com/token"
+	gcsGrantType  = "urn:ietf:params:oauth:grant-type:jwt-bearer"
+	gcsTimeFormat = "20060102T150405Z"
+)
+
+// GCSBackend is a Backend of a Google Cloud Storage bucket using the JSON
+// API. The requests are authorized by the OAuth2 access tokens of a service
+// account, and the signed URLs are signed by its private key.
+type GCSBackend struct {
+	Bucket      string
+	ClientEmail string
+	PrivateKey  *rsa.PrivateKey
+	// TokenURL is the OAuth2 token endpoint, the Google endpoint is used if
+	// empty.
+	TokenURL string
+	// Endpoint is the URL of the storage API like the URL of an emulator,
+	// https://storage.googleapis.com is used if empty.
+	Endpoint string
+	// Client sends the requests, http.DefaultClient is used if nil.
+	Client *http.Client
+
+	mu      sync.Mutex
+	token   string
+	expires time.Time
+	// now returns the time of the tokens and signatures.
+	now func() time.Time
+}
+
+// openGCS opens gs://bucket URLs. The credentials are read from the service
+// account key file of GOOGLE_APPLICATION_CREDENTIALS environment variable.
+func openGCS(u *url.URL) (Backend, error) {
+	if u.Host == "" {
+		return nil, gad.ErrUnexpectedArgValue.NewError("missing bucket in " + u.String())
+	}
+	name := os.Getenv("GOOGLE_APPLICATION_CREDENTIALS")
+	if name == "" {
+		return nil, gad.ErrUnexpectedArgValue.NewError(
+			"GOOGLE_APPLICATION_CREDENTIALS is not set for " + u.String())
+	}
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	return NewGCSBackend(u.Host, data)
+}
+
+// NewGCSBackend returns a GCSBackend of the bucket with the credentials of
+// the JSON key file of a service account.
+func NewGCSBackend(bucket string, credentials []byte) (*GCSBackend, error) {
+	var key struct {
+		ClientEmail string `json:"client_email"`
+		PrivateKey  string `json:"private_key"`
+		TokenURI    string `json:"token_uri"`
+	}
+	if err := json.Unmarshal(credentials, &key); err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode([]byte(key.PrivateKey))
+	if block == nil {
+		return nil, errors.New("invalid private key of service account " + key.ClientEmail)
+	}
+	pk, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		if pk, err = x509.ParsePKCS1PrivateKey(block.Bytes); err != nil {
+			return nil, err
+		}
+	}
+	rsaKey, ok := pk.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("private key of service account " + key.ClientEmail + " is not an RSA key")
+	}
+	return &GCSBackend{
+		Bucket:      bucket,
+		ClientEmail: key.ClientEmail,
+		PrivateKey:  rsaKey,
+		TokenURL:    key.TokenURI,
+	}, nil
+}
+
+func (b *GCSBackend) endpoint() string {
+	if b.Endpoint != "" {
+		return strings.TrimSuffix(b.Endpoint, "/")
+	}
+	return "https://" + gcsHost
+}
+
+// objectURL returns the JSON API URL of the object.
+func (b *GCSBackend) objectURL(key string) string {
+	return b.endpoint() + "/storage/v1/b/" + url.PathEscape(b.Bucket) + "/o/" + url.PathEscape(key)
+}
+
+// List implements Backend interface.
+func (b *GCSBackend) List(prefix, token string) (objects []Info, next string, err error) {
+	q := url.Values{"prefix": {prefix}}
+	if token != "" {
+		q.Set("pageToken", token)
+	}
+	body, err := b.do(http.MethodGet,
+		b.endpoint()+"/storage/v1/b/"+url.PathEscape(b.Bucket)+"/o?"+q.Encode(), "", nil)
+	if err != nil {
+		return
+	}
+	var result struct {
+		NextPageToken string `json:"nextPageToken"`
+		Items         []struct {
+			Name    string    `json:"name"`
+			Size    string    `json:"size"`
+			Updated time.Time `json:"updated"`
+		} `json:"items"`
+	}
+	if err = json.Unmarshal(body, &result); err != nil {
+		return
+	}
+	for _, item := range result.Items {
+		size, _ := strconv.ParseInt(item.Size, 10, 64)
+		objects = append(objects, Info{Key: item.Name, Size: size, Modified: item.Updated})
+	}
+	return objects, result.NextPageToken, nil
+}
+
+// Read implements Backend interface.
+func (b *GCSBackend) Read(key string) ([]byte, error) {
+	return b.do(http.MethodGet, b.objectURL(key)+"?alt=media", key, nil)
+}
+
+// Write implements Backend interface.
+func (b *GCSBackend) Write(key string, data []byte) error {
+	q := url.Values{"uploadType": {"media"}, "name": {key}}
+	_, err := b.do(http.MethodPost,
+		b.endpoint()+"/upload/storage/v1/b/"+url.PathEscape(b.Bucket)+"/o?"+q.Encode(), key, data)
+	return err
+}
+
+// Delete implements Backend interface.
+func (b *GCSBackend) Delete(key string) error {
+	_, err := b.do(http.MethodDelete, b.objectURL(key), key, nil)
+	return err
+}
+
+// SignedURL implements Backend interface. It returns a V4 signed URL signed
+// by the private key of the service account.
+func (b *GCSBackend) SignedURL(key, method string, expires time.Duration) (string, error) {
+	var (
+		t     = b.time()
+		scope = t.Format("20060102") + "/auto/storage/goog4_request"
+		path  = "/" + b.Bucket + "/" + uriEncode(key, false)
+		query = canonicalQuery(url.Values{
+			"X-Goog-Algorithm":     {gcsAlgorithm},
+			"X-Goog-Credential":    {b.ClientEmail + "/" + scope},
+			"X-Goog-Date":          {t.Format(gcsTimeFormat)},
+			"X-Goog-Expires":       {strconv.FormatInt(int64(expires/time.Second), 10)},
+			"X-Goog-SignedHeaders": {"host"},
+		})
+		canonical = strings.ToUpper(method) + "\n" + path + "\n" + query + "\nhost:" + gcsHost +
+			"\n\nhost\nUNSIGNED-PAYLOAD"
+		sum = sha256.Sum256([]byte(canonical))
+	)
+	sig, err := b.sign(gcsAlgorithm + "\n" + t.Format(gcsTimeFormat) + "\n" + scope + "\n" +
+		hex.EncodeToString(sum[:]))
+	if err != nil {
+		return "", err
+	}
+	return "https://" + gcsHost + path + "?" + query + "&X-Goog-Signature=" + hex.EncodeToString(sig), nil
+}
+
+func (b *GCSBackend) do(method, rawURL, key string, data []byte) ([]byte, error) {
+	token, err := b.accessToken()
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest(method, rawURL, bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	if data != nil {
+		req.Header.Set("Content-Type", "application/octet-stream")
+	}
+	body, status, err := b.send(req)
+	if err != nil {
+		return nil, err
+	}
+	if status/100 != 2 {
+		return nil, gcsError(key, status, body)
+	}
+	return body, nil
+}
+
+func (b *GCSBackend) send(req *http.Request) (body []byte, status int, err error) {
+	client := b.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return
+	}
+	defer resp.Body.Close()
+	body, err = io.ReadAll(resp.Body)
+	return body, resp.StatusCode, err
+}
+
+func gcsError(key string, status int, body []byte) error {
+	var e struct {
+		Error struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	_ = json.Unmarshal(body, &e)
+	if status == http.StatusNotFound && key != "" {
+		return ErrNotFound.NewError(key)
+	}
+	msg := strconv.Itoa(status) + " " + http.StatusText(status)
+	if e.Error.Message != "" {
+		msg += ": " + e.Error.Message
+	}
+	return &gad.Error{Name: "BlobError", Message: msg}
+}
+
+// accessToken returns the cached access token or requests a new one by a
+// JWT assertion signed by the private key.
+func (b *GCSBackend) accessToken() (string, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	t := b.time()
+	if b.token != "" && t.Before(b.expires) {
+		return b.token, nil
+	}
+
+	tokenURL := b.TokenURL
+	if tokenURL == "" {
+		tokenURL = gcsTokenURL
+	}
+	enc := base64.RawURLEncoding
+	claims, _ := json.Marshal(map[string]any{
+		"iss":   b.ClientEmail,
+		"scope": gcsScope,
+		"aud":   tokenURL,
+		"iat":   t.Unix(),
+		"exp":   t.Add(time.Hour).Unix(),
+	})
+	assertion := enc.EncodeToString([]byte(`{"alg":"RS256","typ":"JWT"}`)) + "." + enc.EncodeToString(claims)
+	sig, err := b.sign(assertion)
+	if err != nil {
+		return "", err
+	}
+	assertion += "." + enc.EncodeToString(sig)
+
+	req, err := http.NewRequest(http.MethodPost, tokenURL, strings.NewReader(url.Values{
+		"grant_type": {gcsGrantType},
+		"assertion":  {assertion},
+	}.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	body, status, err := b.send(req)
+	if err != nil {
+		return "", err
+	}
+	if status/100 != 2 {
+		return "", &gad.Error{Name: "BlobError",
+			Message: "access token: " + strconv.Itoa(status) + " " + http.StatusText(status)}
+	}
+	var result struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int64  `json:"expires_in"`
+	}
+	if err = json.Unmarshal(body, &result); err != nil {
+		return "", err
+	}
+	b.token = result.AccessToken
+	// renews the token a minute before it expires
+	b.expires = t.Add(time.Duration(result.ExpiresIn)*time.Second - time.Minute)
+	return b.token, nil
+}
+
+func (b *GCSBackend) sign(s string) ([]byte, error) {
+	if b.PrivateKey == nil {
+		return nil, errors.New("missing private key of service account " + b.ClientEmail)
+	}
+	sum := sha256.Sum256([]byte(s))
+	return rsa.SignPKCS1v15(nil, b.PrivateKey, crypto.SHA256, sum[:])
+}
+
+func (b *GCSBackend) time() time.Time {
+	if b.now != nil {
+		return b.now().UTC()
+	}
+	return time.Now().UTC()
+}
@@ -0,0 +1,127 @@
+package blob
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGCSBackend(t *testing.T) {
+	pk, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	der, err := x509.MarshalPKCS8PrivateKey(pk)
+	require.NoError(t, err)
+
+	var (
+		objects = map[string]string{}
+		tokens  int
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/token" {
+			tokens++
+			require.NoError(t, r.ParseForm())
+			require.Equal(t, gcsGrantType, r.Form.Get("grant_type"))
+			parts := strings.Split(r.Form.Get("assertion"), ".")
+			require.Len(t, parts, 3)
+			w.Write([]byte(`{"access_token":"tok","expires_in":3600}`))
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer tok" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		key, _ := url.PathUnescape(strings.TrimPrefix(r.URL.EscapedPath(), "/storage/v1/b/bucket/o/"))
+		switch {
+		case r.URL.Path == "/upload/storage/v1/b/bucket/o":
+			b, _ := io.ReadAll(r.Body)
+			objects[r.URL.Query().Get("name")] = string(b)
+			w.Write([]byte(`{}`))
+		case r.URL.Path == "/storage/v1/b/bucket/o":
+			// one object per page
+			if r.URL.Query().Get("pageToken") == "" {
+				w.Write([]byte(`{"nextPageToken":"t1","items":[` +
+					`{"name":"a/1","size":"1","updated":"2024-01-02T03:04:05.000Z"}]}`))
+			} else {
+				w.Write([]byte(`{"items":[{"name":"a/2","size":"2","updated":"2024-01-02T03:04:05.000Z"}]}`))
+			}
+		case r.Method == http.MethodGet:
+			v, ok := objects[key]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				w.Write([]byte(`{"error":{"message":"No such object"}}`))
+				return
+			}
+			w.Write([]byte(v))
+		case r.Method == http.MethodDelete:
+			delete(objects, key)
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	defer srv.Close()
+
+	creds, _ := json.Marshal(map[string]string{
+		"client_email": "sa@project.iam.gserviceaccount.com",
+		"private_key":  string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})),
+		"token_uri":    srv.URL + "/token",
+	})
+	b, err := NewGCSBackend("bucket", creds)
+	require.NoError(t, err)
+	b.Endpoint = srv.URL
+	b.now = func() time.Time {
+		return time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	}
+
+	require.NoError(t, b.Write("dir/a b.txt", []byte("hello")))
+	require.Equal(t, "hello", objects["dir/a b.txt"])
+	data, err := b.Read("dir/a b.txt")
+	require.NoError(t, err)
+	require.Equal(t, "hello", string(data))
+	require.NoError(t, b.Delete("dir/a b.txt"))
+	_, err = b.Read("dir/a b.txt")
+	require.ErrorIs(t, err, ErrNotFound)
+
+	page, next, err := b.List("a/", "")
+	require.NoError(t, err)
+	require.Equal(t, "t1", next)
+	require.Equal(t, []Info{{Key: "a/1", Size: 1, Modified: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)}}, page)
+	page, next, err = b.List("a/", next)
+	require.NoError(t, err)
+	require.Equal(t, "", next)
+	require.Equal(t, "a/2", page[0].Key)
+	// the access token is cached
+	require.Equal(t, 1, tokens)
+
+	s, err := b.SignedURL("dir/a b.txt", "GET", time.Hour)
+	require.NoError(t, err)
+	u, err := url.Parse(s)
+	require.NoError(t, err)
+	require.Equal(t, "/bucket/dir/a%20b.txt", u.EscapedPath())
+	q := u.Query()
+	require.Equal(t, "sa@project.iam.gserviceaccount.com/20240102/auto/storage/goog4_request", q.Get("X-Goog-Credential"))
+	require.Equal(t, "3600", q.Get("X-Goog-Expires"))
+	sig, err := hex.DecodeString(q.Get("X-Goog-Signature"))
+	require.NoError(t, err)
+	q.Del("X-Goog-Signature")
+	sum := sha256.Sum256([]byte("GET\n/bucket/dir/a%20b.txt\n" + canonicalQuery(q) +
+		"\nhost:storage.googleapis.com\n\nhost\nUNSIGNED-PAYLOAD"))
+	sum = sha256.Sum256([]byte("GOOG4-RSA-SHA256\n20240102T030405Z\n20240102/auto/storage/goog4_request\n" +
+		hex.EncodeToString(sum[:])))
+	require.NoError(t, rsa.VerifyPKCS1v15(&pk.PublicKey, crypto.SHA256, sum[:], sig))
+
+	_, err = NewGCSBackend("bucket", []byte(`{"private_key":"x"}`))
+	require.Error(t, err)
+}
@@ -0,0 +1,247 @@
+package blob
+
+import (
+	"time"
+
+	"github.com/gad-lang/gad"
+	gadtime "github.com/gad-lang/gad/stdlib/time"
+	"github.com/gad-lang/gad/stdlib/vfs"
+)
+
+// Module represents blob module.
+var Module = NewModule(vfs.OS)
+
+// NewModule returns the blob module whose file backends use fsys.
+func NewModule(fsys vfs.FS) gad.Dict {
+	return gad.Dict{
+		// gad:doc
+		// # blob module
+		//
+		// ## Functions
+		// open(url str) -> bucket
+		// Opens the blob storage of url:
+		//
+		// - `file:///dir` or `file://dir` stores the objects as files under dir.
+		// - `s3://bucket?region=r&endpoint=e` is an S3 bucket or a bucket of an
+		//   S3 compatible storage at endpoint. The credentials are read from
+		//   AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY and AWS_SESSION_TOKEN
+		//   environment variables.
+		// - `gs://bucket` is a Google Cloud Storage bucket. The credentials are
+		//   read from the service account key file of
+		//   GOOGLE_APPLICATION_CREDENTIALS environment variable.
+		// - `az://container?account=a` is an Azure Blob Storage container. The
+		//   account defaults to AZURE_STORAGE_ACCOUNT and its shared key is read
+		//   from AZURE_STORAGE_KEY environment variable.
+		//
+		// Other schemes can be registered from Go.
+		"open": &gad.Function{
+			Name:  "open",
+			Value: openFunc(fsys),
+		},
+	}
+}
+
+func openFunc(fsys vfs.FS) gad.CallableFunc {
+	return func(c gad.Call) (_ gad.Object, err error) {
+		u := &gad.Arg{
+			Name:          "url",
+			TypeAssertion: gad.TypeAssertionFromTypes(gad.TStr),
+		}
+		if err = c.Args.Destructure(u); err != nil {
+			return
+		}
+		b, err := OpenFS(fsys, u.Value.ToString())
+		if err != nil {
+			return
+		}
+		return NewBucket(u.Value.ToString(), b), nil
+	}
+}
+
+// gad:doc
+// ## Types
+// ### bucket
+//
+// #### bucket Methods
+//
+// | Method                                     | Return Type                        |
+// |:-------------------------------------------|:-----------------------------------|
+// |.list(prefix str = "")                      | iterator of {key, size, modified}  |
+// |.read(key str)                              | bytes                              |
+// |.write(key str, data str\|bytes)            | nil                                |
+// |.delete(key str)                            | nil                                |
+// |.signedURL(key str; method="GET", expires=15 minutes) | str                      |
+//
+// list iterates the objects in key order lazily, the pages are requested as
+// the iteration goes on. read throws BlobNotFoundError if the object does not
+// exist. expires of signedURL is a duration like time.Hour.
+
+// TBucket is the type of Bucket objects.
+var TBucket = &gad.BuiltinObjType{
+	NameValue: "bucket",
+}
+
+// Bucket is the object of a Backend.
+type Bucket struct {
+	gad.ObjectImpl
+	Name    string
+	Backend Backend
+}
+
+var _ gad.NameCallerObject = (*Bucket)(nil)
+
+// NewBucket returns a new Bucket object of the backend, name is its string.
+func NewBucket(name string, b Backend) *Bucket {
+	return &Bucket{Name: name, Backend: b}
+}
+
+func (*Bucket) Type() gad.ObjectType {
+	return TBucket
+}
+
+// ToString implements gad.Object interface.
+func (o *Bucket) ToString() string {
+	return o.Name
+}
+
+// IsFalsy implements gad.Object interface.
+func (o *Bucket) IsFalsy() bool {
+	return false
+}
+
+// Equal implements gad.Object interface.
+func (o *Bucket) Equal(right gad.Object) bool {
+	v, ok := right.(*Bucket)
+	return ok && v == o
+}
+
+func keyArg() *gad.Arg {
+	return &gad.Arg{
+		Name:          "key",
+		TypeAssertion: gad.TypeAssertionFromTypes(gad.TStr),
+	}
+}
+
+// CallName implements gad.NameCallerObject interface.
+func (o *Bucket) CallName(name string, c gad.Call) (_ gad.Object, err error) {
+	switch name {
+	case "list":
+		if err = c.Args.CheckMaxLen(1); err != nil {
+			return
+		}
+		var prefix string
+		if c.Args.Length() == 1 {
+			prefix = c.Args.GetOnly(0).ToString()
+		}
+		return gad.IteratorObject(o.list(prefix)), nil
+	case "read":
+		key := keyArg()
+		if err = c.Args.Destructure(key); err != nil {
+			return
+		}
+		var data []byte
+		if data, err = o.Backend.Read(key.Value.ToString()); err != nil {
+			return
+		}
+		return gad.Bytes(data), nil
+	case "write":
+		var (
+			key  = keyArg()
+			data = &gad.Arg{
+				Name:          "data",
+				TypeAssertion: gad.TypeAssertionFromTypes(gad.TStr, gad.TBytes),
+			}
+		)
+		if err = c.Args.Destructure(key, data); err != nil {
+			return
+		}
+		var b []byte
+		if v, ok := data.Value.(gad.Bytes); ok {
+			b = v
+		} else {
+			b = []byte(data.Value.ToString())
+		}
+		return gad.Nil, o.Backend.Write(key.Value.ToString(), b)
+	case "delete":
+		key := keyArg()
+		if err = c.Args.Destructure(key); err != nil {
+			return
+		}
+		return gad.Nil, o.Backend.Delete(key.Value.ToString())
+	case "signedURL":
+		var (
+			key    = keyArg()
+			method = &gad.NamedArgVar{
+				Name:          "method",
+				Value:         gad.Str("GET"),
+				TypeAssertion: gad.TypeAssertionFromTypes(gad.TStr),
+			}
+			expires = &gad.NamedArgVar{
+				Name:          "expires",
+				Value:         gad.Int(15 * time.Minute),
+				TypeAssertion: gad.TypeAssertionFromTypes(gad.TInt),
+			}
+			u string
+		)
+		if err = c.Args.Destructure(key); err != nil {
+			return
+		}
+		if err = c.NamedArgs.Get(method, expires); err != nil {
+			return
+		}
+		if u, err = o.Backend.SignedURL(key.Value.ToString(), method.Value.ToString(),
+			time.Duration(expires.Value.(gad.Int))); err != nil {
+			return
+		}
+		return gad.Str(u), nil
+	}
+	return nil, gad.ErrInvalidIndex.NewError(name)
+}
+
+// listCursor is the state of the list iterator.
+type listCursor struct {
+	gad.ObjectImpl
+	page []Info
+	i    int
+	next string
+}
+
+// list returns the iterator of the objects whose keys start with prefix. The
+// pages are requested as the iteration goes on.
+func (o *Bucket) list(prefix string) gad.Iterator {
+	// fill requests the next page until there is an object to yield
+	fill := func(state *gad.IteratorState, cur *listCursor) (err error) {
+		for cur.i == len(cur.page) {
+			if cur.page != nil && cur.next == "" {
+				state.Mode = gad.IteratorStateModeDone
+				return
+			}
+			if cur.page, cur.next, err = o.Backend.List(prefix, cur.next); err != nil {
+				return
+			}
+			if cur.page == nil {
+				cur.page = []Info{}
+			}
+			cur.i = 0
+		}
+		info := cur.page[cur.i]
+		state.Entry.K = gad.Str(info.Key)
+		state.Entry.V = gad.Dict{
+			"key":      gad.Str(info.Key),
+			"size":     gad.Int(info.Size),
+			"modified": &gadtime.Time{Value: info.Modified},
+		}
+		return
+	}
+	return gad.NewIterator(
+		func(*gad.VM) (state *gad.IteratorState, err error) {
+			cur := &listCursor{}
+			state = &gad.IteratorState{Value: cur}
+			return state, fill(state, cur)
+		},
+		func(_ *gad.VM, state *gad.IteratorState) error {
+			cur := state.Value.(*listCursor)
+			cur.i++
+			return fill(state, cur)
+		}).SetInput(o).SetItType(TBucket)
+}
@@ -0,0 +1,118 @@
+package blob_test
+
+import (
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/gad-lang/gad"
+	"github.com/gad-lang/gad/stdlib/blob"
+	"github.com/gad-lang/gad/stdlib/vfs"
+)
+
+func run(t *testing.T, script string) (gad.Object, error) {
+	t.Helper()
+	mm := gad.NewModuleMap()
+	mm.AddBuiltinModule("blob", blob.Module)
+	c := gad.CompileOptions{CompilerOptions: gad.DefaultCompilerOptions}
+	c.ModuleMap = mm
+	bc, err := gad.Compile([]byte(`blob := import("blob");`+script), c)
+	require.NoError(t, err)
+	return gad.NewVM(bc).Run(nil)
+}
+
+func TestFile(t *testing.T) {
+	dir := t.TempDir()
+	open := `b := blob.open(` + strconv.Quote("file://"+filepath.ToSlash(dir)) + `);`
+
+	ret, err := run(t, open+`
+		b.write("x/a.txt", "hello")
+		b.write("x/b.txt", bytes("world!"))
+		b.write("y.txt", "")
+		r := []; for k, _ in b.list() { r = append(r, k) }
+		return [str(b.read("x/a.txt")), collect(map(b.list("x/"), func(o, _) { return [o.key, o.size] })), r]`)
+	require.NoError(t, err)
+	require.Equal(t, gad.Array{
+		gad.Str("hello"),
+		gad.Array{
+			gad.Array{gad.Str("x/a.txt"), gad.Int(5)},
+			gad.Array{gad.Str("x/b.txt"), gad.Int(6)},
+		},
+		gad.Array{gad.Str("x/a.txt"), gad.Str("x/b.txt"), gad.Str("y.txt")},
+	}, ret)
+
+	ret, err = run(t, open+`b.delete("x/a.txt"); for o in b.list("x") { return [o.key, typeName(o.modified)] }`)
+	require.NoError(t, err)
+	require.Equal(t, gad.Array{gad.Str("x/b.txt"), gad.Str("time")}, ret)
+
+	ret, err = run(t, open+`return b.signedURL("y.txt"; expires=1000)`)
+	require.NoError(t, err)
+	require.Equal(t, gad.Str("file://"+filepath.ToSlash(filepath.Join(dir, "y.txt"))), ret)
+
+	_, err = run(t, open+`b.read("x/a.txt")`)
+	require.ErrorIs(t, err, blob.ErrNotFound)
+	_, err = run(t, open+`b.delete("x/a.txt")`)
+	require.ErrorIs(t, err, blob.ErrNotFound)
+	_, err = run(t, open+`b.write("../a.txt", "")`)
+	require.ErrorIs(t, err, gad.ErrUnexpectedArgValue)
+	_, err = run(t, `blob.open("ftp://host/dir")`)
+	require.ErrorIs(t, err, gad.ErrUnexpectedArgValue)
+}
+
+func TestNewModuleFS(t *testing.T) {
+	dir := t.TempDir()
+	mm := gad.NewModuleMap()
+	mm.AddBuiltinModule("blob", blob.NewModule(vfs.Dir(dir)))
+	c := gad.CompileOptions{CompilerOptions: gad.DefaultCompilerOptions}
+	c.ModuleMap = mm
+	bc, err := gad.Compile([]byte(`b := import("blob").open("file:///data"); b.write("a.txt", "x")
+		return str(b.read("a.txt"))`), c)
+	require.NoError(t, err)
+	ret, err := gad.NewVM(bc).Run(nil)
+	require.NoError(t, err)
+	require.Equal(t, gad.Str("x"), ret)
+
+	// the file URLs are resolved in the filesystem of the module
+	data, err := os.ReadFile(filepath.Join(dir, "data", "a.txt"))
+	require.NoError(t, err)
+	require.Equal(t, "x", string(data))
+}
+
+// pageBackend lists the pages of its objects.
+type pageBackend struct {
+	blob.Backend
+	pages [][]blob.Info
+	calls int
+}
+
+func (b *pageBackend) List(_, token string) ([]blob.Info, string, error) {
+	b.calls++
+	i, _ := strconv.Atoi(token)
+	if i+1 < len(b.pages) {
+		return b.pages[i], strconv.Itoa(i + 1), nil
+	}
+	return b.pages[i], "", nil
+}
+
+func TestRegister(t *testing.T) {
+	b := &pageBackend{pages: [][]blob.Info{{{Key: "a"}, {Key: "b"}}, {}, {{Key: "c"}}}}
+	blob.Register("page", func(*url.URL) (blob.Backend, error) {
+		return b, nil
+	})
+	require.Contains(t, blob.Schemes(), "page")
+
+	// pages are requested lazily
+	ret, err := run(t, `for o in blob.open("page://").list() { return o.key }`)
+	require.NoError(t, err)
+	require.Equal(t, gad.Str("a"), ret)
+	require.Equal(t, 1, b.calls)
+
+	ret, err = run(t, `r := []; for k, o in blob.open("page://").list() { r = append(r, k) }; return r`)
+	require.NoError(t, err)
+	require.Equal(t, gad.Array{gad.Str("a"), gad.Str("b"), gad.Str("c")}, ret)
+	require.Equal(t, 4, b.calls)
+}
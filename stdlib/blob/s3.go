@@ -0,0 +1,303 @@
+package blob
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gad-lang/gad"
+)
+
+const (
+	s3Algorithm       = "AWS4-HMAC-SHA256"
+	s3UnsignedPayload = "UNSIGNED-PAYLOAD"
+	s3TimeFormat      = "20060102T150405Z"
+)
+
+// S3Backend is a Backend of an S3 bucket, or a bucket of an S3 compatible
+// storage like MinIO or GCS with HMAC keys if Endpoint is set. The requests
+// are signed by AWS signature version 4.
+type S3Backend struct {
+	Bucket string
+	Region string
+	// Endpoint is the URL of an S3 compatible storage, the bucket is the
+	// first segment of the paths. If empty, the virtual hosted AWS endpoint
+	// of the bucket in Region is used.
+	Endpoint     string
+	AccessKey    string
+	SecretKey    string
+	SessionToken string
+	// Client sends the requests, http.DefaultClient is used if nil.
+	Client *http.Client
+
+	// now returns the time of the signatures.
+	now func() time.Time
+}
+
+// openS3 opens s3://bucket?region=r&endpoint=e URLs. The region defaults to
+// AWS_REGION environment variable or us-east-1, the credentials are read from
+// AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY and AWS_SESSION_TOKEN environment
+// variables.
+func openS3(u *url.URL) (Backend, error) {
+	if u.Host == "" {
+		return nil, gad.ErrUnexpectedArgValue.NewError("missing bucket in " + u.String())
+	}
+	q := u.Query()
+	b := &S3Backend{
+		Bucket:       u.Host,
+		Region:       q.Get("region"),
+		Endpoint:     q.Get("endpoint"),
+		AccessKey:    os.Getenv("AWS_ACCESS_KEY_ID"),
+		SecretKey:    os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		SessionToken: os.Getenv("AWS_SESSION_TOKEN"),
+	}
+	if b.Region == "" {
+		b.Region = os.Getenv("AWS_REGION")
+	}
+	if b.Region == "" {
+		b.Region = "us-east-1"
+	}
+	return b, nil
+}
+
+// url returns the URL of the key, or of the bucket if the key is empty.
+func (b *S3Backend) url(key string) (u *url.URL) {
+	if b.Endpoint != "" {
+		u, _ = url.Parse(strings.TrimSuffix(b.Endpoint, "/"))
+		if u == nil {
+			u = &url.URL{}
+		}
+		u.Path += "/" + b.Bucket + "/" + key
+	} else {
+		host := b.Bucket + ".s3.amazonaws.com"
+		if b.Region != "us-east-1" {
+			host = b.Bucket + ".s3." + b.Region + ".amazonaws.com"
+		}
+		u = &url.URL{Scheme: "https", Host: host, Path: "/" + key}
+	}
+	// sends the path as it is signed
+	u.RawPath = uriEncode(u.Path, false)
+	return u
+}
+
+// List implements Backend interface.
+func (b *S3Backend) List(prefix, token string) (objects []Info, next string, err error) {
+	u := b.url("")
+	q := url.Values{"list-type": {"2"}, "prefix": {prefix}}
+	if token != "" {
+		q.Set("continuation-token", token)
+	}
+	u.RawQuery = q.Encode()
+
+	body, err := b.do(http.MethodGet, u, nil)
+	if err != nil {
+		return
+	}
+	var result struct {
+		IsTruncated           bool
+		NextContinuationToken string
+		Contents              []struct {
+			Key          string
+			Size         int64
+			LastModified time.Time
+		}
+	}
+	if err = xml.Unmarshal(body, &result); err != nil {
+		return
+	}
+	for _, c := range result.Contents {
+		objects = append(objects, Info{Key: c.Key, Size: c.Size, Modified: c.LastModified})
+	}
+	if result.IsTruncated {
+		next = result.NextContinuationToken
+	}
+	return
+}
+
+// Read implements Backend interface.
+func (b *S3Backend) Read(key string) ([]byte, error) {
+	return b.do(http.MethodGet, b.url(key), nil)
+}
+
+// Write implements Backend interface.
+func (b *S3Backend) Write(key string, data []byte) error {
+	_, err := b.do(http.MethodPut, b.url(key), data)
+	return err
+}
+
+// Delete implements Backend interface.
+func (b *S3Backend) Delete(key string) error {
+	_, err := b.do(http.MethodDelete, b.url(key), nil)
+	return err
+}
+
+// SignedURL implements Backend interface. It returns a presigned URL.
+func (b *S3Backend) SignedURL(key, method string, expires time.Duration) (string, error) {
+	var (
+		u     = b.url(key)
+		t     = b.time()
+		scope = b.scope(t)
+		q     = url.Values{
+			"X-Amz-Algorithm":     {s3Algorithm},
+			"X-Amz-Credential":    {b.AccessKey + "/" + scope},
+			"X-Amz-Date":          {t.Format(s3TimeFormat)},
+			"X-Amz-Expires":       {strconv.FormatInt(int64(expires/time.Second), 10)},
+			"X-Amz-SignedHeaders": {"host"},
+		}
+	)
+	if b.SessionToken != "" {
+		q.Set("X-Amz-Security-Token", b.SessionToken)
+	}
+	u.RawQuery = canonicalQuery(q)
+	sig := b.signature(t, strings.ToUpper(method), u, http.Header{}, []string{"host"}, s3UnsignedPayload)
+	u.RawQuery += "&X-Amz-Signature=" + sig
+	return u.String(), nil
+}
+
+func (b *S3Backend) do(method string, u *url.URL, data []byte) ([]byte, error) {
+	var (
+		t           = b.time()
+		sum         = sha256.Sum256(data)
+		payloadHash = hex.EncodeToString(sum[:])
+		header      = http.Header{}
+	)
+	u.RawQuery = canonicalQuery(u.Query())
+	header.Set("X-Amz-Date", t.Format(s3TimeFormat))
+	header.Set("X-Amz-Content-Sha256", payloadHash)
+	if b.SessionToken != "" {
+		header.Set("X-Amz-Security-Token", b.SessionToken)
+	}
+	signed := []string{"host"}
+	for k := range header {
+		signed = append(signed, strings.ToLower(k))
+	}
+	sort.Strings(signed)
+	header.Set("Authorization", s3Algorithm+" Credential="+b.AccessKey+"/"+b.scope(t)+
+		", SignedHeaders="+strings.Join(signed, ";")+
+		", Signature="+b.signature(t, method, u, header, signed, payloadHash))
+
+	req, err := http.NewRequest(method, u.String(), bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	req.Header = header
+	client := b.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode/100 != 2 {
+		return nil, s3Error(u, resp.StatusCode, body)
+	}
+	return body, nil
+}
+
+func s3Error(u *url.URL, status int, body []byte) error {
+	var e struct {
+		Code    string
+		Message string
+	}
+	_ = xml.Unmarshal(body, &e)
+	if status == http.StatusNotFound && e.Code != "NoSuchBucket" {
+		return ErrNotFound.NewError(u.Path)
+	}
+	msg := strconv.Itoa(status) + " " + http.StatusText(status)
+	if e.Code != "" {
+		msg += ": " + e.Code + ": " + e.Message
+	}
+	return &gad.Error{Name: "BlobError", Message: msg}
+}
+
+func (b *S3Backend) time() time.Time {
+	if b.now != nil {
+		return b.now().UTC()
+	}
+	return time.Now().UTC()
+}
+
+func (b *S3Backend) scope(t time.Time) string {
+	return t.Format("20060102") + "/" + b.Region + "/s3/aws4_request"
+}
+
+// signature returns the signature of the request with the signed headers
+// whose names are sorted lower case names.
+func (b *S3Backend) signature(t time.Time, method string, u *url.URL, header http.Header,
+	signed []string, payloadHash string) string {
+	var canonical strings.Builder
+	canonical.WriteString(method + "\n" + uriEncode(u.Path, false) + "\n" + u.RawQuery + "\n")
+	for _, h := range signed {
+		v := u.Host
+		if h != "host" {
+			v = strings.TrimSpace(header.Get(h))
+		}
+		canonical.WriteString(h + ":" + v + "\n")
+	}
+	canonical.WriteString("\n" + strings.Join(signed, ";") + "\n" + payloadHash)
+
+	sum := sha256.Sum256([]byte(canonical.String()))
+	toSign := s3Algorithm + "\n" + t.Format(s3TimeFormat) + "\n" + b.scope(t) + "\n" + hex.EncodeToString(sum[:])
+
+	key := hmacSHA256([]byte("AWS4"+b.SecretKey), t.Format("20060102"))
+	for _, s := range []string{b.Region, "s3", "aws4_request", toSign} {
+		key = hmacSHA256(key, s)
+	}
+	return hex.EncodeToString(key)
+}
+
+func hmacSHA256(key []byte, s string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(s))
+	return h.Sum(nil)
+}
+
+// canonicalQuery returns the query sorted by the keys with the URI encoded
+// keys and values.
+func canonicalQuery(q url.Values) string {
+	keys := make([]string, 0, len(q))
+	for k := range q {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var parts []string
+	for _, k := range keys {
+		for _, v := range q[k] {
+			parts = append(parts, uriEncode(k, true)+"="+uriEncode(v, true))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+// uriEncode encodes s as described by AWS signature version 4, the slashes
+// are encoded if encodeSlash is true.
+func uriEncode(s string, encodeSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9',
+			c == '-', c == '_', c == '.', c == '~', c == '/' && !encodeSlash:
+			b.WriteByte(c)
+		default:
+			b.WriteString("%" + strings.ToUpper(hex.EncodeToString([]byte{c})))
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,168 @@
+// Package cache provides cache module for Gad script language. Caches hold
+// a limited number of values which are evicted in least recently used order
+// and may expire after a time to live, so long running VMs like servers can
+// memoize results without Dicts growing without bound.
+package cache
+
+import (
+	"container/list"
+	"sync"
+	"time"
+
+	"github.com/gad-lang/gad"
+)
+
+// entry is a value of the cache list.
+type entry struct {
+	key     string
+	value   gad.Object
+	expires time.Time
+}
+
+// Cache is a least recently used cache of objects by string keys. If Sync is
+// set, the methods are safe for concurrent use like gad.SyncDict.
+type Cache struct {
+	// Max is the maximum number of entries, the least recently used entry is
+	// evicted when a new entry exceeds it. Zero means no limit.
+	Max int
+	// TTL is the default time to live of the entries, zero means they do not
+	// expire.
+	TTL  time.Duration
+	Sync bool
+
+	mu    sync.Mutex
+	ll    *list.List
+	items map[string]*list.Element
+	// now returns the current time to check the expiry of the entries.
+	now func() time.Time
+}
+
+// New returns a new Cache of max entries whose entries expire after ttl.
+func New(max int, ttl time.Duration) *Cache {
+	return &Cache{
+		Max:   max,
+		TTL:   ttl,
+		ll:    list.New(),
+		items: map[string]*list.Element{},
+		now:   time.Now,
+	}
+}
+
+func (c *Cache) lock() {
+	if c.Sync {
+		c.mu.Lock()
+	}
+}
+
+func (c *Cache) unlock() {
+	if c.Sync {
+		c.mu.Unlock()
+	}
+}
+
+// element returns the element of the key if it exists and is not expired,
+// expired elements are removed.
+func (c *Cache) element(key string) *list.Element {
+	el := c.items[key]
+	if el == nil {
+		return nil
+	}
+	if e := el.Value.(*entry); !e.expires.IsZero() && !c.now().Before(e.expires) {
+		c.remove(el)
+		return nil
+	}
+	return el
+}
+
+func (c *Cache) remove(el *list.Element) {
+	c.ll.Remove(el)
+	delete(c.items, el.Value.(*entry).key)
+}
+
+// Get returns the value of the key and marks it as the most recently used.
+func (c *Cache) Get(key string) (gad.Object, bool) {
+	c.lock()
+	defer c.unlock()
+	el := c.element(key)
+	if el == nil {
+		return nil, false
+	}
+	c.ll.MoveToFront(el)
+	return el.Value.(*entry).value, true
+}
+
+// Has reports whether the key exists without changing the usage order.
+func (c *Cache) Has(key string) bool {
+	c.lock()
+	defer c.unlock()
+	return c.element(key) != nil
+}
+
+// Set sets the value of the key expiring after ttl, zero ttl means the
+// entry does not expire. The least recently used entries are evicted if the
+// cache is full.
+func (c *Cache) Set(key string, value gad.Object, ttl time.Duration) {
+	c.lock()
+	defer c.unlock()
+	e := &entry{key: key, value: value}
+	if ttl > 0 {
+		e.expires = c.now().Add(ttl)
+	}
+	if el := c.items[key]; el != nil {
+		el.Value = e
+		c.ll.MoveToFront(el)
+		return
+	}
+	c.items[key] = c.ll.PushFront(e)
+	for c.Max > 0 && c.ll.Len() > c.Max {
+		c.remove(c.ll.Back())
+	}
+}
+
+// Delete deletes the key and reports whether it existed.
+func (c *Cache) Delete(key string) bool {
+	c.lock()
+	defer c.unlock()
+	el := c.element(key)
+	if el == nil {
+		return false
+	}
+	c.remove(el)
+	return true
+}
+
+// Len returns the number of the entries which are not expired.
+func (c *Cache) Len() int {
+	c.lock()
+	defer c.unlock()
+	for el := c.ll.Back(); el != nil; {
+		prev := el.Prev()
+		c.element(el.Value.(*entry).key)
+		el = prev
+	}
+	return c.ll.Len()
+}
+
+// Keys returns the keys of the entries which are not expired from the most
+// recently used to the least.
+func (c *Cache) Keys() []string {
+	c.lock()
+	defer c.unlock()
+	keys := make([]string, 0, c.ll.Len())
+	for el := c.ll.Front(); el != nil; {
+		next := el.Next()
+		if key := el.Value.(*entry).key; c.element(key) != nil {
+			keys = append(keys, key)
+		}
+		el = next
+	}
+	return keys
+}
+
+// Clear deletes all entries.
+func (c *Cache) Clear() {
+	c.lock()
+	defer c.unlock()
+	c.ll.Init()
+	c.items = map[string]*list.Element{}
+}
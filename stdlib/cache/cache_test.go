@@ -0,0 +1,67 @@
+package cache
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/gad-lang/gad"
+)
+
+func TestEviction(t *testing.T) {
+	c := New(2, 0)
+	c.Set("a", gad.Int(1), 0)
+	c.Set("b", gad.Int(2), 0)
+	_, ok := c.Get("a")
+	require.True(t, ok)
+	c.Set("c", gad.Int(3), 0)
+	require.Equal(t, []string{"c", "a"}, c.Keys())
+	require.False(t, c.Has("b"))
+
+	c.Set("a", gad.Int(4), 0)
+	v, _ := c.Get("a")
+	require.Equal(t, gad.Int(4), v)
+	require.Equal(t, 2, c.Len())
+}
+
+func TestExpiry(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := New(0, time.Minute)
+	c.now = func() time.Time { return now }
+	c.Set("a", gad.Int(1), c.TTL)
+	c.Set("b", gad.Int(2), time.Hour)
+	c.Set("c", gad.Int(3), 0)
+
+	now = now.Add(time.Minute)
+	_, ok := c.Get("a")
+	require.False(t, ok)
+	require.Equal(t, []string{"c", "b"}, c.Keys())
+
+	now = now.Add(time.Hour)
+	require.Equal(t, 1, c.Len())
+	require.False(t, c.Delete("b"))
+	require.True(t, c.Delete("c"))
+	require.Equal(t, 0, c.Len())
+}
+
+func TestSync(t *testing.T) {
+	c := New(10, 0)
+	c.Sync = true
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				key := string(rune('a' + (i+j)%20))
+				c.Set(key, gad.Int(j), 0)
+				c.Get(key)
+				c.Delete(string(rune('a' + j%20)))
+			}
+		}(i)
+	}
+	wg.Wait()
+	require.LessOrEqual(t, c.Len(), 10)
+}
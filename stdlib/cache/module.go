@@ -0,0 +1,57 @@
+package cache
+
+import (
+	"time"
+
+	"github.com/gad-lang/gad"
+)
+
+// Module represents cache module.
+var Module = gad.Dict{
+	// gad:doc
+	// # cache module
+	//
+	// ## Functions
+	// cache(; max=1000, ttl=0, sync=false) -> cache
+	// Returns a new cache of at most max keys, 0 for no limit. The least
+	// recently used keys are evicted when it is full. ttl is the default
+	// duration like time.Minute after which the values expire, 0 means the
+	// values do not expire. If sync is true, the cache is safe to be shared
+	// by the VMs running concurrently like syncDict.
+	"cache": TCache,
+}
+
+func newCache(c gad.Call) (_ gad.Object, err error) {
+	var (
+		max = &gad.NamedArgVar{
+			Name:          "max",
+			Value:         gad.Int(1000),
+			TypeAssertion: gad.TypeAssertionFromTypes(gad.TInt),
+		}
+		ttl = &gad.NamedArgVar{
+			Name:          "ttl",
+			Value:         gad.Int(0),
+			TypeAssertion: gad.TypeAssertionFromTypes(gad.TInt),
+		}
+		sync = &gad.NamedArgVar{
+			Name:          "sync",
+			Value:         gad.False,
+			TypeAssertion: gad.TypeAssertionFromTypes(gad.TBool),
+		}
+	)
+	if err = c.Args.CheckLen(0); err != nil {
+		return
+	}
+	if err = c.NamedArgs.Get(max, ttl, sync); err != nil {
+		return
+	}
+	if max.Value.(gad.Int) < 0 {
+		return nil, gad.ErrUnexpectedArgValue.NewError("negative max")
+	}
+	if ttl.Value.(gad.Int) < 0 {
+		return nil, gad.ErrUnexpectedArgValue.NewError("negative ttl")
+	}
+	ch := New(int(max.Value.(gad.Int)), time.Duration(ttl.Value.(gad.Int)))
+	ch.Sync = !sync.Value.IsFalsy()
+	return &Object{Value: ch}, nil
+}
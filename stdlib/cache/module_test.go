@@ -0,0 +1,52 @@
+package cache_test
+
+import (
+	"testing"
+
+	"github.com/gad-lang/gad"
+	"github.com/gad-lang/gad/stdlib/cache"
+)
+
+func expectRun(t *testing.T, script string, expected gad.Object) {
+	t.Helper()
+	opts := gad.NewTestOpts().Module("cache", cache.Module)
+	gad.TestExpectRun(t, `cache := import("cache");`+script, opts, expected)
+}
+
+func expectErr(t *testing.T, script string, expected error) {
+	t.Helper()
+	gad.TestExpectRunErr(t, `cache := import("cache");`+script, gad.NewTestOpts().Module("cache", cache.Module), expected)
+}
+
+func TestCache(t *testing.T) {
+	expectRun(t, `c := cache.cache(max=2)
+	c.set("a", 1)
+	c.set(2, "b")
+	c.get("a")
+	c.set("c", 3)
+	return [c.get("a"), c.get(2), c.get("2", default="x"), c.has("c"), len(c), c.keys, c.max, c.ttl]`,
+		gad.Array{gad.Int(1), gad.Nil, gad.Str("x"), gad.True, gad.Int(2),
+			gad.Array{gad.Str("a"), gad.Str("c")}, gad.Int(2), gad.Int(0)})
+
+	expectRun(t, `c := cache.cache(ttl=60000000000, sync=true)
+	return [c.ttl, c.delete("a"), c.set("a", 1), c.delete("a"), c.len, c.clear(), str(c)]`,
+		gad.Array{gad.Int(60000000000), gad.False, gad.Nil, gad.True, gad.Int(0), gad.Nil, gad.Str("cache(0)")})
+}
+
+func TestGetOrSet(t *testing.T) {
+	expectRun(t, `c := cache.cache()
+	calls := 0
+	f := func(k) { calls++; return k * 2 }
+	return [c.getOrSet(21, f), c.getOrSet(21, f), calls, c.get("21")]`,
+		gad.Array{gad.Int(42), gad.Int(42), gad.Int(1), gad.Int(42)})
+	expectRun(t, `c := cache.cache()
+	try { c.getOrSet("a", func(k) { throw "x" }) } catch err {}
+	return c.has("a")`, gad.False)
+}
+
+func TestErrors(t *testing.T) {
+	expectErr(t, `cache.cache(max=-1)`, gad.ErrUnexpectedArgValue)
+	expectErr(t, `cache.cache(ttl="1m")`, gad.ErrType)
+	expectErr(t, `cache.cache().getOrSet("a", 1)`, gad.ErrType)
+	expectErr(t, `cache.cache().foo()`, gad.ErrInvalidIndex)
+}
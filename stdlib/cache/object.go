@@ -0,0 +1,189 @@
+package cache
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gad-lang/gad"
+)
+
+// gad:doc
+// ## Types
+// ### cache
+//
+// Go Type
+//
+// ```go
+// // Object represents a Cache and implements gad.Object interface.
+// type Object struct {
+//   Value *Cache
+// }
+// ```
+
+// TCache is the type of cache objects, calling it returns a new cache.
+var TCache = &gad.BuiltinObjType{
+	NameValue: "cache",
+	Value:     newCache,
+}
+
+// Object represents a Cache and implements gad.Object interface.
+type Object struct {
+	Value *Cache
+}
+
+var (
+	_ gad.Object           = (*Object)(nil)
+	_ gad.IndexGetter      = (*Object)(nil)
+	_ gad.NameCallerObject = (*Object)(nil)
+	_ gad.LengthGetter     = (*Object)(nil)
+)
+
+func (*Object) Type() gad.ObjectType {
+	return TCache
+}
+
+// ToString implements gad.Object interface.
+func (o *Object) ToString() string {
+	return "cache(" + strconv.Itoa(o.Value.Len()) + ")"
+}
+
+// IsFalsy implements gad.Object interface.
+func (o *Object) IsFalsy() bool {
+	return false
+}
+
+// Equal implements gad.Object interface.
+func (o *Object) Equal(right gad.Object) bool {
+	v, ok := right.(*Object)
+	return ok && v.Value == o.Value
+}
+
+// Length implements gad.LengthGetter interface.
+func (o *Object) Length() int {
+	return o.Value.Len()
+}
+
+// gad:doc
+// #### cache Getters
+//
+// | Selector  | Return Type                                          |
+// |:----------|:-----------------------------------------------------|
+// |.len       | int                                                  |
+// |.max       | int                                                  |
+// |.ttl       | int, duration                                        |
+// |.keys      | array, from the most recently used to the least      |
+
+// IndexGet implements gad.IndexGetter interface.
+func (o *Object) IndexGet(_ *gad.VM, index gad.Object) (gad.Object, error) {
+	switch index.ToString() {
+	case "len":
+		return gad.Int(o.Value.Len()), nil
+	case "max":
+		return gad.Int(o.Value.Max), nil
+	case "ttl":
+		return gad.Int(o.Value.TTL), nil
+	case "keys":
+		keys := o.Value.Keys()
+		ret := make(gad.Array, len(keys))
+		for i, k := range keys {
+			ret[i] = gad.Str(k)
+		}
+		return ret, nil
+	}
+	return nil, gad.ErrInvalidIndex.NewError(index.ToString())
+}
+
+// gad:doc
+// #### cache Methods
+//
+// | Method                                  | Return Type                    |
+// |:----------------------------------------|:-------------------------------|
+// |.get(key; default=nil)                   | any                            |
+// |.has(key)                                | bool                           |
+// |.set(key, value; ttl=cache ttl)          | nil                            |
+// |.getOrSet(key, fn callable; ttl=cache ttl) | any                          |
+// |.delete(key)                             | bool                           |
+// |.clear()                                 | nil                            |
+//
+// Keys are converted to str like the keys of dicts. get returns the value of
+// the key or default if it does not exist or is expired, and marks the key
+// as the most recently used. has does not change the usage order. set evicts
+// the least recently used keys if the cache is full. getOrSet returns the
+// value of the key, or sets it to the result of fn called with the key and
+// returns it. fn is not called under the lock of a sync cache, so concurrent
+// calls may compute the value of a key more than once. delete reports
+// whether the key existed.
+
+// CallName implements gad.NameCallerObject interface.
+func (o *Object) CallName(name string, c gad.Call) (_ gad.Object, err error) {
+	var (
+		key = &gad.Arg{Name: "key"}
+		ttl = &gad.NamedArgVar{
+			Name:          "ttl",
+			Value:         gad.Int(o.Value.TTL),
+			TypeAssertion: gad.TypeAssertionFromTypes(gad.TInt),
+		}
+	)
+	switch name {
+	case "get":
+		def := &gad.NamedArgVar{Name: "default", Value: gad.Nil}
+		if err = c.Args.Destructure(key); err != nil {
+			return
+		}
+		if err = c.NamedArgs.Get(def); err != nil {
+			return
+		}
+		if v, ok := o.Value.Get(key.Value.ToString()); ok {
+			return v, nil
+		}
+		return def.Value, nil
+	case "has":
+		if err = c.Args.Destructure(key); err != nil {
+			return
+		}
+		return gad.Bool(o.Value.Has(key.Value.ToString())), nil
+	case "set":
+		value := &gad.Arg{Name: "value"}
+		if err = c.Args.Destructure(key, value); err != nil {
+			return
+		}
+		if err = c.NamedArgs.Get(ttl); err != nil {
+			return
+		}
+		o.Value.Set(key.Value.ToString(), value.Value, time.Duration(ttl.Value.(gad.Int)))
+		return gad.Nil, nil
+	case "getOrSet":
+		fn := &gad.Arg{
+			Name:          "fn",
+			TypeAssertion: gad.NewTypeAssertion(gad.TypeAssertionHandlers{"callable": gad.Callable}),
+		}
+		if err = c.Args.Destructure(key, fn); err != nil {
+			return
+		}
+		if err = c.NamedArgs.Get(ttl); err != nil {
+			return
+		}
+		k := key.Value.ToString()
+		if v, ok := o.Value.Get(k); ok {
+			return v, nil
+		}
+		var v gad.Object
+		if v, err = gad.NewInvoker(c.VM, fn.Value).Invoke(gad.Args{gad.Array{key.Value}}, &gad.NamedArgs{}); err != nil {
+			return
+		}
+		o.Value.Set(k, v, time.Duration(ttl.Value.(gad.Int)))
+		return v, nil
+	case "delete":
+		if err = c.Args.Destructure(key); err != nil {
+			return
+		}
+		return gad.Bool(o.Value.Delete(key.Value.ToString())), nil
+	case "clear":
+		if err = c.Args.CheckLen(0); err != nil {
+			return
+		}
+		o.Value.Clear()
+		return gad.Nil, nil
+	}
+	return nil, gad.ErrInvalidIndex.NewError(name)
+}
@@ -0,0 +1,229 @@
+// Package cron provides cron module for Gad script language to parse cron
+// expressions, to compute their fire times and to describe them, e.g. in
+// scheduler configuration scripts.
+package cron
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// bounds are the minimum and maximum values and the names of a field.
+type bounds struct {
+	name     string
+	min, max int
+	names    []string
+}
+
+var (
+	minutes = bounds{name: "minute", max: 59}
+	hours   = bounds{name: "hour", max: 23}
+	days    = bounds{name: "day-of-month", min: 1, max: 31}
+	months  = bounds{name: "month", min: 1, max: 12, names: []string{"", "January", "February", "March",
+		"April", "May", "June", "July", "August", "September", "October", "November", "December"}}
+	weekdays = bounds{name: "day-of-week", max: 6, names: []string{"Sunday", "Monday", "Tuesday",
+		"Wednesday", "Thursday", "Friday", "Saturday"}}
+)
+
+var macros = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// item is a comma separated item of a field, lo through hi every step.
+type item struct {
+	lo, hi, step int
+	star         bool
+}
+
+// field is a parsed field of an expression.
+type field struct {
+	bits  uint64
+	items []item
+	// star reports whether the field starts with "*" like "*" or "*/5".
+	star bool
+}
+
+func (f *field) has(v int) bool {
+	return f.bits&(1<<uint(v)) != 0
+}
+
+// all reports whether the field is "*".
+func (f *field) all() bool {
+	return len(f.items) == 1 && f.items[0].star && f.items[0].step == 1
+}
+
+// single returns the value of the field if it is a single value.
+func (f *field) single() (int, bool) {
+	if len(f.items) == 1 && f.items[0].lo == f.items[0].hi {
+		return f.items[0].lo, true
+	}
+	return 0, false
+}
+
+// Schedule is a parsed cron expression with the fields minute, hour,
+// day-of-month, month and day-of-week.
+type Schedule struct {
+	expr                          string
+	minute, hour, dom, month, dow field
+}
+
+// Parse parses the cron expression of five fields separated by spaces, or
+// one of the macros @yearly, @annually, @monthly, @weekly, @daily, @midnight
+// and @hourly. Fields are lists of values, ranges like 1-5 and steps like
+// */15 or 0-30/10. Months and days of week can be the first three letters of
+// their English names, Sunday is 0 or 7.
+func Parse(expr string) (*Schedule, error) {
+	s := &Schedule{expr: strings.TrimSpace(expr)}
+	spec := s.expr
+	if strings.HasPrefix(spec, "@") {
+		var ok bool
+		if spec, ok = macros[strings.ToLower(spec)]; !ok {
+			return nil, errors.New("unknown macro " + strconv.Quote(s.expr))
+		}
+	}
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, errors.New("expected 5 fields, found " + strconv.Itoa(len(fields)))
+	}
+	for i, f := range []struct {
+		dst *field
+		b   bounds
+	}{
+		{&s.minute, minutes},
+		{&s.hour, hours},
+		{&s.dom, days},
+		{&s.month, months},
+		{&s.dow, weekdays},
+	} {
+		var err error
+		if *f.dst, err = parseField(fields[i], f.b); err != nil {
+			return nil, errors.New(f.b.name + ": " + err.Error())
+		}
+	}
+	return s, nil
+}
+
+func parseField(s string, b bounds) (f field, err error) {
+	f.star = strings.HasPrefix(s, "*")
+	for _, part := range strings.Split(s, ",") {
+		var it item
+		if it, err = parseItem(part, b); err != nil {
+			return
+		}
+		for v := it.lo; v <= it.hi; v += it.step {
+			f.bits |= 1 << uint(v)
+		}
+		f.items = append(f.items, it)
+	}
+	return
+}
+
+func parseItem(s string, b bounds) (it item, err error) {
+	max := b.max
+	if b.name == weekdays.name {
+		// 7 is Sunday too
+		max = 7
+	}
+	it.step = 1
+	r, step, hasStep := strings.Cut(s, "/")
+	if hasStep {
+		if it.step, err = strconv.Atoi(step); err != nil || it.step < 1 {
+			return it, errors.New("invalid step " + strconv.Quote(step))
+		}
+	}
+	switch lo, hi, isRange := strings.Cut(r, "-"); {
+	case r == "*":
+		it.star = true
+		it.lo, it.hi = b.min, b.max
+	case isRange:
+		if it.lo, err = parseValue(lo, b, max); err != nil {
+			return
+		}
+		if it.hi, err = parseValue(hi, b, max); err != nil {
+			return
+		}
+		if it.hi < it.lo {
+			return it, errors.New("invalid range " + strconv.Quote(r))
+		}
+	default:
+		if it.lo, err = parseValue(r, b, max); err != nil {
+			return
+		}
+		it.hi = it.lo
+		if hasStep {
+			it.hi = max
+		}
+	}
+	if it.lo == 7 && it.hi == 7 {
+		it.lo, it.hi = 0, 0
+	}
+	return
+}
+
+func parseValue(s string, b bounds, max int) (int, error) {
+	for i, name := range b.names {
+		if name != "" && strings.EqualFold(s, name[:3]) {
+			return i, nil
+		}
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil || v < b.min || v > max {
+		return 0, errors.New("invalid value " + strconv.Quote(s))
+	}
+	return v, nil
+}
+
+// String returns the expression of the schedule.
+func (s *Schedule) String() string {
+	return s.expr
+}
+
+// Matches reports whether t in its location matches the schedule regardless
+// of the seconds.
+func (s *Schedule) Matches(t time.Time) bool {
+	return s.month.has(int(t.Month())) && s.dayMatches(t) && s.hour.has(t.Hour()) && s.minute.has(t.Minute())
+}
+
+// dayMatches matches day-of-month and day-of-week fields, if both of them
+// are restricted one of them must match like cron does.
+func (s *Schedule) dayMatches(t time.Time) bool {
+	dom := s.dom.has(t.Day())
+	dow := s.dow.has(int(t.Weekday())) || t.Weekday() == time.Sunday && s.dow.has(7)
+	if s.dom.star || s.dow.star {
+		return dom && dow
+	}
+	return dom || dow
+}
+
+// Next returns the first fire time after t in the location of t, or the zero
+// time if there is none in five years like for "0 0 30 2 *". The times
+// skipped by daylight saving time changes do not fire.
+func (s *Schedule) Next(t time.Time) time.Time {
+	loc := t.Location()
+	t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), 0, 0, loc).Add(time.Minute)
+	for limit := t.Year() + 5; t.Year() <= limit; {
+		switch {
+		case !s.month.has(int(t.Month())):
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc)
+		case !s.dayMatches(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc)
+		case !s.hour.has(t.Hour()):
+			// adds durations as the hours may be repeated or skipped by
+			// daylight saving time changes
+			t = t.Add(time.Duration(60-t.Minute()) * time.Minute)
+		case !s.minute.has(t.Minute()):
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
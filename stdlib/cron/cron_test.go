@@ -0,0 +1,75 @@
+package cron_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/gad-lang/gad/stdlib/cron"
+)
+
+func TestNext(t *testing.T) {
+	at := func(s string) time.Time {
+		v, err := time.Parse("2006-01-02 15:04", s)
+		require.NoError(t, err)
+		return v
+	}
+	for _, tc := range []struct {
+		expr, after, next string
+	}{
+		{"* * * * *", "2024-01-01 10:00", "2024-01-01 10:01"},
+		{"*/15 * * * *", "2024-01-01 10:14", "2024-01-01 10:15"},
+		{"*/15 * * * *", "2024-01-01 10:45", "2024-01-01 11:00"},
+		{"0 9 * * mon-fri", "2024-01-05 09:00", "2024-01-08 09:00"},
+		{"0 0 1 * *", "2024-01-31 23:59", "2024-02-01 00:00"},
+		{"0 0 29 2 *", "2024-03-01 00:00", "2028-02-29 00:00"},
+		{"0 0 13 * fri", "2024-01-01 00:00", "2024-01-05 00:00"},
+		{"30 23 31 * *", "2024-04-01 00:00", "2024-05-31 23:30"},
+		{"@hourly", "2024-12-31 23:30", "2025-01-01 00:00"},
+		{"0 12 * * 7", "2024-01-01 00:00", "2024-01-07 12:00"},
+		{"0 0 30 2 *", "2024-01-01 00:00", ""},
+	} {
+		s, err := cron.Parse(tc.expr)
+		require.NoError(t, err, tc.expr)
+		next := s.Next(at(tc.after).Add(30 * time.Second))
+		if tc.next == "" {
+			require.True(t, next.IsZero(), tc.expr)
+		} else {
+			require.Equal(t, at(tc.next), next, tc.expr)
+		}
+	}
+
+	// the location of the time is used
+	loc, err := time.LoadLocation("America/New_York")
+	if err == nil {
+		s, _ := cron.Parse("30 2 * * *")
+		// 02:30 does not exist at the start of daylight saving time
+		next := s.Next(time.Date(2024, 3, 9, 12, 0, 0, 0, loc))
+		require.Equal(t, time.Date(2024, 3, 11, 2, 30, 0, 0, loc), next)
+	}
+}
+
+func TestParse(t *testing.T) {
+	for _, expr := range []string{"", "* * * *", "60 * * * *", "* 24 * * *", "* * 0 * *", "* * * 13 *",
+		"* * * * 8", "*/0 * * * *", "5-1 * * * *", "@reboot", "* * * foo *"} {
+		_, err := cron.Parse(expr)
+		require.Error(t, err, expr)
+	}
+	for expr, desc := range map[string]string{
+		"* * * * *":                  "At every minute.",
+		"*/5 * * * *":                "At every 5th minute.",
+		"0 9 * * 1-5":                "At 09:00 on every day-of-week from Monday through Friday.",
+		"30 * * * *":                 "At minute 30.",
+		"23 0-20/2 * * *":            "At minute 23 past every 2nd hour from 0 through 20.",
+		"0 0,12 1 */2 *":             "At minute 0 past hour 0 and 12 on day-of-month 1 in every 2nd month.",
+		"1,5-7 * * * *":              "At minute 1 and every minute from 5 through 7.",
+		"0 0 13 * 5":                 "At 00:00 on day-of-month 13 or on Friday.",
+		"@weekly":                    "At 00:00 on Sunday.",
+		"0 12 * jan-mar mon,wed,fri": "At 12:00 on Monday, Wednesday and Friday in every month from January through March.",
+	} {
+		s, err := cron.Parse(expr)
+		require.NoError(t, err)
+		require.Equal(t, desc, s.Describe(), expr)
+	}
+}
@@ -0,0 +1,97 @@
+package cron
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Describe returns the English description of the schedule like "At 09:00
+// on every day-of-week from Monday through Friday.".
+func (s *Schedule) Describe() string {
+	var b strings.Builder
+	b.WriteString("At ")
+	m, mOk := s.minute.single()
+	h, hOk := s.hour.single()
+	switch {
+	case mOk && hOk:
+		fmt.Fprintf(&b, "%02d:%02d", h, m)
+	case s.minute.all():
+		b.WriteString("every minute")
+	default:
+		b.WriteString(describeField(&s.minute, minutes))
+	}
+	if !(mOk && hOk) && !s.hour.all() {
+		b.WriteString(" past " + describeField(&s.hour, hours))
+	}
+	if !s.dom.all() {
+		b.WriteString(" on " + describeField(&s.dom, days))
+	}
+	if !s.dow.all() {
+		if !s.dom.all() {
+			b.WriteString(" or")
+		}
+		b.WriteString(" on " + describeField(&s.dow, weekdays))
+	}
+	if !s.month.all() {
+		b.WriteString(" in " + describeField(&s.month, months))
+	}
+	b.WriteString(".")
+	return b.String()
+}
+
+// describeField describes the items of the field like "minute 0 and 30" or
+// "every 2nd hour from 8 through 18".
+func describeField(f *field, b bounds) string {
+	parts := make([]string, len(f.items))
+	for i, it := range f.items {
+		switch {
+		case it.lo == it.hi:
+			parts[i] = valueName(it.lo, b)
+			if i == 0 && b.names == nil {
+				parts[i] = b.name + " " + parts[i]
+			}
+		case it.star:
+			parts[i] = "every " + ordinal(it.step) + b.name
+		default:
+			parts[i] = "every " + ordinal(it.step) + b.name + " from " + valueName(it.lo, b) +
+				" through " + valueName(it.hi, b)
+		}
+	}
+	return joinList(parts)
+}
+
+func valueName(v int, b bounds) string {
+	if b.names != nil {
+		return b.names[v%len(b.names)]
+	}
+	return strconv.Itoa(v)
+}
+
+// ordinal returns "" for 1, otherwise the ordinal number of n and a space.
+func ordinal(n int) string {
+	if n == 1 {
+		return ""
+	}
+	suffix := "th"
+	switch n % 10 {
+	case 1:
+		suffix = "st"
+	case 2:
+		suffix = "nd"
+	case 3:
+		suffix = "rd"
+	}
+	if n%100 >= 11 && n%100 <= 13 {
+		suffix = "th"
+	}
+	return strconv.Itoa(n) + suffix + " "
+}
+
+// joinList joins the parts like "a, b and c".
+func joinList(parts []string) string {
+	if len(parts) == 1 {
+		return parts[0]
+	}
+	return strings.Join(parts[:len(parts)-1], ", ") + " and " + parts[len(parts)-1]
+}
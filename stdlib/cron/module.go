@@ -0,0 +1,196 @@
+package cron
+
+import (
+	"time"
+
+	"github.com/gad-lang/gad"
+	gadtime "github.com/gad-lang/gad/stdlib/time"
+)
+
+// Module represents cron module.
+var Module = gad.Dict{
+	// gad:doc
+	// # cron module
+	//
+	// ## Functions
+	// parse(expr str) -> schedule
+	// Parses the cron expression of the fields minute, hour, day-of-month,
+	// month and day-of-week, e.g. "*/5 * * * *" or "0 9 * * mon-fri", or one
+	// of the macros @yearly, @annually, @monthly, @weekly, @daily, @midnight
+	// and @hourly. If both day-of-month and day-of-week are restricted, the
+	// days matching one of them match.
+	"parse": &gad.Function{
+		Name:  "parse",
+		Value: parseFunc,
+	},
+}
+
+func parseFunc(c gad.Call) (_ gad.Object, err error) {
+	expr := &gad.Arg{
+		Name:          "expr",
+		TypeAssertion: gad.TypeAssertionFromTypes(gad.TStr),
+	}
+	if err = c.Args.Destructure(expr); err != nil {
+		return
+	}
+	s, err := Parse(expr.Value.ToString())
+	if err != nil {
+		return nil, gad.ErrUnexpectedArgValue.NewError(err.Error())
+	}
+	return &Object{Value: s}, nil
+}
+
+// gad:doc
+// ## Types
+// ### schedule
+//
+// Go Type
+//
+// ```go
+// // Object represents a Schedule and implements gad.Object interface.
+// type Object struct {
+//   Value *Schedule
+// }
+// ```
+
+// TSchedule is the type of schedule objects.
+var TSchedule = &gad.BuiltinObjType{
+	NameValue: "schedule",
+}
+
+// Object represents a Schedule and implements gad.Object interface.
+type Object struct {
+	Value *Schedule
+}
+
+var (
+	_ gad.Object           = (*Object)(nil)
+	_ gad.IndexGetter      = (*Object)(nil)
+	_ gad.NameCallerObject = (*Object)(nil)
+	_ gad.Iterabler        = (*Object)(nil)
+)
+
+func (*Object) Type() gad.ObjectType {
+	return TSchedule
+}
+
+// ToString implements gad.Object interface.
+func (o *Object) ToString() string {
+	return o.Value.String()
+}
+
+// IsFalsy implements gad.Object interface.
+func (o *Object) IsFalsy() bool {
+	return false
+}
+
+// Equal implements gad.Object interface.
+func (o *Object) Equal(right gad.Object) bool {
+	v, ok := right.(*Object)
+	return ok && v.Value.String() == o.Value.String()
+}
+
+// gad:doc
+// #### schedule Getters
+//
+// | Selector      | Return Type                                         |
+// |:--------------|:----------------------------------------------------|
+// |.expr          | str                                                 |
+// |.description   | str, e.g. "At 09:00 on Monday."                     |
+
+// IndexGet implements gad.IndexGetter interface.
+func (o *Object) IndexGet(_ *gad.VM, index gad.Object) (gad.Object, error) {
+	switch index.ToString() {
+	case "expr":
+		return gad.Str(o.Value.String()), nil
+	case "description":
+		return gad.Str(o.Value.Describe()), nil
+	}
+	return nil, gad.ErrInvalidIndex.NewError(index.ToString())
+}
+
+// gad:doc
+// #### schedule Methods
+//
+// | Method                     | Return Type                               |
+// |:---------------------------|:------------------------------------------|
+// |.next(after time = now)     | time or nil                               |
+// |.times(after time = now)    | iterator of times                         |
+// |.matches(t time)            | bool                                      |
+//
+// next returns the first fire time after the time in its location, or nil
+// if there is none in five years. times iterates the fire times after the
+// time lazily and endlessly, iterating a schedule iterates its fire times
+// from now. matches reports whether the minute of t matches the schedule.
+
+// CallName implements gad.NameCallerObject interface.
+func (o *Object) CallName(name string, c gad.Call) (_ gad.Object, err error) {
+	switch name {
+	case "next", "times":
+		var after time.Time
+		if after, err = timeArg(c, true); err != nil {
+			return
+		}
+		if name == "times" {
+			return gad.IteratorObject(o.times(after)), nil
+		}
+		if t := o.Value.Next(after); !t.IsZero() {
+			return &gadtime.Time{Value: t}, nil
+		}
+		return gad.Nil, nil
+	case "matches":
+		var t time.Time
+		if t, err = timeArg(c, false); err != nil {
+			return
+		}
+		return gad.Bool(o.Value.Matches(t)), nil
+	}
+	return nil, gad.ErrInvalidIndex.NewError(name)
+}
+
+// timeArg returns the time argument, now if it is optional and not given.
+func timeArg(c gad.Call, optional bool) (time.Time, error) {
+	if optional && c.Args.Length() == 0 {
+		return time.Now(), nil
+	}
+	t := &gad.Arg{
+		Name:          "t",
+		TypeAssertion: gad.TypeAssertionFromTypes(gadtime.TimeType),
+	}
+	if err := c.Args.Destructure(t); err != nil {
+		return time.Time{}, err
+	}
+	return t.Value.(*gadtime.Time).Value, nil
+}
+
+// Iterate implements gad.Iterabler interface.
+func (o *Object) Iterate(*gad.VM, *gad.NamedArgs) gad.Iterator {
+	return o.times(time.Now())
+}
+
+// times returns the iterator of the fire times after t.
+func (o *Object) times(t time.Time) gad.Iterator {
+	// set sets the entry of the time after t at index i. state.Value holds
+	// them too as the entry may be changed by the wrapping iterators like
+	// map.
+	set := func(state *gad.IteratorState, i gad.Int, t time.Time) {
+		if t = o.Value.Next(t); t.IsZero() {
+			state.Mode = gad.IteratorStateModeDone
+			return
+		}
+		v := &gadtime.Time{Value: t}
+		state.Value = gad.Array{i, v}
+		state.Entry.K, state.Entry.V = i, v
+	}
+	return gad.NewIterator(
+		func(*gad.VM) (*gad.IteratorState, error) {
+			state := &gad.IteratorState{}
+			set(state, 0, t)
+			return state, nil
+		},
+		func(_ *gad.VM, state *gad.IteratorState) error {
+			cur := state.Value.(gad.Array)
+			set(state, cur[0].(gad.Int)+1, cur[1].(*gadtime.Time).Value)
+			return nil
+		}).SetInput(o).SetItType(TSchedule)
+}
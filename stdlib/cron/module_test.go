@@ -0,0 +1,45 @@
+package cron_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gad-lang/gad"
+	"github.com/gad-lang/gad/stdlib/cron"
+	gadtime "github.com/gad-lang/gad/stdlib/time"
+)
+
+func expectRun(t *testing.T, script string, expected gad.Object) {
+	t.Helper()
+	after := &gadtime.Time{Value: time.Date(2024, 1, 1, 10, 7, 0, 0, time.UTC)}
+	opts := gad.NewTestOpts().Module("cron", cron.Module).Globals(gad.Dict{"after": after})
+	gad.TestExpectRun(t, `global after; cron := import("cron");`+script, opts, expected)
+}
+
+func expectErr(t *testing.T, script string, expected error) {
+	t.Helper()
+	gad.TestExpectRunErr(t, `cron := import("cron");`+script, gad.NewTestOpts().Module("cron", cron.Module), expected)
+}
+
+func TestModule(t *testing.T) {
+	expectRun(t, `s := cron.parse("*/15 * * * *"); return [s.expr, s.description, str(s), typeName(s)]`,
+		gad.Array{gad.Str("*/15 * * * *"), gad.Str("At every 15th minute."), gad.Str("*/15 * * * *"), gad.Str("schedule")})
+	expectRun(t, `s := cron.parse("*/15 * * * *"); return [s.next(after).Minute, s.matches(after), s.matches(s.next(after))]`,
+		gad.Array{gad.Int(15), gad.False, gad.True})
+	expectRun(t, `return cron.parse("0 0 30 2 *").next(after)`, gad.Nil)
+	// fire times are iterated lazily
+	expectRun(t, `r := []; for i, t in cron.parse("0 */6 * * *").times(after) { r = append(r, [i, t.Hour]); if i == 3 { break } }
+		return r`,
+		gad.Array{
+			gad.Array{gad.Int(0), gad.Int(12)},
+			gad.Array{gad.Int(1), gad.Int(18)},
+			gad.Array{gad.Int(2), gad.Int(0)},
+			gad.Array{gad.Int(3), gad.Int(6)},
+		})
+	expectRun(t, `return collect(map(take(cron.parse("@daily").times(after), 2), func(t, _) { return t.Day }))`,
+		gad.Array{gad.Int(2), gad.Int(3)})
+	expectRun(t, `for t in cron.parse("* * * * *") { return typeName(t) }`, gad.Str("time"))
+
+	expectErr(t, `cron.parse("* * *")`, gad.ErrUnexpectedArgValue)
+	expectErr(t, `cron.parse("* * * * *").next(1)`, gad.ErrType)
+}
@@ -0,0 +1,121 @@
+// Package crypto provides crypto module for Gad script language to hash and
+// verify passwords by bcrypt and argon2id, and to inspect X.509 certificates.
+package crypto
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+)
+
+// Argon2Params are the parameters of argon2id hashes.
+type Argon2Params struct {
+	// Time is the number of passes over the memory.
+	Time uint32
+	// Memory is the size of the memory in KiB.
+	Memory uint32
+	// Threads is the number of threads.
+	Threads uint8
+	// KeyLen is the length of the hash in bytes.
+	KeyLen uint32
+	// SaltLen is the length of the random salt in bytes.
+	SaltLen uint32
+}
+
+// DefaultArgon2Params are the parameters recommended by RFC 9106 for memory
+// constrained environments.
+var DefaultArgon2Params = Argon2Params{
+	Time:    3,
+	Memory:  64 * 1024,
+	Threads: 4,
+	KeyLen:  32,
+	SaltLen: 16,
+}
+
+// Argon2Limits are the maximum parameters of the hashes verified by
+// Argon2Verify, so a crafted hash cannot make it allocate huge memory or run
+// for long. SaltLen is not limited.
+var Argon2Limits = Argon2Params{
+	Time:    16,
+	Memory:  256 * 1024,
+	Threads: 64,
+	KeyLen:  1024,
+}
+
+var b64 = base64.RawStdEncoding
+
+// Argon2Hash returns the argon2id hash of password with a random salt in the
+// PHC string format, e.g. "$argon2id$v=19$m=65536,t=3,p=4$<salt>$<hash>".
+func Argon2Hash(password []byte, p Argon2Params) (string, error) {
+	salt := make([]byte, p.SaltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	key := argon2.IDKey(password, salt, p.Time, p.Memory, p.Threads, p.KeyLen)
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s", argon2.Version,
+		p.Memory, p.Time, p.Threads, b64.EncodeToString(salt), b64.EncodeToString(key)), nil
+}
+
+// Argon2Verify reports whether password matches the argon2id hash in the PHC
+// string format. Hashes whose parameters are zero or exceed Argon2Limits are
+// invalid.
+func Argon2Verify(hash string, password []byte) (bool, error) {
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 || parts[0] != "" || parts[1] != "argon2id" {
+		return false, errors.New("invalid argon2id hash")
+	}
+	var (
+		version int
+		p       Argon2Params
+	)
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return false, errors.New("unsupported argon2id version " + parts[2])
+	}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &p.Memory, &p.Time, &p.Threads); err != nil {
+		return false, errors.New("invalid argon2id parameters " + parts[3])
+	}
+	if p.Time < 1 || p.Time > Argon2Limits.Time || p.Memory < 1 || p.Memory > Argon2Limits.Memory ||
+		p.Threads < 1 || p.Threads > Argon2Limits.Threads {
+		return false, errors.New("argon2id parameters out of range " + parts[3])
+	}
+	salt, err := b64.DecodeString(parts[4])
+	if err != nil {
+		return false, errors.New("invalid argon2id salt")
+	}
+	key, err := b64.DecodeString(parts[5])
+	if err != nil || len(key) == 0 || len(key) > int(Argon2Limits.KeyLen) {
+		return false, errors.New("invalid argon2id key")
+	}
+	other := argon2.IDKey(password, salt, p.Time, p.Memory, p.Threads, uint32(len(key)))
+	return subtle.ConstantTimeCompare(key, other) == 1, nil
+}
+
+// ParseCerts parses the certificates of the PEM blocks in data, other PEM
+// blocks are skipped. data is parsed as a DER certificate if it is not PEM.
+func ParseCerts(data []byte) (certs []*x509.Certificate, err error) {
+	var block *pem.Block
+	for rest := data; ; {
+		if block, rest = pem.Decode(rest); block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		var cert *x509.Certificate
+		if cert, err = x509.ParseCertificate(block.Bytes); err != nil {
+			return
+		}
+		certs = append(certs, cert)
+	}
+	if len(certs) == 0 {
+		return x509.ParseCertificates(data)
+	}
+	return
+}
@@ -0,0 +1,273 @@
+package crypto
+
+import (
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"errors"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/gad-lang/gad"
+	gadtime "github.com/gad-lang/gad/stdlib/time"
+)
+
+// Module represents crypto module.
+var Module = gad.Dict{
+	// gad:doc
+	// # crypto module
+	//
+	// ## Functions
+	// bcrypt(password str|bytes; cost=10) -> str
+	// Returns the bcrypt hash of password which is at most 72 bytes long.
+	"bcrypt": &gad.Function{
+		Name:  "bcrypt",
+		Value: bcryptFunc,
+	},
+	// gad:doc
+	// bcryptVerify(hash str, password str|bytes) -> bool
+	// Reports whether password matches the bcrypt hash.
+	"bcryptVerify": &gad.Function{
+		Name:  "bcryptVerify",
+		Value: bcryptVerifyFunc,
+	},
+	// gad:doc
+	// argon2(password str|bytes; time=3, memory=65536, threads=4, keyLen=32) -> str
+	// Returns the argon2id hash of password with a random salt in the PHC
+	// string format, e.g. "$argon2id$v=19$m=65536,t=3,p=4$<salt>$<hash>".
+	// memory is in KiB.
+	"argon2": &gad.Function{
+		Name:  "argon2",
+		Value: argon2Func,
+	},
+	// gad:doc
+	// argon2Verify(hash str, password str|bytes) -> bool
+	// Reports whether password matches the argon2id hash in the PHC string
+	// format. It throws ErrUnexpectedArgValue if the time,
+	// memory or threads parameters of the hash are zero or too large.
+	"argon2Verify": &gad.Function{
+		Name:  "argon2Verify",
+		Value: argon2VerifyFunc,
+	},
+	// gad:doc
+	// parseCert(data str|bytes) -> dict
+	// Parses the first X.509 certificate of the PEM or DER data, it returns
+	// a dict with the keys:
+	//
+	// - subject, issuer: dicts of commonName, organization,
+	//   organizationalUnit, country, province, locality and string keys
+	// - serial: hexadecimal serial number
+	// - notBefore, notAfter: time
+	// - dnsNames, ipAddresses, emailAddresses, uris: arrays of the subject
+	//   alternative names
+	// - isCA: bool
+	// - signatureAlgorithm, publicKeyAlgorithm: str
+	// - fingerprint: hexadecimal SHA-256 fingerprint
+	"parseCert": &gad.Function{
+		Name:  "parseCert",
+		Value: parseCertFunc,
+	},
+	// gad:doc
+	// parseCerts(data str|bytes) -> array
+	// Parses all X.509 certificates of the PEM or DER data like a chain, see
+	// parseCert.
+	"parseCerts": &gad.Function{
+		Name:  "parseCerts",
+		Value: parseCertsFunc,
+	},
+}
+
+func bytesArg(name string) *gad.Arg {
+	return &gad.Arg{
+		Name:          name,
+		TypeAssertion: gad.TypeAssertionFromTypes(gad.TStr, gad.TBytes),
+	}
+}
+
+func toBytes(o gad.Object) []byte {
+	if b, ok := o.(gad.Bytes); ok {
+		return b
+	}
+	return []byte(o.ToString())
+}
+
+func intArg(name string, value int) *gad.NamedArgVar {
+	return &gad.NamedArgVar{
+		Name:          name,
+		Value:         gad.Int(value),
+		TypeAssertion: gad.TypeAssertionFromTypes(gad.TInt),
+	}
+}
+
+func bcryptFunc(c gad.Call) (_ gad.Object, err error) {
+	var (
+		password = bytesArg("password")
+		cost     = intArg("cost", bcrypt.DefaultCost)
+		hash     []byte
+	)
+	if err = c.Args.Destructure(password); err != nil {
+		return
+	}
+	if err = c.NamedArgs.Get(cost); err != nil {
+		return
+	}
+	if hash, err = bcrypt.GenerateFromPassword(toBytes(password.Value), int(cost.Value.(gad.Int))); err != nil {
+		return nil, gad.ErrUnexpectedArgValue.NewError(err.Error())
+	}
+	return gad.Str(hash), nil
+}
+
+func verifyArgs(c gad.Call) (hash string, password []byte, err error) {
+	var (
+		h = &gad.Arg{
+			Name:          "hash",
+			TypeAssertion: gad.TypeAssertionFromTypes(gad.TStr),
+		}
+		p = bytesArg("password")
+	)
+	if err = c.Args.Destructure(h, p); err != nil {
+		return
+	}
+	return h.Value.ToString(), toBytes(p.Value), nil
+}
+
+func bcryptVerifyFunc(c gad.Call) (_ gad.Object, err error) {
+	hash, password, err := verifyArgs(c)
+	if err != nil {
+		return
+	}
+	switch err = bcrypt.CompareHashAndPassword([]byte(hash), password); {
+	case err == nil:
+		return gad.True, nil
+	case errors.Is(err, bcrypt.ErrMismatchedHashAndPassword):
+		return gad.False, nil
+	}
+	return nil, gad.ErrUnexpectedArgValue.NewError(err.Error())
+}
+
+func argon2Func(c gad.Call) (_ gad.Object, err error) {
+	var (
+		password = bytesArg("password")
+		p        = DefaultArgon2Params
+		t        = intArg("time", int(p.Time))
+		memory   = intArg("memory", int(p.Memory))
+		threads  = intArg("threads", int(p.Threads))
+		keyLen   = intArg("keyLen", int(p.KeyLen))
+		hash     string
+	)
+	if err = c.Args.Destructure(password); err != nil {
+		return
+	}
+	if err = c.NamedArgs.Get(t, memory, threads, keyLen); err != nil {
+		return
+	}
+	for _, v := range []*gad.NamedArgVar{t, memory, threads, keyLen} {
+		if n := v.Value.(gad.Int); n < 1 || n > 1<<32-1 || v == threads && n > 255 {
+			return nil, gad.ErrUnexpectedArgValue.NewError("invalid " + v.Name + " " + n.ToString())
+		}
+	}
+	p.Time = uint32(t.Value.(gad.Int))
+	p.Memory = uint32(memory.Value.(gad.Int))
+	p.Threads = uint8(threads.Value.(gad.Int))
+	p.KeyLen = uint32(keyLen.Value.(gad.Int))
+	if hash, err = Argon2Hash(toBytes(password.Value), p); err != nil {
+		return
+	}
+	return gad.Str(hash), nil
+}
+
+func argon2VerifyFunc(c gad.Call) (_ gad.Object, err error) {
+	hash, password, err := verifyArgs(c)
+	if err != nil {
+		return
+	}
+	ok, err := Argon2Verify(hash, password)
+	if err != nil {
+		return nil, gad.ErrUnexpectedArgValue.NewError(err.Error())
+	}
+	return gad.Bool(ok), nil
+}
+
+func parseCerts(c gad.Call) (certs []*x509.Certificate, err error) {
+	data := bytesArg("data")
+	if err = c.Args.Destructure(data); err != nil {
+		return
+	}
+	if certs, err = ParseCerts(toBytes(data.Value)); err != nil {
+		return nil, gad.ErrUnexpectedArgValue.NewError(err.Error())
+	}
+	if len(certs) == 0 {
+		return nil, gad.ErrUnexpectedArgValue.NewError("no certificate")
+	}
+	return
+}
+
+func parseCertFunc(c gad.Call) (gad.Object, error) {
+	certs, err := parseCerts(c)
+	if err != nil {
+		return nil, err
+	}
+	return certDict(certs[0]), nil
+}
+
+func parseCertsFunc(c gad.Call) (gad.Object, error) {
+	certs, err := parseCerts(c)
+	if err != nil {
+		return nil, err
+	}
+	ret := make(gad.Array, len(certs))
+	for i, cert := range certs {
+		ret[i] = certDict(cert)
+	}
+	return ret, nil
+}
+
+func strs(s []string) gad.Array {
+	ret := make(gad.Array, len(s))
+	for i, v := range s {
+		ret[i] = gad.Str(v)
+	}
+	return ret
+}
+
+func nameDict(n pkix.Name) gad.Dict {
+	return gad.Dict{
+		"commonName":         gad.Str(n.CommonName),
+		"organization":       strs(n.Organization),
+		"organizationalUnit": strs(n.OrganizationalUnit),
+		"country":            strs(n.Country),
+		"province":           strs(n.Province),
+		"locality":           strs(n.Locality),
+		"string":             gad.Str(n.String()),
+	}
+}
+
+func certDict(cert *x509.Certificate) gad.Dict {
+	var (
+		ips         = make(gad.Array, len(cert.IPAddresses))
+		uris        = make(gad.Array, len(cert.URIs))
+		fingerprint = sha256.Sum256(cert.Raw)
+	)
+	for i, ip := range cert.IPAddresses {
+		ips[i] = gad.Str(ip.String())
+	}
+	for i, u := range cert.URIs {
+		uris[i] = gad.Str(u.String())
+	}
+	return gad.Dict{
+		"subject":            nameDict(cert.Subject),
+		"issuer":             nameDict(cert.Issuer),
+		"serial":             gad.Str(cert.SerialNumber.Text(16)),
+		"notBefore":          &gadtime.Time{Value: cert.NotBefore},
+		"notAfter":           &gadtime.Time{Value: cert.NotAfter},
+		"dnsNames":           strs(cert.DNSNames),
+		"ipAddresses":        ips,
+		"emailAddresses":     strs(cert.EmailAddresses),
+		"uris":               uris,
+		"isCA":               gad.Bool(cert.IsCA),
+		"signatureAlgorithm": gad.Str(cert.SignatureAlgorithm.String()),
+		"publicKeyAlgorithm": gad.Str(cert.PublicKeyAlgorithm.String()),
+		"fingerprint":        gad.Str(hex.EncodeToString(fingerprint[:])),
+	}
+}
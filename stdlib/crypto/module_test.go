@@ -0,0 +1,80 @@
+package crypto_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/gad-lang/gad"
+	"github.com/gad-lang/gad/stdlib/crypto"
+)
+
+func expectRun(t *testing.T, script string, expected gad.Object) {
+	t.Helper()
+	opts := gad.NewTestOpts().Module("crypto", crypto.Module)
+	gad.TestExpectRun(t, `crypto := import("crypto");`+script, opts, expected)
+}
+
+func expectErr(t *testing.T, script string, expected error) {
+	t.Helper()
+	gad.TestExpectRunErr(t, `crypto := import("crypto");`+script, gad.NewTestOpts().Module("crypto", crypto.Module), expected)
+}
+
+func TestBcrypt(t *testing.T) {
+	expectRun(t, `h := crypto.bcrypt("secret"; cost=4)
+		return [h[:7], crypto.bcryptVerify(h, "secret"), crypto.bcryptVerify(h, bytes("other"))]`,
+		gad.Array{gad.Str("$2a$04$"), gad.True, gad.False})
+	expectErr(t, `crypto.bcryptVerify("x", "secret")`, gad.ErrUnexpectedArgValue)
+	expectErr(t, `crypto.bcrypt("secret"; cost=99)`, gad.ErrUnexpectedArgValue)
+}
+
+func TestArgon2(t *testing.T) {
+	expectRun(t, `h := crypto.argon2("secret"; time=1, memory=64, threads=1, keyLen=16)
+		return [h[:28], crypto.argon2Verify(h, "secret"), crypto.argon2Verify(h, "other")]`,
+		gad.Array{gad.Str("$argon2id$v=19$m=64,t=1,p=1$"), gad.True, gad.False})
+	// the hash of the reference implementation
+	expectRun(t, `return crypto.argon2Verify(
+		"$argon2id$v=19$m=65536,t=2,p=1$c29tZXNhbHQ$CTFhFdXPJO1aFaMaO6Mm5c8y7cJHAph8ArZWb2GRPPc", "password")`,
+		gad.True)
+	expectErr(t, `crypto.argon2Verify("$argon2i$v=19$m=64,t=1,p=1$c2FsdA$a2V5", "secret")`, gad.ErrUnexpectedArgValue)
+	expectErr(t, `crypto.argon2("secret"; threads=256)`, gad.ErrUnexpectedArgValue)
+	for _, params := range []string{"m=64,t=0,p=1", "m=64,t=1,p=0", "m=0,t=1,p=1", "m=4294967295,t=1,p=1", "m=64,t=100,p=1"} {
+		expectErr(t, `crypto.argon2Verify("$argon2id$v=19$`+params+`$c2FsdA$a2V5", "secret")`, gad.ErrUnexpectedArgValue)
+	}
+}
+
+func TestParseCert(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(0xabc),
+		Subject:      pkix.Name{CommonName: "example.com", Organization: []string{"Example"}},
+		NotBefore:    time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		NotAfter:     time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+		DNSNames:     []string{"example.com", "www.example.com"},
+		IPAddresses:  []net.IP{net.ParseIP("10.0.0.1")},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	require.NoError(t, err)
+	data := strconv.Quote(string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})))
+
+	expectRun(t, `c := crypto.parseCert(`+data+`)
+		return [c.subject.commonName, c.subject.organization, c.issuer.string, c.serial, c.dnsNames,
+			c.ipAddresses, c.isCA, c.publicKeyAlgorithm, c.notAfter.Year, len(c.fingerprint)]`,
+		gad.Array{gad.Str("example.com"), gad.Array{gad.Str("Example")}, gad.Str("CN=example.com,O=Example"),
+			gad.Str("abc"), gad.Array{gad.Str("example.com"), gad.Str("www.example.com")},
+			gad.Array{gad.Str("10.0.0.1")}, gad.False, gad.Str("ECDSA"), gad.Int(2025), gad.Int(64)})
+	expectRun(t, `return len(crypto.parseCerts(`+data+` + `+data+`))`, gad.Int(2))
+
+	expectErr(t, `crypto.parseCert("not a cert")`, gad.ErrUnexpectedArgValue)
+}
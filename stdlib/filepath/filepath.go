@@ -4,27 +4,36 @@ import (
 	"path/filepath"
 
 	"github.com/gad-lang/gad"
+	"github.com/gad-lang/gad/stdlib/vfs"
 )
 
 var (
 	TWalkIterator = &gad.Type{TypeName: "WalkIterator", Parent: gad.TIterator}
-	Module        = gad.Dict{
-		"ext":          gad.MustNewReflectValue(filepath.Ext),
-		"clean":        gad.MustNewReflectValue(filepath.Clean),
-		"join":         gad.MustNewReflectValue(filepath.Join),
-		"base":         gad.MustNewReflectValue(filepath.Base),
-		"dir":          gad.MustNewReflectValue(filepath.Dir),
-		"isAbs":        gad.MustNewReflectValue(filepath.IsAbs),
-		"isLocal":      gad.MustNewReflectValue(filepath.IsLocal),
-		"rel":          gad.MustNewReflectValue(filepath.Rel),
-		"volumeName":   gad.MustNewReflectValue(filepath.VolumeName),
-		"split":        gad.MustNewReflectValue(filepath.Split),
-		"match":        gad.MustNewReflectValue(filepath.Match),
-		"fromSlash":    gad.MustNewReflectValue(filepath.FromSlash),
-		"evalSymlinks": gad.MustNewReflectValue(filepath.EvalSymlinks),
+	// Module is the filepath module using the filesystem of the operating
+	// system.
+	Module = NewModule(vfs.OS)
+)
+
+// NewModule returns the filepath module whose file operations use fsys.
+func NewModule(fsys vfs.FS) gad.Dict {
+	m := gad.Dict{
+		"ext":        gad.MustNewReflectValue(filepath.Ext),
+		"clean":      gad.MustNewReflectValue(filepath.Clean),
+		"join":       gad.MustNewReflectValue(filepath.Join),
+		"base":       gad.MustNewReflectValue(filepath.Base),
+		"dir":        gad.MustNewReflectValue(filepath.Dir),
+		"isAbs":      gad.MustNewReflectValue(filepath.IsAbs),
+		"isLocal":    gad.MustNewReflectValue(filepath.IsLocal),
+		"rel":        gad.MustNewReflectValue(filepath.Rel),
+		"volumeName": gad.MustNewReflectValue(filepath.VolumeName),
+		"split":      gad.MustNewReflectValue(filepath.Split),
+		"match":      gad.MustNewReflectValue(filepath.Match),
+		"fromSlash":  gad.MustNewReflectValue(filepath.FromSlash),
 		"walk": &gad.BuiltinFunction{
-			Name:  "walk",
-			Value: Walk,
+			Name: "walk",
+			Value: func(c gad.Call) (gad.Object, error) {
+				return walk(fsys, c)
+			},
 		},
 		TWalkSkip.TypeName: TWalkSkip,
 		"glob": &gad.BuiltinFunction{
@@ -39,7 +48,7 @@ var (
 					arr     gad.Array
 				)
 
-				if matched, err = filepath.Glob(c.Args.GetOnly(0).ToString()); err != nil {
+				if matched, err = vfs.Glob(fsys, c.Args.GetOnly(0).ToString()); err != nil {
 					return
 				}
 
@@ -72,4 +81,11 @@ var (
 			},
 		},
 	}
-)
+
+	if fsys == vfs.OS {
+		// symbolic links are resolved only in the filesystem of the operating
+		// system.
+		m["evalSymlinks"] = gad.MustNewReflectValue(filepath.EvalSymlinks)
+	}
+	return m
+}
@@ -6,6 +6,7 @@ import (
 	"strings"
 
 	"github.com/gad-lang/gad"
+	"github.com/gad-lang/gad/stdlib/vfs"
 )
 
 const (
@@ -61,7 +62,11 @@ func (f *WalkSkip) Parse(str string) {
 	}
 }
 
-func Walk(c gad.Call) (_ gad.Object, err error) {
+func Walk(c gad.Call) (gad.Object, error) {
+	return walk(vfs.OS, c)
+}
+
+func walk(fsys vfs.FS, c gad.Call) (_ gad.Object, err error) {
 	var (
 		pth = &gad.Arg{
 			Name:          "path",
@@ -119,7 +124,7 @@ func Walk(c gad.Call) (_ gad.Object, err error) {
 
 	first := true
 
-	return gad.Nil, filepath.Walk(basePath, func(path string, info fs.FileInfo, err error) (err2 error) {
+	return gad.Nil, vfs.Walk(fsys, basePath, func(path string, info fs.FileInfo, err error) (err2 error) {
 		if first {
 			first = false
 			if !dotSkip.Value.IsFalsy() {
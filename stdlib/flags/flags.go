@@ -0,0 +1,99 @@
+// Package flags provides flags module for Gad script language to evaluate
+// feature flags. The host injects a Flags object evaluating the flags by a
+// Provider, like a feature flag service client, into the scripts, and tests
+// override the flags locally without the provider.
+package flags
+
+import (
+	"github.com/gad-lang/gad"
+)
+
+// Provider evaluates the feature flags.
+type Provider interface {
+	// Value returns the value of the flag for the context, ok is false if
+	// the flag is not defined.
+	Value(name string, context gad.Dict) (value gad.Object, ok bool, err error)
+}
+
+// ProviderFunc is a function implementing Provider interface.
+type ProviderFunc func(name string, context gad.Dict) (value gad.Object, ok bool, err error)
+
+// Value implements Provider interface.
+func (f ProviderFunc) Value(name string, context gad.Dict) (gad.Object, bool, error) {
+	return f(name, context)
+}
+
+// Static is a Provider of the flag values by the names regardless of the
+// context.
+type Static gad.Dict
+
+// Value implements Provider interface.
+func (s Static) Value(name string, _ gad.Dict) (value gad.Object, ok bool, _ error) {
+	value, ok = s[name]
+	return
+}
+
+// Flags is the object evaluating the feature flags by Provider unless they
+// are overridden.
+type Flags struct {
+	gad.ObjectImpl
+	// Provider evaluates the flags which are not overridden, nil means no
+	// flag is defined.
+	Provider Provider
+	// Overrides are the values of the flags by the names which are used
+	// instead of the values of Provider.
+	Overrides gad.Dict
+}
+
+// New returns a new Flags object evaluating the flags by p.
+func New(p Provider) *Flags {
+	return &Flags{Provider: p, Overrides: gad.Dict{}}
+}
+
+// Value returns the overridden value of the flag or the value of Provider
+// for the context.
+func (f *Flags) Value(name string, context gad.Dict) (gad.Object, bool, error) {
+	if v, ok := f.Overrides[name]; ok {
+		return v, true, nil
+	}
+	if f.Provider == nil {
+		return nil, false, nil
+	}
+	return f.Provider.Value(name, context)
+}
+
+// Typed returns the value of the flag if its type is the type of def, or
+// def if the flag is not defined or has another type. Any value is accepted
+// if def is nil.
+func (f *Flags) Typed(name string, def gad.Object, context gad.Dict) (gad.Object, error) {
+	v, ok, err := f.Value(name, context)
+	if err != nil || !ok {
+		return def, err
+	}
+	if def != gad.Nil && v.Type() != def.Type() {
+		return def, nil
+	}
+	return v, nil
+}
+
+// IsEnabled reports whether the value of the flag is true for the context.
+func (f *Flags) IsEnabled(name string, context gad.Dict) (bool, error) {
+	v, err := f.Typed(name, gad.False, context)
+	if err != nil {
+		return false, err
+	}
+	return v == gad.True, nil
+}
+
+// WithOverrides returns a copy of f whose overrides are the overrides of f
+// updated by overrides.
+func (f *Flags) WithOverrides(overrides gad.Dict) *Flags {
+	o := make(gad.Dict, len(f.Overrides)+len(overrides))
+	for k, v := range f.Overrides {
+		o[k] = v
+	}
+	for k, v := range overrides {
+		o[k] = v
+	}
+	return &Flags{Provider: f.Provider, Overrides: o}
+}
@@ -0,0 +1,32 @@
+package flags
+
+import (
+	"github.com/gad-lang/gad"
+)
+
+// Module represents flags module.
+var Module = gad.Dict{
+	// gad:doc
+	// # flags module
+	//
+	// ## Functions
+	// static(values dict) -> flags
+	// Returns the flags of the values by the names regardless of the context,
+	// which is useful in tests and for the hosts without a feature flag
+	// service. The host injects its flags object evaluated by its provider.
+	"static": &gad.Function{
+		Name:  "static",
+		Value: staticFunc,
+	},
+}
+
+func staticFunc(c gad.Call) (_ gad.Object, err error) {
+	values := &gad.Arg{
+		Name:          "values",
+		TypeAssertion: gad.TypeAssertionFromTypes(gad.TDict),
+	}
+	if err = c.Args.Destructure(values); err != nil {
+		return
+	}
+	return New(Static(values.Value.(gad.Dict).Copy().(gad.Dict))), nil
+}
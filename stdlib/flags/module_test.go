@@ -0,0 +1,80 @@
+package flags_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/gad-lang/gad"
+	"github.com/gad-lang/gad/stdlib/flags"
+)
+
+var errProvider = errors.New("provider down")
+
+// provider enables beta for the users of the beta group and fails for broken.
+var provider = flags.ProviderFunc(func(name string, context gad.Dict) (gad.Object, bool, error) {
+	switch name {
+	case "beta":
+		return gad.Bool(context["group"] == gad.Str("beta")), true, nil
+	case "limit":
+		return gad.Int(10), true, nil
+	case "broken":
+		return nil, false, errProvider
+	}
+	return nil, false, nil
+})
+
+func expectRun(t *testing.T, script string, expected gad.Object) {
+	t.Helper()
+	opts := gad.NewTestOpts().
+		Module("flags", flags.Module).
+		Globals(gad.Dict{"features": flags.New(provider)})
+	gad.TestExpectRun(t, `flags := import("flags"); global features;`+script, opts, expected)
+}
+
+func TestIsEnabled(t *testing.T) {
+	expectRun(t, `return [
+		features.isEnabled("beta"; group="beta"),
+		features.isEnabled("beta"; group="users"),
+		features.isEnabled("beta"),
+		features.isEnabled("limit"),
+		features.isEnabled("unknown"),
+	]`, gad.Array{gad.True, gad.False, gad.False, gad.False, gad.False})
+}
+
+func TestValue(t *testing.T) {
+	expectRun(t, `return [
+		features.value("limit", 5),
+		features.value("limit", "5"),
+		features.value("limit"),
+		features.value("unknown", 5),
+		features.value("unknown"),
+	]`, gad.Array{gad.Int(10), gad.Str("5"), gad.Int(10), gad.Int(5), gad.Nil})
+}
+
+func TestOverrides(t *testing.T) {
+	expectRun(t, `f := features.withOverrides({beta: true, limit: 1})
+	g := f.withOverrides({limit: 2})
+	return [f.isEnabled("beta"), f.value("limit", 0), g.value("limit", 0), g.overrides,
+		features.isEnabled("beta"), features.overrides]`,
+		gad.Array{gad.True, gad.Int(1), gad.Int(2), gad.Dict{"beta": gad.True, "limit": gad.Int(2)},
+			gad.False, gad.Dict{}})
+
+	expectRun(t, `f := flags.static({beta: true, color: "blue"})
+	return [f.isEnabled("beta"; group="x"), f.value("color", ""), f.withOverrides({beta: false}).isEnabled("beta"), typeName(f)]`,
+		gad.Array{gad.True, gad.Str("blue"), gad.False, gad.Str("flags")})
+}
+
+func TestErrors(t *testing.T) {
+	f := flags.New(provider)
+	_, err := f.IsEnabled("broken", nil)
+	require.ErrorIs(t, err, errProvider)
+	v, err := f.WithOverrides(gad.Dict{"broken": gad.True}).IsEnabled("broken", nil)
+	require.NoError(t, err)
+	require.True(t, v)
+
+	ok, err := flags.New(nil).IsEnabled("beta", nil)
+	require.NoError(t, err)
+	require.False(t, ok)
+}
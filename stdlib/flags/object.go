@@ -0,0 +1,126 @@
+package flags
+
+import (
+	"github.com/gad-lang/gad"
+)
+
+// gad:doc
+// ## Types
+// ### flags
+//
+// Go Type
+//
+// ```go
+// // Flags is the object evaluating the feature flags by Provider unless they
+// // are overridden.
+// type Flags struct {
+//   gad.ObjectImpl
+//   Provider  Provider
+//   Overrides gad.Dict
+// }
+// ```
+
+// TFlags is the type of Flags objects.
+var TFlags = &gad.BuiltinObjType{
+	NameValue: "flags",
+}
+
+var (
+	_ gad.IndexGetter      = (*Flags)(nil)
+	_ gad.NameCallerObject = (*Flags)(nil)
+)
+
+func (*Flags) Type() gad.ObjectType {
+	return TFlags
+}
+
+// ToString implements gad.Object interface.
+func (f *Flags) ToString() string {
+	return "flags(" + f.Overrides.ToString() + ")"
+}
+
+// IsFalsy implements gad.Object interface.
+func (f *Flags) IsFalsy() bool {
+	return false
+}
+
+// Equal implements gad.Object interface.
+func (f *Flags) Equal(right gad.Object) bool {
+	v, ok := right.(*Flags)
+	return ok && v == f
+}
+
+// gad:doc
+// #### flags Getters
+//
+// | Selector   | Return Type                                         |
+// |:-----------|:----------------------------------------------------|
+// |.overrides  | dict                                                |
+
+// IndexGet implements gad.IndexGetter interface.
+func (f *Flags) IndexGet(_ *gad.VM, index gad.Object) (gad.Object, error) {
+	switch index.ToString() {
+	case "overrides":
+		return f.Overrides.Copy(), nil
+	}
+	return nil, gad.ErrInvalidIndex.NewError(index.ToString())
+}
+
+// gad:doc
+// #### flags Methods
+//
+// | Method                                   | Return Type                   |
+// |:-----------------------------------------|:------------------------------|
+// |.isEnabled(name str; **context)           | bool                          |
+// |.value(name str, default=nil; **context)  | any                           |
+// |.withOverrides(overrides dict)            | flags                         |
+//
+// The flags are evaluated for the context, like the user or the tenant, by
+// the provider of the host unless they are overridden. isEnabled returns
+// true if the value of the flag is true. value returns the value of the flag
+// if it has the type of default, or default if the flag is not defined or
+// has another type, so flags fall back to safe values. Any value is
+// returned if default is nil. withOverrides returns a copy of the flags
+// whose flags of overrides have their values, which is useful in tests.
+
+// CallName implements gad.NameCallerObject interface.
+func (f *Flags) CallName(name string, c gad.Call) (_ gad.Object, err error) {
+	flag := &gad.Arg{
+		Name:          "name",
+		TypeAssertion: gad.TypeAssertionFromTypes(gad.TStr),
+	}
+	switch name {
+	case "isEnabled":
+		if err = c.Args.Destructure(flag); err != nil {
+			return
+		}
+		var ok bool
+		if ok, err = f.IsEnabled(flag.Value.ToString(), c.NamedArgs.Dict()); err != nil {
+			return
+		}
+		return gad.Bool(ok), nil
+	case "value":
+		if err = c.Args.CheckRangeLen(1, 2); err != nil {
+			return
+		}
+		var rest gad.Array
+		if rest, err = c.Args.DestructureVar(flag); err != nil {
+			return
+		}
+		def := gad.Object(gad.Nil)
+		if len(rest) == 1 {
+			def = rest[0]
+		}
+		return f.Typed(flag.Value.ToString(), def, c.NamedArgs.Dict())
+	case "withOverrides":
+		overrides := &gad.Arg{
+			Name:          "overrides",
+			TypeAssertion: gad.TypeAssertionFromTypes(gad.TDict),
+		}
+		if err = c.Args.Destructure(overrides); err != nil {
+			return
+		}
+		return f.WithOverrides(overrides.Value.(gad.Dict)), nil
+	}
+	return nil, gad.ErrInvalidIndex.NewError(name)
+}
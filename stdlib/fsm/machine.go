@@ -0,0 +1,220 @@
+// Package fsm provides fsm module for Gad script language to define finite
+// state machines with guarded transitions and callbacks, e.g. the states of
+// workflow scripts.
+package fsm
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/gad-lang/gad"
+)
+
+// ErrTransition is returned if an event is not allowed in the current state.
+var ErrTransition = &gad.Error{Name: "TransitionError"}
+
+// Any is the from state of the transitions allowed in all states.
+const Any = "*"
+
+// Transition is a transition of a machine.
+type Transition struct {
+	Event string
+	// From are the states the event is allowed in, all states if it is nil.
+	From []string
+	To   string
+	// Guard allows the transition if it returns a truthy value.
+	Guard gad.Object
+	// Action is called after leaving the from state.
+	Action gad.Object
+}
+
+func (t *Transition) allowed(state string) bool {
+	if t.From == nil {
+		return true
+	}
+	for _, s := range t.From {
+		if s == state {
+			return true
+		}
+	}
+	return false
+}
+
+// Machine is a finite state machine. The callbacks are called with the from
+// state, the to state, the event and the arguments of Fire, and the named
+// arguments of Fire.
+type Machine struct {
+	States      []string
+	Transitions []*Transition
+	Initial     string
+	// OnEnter and OnExit are the callbacks by the states.
+	OnEnter map[string]gad.Object
+	OnExit  map[string]gad.Object
+	// OnTransition is called after every transition.
+	OnTransition gad.Object
+
+	state string
+}
+
+// State returns the current state.
+func (m *Machine) State() string {
+	if m.state == "" {
+		return m.Initial
+	}
+	return m.state
+}
+
+// Reset sets the current state to the initial state.
+func (m *Machine) Reset() {
+	m.state = m.Initial
+}
+
+// Validate checks the states of the machine.
+func (m *Machine) Validate() error {
+	if len(m.States) == 0 {
+		return gad.ErrUnexpectedArgValue.NewError("no states")
+	}
+	states := make(map[string]bool, len(m.States))
+	for _, s := range m.States {
+		if s == "" || s == Any || states[s] {
+			return gad.ErrUnexpectedArgValue.NewError("invalid or duplicate state " + strconv.Quote(s))
+		}
+		states[s] = true
+	}
+	check := func(s string) error {
+		if !states[s] {
+			return gad.ErrUnexpectedArgValue.NewError("unknown state " + strconv.Quote(s))
+		}
+		return nil
+	}
+	if err := check(m.Initial); err != nil {
+		return err
+	}
+	for _, t := range m.Transitions {
+		if t.Event == "" {
+			return gad.ErrUnexpectedArgValue.NewError("transition without event")
+		}
+		if err := check(t.To); err != nil {
+			return err
+		}
+		for _, s := range t.From {
+			if err := check(s); err != nil {
+				return err
+			}
+		}
+	}
+	for _, callbacks := range []map[string]gad.Object{m.OnEnter, m.OnExit} {
+		for s := range callbacks {
+			if err := check(s); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Can reports whether the event has a transition from the current state
+// regardless of the guards.
+func (m *Machine) Can(event string) bool {
+	for _, t := range m.Transitions {
+		if t.Event == event && t.allowed(m.State()) {
+			return true
+		}
+	}
+	return false
+}
+
+// Events returns the events having transitions from the current state.
+func (m *Machine) Events() (events []string) {
+	seen := map[string]bool{}
+	for _, t := range m.Transitions {
+		if !seen[t.Event] && t.allowed(m.State()) {
+			seen[t.Event] = true
+			events = append(events, t.Event)
+		}
+	}
+	return
+}
+
+// Fire makes the first transition of the event from the current state whose
+// guard allows it, and reports whether a transition is made. It returns
+// ErrTransition if the event has no transition from the current state. The
+// callbacks are called in order guard, exit of the from state, action, enter
+// of the to state and on transition, the state is changed before enter.
+func (m *Machine) Fire(vm *gad.VM, event string, args gad.Array, namedArgs *gad.NamedArgs) (ok bool, err error) {
+	if namedArgs == nil {
+		namedArgs = &gad.NamedArgs{}
+	}
+	var (
+		from  = m.State()
+		found bool
+	)
+	for _, t := range m.Transitions {
+		if t.Event != event || !t.allowed(from) {
+			continue
+		}
+		found = true
+		call := func(fn gad.Object) (gad.Object, error) {
+			if fn == nil || fn == gad.Nil {
+				return gad.True, nil
+			}
+			return gad.NewInvoker(vm, fn).Invoke(
+				gad.Args{append(gad.Array{gad.Str(from), gad.Str(t.To), gad.Str(event)}, args...)},
+				namedArgs.Copy().(*gad.NamedArgs))
+		}
+		var allowed gad.Object
+		if allowed, err = call(t.Guard); err != nil || allowed.IsFalsy() {
+			return
+		}
+		if _, err = call(m.OnExit[from]); err != nil {
+			return
+		}
+		if _, err = call(t.Action); err != nil {
+			return
+		}
+		m.state = t.To
+		if _, err = call(m.OnEnter[t.To]); err != nil {
+			return
+		}
+		if _, err = call(m.OnTransition); err != nil {
+			return
+		}
+		return true, nil
+	}
+	if !found {
+		err = ErrTransition.NewError("event " + strconv.Quote(event) + " is not allowed in state " + strconv.Quote(from))
+	}
+	return
+}
+
+// Dot returns the graph of the machine in Graphviz dot language, the current
+// state is filled.
+func (m *Machine) Dot(name string) string {
+	var b strings.Builder
+	b.WriteString("digraph " + strconv.Quote(name) + " {\n")
+	b.WriteString("  rankdir=LR;\n  node [shape=circle];\n")
+	b.WriteString("  \"\" [shape=point];\n  \"\" -> " + strconv.Quote(m.Initial) + ";\n")
+	for _, s := range m.States {
+		b.WriteString("  " + strconv.Quote(s))
+		if s == m.State() {
+			b.WriteString(" [style=filled]")
+		}
+		b.WriteString(";\n")
+	}
+	for _, t := range m.Transitions {
+		from := t.From
+		if from == nil {
+			from = m.States
+		}
+		label := t.Event
+		if t.Guard != nil && t.Guard != gad.Nil {
+			label += " [guarded]"
+		}
+		for _, s := range from {
+			b.WriteString("  " + strconv.Quote(s) + " -> " + strconv.Quote(t.To) +
+				" [label=" + strconv.Quote(label) + "];\n")
+		}
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
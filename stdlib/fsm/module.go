@@ -0,0 +1,155 @@
+package fsm
+
+import (
+	"strconv"
+
+	"github.com/gad-lang/gad"
+)
+
+// Module represents fsm module.
+var Module = gad.Dict{
+	// gad:doc
+	// # fsm module
+	//
+	// ## Functions
+	// fsm(states array, transitions array; initial=states[0], onEnter={}, onExit={}, onTransition=nil) -> fsm
+	// Returns a new state machine of the state names. transitions are dicts
+	// with the keys:
+	//
+	// - event: str
+	// - from: str or array of the states the event is allowed in, "*" for
+	//   all states
+	// - to: str
+	// - guard: optional function allowing the transition if it returns a
+	//   truthy value
+	// - action: optional function called during the transition
+	//
+	// onEnter and onExit are the dicts of the functions by the states,
+	// onTransition is called after every transition.
+	"fsm": TMachine,
+	// gad:doc
+	// ## Errors
+	// TransitionError is thrown by fire if the event is not allowed in the
+	// current state.
+	"TransitionError": ErrTransition,
+}
+
+var callable = gad.TypeAssertionFromTypes(gad.TNil).AcceptHandler("callable", gad.Callable)
+
+func newMachine(c gad.Call) (_ gad.Object, err error) {
+	var (
+		states = &gad.Arg{
+			Name:          "states",
+			TypeAssertion: gad.TypeAssertionFromTypes(gad.TArray),
+		}
+		transitions = &gad.Arg{
+			Name:          "transitions",
+			TypeAssertion: gad.TypeAssertionFromTypes(gad.TArray),
+		}
+		initial = &gad.NamedArgVar{
+			Name:          "initial",
+			Value:         gad.Nil,
+			TypeAssertion: gad.TypeAssertionFromTypes(gad.TStr, gad.TNil),
+		}
+		onEnter = &gad.NamedArgVar{
+			Name:          "onEnter",
+			Value:         gad.Dict{},
+			TypeAssertion: gad.TypeAssertionFromTypes(gad.TDict),
+		}
+		onExit = &gad.NamedArgVar{
+			Name:          "onExit",
+			Value:         gad.Dict{},
+			TypeAssertion: gad.TypeAssertionFromTypes(gad.TDict),
+		}
+		onTransition = &gad.NamedArgVar{
+			Name:          "onTransition",
+			Value:         gad.Nil,
+			TypeAssertion: callable,
+		}
+		m = &Machine{OnEnter: map[string]gad.Object{}, OnExit: map[string]gad.Object{}}
+	)
+	if err = c.Args.Destructure(states, transitions); err != nil {
+		return
+	}
+	if err = c.NamedArgs.Get(initial, onEnter, onExit, onTransition); err != nil {
+		return
+	}
+	for _, s := range states.Value.(gad.Array) {
+		m.States = append(m.States, s.ToString())
+	}
+	if initial.Value != gad.Nil {
+		m.Initial = initial.Value.ToString()
+	} else if len(m.States) > 0 {
+		m.Initial = m.States[0]
+	}
+	for i, v := range transitions.Value.(gad.Array) {
+		var t *Transition
+		if t, err = toTransition("transitions["+strconv.Itoa(i)+"]", v); err != nil {
+			return
+		}
+		m.Transitions = append(m.Transitions, t)
+	}
+	for _, cb := range []struct {
+		arg *gad.NamedArgVar
+		m   map[string]gad.Object
+	}{{onEnter, m.OnEnter}, {onExit, m.OnExit}} {
+		for s, fn := range cb.arg.Value.(gad.Dict) {
+			if !gad.Callable(fn) {
+				return nil, gad.NewArgumentTypeError(cb.arg.Name+"."+s, "callable", fn.Type().Name())
+			}
+			cb.m[s] = fn
+		}
+	}
+	m.OnTransition = onTransition.Value
+	if err = m.Validate(); err != nil {
+		return
+	}
+	m.Reset()
+	return &Object{Value: m}, nil
+}
+
+// toTransition returns the transition of the dict o at pos of the arguments.
+func toTransition(pos string, o gad.Object) (t *Transition, err error) {
+	d, ok := o.(gad.Dict)
+	if !ok {
+		return nil, gad.NewArgumentTypeError(pos, "dict", o.Type().Name())
+	}
+	t = &Transition{}
+	for k, v := range d {
+		switch k {
+		case "event":
+			t.Event = v.ToString()
+		case "to":
+			t.To = v.ToString()
+		case "from":
+			switch f := v.(type) {
+			case gad.Str:
+				if f != Any {
+					t.From = []string{string(f)}
+				}
+			case gad.Array:
+				t.From = make([]string, len(f))
+				for i, s := range f {
+					t.From[i] = s.ToString()
+				}
+			default:
+				return nil, gad.NewArgumentTypeError(pos+".from", "str|array", v.Type().Name())
+			}
+		case "guard", "action":
+			if v != gad.Nil && !gad.Callable(v) {
+				return nil, gad.NewArgumentTypeError(pos+"."+k, "callable", v.Type().Name())
+			}
+			if k == "guard" {
+				t.Guard = v
+			} else {
+				t.Action = v
+			}
+		default:
+			return nil, gad.ErrUnexpectedArgValue.NewError(pos + ": unknown key " + strconv.Quote(k))
+		}
+	}
+	if _, ok = d["from"]; !ok {
+		return nil, gad.ErrUnexpectedArgValue.NewError(pos + ": missing from")
+	}
+	return
+}
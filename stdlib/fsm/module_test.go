@@ -0,0 +1,93 @@
+package fsm_test
+
+import (
+	"testing"
+
+	"github.com/gad-lang/gad"
+	"github.com/gad-lang/gad/stdlib/fsm"
+)
+
+const door = `m := fsm.fsm(["closed", "open", "locked"], [
+		{event: "open", from: "closed", to: "open"},
+		{event: "close", from: "open", to: "closed"},
+		{event: "lock", from: "closed", to: "locked", guard: func(from, to, event; key=nil) { return key == "k" }},
+		{event: "unlock", from: "locked", to: "closed"},
+		{event: "break", from: "*", to: "open"},
+	])
+`
+
+func expectRun(t *testing.T, script string, expected gad.Object) {
+	t.Helper()
+	opts := gad.NewTestOpts().Module("fsm", fsm.Module)
+	gad.TestExpectRun(t, `fsm := import("fsm");`+script, opts, expected)
+}
+
+func expectErr(t *testing.T, script string, expected error) {
+	t.Helper()
+	gad.TestExpectRunErr(t, `fsm := import("fsm");`+script, gad.NewTestOpts().Module("fsm", fsm.Module), expected)
+}
+
+func TestFire(t *testing.T) {
+	expectRun(t, door+`return [m.state, m.fire("open"), m.state, m.events, m.can("lock"), m.fire("close"), m.state]`,
+		gad.Array{gad.Str("closed"), gad.True, gad.Str("open"), gad.Array{gad.Str("close"), gad.Str("break")},
+			gad.False, gad.True, gad.Str("closed")})
+	// guards get the named arguments of fire
+	expectRun(t, door+`return [m.fire("lock"), m.state, m.fire("lock"; key="x"), m.fire("lock"; key="k"), m.state]`,
+		gad.Array{gad.False, gad.Str("closed"), gad.False, gad.True, gad.Str("locked")})
+	expectRun(t, door+`m.fire("lock"; key="k"); m.fire("break"); r := m.state; m.reset(); return [r, m.state, str(m)]`,
+		gad.Array{gad.Str("open"), gad.Str("closed"), gad.Str("fsm(closed)")})
+
+	expectErr(t, door+`m.fire("close")`, fsm.ErrTransition)
+	expectRun(t, door+`try { m.fire("unlock") } catch err { return isError(err, fsm.TransitionError) }`, gad.True)
+}
+
+func TestCallbacks(t *testing.T) {
+	expectRun(t, `log := []
+		m := fsm.fsm(["a", "b", "c"], [
+			{event: "go", from: ["a", "b"], to: "c", action: func(from, to, event, *args) { log = append(log, ["action", from, to, event, args]) }},
+		]; initial="b",
+			onExit={b: func(from, *_) { log = append(log, ["exit", from]) }},
+			onEnter={c: func(from, to, *_) { log = append(log, ["enter", to]) }},
+			onTransition=func(*_) { log = append(log, "done") })
+		m.fire("go", 1, 2)
+		return log`,
+		gad.Array{
+			gad.Array{gad.Str("exit"), gad.Str("b")},
+			gad.Array{gad.Str("action"), gad.Str("b"), gad.Str("c"), gad.Str("go"), gad.Array{gad.Int(1), gad.Int(2)}},
+			gad.Array{gad.Str("enter"), gad.Str("c")},
+			gad.Str("done"),
+		})
+	// a failed action keeps the state
+	expectRun(t, `m := fsm.fsm(["a", "b"], [{event: "go", from: "a", to: "b", action: func(*_) { throw "x" }}])
+		try { m.fire("go") } catch err { return m.state }`, gad.Str("a"))
+}
+
+func TestDot(t *testing.T) {
+	expectRun(t, door+`m.fire("open"); return m.dot(; name="door")`, gad.Str(`digraph "door" {
+  rankdir=LR;
+  node [shape=circle];
+  "" [shape=point];
+  "" -> "closed";
+  "closed";
+  "open" [style=filled];
+  "locked";
+  "closed" -> "open" [label="open"];
+  "open" -> "closed" [label="close"];
+  "closed" -> "locked" [label="lock [guarded]"];
+  "locked" -> "closed" [label="unlock"];
+  "closed" -> "open" [label="break"];
+  "open" -> "open" [label="break"];
+  "locked" -> "open" [label="break"];
+}
+`))
+}
+
+func TestInvalid(t *testing.T) {
+	expectErr(t, `fsm.fsm([], [])`, gad.ErrUnexpectedArgValue)
+	expectErr(t, `fsm.fsm(["a", "a"], [])`, gad.ErrUnexpectedArgValue)
+	expectErr(t, `fsm.fsm(["a"], []; initial="b")`, gad.ErrUnexpectedArgValue)
+	expectErr(t, `fsm.fsm(["a"], [{event: "e", from: "a", to: "b"}])`, gad.ErrUnexpectedArgValue)
+	expectErr(t, `fsm.fsm(["a"], [{event: "e", to: "a"}])`, gad.ErrUnexpectedArgValue)
+	expectErr(t, `fsm.fsm(["a"], [{event: "e", from: "a", to: "a", guard: 1}])`, gad.ErrType)
+	expectErr(t, `fsm.fsm(["a"], []; onEnter={b: func() {}})`, gad.ErrUnexpectedArgValue)
+}
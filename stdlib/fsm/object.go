@@ -0,0 +1,155 @@
+package fsm
+
+import (
+	"github.com/gad-lang/gad"
+)
+
+// gad:doc
+// ## Types
+// ### fsm
+//
+// Go Type
+//
+// ```go
+// // Object represents a Machine and implements gad.Object interface.
+// type Object struct {
+//   Value *Machine
+// }
+// ```
+
+// TMachine is the type of machine objects, calling it returns a new machine.
+var TMachine = &gad.BuiltinObjType{
+	NameValue: "fsm",
+	Value:     newMachine,
+}
+
+// Object represents a Machine and implements gad.Object interface.
+type Object struct {
+	Value *Machine
+}
+
+var (
+	_ gad.Object           = (*Object)(nil)
+	_ gad.IndexGetter      = (*Object)(nil)
+	_ gad.NameCallerObject = (*Object)(nil)
+)
+
+func (*Object) Type() gad.ObjectType {
+	return TMachine
+}
+
+// ToString implements gad.Object interface.
+func (o *Object) ToString() string {
+	return "fsm(" + o.Value.State() + ")"
+}
+
+// IsFalsy implements gad.Object interface.
+func (o *Object) IsFalsy() bool {
+	return false
+}
+
+// Equal implements gad.Object interface.
+func (o *Object) Equal(right gad.Object) bool {
+	v, ok := right.(*Object)
+	return ok && v.Value == o.Value
+}
+
+func strs(s []string) gad.Array {
+	ret := make(gad.Array, len(s))
+	for i, v := range s {
+		ret[i] = gad.Str(v)
+	}
+	return ret
+}
+
+// gad:doc
+// #### fsm Getters
+//
+// | Selector  | Return Type                                          |
+// |:----------|:-----------------------------------------------------|
+// |.state     | str                                                  |
+// |.initial   | str                                                  |
+// |.states    | array                                                |
+// |.events    | array, the events allowed in the current state       |
+
+// IndexGet implements gad.IndexGetter interface.
+func (o *Object) IndexGet(_ *gad.VM, index gad.Object) (gad.Object, error) {
+	switch index.ToString() {
+	case "state":
+		return gad.Str(o.Value.State()), nil
+	case "initial":
+		return gad.Str(o.Value.Initial), nil
+	case "states":
+		return strs(o.Value.States), nil
+	case "events":
+		return strs(o.Value.Events()), nil
+	}
+	return nil, gad.ErrInvalidIndex.NewError(index.ToString())
+}
+
+// gad:doc
+// #### fsm Methods
+//
+// | Method                            | Return Type                          |
+// |:----------------------------------|:-------------------------------------|
+// |.fire(event str, *args; **kwargs)  | bool                                 |
+// |.can(event str)                    | bool                                 |
+// |.reset()                           | nil                                  |
+// |.dot(; name="fsm")                 | str                                  |
+//
+// fire makes the first transition of the event from the current state whose
+// guard allows it and returns true, or returns false if the guards reject
+// it. It throws TransitionError if the event is not allowed in the current
+// state. The guard, onExit of the from state, the action, onEnter of the to
+// state and onTransition are called in order with the from state, the to
+// state, the event, args and kwargs. The state is changed before onEnter is
+// called. can reports whether the event is allowed in the current state
+// regardless of the guards. reset sets the state to the initial state. dot
+// returns the graph of the machine in Graphviz dot language.
+
+// CallName implements gad.NameCallerObject interface.
+func (o *Object) CallName(name string, c gad.Call) (_ gad.Object, err error) {
+	event := &gad.Arg{
+		Name:          "event",
+		TypeAssertion: gad.TypeAssertionFromTypes(gad.TStr),
+	}
+	switch name {
+	case "fire":
+		var (
+			args gad.Array
+			ok   bool
+		)
+		if args, err = c.Args.DestructureVar(event); err != nil {
+			return
+		}
+		if ok, err = o.Value.Fire(c.VM, event.Value.ToString(), args, &c.NamedArgs); err != nil {
+			return
+		}
+		return gad.Bool(ok), nil
+	case "can":
+		if err = c.Args.Destructure(event); err != nil {
+			return
+		}
+		return gad.Bool(o.Value.Can(event.Value.ToString())), nil
+	case "reset":
+		if err = c.Args.CheckLen(0); err != nil {
+			return
+		}
+		o.Value.Reset()
+		return gad.Nil, nil
+	case "dot":
+		if err = c.Args.CheckLen(0); err != nil {
+			return
+		}
+		graphName := &gad.NamedArgVar{
+			Name:          "name",
+			Value:         gad.Str("fsm"),
+			TypeAssertion: gad.TypeAssertionFromTypes(gad.TStr),
+		}
+		if err = c.NamedArgs.Get(graphName); err != nil {
+			return
+		}
+		return gad.Str(o.Value.Dot(graphName.Value.ToString())), nil
+	}
+	return nil, gad.ErrInvalidIndex.NewError(name)
+}
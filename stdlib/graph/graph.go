@@ -0,0 +1,268 @@
+// Package graph provides graph module for Gad script language to order the
+// dependencies, to find the shortest paths and the connected components of
+// directed graphs, e.g. in build and orchestration scripts.
+package graph
+
+import (
+	"container/heap"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/gad-lang/gad"
+)
+
+// ErrCycle is returned by TopoSort if the graph has a cycle.
+var ErrCycle = &gad.Error{Name: "CycleError"}
+
+// Graph is a directed graph with weighted edges. Nodes are identified by
+// their string values and kept in insertion order.
+type Graph struct {
+	nodes []gad.Object
+	index map[string]int
+	// out holds the weights of the edges by the indexes of their nodes
+	out []map[int]float64
+	in  []map[int]struct{}
+}
+
+// New returns a new empty Graph.
+func New() *Graph {
+	return &Graph{index: map[string]int{}}
+}
+
+// Len returns the number of the nodes.
+func (g *Graph) Len() int {
+	return len(g.nodes)
+}
+
+// Nodes returns the nodes in insertion order.
+func (g *Graph) Nodes() []gad.Object {
+	return append([]gad.Object(nil), g.nodes...)
+}
+
+// Edge is an edge of a graph.
+type Edge struct {
+	From, To gad.Object
+	Weight   float64
+}
+
+// Edges returns the edges ordered by the insertion order of their nodes.
+func (g *Graph) Edges() (edges []Edge) {
+	for i, out := range g.out {
+		for _, j := range sortedKeys(out) {
+			edges = append(edges, Edge{From: g.nodes[i], To: g.nodes[j], Weight: out[j]})
+		}
+	}
+	return
+}
+
+// AddNode adds the node if it does not exist and returns its index.
+func (g *Graph) AddNode(node gad.Object) int {
+	key := node.ToString()
+	if i, ok := g.index[key]; ok {
+		return i
+	}
+	g.index[key] = len(g.nodes)
+	g.nodes = append(g.nodes, node)
+	g.out = append(g.out, map[int]float64{})
+	g.in = append(g.in, map[int]struct{}{})
+	return len(g.nodes) - 1
+}
+
+// AddEdge adds the nodes and the edge from a node to another, the weight of
+// an existing edge is replaced.
+func (g *Graph) AddEdge(from, to gad.Object, weight float64) {
+	i, j := g.AddNode(from), g.AddNode(to)
+	g.out[i][j] = weight
+	g.in[j][i] = struct{}{}
+}
+
+// Index returns the index of the node or -1 if it does not exist.
+func (g *Graph) Index(node gad.Object) int {
+	if i, ok := g.index[node.ToString()]; ok {
+		return i
+	}
+	return -1
+}
+
+// TopoSort returns the nodes ordered so that all edges go forward, the first
+// added one of the nodes whose predecessors are sorted comes next. If the
+// graph has a cycle, ErrCycle with the nodes of a cycle is returned.
+func (g *Graph) TopoSort() ([]gad.Object, error) {
+	var (
+		degree = make([]int, len(g.nodes))
+		ready  intHeap
+		sorted = make([]gad.Object, 0, len(g.nodes))
+	)
+	for i := range g.nodes {
+		if degree[i] = len(g.in[i]); degree[i] == 0 {
+			ready = append(ready, i)
+		}
+	}
+	for len(ready) > 0 {
+		i := heap.Pop(&ready).(int)
+		sorted = append(sorted, g.nodes[i])
+		for j := range g.out[i] {
+			if degree[j]--; degree[j] == 0 {
+				heap.Push(&ready, j)
+			}
+		}
+	}
+	if len(sorted) < len(g.nodes) {
+		return nil, ErrCycle.NewError(g.cycle(degree))
+	}
+	return sorted, nil
+}
+
+// cycle returns a cycle of the nodes left by TopoSort like "a -> b -> a".
+func (g *Graph) cycle(degree []int) string {
+	// every left node has a left predecessor, walking them backwards finds
+	// a cycle
+	var (
+		i    int
+		seen = map[int]int{}
+		path []int
+	)
+	for degree[i] == 0 {
+		i++
+	}
+	for {
+		if at, ok := seen[i]; ok {
+			path = path[at:]
+			break
+		}
+		seen[i] = len(path)
+		path = append(path, i)
+		for _, j := range sortedKeys(g.in[i]) {
+			if degree[j] > 0 {
+				i = j
+				break
+			}
+		}
+	}
+	// reverses the path and starts it from the first added node
+	first := 0
+	for k, j := range path {
+		if j < path[first] {
+			first = k
+		}
+	}
+	names := make([]string, 0, len(path)+1)
+	for k := range path {
+		names = append(names, g.nodes[path[(first-k+len(path))%len(path)]].ToString())
+	}
+	return strings.Join(append(names, names[0]), " -> ")
+}
+
+// ShortestPath returns the nodes of the path with the least total weight
+// from a node to another by Dijkstra's algorithm, or nil if there is no
+// path. The weights must not be negative.
+func (g *Graph) ShortestPath(from, to int) []gad.Object {
+	var (
+		dist = make([]float64, len(g.nodes))
+		prev = make([]int, len(g.nodes))
+		q    = &distHeap{dist: dist}
+	)
+	for i := range dist {
+		dist[i] = math.Inf(1)
+		prev[i] = -1
+	}
+	dist[from] = 0
+	heap.Push(q, from)
+	for q.Len() > 0 {
+		i := heap.Pop(q).(int)
+		if i == to {
+			break
+		}
+		for _, j := range sortedKeys(g.out[i]) {
+			if d := dist[i] + g.out[i][j]; d < dist[j] {
+				dist[j] = d
+				prev[j] = i
+				heap.Push(q, j)
+			}
+		}
+	}
+	if math.IsInf(dist[to], 1) {
+		return nil
+	}
+	var path []gad.Object
+	for i := to; i != -1; i = prev[i] {
+		path = append(path, g.nodes[i])
+	}
+	for l, r := 0, len(path)-1; l < r; l, r = l+1, r-1 {
+		path[l], path[r] = path[r], path[l]
+	}
+	return path
+}
+
+// Components returns the weakly connected components, the nodes connected by
+// the edges regardless of their directions. Components and their nodes are
+// in insertion order.
+func (g *Graph) Components() (components [][]gad.Object) {
+	comp := make([]int, len(g.nodes))
+	for i := range comp {
+		comp[i] = -1
+	}
+	for i := range g.nodes {
+		if comp[i] >= 0 {
+			continue
+		}
+		c := len(components)
+		components = append(components, nil)
+		stack := []int{i}
+		comp[i] = c
+		for len(stack) > 0 {
+			j := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			visit := func(k int) {
+				if comp[k] < 0 {
+					comp[k] = c
+					stack = append(stack, k)
+				}
+			}
+			for k := range g.out[j] {
+				visit(k)
+			}
+			for k := range g.in[j] {
+				visit(k)
+			}
+		}
+	}
+	for i, c := range comp {
+		components[c] = append(components[c], g.nodes[i])
+	}
+	return
+}
+
+func sortedKeys[V any](m map[int]V) []int {
+	keys := make([]int, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Ints(keys)
+	return keys
+}
+
+// intHeap is a min heap of ints.
+type intHeap []int
+
+func (h intHeap) Len() int           { return len(h) }
+func (h intHeap) Less(i, j int) bool { return h[i] < h[j] }
+func (h intHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *intHeap) Push(x any)        { *h = append(*h, x.(int)) }
+func (h *intHeap) Pop() any {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
+
+// distHeap is a min heap of node indexes by their distances.
+type distHeap struct {
+	intHeap
+	dist []float64
+}
+
+func (h *distHeap) Less(i, j int) bool {
+	return h.dist[h.intHeap[i]] < h.dist[h.intHeap[j]]
+}
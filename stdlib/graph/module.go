@@ -0,0 +1,20 @@
+package graph
+
+import (
+	"github.com/gad-lang/gad"
+)
+
+// Module represents graph module.
+var Module = gad.Dict{
+	// gad:doc
+	// # graph module
+	//
+	// ## Functions
+	// graph() -> graph
+	// Returns a new empty directed graph.
+	"graph": TGraph,
+	// gad:doc
+	// ## Errors
+	// CycleError is thrown by topoSort if the graph has a cycle.
+	"CycleError": ErrCycle,
+}
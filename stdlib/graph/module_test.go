@@ -0,0 +1,69 @@
+package graph_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/gad-lang/gad"
+	"github.com/gad-lang/gad/stdlib/graph"
+)
+
+func expectRun(t *testing.T, script string, expected gad.Object) {
+	t.Helper()
+	opts := gad.NewTestOpts().Module("graph", graph.Module)
+	gad.TestExpectRun(t, `graph := import("graph");`+script, opts, expected)
+}
+
+func expectErr(t *testing.T, script string, expected error, msg string) {
+	t.Helper()
+	err := gad.TestExpectRunErr(t, `graph := import("graph");`+script, gad.NewTestOpts().Module("graph", graph.Module), expected)
+	require.Contains(t, err.Error(), msg)
+}
+
+func TestTopoSort(t *testing.T) {
+	expectRun(t, `g := graph.graph()
+		g.addEdge("lib", "app").addEdge("base", "lib").addEdge("base", "tools").addNode("docs")
+		return g.topoSort()`,
+		gad.Array{gad.Str("base"), gad.Str("lib"), gad.Str("app"), gad.Str("tools"), gad.Str("docs")})
+	expectRun(t, `return [graph.graph().topoSort(), typeName(graph.graph()), str(graph.graph().addEdge(1, 2))]`,
+		gad.Array{gad.Array{}, gad.Str("graph"), gad.Str("graph(nodes=2, edges=1)")})
+	expectRun(t, `g := graph.graph().addEdge(1, 2).addEdge(2, 3).addEdge(3, 1).addEdge(0, 1)
+		try { g.topoSort() } catch err { return isError(err, graph.CycleError) }`, gad.True)
+
+	expectErr(t, `graph.graph().addEdge("a", "b").addEdge("b", "c").addEdge("c", "b").addEdge("x", "a").topoSort()`,
+		graph.ErrCycle, "b -> c -> b")
+	expectErr(t, `graph.graph().addEdge("a", "a").topoSort()`, graph.ErrCycle, "a -> a")
+	expectErr(t, `graph.graph().addEdge(3, 1).addEdge(1, 2).addEdge(2, 3).topoSort()`, graph.ErrCycle, "3 -> 1 -> 2 -> 3")
+}
+
+func TestShortestPath(t *testing.T) {
+	script := `g := graph.graph()
+		g.addEdge("a", "b"; weight=4).addEdge("a", "c"; weight=1).addEdge("c", "b"; weight=1.5)
+		g.addEdge("b", "d").addEdge("e", "a")
+	`
+	expectRun(t, script+`return [g.shortestPath("a", "d"), g.shortestPath("d", "a"), g.shortestPath("a", "a")]`,
+		gad.Array{
+			gad.Array{gad.Str("a"), gad.Str("c"), gad.Str("b"), gad.Str("d")},
+			gad.Nil,
+			gad.Array{gad.Str("a")},
+		})
+	expectRun(t, script+`return g.edges[0]`,
+		gad.Dict{"from": gad.Str("a"), "to": gad.Str("b"), "weight": gad.Float(4)})
+
+	expectErr(t, `graph.graph().addEdge(1, 2).shortestPath(1, 3)`, gad.ErrUnexpectedArgValue, "unknown node 3")
+	expectErr(t, `graph.graph().addEdge(1, 2; weight=-1)`, gad.ErrUnexpectedArgValue, "negative weight")
+}
+
+func TestComponents(t *testing.T) {
+	expectRun(t, `g := graph.graph().addEdge(1, 2).addEdge(3, 4).addEdge(5, 2).addNode(6).addEdge(4, 3)
+		return [g.components(), g.nodes]`,
+		gad.Array{
+			gad.Array{
+				gad.Array{gad.Int(1), gad.Int(2), gad.Int(5)},
+				gad.Array{gad.Int(3), gad.Int(4)},
+				gad.Array{gad.Int(6)},
+			},
+			gad.Array{gad.Int(1), gad.Int(2), gad.Int(3), gad.Int(4), gad.Int(5), gad.Int(6)},
+		})
+}
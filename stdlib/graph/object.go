@@ -0,0 +1,186 @@
+package graph
+
+import (
+	"strconv"
+
+	"github.com/gad-lang/gad"
+)
+
+// gad:doc
+// ## Types
+// ### graph
+//
+// Go Type
+//
+// ```go
+// // Object represents a Graph and implements gad.Object interface.
+// type Object struct {
+//   Value *Graph
+// }
+// ```
+
+// TGraph is the type of graph objects, calling it returns a new graph.
+var TGraph = &gad.BuiltinObjType{
+	NameValue: "graph",
+	Value:     newGraph,
+}
+
+// Object represents a Graph and implements gad.Object interface.
+type Object struct {
+	Value *Graph
+}
+
+var (
+	_ gad.Object           = (*Object)(nil)
+	_ gad.IndexGetter      = (*Object)(nil)
+	_ gad.NameCallerObject = (*Object)(nil)
+)
+
+func newGraph(c gad.Call) (gad.Object, error) {
+	if err := c.Args.CheckLen(0); err != nil {
+		return nil, err
+	}
+	return &Object{Value: New()}, nil
+}
+
+func (*Object) Type() gad.ObjectType {
+	return TGraph
+}
+
+// ToString implements gad.Object interface.
+func (o *Object) ToString() string {
+	return "graph(nodes=" + strconv.Itoa(o.Value.Len()) + ", edges=" + strconv.Itoa(len(o.Value.Edges())) + ")"
+}
+
+// IsFalsy implements gad.Object interface.
+func (o *Object) IsFalsy() bool {
+	return o.Value.Len() == 0
+}
+
+// Equal implements gad.Object interface.
+func (o *Object) Equal(right gad.Object) bool {
+	v, ok := right.(*Object)
+	return ok && v.Value == o.Value
+}
+
+// gad:doc
+// #### graph Getters
+//
+// | Selector  | Return Type                                  |
+// |:----------|:---------------------------------------------|
+// |.nodes     | array                                        |
+// |.edges     | array of {from, to, weight}                  |
+
+// IndexGet implements gad.IndexGetter interface.
+func (o *Object) IndexGet(_ *gad.VM, index gad.Object) (gad.Object, error) {
+	switch index.ToString() {
+	case "nodes":
+		return gad.Array(o.Value.Nodes()), nil
+	case "edges":
+		edges := o.Value.Edges()
+		ret := make(gad.Array, len(edges))
+		for i, e := range edges {
+			ret[i] = gad.Dict{"from": e.From, "to": e.To, "weight": gad.Float(e.Weight)}
+		}
+		return ret, nil
+	}
+	return nil, gad.ErrInvalidIndex.NewError(index.ToString())
+}
+
+// gad:doc
+// #### graph Methods
+//
+// | Method                              | Return Type                       |
+// |:------------------------------------|:----------------------------------|
+// |.addNode(node)                       | graph                             |
+// |.addEdge(from, to; weight=1)         | graph                             |
+// |.topoSort()                          | array                             |
+// |.shortestPath(from, to)              | array or nil                      |
+// |.components()                        | array of arrays                   |
+//
+// Nodes are identified by their string values and kept in insertion order.
+// addEdge adds the missing nodes and replaces the weight of an existing edge,
+// weights must not be negative. topoSort orders the nodes so that all edges
+// go forward, e.g. a dependency comes before its dependents if the edges are
+// added as `addEdge(dependency, dependent)`. It throws CycleError with the
+// cycle like "a -> b -> a". shortestPath returns the nodes of the path with the least
+// total weight or nil if there is no path. components returns the nodes
+// connected by the edges regardless of their directions.
+
+// CallName implements gad.NameCallerObject interface.
+func (o *Object) CallName(name string, c gad.Call) (_ gad.Object, err error) {
+	switch name {
+	case "addNode":
+		if err = c.Args.CheckLen(1); err != nil {
+			return
+		}
+		o.Value.AddNode(c.Args.GetOnly(0))
+		return o, nil
+	case "addEdge":
+		var (
+			from   = &gad.Arg{Name: "from"}
+			to     = &gad.Arg{Name: "to"}
+			weight = &gad.NamedArgVar{
+				Name:          "weight",
+				Value:         gad.Int(1),
+				TypeAssertion: gad.TypeAssertionFromTypes(gad.TInt, gad.TFloat),
+			}
+		)
+		if err = c.Args.Destructure(from, to); err != nil {
+			return
+		}
+		if err = c.NamedArgs.Get(weight); err != nil {
+			return
+		}
+		var w float64
+		switch v := weight.Value.(type) {
+		case gad.Int:
+			w = float64(v)
+		case gad.Float:
+			w = float64(v)
+		}
+		if w < 0 {
+			return nil, gad.ErrUnexpectedArgValue.NewError("negative weight " + weight.Value.ToString())
+		}
+		o.Value.AddEdge(from.Value, to.Value, w)
+		return o, nil
+	case "topoSort":
+		if err = c.Args.CheckLen(0); err != nil {
+			return
+		}
+		var sorted []gad.Object
+		if sorted, err = o.Value.TopoSort(); err != nil {
+			return
+		}
+		return gad.Array(sorted), nil
+	case "shortestPath":
+		var (
+			from = &gad.Arg{Name: "from"}
+			to   = &gad.Arg{Name: "to"}
+		)
+		if err = c.Args.Destructure(from, to); err != nil {
+			return
+		}
+		var index [2]int
+		for i, n := range []gad.Object{from.Value, to.Value} {
+			if index[i] = o.Value.Index(n); index[i] < 0 {
+				return nil, gad.ErrUnexpectedArgValue.NewError("unknown node " + n.ToString())
+			}
+		}
+		if path := o.Value.ShortestPath(index[0], index[1]); path != nil {
+			return gad.Array(path), nil
+		}
+		return gad.Nil, nil
+	case "components":
+		if err = c.Args.CheckLen(0); err != nil {
+			return
+		}
+		components := o.Value.Components()
+		ret := make(gad.Array, len(components))
+		for i, nodes := range components {
+			ret[i] = gad.Array(nodes)
+		}
+		return ret, nil
+	}
+	return nil, gad.ErrInvalidIndex.NewError(name)
+}
@@ -2,21 +2,47 @@ package helper
 
 import (
 	"github.com/gad-lang/gad"
+	gadask "github.com/gad-lang/gad/stdlib/ask"
+	gadblob "github.com/gad-lang/gad/stdlib/blob"
+	gadcache "github.com/gad-lang/gad/stdlib/cache"
 	goflate "github.com/gad-lang/gad/stdlib/compress/flate"
+	gadcron "github.com/gad-lang/gad/stdlib/cron"
+	gadcrypto "github.com/gad-lang/gad/stdlib/crypto"
 	gadbase64 "github.com/gad-lang/gad/stdlib/encoding/base64"
 	gadfpath "github.com/gad-lang/gad/stdlib/filepath"
+	gadflags "github.com/gad-lang/gad/stdlib/flags"
 	gadfmt "github.com/gad-lang/gad/stdlib/fmt"
+	gadfsm "github.com/gad-lang/gad/stdlib/fsm"
+	gadgraph "github.com/gad-lang/gad/stdlib/graph"
+	gadhtml "github.com/gad-lang/gad/stdlib/html"
 	gadhttp "github.com/gad-lang/gad/stdlib/http"
+	gadhumanize "github.com/gad-lang/gad/stdlib/humanize"
 	gadjson "github.com/gad-lang/gad/stdlib/json"
+	gadjwt "github.com/gad-lang/gad/stdlib/jwt"
+	gadmetrics "github.com/gad-lang/gad/stdlib/metrics"
+	gadnet "github.com/gad-lang/gad/stdlib/net"
 	gados "github.com/gad-lang/gad/stdlib/os"
 	gadpath "github.com/gad-lang/gad/stdlib/path"
+	gadsecrets "github.com/gad-lang/gad/stdlib/secrets"
 	gadstrings "github.com/gad-lang/gad/stdlib/strings"
+	gadterm "github.com/gad-lang/gad/stdlib/term"
+	gadtextdiff "github.com/gad-lang/gad/stdlib/textdiff"
 	gadtime "github.com/gad-lang/gad/stdlib/time"
+	"github.com/gad-lang/gad/stdlib/vfs"
 )
 
 type ModuleMapBuilder struct {
 	Safe     bool
 	Disabled map[string]bool
+	// FS is the filesystem used by os, filepath and blob modules. The filesystem
+	// of the operating system is used if it is nil.
+	FS vfs.FS
+	// Metrics is the buffer of the metrics module, metrics.Default is used
+	// if it is nil.
+	Metrics *gadmetrics.Buffer
+	// Secrets is the store of the secrets module, no secret exists if it is
+	// nil.
+	Secrets gadsecrets.Store
 }
 
 func NewModuleMapBuilder() *ModuleMapBuilder {
@@ -28,23 +54,53 @@ func (b *ModuleMapBuilder) Build() *gad.ModuleMap {
 }
 
 func (b *ModuleMapBuilder) BuildTo(mm *gad.ModuleMap) *gad.ModuleMap {
+	metrics := b.Metrics
+	if metrics == nil {
+		metrics = gadmetrics.Default
+	}
 	mm.AddBuiltinModule("time", gadtime.Module).
 		AddBuiltinModule("strings", gadstrings.Module).
 		AddBuiltinModule("fmt", gadfmt.Module).
 		AddBuiltinModule("json", gadjson.Module).
 		AddBuiltinModule("path", gadpath.Module).
 		AddBuiltinModule("encoding/base64", gadbase64.Module).
-		AddBuiltinModule("compress/flate", goflate.Module)
+		AddBuiltinModule("compress/flate", goflate.Module).
+		AddBuiltinModule("textdiff", gadtextdiff.Module).
+		AddBuiltinModule("net", gadnet.Module).
+		AddBuiltinModule("crypto", gadcrypto.Module).
+		AddBuiltinModule("jwt", gadjwt.Module).
+		AddBuiltinModule("html", gadhtml.Module).
+		AddBuiltinModule("graph", gadgraph.Module).
+		AddBuiltinModule("fsm", gadfsm.Module).
+		AddBuiltinModule("cron", gadcron.Module).
+		AddBuiltinModule("cache", gadcache.Module).
+		AddBuiltinModule("metrics", gadmetrics.NewModule(metrics)).
+		AddBuiltinModule("flags", gadflags.Module).
+		AddBuiltinModule("secrets", gadsecrets.NewModule(b.Secrets)).
+		AddBuiltinModule("humanize", gadhumanize.Module)
 
 	if !b.Safe {
 		if !b.Disabled["http"] {
 			mm.AddBuiltinModule("http", gadhttp.Module)
 		}
+		fsys := b.FS
+		if fsys == nil {
+			fsys = vfs.OS
+		}
 		if !b.Disabled["os"] {
-			mm.AddBuiltinModule("os", gados.Module)
+			mm.AddBuiltinModule("os", gados.NewModule(fsys))
 		}
 		if !b.Disabled["filepath"] {
-			mm.AddBuiltinModule("filepath", gadfpath.Module)
+			mm.AddBuiltinModule("filepath", gadfpath.NewModule(fsys))
+		}
+		if !b.Disabled["term"] {
+			mm.AddBuiltinModule("term", gadterm.Module)
+		}
+		if !b.Disabled["ask"] {
+			mm.AddBuiltinModule("ask", gadask.Module)
+		}
+		if !b.Disabled["blob"] {
+			mm.AddBuiltinModule("blob", gadblob.NewModule(fsys))
 		}
 	}
 	return mm
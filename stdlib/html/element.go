@@ -0,0 +1,271 @@
+// Package html provides html module for Gad script language to parse HTML
+// documents and to query their elements by CSS selectors, e.g. in scraping
+// and reporting scripts.
+package html
+
+import (
+	"strings"
+
+	"github.com/andybalholm/cascadia"
+	nethtml "golang.org/x/net/html"
+
+	"github.com/gad-lang/gad"
+)
+
+// gad:doc
+// ## Types
+// ### element
+//
+// Go Type
+//
+// ```go
+// // Element represents a node of an HTML document and implements gad.Object
+// // interface.
+// type Element struct {
+//   Node *html.Node
+// }
+// ```
+
+// TElement is the type of Element objects.
+var TElement = &gad.BuiltinObjType{
+	NameValue: "element",
+}
+
+// Element represents a node of an HTML document and implements gad.Object
+// interface.
+type Element struct {
+	Node *nethtml.Node
+}
+
+var (
+	_ gad.Object           = (*Element)(nil)
+	_ gad.IndexGetter      = (*Element)(nil)
+	_ gad.NameCallerObject = (*Element)(nil)
+	_ gad.Iterabler        = (*Element)(nil)
+)
+
+// Parse parses the HTML document s.
+func Parse(s string) (*Element, error) {
+	n, err := nethtml.Parse(strings.NewReader(s))
+	if err != nil {
+		return nil, err
+	}
+	return &Element{Node: n}, nil
+}
+
+func (*Element) Type() gad.ObjectType {
+	return TElement
+}
+
+// ToString implements gad.Object interface. It returns the HTML of the
+// element.
+func (o *Element) ToString() string {
+	var b strings.Builder
+	_ = nethtml.Render(&b, o.Node)
+	return b.String()
+}
+
+// IsFalsy implements gad.Object interface.
+func (o *Element) IsFalsy() bool {
+	return o.Node == nil
+}
+
+// Equal implements gad.Object interface.
+func (o *Element) Equal(right gad.Object) bool {
+	v, ok := right.(*Element)
+	return ok && v.Node == o.Node
+}
+
+// Text returns the concatenated text of the element and its descendants.
+func (o *Element) Text() string {
+	var b strings.Builder
+	var walk func(n *nethtml.Node)
+	walk = func(n *nethtml.Node) {
+		if n.Type == nethtml.TextNode {
+			b.WriteString(n.Data)
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(o.Node)
+	return b.String()
+}
+
+// Attr returns the value of the attribute and whether it exists.
+func (o *Element) Attr(name string) (string, bool) {
+	for _, a := range o.Node.Attr {
+		if a.Namespace == "" && strings.EqualFold(a.Key, name) {
+			return a.Val, true
+		}
+	}
+	return "", false
+}
+
+func element(n *nethtml.Node) gad.Object {
+	if n == nil {
+		return gad.Nil
+	}
+	return &Element{Node: n}
+}
+
+// gad:doc
+// #### element Getters
+//
+// | Selector   | Return Type                                     |
+// |:-----------|:------------------------------------------------|
+// |.tag        | str, the lower case tag name                    |
+// |.text       | str, the text of the element and descendants    |
+// |.html       | str, the HTML of the element                    |
+// |.innerHTML  | str, the HTML of the children                   |
+// |.attrs      | dict                                            |
+// |.parent     | element or nil                                  |
+// |.children   | array of the child elements                     |
+
+// IndexGet implements gad.IndexGetter interface.
+func (o *Element) IndexGet(_ *gad.VM, index gad.Object) (gad.Object, error) {
+	switch index.ToString() {
+	case "tag":
+		if o.Node.Type != nethtml.ElementNode {
+			return gad.Str(""), nil
+		}
+		return gad.Str(o.Node.Data), nil
+	case "text":
+		return gad.Str(o.Text()), nil
+	case "html":
+		return gad.Str(o.ToString()), nil
+	case "innerHTML":
+		var b strings.Builder
+		for c := o.Node.FirstChild; c != nil; c = c.NextSibling {
+			_ = nethtml.Render(&b, c)
+		}
+		return gad.Str(b.String()), nil
+	case "attrs":
+		attrs := make(gad.Dict, len(o.Node.Attr))
+		for _, a := range o.Node.Attr {
+			attrs[a.Key] = gad.Str(a.Val)
+		}
+		return attrs, nil
+	case "parent":
+		return element(o.Node.Parent), nil
+	case "children":
+		var children gad.Array
+		for c := o.Node.FirstChild; c != nil; c = c.NextSibling {
+			if c.Type == nethtml.ElementNode {
+				children = append(children, &Element{Node: c})
+			}
+		}
+		return children, nil
+	}
+	return nil, gad.ErrInvalidIndex.NewError(index.ToString())
+}
+
+// gad:doc
+// #### element Methods
+//
+// | Method                          | Return Type                          |
+// |:--------------------------------|:-------------------------------------|
+// |.select(selector str)            | iterator of the matching elements    |
+// |.first(selector str)             | element or nil                       |
+// |.attr(name str; default=nil)     | str or default                       |
+//
+// select iterates the descendants matching the CSS selector lazily in
+// document order, e.g. `doc.select("ul > li a.link[href^='https']")`.
+// Iterating an element iterates its child elements.
+
+// CallName implements gad.NameCallerObject interface.
+func (o *Element) CallName(name string, c gad.Call) (_ gad.Object, err error) {
+	switch name {
+	case "select", "first":
+		sel := &gad.Arg{
+			Name:          "selector",
+			TypeAssertion: gad.TypeAssertionFromTypes(gad.TStr),
+		}
+		if err = c.Args.Destructure(sel); err != nil {
+			return
+		}
+		var s cascadia.Selector
+		if s, err = cascadia.Compile(sel.Value.ToString()); err != nil {
+			return nil, gad.ErrUnexpectedArgValue.NewError(err.Error())
+		}
+		if name == "first" {
+			return element(s.MatchFirst(o.Node)), nil
+		}
+		return gad.IteratorObject(o.iterate(s.Match)), nil
+	case "attr":
+		var (
+			attr = &gad.Arg{
+				Name:          "name",
+				TypeAssertion: gad.TypeAssertionFromTypes(gad.TStr),
+			}
+			def = &gad.NamedArgVar{
+				Name:  "default",
+				Value: gad.Nil,
+			}
+		)
+		if err = c.Args.Destructure(attr); err != nil {
+			return
+		}
+		if err = c.NamedArgs.Get(def); err != nil {
+			return
+		}
+		if v, ok := o.Attr(attr.Value.ToString()); ok {
+			return gad.Str(v), nil
+		}
+		return def.Value, nil
+	}
+	return nil, gad.ErrInvalidIndex.NewError(name)
+}
+
+// Iterate implements gad.Iterabler interface.
+func (o *Element) Iterate(*gad.VM, *gad.NamedArgs) gad.Iterator {
+	return o.iterate(func(n *nethtml.Node) bool {
+		return n.Parent == o.Node && n.Type == nethtml.ElementNode
+	})
+}
+
+// iterate returns the iterator of the descendants matching match in
+// document order.
+func (o *Element) iterate(match func(n *nethtml.Node) bool) gad.Iterator {
+	// find returns the first matching node after n
+	find := func(n *nethtml.Node) *nethtml.Node {
+		for n = o.next(n); n != nil && !match(n); n = o.next(n) {
+		}
+		return n
+	}
+	// set sets the entry of n at index i. state.Value holds them too as the
+	// entry may be changed by the wrapping iterators like map.
+	set := func(state *gad.IteratorState, i gad.Int, n *nethtml.Node) {
+		if n == nil {
+			state.Mode = gad.IteratorStateModeDone
+			return
+		}
+		e := &Element{Node: n}
+		state.Value = gad.Array{i, e}
+		state.Entry.K, state.Entry.V = i, e
+	}
+	return gad.NewIterator(
+		func(*gad.VM) (*gad.IteratorState, error) {
+			state := &gad.IteratorState{}
+			set(state, 0, find(o.Node))
+			return state, nil
+		},
+		func(_ *gad.VM, state *gad.IteratorState) error {
+			cur := state.Value.(gad.Array)
+			set(state, cur[0].(gad.Int)+1, find(cur[1].(*Element).Node))
+			return nil
+		}).SetInput(o).SetItType(TElement)
+}
+
+// next returns the node after n in document order within the element.
+func (o *Element) next(n *nethtml.Node) *nethtml.Node {
+	if n.FirstChild != nil {
+		return n.FirstChild
+	}
+	for ; n != o.Node; n = n.Parent {
+		if n.NextSibling != nil {
+			return n.NextSibling
+		}
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+package html
+
+import (
+	nethtml "golang.org/x/net/html"
+
+	"github.com/gad-lang/gad"
+	"github.com/gad-lang/gad/stdlib"
+)
+
+// Module represents html module.
+var Module = gad.Dict{
+	// gad:doc
+	// # html module
+	//
+	// ## Functions
+	// parse(s str|bytes) -> element
+	// Parses the HTML document s, the missing html, head and body elements
+	// are added like browsers do.
+	"parse": &gad.Function{
+		Name:  "parse",
+		Value: parseFunc,
+	},
+	// gad:doc
+	// escape(s str) -> str
+	// Escapes <, >, &, ' and " characters of s.
+	"escape": &gad.Function{
+		Name:  "escape",
+		Value: stdlib.FuncPsRO(escapeFunc),
+	},
+	// gad:doc
+	// unescape(s str) -> str
+	// Unescapes the entities of s like "&lt;" and "&#39;".
+	"unescape": &gad.Function{
+		Name:  "unescape",
+		Value: stdlib.FuncPsRO(unescapeFunc),
+	},
+}
+
+func parseFunc(c gad.Call) (_ gad.Object, err error) {
+	s := &gad.Arg{
+		Name:          "s",
+		TypeAssertion: gad.TypeAssertionFromTypes(gad.TStr, gad.TBytes),
+	}
+	if err = c.Args.Destructure(s); err != nil {
+		return
+	}
+	var src string
+	if b, ok := s.Value.(gad.Bytes); ok {
+		src = string(b)
+	} else {
+		src = s.Value.ToString()
+	}
+	return Parse(src)
+}
+
+func escapeFunc(s string) gad.Object { return gad.Str(nethtml.EscapeString(s)) }
+
+func unescapeFunc(s string) gad.Object { return gad.Str(nethtml.UnescapeString(s)) }
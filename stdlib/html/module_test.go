@@ -0,0 +1,61 @@
+package html_test
+
+import (
+	"testing"
+
+	"github.com/gad-lang/gad"
+	"github.com/gad-lang/gad/stdlib/html"
+)
+
+const page = `<html><head><title>Links</title></head><body>
+<ul id="nav">
+  <li><a class="link" href="/a">A &amp; B</a></li>
+  <li><a class="link ext" href="https://example.com">Ext</a></li>
+  <li><a href="/c">C</a></li>
+</ul>
+<p>Some <b>bold</b> text</p>
+</body></html>`
+
+func expectRun(t *testing.T, script string, expected gad.Object) {
+	t.Helper()
+	opts := gad.NewTestOpts().Module("html", html.Module).Globals(gad.Dict{"page": gad.Str(page)})
+	gad.TestExpectRun(t, `global page; html := import("html"); doc := html.parse(page);`+script, opts, expected)
+}
+
+func expectErr(t *testing.T, script string, expected error) {
+	t.Helper()
+	gad.TestExpectRunErr(t, `html := import("html"); doc := html.parse("<p>x</p>");`+script, gad.NewTestOpts().Module("html", html.Module), expected)
+}
+
+func TestSelect(t *testing.T) {
+	expectRun(t, `r := []; for a in doc.select("a.link") { r = append(r, [a.text, a.attr("href")]) }; return r`,
+		gad.Array{
+			gad.Array{gad.Str("A & B"), gad.Str("/a")},
+			gad.Array{gad.Str("Ext"), gad.Str("https://example.com")},
+		})
+	expectRun(t, `return collect(map(doc.select("#nav > li a[href^='/']"), func(a, _) { return a.attr("href") }))`,
+		gad.Array{gad.Str("/a"), gad.Str("/c")})
+	expectRun(t, `r := []; for i, e in doc.select("title, b") { r = append(r, [i, e.tag]) }; return r`,
+		gad.Array{gad.Array{gad.Int(0), gad.Str("title")}, gad.Array{gad.Int(1), gad.Str("b")}})
+	// selections are lazy and scoped to the element
+	expectRun(t, `for a in doc.first("ul").select("a") { return a.text }`, gad.Str("A & B"))
+	expectRun(t, `return [collect(doc.first("p").select("a")), doc.first("table")]`, gad.Array{gad.Array{}, gad.Nil})
+}
+
+func TestElement(t *testing.T) {
+	expectRun(t, `p := doc.first("p"); return [p.tag, p.text, p.innerHTML, p.html, str(p.first("b"))]`,
+		gad.Array{gad.Str("p"), gad.Str("Some bold text"), gad.Str("Some <b>bold</b> text"),
+			gad.Str("<p>Some <b>bold</b> text</p>"), gad.Str("<b>bold</b>")})
+	expectRun(t, `a := doc.first("a.ext"); return [a.attrs, a.attr("title"; default=""), a.attr("id"), a.parent.tag]`,
+		gad.Array{gad.Dict{"class": gad.Str("link ext"), "href": gad.Str("https://example.com")},
+			gad.Str(""), gad.Nil, gad.Str("li")})
+	expectRun(t, `ul := doc.first("ul"); r := []; for li in ul { r = append(r, li.tag) }; return [len(ul.children), r]`,
+		gad.Array{gad.Int(3), gad.Array{gad.Str("li"), gad.Str("li"), gad.Str("li")}})
+	expectRun(t, `return [doc.tag, typeName(doc), doc.first("a") == doc.first("a.link"), doc.first("a") == doc.first("a.ext")]`,
+		gad.Array{gad.Str(""), gad.Str("element"), gad.True, gad.False})
+	expectRun(t, `return [html.escape("<a href='x'>"), html.unescape("&lt;&#39;&amp;")]`,
+		gad.Array{gad.Str("&lt;a href=&#39;x&#39;&gt;"), gad.Str("<'&")})
+
+	expectErr(t, `doc.select("a[")`, gad.ErrUnexpectedArgValue)
+	expectErr(t, `doc.name`, gad.ErrInvalidIndex)
+}
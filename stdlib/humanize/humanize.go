@@ -0,0 +1,171 @@
+// Package humanize provides humanize module for Gad script language to format
+// sizes, durations, relative times, ordinals and numbers for humans, e.g. in
+// the reports and the messages of command line scripts.
+package humanize
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	iecUnits = []string{"B", "KiB", "MiB", "GiB", "TiB", "PiB", "EiB"}
+	siUnits  = []string{"B", "kB", "MB", "GB", "TB", "PB", "EB"}
+)
+
+// Bytes returns the size of n bytes in the largest unit which is not greater
+// than it with one decimal digit, like "1.5 KiB". The units are the powers
+// of 1024 unless si is true, then they are the powers of 1000 like "1.5 kB".
+func Bytes(n float64, si bool) string {
+	base, units := 1024.0, iecUnits
+	if si {
+		base, units = 1000, siUnits
+	}
+	sign := ""
+	if n < 0 {
+		sign, n = "-", -n
+	}
+	if n < base {
+		return sign + strconv.FormatFloat(n, 'f', -1, 64) + " B"
+	}
+	i := 0
+	for n >= base && i < len(units)-1 {
+		n /= base
+		i++
+	}
+	return sign + strconv.FormatFloat(n, 'f', 1, 64) + " " + units[i]
+}
+
+var durationUnits = []struct {
+	d    time.Duration
+	name string
+}{
+	{24 * time.Hour, "d"},
+	{time.Hour, "h"},
+	{time.Minute, "m"},
+	{time.Second, "s"},
+	{time.Millisecond, "ms"},
+	{time.Microsecond, "µs"},
+	{time.Nanosecond, "ns"},
+}
+
+// Duration returns d in the most significant units of days, hours, minutes,
+// seconds, milliseconds, microseconds and nanoseconds, like "1h 5m". parts
+// is the number of the units, the less significant units are truncated and
+// the units with zero values are omitted.
+func Duration(d time.Duration, parts int) string {
+	if d == 0 {
+		return "0s"
+	}
+	var sb strings.Builder
+	// uint64 handles the negative minimum value
+	v := uint64(d)
+	if d < 0 {
+		sb.WriteByte('-')
+		v = -v
+	}
+	var n, written int
+	for _, u := range durationUnits {
+		if n == parts {
+			break
+		}
+		q := v / uint64(u.d)
+		if q == 0 && n == 0 {
+			continue
+		}
+		n++
+		if q == 0 {
+			continue
+		}
+		if written > 0 {
+			sb.WriteByte(' ')
+		}
+		written++
+		sb.WriteString(strconv.FormatUint(q, 10))
+		sb.WriteString(u.name)
+		v -= q * uint64(u.d)
+	}
+	return sb.String()
+}
+
+var agoUnits = []struct {
+	d    time.Duration
+	name string
+}{
+	{365 * 24 * time.Hour, "year"},
+	{30 * 24 * time.Hour, "month"},
+	{24 * time.Hour, "day"},
+	{time.Hour, "hour"},
+	{time.Minute, "minute"},
+	{time.Second, "second"},
+}
+
+// TimeAgo returns the time t relative to now in its largest unit, like
+// "3 minutes ago" or "in 2 days". Months are 30 days, years are 365 days and
+// the times closer than a second are "now".
+func TimeAgo(t, now time.Time) string {
+	d := now.Sub(t)
+	future := d < 0
+	if future {
+		d = -d
+	}
+	for _, u := range agoUnits {
+		if d < u.d {
+			continue
+		}
+		n := int64(d / u.d)
+		s := strconv.FormatInt(n, 10) + " " + u.name
+		if n != 1 {
+			s += "s"
+		}
+		if future {
+			return "in " + s
+		}
+		return s + " ago"
+	}
+	return "now"
+}
+
+// Ordinal returns n with its English ordinal suffix, like "1st", "2nd",
+// "3rd", "4th" and "11th".
+func Ordinal(n int64) string {
+	s := strconv.FormatInt(n, 10)
+	if n < 0 {
+		n = -n
+	}
+	switch {
+	case n%100 >= 11 && n%100 <= 13:
+		return s + "th"
+	case n%10 == 1:
+		return s + "st"
+	case n%10 == 2:
+		return s + "nd"
+	case n%10 == 3:
+		return s + "rd"
+	}
+	return s + "th"
+}
+
+// Number returns the number string s whose integer digits are grouped by
+// three with sep, like "1,234,567.89".
+func Number(s, sep string) string {
+	var sign string
+	if strings.HasPrefix(s, "-") || strings.HasPrefix(s, "+") {
+		sign, s = s[:1], s[1:]
+	}
+	frac := ""
+	if i := strings.IndexByte(s, '.'); i >= 0 {
+		s, frac = s[:i], s[i:]
+	}
+	var sb strings.Builder
+	sb.WriteString(sign)
+	for i, c := range s {
+		if i > 0 && (len(s)-i)%3 == 0 {
+			sb.WriteString(sep)
+		}
+		sb.WriteRune(c)
+	}
+	sb.WriteString(frac)
+	return sb.String()
+}
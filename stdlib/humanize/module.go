@@ -0,0 +1,187 @@
+package humanize
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gad-lang/gad"
+	gadtime "github.com/gad-lang/gad/stdlib/time"
+)
+
+// Module represents humanize module.
+var Module = gad.Dict{
+	// gad:doc
+	// # humanize module
+	//
+	// ## Functions
+	// bytes(n int|uint|float; si=false) -> str
+	// Returns the size of n bytes in the largest unit which is not greater
+	// than it with one decimal digit, like "1.5 KiB". The units are the
+	// powers of 1024, or the powers of 1000 like "1.5 kB" if si is true.
+	"bytes": &gad.Function{
+		Name:  "bytes",
+		Value: bytesFunc,
+	},
+	// gad:doc
+	// duration(d int; parts=2) -> str
+	// Returns the duration d in nanoseconds in its most significant units,
+	// like "1h 5m" or "1.5s" as "1s 500ms". parts is the number of the units,
+	// the less significant units are truncated.
+	"duration": &gad.Function{
+		Name:  "duration",
+		Value: durationFunc,
+	},
+	// gad:doc
+	// timeAgo(t time; now=time.Now()) -> str
+	// Returns the time t relative to now in its largest unit, like
+	// "3 minutes ago" or "in 2 days". Months are 30 days and years are 365
+	// days, the times closer than a second are "now".
+	"timeAgo": &gad.Function{
+		Name:  "timeAgo",
+		Value: timeAgoFunc,
+	},
+	// gad:doc
+	// ordinal(n int) -> str
+	// Returns n with its English ordinal suffix, like "1st", "2nd", "3rd" or
+	// "11th".
+	"ordinal": &gad.Function{
+		Name:  "ordinal",
+		Value: ordinalFunc,
+	},
+	// gad:doc
+	// number(n int|uint|float|decimal; sep=",", decimals=-1) -> str
+	// Returns n whose integer digits are grouped by three with sep, like
+	// "1,234,567". decimals is the number of the digits after the point of
+	// the floats and the decimals, all digits are written if it is negative.
+	"number": &gad.Function{
+		Name:  "number",
+		Value: numberFunc,
+	},
+}
+
+func bytesFunc(c gad.Call) (_ gad.Object, err error) {
+	var (
+		n = &gad.Arg{
+			Name:          "n",
+			TypeAssertion: gad.TypeAssertionFromTypes(gad.TInt, gad.TUint, gad.TFloat),
+		}
+		si = &gad.NamedArgVar{
+			Name:          "si",
+			Value:         gad.False,
+			TypeAssertion: gad.TypeAssertionFromTypes(gad.TBool),
+		}
+	)
+	if err = c.Args.Destructure(n); err != nil {
+		return
+	}
+	if err = c.NamedArgs.Get(si); err != nil {
+		return
+	}
+	f, _ := gad.ToGoFloat64(n.Value)
+	return gad.Str(Bytes(f, !si.Value.IsFalsy())), nil
+}
+
+func durationFunc(c gad.Call) (_ gad.Object, err error) {
+	var (
+		d = &gad.Arg{
+			Name:          "d",
+			TypeAssertion: gad.TypeAssertionFromTypes(gad.TInt),
+		}
+		parts = &gad.NamedArgVar{
+			Name:          "parts",
+			Value:         gad.Int(2),
+			TypeAssertion: gad.TypeAssertionFromTypes(gad.TInt),
+		}
+	)
+	if err = c.Args.Destructure(d); err != nil {
+		return
+	}
+	if err = c.NamedArgs.Get(parts); err != nil {
+		return
+	}
+	if parts.Value.(gad.Int) < 1 {
+		return nil, gad.ErrUnexpectedArgValue.NewError("parts must be positive")
+	}
+	return gad.Str(Duration(time.Duration(d.Value.(gad.Int)), int(parts.Value.(gad.Int)))), nil
+}
+
+func timeAgoFunc(c gad.Call) (_ gad.Object, err error) {
+	var (
+		t = &gad.Arg{
+			Name: "t",
+		}
+		now = &gad.NamedArgVar{
+			Name: "now",
+		}
+	)
+	if err = c.Args.Destructure(t); err != nil {
+		return
+	}
+	if err = c.NamedArgs.Get(now); err != nil {
+		return
+	}
+	tm, ok := gadtime.ToTime(t.Value)
+	if !ok {
+		return nil, gad.NewArgumentTypeError("1st", "time", t.Value.Type().Name())
+	}
+	nowTime := time.Now()
+	if now.Value != nil && now.Value != gad.Nil {
+		v, ok := gadtime.ToTime(now.Value)
+		if !ok {
+			return nil, gad.NewArgumentTypeError("now", "time", now.Value.Type().Name())
+		}
+		nowTime = v.Value
+	}
+	return gad.Str(TimeAgo(tm.Value, nowTime)), nil
+}
+
+func ordinalFunc(c gad.Call) (_ gad.Object, err error) {
+	n := &gad.Arg{
+		Name:          "n",
+		TypeAssertion: gad.TypeAssertionFromTypes(gad.TInt),
+	}
+	if err = c.Args.Destructure(n); err != nil {
+		return
+	}
+	return gad.Str(Ordinal(int64(n.Value.(gad.Int)))), nil
+}
+
+func numberFunc(c gad.Call) (_ gad.Object, err error) {
+	var (
+		n = &gad.Arg{
+			Name:          "n",
+			TypeAssertion: gad.TypeAssertionFromTypes(gad.TInt, gad.TUint, gad.TFloat, gad.TDecimal),
+		}
+		sep = &gad.NamedArgVar{
+			Name:          "sep",
+			Value:         gad.Str(","),
+			TypeAssertion: gad.TypeAssertionFromTypes(gad.TStr),
+		}
+		decimals = &gad.NamedArgVar{
+			Name:          "decimals",
+			Value:         gad.Int(-1),
+			TypeAssertion: gad.TypeAssertionFromTypes(gad.TInt),
+		}
+	)
+	if err = c.Args.Destructure(n); err != nil {
+		return
+	}
+	if err = c.NamedArgs.Get(sep, decimals); err != nil {
+		return
+	}
+	prec := int(decimals.Value.(gad.Int))
+	var s string
+	switch v := n.Value.(type) {
+	case gad.Float:
+		s = strconv.FormatFloat(float64(v), 'f', prec, 64)
+	case gad.Decimal:
+		if prec < 0 {
+			s = v.Go().String()
+		} else {
+			s = v.Go().StringFixed(int32(prec))
+		}
+	default:
+		s = v.ToString()
+	}
+	return gad.Str(Number(s, sep.Value.ToString())), nil
+}
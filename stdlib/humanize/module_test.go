@@ -0,0 +1,47 @@
+package humanize_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gad-lang/gad"
+	"github.com/gad-lang/gad/stdlib/humanize"
+	gadtime "github.com/gad-lang/gad/stdlib/time"
+)
+
+func expectRun(t *testing.T, script string, expected gad.Object) {
+	t.Helper()
+	now := &gadtime.Time{Value: time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)}
+	opts := gad.NewTestOpts().Module("humanize", humanize.Module).Module("time", gadtime.Module).Globals(gad.Dict{"now": now})
+	gad.TestExpectRun(t, `global now; h := import("humanize");`+script, opts, expected)
+}
+
+func TestModule(t *testing.T) {
+	expectRun(t, `return [h.bytes(0), h.bytes(1023), h.bytes(1536), h.bytes(123456), h.bytes(5u * 1024 * 1024 * 1024), h.bytes(-2048)]`,
+		gad.Array{gad.Str("0 B"), gad.Str("1023 B"), gad.Str("1.5 KiB"), gad.Str("120.6 KiB"), gad.Str("5.0 GiB"), gad.Str("-2.0 KiB")})
+	expectRun(t, `return [h.bytes(1500; si=true), h.bytes(999; si=true), h.bytes(2.5e6; si=true)]`,
+		gad.Array{gad.Str("1.5 kB"), gad.Str("999 B"), gad.Str("2.5 MB")})
+
+	expectRun(t, `s := 1000000000; return [h.duration(0), h.duration(1500000000), h.duration(3725 * s), h.duration(3725 * s; parts=3),
+			h.duration(3605 * s), h.duration(90061 * s; parts=4), h.duration(-90 * s), h.duration(1500)]`,
+		gad.Array{gad.Str("0s"), gad.Str("1s 500ms"), gad.Str("1h 2m"), gad.Str("1h 2m 5s"),
+			gad.Str("1h"), gad.Str("1d 1h 1m 1s"), gad.Str("-1m 30s"), gad.Str("1µs 500ns")})
+
+	expectRun(t, `time := import("time"); s := 1000000000
+		return [h.timeAgo(now; now=now), h.timeAgo(time.Add(now, -45 * s); now=now),
+			h.timeAgo(time.Add(now, -60 * s); now=now), h.timeAgo(time.Add(now, -3 * 3600 * s); now=now),
+			h.timeAgo(time.Add(now, 2 * 86400 * s); now=now), h.timeAgo(time.Add(now, -400 * 86400 * s); now=now),
+			h.timeAgo(time.Add(now, -40 * 86400 * s); now=now)]`,
+		gad.Array{gad.Str("now"), gad.Str("45 seconds ago"), gad.Str("1 minute ago"), gad.Str("3 hours ago"),
+			gad.Str("in 2 days"), gad.Str("1 year ago"), gad.Str("1 month ago")})
+
+	expectRun(t, `return [h.ordinal(1), h.ordinal(2), h.ordinal(3), h.ordinal(4), h.ordinal(11), h.ordinal(12),
+			h.ordinal(13), h.ordinal(21), h.ordinal(102), h.ordinal(111), h.ordinal(0), h.ordinal(-1)]`,
+		gad.Array{gad.Str("1st"), gad.Str("2nd"), gad.Str("3rd"), gad.Str("4th"), gad.Str("11th"), gad.Str("12th"),
+			gad.Str("13th"), gad.Str("21st"), gad.Str("102nd"), gad.Str("111th"), gad.Str("0th"), gad.Str("-1st")})
+
+	expectRun(t, `return [h.number(0), h.number(999), h.number(1234567), h.number(1234567u), h.number(1234.5),
+			h.number(1234567.891; decimals=2), h.number(1234.5d; decimals=2), h.number(1234567; sep=" ")]`,
+		gad.Array{gad.Str("0"), gad.Str("999"), gad.Str("1,234,567"), gad.Str("1,234,567"),
+			gad.Str("1,234.5"), gad.Str("1,234,567.89"), gad.Str("1,234.50"), gad.Str("1 234 567")})
+}
@@ -22,6 +22,11 @@ import (
 type DecodeOptions struct {
 	FloatFunc func(s string) (gad.Object, error)
 	IntFunc   func(s string) (gad.Object, error)
+	// Types decodes the objects tagged with the names of the registered types
+	// back into their instances, see gad.DecodeObject.
+	Types gad.SerialTypes
+	// VM calls the __decode__ methods of Types.
+	VM *gad.VM
 }
 
 func NewDecodeOptions() *DecodeOptions {
@@ -207,7 +212,7 @@ func (d *decodeState) object() (gad.Object, error) {
 		if err != nil {
 			return object, err
 		}
-		object[key] = v
+		object[gad.Intern(key)] = v
 
 		// Next token must be , or }.
 		if d.opcode == scanSkipSpace {
@@ -220,6 +225,9 @@ func (d *decodeState) object() (gad.Object, error) {
 			panic(phasePanicMsg)
 		}
 	}
+	if d.opts.Types != nil {
+		return d.opts.Types.Decode(d.opts.VM, object)
+	}
 	return object, nil
 }
 
@@ -188,6 +188,8 @@ func objectEncoder(v gad.Object) encoderFunc {
 		return reflectMapEncoder
 	case *gad.ReflectArray:
 		return reflectArrayEncoder
+	case *gad.Obj:
+		return structEncoder
 	default:
 		return noopEncoder
 	}
@@ -410,6 +412,36 @@ func reflectArrayEncoder(e *encodeState, v gad.Object, opts encOpts) {
 	arrayEncoder(e, arr, opts)
 }
 
+// structEncoder encodes the serializable form of a struct instance returned by
+// its __encode__ method.
+func structEncoder(e *encodeState, v gad.Object, opts encOpts) {
+	if e.ptrLevel++; e.ptrLevel > startDetectingCyclesAfter {
+		// Start checking if we've run into a pointer cycle. The serializable
+		// forms are new dicts, so the instances are memorized.
+		if _, ok := e.ptrSeen[v]; ok {
+			e.error(&UnsupportedValueError{v, fmt.Sprintf("encountered a cycle via %s", v.Type().Name())})
+		}
+		e.ptrSeen[v] = struct{}{}
+		defer delete(e.ptrSeen, v)
+	}
+	r, err := gad.EncodeObject(e.vm, v)
+	if err != nil {
+		e.error(&MarshalerError{v, err, "__encode__"})
+	}
+	if obj, ok := r.(*gad.Obj); ok && obj.Type() == v.Type() {
+		// __encode__ would be called again for the instance of its type, so
+		// the fields are encoded by default.
+		d := make(gad.Dict, obj.Length()+1)
+		for k, f := range obj.Fields() {
+			d[k] = f
+		}
+		d[gad.TypeTagKey] = gad.Str(obj.Type().Name())
+		r = d
+	}
+	e.encode(r, opts)
+	e.ptrLevel--
+}
+
 func bytesEncoder(e *encodeState, v gad.Object, _ encOpts) {
 	if v == nil {
 		e.WriteString("null")
@@ -18,7 +18,9 @@ var Module = map[string]gad.Object{
 	//
 	// ## Functions
 	// Marshal(v any) -> bytes
-	// Returns the JSON encoding v or error.
+	// Returns the JSON encoding v or error. Struct instances are encoded as
+	// the values returned by __encode__, by default their fields with their
+	// type names under "__type__" key.
 	"Marshal": &gad.Function{
 		Name:  "Marshal",
 		Value: stdlib.FuncPpVM_ORO(marshalFunc),
@@ -77,14 +79,17 @@ var Module = map[string]gad.Object{
 		Value: stdlib.FuncPORO(noEscapeFunc),
 	},
 	// gad:doc
-	// Unmarshal(p bytes,numericAsDecimal=false,floatsAsDecimal=false,intAsDecimal=false) -> any
+	// Unmarshal(p bytes,numericAsDecimal=false,floatsAsDecimal=false,intAsDecimal=false,types=nil) -> any
 	// if numericAsDecimal is true, set floatsAsDecimal to true and intAsDecimal to true
 	// if floatsAsDecimal is true, parses float values as decimal
 	// if intAsDecimal is true, parses int values as decimal
+	// types is an array of struct types or a dict of type names to types, the
+	// objects tagged with their names under "__type__" key are decoded back
+	// into their instances by __decode__.
 	// Unmarshal parses the JSON-encoded p and returns the result or error.
 	"Unmarshal": &gad.Function{
 		Name:  "Unmarshal",
-		Value: funcPb2b_numberAsDecimal_b_floatAsDecimal_b_intAsDecimal_RO(unmarshalFunc),
+		Value: unmarshalCall,
 	},
 	// gad:doc
 	// Valid(p bytes) -> bool
@@ -169,8 +174,26 @@ func toFloat(s string) (o gad.Object, _ error) {
 	return
 }
 
-func unmarshalFunc(b []byte, numericAsDecimal, floatsAsDecimal, intAsDecimal bool) gad.Object {
-	opts := NewDecodeOptions()
+// unmarshalCall reads types named argument which can not be declared by the
+// generated function, then calls unmarshalFunc.
+func unmarshalCall(c gad.Call) (gad.Object, error) {
+	types, err := gad.NewSerialTypes(c.NamedArgs.GetValue("types"))
+	if err != nil {
+		return gad.Nil, err
+	}
+	fn := funcPb2b_numberAsDecimal_b_floatAsDecimal_b_intAsDecimal_RO(
+		func(b []byte, numericAsDecimal, floatsAsDecimal, intAsDecimal bool) gad.Object {
+			opts := NewDecodeOptions()
+			if len(types) > 0 {
+				opts.Types = types
+				opts.VM = c.VM
+			}
+			return unmarshalFunc(b, opts, numericAsDecimal, floatsAsDecimal, intAsDecimal)
+		})
+	return fn(c)
+}
+
+func unmarshalFunc(b []byte, opts *DecodeOptions, numericAsDecimal, floatsAsDecimal, intAsDecimal bool) gad.Object {
 	if numericAsDecimal {
 		floatsAsDecimal = true
 		intAsDecimal = true
@@ -134,7 +134,54 @@ func TestScript(t *testing.T) {
 		nil, Str(`error: invalid character '\x00' looking for beginning of value`))
 }
 
+func TestSerialProtocol(t *testing.T) {
+	expectRun(t, `json:=import("json");P:=struct("P",fields={x:0,y:0})
+	return str(json.Marshal([P(x=1,y=2)]))`,
+		nil, Str(`[{"__type__":"P","x":1,"y":2}]`))
+	expectRun(t, `json:=import("json");P:=struct("P",fields={x:0,y:0})
+	p:=json.Unmarshal(json.Marshal(P(x=1,y=2)),types=[P])
+	return [typeof(p)==P,p.x,p.y,sort(collect(keys(P.fieldsOf(p))))]`,
+		nil, Array{True, Int(1), Int(2), Array{Str("x"), Str("y")}})
+	expectRun(t, `json:=import("json");P:=struct("P",fields={x:0,y:0})
+	p:=json.Unmarshal(json.Marshal({a:P(x=1)}),types={P:P})
+	return [typeof(p.a)==P,p.a.x]`,
+		nil, Array{True, Int(1)})
+	expectRun(t, `json:=import("json");P:=struct("P",fields={x:0,y:0})
+	p:=json.Unmarshal(json.Marshal(P(x=1)))
+	return [typeName(p),p.__type__,p.x]`,
+		nil, Array{Str("dict"), Str("P"), Int(1)})
+
+	expectRun(t, `json:=import("json");P:=struct("P",fields={x:0,y:0})
+	func __encode__(p P) {
+		return {__type__:"P",xy:[p.x,p.y]}
+	}
+	func __decode__(t P, v) {
+		return P(x=v.xy[0],y=v.xy[1])
+	}
+	b:=json.Marshal(P(x=1,y=2))
+	p:=json.Unmarshal(b,types=[P])
+	return [str(b),typeof(p)==P,p.x,p.y]`,
+		nil, Array{Str(`{"__type__":"P","xy":[1,2]}`), True, Int(1), Int(2)})
+	expectRun(t, `json:=import("json");P:=struct("P",fields={x:0})
+	func __encode__(p P) {
+		throw error("no")
+	}
+	return str(json.Marshal(P(x=1)))`,
+		nil, Str(`error: json: error calling __encode__ for type P: error: no`))
+	expectRun(t, `json:=import("json");P:=struct("P",fields={x:0})
+	func __encode__(p P) {
+		return p
+	}
+	return str(json.Marshal(P(x=1)))`,
+		nil, Str(`{"__type__":"P","x":1}`))
+
+	expectRun(t, `json:=import("json");try{json.Unmarshal("{}",types=1)}catch e{return str(e)}`,
+		nil, Str(NewArgumentTypeError("types", "array|dict", "int").ToString()))
+}
+
 func TestCycle(t *testing.T) {
+	expectRun(t, `json:=import("json");P:=struct("P",fields={x:0,y:nil});p:=P(x=1);p.y=p;return str(json.Marshal(p))`,
+		nil, Str(`error: json: unsupported value: encountered a cycle via P`))
 	expectRun(t, `json:=import("json");a:=[1,2];a[1]=a;return str(json.Marshal(a))`,
 		nil, Str(`error: json: unsupported value: encountered a cycle via array`))
 	expectRun(t, `json:=import("json");a:=[1,2];a[1]=a;return str(json.MarshalIndent(a,""," "))`,
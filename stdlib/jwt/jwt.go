@@ -0,0 +1,236 @@
+// Package jwt provides jwt module for Gad script language to sign and verify
+// JSON Web Tokens by HMAC, RSA, RSA-PSS, ECDSA and Ed25519 algorithms.
+package jwt
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"math/big"
+	"strconv"
+
+	"github.com/gad-lang/gad"
+)
+
+var (
+	// ErrJWT is the cause of all errors of invalid tokens.
+	ErrJWT = &gad.Error{Name: "JWTError"}
+	// ErrMalformed is returned if a token or a key can not be parsed.
+	ErrMalformed = &gad.Error{Name: "JWTMalformedError", Cause: ErrJWT}
+	// ErrAlgorithm is returned if an algorithm is unknown, not allowed or
+	// not usable with the key.
+	ErrAlgorithm = &gad.Error{Name: "JWTAlgorithmError", Cause: ErrJWT}
+	// ErrSignature is returned if the signature of a token is invalid.
+	ErrSignature = &gad.Error{Name: "JWTSignatureError", Cause: ErrJWT}
+	// ErrExpired is returned if the exp claim of a token is in the past.
+	ErrExpired = &gad.Error{Name: "JWTExpiredError", Cause: ErrJWT}
+	// ErrNotValidYet is returned if the nbf claim of a token is in the
+	// future.
+	ErrNotValidYet = &gad.Error{Name: "JWTNotValidYetError", Cause: ErrJWT}
+)
+
+// Algorithms are the supported algorithms.
+var Algorithms = []string{
+	"HS256", "HS384", "HS512",
+	"RS256", "RS384", "RS512",
+	"PS256", "PS384", "PS512",
+	"ES256", "ES384", "ES512",
+	"EdDSA",
+}
+
+func hashOf(alg string) crypto.Hash {
+	switch alg[2:] {
+	case "256":
+		return crypto.SHA256
+	case "384":
+		return crypto.SHA384
+	case "512":
+		return crypto.SHA512
+	}
+	return 0
+}
+
+func digest(alg string, input []byte) []byte {
+	h := hashOf(alg).New()
+	h.Write(input)
+	return h.Sum(nil)
+}
+
+func supported(alg string) bool {
+	for _, a := range Algorithms {
+		if a == alg {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseKey returns the key of alg from data. The key of HMAC algorithms is
+// data itself, other keys are PEM encoded PKCS #8, PKCS #1 or SEC 1 private
+// keys if private is true, otherwise PKIX or PKCS #1 public keys,
+// certificates or private keys whose public keys are returned.
+func ParseKey(alg string, data []byte, private bool) (any, error) {
+	if !supported(alg) {
+		return nil, ErrAlgorithm.NewError("unsupported algorithm " + strconv.Quote(alg))
+	}
+	if alg[0] == 'H' {
+		return data, nil
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, ErrMalformed.NewError("key is not PEM encoded")
+	}
+	var (
+		key any
+		err error
+	)
+	switch block.Type {
+	case "PRIVATE KEY":
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		key, err = x509.ParseECPrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		key, err = x509.ParsePKIXPublicKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		key, err = x509.ParsePKCS1PublicKey(block.Bytes)
+	case "CERTIFICATE":
+		var cert *x509.Certificate
+		if cert, err = x509.ParseCertificate(block.Bytes); err == nil {
+			key = cert.PublicKey
+		}
+	default:
+		return nil, ErrMalformed.NewError("unsupported PEM block " + strconv.Quote(block.Type))
+	}
+	if err != nil {
+		return nil, ErrMalformed.NewError(err.Error())
+	}
+	if signer, ok := key.(crypto.Signer); ok && !private {
+		key = signer.Public()
+	}
+	if !keyFits(alg, key, private) {
+		return nil, ErrAlgorithm.NewError("key is not usable with " + alg)
+	}
+	return key, nil
+}
+
+func keyFits(alg string, key any, private bool) bool {
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		return private && (alg[0] == 'R' || alg[0] == 'P')
+	case *rsa.PublicKey:
+		return !private && (alg[0] == 'R' || alg[0] == 'P')
+	case *ecdsa.PrivateKey:
+		return private && alg[0] == 'E' && alg != "EdDSA" && curveFits(alg, k.Curve.Params().BitSize)
+	case *ecdsa.PublicKey:
+		return !private && alg[0] == 'E' && alg != "EdDSA" && curveFits(alg, k.Curve.Params().BitSize)
+	case ed25519.PrivateKey:
+		return private && alg == "EdDSA"
+	case ed25519.PublicKey:
+		return !private && alg == "EdDSA"
+	}
+	return false
+}
+
+func curveFits(alg string, bits int) bool {
+	switch alg {
+	case "ES256":
+		return bits == 256
+	case "ES384":
+		return bits == 384
+	}
+	return bits == 521
+}
+
+// KeyAlgorithms returns the algorithms usable with the public key.
+func KeyAlgorithms(key any) []string {
+	switch k := key.(type) {
+	case []byte:
+		return []string{"HS256", "HS384", "HS512"}
+	case *rsa.PublicKey:
+		return []string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512"}
+	case *ecdsa.PublicKey:
+		switch k.Curve.Params().BitSize {
+		case 256:
+			return []string{"ES256"}
+		case 384:
+			return []string{"ES384"}
+		case 521:
+			return []string{"ES512"}
+		}
+	case ed25519.PublicKey:
+		return []string{"EdDSA"}
+	}
+	return nil
+}
+
+// Sign returns the signature of input by alg with the private key returned by
+// ParseKey.
+func Sign(alg string, key any, input []byte) ([]byte, error) {
+	switch k := key.(type) {
+	case []byte:
+		h := hmac.New(hashOf(alg).New, k)
+		h.Write(input)
+		return h.Sum(nil), nil
+	case *rsa.PrivateKey:
+		if alg[0] == 'P' {
+			return rsa.SignPSS(rand.Reader, k, hashOf(alg), digest(alg, input),
+				&rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash})
+		}
+		return rsa.SignPKCS1v15(rand.Reader, k, hashOf(alg), digest(alg, input))
+	case *ecdsa.PrivateKey:
+		r, s, err := ecdsa.Sign(rand.Reader, k, digest(alg, input))
+		if err != nil {
+			return nil, err
+		}
+		// the signature is r and s as fixed size big endian integers
+		size := (k.Curve.Params().BitSize + 7) / 8
+		sig := make([]byte, 2*size)
+		r.FillBytes(sig[:size])
+		s.FillBytes(sig[size:])
+		return sig, nil
+	case ed25519.PrivateKey:
+		return ed25519.Sign(k, input), nil
+	}
+	return nil, ErrAlgorithm.NewError("key is not usable with " + alg)
+}
+
+// Verify verifies the signature of input by alg with the public key returned
+// by ParseKey, it returns ErrSignature if the signature is invalid.
+func Verify(alg string, key any, input, sig []byte) error {
+	var ok bool
+	switch k := key.(type) {
+	case []byte:
+		h := hmac.New(hashOf(alg).New, k)
+		h.Write(input)
+		ok = hmac.Equal(sig, h.Sum(nil))
+	case *rsa.PublicKey:
+		if alg[0] == 'P' {
+			ok = rsa.VerifyPSS(k, hashOf(alg), digest(alg, input), sig,
+				&rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthAuto}) == nil
+		} else {
+			ok = rsa.VerifyPKCS1v15(k, hashOf(alg), digest(alg, input), sig) == nil
+		}
+	case *ecdsa.PublicKey:
+		size := (k.Curve.Params().BitSize + 7) / 8
+		if len(sig) == 2*size {
+			r := new(big.Int).SetBytes(sig[:size])
+			s := new(big.Int).SetBytes(sig[size:])
+			ok = ecdsa.Verify(k, digest(alg, input), r, s)
+		}
+	case ed25519.PublicKey:
+		ok = ed25519.Verify(k, input, sig)
+	default:
+		return ErrAlgorithm.NewError("key is not usable with " + alg)
+	}
+	if !ok {
+		return ErrSignature.NewError("invalid signature")
+	}
+	return nil
+}
@@ -0,0 +1,264 @@
+package jwt
+
+import (
+	"encoding/base64"
+	"encoding/pem"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gad-lang/gad"
+	gadjson "github.com/gad-lang/gad/stdlib/json"
+	gadtime "github.com/gad-lang/gad/stdlib/time"
+)
+
+// Module represents jwt module.
+var Module = gad.Dict{
+	// gad:doc
+	// # jwt module
+	//
+	// ## Functions
+	// sign(claims dict, key str|bytes; alg="HS256", header={}) -> str
+	// Returns the signed token of claims. key is the secret of HS256, HS384
+	// and HS512 algorithms, or the PEM encoded private key of RS*, PS*, ES*
+	// and EdDSA algorithms. Times of exp, nbf and iat claims are converted to
+	// Unix seconds. header is merged into the header of the token, e.g. to
+	// set kid.
+	"sign": &gad.Function{
+		Name:  "sign",
+		Value: signFunc,
+	},
+	// gad:doc
+	// verify(token str, key str|bytes; algs=nil, leeway=0) -> dict
+	// Verifies the token and returns its claims. key is the secret or the PEM
+	// encoded public key, certificate or private key. algs are the allowed
+	// algorithms, all algorithms usable with the key by default. exp and nbf
+	// claims are checked with the leeway duration, e.g. time.Minute.
+	// JWTMalformedError, JWTAlgorithmError, JWTSignatureError,
+	// JWTExpiredError or JWTNotValidYetError is thrown if the token is
+	// invalid, all of them are caused by JWTError.
+	"verify": &gad.Function{
+		Name:  "verify",
+		Value: verifyFunc,
+	},
+	// gad:doc
+	// ## Errors
+	// Error, MalformedError, AlgorithmError, SignatureError, ExpiredError
+	// and NotValidYetError are the errors to check with isError.
+	"Error":            ErrJWT,
+	"MalformedError":   ErrMalformed,
+	"AlgorithmError":   ErrAlgorithm,
+	"SignatureError":   ErrSignature,
+	"ExpiredError":     ErrExpired,
+	"NotValidYetError": ErrNotValidYet,
+}
+
+var b64 = base64.RawURLEncoding
+
+func toBytes(o gad.Object) []byte {
+	if b, ok := o.(gad.Bytes); ok {
+		return b
+	}
+	return []byte(o.ToString())
+}
+
+func keyArg() *gad.Arg {
+	return &gad.Arg{
+		Name:          "key",
+		TypeAssertion: gad.TypeAssertionFromTypes(gad.TStr, gad.TBytes),
+	}
+}
+
+func signFunc(c gad.Call) (_ gad.Object, err error) {
+	var (
+		claims = &gad.Arg{
+			Name:          "claims",
+			TypeAssertion: gad.TypeAssertionFromTypes(gad.TDict),
+		}
+		key = keyArg()
+		alg = &gad.NamedArgVar{
+			Name:          "alg",
+			Value:         gad.Str("HS256"),
+			TypeAssertion: gad.TypeAssertionFromTypes(gad.TStr),
+		}
+		header = &gad.NamedArgVar{
+			Name:          "header",
+			Value:         gad.Dict{},
+			TypeAssertion: gad.TypeAssertionFromTypes(gad.TDict),
+		}
+	)
+	if err = c.Args.Destructure(claims, key); err != nil {
+		return
+	}
+	if err = c.NamedArgs.Get(alg, header); err != nil {
+		return
+	}
+	var (
+		a = alg.Value.ToString()
+		k any
+		h = gad.Dict{"typ": gad.Str("JWT")}
+		p = claims.Value.(gad.Dict).Copy().(gad.Dict)
+	)
+	if k, err = ParseKey(a, toBytes(key.Value), true); err != nil {
+		return
+	}
+	for name, v := range header.Value.(gad.Dict) {
+		h[name] = v
+	}
+	h["alg"] = gad.Str(a)
+	for _, name := range []string{"exp", "nbf", "iat"} {
+		if t, ok := p[name].(*gadtime.Time); ok {
+			p[name] = gad.Int(t.Value.Unix())
+		}
+	}
+
+	var parts [2][]byte
+	for i, o := range []gad.Dict{h, p} {
+		if parts[i], err = gadjson.Marshal(c.VM, o); err != nil {
+			return
+		}
+	}
+	input := b64.EncodeToString(parts[0]) + "." + b64.EncodeToString(parts[1])
+	sig, err := Sign(a, k, []byte(input))
+	if err != nil {
+		return
+	}
+	return gad.Str(input + "." + b64.EncodeToString(sig)), nil
+}
+
+func verifyFunc(c gad.Call) (_ gad.Object, err error) {
+	var (
+		token = &gad.Arg{
+			Name:          "token",
+			TypeAssertion: gad.TypeAssertionFromTypes(gad.TStr),
+		}
+		key  = keyArg()
+		algs = &gad.NamedArgVar{
+			Name:          "algs",
+			Value:         gad.Nil,
+			TypeAssertion: gad.TypeAssertionFromTypes(gad.TArray, gad.TNil),
+		}
+		leeway = &gad.NamedArgVar{
+			Name:          "leeway",
+			Value:         gad.Int(0),
+			TypeAssertion: gad.TypeAssertionFromTypes(gad.TInt),
+		}
+	)
+	if err = c.Args.Destructure(token, key); err != nil {
+		return
+	}
+	if err = c.NamedArgs.Get(algs, leeway); err != nil {
+		return
+	}
+
+	parts := strings.Split(token.Value.ToString(), ".")
+	if len(parts) != 3 {
+		return nil, ErrMalformed.NewError("token must have 3 parts")
+	}
+	header, err := decodePart(parts[0])
+	if err != nil {
+		return
+	}
+	claims, err := decodePart(parts[1])
+	if err != nil {
+		return
+	}
+	sig, err := b64.DecodeString(parts[2])
+	if err != nil {
+		return nil, ErrMalformed.NewError("invalid signature encoding")
+	}
+
+	var (
+		a, _    = header["alg"].(gad.Str)
+		allowed []string
+		data    = toBytes(key.Value)
+	)
+	if arr, ok := algs.Value.(gad.Array); ok {
+		for _, v := range arr {
+			allowed = append(allowed, v.ToString())
+		}
+	} else {
+		allowed = defaultAlgorithms(data)
+	}
+	if !contains(allowed, string(a)) {
+		return nil, ErrAlgorithm.NewError("algorithm " + strconv.Quote(string(a)) + " is not allowed")
+	}
+	k, err := ParseKey(string(a), data, false)
+	if err != nil {
+		return
+	}
+	if err = Verify(string(a), k, []byte(parts[0]+"."+parts[1]), sig); err != nil {
+		return
+	}
+
+	var (
+		now = time.Now()
+		lee = time.Duration(leeway.Value.(gad.Int))
+	)
+	if exp, ok, err := numericDate(claims, "exp"); err != nil {
+		return nil, err
+	} else if ok && !now.Before(exp.Add(lee)) {
+		return nil, ErrExpired.NewError("token expired at " + exp.Format(time.RFC3339))
+	}
+	if nbf, ok, err := numericDate(claims, "nbf"); err != nil {
+		return nil, err
+	} else if ok && now.Add(lee).Before(nbf) {
+		return nil, ErrNotValidYet.NewError("token is not valid before " + nbf.Format(time.RFC3339))
+	}
+	return claims, nil
+}
+
+// defaultAlgorithms returns the algorithms usable with the key data.
+func defaultAlgorithms(data []byte) (algs []string) {
+	if block, _ := pem.Decode(data); block == nil {
+		return KeyAlgorithms(data)
+	}
+	for _, alg := range Algorithms {
+		if alg[0] == 'H' {
+			continue
+		}
+		if k, err := ParseKey(alg, data, false); err == nil {
+			return KeyAlgorithms(k)
+		}
+	}
+	return
+}
+
+func contains(s []string, v string) bool {
+	for _, e := range s {
+		if e == v {
+			return true
+		}
+	}
+	return false
+}
+
+func decodePart(s string) (gad.Dict, error) {
+	data, err := b64.DecodeString(s)
+	if err != nil {
+		return nil, ErrMalformed.NewError("invalid part encoding")
+	}
+	o, err := gadjson.Unmarshal(data, gadjson.NewDecodeOptions())
+	if err != nil {
+		return nil, ErrMalformed.NewError(err.Error())
+	}
+	d, ok := o.(gad.Dict)
+	if !ok {
+		return nil, ErrMalformed.NewError("part is not a JSON object")
+	}
+	return d, nil
+}
+
+// numericDate returns the time of the claim in Unix seconds.
+func numericDate(claims gad.Dict, name string) (t time.Time, ok bool, err error) {
+	switch v := claims[name].(type) {
+	case nil, *gad.NilType:
+	case gad.Int:
+		return time.Unix(int64(v), 0), true, nil
+	case gad.Float:
+		return time.UnixMilli(int64(float64(v) * 1000)), true, nil
+	default:
+		err = ErrMalformed.NewError(name + " claim is not a number")
+	}
+	return
+}
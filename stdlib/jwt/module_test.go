@@ -0,0 +1,127 @@
+package jwt_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/gad-lang/gad"
+	"github.com/gad-lang/gad/stdlib/jwt"
+	gadstrings "github.com/gad-lang/gad/stdlib/strings"
+	gadtime "github.com/gad-lang/gad/stdlib/time"
+)
+
+func run(t *testing.T, script string) (gad.Object, error) {
+	t.Helper()
+	mm := gad.NewModuleMap()
+	mm.AddBuiltinModule("jwt", jwt.Module).
+		AddBuiltinModule("time", gadtime.Module).
+		AddBuiltinModule("strings", gadstrings.Module)
+	c := gad.CompileOptions{CompilerOptions: gad.DefaultCompilerOptions}
+	c.ModuleMap = mm
+	bc, err := gad.Compile([]byte(`jwt := import("jwt"); time := import("time"); strings := import("strings");`+script), c)
+	require.NoError(t, err)
+	return gad.NewVM(bc).Run(nil)
+}
+
+func expectRun(t *testing.T, script string, expected gad.Object) {
+	t.Helper()
+	ret, err := run(t, script)
+	require.NoError(t, err)
+	require.Equal(t, expected, ret)
+}
+
+func expectErr(t *testing.T, script string, expected error) {
+	t.Helper()
+	opts := gad.NewTestOpts().
+		Module("jwt", jwt.Module).
+		Module("time", gadtime.Module).
+		Module("strings", gadstrings.Module)
+	gad.TestExpectRunErr(t, `jwt := import("jwt"); time := import("time"); strings := import("strings");`+script,
+		opts, expected)
+}
+
+func TestHMAC(t *testing.T) {
+	// the example token of jwt.io
+	expectRun(t, `return jwt.verify("eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9.eyJzdWIiOiIxMjM0NTY3ODkwIiwibmFtZSI6Ikpv`+
+		`aG4gRG9lIiwiaWF0IjoxNTE2MjM5MDIyfQ.SflKxwRJSMeKKF2QT4fwpMeJf36POk6yJV_adQssw5c", "your-256-bit-secret")`,
+		gad.Dict{"sub": gad.Str("1234567890"), "name": gad.Str("John Doe"), "iat": gad.Int(1516239022)})
+
+	expectRun(t, `tok := jwt.sign({sub: "x", exp: time.Now() + time.Hour}, "secret"; alg="HS512", header={kid: "k1"})
+		c := jwt.verify(tok, bytes("secret"))
+		return [c.sub, typeName(c.exp), len(strings.Split(tok, "."))]`,
+		gad.Array{gad.Str("x"), gad.Str("int"), gad.Int(3)})
+
+	expectErr(t, `jwt.verify(jwt.sign({}, "secret"), "other")`, jwt.ErrSignature)
+	expectErr(t, `jwt.verify(jwt.sign({}, "secret"), "secret"; algs=["HS512"])`, jwt.ErrAlgorithm)
+	expectErr(t, `jwt.verify("a.b", "secret")`, jwt.ErrMalformed)
+	expectErr(t, `jwt.verify("e30.e30.", "secret")`, jwt.ErrAlgorithm)
+	expectErr(t, `jwt.sign({}, "secret"; alg="none")`, jwt.ErrAlgorithm)
+}
+
+func TestTimes(t *testing.T) {
+	expectErr(t, `jwt.verify(jwt.sign({exp: time.Now() - time.Minute}, "s"), "s")`, jwt.ErrExpired)
+	expectErr(t, `jwt.verify(jwt.sign({nbf: time.Now() + time.Hour}, "s"), "s")`, jwt.ErrNotValidYet)
+	expectErr(t, `jwt.verify(jwt.sign({exp: "tomorrow"}, "s"), "s")`, jwt.ErrMalformed)
+	expectRun(t, `return jwt.verify(jwt.sign({exp: time.Now() - time.Minute, a: 1}, "s"), "s"; leeway=time.Hour).a`,
+		gad.Int(1))
+	expectRun(t, `try { jwt.verify(jwt.sign({exp: 1}, "s"), "s") } catch err {
+			return [isError(err, jwt.ExpiredError), isError(err, jwt.Error), isError(err, jwt.SignatureError)] }`,
+		gad.Array{gad.True, gad.True, gad.False})
+}
+
+func pemOf(t *testing.T, typ string, der []byte, err error) string {
+	t.Helper()
+	require.NoError(t, err)
+	return strconv.Quote(string(pem.EncodeToMemory(&pem.Block{Type: typ, Bytes: der})))
+}
+
+func TestKeys(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	edPub, edKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	rsaPriv := pemOf(t, "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(rsaKey), nil)
+	rsaDer, err := x509.MarshalPKIXPublicKey(&rsaKey.PublicKey)
+	rsaPub := pemOf(t, "PUBLIC KEY", rsaDer, err)
+	ecDer, err := x509.MarshalECPrivateKey(ecKey)
+	ecPriv := pemOf(t, "EC PRIVATE KEY", ecDer, err)
+	ecDer, err = x509.MarshalPKIXPublicKey(&ecKey.PublicKey)
+	ecPub := pemOf(t, "PUBLIC KEY", ecDer, err)
+	edDer, err := x509.MarshalPKCS8PrivateKey(edKey)
+	edPriv := pemOf(t, "PRIVATE KEY", edDer, err)
+	edDer, err = x509.MarshalPKIXPublicKey(edPub)
+	edPubPEM := pemOf(t, "PUBLIC KEY", edDer, err)
+
+	for _, tc := range []struct{ alg, priv, pub string }{
+		{"RS256", rsaPriv, rsaPub},
+		{"PS384", rsaPriv, rsaPub},
+		{"ES256", ecPriv, ecPub},
+		{"EdDSA", edPriv, edPubPEM},
+	} {
+		sign := `jwt.sign({sub: "x"}, ` + tc.priv + `; alg="` + tc.alg + `")`
+		expectRun(t, `return jwt.verify(`+sign+`, `+tc.pub+`).sub`, gad.Str("x"))
+		// private keys verify by their public keys
+		expectRun(t, `return jwt.verify(`+sign+`, `+tc.priv+`).sub`, gad.Str("x"))
+	}
+
+	expectErr(t, `jwt.verify(jwt.sign({}, `+rsaPriv+`; alg="RS256"), `+ecPub+`)`, jwt.ErrAlgorithm)
+	expectErr(t, `jwt.sign({}, `+ecPriv+`; alg="ES384")`, jwt.ErrAlgorithm)
+	// the public key is not an HMAC secret unless allowed explicitly
+	expectErr(t, `jwt.verify(jwt.sign({}, `+rsaPub+`), `+rsaPub+`)`, jwt.ErrAlgorithm)
+	// tampered claims
+	expectErr(t, `tok := strings.Split(jwt.sign({a: 1}, `+edPriv+`; alg="EdDSA"), ".")
+		tok[1] = strings.Split(jwt.sign({a: 2}, "s"), ".")[1]
+		jwt.verify(strings.Join(tok, "."), `+edPubPEM+`)`, jwt.ErrSignature)
+}
@@ -0,0 +1,192 @@
+// Package metrics provides metrics module for Gad script language. Scripts
+// update counters, gauges and histograms which are buffered in a Buffer and
+// drained periodically by the host to a Collector, which may export them to
+// Prometheus or other monitoring systems.
+package metrics
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/gad-lang/gad"
+)
+
+// Kind is the kind of a metric.
+type Kind int
+
+const (
+	// Counter is a metric which only increases.
+	Counter Kind = iota
+	// Gauge is a metric which is set to arbitrary values.
+	Gauge
+	// Histogram is a metric of the distribution of observed values.
+	Histogram
+)
+
+func (k Kind) String() string {
+	switch k {
+	case Counter:
+		return "counter"
+	case Gauge:
+		return "gauge"
+	case Histogram:
+		return "histogram"
+	}
+	return "unknown"
+}
+
+// Labels are the label values of a metric by the label names.
+type Labels map[string]string
+
+// Collector receives the metrics drained from a Buffer. The labels must not
+// be modified.
+type Collector interface {
+	// AddCounter adds delta to the counter of the labels.
+	AddCounter(name string, labels Labels, delta float64)
+	// SetGauge sets the gauge of the labels to value.
+	SetGauge(name string, labels Labels, value float64)
+	// Observe adds the observed values to the histogram of the labels.
+	Observe(name string, labels Labels, values []float64)
+}
+
+var (
+	nameRe  = regexp.MustCompile(`^[a-zA-Z_:][a-zA-Z0-9_:]*$`)
+	labelRe = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+)
+
+// series is the buffered state of a metric with labels.
+type series struct {
+	kind   Kind
+	name   string
+	labels Labels
+	// delta is the increase of a counter since the last drain.
+	delta float64
+	// value is the value of a gauge, dirty is true if it is changed since
+	// the last drain.
+	value float64
+	dirty bool
+	// observations are the values observed by a histogram since the last
+	// drain.
+	observations []float64
+}
+
+// Buffer buffers the updates of the metrics until they are drained. It is
+// safe for concurrent use, so VMs running concurrently may share it.
+type Buffer struct {
+	mu     sync.Mutex
+	kinds  map[string]Kind
+	series map[string]*series
+}
+
+// Default is the Buffer of Module.
+var Default = NewBuffer()
+
+// NewBuffer returns a new empty Buffer.
+func NewBuffer() *Buffer {
+	return &Buffer{kinds: map[string]Kind{}, series: map[string]*series{}}
+}
+
+// get returns the series of the metric, it returns an error if the name or
+// a label name is invalid or the name is used by a metric of another kind.
+func (b *Buffer) get(kind Kind, name string, labels Labels) (*series, error) {
+	if !nameRe.MatchString(name) {
+		return nil, gad.ErrUnexpectedArgValue.NewError("invalid metric name " + name)
+	}
+	names := make([]string, 0, len(labels))
+	for l := range labels {
+		if !labelRe.MatchString(l) || strings.HasPrefix(l, "__") {
+			return nil, gad.ErrUnexpectedArgValue.NewError("invalid label name " + l)
+		}
+		names = append(names, l)
+	}
+	sort.Strings(names)
+	var key strings.Builder
+	key.WriteString(name)
+	for _, l := range names {
+		key.WriteString("\x00" + l + "\x00" + labels[l])
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if k, ok := b.kinds[name]; ok && k != kind {
+		return nil, gad.ErrUnexpectedArgValue.NewError("metric " + name + " is a " + k.String())
+	}
+	b.kinds[name] = kind
+	s := b.series[key.String()]
+	if s == nil {
+		s = &series{kind: kind, name: name, labels: labels}
+		b.series[key.String()] = s
+	}
+	return s, nil
+}
+
+func (b *Buffer) add(s *series, delta float64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if s.kind == Counter {
+		s.delta += delta
+	} else {
+		s.value += delta
+		s.dirty = true
+	}
+}
+
+func (b *Buffer) set(s *series, value float64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	s.value = value
+	s.dirty = true
+}
+
+func (b *Buffer) observe(s *series, value float64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	s.observations = append(s.observations, value)
+}
+
+// Drain sends the updates buffered since the last drain to c in the order of
+// the names and labels of the metrics. Gauges keep their values, so later
+// increments are relative to them.
+func (b *Buffer) Drain(c Collector) {
+	b.mu.Lock()
+	keys := make([]string, 0, len(b.series))
+	for k := range b.series {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	type update struct {
+		s            *series
+		v            float64
+		observations []float64
+	}
+	var updates []update
+	for _, k := range keys {
+		s := b.series[k]
+		switch {
+		case s.kind == Counter && s.delta != 0:
+			updates = append(updates, update{s: s, v: s.delta})
+			s.delta = 0
+		case s.kind == Gauge && s.dirty:
+			updates = append(updates, update{s: s, v: s.value})
+			s.dirty = false
+		case s.kind == Histogram && len(s.observations) > 0:
+			updates = append(updates, update{s: s, observations: s.observations})
+			s.observations = nil
+		}
+	}
+	b.mu.Unlock()
+
+	// the collector is called without the lock as it may be slow
+	for _, u := range updates {
+		switch u.s.kind {
+		case Counter:
+			c.AddCounter(u.s.name, u.s.labels, u.v)
+		case Gauge:
+			c.SetGauge(u.s.name, u.s.labels, u.v)
+		case Histogram:
+			c.Observe(u.s.name, u.s.labels, u.observations)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package metrics
+
+import (
+	"github.com/gad-lang/gad"
+)
+
+// Module is the metrics module buffering the metrics in Default.
+var Module = NewModule(Default)
+
+// NewModule returns the metrics module buffering the metrics in b.
+func NewModule(b *Buffer) gad.Dict {
+	return gad.Dict{
+		// gad:doc
+		// # metrics module
+		//
+		// ## Functions
+		// counter(name str; **labels) -> counter
+		// Returns the counter of the name and the labels, the metrics of the
+		// same name and labels share their values. The label values are
+		// converted to str.
+		"counter": &gad.Function{
+			Name:  "counter",
+			Value: b.metricFunc(Counter),
+		},
+		// gad:doc
+		// gauge(name str; **labels) -> gauge
+		// Returns the gauge of the name and the labels.
+		"gauge": &gad.Function{
+			Name:  "gauge",
+			Value: b.metricFunc(Gauge),
+		},
+		// gad:doc
+		// histogram(name str; **labels) -> histogram
+		// Returns the histogram of the name and the labels. The buckets of the
+		// histograms are defined by the host.
+		"histogram": &gad.Function{
+			Name:  "histogram",
+			Value: b.metricFunc(Histogram),
+		},
+	}
+}
+
+// metricFunc returns the function returning the metrics of the kind. It
+// throws an error if the name or a label name is not a valid Prometheus name
+// or the name is used by a metric of another kind.
+func (b *Buffer) metricFunc(kind Kind) gad.CallableFunc {
+	return func(c gad.Call) (_ gad.Object, err error) {
+		name := &gad.Arg{
+			Name:          "name",
+			TypeAssertion: gad.TypeAssertionFromTypes(gad.TStr),
+		}
+		if err = c.Args.Destructure(name); err != nil {
+			return
+		}
+		labels := Labels{}
+		for _, kv := range c.NamedArgs.UnreadPairs() {
+			labels[kv.K.ToString()] = kv.V.ToString()
+		}
+		var s *series
+		if s, err = b.get(kind, name.Value.ToString(), labels); err != nil {
+			return
+		}
+		return &Metric{buf: b, s: s}, nil
+	}
+}
@@ -0,0 +1,99 @@
+package metrics_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/gad-lang/gad"
+	"github.com/gad-lang/gad/stdlib/metrics"
+)
+
+// recorder records the drained metrics as strings.
+type recorder []string
+
+func (r *recorder) AddCounter(name string, labels metrics.Labels, delta float64) {
+	*r = append(*r, fmt.Sprintf("counter %s %v %v", name, labels, delta))
+}
+
+func (r *recorder) SetGauge(name string, labels metrics.Labels, value float64) {
+	*r = append(*r, fmt.Sprintf("gauge %s %v %v", name, labels, value))
+}
+
+func (r *recorder) Observe(name string, labels metrics.Labels, values []float64) {
+	*r = append(*r, fmt.Sprintf("histogram %s %v %v", name, labels, values))
+}
+
+func run(t *testing.T, b *metrics.Buffer, script string) (gad.Object, error) {
+	t.Helper()
+	mm := gad.NewModuleMap()
+	mm.AddBuiltinModule("metrics", metrics.NewModule(b))
+	c := gad.CompileOptions{CompilerOptions: gad.DefaultCompilerOptions}
+	c.ModuleMap = mm
+	bc, err := gad.Compile([]byte(`metrics := import("metrics");`+script), c)
+	require.NoError(t, err)
+	return gad.NewVM(bc).Run(nil)
+}
+
+func TestDrain(t *testing.T) {
+	b := metrics.NewBuffer()
+	ret, err := run(t, b, `
+	requests := func(method) { return metrics.counter("http_requests_total", method=method, code=200) }
+	requests("GET").inc()
+	requests("GET").inc(2)
+	requests("POST").inc(0.5)
+	metrics.counter("unused_total").inc(0)
+	g := metrics.gauge("queue_size")
+	g.set(10)
+	g.dec()
+	g.inc(3)
+	h := metrics.histogram("latency_seconds", route="/")
+	h.observe(0.25)
+	h.observe(1)
+	return [str(requests("GET")), requests("GET").name, requests("GET").labels, typeName(g), typeName(h)]`)
+	require.NoError(t, err)
+	require.Equal(t, gad.Array{
+		gad.Str(`http_requests_total{code="200",method="GET"}`),
+		gad.Str("http_requests_total"),
+		gad.Dict{"method": gad.Str("GET"), "code": gad.Str("200")},
+		gad.Str("gauge"),
+		gad.Str("histogram"),
+	}, ret)
+
+	var r recorder
+	b.Drain(&r)
+	require.Equal(t, recorder{
+		"counter http_requests_total map[code:200 method:GET] 3",
+		"counter http_requests_total map[code:200 method:POST] 0.5",
+		"histogram latency_seconds map[route:/] [0.25 1]",
+		"gauge queue_size map[] 12",
+	}, r)
+
+	r = nil
+	b.Drain(&r)
+	require.Empty(t, r)
+
+	_, err = run(t, b, `metrics.gauge("queue_size").inc()`)
+	require.NoError(t, err)
+	b.Drain(&r)
+	require.Equal(t, recorder{"gauge queue_size map[] 13"}, r)
+}
+
+func TestErrors(t *testing.T) {
+	for script, expected := range map[string]error{
+		`metrics.counter("1x")`:                    gad.ErrUnexpectedArgValue,
+		`metrics.counter("x", __name="a")`:         gad.ErrUnexpectedArgValue,
+		`metrics.counter("x"); metrics.gauge("x")`: gad.ErrUnexpectedArgValue,
+		`metrics.counter("x").inc(-1)`:             gad.ErrUnexpectedArgValue,
+		`metrics.counter("x").set(1)`:              gad.ErrInvalidIndex,
+		`metrics.histogram("x").inc()`:             gad.ErrInvalidIndex,
+		`metrics.gauge("x").set("1")`:              gad.ErrType,
+		`metrics.counter(1)`:                       gad.ErrType,
+	} {
+		t.Run(script, func(t *testing.T) {
+			_, err := run(t, metrics.NewBuffer(), script)
+			require.ErrorIs(t, err, expected)
+		})
+	}
+}
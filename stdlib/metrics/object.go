@@ -0,0 +1,181 @@
+package metrics
+
+import (
+	"sort"
+	"strconv"
+
+	"github.com/gad-lang/gad"
+)
+
+// gad:doc
+// ## Types
+// ### counter, gauge and histogram
+//
+// Go Type
+//
+// ```go
+// // Metric is a metric with labels buffered in a Buffer.
+// type Metric struct {
+//   gad.ObjectImpl
+// }
+// ```
+
+var (
+	// TCounter is the type of counter metrics.
+	TCounter = &gad.BuiltinObjType{NameValue: "counter"}
+	// TGauge is the type of gauge metrics.
+	TGauge = &gad.BuiltinObjType{NameValue: "gauge"}
+	// THistogram is the type of histogram metrics.
+	THistogram = &gad.BuiltinObjType{NameValue: "histogram"}
+)
+
+// Metric is a metric with labels buffered in a Buffer.
+type Metric struct {
+	gad.ObjectImpl
+	buf *Buffer
+	s   *series
+}
+
+var (
+	_ gad.IndexGetter      = (*Metric)(nil)
+	_ gad.NameCallerObject = (*Metric)(nil)
+)
+
+func (m *Metric) Type() gad.ObjectType {
+	switch m.s.kind {
+	case Gauge:
+		return TGauge
+	case Histogram:
+		return THistogram
+	}
+	return TCounter
+}
+
+// ToString implements gad.Object interface.
+func (m *Metric) ToString() string {
+	names := make([]string, 0, len(m.s.labels))
+	for l := range m.s.labels {
+		names = append(names, l)
+	}
+	sort.Strings(names)
+	s := m.s.name
+	if len(names) > 0 {
+		s += "{"
+		for i, l := range names {
+			if i > 0 {
+				s += ","
+			}
+			s += l + "=" + strconv.Quote(m.s.labels[l])
+		}
+		s += "}"
+	}
+	return s
+}
+
+// IsFalsy implements gad.Object interface.
+func (m *Metric) IsFalsy() bool {
+	return false
+}
+
+// Equal implements gad.Object interface.
+func (m *Metric) Equal(right gad.Object) bool {
+	v, ok := right.(*Metric)
+	return ok && v.s == m.s
+}
+
+// gad:doc
+// #### Metric Getters
+//
+// | Selector  | Return Type                                          |
+// |:----------|:-----------------------------------------------------|
+// |.name      | str                                                  |
+// |.labels    | dict                                                 |
+
+// IndexGet implements gad.IndexGetter interface.
+func (m *Metric) IndexGet(_ *gad.VM, index gad.Object) (gad.Object, error) {
+	switch index.ToString() {
+	case "name":
+		return gad.Str(m.s.name), nil
+	case "labels":
+		d := make(gad.Dict, len(m.s.labels))
+		for k, v := range m.s.labels {
+			d[k] = gad.Str(v)
+		}
+		return d, nil
+	}
+	return nil, gad.ErrInvalidIndex.NewError(index.ToString())
+}
+
+// gad:doc
+// #### counter Methods
+//
+// | Method                   | Return Type                                   |
+// |:-------------------------|:----------------------------------------------|
+// |.inc(delta=1)             | nil                                           |
+//
+// #### gauge Methods
+//
+// | Method                   | Return Type                                   |
+// |:-------------------------|:----------------------------------------------|
+// |.set(value)               | nil                                           |
+// |.inc(delta=1)             | nil                                           |
+// |.dec(delta=1)             | nil                                           |
+//
+// #### histogram Methods
+//
+// | Method                   | Return Type                                   |
+// |:-------------------------|:----------------------------------------------|
+// |.observe(value)           | nil                                           |
+//
+// The values are int, uint, float or decimal. inc of a counter throws an
+// error if delta is negative.
+
+// CallName implements gad.NameCallerObject interface.
+func (m *Metric) CallName(name string, c gad.Call) (_ gad.Object, err error) {
+	var v float64
+	switch {
+	case name == "inc" && m.s.kind != Histogram,
+		name == "dec" && m.s.kind == Gauge:
+		v = 1
+		if err = c.Args.CheckMaxLen(1); err != nil {
+			return
+		}
+		if c.Args.Length() == 1 {
+			if v, err = value("delta", c.Args.GetOnly(0)); err != nil {
+				return
+			}
+		}
+		if name == "dec" {
+			v = -v
+		} else if m.s.kind == Counter && v < 0 {
+			return nil, gad.ErrUnexpectedArgValue.NewError("negative counter delta")
+		}
+		m.buf.add(m.s, v)
+	case name == "set" && m.s.kind == Gauge,
+		name == "observe" && m.s.kind == Histogram:
+		if err = c.Args.CheckLen(1); err != nil {
+			return
+		}
+		if v, err = value("value", c.Args.GetOnly(0)); err != nil {
+			return
+		}
+		if name == "set" {
+			m.buf.set(m.s, v)
+		} else {
+			m.buf.observe(m.s, v)
+		}
+	default:
+		return nil, gad.ErrInvalidIndex.NewError(name)
+	}
+	return gad.Nil, nil
+}
+
+// value returns the float64 of the numeric argument o.
+func value(name string, o gad.Object) (float64, error) {
+	switch o.(type) {
+	case gad.Int, gad.Uint, gad.Float, gad.Decimal:
+		v, _ := gad.ToGoFloat64(o)
+		return v, nil
+	}
+	return 0, gad.NewArgumentTypeError(name, "int|uint|float|decimal", o.Type().Name())
+}
@@ -0,0 +1,200 @@
+package net
+
+import (
+	"net/netip"
+
+	"github.com/gad-lang/gad"
+)
+
+// gad:doc
+// ### cidr
+//
+// Go Type
+//
+// ```go
+// // CIDR represents IPv4 and IPv6 subnets and implements gad.Object interface.
+// type CIDR struct {
+//   Value netip.Prefix
+// }
+// ```
+
+// TCIDR is the type of CIDR objects, calling it parses a subnet.
+var TCIDR = &gad.BuiltinObjType{
+	NameValue: "cidr",
+	Value:     newCIDR,
+}
+
+// CIDR represents IPv4 and IPv6 subnets and implements gad.Object interface.
+// The prefix is masked, e.g. 10.1.2.3/8 is 10.0.0.0/8.
+type CIDR struct {
+	Value netip.Prefix
+}
+
+var (
+	_ gad.Object           = (*CIDR)(nil)
+	_ gad.IndexGetter      = (*CIDR)(nil)
+	_ gad.NameCallerObject = (*CIDR)(nil)
+	_ gad.Iterabler        = (*CIDR)(nil)
+)
+
+func (*CIDR) Type() gad.ObjectType {
+	return TCIDR
+}
+
+// ToString implements gad.Object interface.
+func (o *CIDR) ToString() string {
+	return o.Value.String()
+}
+
+// IsFalsy implements gad.Object interface.
+func (o *CIDR) IsFalsy() bool {
+	return !o.Value.IsValid()
+}
+
+// Equal implements gad.Object interface.
+func (o *CIDR) Equal(right gad.Object) bool {
+	switch v := right.(type) {
+	case *CIDR:
+		return o.Value == v.Value
+	case gad.Str:
+		p, err := netip.ParsePrefix(string(v))
+		return err == nil && o.Value == p.Masked()
+	}
+	return false
+}
+
+// gad:doc
+// #### cidr Getters
+//
+// | Selector  | Return Type                                   |
+// |:----------|:----------------------------------------------|
+// |.network   | ip, the first address                         |
+// |.last      | ip, the last address, broadcast for IPv4      |
+// |.bits      | int, the prefix length                        |
+// |.version   | int                                           |
+
+// IndexGet implements gad.IndexGetter interface.
+func (o *CIDR) IndexGet(_ *gad.VM, index gad.Object) (gad.Object, error) {
+	switch index.ToString() {
+	case "network":
+		return &IP{Value: o.Value.Addr()}, nil
+	case "last":
+		return &IP{Value: o.last()}, nil
+	case "bits":
+		return gad.Int(o.Value.Bits()), nil
+	case "version":
+		if o.Value.Addr().Is4() {
+			return gad.Int(4), nil
+		}
+		return gad.Int(6), nil
+	}
+	return nil, gad.ErrInvalidIndex.NewError(index.ToString())
+}
+
+// gad:doc
+// #### cidr Methods
+//
+// | Method                  | Return Type                                  |
+// |:------------------------|:---------------------------------------------|
+// |.contains(v ip|str|cidr) | bool                                         |
+// |.hosts()                 | iterator of the host addresses               |
+//
+// Iterating a cidr iterates its hosts lazily. The network and the broadcast
+// addresses of the IPv4 subnets larger than /31 are not hosts.
+
+// CallName implements gad.NameCallerObject interface.
+func (o *CIDR) CallName(name string, c gad.Call) (gad.Object, error) {
+	switch name {
+	case "contains":
+		if err := c.Args.CheckLen(1); err != nil {
+			return nil, err
+		}
+		if p, ok := c.Args.GetOnly(0).(*CIDR); ok {
+			return gad.Bool(p.Value.Bits() >= o.Value.Bits() && o.Value.Contains(p.Value.Addr())), nil
+		}
+		a, err := ToAddr(c.Args.GetOnly(0))
+		if err != nil {
+			return nil, err
+		}
+		return gad.Bool(o.Value.Contains(a)), nil
+	case "hosts":
+		if err := c.Args.CheckLen(0); err != nil {
+			return nil, err
+		}
+		return gad.IteratorObject(o.hosts()), nil
+	}
+	return nil, gad.ErrInvalidIndex.NewError(name)
+}
+
+// Iterate implements gad.Iterabler interface.
+func (o *CIDR) Iterate(*gad.VM, *gad.NamedArgs) gad.Iterator {
+	return o.hosts()
+}
+
+// last returns the last address of the subnet.
+func (o *CIDR) last() netip.Addr {
+	b := o.Value.Addr().AsSlice()
+	for i := o.Value.Bits(); i < len(b)*8; i++ {
+		b[i/8] |= 0x80 >> (i % 8)
+	}
+	a, _ := netip.AddrFromSlice(b)
+	return a
+}
+
+// hosts returns the iterator of the host addresses. The keys of the entries
+// are their indexes.
+func (o *CIDR) hosts() gad.Iterator {
+	first, last := o.Value.Addr(), o.last()
+	if first.Is4() && o.Value.Bits() < 31 {
+		first, last = first.Next(), last.Prev()
+	}
+	return gad.NewIterator(
+		func(*gad.VM) (*gad.IteratorState, error) {
+			state := &gad.IteratorState{}
+			setHost(state, 0, first)
+			return state, nil
+		},
+		func(_ *gad.VM, state *gad.IteratorState) error {
+			cur := state.Value.(gad.Array)
+			if a := cur[1].(*IP).Value; a == last {
+				state.Mode = gad.IteratorStateModeDone
+			} else {
+				setHost(state, cur[0].(gad.Int)+1, a.Next())
+			}
+			return nil
+		}).SetInput(o).SetItType(TCIDR)
+}
+
+// setHost sets the entry of the host a at index i. state.Value holds them too
+// as the entry may be changed by the wrapping iterators like map.
+func setHost(state *gad.IteratorState, i gad.Int, a netip.Addr) {
+	ip := &IP{Value: a}
+	state.Value = gad.Array{i, ip}
+	state.Entry.K, state.Entry.V = i, ip
+}
+
+// ToPrefix returns the masked prefix of a cidr object or a string.
+func ToPrefix(o gad.Object) (netip.Prefix, error) {
+	switch v := o.(type) {
+	case *CIDR:
+		return v.Value, nil
+	case gad.Str:
+		p, err := netip.ParsePrefix(string(v))
+		if err != nil {
+			return p, gad.ErrUnexpectedArgValue.NewError(err.Error())
+		}
+		return p.Masked(), nil
+	}
+	return netip.Prefix{}, gad.NewArgumentTypeError("1st", "cidr|str", o.Type().Name())
+}
+
+func newCIDR(c gad.Call) (gad.Object, error) {
+	if err := c.Args.CheckLen(1); err != nil {
+		return nil, err
+	}
+	p, err := ToPrefix(c.Args.GetOnly(0))
+	if err != nil {
+		return nil, err
+	}
+	return &CIDR{Value: p}, nil
+}
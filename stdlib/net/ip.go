@@ -0,0 +1,185 @@
+// Package net provides net module for Gad script language to work with IPv4
+// and IPv6 addresses and subnets, e.g. in network inventory scripts.
+package net
+
+import (
+	"net/netip"
+
+	"github.com/gad-lang/gad"
+	"github.com/gad-lang/gad/token"
+)
+
+// gad:doc
+// ## Types
+// ### ip
+//
+// Go Type
+//
+// ```go
+// // IP represents IPv4 and IPv6 addresses and implements gad.Object interface.
+// type IP struct {
+//   Value netip.Addr
+// }
+// ```
+
+// TIP is the type of IP objects, calling it parses an address.
+var TIP = &gad.BuiltinObjType{
+	NameValue: "ip",
+	Value:     newIP,
+}
+
+// IP represents IPv4 and IPv6 addresses and implements gad.Object interface.
+type IP struct {
+	Value netip.Addr
+}
+
+var (
+	_ gad.Object                = (*IP)(nil)
+	_ gad.IndexGetter           = (*IP)(nil)
+	_ gad.BinaryOperatorHandler = (*IP)(nil)
+)
+
+func (*IP) Type() gad.ObjectType {
+	return TIP
+}
+
+// ToString implements gad.Object interface.
+func (o *IP) ToString() string {
+	return o.Value.String()
+}
+
+// IsFalsy implements gad.Object interface.
+func (o *IP) IsFalsy() bool {
+	return !o.Value.IsValid()
+}
+
+// Equal implements gad.Object interface.
+func (o *IP) Equal(right gad.Object) bool {
+	switch v := right.(type) {
+	case *IP:
+		return o.Value == v.Value
+	case gad.Str:
+		a, err := netip.ParseAddr(string(v))
+		return err == nil && o.Value == a
+	}
+	return false
+}
+
+// gad:doc
+// #### ip Getters
+//
+// | Selector       | Return Type |
+// |:---------------|:------------|
+// |.version        | int         |
+// |.isLoopback     | bool        |
+// |.isPrivate      | bool        |
+// |.isMulticast    | bool        |
+// |.isUnspecified  | bool        |
+// |.isGlobal       | bool        |
+
+// IndexGet implements gad.IndexGetter interface.
+func (o *IP) IndexGet(_ *gad.VM, index gad.Object) (gad.Object, error) {
+	switch index.ToString() {
+	case "version":
+		if o.Value.Is4() {
+			return gad.Int(4), nil
+		}
+		return gad.Int(6), nil
+	case "isLoopback":
+		return gad.Bool(o.Value.IsLoopback()), nil
+	case "isPrivate":
+		return gad.Bool(o.Value.IsPrivate()), nil
+	case "isMulticast":
+		return gad.Bool(o.Value.IsMulticast()), nil
+	case "isUnspecified":
+		return gad.Bool(o.Value.IsUnspecified()), nil
+	case "isGlobal":
+		return gad.Bool(o.Value.IsGlobalUnicast()), nil
+	}
+	return nil, gad.ErrInvalidIndex.NewError(index.ToString())
+}
+
+// gad:doc
+// #### Overloaded ip Operators
+//
+// - `ip + int` -> ip
+// - `ip - int` -> ip
+// - `ip < ip` -> bool
+// - `ip > ip` -> bool
+// - `ip <= ip` -> bool
+// - `ip >= ip` -> bool
+
+// BinaryOp implements gad.BinaryOperatorHandler interface.
+func (o *IP) BinaryOp(_ *gad.VM, tok token.Token, right gad.Object) (gad.Object, error) {
+	switch v := right.(type) {
+	case gad.Int:
+		switch tok {
+		case token.Add:
+			return o.add(int64(v))
+		case token.Sub:
+			return o.add(-int64(v))
+		}
+	case *IP:
+		c := o.Value.Compare(v.Value)
+		switch tok {
+		case token.Less:
+			return gad.Bool(c < 0), nil
+		case token.LessEq:
+			return gad.Bool(c <= 0), nil
+		case token.Greater:
+			return gad.Bool(c > 0), nil
+		case token.GreaterEq:
+			return gad.Bool(c >= 0), nil
+		}
+	}
+	return nil, gad.NewOperandTypeError(tok.String(), o.Type().Name(), right.Type().Name())
+}
+
+// add returns the address n after o, or before if n is negative.
+func (o *IP) add(n int64) (gad.Object, error) {
+	b := o.Value.AsSlice()
+	carry := n
+	for i := len(b) - 1; i >= 0 && carry != 0; i-- {
+		sum := int64(b[i]) + carry%256
+		carry /= 256
+		if sum < 0 {
+			sum += 256
+			carry--
+		} else if sum > 255 {
+			sum -= 256
+			carry++
+		}
+		b[i] = byte(sum)
+	}
+	if carry != 0 {
+		return nil, gad.ErrIndexOutOfBounds.NewError("address overflow")
+	}
+	a, _ := netip.AddrFromSlice(b)
+	return &IP{Value: a.WithZone(o.Value.Zone())}, nil
+}
+
+// ToAddr returns the address of an ip object or a string.
+func ToAddr(o gad.Object) (netip.Addr, error) {
+	switch v := o.(type) {
+	case *IP:
+		return v.Value, nil
+	case gad.Str:
+		a, err := netip.ParseAddr(string(v))
+		if err != nil {
+			return a, gad.ErrUnexpectedArgValue.NewError(err.Error())
+		}
+		return a, nil
+	}
+	return netip.Addr{}, gad.NewArgumentTypeError("1st", "ip|str", o.Type().Name())
+}
+
+func newIP(c gad.Call) (gad.Object, error) {
+	if err := c.Args.CheckLen(1); err != nil {
+		return nil, err
+	}
+	a, err := ToAddr(c.Args.GetOnly(0))
+	if err != nil {
+		return nil, err
+	}
+	return &IP{Value: a}, nil
+}
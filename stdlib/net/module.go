@@ -0,0 +1,21 @@
+package net
+
+import (
+	"github.com/gad-lang/gad"
+)
+
+// Module represents net module.
+var Module = gad.Dict{
+	// gad:doc
+	// # net module
+	//
+	// ## Functions
+	// ip(s str|ip) -> ip
+	// Parses the IPv4 or IPv6 address s, e.g. "10.0.0.1" or "fe80::1".
+	"ip": TIP,
+	// gad:doc
+	// cidr(s str|cidr) -> cidr
+	// Parses the subnet s in CIDR notation, e.g. "10.0.0.0/8" or "fd00::/64".
+	// The address bits out of the prefix are cleared.
+	"cidr": TCIDR,
+}
@@ -0,0 +1,65 @@
+package net_test
+
+import (
+	"testing"
+
+	"github.com/gad-lang/gad"
+	gadnet "github.com/gad-lang/gad/stdlib/net"
+)
+
+func expectRun(t *testing.T, script string, expected gad.Object) {
+	t.Helper()
+	opts := gad.NewTestOpts().Module("net", gadnet.Module)
+	gad.TestExpectRun(t, `net := import("net");`+script, opts, expected)
+}
+
+func expectErr(t *testing.T, script string, expected error) {
+	t.Helper()
+	gad.TestExpectRunErr(t, `net := import("net");`+script, gad.NewTestOpts().Module("net", gadnet.Module), expected)
+}
+
+func TestIP(t *testing.T) {
+	expectRun(t, `return str(net.ip("10.0.0.1"))`, gad.Str("10.0.0.1"))
+	expectRun(t, `return str(net.ip("FE80::1"))`, gad.Str("fe80::1"))
+	expectRun(t, `ip := net.ip("10.0.0.1"); return [ip.version, ip.isPrivate, ip.isLoopback, ip.isGlobal]`,
+		gad.Array{gad.Int(4), gad.True, gad.False, gad.True})
+	expectRun(t, `ip := net.ip("::1"); return [ip.version, ip.isLoopback, ip.isUnspecified]`,
+		gad.Array{gad.Int(6), gad.True, gad.False})
+	expectRun(t, `ip := net.ip("10.0.0.255"); return [str(ip+1), str(ip-256), str(ip+65281)]`,
+		gad.Array{gad.Str("10.0.1.0"), gad.Str("9.255.255.255"), gad.Str("10.1.0.0")})
+	expectRun(t, `return str(net.ip("2001:db8::ffff") + 1)`, gad.Str("2001:db8::1:0"))
+	expectRun(t, `a := net.ip("10.0.0.1"); b := net.ip("10.0.0.2"); return [a < b, a >= b, a == "10.0.0.1", a == b]`,
+		gad.Array{gad.True, gad.False, gad.True, gad.False})
+	expectRun(t, `return typeName(net.ip("10.0.0.1"))`, gad.Str("ip"))
+
+	expectErr(t, `net.ip("10.0.0.256")`, gad.ErrUnexpectedArgValue)
+	expectErr(t, `net.ip("255.255.255.255") + 1`, gad.ErrIndexOutOfBounds)
+	expectErr(t, `net.ip("10.0.0.1").mac`, gad.ErrInvalidIndex)
+}
+
+func TestCIDR(t *testing.T) {
+	expectRun(t, `return str(net.cidr("10.1.2.3/8"))`, gad.Str("10.0.0.0/8"))
+	expectRun(t, `c := net.cidr("10.0.0.0/8"); return [str(c.network), str(c.last), c.bits, c.version]`,
+		gad.Array{gad.Str("10.0.0.0"), gad.Str("10.255.255.255"), gad.Int(8), gad.Int(4)})
+	expectRun(t, `c := net.cidr("10.0.0.0/8")
+		return [c.contains("10.2.3.4"), c.contains(net.ip("11.0.0.1")), c.contains(net.cidr("10.1.0.0/16")),
+			c.contains(net.cidr("0.0.0.0/0")), c.contains("::1")]`,
+		gad.Array{gad.True, gad.False, gad.True, gad.False, gad.False})
+	expectRun(t, `c := net.cidr("fd00::/64"); return [c.contains("fd00::1"), c.contains("fd01::1"), str(c.last)]`,
+		gad.Array{gad.True, gad.False, gad.Str("fd00::ffff:ffff:ffff:ffff")})
+
+	expectRun(t, `r := []; for i, ip in net.cidr("192.168.1.0/30") { r = append(r, [i, str(ip)]) }; return r`,
+		gad.Array{gad.Array{gad.Int(0), gad.Str("192.168.1.1")}, gad.Array{gad.Int(1), gad.Str("192.168.1.2")}})
+	expectRun(t, `r := []; for ip in net.cidr("10.0.0.0/31") { r = append(r, str(ip)) }; return r`,
+		gad.Array{gad.Str("10.0.0.0"), gad.Str("10.0.0.1")})
+	expectRun(t, `r := []; for ip in net.cidr("10.0.0.7/32").hosts() { r = append(r, str(ip)) }; return r`,
+		gad.Array{gad.Str("10.0.0.7")})
+	// hosts are iterated lazily
+	expectRun(t, `r := []; for ip in net.cidr("2001:db8::/32") { r = append(r, str(ip)); if len(r) == 2 { break } }; return r`,
+		gad.Array{gad.Str("2001:db8::"), gad.Str("2001:db8::1")})
+	expectRun(t, `return collect(map(take(net.cidr("10.0.0.0/8").hosts(), 2), func(ip, _) { return str(ip) }))`,
+		gad.Array{gad.Str("10.0.0.1"), gad.Str("10.0.0.2")})
+
+	expectErr(t, `net.cidr("10.0.0.0/33")`, gad.ErrUnexpectedArgValue)
+	expectErr(t, `net.cidr("10.0.0.0/8").contains(1)`, gad.ErrType)
+}
@@ -7,6 +7,7 @@ import (
 
 	"github.com/gad-lang/gad"
 	"github.com/gad-lang/gad/helper"
+	"github.com/gad-lang/gad/stdlib/vfs"
 	cmdu "github.com/unapu-go/cmd-utils"
 )
 
@@ -103,7 +104,11 @@ func Exec(c gad.Call) (o gad.Object, err error) {
 	return
 }
 
-func Exists(c gad.Call) (o gad.Object, err error) {
+func Exists(c gad.Call) (gad.Object, error) {
+	return exists(vfs.OS, c)
+}
+
+func exists(fsys vfs.FS, c gad.Call) (o gad.Object, err error) {
 	pth := &gad.Arg{
 		Name:          "path",
 		TypeAssertion: gad.TypeAssertionFromTypes(gad.TStr),
@@ -112,7 +117,7 @@ func Exists(c gad.Call) (o gad.Object, err error) {
 		return
 	}
 
-	if _, err = os.Stat(pth.Value.ToString()); err != nil {
+	if _, err = fsys.Stat(pth.Value.ToString()); err != nil {
 		if os.IsNotExist(err) {
 			return gad.False, nil
 		}
@@ -121,7 +126,11 @@ func Exists(c gad.Call) (o gad.Object, err error) {
 	return gad.True, nil
 }
 
-func CreateFile(c gad.Call) (o gad.Object, err error) {
+func CreateFile(c gad.Call) (gad.Object, error) {
+	return createFile(vfs.OS, c)
+}
+
+func createFile(fsys vfs.FS, c gad.Call) (o gad.Object, err error) {
 	var (
 		pth = &gad.Arg{
 			Name:          "path",
@@ -142,7 +151,7 @@ func CreateFile(c gad.Call) (o gad.Object, err error) {
 			Value:         gad.No,
 		}
 
-		f *os.File
+		f vfs.File
 	)
 
 	if err = c.Args.Destructure(pth); err != nil {
@@ -152,11 +161,11 @@ func CreateFile(c gad.Call) (o gad.Object, err error) {
 		return
 	}
 
-	if mode := perm.Value.(gad.Int); mode > 0 {
-		f, err = os.OpenFile(pth.Value.ToString(), os.O_RDWR|os.O_CREATE|os.O_TRUNC, os.FileMode(mode))
-	} else {
-		f, err = os.Create(pth.Value.ToString())
+	mode := os.FileMode(0666)
+	if v := perm.Value.(gad.Int); v > 0 {
+		mode = os.FileMode(v)
 	}
+	f, err = fsys.OpenFile(pth.Value.ToString(), os.O_RDWR|os.O_CREATE|os.O_TRUNC, mode)
 
 	if err != nil {
 		return
@@ -180,7 +189,11 @@ func CreateFile(c gad.Call) (o gad.Object, err error) {
 	return
 }
 
-func OpenFile(c gad.Call) (o gad.Object, err error) {
+func OpenFile(c gad.Call) (gad.Object, error) {
+	return openFile(vfs.OS, c)
+}
+
+func openFile(fsys vfs.FS, c gad.Call) (o gad.Object, err error) {
 	var (
 		pth = &gad.Arg{
 			Name:          "path",
@@ -199,7 +212,7 @@ func OpenFile(c gad.Call) (o gad.Object, err error) {
 			Value:         gad.Int(0),
 		}
 
-		f *os.File
+		f vfs.File
 	)
 
 	if err = c.Args.Destructure(pth); err != nil {
@@ -209,7 +222,7 @@ func OpenFile(c gad.Call) (o gad.Object, err error) {
 		return
 	}
 
-	f, err = os.OpenFile(pth.Value.ToString(), int(flag.Value.(FileFlag)), os.FileMode(perm.Value.(gad.Int)))
+	f, err = fsys.OpenFile(pth.Value.ToString(), int(flag.Value.(FileFlag)), os.FileMode(perm.Value.(gad.Int)))
 
 	if err != nil {
 		return
@@ -220,14 +233,18 @@ func OpenFile(c gad.Call) (o gad.Object, err error) {
 	return
 }
 
-func ReadFile(c gad.Call) (o gad.Object, err error) {
+func ReadFile(c gad.Call) (gad.Object, error) {
+	return readFile(vfs.OS, c)
+}
+
+func readFile(fsys vfs.FS, c gad.Call) (o gad.Object, err error) {
 	var (
 		pth = &gad.Arg{
 			Name:          "path",
 			TypeAssertion: gad.TypeAssertionFromTypes(gad.TStr),
 		}
 
-		f    *os.File
+		f    vfs.File
 		data []byte
 	)
 
@@ -235,7 +252,7 @@ func ReadFile(c gad.Call) (o gad.Object, err error) {
 		return
 	}
 
-	if f, err = os.Open(pth.Value.ToString()); err != nil {
+	if f, err = fsys.OpenFile(pth.Value.ToString(), os.O_RDONLY, 0); err != nil {
 		return
 	}
 
@@ -6,11 +6,16 @@ import (
 	"reflect"
 
 	"github.com/gad-lang/gad"
+	"github.com/gad-lang/gad/stdlib/vfs"
 	cmdu "github.com/unapu-go/cmd-utils"
 )
 
-var (
-	Module = gad.Dict{
+// Module is the os module using the filesystem of the operating system.
+var Module = NewModule(vfs.OS)
+
+// NewModule returns the os module whose file operations use fsys.
+func NewModule(fsys vfs.FS) gad.Dict {
+	return gad.Dict{
 		"FileFlag":     TFileFlag,
 		"pwd":          gad.MustNewReflectValue(os.Getwd),
 		"uid":          gad.MustNewReflectValue(os.Getuid),
@@ -23,30 +28,38 @@ var (
 		"getGroupByID": gad.MustNewReflectValue(user.LookupGroupId),
 		"Cmd":          gad.NewReflectType(reflect.TypeOf(cmdu.CmdBuilder{})),
 		"env":          gad.MustNewReflectValue(cmdu.OsEnv),
-		"mkdir":        gad.MustNewReflectValue(os.Mkdir),
-		"mkdirAll":     gad.MustNewReflectValue(os.MkdirAll),
-		"rm":           gad.MustNewReflectValue(os.Remove),
-		"rmAll":        gad.MustNewReflectValue(os.RemoveAll),
-		"stat":         gad.MustNewReflectValue(os.Stat),
+		"mkdir":        gad.MustNewReflectValue(fsys.Mkdir),
+		"mkdirAll":     gad.MustNewReflectValue(fsys.MkdirAll),
+		"rm":           gad.MustNewReflectValue(fsys.Remove),
+		"rmAll":        gad.MustNewReflectValue(fsys.RemoveAll),
+		"stat":         gad.MustNewReflectValue(fsys.Stat),
 		"exec": &gad.Function{
 			Name:  "exec",
 			Value: Exec,
 		},
 		"exists": &gad.Function{
-			Name:  "exists",
-			Value: Exists,
+			Name: "exists",
+			Value: func(c gad.Call) (gad.Object, error) {
+				return exists(fsys, c)
+			},
 		},
 		"createFile": &gad.Function{
-			Name:  "createFile",
-			Value: CreateFile,
+			Name: "createFile",
+			Value: func(c gad.Call) (gad.Object, error) {
+				return createFile(fsys, c)
+			},
 		},
 		"openFile": &gad.Function{
-			Name:  "openFile",
-			Value: OpenFile,
+			Name: "openFile",
+			Value: func(c gad.Call) (gad.Object, error) {
+				return openFile(fsys, c)
+			},
 		},
 		"readFile": &gad.Function{
-			Name:  "readFile",
-			Value: ReadFile,
+			Name: "readFile",
+			Value: func(c gad.Call) (gad.Object, error) {
+				return readFile(fsys, c)
+			},
 		},
 	}
-)
+}
@@ -3,8 +3,10 @@ package os
 import (
 	"reflect"
 	"testing"
+	"testing/fstest"
 
 	"github.com/gad-lang/gad"
+	"github.com/gad-lang/gad/stdlib/vfs"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -63,3 +65,15 @@ func expectRun(t *testing.T, script string, opts *gad.TestOpts, expect gad.Objec
 	script = `const os = import("os");` + script
 	gad.TestExpectRun(t, script, opts, expect)
 }
+
+func TestNewModuleFS(t *testing.T) {
+	opts := gad.NewTestOpts().Module("os", NewModule(vfs.FromFS(fstest.MapFS{
+		"etc/app.conf": {Data: []byte("debug=1")},
+	})))
+	gad.TestExpectRun(t, `os := import("os"); return [os.exists("/etc/app.conf"), os.exists("/etc/x")]`,
+		opts, gad.Array{gad.True, gad.False})
+	gad.TestExpectRun(t, `os := import("os"); return str(os.readFile("/etc/app.conf"))`,
+		opts, gad.Str("debug=1"))
+	gad.TestExpectRun(t, `os := import("os"); try { os.createFile("/x") } catch err { return str(err) }`,
+		opts, gad.Str("error: open /x: permission denied"))
+}
@@ -0,0 +1,52 @@
+package secrets
+
+import (
+	"github.com/gad-lang/gad"
+)
+
+// Module is the secrets module without a store, so get throws
+// SecretNotFoundError for all names.
+var Module = NewModule(nil)
+
+// NewModule returns the secrets module getting the secrets from s.
+func NewModule(s Store) gad.Dict {
+	return gad.Dict{
+		// gad:doc
+		// # secrets module
+		//
+		// ## Functions
+		// get(name str) -> Secret
+		// Returns the secret of the name from the store of the host. It throws
+		// SecretNotFoundError if the secret does not exist.
+		"get": &gad.Function{
+			Name: "get",
+			Value: func(c gad.Call) (_ gad.Object, err error) {
+				name := &gad.Arg{
+					Name:          "name",
+					TypeAssertion: gad.TypeAssertionFromTypes(gad.TStr),
+				}
+				if err = c.Args.Destructure(name); err != nil {
+					return
+				}
+				var (
+					value string
+					ok    bool
+				)
+				if s != nil {
+					if value, ok, err = s.Get(name.Value.ToString()); err != nil {
+						return
+					}
+				}
+				if !ok {
+					return nil, ErrNotFound.NewError(name.Value.ToString())
+				}
+				return New(name.Value.ToString(), value), nil
+			},
+		},
+		"Secret": TSecret,
+		// gad:doc
+		// ## Errors
+		// SecretNotFoundError is thrown by get if the secret does not exist.
+		"SecretNotFoundError": ErrNotFound,
+	}
+}
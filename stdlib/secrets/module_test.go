@@ -0,0 +1,63 @@
+package secrets_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/gad-lang/gad"
+	gadfmt "github.com/gad-lang/gad/stdlib/fmt"
+	gadjson "github.com/gad-lang/gad/stdlib/json"
+	"github.com/gad-lang/gad/stdlib/secrets"
+)
+
+var store = secrets.Static{"token": "s3cr3t", "empty": ""}
+
+func expectRun(t *testing.T, script string, expected gad.Object) {
+	t.Helper()
+	opts := gad.NewTestOpts().
+		Module("secrets", secrets.NewModule(store)).
+		Module("fmt", gadfmt.Module).
+		Module("json", gadjson.Module)
+	gad.TestExpectRun(t, `secrets := import("secrets"); fmt := import("fmt"); json := import("json");`+script,
+		opts, expected)
+}
+
+func expectErr(t *testing.T, s secrets.Store, script string, expected error) {
+	t.Helper()
+	gad.TestExpectRunErr(t, `secrets := import("secrets");`+script, gad.NewTestOpts().Module("secrets", secrets.NewModule(s)), expected)
+}
+
+func TestRedaction(t *testing.T) {
+	expectRun(t, `s := secrets.get("token")
+	return [str(s), repr(s), "Bearer " + s, fmt.Sprintf("%v %s", s, s), str(json.Marshal({token: s})),
+		s.name, s.expose(), typeName(s)]`,
+		gad.Array{gad.Str("***"), gad.Str("‹Secret token ***›"), gad.Str("Bearer ***"), gad.Str("*** ***"),
+			gad.Str(`{"token":"***"}`), gad.Str("token"), gad.Str("s3cr3t"), gad.Str("Secret")})
+
+	expectRun(t, `s := secrets.Secret("abc")
+	try { throw error(s) } catch err { return [str(err), repr(s), s.name] }`,
+		gad.Array{gad.Str("error: ***"), gad.Str("‹Secret ***›"), gad.Str("")})
+
+	s := secrets.New("token", "s3cr3t")
+	require.Equal(t, "*** *** ***", fmt.Sprintf("%v %s %q", s, s, s))
+}
+
+func TestEqual(t *testing.T) {
+	expectRun(t, `s := secrets.get("token")
+	return [s == secrets.Secret("s3cr3t"), s == secrets.Secret("x"), s == "s3cr3t", bool(s), bool(secrets.get("empty"))]`,
+		gad.Array{gad.True, gad.False, gad.False, gad.True, gad.False})
+}
+
+func TestErrors(t *testing.T) {
+	expectErr(t, store, `secrets.get("missing")`, secrets.ErrNotFound)
+	expectErr(t, nil, `secrets.get("token")`, secrets.ErrNotFound)
+	expectErr(t, store, `try { secrets.get("missing") } catch err { if isError(err, secrets.SecretNotFoundError) { throw err } }`,
+		secrets.ErrNotFound)
+	errStore := errors.New("store down")
+	expectErr(t, secrets.StoreFunc(func(string) (string, bool, error) { return "", false, errStore }),
+		`secrets.get("token")`, errStore)
+	expectErr(t, store, `secrets.Secret(1)`, gad.ErrType)
+}
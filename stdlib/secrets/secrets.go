@@ -0,0 +1,201 @@
+// Package secrets provides secrets module for Gad script language. Scripts
+// get the secrets from a Store injected by the host as Secret objects which
+// are redacted when they are printed, logged, encoded or traced, and reveal
+// their values only explicitly, so they do not leak into script output.
+package secrets
+
+import (
+	"crypto/subtle"
+	"fmt"
+
+	"github.com/gad-lang/gad"
+)
+
+// Redacted is the string of Secret objects.
+const Redacted = "***"
+
+// ErrNotFound is returned if a secret does not exist.
+var ErrNotFound = &gad.Error{Name: "SecretNotFoundError"}
+
+// Store is a store of secrets like environment variables or a vault.
+type Store interface {
+	// Get returns the value of the secret, ok is false if it does not exist.
+	Get(name string) (value string, ok bool, err error)
+}
+
+// StoreFunc is a function implementing Store interface.
+type StoreFunc func(name string) (value string, ok bool, err error)
+
+// Get implements Store interface.
+func (f StoreFunc) Get(name string) (string, bool, error) {
+	return f(name)
+}
+
+// Static is a Store of the secrets by the names.
+type Static map[string]string
+
+// Get implements Store interface.
+func (s Static) Get(name string) (value string, ok bool, _ error) {
+	value, ok = s[name]
+	return
+}
+
+// gad:doc
+// ## Types
+// ### Secret
+//
+// Go Type
+//
+// ```go
+// // Secret is a secret value which is redacted as *** unless it is exposed.
+// type Secret struct {
+//   gad.ObjectImpl
+//   Name string
+// }
+// ```
+//
+// Calling Secret(value str, name="") returns a secret of a value like a
+// token received at run time.
+
+// TSecret is the type of Secret objects, calling it returns a new Secret.
+var TSecret = &gad.BuiltinObjType{
+	NameValue: "Secret",
+	Value:     newSecret,
+}
+
+// Secret is a secret value which is redacted as *** unless it is exposed.
+type Secret struct {
+	gad.ObjectImpl
+	Name  string
+	value string
+}
+
+var (
+	_ gad.IndexGetter      = (*Secret)(nil)
+	_ gad.NameCallerObject = (*Secret)(nil)
+	_ gad.Representer      = (*Secret)(nil)
+	_ gad.Transferer       = (*Secret)(nil)
+	_ fmt.Formatter        = (*Secret)(nil)
+)
+
+// New returns a new Secret of the value, name describes it in Repr.
+func New(name, value string) *Secret {
+	return &Secret{Name: name, value: value}
+}
+
+// Expose returns the value of the secret.
+func (s *Secret) Expose() string {
+	return s.value
+}
+
+func (*Secret) Type() gad.ObjectType {
+	return TSecret
+}
+
+// ToString implements gad.Object interface. It returns Redacted.
+func (s *Secret) ToString() string {
+	return Redacted
+}
+
+// Repr implements gad.Representer interface. The value is redacted.
+func (s *Secret) Repr(*gad.VM) (string, error) {
+	if s.Name == "" {
+		return gad.ReprQuote("Secret " + Redacted), nil
+	}
+	return gad.ReprQuote("Secret " + s.Name + " " + Redacted), nil
+}
+
+// Format implements fmt.Formatter interface, so the value is redacted in Go
+// logs too.
+func (s *Secret) Format(f fmt.State, _ rune) {
+	_, _ = f.Write([]byte(Redacted))
+}
+
+// MarshalText implements encoding.TextMarshaler interface. It returns
+// Redacted.
+func (s *Secret) MarshalText() ([]byte, error) {
+	return []byte(Redacted), nil
+}
+
+// ToInterface implements gad.ToIterfaceConverter interface. It returns
+// Redacted, so Go functions do not receive the value.
+func (s *Secret) ToInterface() any {
+	return Redacted
+}
+
+// IsFalsy implements gad.Object interface.
+func (s *Secret) IsFalsy() bool {
+	return s.value == ""
+}
+
+// Equal implements gad.Object interface. Secrets are compared in constant
+// time.
+func (s *Secret) Equal(right gad.Object) bool {
+	v, ok := right.(*Secret)
+	return ok && subtle.ConstantTimeCompare([]byte(v.value), []byte(s.value)) == 1
+}
+
+// Transfer implements gad.Transferer interface. Secrets are immutable, so it
+// returns s.
+func (s *Secret) Transfer() (gad.Object, error) {
+	return s, nil
+}
+
+// gad:doc
+// #### Secret Getters
+//
+// | Selector  | Return Type                                          |
+// |:----------|:-----------------------------------------------------|
+// |.name      | str                                                  |
+//
+// #### Secret Methods
+//
+// | Method     | Return Type                                          |
+// |:-----------|:-----------------------------------------------------|
+// |.expose()   | str                                                  |
+//
+// Secrets are printed as *** by str, repr, print functions, json and the
+// error traces. expose returns the value. Secrets are equal if their values
+// are equal.
+
+// IndexGet implements gad.IndexGetter interface.
+func (s *Secret) IndexGet(_ *gad.VM, index gad.Object) (gad.Object, error) {
+	switch index.ToString() {
+	case "name":
+		return gad.Str(s.Name), nil
+	}
+	return nil, gad.ErrInvalidIndex.NewError(index.ToString())
+}
+
+// CallName implements gad.NameCallerObject interface.
+func (s *Secret) CallName(name string, c gad.Call) (_ gad.Object, err error) {
+	switch name {
+	case "expose":
+		if err = c.Args.CheckLen(0); err != nil {
+			return
+		}
+		return gad.Str(s.value), nil
+	}
+	return nil, gad.ErrInvalidIndex.NewError(name)
+}
+
+func newSecret(c gad.Call) (_ gad.Object, err error) {
+	var (
+		value = &gad.Arg{
+			Name:          "value",
+			TypeAssertion: gad.TypeAssertionFromTypes(gad.TStr),
+		}
+		name = &gad.NamedArgVar{
+			Name:          "name",
+			Value:         gad.Str(""),
+			TypeAssertion: gad.TypeAssertionFromTypes(gad.TStr),
+		}
+	)
+	if err = c.Args.Destructure(value); err != nil {
+		return
+	}
+	if err = c.NamedArgs.Get(name); err != nil {
+		return
+	}
+	return New(name.Value.ToString(), value.Value.ToString()), nil
+}
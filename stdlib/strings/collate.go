@@ -0,0 +1,412 @@
+package strings
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/gad-lang/gad"
+)
+
+// Collator compares the strings in the order of a language for the lists
+// shown to the users. Letters are compared ignoring their accents and cases
+// unless the strings differ only by them, then the accents and the lower
+// cases are ordered first. It covers the Latin letters of the European
+// languages, the letters of the other scripts are ordered by their code
+// points.
+type Collator struct {
+	gad.ObjectImpl
+	// Locale is the BCP 47 tag of the language like "sv" or "de-AT", its
+	// letters like "å" in Swedish which are ordered as separate letters are
+	// tailored.
+	Locale string
+	// Numeric compares the digits by the values of the numbers, so "a2" is
+	// before "a10".
+	Numeric bool
+	tailor  map[rune]int32
+}
+
+// NewCollator returns a new Collator for the locale.
+func NewCollator(locale string, numeric bool) *Collator {
+	lang, _, _ := strings.Cut(strings.ToLower(strings.ReplaceAll(locale, "_", "-")), "-")
+	return &Collator{Locale: locale, Numeric: numeric, tailor: tailorings[lang]}
+}
+
+// letter is a letter whose accent or ligature is collated at the secondary
+// level.
+type letter struct {
+	base   string
+	accent uint8
+}
+
+var letters = func() map[rune]letter {
+	m := map[rune]letter{
+		'ß': {base: "ss", accent: 1},
+		'æ': {base: "ae", accent: 1},
+		'œ': {base: "oe", accent: 1},
+		'þ': {base: "th", accent: 1},
+		'ð': {base: "d", accent: 1},
+	}
+	for base, accented := range map[rune]string{
+		'a': "àáâãäåāăą",
+		'c': "çćĉċč",
+		'd': "ďđ",
+		'e': "èéêëēĕėęě",
+		'g': "ĝğġģ",
+		'h': "ĥħ",
+		'i': "ìíîïĩīĭįı",
+		'j': "ĵ",
+		'k': "ķ",
+		'l': "ĺļľŀł",
+		'n': "ñńņňŉ",
+		'o': "òóôõöøōŏő",
+		'r': "ŕŗř",
+		's': "śŝşšș",
+		't': "ţťŧț",
+		'u': "ùúûüũūŭůűų",
+		'w': "ŵ",
+		'y': "ýÿŷ",
+		'z': "źżž",
+	} {
+		i := 0
+		for _, r := range accented {
+			i++
+			m[r] = letter{base: string(base), accent: uint8(i)}
+		}
+	}
+	return m
+}()
+
+// after returns the primary weight of a letter ordered after the base letter.
+func after(base rune, n int32) int32 {
+	return int32(base)<<4 + n
+}
+
+// tailorings are the primary weights of the letters which are separate
+// letters in the languages.
+var tailorings = map[string]map[rune]int32{
+	"sv": {'å': after('z', 1), 'ä': after('z', 2), 'æ': after('z', 2), 'ö': after('z', 3), 'ø': after('z', 3)},
+	"fi": {'å': after('z', 1), 'ä': after('z', 2), 'æ': after('z', 2), 'ö': after('z', 3), 'ø': after('z', 3)},
+	"da": {'æ': after('z', 1), 'ä': after('z', 1), 'ø': after('z', 2), 'ö': after('z', 2), 'å': after('z', 3)},
+	"nb": {'æ': after('z', 1), 'ä': after('z', 1), 'ø': after('z', 2), 'ö': after('z', 2), 'å': after('z', 3)},
+	"nn": {'æ': after('z', 1), 'ä': after('z', 1), 'ø': after('z', 2), 'ö': after('z', 2), 'å': after('z', 3)},
+	"no": {'æ': after('z', 1), 'ä': after('z', 1), 'ø': after('z', 2), 'ö': after('z', 2), 'å': after('z', 3)},
+	"es": {'ñ': after('n', 1)},
+	"pl": {
+		'ą': after('a', 1), 'ć': after('c', 1), 'ę': after('e', 1), 'ł': after('l', 1),
+		'ń': after('n', 1), 'ó': after('o', 1), 'ś': after('s', 1), 'ź': after('z', 1),
+		'ż': after('z', 2),
+	},
+	"cs": {'č': after('c', 1), 'ř': after('r', 1), 'š': after('s', 1), 'ž': after('z', 1)},
+	"sk": {'ä': after('a', 1), 'č': after('c', 1), 'ô': after('o', 1), 'š': after('s', 1), 'ž': after('z', 1)},
+	"tr": {
+		'ç': after('c', 1), 'ğ': after('g', 1), 'ı': after('i', -1), 'ö': after('o', 1),
+		'ş': after('s', 1), 'ü': after('u', 1),
+	},
+	"et": {'š': after('s', 1), 'ž': after('s', 2), 'õ': after('w', 1), 'ä': after('w', 2), 'ö': after('w', 3), 'ü': after('w', 4)},
+}
+
+// the classes of the collation elements in their order
+const (
+	classSpace int32 = iota
+	classPunct
+	classDigit
+	classLetter
+)
+
+// element is a collation element.
+type element struct {
+	class   int32
+	primary int32
+	// num are the digits without the leading zeros of a number if Numeric
+	// is set
+	num       string
+	secondary uint8
+	tertiary  uint8
+}
+
+func (c *Collator) elements(s string) (elems []element) {
+	for i := 0; i < len(s); {
+		r, n := utf8.DecodeRuneInString(s[i:])
+		if c.Numeric && r >= '0' && r <= '9' {
+			j := i
+			for j < len(s) && s[j] >= '0' && s[j] <= '9' {
+				j++
+			}
+			num := strings.TrimLeft(s[i:j], "0")
+			elems = append(elems, element{class: classDigit, num: num, secondary: uint8(j - i - len(num))})
+			i = j
+			continue
+		}
+		i += n
+
+		var (
+			lower = unicode.ToLower(r)
+			e     = element{primary: lower}
+		)
+		if lower != r {
+			e.tertiary = 1
+		}
+		switch {
+		case unicode.IsSpace(r):
+			e.class = classSpace
+		case unicode.IsDigit(r):
+			e.class = classDigit
+		case unicode.IsLetter(r):
+			e.class = classLetter
+		default:
+			e.class = classPunct
+		}
+		if e.class != classLetter {
+			elems = append(elems, e)
+			continue
+		}
+		if w, ok := c.tailor[lower]; ok {
+			e.primary = w
+			elems = append(elems, e)
+			continue
+		}
+		if l, ok := letters[lower]; ok {
+			for k, b := range l.base {
+				e := element{class: classLetter, primary: after(b, 0), tertiary: e.tertiary}
+				if k == 0 {
+					e.secondary = l.accent
+				}
+				elems = append(elems, e)
+			}
+			continue
+		}
+		e.primary = after(lower, 0)
+		elems = append(elems, e)
+	}
+	return
+}
+
+func comparePrimary(a, b element) int {
+	switch {
+	case a.class != b.class:
+		return cmpInt32(a.class, b.class)
+	case a.num != "" || b.num != "":
+		if len(a.num) != len(b.num) {
+			return cmpInt32(int32(len(a.num)), int32(len(b.num)))
+		}
+		return strings.Compare(a.num, b.num)
+	}
+	return cmpInt32(a.primary, b.primary)
+}
+
+func cmpInt32(a, b int32) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// Compare returns -1, 0 or 1 if a is before, equal to or after b. The
+// strings differing only by their accents or cases are ordered, and the
+// strings equal by the collation are compared by their bytes, so 0 is
+// returned only if they are equal.
+func (c *Collator) Compare(a, b string) int {
+	ea, eb := c.elements(a), c.elements(b)
+	levels := []func(a, b element) int{
+		comparePrimary,
+		func(a, b element) int { return cmpInt32(int32(a.secondary), int32(b.secondary)) },
+		func(a, b element) int { return cmpInt32(int32(a.tertiary), int32(b.tertiary)) },
+	}
+	for _, cmp := range levels {
+		for i := 0; i < len(ea) && i < len(eb); i++ {
+			if r := cmp(ea[i], eb[i]); r != 0 {
+				return r
+			}
+		}
+		if len(ea) != len(eb) {
+			return cmpInt32(int32(len(ea)), int32(len(eb)))
+		}
+	}
+	return strings.Compare(a, b)
+}
+
+// gad:doc
+// ## Types
+// ### Collator
+//
+// Go Type
+//
+// ```go
+// // Collator compares the strings in the order of a language.
+// type Collator struct {
+//   gad.ObjectImpl
+//   Locale  string
+//   Numeric bool
+// }
+// ```
+//
+// Calling Collator(; locale="", numeric=false) returns a collator.
+
+// TCollator is the type of Collator objects, calling it returns a new
+// Collator.
+var TCollator = &gad.BuiltinObjType{
+	NameValue: "Collator",
+	Value:     newCollatorFunc,
+}
+
+var (
+	_ gad.IndexGetter      = (*Collator)(nil)
+	_ gad.NameCallerObject = (*Collator)(nil)
+	_ gad.CallerObject     = (*Collator)(nil)
+)
+
+func (*Collator) Type() gad.ObjectType {
+	return TCollator
+}
+
+// ToString implements gad.Object interface.
+func (c *Collator) ToString() string {
+	s := "Collator(locale=" + gad.Str(c.Locale).Quoted()
+	if c.Numeric {
+		s += ", numeric"
+	}
+	return s + ")"
+}
+
+// IsFalsy implements gad.Object interface.
+func (c *Collator) IsFalsy() bool {
+	return false
+}
+
+// Equal implements gad.Object interface.
+func (c *Collator) Equal(right gad.Object) bool {
+	v, ok := right.(*Collator)
+	return ok && v.Locale == c.Locale && v.Numeric == c.Numeric
+}
+
+// gad:doc
+// #### Collator Getters
+//
+// | Selector  | Return Type                                          |
+// |:----------|:-----------------------------------------------------|
+// |.locale    | str                                                  |
+// |.numeric   | bool                                                 |
+//
+// #### Collator Methods
+//
+// | Method              | Return Type                                 |
+// |:--------------------|:--------------------------------------------|
+// |.compare(a, b)       | int                                         |
+// |.equal(a, b)         | bool                                        |
+//
+// compare returns -1, 0 or 1 like Compare. equal reports whether a and b are
+// equal ignoring their accents and cases. Calling a collator with a and b
+// reports whether a is before b, so it is the less function of sort, e.g.
+// sort(names; less=strings.Collator(locale="sv")).
+
+// IndexGet implements gad.IndexGetter interface.
+func (c *Collator) IndexGet(_ *gad.VM, index gad.Object) (gad.Object, error) {
+	switch index.ToString() {
+	case "locale":
+		return gad.Str(c.Locale), nil
+	case "numeric":
+		return gad.Bool(c.Numeric), nil
+	}
+	return nil, gad.ErrInvalidIndex.NewError(index.ToString())
+}
+
+// Call implements gad.CallerObject interface. It reports whether the first
+// argument is before the second one.
+func (c *Collator) Call(call gad.Call) (_ gad.Object, err error) {
+	if err = call.Args.CheckLen(2); err != nil {
+		return
+	}
+	return gad.Bool(c.Compare(call.Args.GetOnly(0).ToString(), call.Args.GetOnly(1).ToString()) < 0), nil
+}
+
+// CallName implements gad.NameCallerObject interface.
+func (c *Collator) CallName(name string, call gad.Call) (_ gad.Object, err error) {
+	switch name {
+	case "compare", "equal":
+		var (
+			a = &gad.Arg{
+				Name:          "a",
+				TypeAssertion: gad.TypeAssertionFromTypes(gad.TStr),
+			}
+			b = &gad.Arg{
+				Name:          "b",
+				TypeAssertion: gad.TypeAssertionFromTypes(gad.TStr),
+			}
+		)
+		if err = call.Args.Destructure(a, b); err != nil {
+			return
+		}
+		if name == "equal" {
+			return gad.Bool(c.Equivalent(a.Value.ToString(), b.Value.ToString())), nil
+		}
+		return gad.Int(c.Compare(a.Value.ToString(), b.Value.ToString())), nil
+	}
+	return nil, gad.ErrInvalidIndex.NewError(name)
+}
+
+// Equivalent reports whether a and b are equal ignoring their accents and
+// cases.
+func (c *Collator) Equivalent(a, b string) bool {
+	ea, eb := c.elements(a), c.elements(b)
+	if len(ea) != len(eb) {
+		return false
+	}
+	for i := range ea {
+		if comparePrimary(ea[i], eb[i]) != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// collatorArgs returns the collator of the named arguments locale and numeric.
+func collatorArgs(c gad.Call) (_ *Collator, err error) {
+	var (
+		locale = &gad.NamedArgVar{
+			Name:          "locale",
+			Value:         gad.Str(""),
+			TypeAssertion: gad.TypeAssertionFromTypes(gad.TStr),
+		}
+		numeric = &gad.NamedArgVar{
+			Name:          "numeric",
+			Value:         gad.False,
+			TypeAssertion: gad.TypeAssertionFromTypes(gad.TBool),
+		}
+	)
+	if err = c.NamedArgs.Get(locale, numeric); err != nil {
+		return
+	}
+	return NewCollator(locale.Value.ToString(), !numeric.Value.IsFalsy()), nil
+}
+
+func newCollatorFunc(c gad.Call) (_ gad.Object, err error) {
+	if err = c.Args.CheckLen(0); err != nil {
+		return
+	}
+	return collatorArgs(c)
+}
+
+func compareFunc(c gad.Call) (_ gad.Object, err error) {
+	var (
+		a = &gad.Arg{
+			Name:          "s1",
+			TypeAssertion: gad.TypeAssertionFromTypes(gad.TStr),
+		}
+		b = &gad.Arg{
+			Name:          "s2",
+			TypeAssertion: gad.TypeAssertionFromTypes(gad.TStr),
+		}
+		col *Collator
+	)
+	if err = c.Args.Destructure(a, b); err != nil {
+		return
+	}
+	if col, err = collatorArgs(c); err != nil {
+		return
+	}
+	return gad.Int(col.Compare(a.Value.ToString(), b.Value.ToString())), nil
+}
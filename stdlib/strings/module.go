@@ -27,6 +27,16 @@ var Module = map[string]gad.Object{
 	// # strings module
 	//
 	// ## Functions
+	// Compare(s1 string, s2 string; locale="", numeric=false) -> int
+	// Returns -1, 0 or 1 if s1 is before, equal to or after s2 in the order of
+	// the language of locale like "sv" or "de". Accents and cases are ignored
+	// unless the strings differ only by them. If numeric is true, the digits
+	// are compared by the values of the numbers, so "a2" is before "a10".
+	"Compare": &gad.Function{
+		Name:  "Compare",
+		Value: compareFunc,
+	},
+	// gad:doc
 	// Contains(s string, substr string) -> bool
 	// Reports whether substr is within s.
 	"Contains": &gad.Function{
@@ -530,6 +540,7 @@ var Module = map[string]gad.Object{
 			return truncFunc(s, limit, emph.Value.ToString()), nil
 		},
 	},
+	"Collator": TCollator,
 }
 
 func containsFunc(s, substr string) gad.Object {
@@ -732,6 +732,62 @@ func TestScript(t *testing.T) {
 	}
 }
 
+func TestCollator(t *testing.T) {
+	ret := func(s string) string {
+		return fmt.Sprintf(`
+		strings := import("strings")
+		return %s`, s)
+	}
+	arr := func(s ...string) Array {
+		a := make(Array, len(s))
+		for i, v := range s {
+			a[i] = Str(v)
+		}
+		return a
+	}
+	testCases := []struct {
+		s string
+		e Object
+	}{
+		{s: `strings.Compare("a", "b")`, e: Int(-1)},
+		{s: `strings.Compare("b", "a")`, e: Int(1)},
+		{s: `strings.Compare("a", "a")`, e: Int(0)},
+		{s: `strings.Compare("é", "f")`, e: Int(-1)},
+		{s: `strings.Compare("e", "é")`, e: Int(-1)},
+		{s: `strings.Compare("a", "A")`, e: Int(-1)},
+		{s: `strings.Compare("Ab", "ac")`, e: Int(-1)},
+		{s: `strings.Compare("ö", "z")`, e: Int(-1)},
+		{s: `strings.Compare("ö", "z"; locale="sv")`, e: Int(1)},
+		{s: `strings.Compare("ö", "z"; locale="de_DE")`, e: Int(-1)},
+		{s: `strings.Compare("ñ", "nz"; locale="es")`, e: Int(1)},
+		{s: `strings.Compare("ñ", "nz")`, e: Int(-1)},
+		{s: `strings.Compare("straße", "strasse")`, e: Int(1)},
+		{s: `strings.Compare("straße", "strast")`, e: Int(-1)},
+		{s: `strings.Compare("a10", "a2")`, e: Int(-1)},
+		{s: `strings.Compare("a10", "a2"; numeric=true)`, e: Int(1)},
+		{s: `strings.Compare("a02", "a2"; numeric=true)`, e: Int(1)},
+		{s: `sort(["Zoë", "zebra", "Ärger", "apple", "Öl"]; less=strings.Collator())`,
+			e: arr("apple", "Ärger", "Öl", "zebra", "Zoë")},
+		{s: `sort(["Zoë", "zebra", "Ärger", "apple", "Öl"]; less=strings.Collator(locale="sv"))`,
+			e: arr("apple", "zebra", "Zoë", "Ärger", "Öl")},
+		{s: `sort(["file10", "file2", "file1"]; less=strings.Collator(numeric=true))`,
+			e: arr("file1", "file2", "file10")},
+		{s: `strings.Collator(locale="pl").compare("łza", "lz")`, e: Int(1)},
+		{s: `strings.Collator().compare("łza", "lz")`, e: Int(1)},
+		{s: `strings.Collator().equal("Résumé", "resume")`, e: True},
+		{s: `strings.Collator().equal("Résumé", "resumes")`, e: False},
+		{s: `strings.Collator(locale="sv", numeric=true).locale`, e: Str("sv")},
+		{s: `strings.Collator(locale="sv", numeric=true).numeric`, e: True},
+		{s: `str(strings.Collator(locale="sv", numeric=true))`, e: Str(`Collator(locale="sv", numeric)`)},
+		{s: `typeName(strings.Collator())`, e: Str("Collator")},
+	}
+	for _, tt := range testCases {
+		t.Run(tt.s, func(t *testing.T) {
+			expectRun(t, ret(tt.s), tt.e)
+		})
+	}
+}
+
 func expectRun(t *testing.T, script string, expected Object) {
 	t.Helper()
 	mm := NewModuleMap()
@@ -0,0 +1,197 @@
+package term
+
+import (
+	"io"
+
+	"github.com/gad-lang/gad"
+)
+
+// Module represents term module.
+var Module = gad.Dict{
+	// gad:doc
+	// # term module
+	//
+	// ## Styles
+	// The ANSI escape codes of the text styles to concatenate with the strings.
+	// The style is ended by reset.
+	//
+	// reset
+	// bold
+	// dim
+	// italic
+	// underline
+	// blink
+	// reverse
+	// strike
+	"reset":     gad.Str(Reset),
+	"bold":      gad.Str(Bold),
+	"dim":       gad.Str(Dim),
+	"italic":    gad.Str(Italic),
+	"underline": gad.Str(Underline),
+	"blink":     gad.Str(Blink),
+	"reverse":   gad.Str(Reverse),
+	"strike":    gad.Str(Strike),
+	// gad:doc
+	// ## Colors
+	// The ANSI escape codes of the foreground colors and the background colors
+	// prefixed by bg.
+	//
+	// black, bgBlack
+	// red, bgRed
+	// green, bgGreen
+	// yellow, bgYellow
+	// blue, bgBlue
+	// magenta, bgMagenta
+	// cyan, bgCyan
+	// white, bgWhite
+	// gray, bgGray
+	"black":     gad.Str(Black),
+	"red":       gad.Str(Red),
+	"green":     gad.Str(Green),
+	"yellow":    gad.Str(Yellow),
+	"blue":      gad.Str(Blue),
+	"magenta":   gad.Str(Magenta),
+	"cyan":      gad.Str(Cyan),
+	"white":     gad.Str(White),
+	"gray":      gad.Str(Gray),
+	"bgBlack":   gad.Str(BgBlack),
+	"bgRed":     gad.Str(BgRed),
+	"bgGreen":   gad.Str(BgGreen),
+	"bgYellow":  gad.Str(BgYellow),
+	"bgBlue":    gad.Str(BgBlue),
+	"bgMagenta": gad.Str(BgMagenta),
+	"bgCyan":    gad.Str(BgCyan),
+	"bgWhite":   gad.Str(BgWhite),
+	"bgGray":    gad.Str(BgGray),
+	// gad:doc
+	// ## Functions
+	// isTerminal() -> bool
+	// Reports whether the stdout is a terminal.
+	"isTerminal": &gad.Function{
+		Name:  "isTerminal",
+		Value: isTerminalFunc,
+	},
+	// gad:doc
+	// size() -> [cols int, rows int]
+	// Returns the number of the columns and the rows of the stdout terminal.
+	// COLUMNS and LINES environment variables are used if the stdout is not a
+	// terminal, otherwise NotTerminalError is thrown.
+	"size": &gad.Function{
+		Name:  "size",
+		Value: sizeFunc,
+	},
+	// gad:doc
+	// clear()
+	// Clears the screen and moves the cursor to the top left corner.
+	"clear": &gad.Function{
+		Name:  "clear",
+		Value: writeFunc(Clear),
+	},
+	// gad:doc
+	// clearLine()
+	// Clears the current line and moves the cursor to its start.
+	"clearLine": &gad.Function{
+		Name:  "clearLine",
+		Value: writeFunc(ClearLine),
+	},
+	// gad:doc
+	// moveTo(col int, row int)
+	// Moves the cursor to the 1-based column and row.
+	"moveTo": &gad.Function{
+		Name:  "moveTo",
+		Value: moveToFunc,
+	},
+	// gad:doc
+	// hideCursor()
+	// Hides the cursor.
+	"hideCursor": &gad.Function{
+		Name:  "hideCursor",
+		Value: writeFunc(HideCursor),
+	},
+	// gad:doc
+	// showCursor()
+	// Shows the cursor.
+	"showCursor": &gad.Function{
+		Name:  "showCursor",
+		Value: writeFunc(ShowCursor),
+	},
+	// gad:doc
+	// prompt(msg str = ""; hidden=false) -> str
+	// Writes msg to the stdout and returns the line read from the stdin. The
+	// input is not echoed on a terminal if hidden is true, e.g. for passwords.
+	// Throws an error if the stdin is closed before a line is read.
+	"prompt": &gad.Function{
+		Name:  "prompt",
+		Value: promptFunc,
+	},
+}
+
+func isTerminalFunc(c gad.Call) (gad.Object, error) {
+	if err := c.Args.CheckLen(0); err != nil {
+		return nil, err
+	}
+	return gad.Bool(IsTerminal(File(c.VM.StdOut))), nil
+}
+
+func sizeFunc(c gad.Call) (gad.Object, error) {
+	if err := c.Args.CheckLen(0); err != nil {
+		return nil, err
+	}
+	cols, rows, err := Size(File(c.VM.StdOut))
+	if err != nil {
+		return nil, err
+	}
+	return gad.Array{gad.Int(cols), gad.Int(rows)}, nil
+}
+
+// writeFunc returns a function writing the escape code s to the stdout.
+func writeFunc(s string) gad.CallableFunc {
+	return func(c gad.Call) (gad.Object, error) {
+		if err := c.Args.CheckLen(0); err != nil {
+			return nil, err
+		}
+		_, err := io.WriteString(c.VM.StdOut, s)
+		return gad.Nil, err
+	}
+}
+
+func moveToFunc(c gad.Call) (_ gad.Object, err error) {
+	var (
+		col = &gad.Arg{
+			Name:          "col",
+			TypeAssertion: gad.TypeAssertionFromTypes(gad.TInt),
+		}
+		row = &gad.Arg{
+			Name:          "row",
+			TypeAssertion: gad.TypeAssertionFromTypes(gad.TInt),
+		}
+	)
+	if err = c.Args.Destructure(col, row); err != nil {
+		return
+	}
+	_, err = io.WriteString(c.VM.StdOut, MoveTo(int(col.Value.(gad.Int)), int(row.Value.(gad.Int))))
+	return gad.Nil, err
+}
+
+func promptFunc(c gad.Call) (_ gad.Object, err error) {
+	if err = c.Args.CheckMaxLen(1); err != nil {
+		return
+	}
+	hidden := &gad.NamedArgVar{
+		Name:          "hidden",
+		TypeAssertion: gad.TypeAssertionFlag(),
+		Value:         gad.No,
+	}
+	if err = c.NamedArgs.Get(hidden); err != nil {
+		return
+	}
+	var msg string
+	if c.Args.Length() == 1 {
+		msg = c.Args.GetOnly(0).ToString()
+	}
+	line, err := Prompt(c.VM.StdIn, c.VM.StdOut, msg, !hidden.Value.IsFalsy())
+	if err != nil {
+		return nil, err
+	}
+	return gad.Str(line), nil
+}
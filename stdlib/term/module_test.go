@@ -0,0 +1,68 @@
+package term_test
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/gad-lang/gad"
+	"github.com/gad-lang/gad/stdlib/term"
+)
+
+func run(t *testing.T, script string, in io.Reader) (gad.Object, string, error) {
+	t.Helper()
+	mm := gad.NewModuleMap()
+	mm.AddBuiltinModule("term", term.Module)
+	c := gad.CompileOptions{CompilerOptions: gad.DefaultCompilerOptions}
+	c.ModuleMap = mm
+	bc, err := gad.Compile([]byte(`term := import("term");`+script), c)
+	require.NoError(t, err)
+	var out bytes.Buffer
+	ret, err := gad.NewVM(bc).RunOpts(&gad.RunOpts{StdIn: in, StdOut: &out})
+	return ret, out.String(), err
+}
+
+func TestModule(t *testing.T) {
+	ret, out, err := run(t, `return term.bold + term.red + "x" + term.reset`, nil)
+	require.NoError(t, err)
+	require.Equal(t, gad.Str("\x1b[1m\x1b[31mx\x1b[0m"), ret)
+	require.Empty(t, out)
+
+	_, out, err = run(t, `term.clear(); term.moveTo(3, 2); term.hideCursor(); term.showCursor(); term.clearLine()`, nil)
+	require.NoError(t, err)
+	require.Equal(t, "\x1b[2J\x1b[H\x1b[2;3H\x1b[?25l\x1b[?25h\r\x1b[2K", out)
+
+	ret, _, err = run(t, `return term.isTerminal()`, nil)
+	require.NoError(t, err)
+	require.Equal(t, gad.False, ret)
+}
+
+func TestSize(t *testing.T) {
+	t.Setenv("COLUMNS", "")
+	t.Setenv("LINES", "")
+	_, _, err := run(t, `return term.size()`, nil)
+	require.ErrorIs(t, err, term.ErrNotTerminal)
+
+	t.Setenv("COLUMNS", "120")
+	t.Setenv("LINES", "40")
+	ret, _, err := run(t, `cols, rows := term.size(); return [cols, rows]`, nil)
+	require.NoError(t, err)
+	require.Equal(t, gad.Array{gad.Int(120), gad.Int(40)}, ret)
+}
+
+func TestPrompt(t *testing.T) {
+	in := strings.NewReader("alice\r\nsecret\nrest")
+	ret, out, err := run(t, `return [term.prompt("user: "), term.prompt("pass: "; hidden), term.prompt()]`, in)
+	require.NoError(t, err)
+	require.Equal(t, gad.Array{gad.Str("alice"), gad.Str("secret"), gad.Str("rest")}, ret)
+	require.Equal(t, "user: pass: ", out)
+
+	_, _, err = run(t, `return term.prompt("x")`, strings.NewReader(""))
+	require.ErrorIs(t, err, io.EOF)
+
+	_, _, err = run(t, `return term.prompt("x"; quiet)`, strings.NewReader(""))
+	require.Error(t, err)
+}
@@ -0,0 +1,10 @@
+//go:build !linux && !darwin && !freebsd && !netbsd && !openbsd
+// +build !linux,!darwin,!freebsd,!netbsd,!openbsd
+
+package term
+
+import "os"
+
+func size(*os.File) (cols, rows int, err error) {
+	return 0, 0, ErrNotTerminal
+}
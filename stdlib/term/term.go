@@ -0,0 +1,148 @@
+// Package term provides term module for Gad script language to style the
+// output with ANSI escape codes, move the cursor, get the size of the terminal
+// and prompt the user. Its Go functions are shared with the gad command.
+package term
+
+import (
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/gad-lang/gad"
+	"github.com/peterh/liner"
+)
+
+// ANSI escape codes of the text styles and the colors.
+const (
+	Reset     = "\x1b[0m"
+	Bold      = "\x1b[1m"
+	Dim       = "\x1b[2m"
+	Italic    = "\x1b[3m"
+	Underline = "\x1b[4m"
+	Blink     = "\x1b[5m"
+	Reverse   = "\x1b[7m"
+	Strike    = "\x1b[9m"
+
+	Black   = "\x1b[30m"
+	Red     = "\x1b[31m"
+	Green   = "\x1b[32m"
+	Yellow  = "\x1b[33m"
+	Blue    = "\x1b[34m"
+	Magenta = "\x1b[35m"
+	Cyan    = "\x1b[36m"
+	White   = "\x1b[37m"
+	Gray    = "\x1b[90m"
+
+	BgBlack   = "\x1b[40m"
+	BgRed     = "\x1b[41m"
+	BgGreen   = "\x1b[42m"
+	BgYellow  = "\x1b[43m"
+	BgBlue    = "\x1b[44m"
+	BgMagenta = "\x1b[45m"
+	BgCyan    = "\x1b[46m"
+	BgWhite   = "\x1b[47m"
+	BgGray    = "\x1b[100m"
+)
+
+// ANSI escape codes of the screen and the cursor.
+const (
+	Clear      = "\x1b[2J\x1b[H"
+	ClearLine  = "\r\x1b[2K"
+	HideCursor = "\x1b[?25l"
+	ShowCursor = "\x1b[?25h"
+)
+
+// ErrNotTerminal is returned if a terminal is required.
+var ErrNotTerminal = &gad.Error{Name: "NotTerminalError", Message: "not a terminal"}
+
+// MoveTo returns the escape code moving the cursor to the 1-based column and
+// row.
+func MoveTo(col, row int) string {
+	return "\x1b[" + strconv.Itoa(row) + ";" + strconv.Itoa(col) + "H"
+}
+
+// File returns the file which v reads from or writes to, or nil. The reader
+// and writer objects like the stdin and stdout of the VM are unwrapped.
+func File(v any) *os.File {
+	// a few levels are enough for the stack readers and writers
+	for i := 0; i < 4; i++ {
+		switch t := v.(type) {
+		case *os.File:
+			return t
+		case gad.Reader:
+			v = t.GoReader()
+		case gad.Writer:
+			v = t.GoWriter()
+		default:
+			return nil
+		}
+	}
+	return nil
+}
+
+// IsTerminal reports whether f is a terminal.
+func IsTerminal(f *os.File) bool {
+	if f == nil {
+		return false
+	}
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
+
+// Size returns the number of the columns and the rows of the terminal f. If f
+// is not a terminal, COLUMNS and LINES environment variables are used if set,
+// otherwise ErrNotTerminal is returned.
+func Size(f *os.File) (cols, rows int, err error) {
+	if IsTerminal(f) {
+		if cols, rows, err = size(f); err == nil {
+			return
+		}
+	}
+	cols, _ = strconv.Atoi(os.Getenv("COLUMNS"))
+	rows, _ = strconv.Atoi(os.Getenv("LINES"))
+	if cols <= 0 || rows <= 0 {
+		return 0, 0, ErrNotTerminal
+	}
+	return cols, rows, nil
+}
+
+// Prompt writes msg to out and returns the line read from in without the line
+// ending. If in is the stdin terminal, the line is edited by liner and the
+// input is not echoed if hidden is true. io.EOF is returned if in is closed
+// before a line is read.
+func Prompt(in io.Reader, out io.Writer, msg string, hidden bool) (string, error) {
+	if f := File(in); f == os.Stdin && IsTerminal(f) && liner.TerminalSupported() {
+		line := liner.NewLiner()
+		defer line.Close()
+		if hidden {
+			return line.PasswordPrompt(msg)
+		}
+		return line.Prompt(msg)
+	}
+
+	if _, err := io.WriteString(out, msg); err != nil {
+		return "", err
+	}
+	// reads byte by byte not to consume the input after the line
+	var (
+		b   strings.Builder
+		buf [1]byte
+	)
+	for {
+		n, err := in.Read(buf[:])
+		if n == 1 {
+			if buf[0] == '\n' {
+				break
+			}
+			b.WriteByte(buf[0])
+		}
+		if err == io.EOF && b.Len() > 0 {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+	}
+	return strings.TrimSuffix(b.String(), "\r"), nil
+}
@@ -0,0 +1,174 @@
+package textdiff
+
+import (
+	"strconv"
+
+	"github.com/gad-lang/gad"
+)
+
+// Module represents textdiff module.
+var Module = gad.Dict{
+	// gad:doc
+	// # textdiff module
+	//
+	// ## Functions
+	// unified(a str, b str; context=3, from="a", to="b") -> str
+	// Returns the unified diff of the lines of a and b with context unchanged
+	// lines around the changes, from and to are the names in the file headers.
+	// Returns an empty string if a and b are equal.
+	"unified": &gad.Function{
+		Name:  "unified",
+		Value: unifiedFunc,
+	},
+	// gad:doc
+	// hunks(a str, b str; context=3) -> array
+	// Returns the hunks of the unified diff of a and b. A hunk is a dict with
+	// fromLine, fromCount, toLine, toCount and lines keys. The lines are
+	// prefixed by " " for the context, "-" for the removed and "+" for the
+	// added lines, and keep their line endings.
+	"hunks": &gad.Function{
+		Name:  "hunks",
+		Value: hunksFunc,
+	},
+	// gad:doc
+	// apply(text str, patch str|array) -> str
+	// Returns text patched by the unified diff of a single file or the hunks.
+	// A hunk is applied at its line or at the nearest position its removed and
+	// context lines match, otherwise PatchError is thrown.
+	"apply": &gad.Function{
+		Name:  "apply",
+		Value: applyFunc,
+	},
+}
+
+func textArgs() (a, b *gad.Arg, context *gad.NamedArgVar) {
+	a = &gad.Arg{
+		Name:          "a",
+		TypeAssertion: gad.TypeAssertionFromTypes(gad.TStr),
+	}
+	b = &gad.Arg{
+		Name:          "b",
+		TypeAssertion: gad.TypeAssertionFromTypes(gad.TStr),
+	}
+	context = &gad.NamedArgVar{
+		Name:          "context",
+		Value:         gad.Int(3),
+		TypeAssertion: gad.TypeAssertionFromTypes(gad.TInt),
+	}
+	return
+}
+
+func unifiedFunc(c gad.Call) (_ gad.Object, err error) {
+	var (
+		a, b, context = textArgs()
+		from          = &gad.NamedArgVar{
+			Name:          "from",
+			Value:         gad.Str("a"),
+			TypeAssertion: gad.TypeAssertionFromTypes(gad.TStr),
+		}
+		to = &gad.NamedArgVar{
+			Name:          "to",
+			Value:         gad.Str("b"),
+			TypeAssertion: gad.TypeAssertionFromTypes(gad.TStr),
+		}
+	)
+	if err = c.Args.Destructure(a, b); err != nil {
+		return
+	}
+	if err = c.NamedArgs.Get(context, from, to); err != nil {
+		return
+	}
+	return gad.Str(Unified(a.Value.ToString(), b.Value.ToString(), from.Value.ToString(), to.Value.ToString(),
+		int(context.Value.(gad.Int)))), nil
+}
+
+func hunksFunc(c gad.Call) (_ gad.Object, err error) {
+	a, b, context := textArgs()
+	if err = c.Args.Destructure(a, b); err != nil {
+		return
+	}
+	if err = c.NamedArgs.Get(context); err != nil {
+		return
+	}
+	hunks := Hunks(a.Value.ToString(), b.Value.ToString(), int(context.Value.(gad.Int)))
+	ret := make(gad.Array, len(hunks))
+	for i, h := range hunks {
+		lines := make(gad.Array, len(h.Lines))
+		for j, l := range h.Lines {
+			lines[j] = gad.Str(l)
+		}
+		ret[i] = gad.Dict{
+			"fromLine":  gad.Int(h.FromLine),
+			"fromCount": gad.Int(h.FromCount),
+			"toLine":    gad.Int(h.ToLine),
+			"toCount":   gad.Int(h.ToCount),
+			"lines":     lines,
+		}
+	}
+	return ret, nil
+}
+
+func applyFunc(c gad.Call) (_ gad.Object, err error) {
+	var (
+		text = &gad.Arg{
+			Name:          "text",
+			TypeAssertion: gad.TypeAssertionFromTypes(gad.TStr),
+		}
+		patch = &gad.Arg{
+			Name:          "patch",
+			TypeAssertion: gad.TypeAssertionFromTypes(gad.TStr, gad.TArray),
+		}
+		hunks []Hunk
+	)
+	if err = c.Args.Destructure(text, patch); err != nil {
+		return
+	}
+	switch p := patch.Value.(type) {
+	case gad.Str:
+		if hunks, err = ParseUnified(string(p)); err != nil {
+			return
+		}
+	case gad.Array:
+		if hunks, err = toHunks(p); err != nil {
+			return
+		}
+	}
+	s, err := Apply(text.Value.ToString(), hunks)
+	if err != nil {
+		return
+	}
+	return gad.Str(s), nil
+}
+
+// toHunks converts the hunk dicts returned by hunks function.
+func toHunks(arr gad.Array) ([]Hunk, error) {
+	hunks := make([]Hunk, len(arr))
+	for i, v := range arr {
+		d, ok := v.(gad.Dict)
+		if !ok {
+			return nil, gad.NewArgumentTypeError("patch["+strconv.Itoa(i)+"]", "dict", v.Type().Name())
+		}
+		h := &hunks[i]
+		for name, dst := range map[string]*int{
+			"fromLine":  &h.FromLine,
+			"fromCount": &h.FromCount,
+			"toLine":    &h.ToLine,
+			"toCount":   &h.ToCount,
+		} {
+			n, ok := d[name].(gad.Int)
+			if !ok {
+				return nil, ErrPatch.NewError("hunk " + strconv.Itoa(i) + ": " + name + " is not an int")
+			}
+			*dst = int(n)
+		}
+		lines, ok := d["lines"].(gad.Array)
+		if !ok {
+			return nil, ErrPatch.NewError("hunk " + strconv.Itoa(i) + ": lines is not an array")
+		}
+		h.Lines = make([]string, len(lines))
+		for j, l := range lines {
+			h.Lines[j] = l.ToString()
+		}
+	}
+	return hunks, nil
+}
@@ -0,0 +1,89 @@
+package textdiff_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/gad-lang/gad"
+	"github.com/gad-lang/gad/stdlib/textdiff"
+)
+
+const (
+	textA = "a\nb\nc\nd\ne\nf\ng\nh\n"
+	textB = "a\nB\nc\nd\ne\nf\ng\nh\ni"
+	patch = "--- a\n+++ b\n" +
+		"@@ -1,3 +1,3 @@\n a\n-b\n+B\n c\n" +
+		"@@ -8 +8,2 @@\n h\n+i\n\\ No newline at end of file\n"
+)
+
+func run(t *testing.T, script string) (gad.Object, error) {
+	t.Helper()
+	mm := gad.NewModuleMap()
+	mm.AddBuiltinModule("textdiff", textdiff.Module)
+	c := gad.CompileOptions{CompilerOptions: gad.DefaultCompilerOptions}
+	c.ModuleMap = mm
+	bc, err := gad.Compile([]byte(`textdiff := import("textdiff");`+script), c)
+	require.NoError(t, err)
+	return gad.NewVM(bc).RunOpts(&gad.RunOpts{
+		Globals: gad.Dict{"a": gad.Str(textA), "b": gad.Str(textB), "patch": gad.Str(patch)},
+	})
+}
+
+func TestUnified(t *testing.T) {
+	require.Equal(t, patch, textdiff.Unified(textA, textB, "a", "b", 1))
+	require.Equal(t, "", textdiff.Unified(textA, textA, "a", "b", 3))
+	require.Equal(t, "--- a\n+++ b\n@@ -0,0 +1,2 @@\n+x\n+y\n", textdiff.Unified("", "x\ny\n", "a", "b", 3))
+	require.Equal(t, "--- a\n+++ b\n@@ -1,2 +0,0 @@\n-x\n-y\n", textdiff.Unified("x\ny\n", "", "a", "b", 3))
+
+	// hunks whose contexts overlap are merged
+	hunks := textdiff.Hunks(textA, textB, 3)
+	require.Len(t, hunks, 1)
+	require.Equal(t, textdiff.Hunk{FromLine: 1, FromCount: 8, ToLine: 1, ToCount: 9, Lines: []string{
+		" a\n", "-b\n", "+B\n", " c\n", " d\n", " e\n", " f\n", " g\n", " h\n", "+i",
+	}}, hunks[0])
+
+	ret, err := run(t, `global (a, b); return textdiff.unified(a, b; context=1)`)
+	require.NoError(t, err)
+	require.Equal(t, gad.Str(patch), ret)
+
+	ret, err = run(t, `global (a, b); return textdiff.unified(a, b; context=0, from="old", to="new")`)
+	require.NoError(t, err)
+	require.Equal(t, gad.Str("--- old\n+++ new\n@@ -2 +2 @@\n-b\n+B\n@@ -8,0 +9 @@\n+i\n\\ No newline at end of file\n"), ret)
+
+	ret, err = run(t, `global (a, b); return textdiff.hunks(a, b; context=0)`)
+	require.NoError(t, err)
+	require.Equal(t, gad.Array{
+		gad.Dict{"fromLine": gad.Int(2), "fromCount": gad.Int(1), "toLine": gad.Int(2), "toCount": gad.Int(1),
+			"lines": gad.Array{gad.Str("-b\n"), gad.Str("+B\n")}},
+		gad.Dict{"fromLine": gad.Int(8), "fromCount": gad.Int(0), "toLine": gad.Int(9), "toCount": gad.Int(1),
+			"lines": gad.Array{gad.Str("+i")}},
+	}, ret)
+}
+
+func TestApply(t *testing.T) {
+	ret, err := run(t, `global (a, patch); return textdiff.apply(a, patch)`)
+	require.NoError(t, err)
+	require.Equal(t, gad.Str(textB), ret)
+
+	ret, err = run(t, `global (a, b); return textdiff.apply(a, textdiff.hunks(a, b))`)
+	require.NoError(t, err)
+	require.Equal(t, gad.Str(textB), ret)
+
+	// hunks are applied at the nearest position they match
+	ret, err = run(t, `global patch; return textdiff.apply("x\ny\na\nb\nc\nd\ne\nf\ng\nh\n", patch)`)
+	require.NoError(t, err)
+	require.Equal(t, gad.Str("x\ny\na\nB\nc\nd\ne\nf\ng\nh\ni"), ret)
+
+	_, err = run(t, `global patch; return textdiff.apply("a\nc\n", patch)`)
+	require.ErrorIs(t, err, textdiff.ErrPatch)
+
+	_, err = run(t, `return textdiff.apply("a\n", "@@ -1,2 +1 @@\n-a\n")`)
+	require.ErrorIs(t, err, textdiff.ErrPatch)
+
+	_, err = run(t, `return textdiff.apply("a\n", "@@ -x +1 @@\n-a\n")`)
+	require.ErrorIs(t, err, textdiff.ErrPatch)
+
+	_, err = run(t, `return textdiff.apply("a\n", [{lines: []}])`)
+	require.ErrorIs(t, err, textdiff.ErrPatch)
+}
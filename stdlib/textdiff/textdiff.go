@@ -0,0 +1,367 @@
+// Package textdiff provides textdiff module for Gad script language to compare
+// texts line by line, format the differences as unified diffs and apply them.
+package textdiff
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/gad-lang/gad"
+)
+
+// ErrPatch is returned if a patch is malformed or does not apply.
+var ErrPatch = &gad.Error{Name: "PatchError"}
+
+// NoNewline is the marker of the unified diffs following a line which does not
+// end with a newline.
+const NoNewline = `\ No newline at end of file`
+
+// Hunk is a group of the changed lines and their context. FromLine and ToLine
+// are 1-based, or the number of the preceding line if the count is zero.
+// Lines are prefixed by ' ' for the context, '-' for the removed and '+' for
+// the added lines, and keep their line endings.
+type Hunk struct {
+	FromLine, FromCount int
+	ToLine, ToCount     int
+	Lines               []string
+}
+
+// Header returns the "@@ -l,s +l,s @@" header of the hunk.
+func (h Hunk) Header() string {
+	return "@@ -" + hunkRange(h.FromLine, h.FromCount) + " +" + hunkRange(h.ToLine, h.ToCount) + " @@"
+}
+
+func hunkRange(line, count int) string {
+	if count == 1 {
+		return strconv.Itoa(line)
+	}
+	return strconv.Itoa(line) + "," + strconv.Itoa(count)
+}
+
+// SplitLines splits s after the newlines.
+func SplitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	lines := strings.SplitAfter(s, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// Hunks returns the hunks of the differences between the lines of a and b with
+// context unchanged lines around the changes. Hunks whose contexts overlap are
+// merged.
+func Hunks(a, b string, context int) []Hunk {
+	if context < 0 {
+		context = 0
+	}
+	var (
+		al, bl = SplitLines(a), SplitLines(b)
+		ops    = diff(al, bl)
+		hunks  []Hunk
+	)
+	for i := 0; i < len(ops); {
+		if ops[i].kind == ' ' {
+			i++
+			continue
+		}
+		start := i - context
+		if start < 0 {
+			start = 0
+		}
+		// extends the hunk while the next change is within the contexts
+		end, equal := i, 0
+		for ; end < len(ops) && equal <= 2*context; end++ {
+			if ops[end].kind == ' ' {
+				equal++
+			} else {
+				equal = 0
+			}
+		}
+		end -= equal - context
+		if end > len(ops) {
+			end = len(ops)
+		}
+
+		h := Hunk{FromLine: ops[start].a, ToLine: ops[start].b}
+		for _, o := range ops[start:end] {
+			switch o.kind {
+			case ' ':
+				h.Lines = append(h.Lines, " "+al[o.a])
+				h.FromCount++
+				h.ToCount++
+			case '-':
+				h.Lines = append(h.Lines, "-"+al[o.a])
+				h.FromCount++
+			case '+':
+				h.Lines = append(h.Lines, "+"+bl[o.b])
+				h.ToCount++
+			}
+		}
+		if h.FromCount > 0 {
+			h.FromLine++
+		}
+		if h.ToCount > 0 {
+			h.ToLine++
+		}
+		hunks = append(hunks, h)
+		i = end
+	}
+	return hunks
+}
+
+// Unified returns the unified diff of a and b named from and to with context
+// lines around the changes. The empty string is returned if a and b are equal.
+func Unified(a, b, from, to string, context int) string {
+	hunks := Hunks(a, b, context)
+	if len(hunks) == 0 {
+		return ""
+	}
+	var s strings.Builder
+	s.WriteString("--- " + from + "\n+++ " + to + "\n")
+	for _, h := range hunks {
+		s.WriteString(h.Header() + "\n")
+		for _, l := range h.Lines {
+			s.WriteString(l)
+			if !strings.HasSuffix(l, "\n") {
+				s.WriteString("\n" + NoNewline + "\n")
+			}
+		}
+	}
+	return s.String()
+}
+
+// ParseUnified returns the hunks of the unified diff of a single file. The
+// lines before the first hunk like the file headers are ignored.
+func ParseUnified(patch string) (hunks []Hunk, err error) {
+	lines := SplitLines(patch)
+	for i := 0; i < len(lines); i++ {
+		if !strings.HasPrefix(lines[i], "@@ ") {
+			if len(hunks) > 0 && strings.HasPrefix(lines[i], "--- ") {
+				return nil, ErrPatch.NewError("multiple files in patch")
+			}
+			continue
+		}
+		var h Hunk
+		if h, err = parseHeader(strings.TrimRight(lines[i], "\r\n")); err != nil {
+			return
+		}
+		from, to := h.FromCount, h.ToCount
+		for from > 0 || to > 0 {
+			if i++; i == len(lines) {
+				return nil, ErrPatch.NewError("unexpected end of hunk " + h.Header())
+			}
+			l := lines[i]
+			if strings.HasPrefix(l, NoNewline) {
+				h.noNewline()
+				continue
+			}
+			if !strings.HasSuffix(l, "\n") {
+				l += "\n"
+			}
+			switch l[0] {
+			case ' ':
+				from--
+				to--
+			case '-':
+				from--
+			case '+':
+				to--
+			case '\n':
+				// an empty context line whose space is trimmed
+				l = " \n"
+				from--
+				to--
+			default:
+				return nil, ErrPatch.NewError("invalid line " + strconv.Quote(l) + " in hunk " + h.Header())
+			}
+			if from < 0 || to < 0 {
+				return nil, ErrPatch.NewError("line count mismatch in hunk " + h.Header())
+			}
+			h.Lines = append(h.Lines, l)
+		}
+		if i+1 < len(lines) && strings.HasPrefix(lines[i+1], NoNewline) {
+			i++
+			h.noNewline()
+		}
+		hunks = append(hunks, h)
+	}
+	return
+}
+
+// noNewline removes the newline of the last line.
+func (h *Hunk) noNewline() {
+	if last := len(h.Lines) - 1; last >= 0 {
+		h.Lines[last] = strings.TrimSuffix(h.Lines[last], "\n")
+	}
+}
+
+func parseHeader(s string) (h Hunk, err error) {
+	invalid := ErrPatch.NewError("invalid hunk header " + strconv.Quote(s))
+	fields := strings.Fields(s)
+	if len(fields) < 4 || fields[3] != "@@" || fields[1][0] != '-' || fields[2][0] != '+' {
+		return h, invalid
+	}
+	var ok bool
+	if h.FromLine, h.FromCount, ok = parseRange(fields[1][1:]); !ok {
+		return h, invalid
+	}
+	if h.ToLine, h.ToCount, ok = parseRange(fields[2][1:]); !ok {
+		return h, invalid
+	}
+	return
+}
+
+func parseRange(s string) (line, count int, ok bool) {
+	count = 1
+	var err error
+	if i := strings.IndexByte(s, ','); i >= 0 {
+		if count, err = strconv.Atoi(s[i+1:]); err != nil || count < 0 {
+			return
+		}
+		s = s[:i]
+	}
+	if line, err = strconv.Atoi(s); err != nil || line < 0 {
+		return
+	}
+	return line, count, true
+}
+
+// Apply returns text patched by the hunks. A hunk is applied at its line if
+// its removed and context lines match there, otherwise at the nearest
+// position they match after the previous hunk. ErrPatch is returned if a hunk
+// does not match.
+func Apply(text string, hunks []Hunk) (string, error) {
+	var (
+		lines = SplitLines(text)
+		out   []string
+		pos   int
+	)
+	for _, h := range hunks {
+		var old, repl []string
+		for _, l := range h.Lines {
+			if l == "" {
+				continue
+			}
+			switch l[0] {
+			case ' ':
+				old = append(old, l[1:])
+				repl = append(repl, l[1:])
+			case '-':
+				old = append(old, l[1:])
+			case '+':
+				repl = append(repl, l[1:])
+			default:
+				return "", ErrPatch.NewError("invalid line " + strconv.Quote(l) + " in hunk " + h.Header())
+			}
+		}
+		at := h.FromLine - 1
+		if h.FromCount == 0 {
+			at = h.FromLine
+		}
+		start := -1
+		for d := 0; start < 0 && (at-d >= pos || at+d+len(old) <= len(lines)); d++ {
+			if at-d >= pos && matchAt(lines, old, at-d) {
+				start = at - d
+			} else if at+d >= pos && matchAt(lines, old, at+d) {
+				start = at + d
+			}
+		}
+		if start < 0 {
+			return "", ErrPatch.NewError("hunk " + h.Header() + " does not apply")
+		}
+		out = append(append(out, lines[pos:start]...), repl...)
+		pos = start + len(old)
+	}
+	return strings.Join(append(out, lines[pos:]...), ""), nil
+}
+
+func matchAt(lines, old []string, at int) bool {
+	if at < 0 || at+len(old) > len(lines) {
+		return false
+	}
+	for i, l := range old {
+		if lines[at+i] != l {
+			return false
+		}
+	}
+	return true
+}
+
+// op is an edit of the lines, a and b are the indexes of the lines of a and b
+// before the edit.
+type op struct {
+	kind byte
+	a, b int
+}
+
+// diff returns the shortest edit script of a to b by Myers' algorithm.
+func diff(a, b []string) []op {
+	var (
+		n, m  = len(a), len(b)
+		max   = n + m
+		off   = max + 1
+		v     = make([]int, 2*max+3)
+		trace [][]int
+	)
+	// trace holds v of the diagonals -d-1..d+1 before each step d
+	for d := 0; d <= max; d++ {
+		trace = append(trace, append([]int(nil), v[off-d-1:off+d+2]...))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[off+k-1] < v[off+k+1]) {
+				x = v[off+k+1]
+			} else {
+				x = v[off+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[off+k] = x
+			if x >= n && y >= m {
+				return backtrack(trace, n, m)
+			}
+		}
+	}
+	return nil
+}
+
+func backtrack(trace [][]int, x, y int) []op {
+	var ops []op
+	for d := len(trace) - 1; d >= 0; d-- {
+		var (
+			v     = trace[d]
+			k     = x - y
+			prevK int
+		)
+		// v[i] is the diagonal i-d-1
+		if k == -d || (k != d && v[k-1+d+1] < v[k+1+d+1]) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := v[prevK+d+1]
+		prevY := prevX - prevK
+		for x > prevX && y > prevY {
+			x--
+			y--
+			ops = append(ops, op{' ', x, y})
+		}
+		if d > 0 {
+			if x == prevX {
+				ops = append(ops, op{'+', x, prevY})
+			} else {
+				ops = append(ops, op{'-', prevX, y})
+			}
+		}
+		x, y = prevX, prevY
+	}
+	for i, j := 0, len(ops)-1; i < j; i, j = i+1, j-1 {
+		ops[i], ops[j] = ops[j], ops[i]
+	}
+	return ops
+}
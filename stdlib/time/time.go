@@ -78,7 +78,10 @@ type Time struct {
 	Value time.Time
 }
 
-var _ gad.NameCallerObject = (*Time)(nil)
+var (
+	_ gad.NameCallerObject = (*Time)(nil)
+	_ gad.Transferer       = (*Time)(nil)
+)
 
 func (*Time) Type() gad.ObjectType {
 	return TimeType
@@ -102,6 +105,11 @@ func (o *Time) Equal(right gad.Object) bool {
 	return false
 }
 
+// Transfer implements gad.Transferer interface.
+func (o *Time) Transfer() (gad.Object, error) {
+	return &Time{Value: o.Value}, nil
+}
+
 // gad:doc
 // #### Overloaded time Operators
 //
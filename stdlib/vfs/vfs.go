@@ -0,0 +1,179 @@
+// Package vfs provides the filesystem abstraction used by the file operations
+// of os and filepath modules, so embedders can run scripts on an in-memory
+// filesystem or jail them into a directory.
+package vfs
+
+import (
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+)
+
+// FS is a filesystem like fs.FS which also supports writing. Names are OS
+// paths.
+type FS interface {
+	OpenFile(name string, flag int, perm fs.FileMode) (File, error)
+	Stat(name string) (fs.FileInfo, error)
+	ReadDir(name string) ([]fs.DirEntry, error)
+	Mkdir(name string, perm fs.FileMode) error
+	MkdirAll(name string, perm fs.FileMode) error
+	Remove(name string) error
+	RemoveAll(name string) error
+}
+
+// File is an open file of FS.
+type File interface {
+	fs.File
+	io.Writer
+}
+
+// OS is the FS of the operating system.
+var OS FS = osFS{}
+
+type osFS struct{}
+
+func (osFS) OpenFile(name string, flag int, perm fs.FileMode) (File, error) {
+	f, err := os.OpenFile(name, flag, perm)
+	if err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func (osFS) Stat(name string) (fs.FileInfo, error)        { return os.Stat(name) }
+func (osFS) ReadDir(name string) ([]fs.DirEntry, error)   { return os.ReadDir(name) }
+func (osFS) Mkdir(name string, perm fs.FileMode) error    { return os.Mkdir(name, perm) }
+func (osFS) MkdirAll(name string, perm fs.FileMode) error { return os.MkdirAll(name, perm) }
+func (osFS) Remove(name string) error                     { return os.Remove(name) }
+func (osFS) RemoveAll(name string) error                  { return os.RemoveAll(name) }
+
+// Dir returns an FS jailed into root directory of the OS. Absolute names are
+// resolved from root, relative names are resolved from root as working
+// directory and ".." cannot go above root. Symbolic links inside root are not
+// checked.
+func Dir(root string) FS {
+	return &dirFS{root: filepath.Clean(root)}
+}
+
+type dirFS struct {
+	root string
+}
+
+func (d *dirFS) resolve(name string) string {
+	return filepath.Join(d.root, filepath.FromSlash(path.Clean("/"+filepath.ToSlash(name))))
+}
+
+// hidePath replaces the real path in the error with the name to not expose
+// the root.
+func hidePath(err error, name string) error {
+	switch t := err.(type) {
+	case *fs.PathError:
+		t.Path = name
+	case *os.LinkError:
+		t.Old, t.New = name, name
+	}
+	return err
+}
+
+func (d *dirFS) OpenFile(name string, flag int, perm fs.FileMode) (File, error) {
+	f, err := os.OpenFile(d.resolve(name), flag, perm)
+	if err != nil {
+		return nil, hidePath(err, name)
+	}
+	return f, nil
+}
+
+func (d *dirFS) Stat(name string) (fs.FileInfo, error) {
+	fi, err := os.Stat(d.resolve(name))
+	return fi, hidePath(err, name)
+}
+
+func (d *dirFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	entries, err := os.ReadDir(d.resolve(name))
+	return entries, hidePath(err, name)
+}
+
+func (d *dirFS) Mkdir(name string, perm fs.FileMode) error {
+	return hidePath(os.Mkdir(d.resolve(name), perm), name)
+}
+
+func (d *dirFS) MkdirAll(name string, perm fs.FileMode) error {
+	return hidePath(os.MkdirAll(d.resolve(name), perm), name)
+}
+
+func (d *dirFS) Remove(name string) error {
+	return hidePath(os.Remove(d.resolve(name)), name)
+}
+
+func (d *dirFS) RemoveAll(name string) error {
+	if p := d.resolve(name); p != d.root {
+		return hidePath(os.RemoveAll(p), name)
+	}
+	return &fs.PathError{Op: "removeall", Path: name, Err: fs.ErrPermission}
+}
+
+// FromFS returns a read-only FS of fsys, e.g. an fstest.MapFS or an embed.FS.
+// Names are converted to fs.FS names by removing the leading separator.
+// Writing operations fail with fs.ErrPermission.
+func FromFS(fsys fs.FS) FS {
+	return &readOnlyFS{fsys: fsys}
+}
+
+type readOnlyFS struct {
+	fsys fs.FS
+}
+
+type readOnlyFile struct {
+	fs.File
+}
+
+func (f *readOnlyFile) Write([]byte) (int, error) {
+	return 0, fs.ErrPermission
+}
+
+func fsName(name string) string {
+	name = path.Clean("/" + filepath.ToSlash(name))
+	if name == "/" {
+		return "."
+	}
+	return name[1:]
+}
+
+func (r *readOnlyFS) OpenFile(name string, flag int, _ fs.FileMode) (File, error) {
+	if flag&(os.O_WRONLY|os.O_RDWR|os.O_CREATE|os.O_TRUNC|os.O_APPEND) != 0 {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrPermission}
+	}
+	f, err := r.fsys.Open(fsName(name))
+	if err != nil {
+		return nil, hidePath(err, name)
+	}
+	return &readOnlyFile{f}, nil
+}
+
+func (r *readOnlyFS) Stat(name string) (fs.FileInfo, error) {
+	fi, err := fs.Stat(r.fsys, fsName(name))
+	return fi, hidePath(err, name)
+}
+
+func (r *readOnlyFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	entries, err := fs.ReadDir(r.fsys, fsName(name))
+	return entries, hidePath(err, name)
+}
+
+func (r *readOnlyFS) Mkdir(name string, _ fs.FileMode) error {
+	return &fs.PathError{Op: "mkdir", Path: name, Err: fs.ErrPermission}
+}
+
+func (r *readOnlyFS) MkdirAll(name string, _ fs.FileMode) error {
+	return &fs.PathError{Op: "mkdir", Path: name, Err: fs.ErrPermission}
+}
+
+func (r *readOnlyFS) Remove(name string) error {
+	return &fs.PathError{Op: "remove", Path: name, Err: fs.ErrPermission}
+}
+
+func (r *readOnlyFS) RemoveAll(name string) error {
+	return &fs.PathError{Op: "removeall", Path: name, Err: fs.ErrPermission}
+}
@@ -0,0 +1,81 @@
+package vfs
+
+import (
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDir(t *testing.T) {
+	tmp := t.TempDir()
+	fsys := Dir(tmp)
+
+	require.NoError(t, fsys.MkdirAll("/a/b", 0o755))
+	f, err := fsys.OpenFile("../../a/b/c.txt", os.O_RDWR|os.O_CREATE, 0o644)
+	require.NoError(t, err)
+	_, err = f.Write([]byte("data"))
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	data, err := os.ReadFile(filepath.Join(tmp, "a", "b", "c.txt"))
+	require.NoError(t, err)
+	require.Equal(t, "data", string(data))
+
+	fi, err := fsys.Stat("a/b/c.txt")
+	require.NoError(t, err)
+	require.Equal(t, int64(4), fi.Size())
+
+	_, err = fsys.Stat("/x")
+	require.ErrorIs(t, err, fs.ErrNotExist)
+	require.NotContains(t, err.Error(), tmp)
+
+	require.ErrorIs(t, fsys.RemoveAll("/"), fs.ErrPermission)
+	require.NoError(t, fsys.RemoveAll("a"))
+	entries, err := fsys.ReadDir(".")
+	require.NoError(t, err)
+	require.Empty(t, entries)
+}
+
+func TestFromFS(t *testing.T) {
+	fsys := FromFS(fstest.MapFS{
+		"a/x.txt": {Data: []byte("x")},
+		"a/y.gad": {Data: []byte("y")},
+		"b/z.txt": {Data: []byte("z")},
+	})
+
+	f, err := fsys.OpenFile("/a/x.txt", os.O_RDONLY, 0)
+	require.NoError(t, err)
+	data, err := io.ReadAll(f)
+	require.NoError(t, err)
+	require.Equal(t, "x", string(data))
+	_, err = f.Write([]byte("x"))
+	require.ErrorIs(t, err, fs.ErrPermission)
+
+	_, err = fsys.OpenFile("a/x.txt", os.O_RDWR, 0)
+	require.ErrorIs(t, err, fs.ErrPermission)
+	require.ErrorIs(t, fsys.Mkdir("c", 0o755), fs.ErrPermission)
+	require.ErrorIs(t, fsys.Remove("a/x.txt"), fs.ErrPermission)
+
+	matches, err := Glob(fsys, "*/*.txt")
+	require.NoError(t, err)
+	require.Equal(t, []string{"a/x.txt", "b/z.txt"}, matches)
+
+	var walked []string
+	err = Walk(fsys, "/", func(path string, info fs.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if path == "/b" {
+			return filepath.SkipDir
+		}
+		walked = append(walked, path)
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{"/", "/a", "/a/x.txt", "/a/y.gad"}, walked)
+}
@@ -0,0 +1,120 @@
+package vfs
+
+import (
+	"io/fs"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// Walk walks the file tree of fsys rooted at root like filepath.Walk.
+func Walk(fsys FS, root string, fn filepath.WalkFunc) error {
+	info, err := fsys.Stat(root)
+	if err != nil {
+		err = fn(root, nil, err)
+	} else {
+		err = walk(fsys, root, info, fn)
+	}
+	if err == filepath.SkipDir || err == filepath.SkipAll {
+		return nil
+	}
+	return err
+}
+
+func walk(fsys FS, path string, info fs.FileInfo, fn filepath.WalkFunc) error {
+	if !info.IsDir() {
+		return fn(path, info, nil)
+	}
+
+	entries, err := fsys.ReadDir(path)
+	err1 := fn(path, info, err)
+	if err != nil || err1 != nil {
+		return err1
+	}
+
+	for _, e := range entries {
+		name := filepath.Join(path, e.Name())
+		fi, err := e.Info()
+		if err != nil {
+			if err = fn(name, fi, err); err != nil && err != filepath.SkipDir {
+				return err
+			}
+			continue
+		}
+		if err = walk(fsys, name, fi, fn); err != nil {
+			if !fi.IsDir() || err != filepath.SkipDir {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Glob returns the names of files of fsys matching pattern like
+// filepath.Glob.
+func Glob(fsys FS, pattern string) (matches []string, err error) {
+	if _, err = filepath.Match(pattern, ""); err != nil {
+		return nil, err
+	}
+	if !hasMeta(pattern) {
+		if _, err = fsys.Stat(pattern); err != nil {
+			return nil, nil
+		}
+		return []string{pattern}, nil
+	}
+
+	dir, file := filepath.Split(pattern)
+	switch dir {
+	case "":
+		dir = "."
+	case string(filepath.Separator):
+	default:
+		dir = dir[:len(dir)-1]
+	}
+
+	if !hasMeta(dir) {
+		return glob(fsys, dir, file, nil)
+	}
+	if dir == pattern {
+		return nil, filepath.ErrBadPattern
+	}
+
+	var dirs []string
+	if dirs, err = Glob(fsys, dir); err != nil {
+		return
+	}
+	for _, d := range dirs {
+		if matches, err = glob(fsys, d, file, matches); err != nil {
+			return
+		}
+	}
+	return
+}
+
+func glob(fsys FS, dir, pattern string, matches []string) ([]string, error) {
+	if fi, err := fsys.Stat(dir); err != nil || !fi.IsDir() {
+		return matches, nil
+	}
+	entries, err := fsys.ReadDir(dir)
+	if err != nil {
+		return matches, nil
+	}
+	for _, e := range entries {
+		matched, err := filepath.Match(pattern, e.Name())
+		if err != nil {
+			return matches, err
+		}
+		if matched {
+			matches = append(matches, filepath.Join(dir, e.Name()))
+		}
+	}
+	return matches, nil
+}
+
+func hasMeta(path string) bool {
+	magicChars := `*?[`
+	if runtime.GOOS != "windows" {
+		magicChars = `*?[\`
+	}
+	return strings.ContainsAny(path, magicChars)
+}
@@ -0,0 +1,192 @@
+package gad
+
+import (
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gad-lang/gad/repr"
+	"github.com/gad-lang/gad/runehelper"
+)
+
+// StableString returns the string of o whose entries of the dicts and the
+// key value arrays are sorted by the keys, so it does not depend on the
+// insertion order. If canonical is true, the values are written as the
+// literals of their types, like 1u, 1.0, 1d, 'a', "a" and bytes(1), the keys
+// are always quoted and the other objects are written by repr, so the strings
+// of the values which are not equal differ. It is used by str builtin if
+// sortKeys or canonical is given. Cycles are written as ‹↶›.
+func StableString(vm *VM, o Object, canonical bool) (string, error) {
+	w := &stableWriter{vm: vm, canonical: canonical, active: map[any]bool{}}
+	if err := w.write(o, true); err != nil {
+		return "", err
+	}
+	return w.sb.String(), nil
+}
+
+type stableWriter struct {
+	vm        *VM
+	canonical bool
+	sb        strings.Builder
+	active    map[any]bool
+}
+
+// enter reports whether the container identified by key is not being
+// written, otherwise it writes the cycle mark.
+func (w *stableWriter) enter(key any) bool {
+	if key == nil {
+		return true
+	}
+	if w.active[key] {
+		w.sb.WriteString(repr.Quote("↶"))
+		return false
+	}
+	w.active[key] = true
+	return true
+}
+
+func (w *stableWriter) leave(key any) {
+	if key != nil {
+		delete(w.active, key)
+	}
+}
+
+func (w *stableWriter) key(k string) {
+	if !w.canonical && runehelper.IsIdentifierRunes([]rune(k)) {
+		w.sb.WriteString(k)
+	} else {
+		w.sb.WriteString(strconv.Quote(k))
+	}
+}
+
+func (w *stableWriter) write(o Object, top bool) (err error) {
+	switch v := o.(type) {
+	case Array:
+		if !w.enter(v.identity()) {
+			return
+		}
+		defer w.leave(v.identity())
+		w.sb.WriteByte('[')
+		for i, e := range v {
+			if i > 0 {
+				w.sb.WriteString(", ")
+			}
+			if err = w.write(e, false); err != nil {
+				return
+			}
+		}
+		w.sb.WriteByte(']')
+	case Dict:
+		if !w.enter(v.identity()) {
+			return
+		}
+		defer w.leave(v.identity())
+		w.sb.WriteByte('{')
+		for i, k := range v.SortedKeys() {
+			if i > 0 {
+				w.sb.WriteString(", ")
+			}
+			w.key(string(k.(Str)))
+			w.sb.WriteString(": ")
+			if err = w.write(v[string(k.(Str))], false); err != nil {
+				return
+			}
+		}
+		w.sb.WriteByte('}')
+	case *SyncDict:
+		v.RLock()
+		d := v.Value.Copy().(Dict)
+		v.RUnlock()
+		if !w.enter(v) {
+			return
+		}
+		defer w.leave(v)
+		return w.write(d, false)
+	case *COWDict:
+		return w.write(v.Load(), false)
+	case KeyValueArray:
+		kvs := append(KeyValueArray{}, v...)
+		sort.SliceStable(kvs, func(i, j int) bool {
+			return kvs[i].K.ToString() < kvs[j].K.ToString()
+		})
+		w.sb.WriteString("(;")
+		for i, kv := range kvs {
+			if i > 0 {
+				w.sb.WriteString(", ")
+			}
+			if err = w.write(kv, false); err != nil {
+				return
+			}
+		}
+		w.sb.WriteByte(')')
+	case *KeyValue:
+		if k, ok := v.K.(Str); ok {
+			w.key(string(k))
+		} else if err = w.write(v.K, false); err != nil {
+			return
+		}
+		if v.V != Yes || w.canonical {
+			w.sb.WriteByte('=')
+			return w.write(v.V, false)
+		}
+	default:
+		if !w.canonical {
+			if top {
+				w.sb.WriteString(o.ToString())
+			} else {
+				w.sb.WriteString(ToCode(o))
+			}
+			return
+		}
+		var s string
+		if s, err = w.literal(o); err == nil {
+			w.sb.WriteString(s)
+		}
+	}
+	return
+}
+
+// literal returns the canonical string of o which is not a container.
+func (w *stableWriter) literal(o Object) (string, error) {
+	switch v := o.(type) {
+	case *NilType, Bool, Flag, Int:
+		return o.ToString(), nil
+	case Uint:
+		return o.ToString() + "u", nil
+	case Float:
+		f := float64(v)
+		s := strconv.FormatFloat(f, 'g', -1, 64)
+		if !math.IsInf(f, 0) && !math.IsNaN(f) && !strings.ContainsAny(s, ".e") {
+			s += ".0"
+		}
+		return s, nil
+	case Decimal:
+		return o.ToString() + "d", nil
+	case Char:
+		return strconv.QuoteRune(rune(v)), nil
+	case Str:
+		return strconv.Quote(string(v)), nil
+	case RawStr:
+		return strconv.Quote(string(v)), nil
+	case Bytes:
+		var sb strings.Builder
+		sb.WriteString("bytes(")
+		for i, b := range v {
+			if i > 0 {
+				sb.WriteString(", ")
+			}
+			sb.WriteString(strconv.Itoa(int(b)))
+		}
+		sb.WriteByte(')')
+		return sb.String(), nil
+	}
+	if w.vm == nil {
+		return repr.Quote(o.Type().Name() + ":" + o.ToString()), nil
+	}
+	s, err := Val(w.vm.Builtins.Call(BuiltinRepr, Call{VM: w.vm, Args: Args{Array{o}}}))
+	if err != nil {
+		return "", err
+	}
+	return s.ToString(), nil
+}
@@ -0,0 +1,72 @@
+package gad
+
+import (
+	"sync"
+	"unsafe"
+)
+
+const (
+	// strConcatMinLen is the minimum length of the concatenated strings to
+	// use the concat buffers. Shorter strings are concatenated as usual.
+	strConcatMinLen = 64
+	// strConcatBuffers is the number of the concat buffers of a VM.
+	strConcatBuffers = 4
+)
+
+// strConcat appends strings in place to make repeated concatenations like
+// `s += part` in loops O(n) instead of O(n²). The result of a concatenation
+// shares the memory of a buffer which is extended by the next concatenation
+// to the end of the result. Bytes in the buffers are never modified after
+// they are written, so strings sharing a buffer stay immutable.
+type strConcat struct {
+	mu   sync.Mutex
+	bufs [strConcatBuffers][]byte
+	next int
+}
+
+// concat returns a + b.
+func (c *strConcat) concat(a, b string) string {
+	n := len(a) + len(b)
+	if n < strConcatMinLen || a == "" || b == "" {
+		return a + b
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for i, buf := range c.bufs {
+		if len(buf) != len(a) || unsafe.SliceData(buf) != unsafe.StringData(a) {
+			continue
+		}
+		if n > cap(buf) {
+			grown := make([]byte, len(buf), 2*n)
+			copy(grown, buf)
+			buf = grown
+		}
+		buf = append(buf, b...)
+		c.bufs[i] = buf
+		return unsafe.String(unsafe.SliceData(buf), n)
+	}
+
+	buf := make([]byte, 0, n)
+	buf = append(append(buf, a...), b...)
+	c.bufs[c.next] = buf
+	c.next = (c.next + 1) % strConcatBuffers
+	return unsafe.String(unsafe.SliceData(buf), n)
+}
+
+// reset releases the buffers.
+func (c *strConcat) reset() {
+	c.mu.Lock()
+	c.bufs = [strConcatBuffers][]byte{}
+	c.next = 0
+	c.mu.Unlock()
+}
+
+// concatStr returns a + b using the concat buffers of the VM if vm is not nil.
+func (vm *VM) concatStr(a, b string) string {
+	if vm == nil {
+		return a + b
+	}
+	return vm.strConcat.concat(a, b)
+}
@@ -0,0 +1,38 @@
+package gad
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestStrConcat(t *testing.T) {
+	var c strConcat
+	part := strings.Repeat("x", strConcatMinLen)
+
+	s := c.concat(part, "a")
+	// the buffer is grown by the first append, then it is extended in place
+	s2 := c.concat(c.concat(s, "b"), "b")
+	s3 := c.concat(s2, "b")
+	require.True(t, sameString(s2, s3[:len(s2)]), "buffer is not extended")
+
+	// s2 is not at the end of the buffer, so a new one is used
+	s4 := c.concat(s2, "c")
+	require.Equal(t, part+"a", s)
+	require.Equal(t, part+"abb", s2)
+	require.Equal(t, part+"abbb", s3)
+	require.Equal(t, part+"abbc", s4)
+
+	for i := 0; i < 100; i++ {
+		s3 = c.concat(s3, part)
+	}
+	require.Equal(t, part+"abbb"+strings.Repeat(part, 100), s3)
+	require.Equal(t, part+"abbc", s4)
+
+	require.Equal(t, "ab", c.concat("a", "b"))
+	require.Equal(t, part, c.concat(part, ""))
+
+	c.reset()
+	require.Equal(t, part+"abbb"+strings.Repeat(part, 100), s3)
+}
@@ -7,6 +7,7 @@ package gad
 import (
 	"errors"
 	"fmt"
+	"slices"
 	"sort"
 	"strconv"
 )
@@ -72,6 +73,16 @@ type Symbol struct {
 	Assigned bool
 	Constant bool
 	Original *Symbol
+	// Type is the declared types of variable, checked on every assignment.
+	Type []*SymbolInfo
+	// value is the literal value of a constant inlined by the inline pass.
+	value Object
+	// mutated is set if the local is assigned after its definition, possibly
+	// by a closure, see escape pass.
+	mutated bool
+	// module is the name of the module if the variable is defined by an
+	// import expression, see Compiler.warnDeprecatedMember.
+	module string
 }
 
 func (s *Symbol) String() string {
@@ -94,6 +105,7 @@ type SymbolTable struct {
 	disableParams    bool
 	shadowedBuiltins []string
 	builtins         *Builtins
+	localNames       []string
 }
 
 // NewSymbolTable creates new symbol table object.
@@ -200,6 +212,7 @@ func (st *SymbolTable) defineParamsVar(names []string) error {
 		st.numDefinition++
 		st.store[param] = symbol
 		st.updateMaxDefs(symbol.Index + 1)
+		st.setLocalName(symbol.Index, param)
 		st.shadowBuiltin(param)
 	}
 	return nil
@@ -250,6 +263,35 @@ func (st *SymbolTable) Resolve(name string) (symbol *Symbol, ok bool) {
 	return
 }
 
+// constValue returns the inlinable value of the constant resolved by name or
+// nil. Unlike Resolve, it does not define free symbols.
+func (st *SymbolTable) constValue(name string) Object {
+	for ; st != nil; st = st.parent {
+		if symbol, ok := st.store[name]; ok {
+			for symbol.Original != nil {
+				symbol = symbol.Original
+			}
+			return symbol.value
+		}
+	}
+	return nil
+}
+
+// moduleOf returns the module name of the variable resolved by name if it is
+// defined by an import expression. Unlike Resolve, it does not define free
+// symbols.
+func (st *SymbolTable) moduleOf(name string) string {
+	for ; st != nil; st = st.parent {
+		if symbol, ok := st.store[name]; ok {
+			for symbol.Original != nil {
+				symbol = symbol.Original
+			}
+			return symbol.module
+		}
+	}
+	return ""
+}
+
 // DefineLocal adds a new symbol with ScopeLocal in the current scope.
 func (st *SymbolTable) DefineLocal(name string) (*Symbol, bool) {
 	symbol, ok := st.store[name]
@@ -269,6 +311,7 @@ func (st *SymbolTable) DefineLocal(name string) (*Symbol, bool) {
 	st.store[name] = symbol
 
 	st.updateMaxDefs(symbol.Index + 1)
+	st.setLocalName(symbol.Index, name)
 	st.shadowBuiltin(name)
 
 	return symbol, false
@@ -303,6 +346,28 @@ func (st *SymbolTable) updateMaxDefs(numDefs int) {
 	}
 }
 
+// setLocalName records the name of the local variable at index in the
+// function scope.
+func (st *SymbolTable) setLocalName(index int, name string) {
+	for st.block {
+		st = st.parent
+	}
+	if n := index + 1 - len(st.localNames); n > 0 {
+		st.localNames = append(st.localNames, make([]string, n)...)
+	}
+	st.localNames[index] = name
+}
+
+// LocalNames returns the names of the local variables of the function scope by
+// their indexes. Variables of different blocks may share an index, the last
+// defined one is returned.
+func (st *SymbolTable) LocalNames() []string {
+	for st.block {
+		st = st.parent
+	}
+	return slices.Clone(st.localNames)
+}
+
 // NextIndex returns the next symbol index.
 func (st *SymbolTable) NextIndex() int {
 	if st.block {
@@ -336,6 +401,25 @@ func (st *SymbolTable) DefineGlobal(name string) (*Symbol, error) {
 	return s, nil
 }
 
+// Undefine removes the symbol with given name from the current scope and
+// returns the removed symbol. Index of a removed local symbol is not reused.
+func (st *SymbolTable) Undefine(name string) (*Symbol, bool) {
+	symbol, ok := st.store[name]
+	if !ok {
+		return nil, false
+	}
+
+	delete(st.store, name)
+
+	for i, n := range st.shadowedBuiltins {
+		if n == name {
+			st.shadowedBuiltins = append(st.shadowedBuiltins[:i], st.shadowedBuiltins[i+1:]...)
+			break
+		}
+	}
+	return symbol, true
+}
+
 // DefineGlobals adds a new symbols with ScopeGlobal in the current scope.
 func (st *SymbolTable) DefineGlobals(names []string) (s []*Symbol, err error) {
 	s = make([]*Symbol, len(names))
@@ -447,7 +531,7 @@ func (st *SymbolTable) ShadowedBuiltins() []string {
 }
 
 func (st *SymbolTable) shadowBuiltin(name string) {
-	if _, ok := BuiltinsMap[name]; ok {
+	if _, ok := st.builtins.Map[name]; ok {
 		st.shadowedBuiltins = append(st.shadowedBuiltins, name)
 	}
 }
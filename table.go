@@ -0,0 +1,152 @@
+package gad
+
+import (
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// Column alignments of TableOptions.
+const (
+	AlignLeft   = "left"
+	AlignRight  = "right"
+	AlignCenter = "center"
+)
+
+// TableOptions configures WriteTable.
+type TableOptions struct {
+	// Columns are the header of the table and the keys of the dict rows.
+	// If empty, the sorted keys of the dict rows are used.
+	Columns []string
+	// Align is the alignments of the columns, missing ones are DefaultAlign.
+	Align []string
+	// DefaultAlign is the alignment of the columns missing in Align, empty
+	// means AlignLeft.
+	DefaultAlign string
+	// MaxWidth truncates the cells longer than it, zero means no limit.
+	MaxWidth int
+}
+
+// WriteTable writes the rows aligned in columns to w and returns the number of
+// bytes written. A row is a dict or an array, the cells of dict rows are the
+// values of the columns and the cells of array rows are their items. The
+// header and its underline are written if there are columns.
+func WriteTable(w io.Writer, rows Array, opts TableOptions) (int, error) {
+	columns := opts.Columns
+	if len(columns) == 0 {
+		columns = tableColumns(rows)
+	}
+
+	var (
+		cells  = make([][]string, 0, len(rows)+2)
+		widths = make([]int, len(columns))
+	)
+	if len(columns) > 0 {
+		header := make([]string, len(columns))
+		for i, c := range columns {
+			header[i] = truncateCell(c, opts.MaxWidth)
+		}
+		// the underline of the header is set after the widths are known
+		cells = append(cells, header, nil)
+	}
+	for _, row := range rows {
+		var line []string
+		switch r := row.(type) {
+		case Dict:
+			line = make([]string, len(columns))
+			for i, c := range columns {
+				if v, ok := r[c]; ok {
+					line[i] = v.ToString()
+				}
+			}
+		case Array:
+			line = make([]string, len(r))
+			for i, v := range r {
+				line[i] = v.ToString()
+			}
+		default:
+			line = []string{row.ToString()}
+		}
+		for i := range line {
+			line[i] = truncateCell(line[i], opts.MaxWidth)
+		}
+		cells = append(cells, line)
+	}
+
+	for _, line := range cells {
+		for i, s := range line {
+			if i == len(widths) {
+				widths = append(widths, 0)
+			}
+			if n := utf8.RuneCountInString(s); n > widths[i] {
+				widths[i] = n
+			}
+		}
+	}
+
+	if len(columns) > 0 {
+		under := make([]string, len(columns))
+		for i := range columns {
+			under[i] = strings.Repeat("-", widths[i])
+		}
+		cells[1] = under
+	}
+
+	var b strings.Builder
+	for _, line := range cells {
+		var l strings.Builder
+		for i, width := range widths {
+			if i > 0 {
+				l.WriteString("  ")
+			}
+			var s string
+			if i < len(line) {
+				s = line[i]
+			}
+			align := opts.DefaultAlign
+			if i < len(opts.Align) && opts.Align[i] != "" {
+				align = opts.Align[i]
+			}
+			l.WriteString(alignCell(s, width, align))
+		}
+		b.WriteString(strings.TrimRight(l.String(), " "))
+		b.WriteByte('\n')
+	}
+	return io.WriteString(w, b.String())
+}
+
+// tableColumns returns the sorted keys of the dict rows.
+func tableColumns(rows Array) []string {
+	keys := Dict{}
+	for _, row := range rows {
+		if d, ok := row.(Dict); ok {
+			for k := range d {
+				keys[k] = Nil
+			}
+		}
+	}
+	return sortedKeys(keys)
+}
+
+func truncateCell(s string, max int) string {
+	if max <= 0 || utf8.RuneCountInString(s) <= max {
+		return s
+	}
+	if max == 1 {
+		return "…"
+	}
+	return string([]rune(s)[:max-1]) + "…"
+}
+
+func alignCell(s string, width int, align string) string {
+	pad := width - utf8.RuneCountInString(s)
+	switch align {
+	case AlignRight:
+		return strings.Repeat(" ", pad) + s
+	case AlignCenter:
+		left := pad / 2
+		return strings.Repeat(" ", left) + s + strings.Repeat(" ", pad-left)
+	default:
+		return s + strings.Repeat(" ", pad)
+	}
+}
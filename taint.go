@@ -0,0 +1,509 @@
+package gad
+
+import (
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/gad-lang/gad/parser"
+	"github.com/gad-lang/gad/token"
+)
+
+// DefaultTaintSinks are the sinks of TaintPolicy if its Sinks is nil: the
+// builtins writing to stdout, the print functions of fmt module, the
+// functions of the modules reaching the network and the files and the write
+// method of blob buckets.
+var DefaultTaintSinks = []string{
+	"write", "print", "printf", "println",
+	"fmt.Print", "fmt.Printf", "fmt.Println",
+	"http", "os", "filepath", "blob", "term",
+	"bucket.write",
+}
+
+// TaintPolicy enables taint tracking if it is given as SetupOpts.Taint.
+// Values marked by Taint, like personal data given to the script, stay
+// tainted through binary operations, indexing, slicing, iteration and Go
+// function calls, and passing tainted values to the sinks which are not
+// approved throws TaintError or is logged.
+//
+// Comparison results are not tainted, so the tracking is limited to the data
+// flow.
+type TaintPolicy struct {
+	// Sinks are the functions which must not receive tainted values.
+	// Builtins are named like "print", the functions of the builtin modules
+	// like "http.post" and the methods of the Go objects like
+	// "bucket.write" by their type names. A module name like "http" denotes
+	// all functions of the module. DefaultTaintSinks are used if it is nil.
+	Sinks []string
+	// Approved are the sinks which may receive tainted values, e.g.
+	// "http.post" if the other functions of http module are sinks.
+	Approved []string
+	// Log is called when a tainted value is passed to a sink if it is not
+	// nil and the sink receives the value, otherwise TaintError is thrown.
+	Log func(v TaintViolation)
+}
+
+// TaintViolation describes a tainted value passed to a sink.
+type TaintViolation struct {
+	// Sink is the name of the sink function, or "dict key" if a tainted
+	// value is used as the key of a dict.
+	Sink string
+	Pos  parser.SourceFilePos
+}
+
+// isSink reports whether the function name is a sink which is not approved.
+func (p *TaintPolicy) isSink(name string) bool {
+	match := func(names []string) bool {
+		for _, s := range names {
+			if s == name || strings.HasPrefix(name, s+".") {
+				return true
+			}
+		}
+		return false
+	}
+	sinks := p.Sinks
+	if sinks == nil {
+		sinks = DefaultTaintSinks
+	}
+	return match(sinks) && !match(p.Approved)
+}
+
+// Tainted is a value derived from a marked input while taint tracking is
+// enabled by SetupOpts.Taint. It behaves like its value, VM unwraps the
+// tainted values before they are passed to Go functions and taints their
+// results and the arguments of the callbacks they invoke. Tainted values
+// should be given only to the VMs tracking taint.
+type Tainted struct {
+	Value Object
+}
+
+var (
+	_ IndexGetter = (*Tainted)(nil)
+	_ IndexSetter = (*Tainted)(nil)
+	_ Iterabler   = (*Tainted)(nil)
+	_ Slicer      = (*Tainted)(nil)
+)
+
+// Taint returns the tainted o. Tainted, nil and bool values are returned as
+// they are.
+func Taint(o Object) Object {
+	switch o.(type) {
+	case *Tainted, *NilType, Bool:
+		return o
+	}
+	return &Tainted{Value: o}
+}
+
+// IsTainted reports whether o is tainted or it is a container like an
+// array, a dict, a key value array, a deque or a heap containing tainted
+// values.
+func IsTainted(o Object) bool {
+	switch t := o.(type) {
+	case *Tainted:
+		return true
+	case Array:
+		for _, v := range t {
+			if IsTainted(v) {
+				return true
+			}
+		}
+	case Dict:
+		for _, v := range t {
+			if IsTainted(v) {
+				return true
+			}
+		}
+	case *SyncDict:
+		t.RLock()
+		defer t.RUnlock()
+		return IsTainted(t.Value)
+	case *KeyValue:
+		return IsTainted(t.K) || IsTainted(t.V)
+	case KeyValueArray:
+		for _, kv := range t {
+			if IsTainted(kv) {
+				return true
+			}
+		}
+	case KeyValueArrays:
+		for _, kva := range t {
+			if IsTainted(kva) {
+				return true
+			}
+		}
+	case *NamedArgs:
+		return IsTainted(t.sources)
+	case *Deque:
+		return IsTainted(t.Values())
+	case *Heap:
+		return IsTainted(t.items) || IsTainted(t.keys)
+	}
+	return false
+}
+
+// Untaint returns the value of o with the values of the tainted values in it.
+// The containers holding tainted values are copied. It reports whether o is
+// tainted.
+func Untaint(o Object) (Object, bool) {
+	if _, ok := o.(*Tainted); !ok && !IsTainted(o) {
+		return o, false
+	}
+	switch t := o.(type) {
+	case *Tainted:
+		v, _ := Untaint(t.Value)
+		return v, true
+	case Array:
+		ret := make(Array, len(t))
+		for i, v := range t {
+			ret[i], _ = Untaint(v)
+		}
+		return ret, true
+	case Dict:
+		ret := make(Dict, len(t))
+		for k, v := range t {
+			ret[k], _ = Untaint(v)
+		}
+		return ret, true
+	case *SyncDict:
+		t.RLock()
+		defer t.RUnlock()
+		v, _ := Untaint(t.Value)
+		return &SyncDict{Value: v.(Dict)}, true
+	case *KeyValue:
+		k, _ := Untaint(t.K)
+		v, _ := Untaint(t.V)
+		return &KeyValue{K: k, V: v}, true
+	case KeyValueArray:
+		ret := make(KeyValueArray, len(t))
+		for i, kv := range t {
+			v, _ := Untaint(kv)
+			ret[i] = v.(*KeyValue)
+		}
+		return ret, true
+	case KeyValueArrays:
+		ret := make(KeyValueArrays, len(t))
+		for i, kva := range t {
+			v, _ := Untaint(kva)
+			ret[i] = v.(KeyValueArray)
+		}
+		return ret, true
+	case *NamedArgs:
+		v, _ := Untaint(t.sources)
+		return NewNamedArgs(v.(KeyValueArrays)...), true
+	case *Deque:
+		v, _ := Untaint(t.Values())
+		return NewDeque(t.maxLen, v.(Array)...), true
+	case *Heap:
+		items, _ := Untaint(t.items)
+		keys, _ := Untaint(t.keys)
+		return &Heap{items: items.(Array), keys: keys.(Array), opts: t.opts}, true
+	}
+	return o, false
+}
+
+func (t *Tainted) Type() ObjectType {
+	return t.Value.Type()
+}
+
+func (t *Tainted) ToString() string {
+	return t.Value.ToString()
+}
+
+func (t *Tainted) IsFalsy() bool {
+	return t.Value.IsFalsy()
+}
+
+func (t *Tainted) Equal(right Object) bool {
+	right, _ = Untaint(right)
+	return t.Value.Equal(right)
+}
+
+// IndexGet implements IndexGetter interface. The result is tainted.
+func (t *Tainted) IndexGet(vm *VM, index Object) (Object, error) {
+	ig, ok := t.Value.(IndexGetter)
+	if !ok {
+		return nil, ErrNotIndexable.NewError(t.Value.Type().Name())
+	}
+	index, _ = Untaint(index)
+	v, err := Val(ig.IndexGet(vm, index))
+	if err != nil {
+		return nil, err
+	}
+	return Taint(v), nil
+}
+
+// IndexSet implements IndexSetter interface.
+func (t *Tainted) IndexSet(vm *VM, index, value Object) (err error) {
+	is, ok := t.Value.(IndexSetter)
+	if !ok {
+		return ErrNotIndexAssignable.NewError(t.Value.Type().Name())
+	}
+	if vm != nil && vm.taint != nil {
+		if index, err = vm.taintKey(t.Value, index); err != nil {
+			return
+		}
+	} else {
+		index, _ = Untaint(index)
+	}
+	return is.IndexSet(vm, index, value)
+}
+
+// Iterate implements Iterabler interface. The values of the iteration are
+// tainted.
+func (t *Tainted) Iterate(vm *VM, na *NamedArgs) Iterator {
+	_, it, err := ToIterator(vm, t.Value, na)
+	if err != nil || it == nil {
+		return nil
+	}
+	return &taintedIterator{Iterator: it}
+}
+
+// Length implements Slicer interface.
+func (t *Tainted) Length() int {
+	if l, ok := t.Value.(LengthGetter); ok {
+		return l.Length()
+	}
+	return 0
+}
+
+// Slice implements Slicer interface. The result is tainted.
+func (t *Tainted) Slice(low, high int) Object {
+	switch v := t.Value.(type) {
+	case Array:
+		return Taint(v[low:high])
+	case Str:
+		return Taint(v[low:high])
+	case Bytes:
+		return Taint(v[low:high])
+	case Slicer:
+		return Taint(v.Slice(low, high))
+	}
+	return t
+}
+
+// taintedIterator taints the values of Iterator.
+type taintedIterator struct {
+	Iterator
+}
+
+func (it *taintedIterator) Start(vm *VM) (state *IteratorState, err error) {
+	if state, err = it.Iterator.Start(vm); err == nil && state != nil {
+		state.Entry.V = Taint(state.Entry.V)
+	}
+	return
+}
+
+func (it *taintedIterator) Next(vm *VM, state *IteratorState) (err error) {
+	if err = it.Iterator.Next(vm, state); err == nil {
+		state.Entry.V = Taint(state.Entry.V)
+	}
+	return
+}
+
+// taintState is shared by a VM and its child VMs to resolve the sinks.
+type taintState struct {
+	mu    sync.RWMutex
+	funcs map[Object]string
+}
+
+func (vm *VM) initTaint() {
+	s := &taintState{funcs: map[Object]string{}}
+	for name, t := range vm.Builtins.Map {
+		if vm.Taint.isSink(name) {
+			objs := []Object{vm.Builtins.Objects[t]}
+			if mc, ok := objs[0].(MethodCaller); ok {
+				// VM calls the caller of the builtins without methods
+				objs = append(objs, mc.Caller())
+			}
+			for _, obj := range objs {
+				if obj != nil && reflect.TypeOf(obj).Comparable() {
+					s.funcs[obj] = name
+				}
+			}
+		}
+	}
+	vm.taint = s
+}
+
+// taintModule registers the sink functions of a builtin module.
+func (vm *VM) taintModule(module Object) {
+	d, ok := module.(Dict)
+	if !ok {
+		return
+	}
+	name, ok := d[AttrModuleName]
+	if !ok {
+		return
+	}
+
+	vm.taint.mu.Lock()
+	defer vm.taint.mu.Unlock()
+
+	for k, v := range d {
+		member := name.ToString() + "." + k
+		if !vm.Taint.isSink(member) || !Callable(v) || !reflect.TypeOf(v).Comparable() {
+			continue
+		}
+		vm.taint.funcs[v] = member
+	}
+}
+
+// taintCall unwraps the tainted arguments of the call of fn, whose name is
+// name if it is a method, and reports whether any argument is tainted. It
+// returns TaintError if fn is a sink which is not approved and Log is not
+// set.
+func (vm *VM) taintCall(fn Object, name string, c *Call) (tainted bool, err error) {
+	var args Args
+	for i, arr := range c.Args {
+		var cp Array
+		for j, v := range arr {
+			if u, ok := Untaint(v); ok {
+				if cp == nil {
+					// arrays of the arguments may be the stack of VM
+					cp = append(Array{}, arr...)
+				}
+				cp[j] = u
+			}
+		}
+		if cp != nil {
+			if args == nil {
+				args = append(Args{}, c.Args...)
+			}
+			args[i] = cp
+		}
+	}
+	if args != nil {
+		c.Args = args
+		tainted = true
+	}
+	if !c.NamedArgs.IsFalsy() {
+		var (
+			pairs KeyValueArray
+			found bool
+		)
+		c.NamedArgs.Walk(func(kv *KeyValue) error {
+			v, ok := Untaint(kv.V)
+			found = found || ok
+			pairs = append(pairs, &KeyValue{K: kv.K, V: v})
+			return nil
+		})
+		if found {
+			c.NamedArgs = *NewNamedArgs(pairs)
+			tainted = true
+		}
+	}
+	if !tainted {
+		return
+	}
+
+	if name == "" {
+		if !reflect.TypeOf(fn).Comparable() {
+			return
+		}
+		vm.taint.mu.RLock()
+		name = vm.taint.funcs[fn]
+		vm.taint.mu.RUnlock()
+		if name == "" {
+			return
+		}
+	} else if !vm.Taint.isSink(name) {
+		return
+	}
+
+	return tainted, vm.taintViolation(name, "tainted value passed to "+name)
+}
+
+// taintCallback returns the arguments of a callback invoked by a Go function
+// through Invoker. The arguments are tainted if the Go function received a
+// tainted argument, as the values of the callback may be derived from it.
+func (vm *VM) taintCallback(args Args, namedArgs *NamedArgs) (Args, *NamedArgs) {
+	if vm.taint == nil || vm.taintedCalls == 0 {
+		return args, namedArgs
+	}
+	ret := make(Args, len(args))
+	for i, arr := range args {
+		ret[i] = make(Array, len(arr))
+		for j, v := range arr {
+			ret[i][j] = Taint(v)
+		}
+	}
+	if namedArgs != nil && !namedArgs.IsFalsy() {
+		var pairs KeyValueArray
+		namedArgs.Walk(func(kv *KeyValue) error {
+			pairs = append(pairs, &KeyValue{K: kv.K, V: Taint(kv.V)})
+			return nil
+		})
+		namedArgs = NewNamedArgs(pairs)
+	}
+	return ret, namedArgs
+}
+
+// callTainted calls the Go function callee like VM calls it, so the tainted
+// arguments are unwrapped, the sinks are checked and the result is tainted.
+func (vm *VM) callTainted(callee CallerObject, c Call) (Object, error) {
+	if vm.taint == nil {
+		return Val(callee.Call(c))
+	}
+	tainted, err := vm.taintCall(callee, "", &c)
+	if err != nil {
+		return nil, err
+	}
+	if tainted {
+		vm.taintedCalls++
+		defer func() { vm.taintedCalls-- }()
+	}
+	ret, err := Val(callee.Call(c))
+	if err == nil && tainted {
+		ret = Taint(ret)
+	}
+	return ret, err
+}
+
+// taintKey returns the untainted index of target. The keys of the dicts are
+// stored as plain strings, which would drop the taint of the index, so a
+// tainted key is a violation of "dict key" sink.
+func (vm *VM) taintKey(target, index Object) (Object, error) {
+	index, tainted := Untaint(index)
+	if !tainted {
+		return index, nil
+	}
+	switch target.(type) {
+	case Dict, *SyncDict:
+		return index, vm.taintViolation("dict key", "tainted value used as dict key")
+	}
+	return index, nil
+}
+
+// taintViolation logs the violation of the sink if Log is set, otherwise it
+// returns TaintError with msg.
+func (vm *VM) taintViolation(sink, msg string) error {
+	v := TaintViolation{Sink: sink}
+	if vm.bytecode.FileSet != nil {
+		v.Pos = vm.bytecode.FileSet.Position(vm.getSourcePos())
+	}
+	if vm.Taint.Log != nil {
+		vm.Taint.Log(v)
+		return nil
+	}
+	return ErrTaint.NewError(msg)
+}
+
+// taintBinaryOp returns the tainted result of the binary operation if an
+// operand is tainted, handled is false otherwise.
+func (vm *VM) taintBinaryOp(tok token.Token, left, right Object) (ret Object, handled bool, err error) {
+	l, lt := left.(*Tainted)
+	r, rt := right.(*Tainted)
+	if !lt && !rt {
+		return
+	}
+	if lt {
+		left = l.Value
+	}
+	if rt {
+		right = r.Value
+	}
+	if ret, err = Val(vm.Builtins.Call(BuiltinBinaryOp, Call{VM: vm, Args: Args{Array{BinaryOperatorTypes[tok], left, right}}})); err != nil {
+		return nil, true, err
+	}
+	return Taint(ret), true, nil
+}
@@ -86,6 +86,7 @@ const (
 	Dec // --
 	UnaryOperatorEnd_
 	Lambda          // =>
+	RArrow          // ->
 	Not             // !
 	Null            // a == nil || nil == a
 	NotNull         // a != nil || nil != a
@@ -202,6 +203,7 @@ var tokens = [...]string{
 	Greater:            ">",
 	Assign:             "=",
 	Lambda:             "=>",
+	RArrow:             "->",
 	Not:                "!",
 	NotEqual:           "!=",
 	LessEq:             "<=",
@@ -0,0 +1,132 @@
+package gad
+
+// Transferer is implemented by the objects which can be handed to another VM
+// or goroutine by Transfer. Transfer returns a copy of the object or the
+// object itself if it is immutable.
+type Transferer interface {
+	Object
+	Transfer() (Object, error)
+}
+
+// Transfer returns a copy of the object graph o which can be safely handed to
+// another VM or goroutine. The immutable values, the builtin types and the Go
+// functions are not copied. Arrays, dicts, bytes and the numeric arrays are
+// deep copied preserving the shared and the cyclic references, sync dicts and
+// copy-on-write dicts are copied from a snapshot of their values. Objects
+// implementing Transferer interface transfer themselves.
+//
+// The values bound to a VM or to the host, like compiled functions, buffers,
+// readers, writers, iterators, host byte views, pointers, reflect proxies and
+// the instances of the script types are not transferable and ErrNotTransferable
+// is returned.
+func Transfer(o Object) (Object, error) {
+	t := &transfer{seen: map[any]Object{}}
+	return t.object(o)
+}
+
+type transfer struct {
+	seen map[any]Object
+}
+
+func (t *transfer) object(o Object) (_ Object, err error) {
+	switch v := o.(type) {
+	case nil:
+		return Nil, nil
+	case *NilType, Bool, Flag, Int, Uint, Float, Decimal, Char, Str, RawStr,
+		*Regexp, *BuiltinObjType, *BuiltinFunction, *Function:
+		return o, nil
+	case Bytes:
+		return append(Bytes{}, v...), nil
+	case IntArray:
+		return v.Copy(), nil
+	case FloatArray:
+		return v.Copy(), nil
+	case *Error:
+		return v.Copy(), nil
+	case *Tainted:
+		if o, err = t.object(v.Value); err != nil {
+			return
+		}
+		return Taint(o), nil
+	case Array:
+		if len(v) == 0 {
+			return Array{}, nil
+		}
+		key := v.identity()
+		if r, ok := t.seen[key]; ok {
+			return r, nil
+		}
+		r := make(Array, len(v))
+		t.seen[key] = r
+		for i, e := range v {
+			if r[i], err = t.object(e); err != nil {
+				return
+			}
+		}
+		return r, nil
+	case *ArrayView:
+		return t.object(v.Values())
+	case Dict:
+		if v == nil {
+			return Dict{}, nil
+		}
+		key := v.identity()
+		if r, ok := t.seen[key]; ok {
+			return r, nil
+		}
+		r := make(Dict, len(v))
+		t.seen[key] = r
+		for k, e := range v {
+			if r[k], err = t.object(e); err != nil {
+				return
+			}
+		}
+		return r, nil
+	case *SyncDict:
+		if r, ok := t.seen[v]; ok {
+			return r, nil
+		}
+		r := &SyncDict{}
+		t.seen[v] = r
+		v.RLock()
+		d := v.Value.Copy().(Dict)
+		v.RUnlock()
+		if o, err = t.object(d); err != nil {
+			return
+		}
+		r.Value = o.(Dict)
+		return r, nil
+	case *COWDict:
+		if r, ok := t.seen[v]; ok {
+			return r, nil
+		}
+		r := &COWDict{}
+		t.seen[v] = r
+		if o, err = t.object(v.Load()); err != nil {
+			return
+		}
+		r.Store(o.(Dict))
+		return r, nil
+	case *KeyValue:
+		r := &KeyValue{}
+		if r.K, err = t.object(v.K); err != nil {
+			return
+		}
+		if r.V, err = t.object(v.V); err != nil {
+			return
+		}
+		return r, nil
+	case KeyValueArray:
+		r := make(KeyValueArray, len(v))
+		for i, kv := range v {
+			if o, err = t.object(kv); err != nil {
+				return
+			}
+			r[i] = o.(*KeyValue)
+		}
+		return r, nil
+	case Transferer:
+		return v.Transfer()
+	}
+	return nil, ErrNotTransferable.NewError(o.Type().Name() + " cannot be transferred")
+}
@@ -0,0 +1,386 @@
+package gad
+
+import (
+	"fmt"
+
+	"github.com/gad-lang/gad/token"
+)
+
+// Verify checks that bc can be run safely by the VM. It should be called
+// before running Bytecode loaded from an untrusted source, since the VM
+// trusts the compiler and does not check the instructions while running. It
+// checks opcodes and their operands, jump targets, constant, local, free
+// variable, builtin and module indexes, and that no instruction of compiled
+// functions can pop values below its frame or push above the stack size. If
+// builtins is nil, default builtins are used. Returned error wraps
+// ErrInvalidBytecode.
+func (bc *Bytecode) Verify(builtins *Builtins) error {
+	if bc == nil || bc.Main == nil {
+		return ErrInvalidBytecode.NewError("main function is missing")
+	}
+	if bc.NumModules < 0 || len(bc.ModuleNames) > bc.NumModules {
+		return ErrInvalidBytecode.NewError(fmt.Sprintf("invalid number of modules %d", bc.NumModules))
+	}
+	if builtins == nil {
+		builtins = NewBuiltins()
+	}
+
+	v := &verifier{
+		bc:       bc,
+		builtins: builtins,
+		numFree:  make(map[*CompiledFunction]int),
+	}
+
+	fns := []*CompiledFunction{bc.Main}
+	for i, o := range bc.Constants {
+		if o == nil {
+			return ErrInvalidBytecode.NewError(fmt.Sprintf("constant %d is nil", i))
+		}
+		if fn := verifiableFunction(o); fn != nil {
+			fns = append(fns, fn)
+		}
+	}
+	v.loadFunction(bc.Main)
+	for _, fn := range fns {
+		if err := v.scanClosures(fn); err != nil {
+			return err
+		}
+	}
+	for _, fn := range fns {
+		if _, ok := v.numFree[fn]; !ok {
+			// function is not used by instructions
+			v.numFree[fn] = 1 << 8
+		}
+	}
+
+	for _, fn := range fns {
+		if err := v.function(fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// verifiableFunction returns the compiled function of a constant or nil.
+func verifiableFunction(o Object) *CompiledFunction {
+	switch t := o.(type) {
+	case *CompiledFunction:
+		return t
+	case *CallerObjectWithMethods:
+		fn, _ := t.CallerObject.(*CompiledFunction)
+		return fn
+	}
+	return nil
+}
+
+type verifier struct {
+	bc       *Bytecode
+	builtins *Builtins
+	// numFree is the minimum number of free variables a function is
+	// instantiated with.
+	numFree map[*CompiledFunction]int
+}
+
+func (v *verifier) errorf(fn *CompiledFunction, ip int, format string, args ...any) error {
+	name := fn.Name
+	if fn == v.bc.Main {
+		name = "main"
+	}
+	return ErrInvalidBytecode.NewError(
+		fmt.Sprintf("%s at %04d: ", name, ip) + fmt.Sprintf(format, args...))
+}
+
+// decode returns the operands of the instruction at ip.
+func (v *verifier) decode(fn *CompiledFunction, ip int, operands []int) ([]int, int, error) {
+	op := fn.Instructions[ip]
+	if int(op) >= len(OpcodeNames) || OpcodeNames[op] == "" {
+		return nil, 0, v.errorf(fn, ip, "invalid opcode %d", op)
+	}
+	width := 0
+	for _, w := range OpcodeOperands[op] {
+		width += w
+	}
+	if ip+width >= len(fn.Instructions) {
+		return nil, 0, v.errorf(fn, ip, "truncated %s instruction", OpcodeNames[op])
+	}
+	operands, offset := ReadOperands(OpcodeOperands[op], fn.Instructions[ip+1:], operands)
+	return operands, offset, nil
+}
+
+// scanClosures updates the number of free variables of the functions used by
+// fn. Closures get their free variables from OpClosure, others use Free.
+func (v *verifier) scanClosures(fn *CompiledFunction) error {
+	var (
+		operands []int
+		offset   int
+		err      error
+	)
+	for ip := 0; ip < len(fn.Instructions); ip += offset + 1 {
+		if operands, offset, err = v.decode(fn, ip, operands); err != nil {
+			return err
+		}
+		switch Opcode(fn.Instructions[ip]) {
+		case OpConstant, OpLoadModule:
+			if operands[0] < len(v.bc.Constants) {
+				if cf := verifiableFunction(v.bc.Constants[operands[0]]); cf != nil {
+					v.loadFunction(cf)
+				}
+			}
+		case OpClosure:
+			if operands[0] >= len(v.bc.Constants) {
+				return v.errorf(fn, ip, "constant index %d out of range", operands[0])
+			}
+			cf, ok := v.bc.Constants[operands[0]].(*CompiledFunction)
+			if !ok {
+				return v.errorf(fn, ip, "closure of non-function constant %d", operands[0])
+			}
+			v.setNumFree(cf, operands[1])
+		}
+	}
+	return nil
+}
+
+// loadFunction records that fn is used as is with its Free variables.
+func (v *verifier) loadFunction(fn *CompiledFunction) {
+	v.setNumFree(fn, len(fn.Free))
+}
+
+func (v *verifier) setNumFree(fn *CompiledFunction, n int) {
+	if old, ok := v.numFree[fn]; !ok || n < old {
+		v.numFree[fn] = n
+	}
+}
+
+// function verifies the instructions of fn.
+func (v *verifier) function(fn *CompiledFunction) error {
+	insts := fn.Instructions
+	if fn.NumLocals < 0 || fn.NumLocals >= stackSize ||
+		fn.NumLocals < len(fn.Params)+fn.NamedParams.len {
+		return v.errorf(fn, 0, "invalid number of locals %d", fn.NumLocals)
+	}
+	if len(insts) == 0 {
+		return v.errorf(fn, 0, "no instructions")
+	}
+
+	// first pass checks static operands and collects instruction starts
+	var (
+		starts   = make([]bool, len(insts))
+		last     int
+		operands []int
+		offset   int
+		err      error
+	)
+	for ip := 0; ip < len(insts); ip += offset + 1 {
+		starts[ip] = true
+		last = ip
+		if operands, offset, err = v.decode(fn, ip, operands); err != nil {
+			return err
+		}
+		if err = v.operands(fn, ip, Opcode(insts[ip]), operands); err != nil {
+			return err
+		}
+	}
+	switch Opcode(insts[last]) {
+	case OpReturn, OpJump:
+	default:
+		return v.errorf(fn, last, "function does not end with return")
+	}
+
+	// second pass follows the control flow to check jump targets and the
+	// stack depth of reachable instructions. Compiled code may leave values
+	// on the stack in some branches, which are dropped on return, so the
+	// range of possible depths is tracked for every instruction. The minimum
+	// is used to check that no instruction pops the values below the frame
+	// and the maximum to check that loops do not grow the stack past its size.
+	var (
+		minDepths = make([]int, len(insts))
+		maxDepths = make([]int, len(insts))
+		work      = []int{0}
+	)
+	for i := range minDepths {
+		minDepths[i] = -1
+	}
+	flow := func(from, to, lo, hi int) error {
+		if to < 0 || to >= len(insts) || !starts[to] {
+			return v.errorf(fn, from, "invalid jump target %d", to)
+		}
+		if hi+fn.NumLocals >= stackSize {
+			return v.errorf(fn, from, "stack overflow")
+		}
+		if d := minDepths[to]; d < 0 {
+			minDepths[to], maxDepths[to] = lo, hi
+		} else if lo < d || hi > maxDepths[to] {
+			minDepths[to], maxDepths[to] = min(lo, d), max(hi, maxDepths[to])
+		} else {
+			return nil
+		}
+		work = append(work, to)
+		return nil
+	}
+	minDepths[0] = 0
+
+	for len(work) > 0 {
+		ip := work[len(work)-1]
+		work = work[:len(work)-1]
+
+		op := Opcode(insts[ip])
+		operands, offset, _ = v.decode(fn, ip, operands)
+		next := ip + offset + 1
+		lo, hi := minDepths[ip], maxDepths[ip]
+
+		pop, push := stackEffect(op, operands)
+		if lo < pop {
+			return v.errorf(fn, ip, "stack underflow in %s", OpcodeNames[op])
+		}
+		afterLo, afterHi := lo-pop+push, hi-pop+push
+
+		switch op {
+		case OpReturn:
+			continue
+		case OpThrow:
+			if operands[0] == 1 {
+				continue
+			}
+		case OpJump:
+			if err = flow(ip, operands[0], lo, hi); err != nil {
+				return err
+			}
+			continue
+		case OpJumpFalsy, OpJumpNil:
+			err = flow(ip, operands[0], afterLo, afterHi)
+		case OpAndJump, OpOrJump, OpJumpNotNil:
+			// jumps keep the value on the stack
+			err = flow(ip, operands[0], lo, hi)
+		case OpIterNextElse:
+			if err = flow(ip, operands[0], afterLo, afterHi); err == nil {
+				err = flow(ip, operands[1], afterLo, afterHi)
+			}
+			if err != nil {
+				return err
+			}
+			continue
+		case OpSetupTry:
+			// error handlers restore the stack depth of OpSetupTry
+			for _, pos := range operands {
+				if pos > 0 {
+					if err = flow(ip, pos, lo, hi); err != nil {
+						return err
+					}
+				}
+			}
+		}
+		if err != nil {
+			return err
+		}
+		if next >= len(insts) {
+			return v.errorf(fn, ip, "control flows past the end of function")
+		}
+		if err = flow(ip, next, afterLo, afterHi); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// operands checks the operands of an instruction which do not depend on the
+// control flow.
+func (v *verifier) operands(fn *CompiledFunction, ip int, op Opcode, operands []int) error {
+	switch op {
+	case OpConstant, OpGetGlobal, OpSetGlobal, OpClosure:
+		if operands[0] >= len(v.bc.Constants) {
+			return v.errorf(fn, ip, "constant index %d out of range", operands[0])
+		}
+	case OpLoadModule:
+		if operands[0] >= len(v.bc.Constants) {
+			return v.errorf(fn, ip, "constant index %d out of range", operands[0])
+		}
+		if operands[1] >= v.bc.NumModules {
+			return v.errorf(fn, ip, "module index %d out of range", operands[1])
+		}
+	case OpStoreModule:
+		if operands[0] >= v.bc.NumModules {
+			return v.errorf(fn, ip, "module index %d out of range", operands[0])
+		}
+	case OpGetLocal, OpSetLocal, OpGetLocalPtr, OpDefineLocal:
+		if operands[0] >= fn.NumLocals {
+			return v.errorf(fn, ip, "local index %d out of range", operands[0])
+		}
+	case OpGetFree, OpSetFree, OpGetFreePtr:
+		if operands[0] >= v.numFree[fn] {
+			return v.errorf(fn, ip, "free variable index %d out of range", operands[0])
+		}
+	case OpGetBuiltin:
+		if v.builtins.Objects[BuiltinType(operands[0])] == nil {
+			return v.errorf(fn, ip, "unknown builtin %d", operands[0])
+		}
+	case OpBinaryOp:
+		if _, ok := BinaryOperatorTypes[token.Token(operands[0])]; !ok {
+			return v.errorf(fn, ip, "invalid binary operator %d", operands[0])
+		}
+	case OpReturn, OpThrow, OpKeyValue:
+		if operands[0] > 1 {
+			return v.errorf(fn, ip, "invalid %s operand %d", OpcodeNames[op], operands[0])
+		}
+	case OpDict:
+		if operands[0]%2 != 0 {
+			return v.errorf(fn, ip, "odd number of dict items %d", operands[0])
+		}
+	}
+	return nil
+}
+
+// stackEffect returns the number of values an instruction pops from and
+// pushes to the stack if it continues to the next instruction.
+func stackEffect(op Opcode, operands []int) (pop, push int) {
+	switch op {
+	case OpConstant, OpGetGlobal, OpGetLocal, OpGetBuiltin, OpNil, OpStdIn,
+		OpStdOut, OpStdErr, OpDotName, OpDotFile, OpIsModule, OpGetFree,
+		OpGetLocalPtr, OpGetFreePtr, OpTrue, OpFalse, OpYes, OpNo, OpCallee,
+		OpArgs, OpNamedArgs, OpSetupCatch:
+		return 0, 1
+	case OpSetGlobal, OpSetLocal, OpSetFree, OpDefineLocal, OpPop,
+		OpJumpFalsy, OpAndJump, OpOrJump, OpJumpNotNil:
+		return 1, 0
+	case OpBinaryOp, OpEqual, OpNotEqual:
+		return 2, 1
+	case OpUnary, OpIsNil, OpNotIsNil, OpIterInit, OpIterNext, OpIterKey,
+		OpIterValue, OpStoreModule, OpJumpNil, OpIterNextElse:
+		return 1, 1
+	case OpCall:
+		return operands[0] + callNamedArgs(operands[1]) + 1, 1
+	case OpCallName:
+		return operands[0] + callNamedArgs(operands[1]) + 2, 1
+	case OpDict, OpArray, OpKeyValueArray:
+		return operands[0], 1
+	case OpKeyValue:
+		return 1 + operands[0], 1
+	case OpGetIndex:
+		return operands[0] + 1, 1
+	case OpSliceIndex:
+		return 3, 1
+	case OpSetIndex:
+		return 3, 0
+	case OpClosure:
+		return operands[1], 1
+	case OpLoadModule:
+		return 0, 2
+	case OpModuleHooks:
+		return 2, 0
+	case OpReturn:
+		return operands[0], 0
+	case OpThrow:
+		return operands[0], 0
+	}
+	return 0, 0
+}
+
+// callNamedArgs returns the number of named argument values of a call.
+func callNamedArgs(flags int) (n int) {
+	if OpCallFlag(flags).Has(OpCallFlagNamedArgs) {
+		n++
+	}
+	if OpCallFlag(flags).Has(OpCallFlagVarNamedArgs) {
+		n++
+	}
+	return
+}
@@ -0,0 +1,95 @@
+package gad_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	. "github.com/gad-lang/gad"
+)
+
+func TestBytecodeVerify(t *testing.T) {
+	for _, src := range []string{
+		`return 1`,
+		`a := [1, 2]; for i, v in a { if v == 2 { break } }; return a`,
+		`f := func(x, *y; z=1) { return x ?? z }; return f(1, 2; z=3)`,
+		`x := 0; g := func() { x++; return func() { return x } }; return g()()`,
+		`try { throw "a" } catch e { return e } finally { x := 1 }`,
+		`m := import("mod"); return m.x`,
+	} {
+		opts := CompileOptions{CompilerOptions: DefaultCompilerOptions}
+		opts.ModuleMap = NewModuleMap().AddSourceModule("mod", []byte(`return {x: 1}`))
+		bc, err := Compile([]byte(src), opts)
+		require.NoError(t, err, src)
+		require.NoError(t, bc.Verify(nil), src)
+	}
+
+	verify := func(fn *CompiledFunction, constants ...Object) error {
+		return (&Bytecode{Main: fn, Constants: constants}).Verify(nil)
+	}
+	ret := makeInst(OpReturn, 0)
+
+	require.NoError(t, verify(compFunc(concatInsts(makeInst(OpConstant, 0), makeInst(OpReturn, 1))), Int(1)))
+
+	for _, tt := range []struct {
+		fn        *CompiledFunction
+		constants []Object
+		err       string
+	}{
+		{compFunc(nil), nil, "no instructions"},
+		{compFunc([]byte{255}), nil, "invalid opcode 255"},
+		{compFunc([]byte{byte(OpConstant), 0}), nil, "truncated CONSTANT instruction"},
+		{compFunc(makeInst(OpConstant, 0)), nil, "constant index 0 out of range"},
+		{compFunc(makeInst(OpConstant, 0)), []Object{Int(1)}, "does not end with return"},
+		{compFunc(concatInsts(makeInst(OpJump, 4), ret)), nil, "invalid jump target 4"},
+		{compFunc(concatInsts(makeInst(OpJump, 1), ret)), nil, "invalid jump target 1"},
+		{compFunc(concatInsts(makeInst(OpGetLocal, 0), ret)), nil, "local index 0 out of range"},
+		{compFunc(concatInsts(makeInst(OpGetFree, 0), ret)), nil, "free variable index 0 out of range"},
+		{compFunc(concatInsts(makeInst(OpLoadModule, 0, 0), ret)), []Object{Int(1)}, "module index 0 out of range"},
+		{compFunc(concatInsts(makeInst(OpGetBuiltin, 60000), ret)), nil, "unknown builtin 60000"},
+		{compFunc(concatInsts(makeInst(OpBinaryOp, 0), ret)), nil, "invalid binary operator 0"},
+		{compFunc(concatInsts(makeInst(OpPop), ret)), nil, "stack underflow in POP"},
+		{compFunc(makeInst(OpReturn, 1)), nil, "stack underflow in RETURN"},
+		{compFunc(concatInsts(makeInst(OpJumpFalsy, 3), ret)), nil, "stack underflow in JUMPFALSY"},
+		{compFunc(concatInsts(makeInst(OpNil), makeInst(OpJumpFalsy, 0), ret)), nil, ""},
+		{compFunc(concatInsts(makeInst(OpNil), makeInst(OpReturn, 0))), nil, ""},
+		{compFunc(ret, withLocals(1), withParams("a", "b")), nil, "invalid number of locals 1"},
+		{compFunc(concatInsts(makeInst(OpClosure, 0, 0), ret)), []Object{Int(1)}, "closure of non-function constant 0"},
+		{compFunc(concatInsts(makeInst(OpNil), makeInst(OpJump, 0))), nil, "stack overflow"},
+	} {
+		err := verify(tt.fn, tt.constants...)
+		if tt.err == "" {
+			require.NoError(t, err)
+			continue
+		}
+		require.ErrorIs(t, err, ErrInvalidBytecode, tt.err)
+		require.Contains(t, err.Error(), tt.err)
+	}
+
+	// free variables of closures
+	closure := compFunc(concatInsts(makeInst(OpGetFree, 0), makeInst(OpReturn, 1)))
+	require.NoError(t, verify(compFunc(concatInsts(
+		makeInst(OpNil), makeInst(OpClosure, 0, 1), makeInst(OpReturn, 1))), closure))
+	require.ErrorContains(t, verify(compFunc(concatInsts(
+		makeInst(OpConstant, 0), makeInst(OpReturn, 1))), closure), "free variable index 0 out of range")
+
+	var insts []byte
+	for i := 0; i < 3000; i++ {
+		insts = append(insts, makeInst(OpNil)...)
+	}
+	require.ErrorContains(t, verify(compFunc(concatInsts(insts, ret))), "stack overflow")
+
+	// operands of the iteration instructions are checked by VM
+	for _, op := range []Opcode{OpIterNext, OpIterKey, OpIterValue} {
+		bc := &Bytecode{Main: compFunc(concatInsts(makeInst(OpConstant, 0), makeInst(op),
+			makeInst(OpPop), makeInst(OpNil), makeInst(OpReturn, 1))), Constants: []Object{Int(1)}}
+		require.NoError(t, bc.Verify(nil))
+		_, err := NewVM(bc).Run()
+		require.ErrorIs(t, err, ErrInvalidBytecode)
+		require.ErrorContains(t, err, op.String()+" operand is int, not an iterator")
+	}
+
+	require.ErrorIs(t, (&Bytecode{}).Verify(nil), ErrInvalidBytecode)
+	require.ErrorIs(t, (&Bytecode{Main: compFunc(ret), NumModules: 1, ModuleNames: []string{"a", "b"}}).Verify(nil),
+		ErrInvalidBytecode)
+}
@@ -13,6 +13,7 @@ import (
 	"strconv"
 	"sync"
 	"sync/atomic"
+	"time"
 
 	"github.com/gad-lang/gad/parser"
 	"github.com/gad-lang/gad/parser/source"
@@ -42,6 +43,20 @@ type VM struct {
 	mu           sync.Mutex
 	err          error
 	noPanic      bool
+	suspendValue Object
+	snapshot     *Snapshot
+	safepointN   int
+	callN        int
+	auditState   *auditState
+	deprecations *deprecationState
+	taint        *taintState
+	taintedCalls int
+	cleanups     []Object
+	timers       map[*Timer]struct{}
+	budget       *budgetState
+	finalizing   *time.Timer
+	strConcat    strConcat
+	deepCopies   map[any]Object
 
 	StdOut, StdErr *StackWriter
 	StdIn          *StackReader
@@ -66,6 +81,8 @@ func NewVM(bc *Bytecode) *VM {
 
 // SetRecover recovers panic when Run panics and returns panic as an error.
 // If error handler is present `try-catch-finally`, VM continues to run from catch/finally.
+// Recovered panics are thrown as ErrPanic errors whose cause is a GoPanic
+// holding the Go stack, they can be inspected with recover builtin in catch.
 func (vm *VM) SetRecover(v bool) *VM {
 	vm.mu.Lock()
 	defer vm.mu.Unlock()
@@ -94,9 +111,84 @@ func (vm *VM) Clear() *VM {
 	vm.pool.clear()
 	vm.modulesCache = nil
 	vm.globals = nil
+	vm.strConcat.reset()
 	return vm
 }
 
+// moduleHooks calls __init__ function of the imported source module and
+// registers its __cleanup__ function to be called by Close.
+func (vm *VM) moduleHooks(initFn, cleanupFn Object) error {
+	if initFn != Nil {
+		if _, err := NewInvoker(vm, initFn).Invoke(Args{}, nil); err != nil {
+			return err
+		}
+	}
+
+	if cleanupFn != Nil {
+		root := vm.pool.root
+		root.pool.mu.Lock()
+		root.cleanups = append(root.cleanups, cleanupFn)
+		root.pool.mu.Unlock()
+	}
+	return nil
+}
+
+// Close calls the __cleanup__ functions of the imported source modules in
+// reverse import order and removes modules cache, so modules are imported and
+// initialized again on the next run.
+func (vm *VM) Close() error {
+	root := vm.pool.root
+	root.pool.mu.Lock()
+	cleanups := root.cleanups
+	root.cleanups = nil
+	root.pool.mu.Unlock()
+
+	var errs []error
+	for i := len(cleanups) - 1; i >= 0; i-- {
+		if _, err := NewInvoker(root, cleanups[i]).Invoke(Args{}, nil); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	root.mu.Lock()
+	root.modulesCache = nil
+	root.mu.Unlock()
+	return errors.Join(errs...)
+}
+
+// addTimer registers the pending timer t to the root VM to stop it when the
+// run ends.
+func (vm *VM) addTimer(t *Timer) {
+	root := vm.pool.root
+	root.pool.mu.Lock()
+	if root.timers == nil {
+		root.timers = make(map[*Timer]struct{})
+	}
+	root.timers[t] = struct{}{}
+	root.pool.mu.Unlock()
+}
+
+// removeTimer removes the timer t which is not pending anymore.
+func (vm *VM) removeTimer(t *Timer) {
+	root := vm.pool.root
+	root.pool.mu.Lock()
+	delete(root.timers, t)
+	root.pool.mu.Unlock()
+}
+
+// stopTimers stops the pending timers of the run, so their functions are not
+// called on the finished VM.
+func (vm *VM) stopTimers() {
+	vm.pool.mu.Lock()
+	timers := vm.timers
+	vm.timers = nil
+	vm.pool.mu.Unlock()
+
+	for t := range timers {
+		t.Stop()
+	}
+}
+
 // GetGlobals returns global variables.
 func (vm *VM) GetGlobals() Object {
 	return vm.globals
@@ -177,12 +269,14 @@ func (vm *VM) init(opts *RunOpts) error {
 
 	vm.initGlobals(opts.Globals)
 	vm.resetState(opts.Args, opts.NamedArgs)
+	vm.budget = newBudgetState(opts.Budget, opts.parentBudget)
 
 	return nil
 }
 
 func (vm *VM) resetState(args Args, namedArgs *NamedArgs) {
 	vm.err = nil
+	vm.snapshot = nil
 	atomic.StoreInt64(&vm.abort, 0)
 	vm.initCurrentFrame(args, namedArgs)
 	vm.frameIndex = 1
@@ -203,6 +297,10 @@ func (vm *VM) Setup(opts SetupOpts) *VM {
 
 	vm.SetupOpts = &opts
 
+	if opts.Audit != nil {
+		vm.auditState = &auditState{}
+	}
+
 	if vm.Builtins == nil {
 		vm.Builtins = NewBuiltins()
 	}
@@ -213,6 +311,14 @@ func (vm *VM) Setup(opts SetupOpts) *VM {
 
 	vm.Builtins.Objects = vm.Builtins.Objects.Build()
 
+	if opts.OnDeprecated != nil && len(opts.Deprecations) > 0 {
+		vm.initDeprecations()
+	}
+
+	if opts.Taint != nil {
+		vm.initTaint()
+	}
+
 	if vm.ObjectConverters == nil {
 		vm.ObjectConverters = NewObjectConverters()
 	}
@@ -240,10 +346,12 @@ func (vm *VM) initAndRun(opts *RunOpts) (Object, error) {
 	vm.Setup(SetupOpts{})
 
 	vm.err = nil
+	vm.snapshot = nil
 	atomic.StoreInt64(&vm.abort, 0)
 	vm.initGlobals(opts.Globals)
 	vm.initCurrentFrame(opts.Args, opts.NamedArgs)
 	vm.frameIndex = 1
+	vm.budget = newBudgetState(opts.Budget, opts.parentBudget)
 
 	if opts.StdIn != nil {
 		if s, _ := opts.StdIn.(*StackReader); s != nil {
@@ -279,6 +387,12 @@ func (vm *VM) initAndRun(opts *RunOpts) (Object, error) {
 		}
 	}
 
+	// nested VMs invoked after the run must not step its budget
+	defer func() { vm.budget = nil }()
+	if vm.pool.root == vm {
+		defer vm.stopTimers()
+	}
+
 	for run := true; run; {
 		run = vm.safeRun()
 	}
@@ -397,10 +511,213 @@ func (vm *VM) initCurrentFrame(args Args, named *NamedArgs) {
 
 	vm.curFrame.errHandlers = nil
 	vm.curFrame.basePointer = 0
+	vm.curFrame.debugLine = 0
 	vm.sp = vm.curFrame.fn.NumLocals
 	vm.ip = -1
 }
 
+// safepoint checks whether context is done and calls Safepoint callback.
+func (vm *VM) safepoint() error {
+	vm.safepointN = 0
+	select {
+	case <-vm.Context.Done():
+		return vm.Context.Err()
+	default:
+	}
+	if vm.Safepoint != nil {
+		return vm.Safepoint(vm)
+	}
+	return nil
+}
+
+// sleep pauses the caller for given duration. It returns early with an error
+// if VM is aborted or its Context is done.
+func (vm *VM) sleep(d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+	_, err := vm.wait(nil, d)
+	return err
+}
+
+// wait blocks until ch is closed or given duration elapses if it is greater
+// than zero. It returns early with an error if VM is aborted or its Context is
+// done.
+func (vm *VM) wait(ch <-chan struct{}, d time.Duration) (timedOut bool, err error) {
+	var timeout <-chan time.Time
+	if d > 0 {
+		timer := time.NewTimer(d)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	ticker := time.NewTicker(10 * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ch:
+			return false, nil
+		case <-timeout:
+			return true, nil
+		case <-vm.Context.Done():
+			return false, vm.Context.Err()
+		case <-ticker.C:
+			if vm.Aborted() {
+				return false, ErrVMAborted
+			}
+		}
+	}
+}
+
+// receive receives a value from ch, ok is false if ch is closed. It returns
+// early with an error if VM is aborted or its Context is done.
+func (vm *VM) receive(ch <-chan Object) (v Object, ok bool, err error) {
+	select {
+	case v, ok = <-ch:
+		return
+	default:
+	}
+
+	ticker := time.NewTicker(10 * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case v, ok = <-ch:
+			return
+		case <-vm.Context.Done():
+			return nil, false, vm.Context.Err()
+		case <-ticker.C:
+			if vm.Aborted() {
+				return nil, false, ErrVMAborted
+			}
+		}
+	}
+}
+
+// send sends v to ch. It returns early with an error if VM is aborted or its
+// Context is done.
+func (vm *VM) send(ch chan<- Object, v Object) error {
+	select {
+	case ch <- v:
+		return nil
+	default:
+	}
+
+	ticker := time.NewTicker(10 * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case ch <- v:
+			return nil
+		case <-vm.Context.Done():
+			return vm.Context.Err()
+		case <-ticker.C:
+			if vm.Aborted() {
+				return ErrVMAborted
+			}
+		}
+	}
+}
+
+// finalize starts graceful finalization of aborted VM if it is enabled and any
+// frame has a pending finally block. It returns false if VM must stop.
+func (vm *VM) finalize() bool {
+	if vm.AbortGracePeriod <= 0 || vm.finalizing != nil || !vm.hasFinally() {
+		return false
+	}
+
+	atomic.StoreInt64(&vm.abort, 0)
+	vm.finalizing = time.AfterFunc(vm.AbortGracePeriod, vm.Abort)
+
+	if err := vm.throw(vm.newError(ErrVMAborted), false); err != nil {
+		return false
+	}
+	return true
+}
+
+// endFinalize ends graceful finalization if it is started and sets
+// ErrVMAborted as the result of run.
+func (vm *VM) endFinalize() {
+	if vm.finalizing == nil {
+		return
+	}
+	vm.finalizing.Stop()
+	vm.finalizing = nil
+	vm.err = ErrVMAborted
+}
+
+func (vm *VM) hasFinally() bool {
+	for i := vm.frameIndex - 1; i >= 0; i-- {
+		if h := vm.frames[i].errHandlers; h != nil {
+			for _, handler := range h.handlers {
+				if handler.finally > 0 {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// runFrameDefers runs deferred functions of frames except current one which
+// are not unwound due to abort.
+func (vm *VM) runFrameDefers() {
+	for i := vm.frameIndex - 2; i >= 0; i-- {
+		f := &vm.frames[i]
+		for _, fn := range f.defers {
+			fn()
+		}
+		f.defers = nil
+	}
+}
+
+// sampleCall reports whether the current call is reported to OnCall.
+func (vm *VM) sampleCall() bool {
+	if vm.OnCall == nil {
+		return false
+	}
+	if vm.OnCallSampleRate > 1 {
+		if vm.callN++; vm.callN < vm.OnCallSampleRate {
+			return false
+		}
+		vm.callN = 0
+	}
+	return true
+}
+
+// reportCall calls OnCall if the call of the frame is sampled.
+func (vm *VM) reportCall(f *frame, err error) {
+	if f.callStart.IsZero() {
+		return
+	}
+	d := time.Since(f.callStart)
+	f.callStart = time.Time{}
+	vm.OnCall(newCallInfo(f.fn), d, err)
+}
+
+// isRunning reports whether fn is called by a frame of the VM.
+func (vm *VM) isRunning(fn *CompiledFunction) bool {
+	for i := vm.frameIndex; i > 0; i-- {
+		if vm.frames[i-1].fn == fn {
+			return true
+		}
+	}
+	return false
+}
+
+func (vm *VM) cyclicImportError(moduleIndex int) error {
+	name := "#" + strconv.Itoa(moduleIndex)
+	if moduleIndex < len(vm.bytecode.ModuleNames) {
+		name = vm.bytecode.ModuleNames[moduleIndex]
+	}
+	return ErrCyclicImport.NewError(fmt.Sprintf(
+		"module '%s' is imported before it is initialized, "+
+			"import it lazily in a function", name))
+}
+
 func (vm *VM) clearCurrentFrame() {
 	for _, f := range vm.curFrame.defers {
 		f()
@@ -415,7 +732,7 @@ func (vm *VM) clearCurrentFrame() {
 
 func (vm *VM) handlePanic(r any) {
 	if vm.sp < stackSize && vm.frameIndex <= frameSize && vm.err == nil {
-		if err := vm.throwGenErr(fmt.Errorf("%v", r)); err != nil {
+		if err := vm.throwGenErr(newPanicError(r)); err != nil {
 			vm.err = err
 			gostack := debugStack()
 			if vm.err != nil {
@@ -436,6 +753,30 @@ func (vm *VM) handlePanic(r any) {
 	vm.err = fmt.Errorf("panic: %v\nGo Stack:\n%s", r, gostack)
 }
 
+// recover returns the error handled by the current catch block if it is a
+// panic, otherwise returns nil.
+func (vm *VM) recover() *RuntimeError {
+	if eh := vm.curFrame.errHandlers; eh != nil {
+		for i := len(eh.handlers) - 1; i >= 0; i-- {
+			if caught := eh.handlers[i].caught; caught != nil {
+				if caught.Panic {
+					return caught
+				}
+				break
+			}
+		}
+	}
+	return nil
+}
+
+func newPanicError(r any) *Error {
+	return &Error{
+		Name:    ErrPanic.Name,
+		Message: fmt.Sprint(r),
+		Cause:   &GoPanic{Value: r, Stack: debugStack()},
+	}
+}
+
 func (vm *VM) GetSymbolValue(symbol *SymbolInfo) (value Object, err error) {
 	switch symbol.Scope {
 	case ScopeGlobal:
@@ -527,6 +868,7 @@ func (vm *VM) xOpSetupCatch() {
 
 		if errHandlers.err != nil {
 			value = errHandlers.err
+			hdl.caught = errHandlers.err
 			errHandlers.err = nil
 		}
 	}
@@ -543,6 +885,7 @@ func (vm *VM) xOpSetupFinally() {
 		hdl := errHandlers.last()
 		hdl.catch = 0
 		hdl.finally = 0
+		hdl.caught = nil
 	}
 }
 
@@ -594,11 +937,16 @@ func (vm *VM) throwGenErr(err error) error {
 			e.fileSet = vm.bytecode.FileSet
 		}
 		return vm.throw(e, true)
-	} else if e, ok := err.(*Error); ok {
-		return vm.throw(vm.newError(e), false)
 	}
 
-	return vm.throw(vm.newErrorFromError(err), false)
+	var e *RuntimeError
+	if v, ok := err.(*Error); ok {
+		e = vm.newError(v)
+	} else {
+		e = vm.newErrorFromError(err)
+	}
+	e.Panic = IsPanicError(err)
+	return vm.throw(e, false)
 }
 
 func (vm *VM) throw(err *RuntimeError, noTrace bool) error {
@@ -610,6 +958,7 @@ func (vm *VM) throw(err *RuntimeError, noTrace bool) error {
 	if vm.curFrame.errHandlers.hasHandler() {
 		return vm.handleThrownError(vm.curFrame, err)
 	}
+	vm.reportCall(vm.curFrame, err)
 
 	// find previous frames having error handler
 	var frame *frame
@@ -622,6 +971,11 @@ func (vm *VM) throw(err *RuntimeError, noTrace bool) error {
 			frame = f
 			break
 		}
+		vm.reportCall(f, err)
+		for _, fn := range f.defers {
+			fn()
+		}
+		f.defers = nil
 		f.freeVars = nil
 		f.fn = nil
 		f.args = nil
@@ -652,7 +1006,8 @@ func (vm *VM) handleThrownError(frame *frame, err *RuntimeError) error {
 	handler := frame.errHandlers.last()
 
 	// if we have catch>0 goto catch else follow finally (one of them must be set)
-	if handler.catch > 0 {
+	// catch is skipped while finalizing aborted VM or exiting
+	if handler.catch > 0 && vm.finalizing == nil && !isExitError(err) {
 		vm.ip = handler.catch - 1
 	} else if handler.finally > 0 {
 		vm.ip = handler.finally - 1
@@ -671,6 +1026,12 @@ func (vm *VM) handleThrownError(frame *frame, err *RuntimeError) error {
 	return nil
 }
 
+// isExitError reports whether err is raised by exit builtin.
+func isExitError(err error) bool {
+	var e *ExitError
+	return errors.As(err, &e)
+}
+
 func (vm *VM) xOpCallName() (err error) {
 	var (
 		numArgs     = int(vm.curInsts[vm.ip+1])
@@ -698,6 +1059,11 @@ func (vm *VM) xOpCallName() (err error) {
 	vm.sp--
 	vm.stack[vm.sp] = nil
 
+	if m := TypeMethodOf(obj, name.ToString()); m != nil {
+		vm.stack[vm.sp-numArgs-kwCount-1] = m
+		return vm.xOpCallAny(m, numArgs, flags)
+	}
+
 	if nameCaller, ok := obj.(NameCallerObject); ok {
 		c := Call{
 			VM:   vm,
@@ -724,6 +1090,16 @@ func (vm *VM) xOpCallName() (err error) {
 		}
 
 		c.Args[0] = vm.stack[vm.sp-numArgs-kwCount : vm.sp-expandArgs-kwCount]
+		var tainted bool
+		if vm.taint != nil {
+			if tainted, err = vm.taintCall(obj, obj.Type().Name()+"."+name.ToString(), &c); err != nil {
+				return
+			}
+		}
+		if tainted {
+			vm.taintedCalls++
+			defer func() { vm.taintedCalls-- }()
+		}
 		ret, err := Val(nameCaller.CallName(name.ToString(), c))
 		for i := 0; i < numArgs+kwCount; i++ {
 			vm.sp--
@@ -732,6 +1108,9 @@ func (vm *VM) xOpCallName() (err error) {
 		if err != nil {
 			return err
 		}
+		if tainted {
+			ret = Taint(ret)
+		}
 
 		vm.stack[vm.sp-1] = ret
 		vm.ip += 2
@@ -978,6 +1357,7 @@ func (vm *VM) xOpCallCompiled(cfunc *CompiledFunction, numArgs int, flags OpCall
 			vm.curFrame.errHandlers = nil // reset error handlers if any set
 			vm.curFrame.namedArgs = &namedParams
 			vm.curFrame.args = args
+			vm.curFrame.debugLine = 0
 			return nil
 		}
 	}
@@ -992,6 +1372,11 @@ func (vm *VM) xOpCallCompiled(cfunc *CompiledFunction, numArgs int, flags OpCall
 	frame.freeVars = cfunc.Free
 	frame.errHandlers = nil
 	frame.basePointer = basePointer
+	frame.callStart = time.Time{}
+	frame.debugLine = 0
+	if vm.sampleCall() {
+		frame.callStart = time.Now()
+	}
 	vm.curFrame.ip = vm.ip + 2
 	vm.curInsts = cfunc.Instructions
 	vm.curFrame = frame
@@ -1049,9 +1434,37 @@ func (vm *VM) xOpCallObject(co_ Object, numArgs int, flags OpCallFlag) (err erro
 		result Object
 	)
 
-	if result, err = Val(co.Call(c)); err != nil {
+	if vm.auditState != nil {
+		vm.auditCall(co_, c.Args)
+	}
+	if vm.deprecations != nil {
+		vm.deprecatedCall(co_)
+	}
+
+	var tainted bool
+	if vm.taint != nil {
+		if tainted, err = vm.taintCall(co_, "", &c); err != nil {
+			return
+		}
+	}
+	if tainted {
+		vm.taintedCalls++
+		defer func() { vm.taintedCalls-- }()
+	}
+
+	if vm.sampleCall() {
+		start := time.Now()
+		result, err = Val(co.Call(c))
+		vm.OnCall(newCallInfo(co_), time.Since(start), err)
+	} else {
+		result, err = Val(co.Call(c))
+	}
+	if err != nil {
 		return err
 	}
+	if tainted {
+		result = Taint(result)
+	}
 
 	for i := 0; i < numArgs+kwCount; i++ {
 		vm.sp--
@@ -1068,6 +1481,15 @@ func (vm *VM) xOpUnary() error {
 	right := vm.stack[vm.sp-1]
 	var value Object
 
+	if t, ok := right.(*Tainted); ok && vm.taint != nil {
+		vm.stack[vm.sp-1] = t.Value
+		if err := vm.xOpUnary(); err != nil {
+			return err
+		}
+		vm.stack[vm.sp-1] = Taint(vm.stack[vm.sp-1])
+		return nil
+	}
+
 	switch tok {
 	case token.Not:
 		switch right.(type) {
@@ -1242,7 +1664,9 @@ func (vm *VM) xOpSliceIndex() error {
 
 	switch obj := obj.(type) {
 	case Array:
-		vm.stack[vm.sp] = obj[low:high]
+		// capacity is limited so appending to the slice does not overwrite
+		// the array
+		vm.stack[vm.sp] = obj[low:high:high]
 	case Str:
 		vm.stack[vm.sp] = obj[low:high]
 	case Bytes:
@@ -1321,6 +1745,9 @@ type errHandler struct {
 	catch    int
 	finally  int
 	returnTo int
+	// caught is the error handled by catch block, it is returned by recover
+	// builtin if it is a panic.
+	caught *RuntimeError
 }
 
 type errHandlers struct {
@@ -1386,6 +1813,11 @@ type frame struct {
 	args        Args
 	namedArgs   *NamedArgs
 	defers      []func()
+	// callStart is the start time of the call if it is sampled for OnCall.
+	callStart time.Time
+	// debugFile and debugLine are the last source line reported to Debugger.
+	debugFile string
+	debugLine int
 }
 
 func (f *frame) Defer(fn func()) {
@@ -1461,6 +1893,7 @@ func (v *vmPool) _acquire(vm *VM, cf *CompiledFunction) *VM {
 	vm.bytecode.FileSet = v.root.bytecode.FileSet
 	vm.bytecode.Constants = v.root.bytecode.Constants
 	vm.bytecode.NumModules = v.root.bytecode.NumModules
+	vm.bytecode.ModuleNames = v.root.bytecode.ModuleNames
 	vm.bytecode.Main = cf
 	vm.constants = v.root.bytecode.Constants
 	vm.modulesCache = v.root.modulesCache
@@ -1469,6 +1902,9 @@ func (v *vmPool) _acquire(vm *VM, cf *CompiledFunction) *VM {
 	}
 	vm.noPanic = v.root.noPanic
 	vm.SetupOpts = v.root.SetupOpts
+	vm.auditState = v.root.auditState
+	vm.deprecations = v.root.deprecations
+	vm.taint = v.root.taint
 	vm.ObjectToWriter = v.root.ObjectToWriter
 
 	if v.vms == nil {
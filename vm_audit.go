@@ -0,0 +1,138 @@
+package gad
+
+import (
+	"reflect"
+	"sync"
+
+	"github.com/gad-lang/gad/parser"
+)
+
+// AuditKind is the kind of an AuditEvent.
+type AuditKind int
+
+const (
+	// AuditGlobalSet is recorded when a global variable is assigned.
+	AuditGlobalSet AuditKind = iota + 1
+	// AuditReflectSet is recorded when an index or a field of a reflected Go
+	// value is assigned.
+	AuditReflectSet
+	// AuditImport is recorded when a module is imported.
+	AuditImport
+	// AuditModuleCall is recorded when a function of a builtin module is
+	// called.
+	AuditModuleCall
+)
+
+func (k AuditKind) String() string {
+	switch k {
+	case AuditGlobalSet:
+		return "global"
+	case AuditReflectSet:
+		return "reflect"
+	case AuditImport:
+		return "import"
+	case AuditModuleCall:
+		return "call"
+	}
+	return "unknown"
+}
+
+// AuditEvent is a mutating or an external operation recorded while a script
+// is running if SetupOpts.Audit is set.
+type AuditEvent struct {
+	Kind AuditKind
+	// Name is the name of the global variable, the imported module or the
+	// called function as "module.func".
+	Name string
+	// Target is the reflected value assigned by AuditReflectSet.
+	Target Object
+	// Index is the index or the field name assigned by AuditReflectSet.
+	Index Object
+	// Value is the assigned value or the arguments Array of the call.
+	Value Object
+	// Pos is the source position of the operation.
+	Pos parser.SourceFilePos
+}
+
+// AuditLog collects AuditEvents. Its Record method can be used as
+// SetupOpts.Audit. It is safe to use concurrently.
+type AuditLog struct {
+	mu     sync.Mutex
+	events []AuditEvent
+}
+
+// Record appends e to the log.
+func (l *AuditLog) Record(e AuditEvent) {
+	l.mu.Lock()
+	l.events = append(l.events, e)
+	l.mu.Unlock()
+}
+
+// Events returns the recorded events in order.
+func (l *AuditLog) Events() []AuditEvent {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]AuditEvent{}, l.events...)
+}
+
+// auditState is shared by a VM and its child VMs to resolve the functions of
+// the loaded builtin modules.
+type auditState struct {
+	mu    sync.RWMutex
+	funcs map[Object]string
+}
+
+func (vm *VM) audit(e AuditEvent) {
+	if vm.bytecode.FileSet != nil {
+		e.Pos = vm.bytecode.FileSet.Position(vm.getSourcePos())
+	}
+	vm.Audit(e)
+}
+
+func (vm *VM) auditImport(moduleIndex int) {
+	var name string
+	if moduleIndex < len(vm.bytecode.ModuleNames) {
+		name = vm.bytecode.ModuleNames[moduleIndex]
+	}
+	vm.audit(AuditEvent{Kind: AuditImport, Name: name})
+}
+
+// auditModule registers the functions of a builtin module to be recorded
+// when they are called.
+func (vm *VM) auditModule(module Object) {
+	d, ok := module.(Dict)
+	if !ok {
+		return
+	}
+	name, ok := d[AttrModuleName]
+	if !ok {
+		return
+	}
+
+	vm.auditState.mu.Lock()
+	defer vm.auditState.mu.Unlock()
+
+	if vm.auditState.funcs == nil {
+		vm.auditState.funcs = make(map[Object]string)
+	}
+	for k, v := range d {
+		if _, ok := v.(*CompiledFunction); ok || !Callable(v) || !reflect.TypeOf(v).Comparable() {
+			continue
+		}
+		vm.auditState.funcs[v] = name.ToString() + "." + k
+	}
+}
+
+func (vm *VM) auditCall(fn Object, args Args) {
+	if !reflect.TypeOf(fn).Comparable() {
+		return
+	}
+
+	vm.auditState.mu.RLock()
+	name, ok := vm.auditState.funcs[fn]
+	vm.auditState.mu.RUnlock()
+
+	if ok {
+		vm.audit(AuditEvent{Kind: AuditModuleCall, Name: name, Value: append(Array{}, args.Values()...)})
+	}
+}
@@ -12,6 +12,11 @@ type vmCompiledFuncCaller struct {
 	namedArgs *NamedArgs
 	closed    bool
 	callee    CallerObject
+	budget    *Budget
+	// parentBudget is the budget state of the invoking VM.
+	parentBudget *budgetState
+	// parent is the invoking VM.
+	parent *VM
 }
 
 func (r *vmCompiledFuncCaller) Callee() CallerObject {
@@ -23,7 +28,9 @@ func (r *vmCompiledFuncCaller) Call() (ret Object, err error) {
 		return nil, ErrVMAborted
 	}
 
-	r.vm.resetState(r.args, r.namedArgs)
+	// the arguments are changed by the callers between the calls
+	r.vm.resetState(r.parent.taintCallback(r.args, r.namedArgs))
+	r.vm.budget = newBudgetState(r.budget, r.parentBudget)
 
 	defer func() {
 		if r.vm.Aborted() {
@@ -55,10 +62,11 @@ func (r *vmObjectCaller) Callee() CallerObject {
 }
 
 func (r *vmObjectCaller) Call() (ret Object, err error) {
-	return r.callee.Call(Call{
+	args, namedArgs := r.vm.taintCallback(r.args, &r.namedArgs)
+	return r.vm.callTainted(r.callee, Call{
 		VM:        r.vm,
-		Args:      r.args,
-		NamedArgs: r.namedArgs,
+		Args:      args,
+		NamedArgs: *namedArgs,
 	})
 }
 
@@ -0,0 +1,136 @@
+package gad
+
+import (
+	"github.com/gad-lang/gad/parser"
+	"github.com/gad-lang/gad/parser/source"
+)
+
+// Debugger is notified by VM before it runs the first instruction of a source
+// line. VM is paused until Line returns, so a debugger can inspect the call
+// stack with VM.DebugFrames and evaluate expressions with VM.DebugEval. If Line
+// returns an error, VM stops with the error which cannot be caught by script.
+type Debugger interface {
+	Line(vm *VM, pos parser.SourceFilePos) error
+}
+
+// DebugFrame is a frame of the call stack of a VM paused by Debugger.
+type DebugFrame struct {
+	// Fn is the function of the frame.
+	Fn *CompiledFunction
+	// Pos is the position of the current instruction of the frame.
+	Pos parser.SourceFilePos
+
+	vm *VM
+	f  *frame
+}
+
+// Locals returns the defined local variables of the frame by their names.
+func (f *DebugFrame) Locals() Dict {
+	locals := Dict{}
+	for i, name := range f.Fn.LocalNames {
+		if name == "" || name == "_" || i >= f.Fn.NumLocals {
+			continue
+		}
+		v := f.vm.stack[f.f.basePointer+i]
+		if p, ok := v.(*ObjectPtr); ok {
+			v = *p.Value
+		}
+		if v != nil {
+			locals[name] = v
+		}
+	}
+	return locals
+}
+
+// CallDepth returns the number of the frames of the call stack.
+func (vm *VM) CallDepth() int {
+	return vm.frameIndex
+}
+
+// DebugFrames returns the frames of the call stack from the innermost one. It
+// must be called while VM is paused by Debugger.
+func (vm *VM) DebugFrames() []*DebugFrame {
+	frames := make([]*DebugFrame, 0, vm.frameIndex)
+	for i := vm.frameIndex - 1; i >= 0; i-- {
+		f := &vm.frames[i]
+		ip := vm.ip + 1
+		if f != vm.curFrame {
+			// ip of the caller frame is after the call instruction
+			ip = f.ip - 1
+		}
+		df := &DebugFrame{Fn: f.fn, vm: vm, f: f}
+		if vm.bytecode.FileSet != nil {
+			df.Pos = vm.bytecode.FileSet.Position(f.fn.SourcePos(ip))
+		}
+		frames = append(frames, df)
+	}
+	return frames
+}
+
+// DebugEval evaluates the expression src in the frame of DebugFrames at index
+// frame. Identifiers are resolved from the local variables of the frame and
+// the globals of VM. It must be called while VM is paused by Debugger.
+func (vm *VM) DebugEval(frame int, src string) (Object, error) {
+	frames := vm.DebugFrames()
+	if frame < 0 || frame >= len(frames) {
+		return nil, ErrIndexOutOfBounds
+	}
+
+	bc, err := CompileExpr([]byte(src), CompileOptions{
+		CompilerOptions: CompilerOptions{
+			Module: &ModuleInfo{Name: "(debug)"},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return NewVM(bc).Setup(SetupOpts{Builtins: vm.Builtins}).RunOpts(&RunOpts{
+		Globals: &debugScope{locals: frames[frame].Locals(), globals: vm.globals},
+	})
+}
+
+// debugScope resolves the identifiers of DebugEval expressions.
+type debugScope struct {
+	ObjectImpl
+	locals  Dict
+	globals IndexGetSetter
+}
+
+func (s *debugScope) Type() ObjectType {
+	return TDict
+}
+
+func (s *debugScope) ToString() string {
+	return s.locals.ToString()
+}
+
+func (s *debugScope) IndexGet(vm *VM, index Object) (Object, error) {
+	if v, ok := s.locals[index.ToString()]; ok {
+		return v, nil
+	}
+	if s.globals != nil {
+		return s.globals.IndexGet(vm, index)
+	}
+	return Nil, nil
+}
+
+func (s *debugScope) IndexSet(vm *VM, index, value Object) error {
+	return s.locals.IndexSet(vm, index, value)
+}
+
+// debugStep calls Debugger if the next instruction starts a new source line
+// in the current frame.
+func (vm *VM) debugStep() error {
+	f := vm.curFrame
+	p, ok := f.fn.SourceMap[vm.ip+1]
+	if !ok || vm.bytecode.FileSet == nil {
+		return nil
+	}
+	pos := vm.bytecode.FileSet.Position(source.Pos(p))
+	if !pos.IsValid() || (pos.Line == f.debugLine && pos.Filename == f.debugFile) {
+		return nil
+	}
+	f.debugFile, f.debugLine = pos.Filename, pos.Line
+	return vm.Debugger.Line(vm, pos)
+}
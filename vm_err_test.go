@@ -244,6 +244,49 @@ func TestVMNoPanic(t *testing.T) {
 		NewTestOpts().NoPanic().Args(panicFunc), `index out of range [0] with length 0`)
 }
 
+func TestVMRecover(t *testing.T) {
+	panicFunc := &Function{
+		Name: "panicFunc",
+		Value: func(call Call) (Object, error) {
+			panic("boom")
+		},
+	}
+
+	TestExpectRun(t, `return recover()`, nil, Nil)
+	TestExpectRun(t, `try { [][1] } catch err { return recover().Message == err.Message }`, nil, True)
+	TestExpectRun(t, `try { 1/0 } catch { return recover().Literal }`,
+		nil, Str("ZeroDivisionError"))
+	TestExpectRun(t, `try { throw "x" } catch { return recover() }`, nil, Nil)
+	TestExpectRun(t, `try { throw TypeError.New("x") } catch { return recover() }`, nil, Nil)
+	TestExpectRun(t, `try { [][1] } catch err { try { throw err } catch { return recover() != nil } }`,
+		nil, True)
+	TestExpectRun(t, `try { [][1] } catch { return func() { return recover() }() }`, nil, Nil)
+	TestExpectRun(t, `try { [][1] } catch {} finally { return recover() }`, nil, Nil)
+	TestExpectRun(t, `try { [][1] } catch { try {} finally {}; return recover() != nil }`, nil, True)
+
+	TestExpectRun(t, `param panic; try { panic() } catch { e := recover(); return [e.Literal, e.Message] }`,
+		NewTestOpts().NoPanic().Args(panicFunc), Array{Str("PanicError"), Str("boom")})
+	TestExpectRun(t, `param panic; try { panic() } catch { return len(recover().GoStack) > 0 }`,
+		NewTestOpts().NoPanic().Args(panicFunc), True)
+
+	c, err := Compile([]byte(`param panic; panic()`), CompileOptions{})
+	require.NoError(t, err)
+	_, err = NewVM(c).SetRecover(true).Run(panicFunc)
+	require.Error(t, err)
+	require.True(t, errors.Is(err, ErrPanic))
+	require.True(t, IsPanicError(err))
+	var p *GoPanic
+	require.True(t, errors.As(err, &p))
+	require.Equal(t, "boom", p.Value)
+	require.NotEmpty(t, p.Stack)
+
+	c, err = Compile([]byte(`throw "x"`), CompileOptions{})
+	require.NoError(t, err)
+	_, err = NewVM(c).Run()
+	require.Error(t, err)
+	require.False(t, IsPanicError(err))
+}
+
 func TestVMCatchAll(t *testing.T) {
 	catchAll := `
 	return func(callable, *args) {
@@ -3,6 +3,9 @@ package gad
 import (
 	"fmt"
 	"math"
+	"reflect"
+
+	"github.com/gad-lang/gad/token"
 )
 
 func ToStr(vm *VM, o Object) (_ Str, err error) {
@@ -64,10 +67,67 @@ func ToReprTypedRS(vm *VM, typ ObjectType, o any) (s string, err error) {
 	return ReprQuote(s + v), nil
 }
 
+// BinaryOp evaluates binary operation of left and right operands using the
+// binaryOp builtin, so user defined operator methods are also considered.
+func BinaryOp(vm *VM, tok token.Token, left, right Object) (Object, error) {
+	return Val(vm.Builtins.Call(BuiltinBinaryOp, Call{VM: vm, Args: Args{Array{BinaryOperatorTypes[tok], left, right}}}))
+}
+
 func DeepCopy(vm *VM, o Object) (Object, error) {
 	return Val(vm.Builtins.Call(BuiltinDeepCopy, Call{VM: vm, Args: Args{Array{o}}}))
 }
 
+// beginDeepCopy starts tracking the copies of the objects if vm is not
+// running a deep copy, so the shared references and the cycles are preserved
+// by the copy. The returned function ends it.
+func (vm *VM) beginDeepCopy() (end func()) {
+	if vm == nil || vm.deepCopies != nil {
+		return func() {}
+	}
+	vm.deepCopies = map[any]Object{}
+	return func() { vm.deepCopies = nil }
+}
+
+// deepCopyOf returns the copy of the object identified by key if it is
+// already copied by the running deep copy.
+func (vm *VM) deepCopyOf(key any) (cp Object, ok bool) {
+	if vm != nil && key != nil {
+		cp, ok = vm.deepCopies[key]
+	}
+	return
+}
+
+// addDeepCopy records cp as the copy of the object identified by key. It must
+// be called before the values of the object are copied.
+func (vm *VM) addDeepCopy(key any, cp Object) {
+	if vm != nil && key != nil && vm.deepCopies != nil {
+		vm.deepCopies[key] = cp
+	}
+}
+
+// arrayKey identifies an array by its backing array and length.
+type arrayKey struct {
+	p *Object
+	n int
+}
+
+// identity returns the key of the array for the deep copies, nil if it is
+// empty.
+func (o Array) identity() any {
+	if len(o) == 0 {
+		return nil
+	}
+	return arrayKey{&o[0], len(o)}
+}
+
+// identity returns the key of the dict for the deep copies, nil if it is nil.
+func (o Dict) identity() any {
+	if o == nil {
+		return nil
+	}
+	return reflect.ValueOf(o).UnsafePointer()
+}
+
 func Copy(o Object) Object {
 	if cp, _ := o.(Copier); cp != nil {
 		return cp.Copy()
@@ -201,6 +261,16 @@ func ToIterator(vm *VM, obj Object, na *NamedArgs) (l int, it Iterator, err erro
 	return
 }
 
+// IterateToChan sends values of it to ch and closes ch when iteration ends,
+// fails, the VM is aborted or its Context is done, so consumers ranging over
+// ch always terminate.
+func IterateToChan(vm *VM, it Iterator, ch chan<- Object) error {
+	defer close(ch)
+	return Iterate(vm, it, nil, func(e *KeyValue) error {
+		return vm.send(ch, e.V)
+	})
+}
+
 func ToStateIterator(vm *VM, obj Object, na *NamedArgs) (l int, sit *StateIteratorObject, err error) {
 	var it Iterator
 	if l, it, err = ToIterator(vm, obj, na); err == nil {
@@ -263,6 +333,9 @@ func CollectCb(vm *VM, o Object, na *NamedArgs, cb func(e *KeyValue, i *Int) Obj
 				err = it.Next(vm, state)
 			}
 		} else {
+			if l = IteratorLengthHint(it); l > 0 {
+				values = make(Array, 0, l)
+			}
 			state, err = it.Start(vm)
 			for err == nil && state.Mode != IteratorStateModeDone {
 				if state.Mode != IteratorStateModeContinue {
@@ -26,6 +26,7 @@ type Invoker struct {
 	isCompiled      bool
 	dorelease       bool
 	validArgs       bool
+	budget          *Budget
 	prepareHandlers []func(vm *VM)
 }
 
@@ -54,6 +55,13 @@ func (inv *Invoker) ValidArgs(v bool) *Invoker {
 	return inv
 }
 
+// Budget sets the Budget of every invocation of compiled function callee. The
+// invocations are also counted in the budget of the invoking VM's run.
+func (inv *Invoker) Budget(b *Budget) *Invoker {
+	inv.budget = b
+	return inv
+}
+
 func (inv *Invoker) acquire(usePool bool) {
 	if !inv.isCompiled {
 		inv.child = inv.vm
@@ -103,8 +111,11 @@ func (inv *Invoker) Invoke(args Args, namedArgs *NamedArgs) (Object, error) {
 				return nil, err
 			}
 		}
-		return inv.child.RunOpts(&RunOpts{Globals: inv.vm.globals, Args: args, NamedArgs: namedArgs})
+		args, namedArgs = inv.vm.taintCallback(args, namedArgs)
+		return inv.child.RunOpts(&RunOpts{Globals: inv.vm.globals, Args: args, NamedArgs: namedArgs,
+			Budget: inv.budget, parentBudget: inv.vm.budget})
 	}
+	args, _ = inv.vm.taintCallback(args, nil)
 	return inv.invokeObject(inv.callee, args)
 }
 
@@ -113,10 +124,10 @@ func (inv *Invoker) invokeObject(co Object, args Args) (Object, error) {
 	if callee == nil {
 		return Nil, ErrNotCallable.NewError(co.Type().Name())
 	}
-	return Val(callee.Call(Call{
+	return inv.vm.callTainted(callee, Call{
 		VM:   inv.vm,
 		Args: args,
-	}))
+	})
 }
 
 // Caller create new VM caller object.
@@ -147,10 +158,13 @@ do:
 		}
 
 		return &vmCompiledFuncCaller{
-			callee:    inv.callee.(CallerObject),
-			vm:        inv.child,
-			args:      args,
-			namedArgs: namedArgs,
+			callee:       inv.callee.(CallerObject),
+			vm:           inv.child,
+			args:         args,
+			namedArgs:    namedArgs,
+			budget:       inv.budget,
+			parentBudget: inv.vm.budget,
+			parent:       inv.vm,
 		}, nil
 	}
 
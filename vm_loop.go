@@ -8,9 +8,34 @@ import (
 )
 
 func (vm *VM) loop() {
-	var op Opcode
+	var (
+		op       Opcode
+		interval = vm.SafepointInterval
+		budget   = vm.budget
+		debugger = vm.Debugger != nil
+	)
 VMLoop:
 	for atomic.LoadInt64(&vm.abort) == 0 {
+		if budget != nil {
+			if err := budget.step(); err != nil {
+				vm.err = err
+				return
+			}
+		}
+		if interval > 0 {
+			if vm.safepointN++; vm.safepointN >= interval {
+				if err := vm.safepoint(); err != nil {
+					vm.err = err
+					return
+				}
+			}
+		}
+		if debugger {
+			if err := vm.debugStep(); err != nil {
+				vm.err = err
+				return
+			}
+		}
 		vm.ip++
 		op = Opcode(vm.curInsts[vm.ip])
 		switch op {
@@ -45,7 +70,17 @@ VMLoop:
 			tok := token.Token(vm.curInsts[vm.ip+1])
 			left, right := vm.stack[vm.sp-2], vm.stack[vm.sp-1]
 
-			value, err := Val(vm.Builtins.Call(BuiltinBinaryOp, Call{VM: vm, Args: Args{Array{BinaryOperatorTypes[tok], left, right}}}))
+			var (
+				value   Object
+				err     error
+				handled bool
+			)
+			if vm.taint != nil {
+				value, handled, err = vm.taintBinaryOp(tok, left, right)
+			}
+			if !handled {
+				value, err = Val(vm.Builtins.Call(BuiltinBinaryOp, Call{VM: vm, Args: Args{Array{BinaryOperatorTypes[tok], left, right}}}))
+			}
 
 			if err == nil {
 				vm.stack[vm.sp-2] = value
@@ -112,11 +147,19 @@ VMLoop:
 			}
 		case OpEqual:
 			left, right := vm.stack[vm.sp-2], vm.stack[vm.sp-1]
+			if vm.taint != nil {
+				left, _ = Untaint(left)
+				right, _ = Untaint(right)
+			}
 			vm.stack[vm.sp-2] = Bool(left.Equal(right))
 			vm.sp--
 			vm.stack[vm.sp] = nil
 		case OpNotEqual:
 			left, right := vm.stack[vm.sp-2], vm.stack[vm.sp-1]
+			if vm.taint != nil {
+				left, _ = Untaint(left)
+				right, _ = Untaint(right)
+			}
 
 			switch left := left.(type) {
 			case Int:
@@ -186,6 +229,7 @@ VMLoop:
 			if vm.frameIndex == 1 {
 				return
 			}
+			vm.reportCall(vm.curFrame, nil)
 			vm.clearCurrentFrame()
 			parent := &(vm.frames[vm.frameIndex-2])
 			vm.frameIndex--
@@ -194,6 +238,9 @@ VMLoop:
 			vm.curInsts = vm.curFrame.fn.Instructions
 		case OpGetBuiltin:
 			builtinIndex := BuiltinType(int(vm.curInsts[vm.ip+2]) | int(vm.curInsts[vm.ip+1])<<8)
+			if vm.deprecations != nil {
+				vm.deprecatedBuiltin(builtinIndex)
+			}
 			vm.stack[vm.sp] = vm.Builtins.Objects[builtinIndex]
 			vm.sp++
 			vm.ip += 2
@@ -215,17 +262,7 @@ VMLoop:
 				vm.stack[vm.sp-numFree+i] = nil
 			}
 			vm.sp -= numFree
-			newFn := &CompiledFunction{
-				Instructions: fn.Instructions,
-				NumLocals:    fn.NumLocals,
-				SourceMap:    fn.SourceMap,
-				Free:         free,
-				Params:       fn.Params,
-				NamedParams:  fn.NamedParams,
-				sourceFile:   fn.sourceFile,
-				module:       fn.module,
-			}
-			vm.stack[vm.sp] = newFn
+			vm.stack[vm.sp] = fn.Closure(free)
 			vm.sp++
 			vm.ip += 3
 		case OpJump:
@@ -287,6 +324,10 @@ VMLoop:
 				value = *v.Value
 			}
 
+			if vm.auditState != nil {
+				vm.audit(AuditEvent{Kind: AuditGlobalSet, Name: index.ToString(), Value: value})
+			}
+
 			if err := vm.globals.IndexSet(vm, index, value); err != nil {
 				if err := vm.throwGenErr(err); err != nil {
 					vm.err = err
@@ -397,6 +438,23 @@ VMLoop:
 			if is, _ := target.(IndexSetter); is != nil {
 				index := vm.stack[vm.sp-1]
 
+				if vm.taint != nil {
+					var err error
+					if index, err = vm.taintKey(target, index); err != nil {
+						if err = vm.throwGenErr(err); err != nil {
+							vm.err = err
+							return
+						}
+						continue
+					}
+				}
+
+				if vm.auditState != nil {
+					if _, ok := target.(ReflectValuer); ok {
+						vm.audit(AuditEvent{Kind: AuditReflectSet, Target: target, Index: index, Value: value})
+					}
+				}
+
 				err := is.IndexSet(vm, index, value)
 
 				if err != nil {
@@ -529,7 +587,10 @@ VMLoop:
 				return
 			}
 		case OpIterNext:
-			iterator := vm.stack[vm.sp-1].(*StateIteratorObject)
+			iterator := vm.stateIterator(op)
+			if iterator == nil {
+				return
+			}
 			hasMore, err := iterator.Read()
 			if err != nil {
 				if err = vm.throwGenErr(err); err != nil {
@@ -539,7 +600,10 @@ VMLoop:
 			}
 			vm.stack[vm.sp-1] = Bool(hasMore)
 		case OpIterNextElse:
-			iterator := vm.stack[vm.sp-1].(*StateIteratorObject)
+			iterator := vm.stateIterator(op)
+			if iterator == nil {
+				return
+			}
 			truePos := int(vm.curInsts[vm.ip+2]) | int(vm.curInsts[vm.ip+1])<<8
 			falsePos := int(vm.curInsts[vm.ip+4]) | int(vm.curInsts[vm.ip+3])<<8
 			vm.ip += 4
@@ -560,19 +624,35 @@ VMLoop:
 				vm.ip = falsePos - 1
 			}
 		case OpIterKey:
-			iterator := vm.stack[vm.sp-1]
-			val := iterator.(*StateIteratorObject).State.Entry.K
-			vm.stack[vm.sp-1] = val
+			iterator := vm.stateIterator(op)
+			if iterator == nil {
+				return
+			}
+			vm.stack[vm.sp-1] = iterator.State.Entry.K
 		case OpIterValue:
-			iterator := vm.stack[vm.sp-1]
-			val := iterator.(*StateIteratorObject).State.Entry.V
-			vm.stack[vm.sp-1] = val
+			iterator := vm.stateIterator(op)
+			if iterator == nil {
+				return
+			}
+			vm.stack[vm.sp-1] = iterator.State.Entry.V
 		case OpLoadModule:
 			cidx := int(vm.curInsts[vm.ip+2]) | int(vm.curInsts[vm.ip+1])<<8
 			midx := int(vm.curInsts[vm.ip+4]) | int(vm.curInsts[vm.ip+3])<<8
 			value := vm.modulesCache[midx]
 
+			if vm.auditState != nil {
+				vm.auditImport(midx)
+			}
+
 			if value == nil {
+				if fn, ok := vm.constants[cidx].(*CompiledFunction); ok && vm.isRunning(fn) {
+					// module imports itself through a cycle before returning
+					if err := vm.throwGenErr(vm.cyclicImportError(midx)); err != nil {
+						vm.err = err
+						return
+					}
+					continue
+				}
 				// module cache is empty, load the object from constants
 				vm.stack[vm.sp] = vm.constants[cidx]
 				vm.sp++
@@ -594,14 +674,47 @@ VMLoop:
 			midx := int(vm.curInsts[vm.ip+2]) | int(vm.curInsts[vm.ip+1])<<8
 			value := vm.stack[vm.sp-1]
 
-			if v, ok := value.(Copier); ok {
+			if f, ok := value.(*moduleFactory); ok {
+				// create the module for this VM
+				var err error
+				if value, err = f.instance(vm); err != nil {
+					if err = vm.throwGenErr(err); err != nil {
+						vm.err = err
+						return
+					}
+					continue
+				}
+				vm.stack[vm.sp-1] = value
+			} else if v, ok := value.(Copier); ok {
 				// store deep copy of the module if supported
 				value = v.Copy()
 				vm.stack[vm.sp-1] = value
 			}
 
+			if vm.auditState != nil {
+				vm.auditModule(value)
+			}
+			if vm.deprecations != nil {
+				vm.deprecatedModule(value)
+			}
+			if vm.taint != nil {
+				vm.taintModule(value)
+			}
+
 			vm.modulesCache[midx] = value
 			vm.ip += 2
+		case OpModuleHooks:
+			initFn, cleanupFn := vm.stack[vm.sp-2], vm.stack[vm.sp-1]
+			vm.stack[vm.sp-2], vm.stack[vm.sp-1] = nil, nil
+			vm.sp -= 2
+
+			if err := vm.moduleHooks(initFn, cleanupFn); err != nil {
+				if err = vm.throwGenErr(err); err != nil {
+					vm.err = err
+					return
+				}
+				continue
+			}
 		case OpSetupTry:
 			vm.xOpSetupTry()
 		case OpSetupCatch:
@@ -647,5 +760,33 @@ VMLoop:
 			return
 		}
 	}
+
+	if vm.suspendValue != nil {
+		if vm.err = vm.takeSnapshot(); vm.err == nil {
+			vm.err = ErrVMSuspended
+		}
+		vm.suspendValue = nil
+		return
+	}
+
+	if vm.finalize() {
+		goto VMLoop
+	}
 	vm.err = ErrVMAborted
 }
+
+// stateIterator returns the iterator of the iteration instruction op on top
+// of the stack. It sets the error of VM and returns nil if the bytecode does
+// not put an iterator there, as the verifier does not check the operands.
+func (vm *VM) stateIterator(op Opcode) *StateIteratorObject {
+	v := vm.stack[vm.sp-1]
+	if it, ok := v.(*StateIteratorObject); ok {
+		return it
+	}
+	typ := "nil"
+	if v != nil {
+		typ = v.Type().Name()
+	}
+	vm.err = ErrInvalidBytecode.NewError(fmt.Sprintf("%s operand is %s, not an iterator", op, typ))
+	return nil
+}
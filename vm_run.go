@@ -4,6 +4,10 @@ import (
 	"context"
 	"errors"
 	"io"
+	"runtime"
+	"strconv"
+	"sync/atomic"
+	"time"
 )
 
 type SetupOpts struct {
@@ -11,6 +15,88 @@ type SetupOpts struct {
 	Builtins         *Builtins
 	ToRawStrHandler  func(vm *VM, s Str) RawStr
 	Context          context.Context
+
+	// SafepointInterval is the number of instructions between safepoints.
+	// At every safepoint VM checks whether Context is done and calls
+	// Safepoint callback if set. Safepoints are disabled if it is zero.
+	SafepointInterval int
+	// Safepoint is called at every safepoint. If it returns an error, VM stops
+	// with the error which cannot be caught by script.
+	Safepoint func(vm *VM) error
+
+	// AbortGracePeriod enables graceful abort if it is greater than zero.
+	// When VM is aborted, pending finally blocks are run before unwinding,
+	// catch blocks are skipped. If finalization takes longer than grace
+	// period, VM stops immediately.
+	AbortGracePeriod time.Duration
+
+	// DeadlockTimeout enables deadlock detection of synchronization objects
+	// for debugging if it is greater than zero. Locking a mutex held by the
+	// same VM fails immediately and waiting for a mutex, waitgroup or once
+	// longer than timeout throws DeadlockError.
+	DeadlockTimeout time.Duration
+
+	// OnCall is called after a function called by VM returns with the
+	// duration and the error of the call, if any. Builtin and compiled
+	// function calls are reported, the main function is not. It is called
+	// from the goroutine running the VM, so it must be safe to use
+	// concurrently if VMs run in parallel.
+	OnCall func(info CallInfo, d time.Duration, err error)
+	// OnCallSampleRate reports every Nth call to OnCall to reduce the
+	// overhead. Every call is reported if it is less than 2.
+	OnCallSampleRate int
+
+	// Audit is called for every global variable assignment, reflected Go
+	// value assignment, import and builtin module function call to review
+	// what a script does. See AuditLog.
+	Audit func(e AuditEvent)
+
+	// Debugger is notified before VM runs a new source line if it is not nil.
+	// See Debugger.
+	Debugger Debugger
+
+	// Deprecations are the deprecated builtins and module members. If
+	// OnDeprecated is not nil, it is called once for every deprecated builtin
+	// which is used and deprecated builtin module function which is called.
+	Deprecations Deprecations
+	OnDeprecated func(e DeprecationEvent)
+
+	// Taint enables taint tracking of the values marked by Taint if it is
+	// not nil. See TaintPolicy.
+	Taint *TaintPolicy
+}
+
+// CallInfo describes a function call reported to SetupOpts.OnCall.
+type CallInfo struct {
+	// Name is the name of the function. It is empty for anonymous compiled
+	// functions.
+	Name string
+	// Fn is the called function.
+	Fn Object
+}
+
+func newCallInfo(fn Object) CallInfo {
+	info := CallInfo{Fn: fn}
+	switch t := fn.(type) {
+	case *CompiledFunction:
+		info.Name = t.Name
+	case *BuiltinFunction:
+		info.Name = t.Name
+	case *Function:
+		info.Name = t.Name
+	case ObjectType:
+		info.Name = t.Name()
+	default:
+		info.Name = fn.Type().Name()
+	}
+	return info
+}
+
+// SafepointYield is a Safepoint callback yielding the processor to let other
+// goroutines and VMs run.
+func SafepointYield(*VM) error {
+	runtime.Gosched()
+	return nil
 }
 
 type RunOpts struct {
@@ -21,6 +107,81 @@ type RunOpts struct {
 	StdOut         io.Writer
 	StdErr         io.Writer
 	ObjectToWriter ObjectToWriter
+	// Budget limits the run if it is not nil.
+	Budget *Budget
+
+	// parentBudget is the budget state of the VM invoking the run.
+	parentBudget *budgetState
+}
+
+// Budget limits a single run of VM independent of the VM's context. Nested
+// VMs of functions invoked by Go are counted in the budget of the run, they
+// can be limited further with Invoker.Budget.
+type Budget struct {
+	// Timeout is the maximum duration of the run. Zero means no timeout.
+	// Timeout is checked periodically while VM executes instructions, so a
+	// blocking Go call is not interrupted.
+	Timeout time.Duration
+	// MaxOps is the maximum number of instructions to execute. Zero means no
+	// limit.
+	MaxOps int
+}
+
+// BudgetExceededError is returned if a run exceeds its Budget.
+type BudgetExceededError struct {
+	Budget *Budget
+	// Ops is the number of executed instructions.
+	Ops int
+	// Timeout reports whether the timeout is exceeded, otherwise MaxOps is
+	// exceeded.
+	Timeout bool
+}
+
+func (e *BudgetExceededError) Error() string {
+	if e.Timeout {
+		return "BudgetExceededError: timeout " + e.Budget.Timeout.String() + " exceeded"
+	}
+	return "BudgetExceededError: max ops " + strconv.Itoa(e.Budget.MaxOps) + " exceeded"
+}
+
+// budgetTimeCheckMask determines the interval of instructions to check
+// Budget timeout.
+const budgetTimeCheckMask = 0xff
+
+// budgetState is shared by the VM of a run and the nested VMs invoked by Go
+// which can run concurrently, so the ops are counted atomically.
+type budgetState struct {
+	*Budget
+	ops      int64
+	deadline time.Time
+	parent   *budgetState
+}
+
+// newBudgetState returns the budget state of b, which also steps the parent
+// state. It returns parent if b does not limit the run.
+func newBudgetState(b *Budget, parent *budgetState) *budgetState {
+	if b == nil || (b.Timeout <= 0 && b.MaxOps <= 0) {
+		return parent
+	}
+	s := &budgetState{Budget: b, parent: parent}
+	if b.Timeout > 0 {
+		s.deadline = time.Now().Add(b.Timeout)
+	}
+	return s
+}
+
+func (s *budgetState) step() error {
+	ops := int(atomic.AddInt64(&s.ops, 1))
+	if s.MaxOps > 0 && ops > s.MaxOps {
+		return &BudgetExceededError{Budget: s.Budget, Ops: ops - 1}
+	}
+	if !s.deadline.IsZero() && ops&budgetTimeCheckMask == 0 && time.Now().After(s.deadline) {
+		return &BudgetExceededError{Budget: s.Budget, Ops: ops, Timeout: true}
+	}
+	if s.parent != nil {
+		return s.parent.step()
+	}
+	return nil
 }
 
 // Run runs VM and executes the instructions until the OpReturn Opcode or Abort call.
@@ -57,10 +218,17 @@ func (vm *VM) safeRun() (rerun bool) {
 		vm.clearCurrentFrame()
 	}()
 	vm.loop()
+	vm.endFinalize()
+	if vm.err == ErrVMAborted {
+		vm.runFrameDefers()
+	}
 	return
 }
 
 func (vm *VM) run() (Object, error) {
+	// nested VMs invoked after the run must not step its budget
+	defer func() { vm.budget = nil }()
+
 	for run := true; run; {
 		run = vm.safeRun()
 	}
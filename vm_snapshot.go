@@ -0,0 +1,193 @@
+package gad
+
+import (
+	"errors"
+	"fmt"
+	"sync/atomic"
+)
+
+// Snapshot is the state of a VM suspended by the suspend builtin. It can be
+// resumed with VM.Resume in the same process or encoded with the encoder
+// package and resumed in another process with the same Bytecode.
+type Snapshot struct {
+	// Value is the argument passed to suspend builtin.
+	Value  Object
+	Stack  []Object
+	Frames []*SnapshotFrame
+}
+
+// SnapshotFrame is a call frame of a suspended VM.
+type SnapshotFrame struct {
+	Fn          *CompiledFunction
+	Free        []*ObjectPtr
+	IP          int
+	BasePointer int
+	Args        Args
+	NamedArgs   *NamedArgs
+	Handlers    []SnapshotErrHandler
+}
+
+// SnapshotErrHandler is an active try statement of a suspended frame.
+type SnapshotErrHandler struct {
+	SP       int
+	Catch    int
+	Finally  int
+	ReturnTo int
+}
+
+// Snapshot returns the state of VM saved by the last suspend call or nil if
+// VM is not suspended.
+func (vm *VM) Snapshot() *Snapshot {
+	return vm.snapshot
+}
+
+// suspend stops the VM loop after the current call returns. It is only
+// allowed if the VM is not invoked by a Go function.
+func (vm *VM) suspend(value Object) error {
+	if vm.pool.root != vm {
+		return errors.New("suspend is not allowed in a function invoked by Go")
+	}
+	vm.suspendValue = value
+	atomic.StoreInt64(&vm.abort, 1)
+	return nil
+}
+
+// takeSnapshot saves the state of suspended VM. It must be called by loop
+// before the current frame is cleared.
+func (vm *VM) takeSnapshot() (err error) {
+	vm.curFrame.ip = vm.ip
+
+	s := &Snapshot{
+		Value:  vm.suspendValue,
+		Stack:  make([]Object, vm.sp),
+		Frames: make([]*SnapshotFrame, vm.frameIndex),
+	}
+	copy(s.Stack, vm.stack[:vm.sp])
+
+	for i := range s.Frames {
+		f := &vm.frames[i]
+		if len(f.defers) > 0 {
+			return errors.New("cannot suspend: frame has deferred functions")
+		}
+
+		sf := &SnapshotFrame{
+			Fn:          f.fn,
+			Free:        f.freeVars,
+			IP:          f.ip,
+			BasePointer: f.basePointer,
+			Args:        f.args,
+			NamedArgs:   f.namedArgs,
+		}
+
+		if f.errHandlers != nil {
+			if f.errHandlers.hasError() {
+				return errors.New("cannot suspend: frame has unhandled error")
+			}
+			for _, h := range f.errHandlers.handlers {
+				sf.Handlers = append(sf.Handlers, SnapshotErrHandler{
+					SP:       h.sp,
+					Catch:    h.catch,
+					Finally:  h.finally,
+					ReturnTo: h.returnTo,
+				})
+			}
+		}
+		s.Frames[i] = sf
+	}
+
+	vm.snapshot = s
+	return
+}
+
+// Resume restores the state of suspended VM from the snapshot and continues
+// to run. Given value is returned by the suspend call. Bytecode must be the
+// one which is run by suspended VM.
+func (vm *VM) Resume(s *Snapshot, value Object, opts *RunOpts) (Object, error) {
+	vm.mu.Lock()
+	defer vm.mu.Unlock()
+
+	if err := s.validate(); err != nil {
+		return nil, err
+	}
+
+	if opts == nil {
+		opts = &RunOpts{}
+	}
+
+	if err := vm.init(opts); err != nil {
+		return nil, err
+	}
+
+	for i := range vm.stack {
+		vm.stack[i] = nil
+	}
+	copy(vm.stack[:], s.Stack)
+	vm.sp = len(s.Stack)
+
+	for i, sf := range s.Frames {
+		f := &vm.frames[i]
+		*f = frame{
+			fn:          sf.Fn,
+			freeVars:    sf.Free,
+			ip:          sf.IP,
+			basePointer: sf.BasePointer,
+			args:        sf.Args,
+			namedArgs:   sf.NamedArgs,
+		}
+		if len(sf.Handlers) > 0 {
+			f.errHandlers = &errHandlers{}
+			for _, h := range sf.Handlers {
+				f.errHandlers.handlers = append(f.errHandlers.handlers, errHandler{
+					sp:       h.SP,
+					catch:    h.Catch,
+					finally:  h.Finally,
+					returnTo: h.ReturnTo,
+				})
+			}
+		}
+	}
+
+	vm.frameIndex = len(s.Frames)
+	vm.curFrame = &vm.frames[vm.frameIndex-1]
+	vm.curInsts = vm.curFrame.fn.Instructions
+	vm.ip = vm.curFrame.ip
+
+	if value == nil {
+		value = Nil
+	}
+	vm.stack[vm.sp-1] = value
+	return vm.run()
+}
+
+// validate checks that the stack pointer, the frames and their instruction
+// pointers are in range, so a malformed snapshot is not restored.
+func (s *Snapshot) validate() error {
+	if len(s.Frames) == 0 || len(s.Stack) == 0 {
+		return errors.New("invalid Snapshot")
+	}
+	if len(s.Stack) >= stackSize {
+		return fmt.Errorf("invalid Snapshot: stack pointer %d out of range", len(s.Stack))
+	}
+	if len(s.Frames) > frameSize {
+		return fmt.Errorf("invalid Snapshot: %d frames exceed the frame size", len(s.Frames))
+	}
+	for i, f := range s.Frames {
+		if f == nil || f.Fn == nil {
+			return fmt.Errorf("invalid Snapshot: frame %d has no function", i)
+		}
+		n := len(f.Fn.Instructions)
+		if f.IP < 0 || f.IP >= n {
+			return fmt.Errorf("invalid Snapshot: instruction pointer %d of frame %d out of range", f.IP, i)
+		}
+		if f.BasePointer < 0 || f.BasePointer > len(s.Stack) {
+			return fmt.Errorf("invalid Snapshot: base pointer %d of frame %d out of range", f.BasePointer, i)
+		}
+		for _, h := range f.Handlers {
+			if h.SP < 0 || h.SP > len(s.Stack) || h.Catch < 0 || h.Catch >= n ||
+				h.Finally < 0 || h.Finally >= n || h.ReturnTo < 0 || h.ReturnTo >= n {
+				return fmt.Errorf("invalid Snapshot: error handler of frame %d out of range", i)
+			}
+		}
+	}
+	return nil
+}
@@ -1,17 +1,23 @@
 package gad_test
 
 import (
+	"bufio"
 	"bytes"
+	"context"
 	"errors"
+	"flag"
 	"fmt"
 	"io"
 	"reflect"
 	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 
 	. "github.com/gad-lang/gad"
+	"github.com/gad-lang/gad/parser"
 )
 
 func TestVMBinaryOperator(t *testing.T) {
@@ -54,6 +60,117 @@ func TestVMDict(t *testing.T) {
 		Array{Dict{"a": Int(1)}, Dict{"a": Int(1)}})
 }
 
+func TestVMCapacityHints(t *testing.T) {
+	TestExpectRun(t, `return array(1, 2; length=4, fill=0)`, nil, Array{Int(1), Int(2), Int(0), Int(0)})
+	TestExpectRun(t, `return array(;length=2)`, nil, Array{Nil, Nil})
+	TestExpectRun(t, `a := array(;cap=10); a += [1]; return a`, nil, Array{Int(1)})
+	TestExpectRun(t, `return dict(;cap=10)`, nil, Dict{})
+	TestExpectRun(t, `return dict({a:1};cap=10, b=2)`, nil, Dict{"a": Int(1), "b": Int(2)})
+	expectErrIs(t, `array(1, 2; length=1)`, nil, ErrUnexpectedArgValue)
+	expectErrIs(t, `array(;size=1)`, nil, ErrUnexpectedNamedArg)
+	expectErrIs(t, `array(;cap=1000000000000000)`, nil, ErrUnexpectedArgValue)
+	expectErrIs(t, `array(;cap=-1)`, nil, ErrUnexpectedArgValue)
+	expectErrIs(t, `array(;length=1000000000000000)`, nil, ErrUnexpectedArgValue)
+	expectErrIs(t, `dict(;cap=1000000000000000)`, nil, ErrUnexpectedArgValue)
+	expectErrIs(t, `dict(;cap=-1)`, nil, ErrUnexpectedArgValue)
+
+	run := func(script string) Object {
+		t.Helper()
+		bc, err := Compile([]byte(script), DefaultCompileOptions)
+		require.NoError(t, err)
+		ret, err := NewVM(bc).Run()
+		require.NoError(t, err)
+		return ret
+	}
+	require.Equal(t, 10, cap(run(`return array(1; cap=10)`).(Array)))
+	require.Equal(t, 5, cap(run(`return array(;length=5, cap=2)`).(Array)))
+
+	for _, script := range []string{
+		`return collect(map([1, 2, 3, 4], (v, _) => v * 2))`,
+		`return collect(values(map([1, 2, 3, 4], (v, _) => v * 2)))`,
+		`return collect(take(map([1, 2, 3, 4, 5, 6], (v, _) => v * 2), 4))`,
+	} {
+		arr := run(script).(Array)
+		require.Equal(t, Array{Int(2), Int(4), Int(6), Int(8)}, arr, script)
+		require.Equal(t, 4, cap(arr), script)
+	}
+
+	it := NewPipedInvokeIterator(Array{Int(1), Int(2)}.Iterate(nil, &NamedArgs{}), Array{nil, nil}, 0, nil)
+	require.Equal(t, 2, IteratorLengthHint(it))
+	require.Equal(t, 1, IteratorLengthHint(TakeIterator(it, 1)))
+	require.Equal(t, 2, IteratorLengthHint(TakeIterator(it, 3)))
+	require.Equal(t, -1, IteratorLengthHint(TakeIterator(NewIterator(nil, nil), 3)))
+
+	// the length hint is not the count of take if the length is unknown
+	TestExpectRun(t, `return collect(take(takeWhile(counter(), (v, _) => v < 5), 1000000000000))`,
+		nil, Array{Int(0), Int(1), Int(2), Int(3), Int(4)})
+	require.Equal(t, -1, IteratorLengthHint(NewIterator(nil, nil)))
+}
+
+func TestVMNumArray(t *testing.T) {
+	TestExpectRun(t, `return intarray(1, 2, [3, 4u], 'a')`, nil, IntArray{1, 2, 3, 4, 97})
+	TestExpectRun(t, `return intarray(;length=3)`, nil, IntArray{0, 0, 0})
+	TestExpectRun(t, `return floatarray(1, 2.5, intarray(3))`, nil, FloatArray{1, 2.5, 3})
+	TestExpectRun(t, `return intarray(floatarray(1.5, -2.5))`, nil, IntArray{1, -2})
+	TestExpectRun(t, `return [typeName(intarray()), typeName(floatarray())]`, nil,
+		Array{Str("intarray"), Str("floatarray")})
+
+	TestExpectRun(t, `a := intarray(1, 2, 3); return a + intarray(10, 20, 30)`, nil, IntArray{11, 22, 33})
+	TestExpectRun(t, `a := intarray(1, 2, 3); return [a * 2, 10 - a, a % 2, a << 1]`, nil,
+		Array{IntArray{2, 4, 6}, IntArray{9, 8, 7}, IntArray{1, 0, 1}, IntArray{2, 4, 6}})
+	TestExpectRun(t, `a := intarray(1, 2); return [a / 2.0, 1.0 / floatarray(2, 4), a + floatarray(0.5, 0.5)]`, nil,
+		Array{FloatArray{0.5, 1}, FloatArray{0.5, 0.25}, FloatArray{1.5, 2.5}})
+	TestExpectRun(t, `a := floatarray(1, 2); a += 1; a[0] = 5; return a`, nil, FloatArray{5, 3})
+	expectErrIs(t, `intarray(1, 2) + intarray(1, 2, 3)`, nil, ErrInvalidOperator)
+	expectErrIs(t, `intarray(1, 2) / intarray(1, 0)`, nil, ErrZeroDivision)
+	expectErrIs(t, `floatarray(1) % 2`, nil, ErrType)
+	expectErrIs(t, `intarray([nil])`, nil, ErrType)
+
+	TestExpectRun(t, `a := intarray(3, 1, 2); return [a.sum(), a.mean(), a.min(), a.max(), len(a)]`, nil,
+		Array{Int(6), Float(2), Int(1), Int(3), Int(3)})
+	TestExpectRun(t, `a := floatarray(1.5, -1); return [a.sum(), a.min(), a.max()]`, nil,
+		Array{Float(0.5), Float(-1), Float(1.5)})
+	TestExpectRun(t, `a := intarray(); return [a.sum(), a.mean(), a.min()]`, nil, Array{Int(0), Nil, Nil})
+	TestExpectRun(t, `a := intarray(1, 2, 3); return [a.toArray(), collect(a), a[1:], append(a, 4), a[-1]]`, nil,
+		Array{Array{Int(1), Int(2), Int(3)}, Array{Int(1), Int(2), Int(3)}, IntArray{2, 3}, IntArray{1, 2, 3, 4}, Int(3)})
+	TestExpectRun(t, `s := 0; for v in floatarray(1, 2) { s += v }; return s`, nil, Float(3))
+}
+
+func TestVMLinesScan(t *testing.T) {
+	TestExpectRun(t, `return collect(lines("a\nb\r\n\nc"))`, nil, Array{Str("a"), Str("b"), Str(""), Str("c")})
+	TestExpectRun(t, `return collect(lines(bytes("a\nb\n")))`, nil, Array{Str("a"), Str("b")})
+	TestExpectRun(t, `b := buffer(); write(b, "x\ny"); r := []; for i, l in lines(b) { r = append(r, [i, l]) }; return r`,
+		nil, Array{Array{Int(0), Str("x")}, Array{Int(1), Str("y")}})
+	TestExpectRun(t, `return typeName(lines(""))`, nil, Str("ReaderScanIterator"))
+	TestExpectRun(t, `return collect(scan("a b\n c"))`, nil, Array{Str("a b"), Str(" c")})
+	TestExpectRun(t, `return collect(scan(" a b\n  c ";split="words"))`, nil, Array{Str("a"), Str("b"), Str("c")})
+	TestExpectRun(t, `return collect(scan("aé";split="runes"))`, nil, Array{Str("a"), Str("é")})
+	TestExpectRun(t, `return collect(scan("abcde";split=func(data, eof) {
+		if len(data) >= 2 { return [2, data[:2]] }
+		if eof && len(data) > 0 { return [len(data), str(data)] }
+		return nil
+	}))`, nil, Array{Str("ab"), Str("cd"), Str("e")})
+	expectErrIs(t, `scan("a";split="x")`, nil, ErrUnexpectedArgValue)
+	expectErrIs(t, `collect(scan("abc";split=func(data, eof) => 1))`, nil, ErrType)
+	expectErrIs(t, `collect(scan("abc";split=func(data, eof) => [0, data]))`, nil, ErrType)
+	expectErrIs(t, `collect(scan("abc";split=func(data, eof) => [4, data]))`, nil, ErrType)
+	TestExpectRun(t, `return collect(scan("abc";split=func(data, eof) => eof ? [0, ""] : nil))`, nil, Array{})
+	TestExpectRun(t, `return collect(scan("abc";split=func(data, eof) {
+		if len(data) > 1 { return [1, data[:1]] }
+		return eof ? [0, ""] : nil
+	}))`, nil, Array{Str("a"), Str("b")})
+	// data is not the buffer of the scanner
+	TestExpectRun(t, `s := ""; for i := 0; i < 2000; i++ { s += str(i) + "," }
+	var first
+	collect(scan(s;split=func(data, eof) {
+		first ??= data
+		return len(data) > 0 ? [1, data[:1]] : nil
+	}))
+	return str(first[:8])`, nil, Str("0,1,2,3,"))
+	expectErrIs(t, `lines(1)`, nil, ErrType)
+	expectErrIs(t, `collect(lines("abcdef";maxLen=4))`, nil, bufio.ErrTooLong)
+}
+
 func TestVMArray(t *testing.T) {
 	TestExpectRun(t, `return [1, 2 * 2, 3 + 3]`, nil, Array{Int(1), Int(4), Int(6)})
 	TestExpectRun(t, `return [1, 2] + [3] + {c:4} + (;d=5)`, nil, Array{Int(1), Int(2), Int(3), Int(4), Int(5)})
@@ -66,6 +183,21 @@ func TestVMArray(t *testing.T) {
 	// array index set
 	expectErrIs(t, `a1 := [1, 2, 3]; a1[3] = 5`, nil, ErrIndexOutOfBounds)
 
+	// appending to a slice does not overwrite the array
+	TestExpectRun(t, `a := [1, 2, 3]; b := a[:1]; b = append(b, 9); return [a, b]`,
+		nil, Array{Array{Int(1), Int(2), Int(3)}, Array{Int(1), Int(9)}})
+
+	// views
+	TestExpectRun(t, `a := [1, 2, 3, 4]; v := view(a, 1, -1); v[0] = 0; return [a, collect(v), len(v), typeName(v)]`,
+		nil, Array{Array{Int(1), Int(2), Int(3), Int(4)}, Array{Int(0), Int(3)}, Int(2), Str("arrayView")})
+	TestExpectRun(t, `a := [1, 2, 3, 4]; v := view(a, 2); w := v[1:]; v[1] = 0; return [collect(v), collect(w), typeName(w), v + [5]]`,
+		nil, Array{Array{Int(3), Int(0)}, Array{Int(4)}, Str("arrayView"), Array{Int(3), Int(0), Int(5)}})
+	TestExpectRun(t, `a := [3, 1, 2]; v := view(a); sort(v); return [a, collect(v)]`,
+		nil, Array{Array{Int(3), Int(1), Int(2)}, Array{Int(1), Int(2), Int(3)}})
+	expectErrIs(t, `view([1, 2], 2, 1)`, nil, ErrInvalidIndex)
+	expectErrIs(t, `view([1, 2], 0, 3)`, nil, ErrIndexOutOfBounds)
+	expectErrIs(t, `view("ab")`, nil, ErrType)
+
 	// index operator
 	arr := Array{Int(1), Int(2), Int(3), Int(4), Int(5), Int(6)}
 	arrStr := `[1, 2, 3, 4, 5, 6]`
@@ -259,6 +391,49 @@ func TestVMDecl(t *testing.T) {
 		}
 	}()
 	return x`, nil, Int(2))
+
+	TestExpectRun(t, `var x int = 1; x = 2; x += 3; x++; return x`, nil, Int(6))
+	TestExpectRun(t, `var x int; return x`, nil, Nil)
+	TestExpectRun(t, `var x int|str; x = "a"; x = 1; return x`, nil, Int(1))
+	TestExpectRun(t, `var (x int = 1, y str = "a"); return [x, y]`, nil, Array{Int(1), Str("a")})
+	TestExpectRun(t, `var x int; func() { x = 2 }(); return x`, nil, Int(2))
+	TestExpectRun(t, `var x int; if true { x := "a" }; return x`, nil, Nil)
+	TestExpectRun(t, `global a dict; return a`,
+		NewTestOpts().Globals(Dict{"a": Dict{}}), Dict{})
+	expectErrHas(t, `var x int = "a"`, nil,
+		`TypeError: invalid type for variable 'x': expected int, found str`)
+	expectErrHas(t, `var x int = 1; x = 1.5`, nil,
+		`TypeError: invalid type for variable 'x': expected int, found float`)
+	expectErrHas(t, `var x int = 1; x += 1.5`, nil,
+		`TypeError: invalid type for variable 'x': expected int, found float`)
+	expectErrHas(t, `var x int; func() { x = "a" }()`, nil,
+		`TypeError: invalid type for variable 'x': expected int, found str`)
+	expectErrHas(t, `var (x int, y); x, y = ["a", 1]`, nil,
+		`TypeError: invalid type for variable 'x': expected int, found str`)
+	expectErrHas(t, `global a dict`, nil,
+		`TypeError: invalid type for variable 'a': expected dict, found nil`)
+	expectErrHas(t, `var x unknownType`, NewTestOpts().CompilerError(),
+		`Compile Error: unresolved reference "unknownType"`)
+
+	TestExpectRun(t, `func f(a int) -> str { return str(a) }; return f(1)`, nil, Str("1"))
+	TestExpectRun(t, `func f(a) -> int|str { if a { return 1 }; return "s" }; return [f(true), f(false)]`,
+		nil, Array{Int(1), Str("s")})
+	TestExpectRun(t, `f := func() -> int => 2; return f()`, nil, Int(2))
+	TestExpectRun(t, `func f() -> str { return "a" }; return repr(f)`,
+		nil, Str(`‹compiledFunction:‹compiledFunction f() -> str››`))
+	expectErrHas(t, `func f() -> str { return 1 }; f()`, nil,
+		`TypeError: invalid return type of function 'f': expected str, found int`)
+	expectErrHas(t, `func f() -> int {}; f()`, nil,
+		`TypeError: invalid return type of function 'f': expected int, found nil`)
+	expectErrHas(t, `(func() -> int => "x")()`, nil,
+		`TypeError: invalid return type of function: expected int, found str`)
+	// closures
+	expectErrHas(t, `x := 1; func f() -> str { return x }; f()`, nil,
+		`TypeError: invalid return type of function 'f': expected str, found int`)
+	TestExpectRun(t, `x := 1; func f() -> str { return str(x) }; return [f(), repr(f)]`,
+		nil, Array{Str("1"), Str(`‹compiledFunction f() -> str›`)})
+	expectErrHas(t, `func f() -> unknownType {}`, NewTestOpts().CompilerError(),
+		`Compile Error: unresolved reference "unknownType"`)
 }
 
 func TestVMAssignment(t *testing.T) {
@@ -932,6 +1107,159 @@ func TestVMIterator(t *testing.T) {
 		nil, Str(`[0=1, 1=2, 2=3, 3=4, 4=5, 5=6]`))
 	TestExpectRun(t, `return str(collect(enumerate(zip([1,2,3],[4,5,6]);keys)))`,
 		nil, Str(`[0, 1, 2, 0, 1, 2]`))
+
+	TestExpectRun(t, `return str(collect(zipLongest([1,2,3],[4,5])))`,
+		nil, Str(`[[1, 4], [2, 5], [3, nil]]`))
+	TestExpectRun(t, `return str(collect(zipLongest([1],[4,5];fill=0)))`,
+		nil, Str(`[[1, 4], [0, 5]]`))
+	TestExpectRun(t, `return str(collect(zipLongest({a:1,b:2},[3];sorted)))`,
+		nil, Str(`[[1, 3], [2, nil]]`))
+	TestExpectRun(t, `return str(collect(zipLongest()))`, nil, Str(`[]`))
+	TestExpectRun(t, `return str(collect(items(enumerate(zipLongest([1],[2,3])))))`,
+		nil, Str(`[0=[0=[1, 2]], 1=[1=[nil, 3]]]`))
+	TestExpectRun(t, `return repr(product([1,2],[3]))`,
+		nil, Str(`‹ProductIterator:[[1, 2], [3]]›`))
+	TestExpectRun(t, `return str(collect(product([1,2],"ab")))`,
+		nil, Str(`[[1, 'a'], [1, 'b'], [2, 'a'], [2, 'b']]`))
+	TestExpectRun(t, `return str(collect(product([0,1];repeat=2)))`,
+		nil, Str(`[[0, 0], [0, 1], [1, 0], [1, 1]]`))
+	TestExpectRun(t, `return str(collect(product([1,2],[])))`, nil, Str(`[]`))
+	TestExpectRun(t, `return str(collect(keys(product([1,2],[3,4]))))`, nil, Str(`[0, 1, 2, 3]`))
+	TestExpectRun(t, `return str(collect(permutations([1,2,3])))`,
+		nil, Str(`[[1, 2, 3], [1, 3, 2], [2, 1, 3], [2, 3, 1], [3, 1, 2], [3, 2, 1]]`))
+	TestExpectRun(t, `return str(collect(permutations([1,2,3], 2)))`,
+		nil, Str(`[[1, 2], [1, 3], [2, 1], [2, 3], [3, 1], [3, 2]]`))
+	TestExpectRun(t, `return str(collect(permutations([1,2], 3)))`, nil, Str(`[]`))
+	TestExpectRun(t, `return str(collect(combinations([1,2,3,4], 2)))`,
+		nil, Str(`[[1, 2], [1, 3], [1, 4], [2, 3], [2, 4], [3, 4]]`))
+	TestExpectRun(t, `return str(collect(combinations([1,2,3], 0)))`, nil, Str(`[[]]`))
+	TestExpectRun(t, `return str(collect(combinations([1,2], 3)))`, nil, Str(`[]`))
+	TestExpectRun(t, `return str(collect(map(combinations([1,2,3], 2), (v, _) => v[0]+v[1])))`,
+		nil, Str(`[3, 4, 5]`))
+	expectErrIs(t, `combinations([1,2])`, nil, ErrWrongNumArguments)
+	expectErrIs(t, `permutations([1,2], -1)`, nil, ErrType)
+	expectErrIs(t, `product([1];repeat="a")`, nil, ErrType)
+
+	TestExpectRun(t, `return str(collect(take(counter(), 3)))`, nil, Str(`[0, 1, 2]`))
+	TestExpectRun(t, `return str(collect(take(counter(10, 5), 3)))`, nil, Str(`[10, 15, 20]`))
+	TestExpectRun(t, `return str(collect(take(counter(1.5, 0.5), 3)))`, nil, Str(`[1.5, 2, 2.5]`))
+	TestExpectRun(t, `return repr(take(counter(), 3))`, nil, Str(`‹TakeIterator:‹CounterIterator:0, 1›, 3›`))
+	TestExpectRun(t, `return str(collect(take(cycle([1,2,3]), 7)))`, nil, Str(`[1, 2, 3, 1, 2, 3, 1]`))
+	TestExpectRun(t, `return str(collect(take(cycle([]), 3)))`, nil, Str(`[]`))
+	TestExpectRun(t, `return str(collect(keys(take(cycle("ab"), 3))))`, nil, Str(`[0, 1, 2]`))
+	TestExpectRun(t, `return str(collect(take(repeatIter("a"), 3)))`, nil, Str(`["a", "a", "a"]`))
+	TestExpectRun(t, `return str(collect(repeatIter("a", 2)))`, nil, Str(`["a", "a"]`))
+	TestExpectRun(t, `return str(collect(repeatIter("a", 0)))`, nil, Str(`[]`))
+	TestExpectRun(t, `return str(collect(take(iterate((x) => x*2, 1), 5)))`, nil, Str(`[1, 2, 4, 8, 16]`))
+	TestExpectRun(t, `return str(collect(take([1,2,3], 0)))`, nil, Str(`[]`))
+	TestExpectRun(t, `return str(collect(take([1,2,3], 5)))`, nil, Str(`[1, 2, 3]`))
+	TestExpectRun(t, `return str(collect(takeWhile(counter(), (v, _) => v < 4)))`, nil, Str(`[0, 1, 2, 3]`))
+	TestExpectRun(t, `return str(collect(map(take(counter(), 3), (v, _) => v*10)))`, nil, Str(`[0, 10, 20]`))
+	expectErrIs(t, `repeatIter(1, -1)`, nil, ErrType)
+	expectErrIs(t, `take(counter())`, nil, ErrWrongNumArguments)
+	expectErrIs(t, `iterate(1, 2)`, nil, ErrType)
+
+	chanGlobals := Dict{
+		"fromChan": &Function{Value: func(c Call) (Object, error) {
+			ch := make(chan Object)
+			go func() {
+				defer close(ch)
+				for i := 1; i <= 3; i++ {
+					ch <- Int(i)
+				}
+			}()
+			return IteratorObject(ChanIterator(ch)), nil
+		}},
+		"sumChan": &Function{Value: func(c Call) (Object, error) {
+			_, it, err := ToIterator(c.VM, c.Args.Get(0), &c.NamedArgs)
+			if err != nil {
+				return nil, err
+			}
+			var (
+				ch   = make(chan Object)
+				sum  = make(chan Int)
+				errc = make(chan error, 1)
+			)
+			go func() {
+				var s Int
+				for v := range ch {
+					s += v.(Int)
+				}
+				sum <- s
+			}()
+			errc <- IterateToChan(c.VM, it, ch)
+			if err = <-errc; err != nil {
+				return nil, err
+			}
+			return <-sum, nil
+		}},
+	}
+	TestExpectRun(t, `global fromChan; return str(collect(map(fromChan(), (v, _) => v*2)))`,
+		NewTestOpts().Globals(chanGlobals), Str(`[2, 4, 6]`))
+	TestExpectRun(t, `global sumChan; return sumChan(take(counter(1), 4))`,
+		NewTestOpts().Globals(chanGlobals), Int(10))
+	TestExpectRun(t, `global fromChan; global sumChan; return sumChan(fromChan())`,
+		NewTestOpts().Globals(chanGlobals), Int(6))
+
+	// blocked channels are released when VM is aborted or its Context is done
+	chanGlobals = Dict{
+		"recv": &Function{Value: func(c Call) (Object, error) {
+			return IteratorObject(ChanIterator(make(chan Object))), nil
+		}},
+		"send": &Function{Value: func(c Call) (Object, error) {
+			_, it, err := ToIterator(c.VM, Array{Int(1)}, &c.NamedArgs)
+			if err != nil {
+				return nil, err
+			}
+			return Nil, IterateToChan(c.VM, it, make(chan Object))
+		}},
+	}
+	for _, src := range []string{`global recv; for v in recv() {}`, `global send; send()`} {
+		c, err := Compile([]byte(src), CompileOptions{})
+		require.NoError(t, err)
+		vm := NewVM(c)
+		go func() {
+			time.Sleep(20 * time.Millisecond)
+			vm.Abort()
+		}()
+		_, err = vm.RunOpts(&RunOpts{Globals: chanGlobals})
+		require.ErrorIs(t, err, ErrVMAborted, src)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		_, err = NewVM(c).Setup(SetupOpts{Context: ctx}).RunOpts(&RunOpts{Globals: chanGlobals})
+		cancel()
+		require.ErrorIs(t, err, context.DeadlineExceeded, src)
+	}
+
+	TestExpectRun(t, `n := 0; c := cached(take(map(counter(), (v, _) => { n++; return v }), 3));
+	return str([collect(c), collect(c), n])`, nil, Str(`[[0, 1, 2], [0, 1, 2], 3]`))
+	TestExpectRun(t, `return str(collect(items(cached("ab"))))`, nil, Str(`[0=a, 1=b]`))
+	TestExpectRun(t, `return str(collect(cached(filter([1,2,3,4], (v, _, _) => v%2))))`, nil, Str(`[1, 3]`))
+	TestExpectRun(t, `return repr(cached([1]))`, nil, Str(`‹CachedIterator:‹ArrayIterator:[1]››`))
+	TestExpectRun(t, `a, b := tee([1,2,3], 2); return str([collect(a), collect(b)])`, nil, Str(`[[1, 2, 3], [1, 2, 3]]`))
+	TestExpectRun(t, `n := 0; x := tee(map([1,2], (v, _) => { n++; return v*10 }));
+	return str([len(x), collect(x[1]), collect(x[0]), n])`, nil, Str(`[2, [10, 20], [10, 20], 2]`))
+	TestExpectRun(t, `return len(tee([1], 0))`, nil, Int(0))
+	expectErrIs(t, `tee([1], -1)`, nil, ErrType)
+	expectErrIs(t, `cached()`, nil, ErrWrongNumArguments)
+
+	TestExpectRun(t, `return reduce(counter(1), (a, v, k) => a > 10 ? stop(a) : a + v, 0)`, nil, Int(15))
+	TestExpectRun(t, `return reduce([1,2,3], (a, v, k) => stop("x"), 0)`, nil, Str("x"))
+	TestExpectRun(t, `return str(collect(scan([1,2,3], (a, v, k) => a + v)))`, nil, Str(`[1, 3, 6]`))
+	TestExpectRun(t, `return str(collect(scan([1,2,3], (a, v, k) => a + v, 10)))`, nil, Str(`[11, 13, 16]`))
+	TestExpectRun(t, `return str(collect(keys(scan("abc", (a, v, k) => a + v))))`, nil, Str(`[0, 1, 2]`))
+	TestExpectRun(t, `return str(collect(take(scan(counter(1), (a, v, k) => a * v), 5)))`, nil, Str(`[1, 2, 6, 24, 120]`))
+	TestExpectRun(t, `return str(collect(scan([], (a, v, k) => a + v, 10)))`, nil, Str(`[]`))
+	TestExpectRun(t, `return reduceRight(["a","b","c"], (a, v, k) => a + v)`, nil, Str("cba"))
+	TestExpectRun(t, `return reduceRight(["a","b","c"], (a, v, k) => a + v, ">")`, nil, Str(">cba"))
+	TestExpectRun(t, `return reduceRight({a: 1}, (a, v, k) => a + k, "")`, nil, Str("a"))
+	TestExpectRun(t, `return reduceRight([1,2,3,4], (a, v, k) => v == 2 ? stop(a) : a + v)`, nil, Int(7))
+	TestExpectRun(t, `return reduceRight([], (a, v, k) => a + v)`, nil, Nil)
+	TestExpectRun(t, `return str(stop(1))`, nil, Str("stop(1)"))
+	TestExpectRun(t, `return typeName(stop())`, nil, Str("IterationStop"))
+	expectErrIs(t, `scan([1])`, nil, ErrWrongNumArguments)
+	expectErrIs(t, `reduceRight(1, (a, v, k) => a)`, nil, ErrType)
+	expectErrIs(t, `stop(1, 2)`, nil, ErrWrongNumArguments)
 }
 
 func TestVMBuiltinFunction(t *testing.T) {
@@ -1042,6 +1370,27 @@ func TestVMBuiltinFunction(t *testing.T) {
 			b[1].c = 4
 			return a == b, a[0], b[0], a[1] == b[1], a[1].c == b[1].c, a[1].c, b[1].c`,
 		nil, Array{False, Int(1), Int(2), False, False, Int(3), Int(4)})
+	TestExpectRun(t, `s := [1]; a := {x: s, y: s}; b := dcopy(a);
+			b.x[0] = 2
+			return s[0], b.y[0]`,
+		nil, Array{Int(1), Int(2)})
+	TestExpectRun(t, `a := {x: 1}; a.self = a; a.arr = [a]; b := dcopy(a);
+			b.x = 2
+			return a.x, b.self.x, b.arr[0].x, b.self.arr[0].self.x`,
+		nil, Array{Int(1), Int(2), Int(2), Int(2)})
+	TestExpectRun(t, `a := [1, nil]; a[1] = a; b := dcopy(a);
+			b[0] = 2
+			return a[0], b[1][0], b[1][1][0]`,
+		nil, Array{Int(1), Int(2), Int(2)})
+	TestExpectRun(t, `global a; a.self = a; b := dcopy(a);
+			b.x = 2
+			return a.x, b.self.x`,
+		NewTestOpts().Globals(Dict{"a": &SyncDict{Value: Dict{"x": Int(1)}}}).Skip2Pass(),
+		Array{Int(1), Int(2)})
+	TestExpectRun(t, `T := struct("T", fields={x: 1}); a := T(); a.self = a; b := dcopy(a);
+			b.x = 2
+			return a.x, b.self.x`,
+		nil, Array{Int(1), Int(2)})
 	expectErrIs(t, `dcopy()`, nil, ErrWrongNumArguments)
 	expectErrIs(t, `dcopy(1, 2)`, nil, ErrWrongNumArguments)
 
@@ -1156,6 +1505,30 @@ func TestVMBuiltinFunction(t *testing.T) {
 	expectErrIs(t, `sort()`, nil, ErrWrongNumArguments)
 	expectErrIs(t, `sort([], [])`, nil, ErrWrongNumArguments)
 	expectErrIs(t, `sort({})`, nil, ErrType)
+	TestExpectRun(t, `return sort(["file10.txt", "file2.txt", "file02.txt", "file1.txt"]; natural)`,
+		nil, Array{Str("file1.txt"), Str("file2.txt"), Str("file02.txt"), Str("file10.txt")})
+	TestExpectRun(t, `return sort(["a10", "a9", "b1"]; natural, reverse)`,
+		nil, Array{Str("b1"), Str("a10"), Str("a9")})
+	TestExpectRun(t, `return sort([1, 3, 2]; reverse)`,
+		nil, Array{Int(3), Int(2), Int(1)})
+	TestExpectRun(t, `return sort("acb"; reverse)`,
+		nil, Str("cba"))
+	TestExpectRun(t, `return sort(bytes("acb"); key=func(b) => -b)`,
+		nil, Bytes(Str("cba")))
+	TestExpectRun(t, `r := sort([{n: "b", a: 2}, {n: "a", a: 1}, {n: "c", a: 2}]; key=func(d) => d.a, stable); return [r[0].n, r[1].n, r[2].n]`,
+		nil, Array{Str("a"), Str("b"), Str("c")})
+	TestExpectRun(t, `r := sort([{n: "b", a: 2}, {n: "a", a: 1}, {n: "c", a: 2}]; key=func(d) => d.a, stable, reverse); return [r[0].n, r[1].n, r[2].n]`,
+		nil, Array{Str("b"), Str("c"), Str("a")})
+	TestExpectRun(t, `return sort(["ccc", "a", "bb"]; cmp=func(a, b) => len(a) - len(b))`,
+		nil, Array{Str("a"), Str("bb"), Str("ccc")})
+	TestExpectRun(t, `return sort((;c=1, a=3, b=2); key=func(kv) => kv.v)`,
+		nil, KeyValueArray{{Str("c"), Int(1)}, {Str("b"), Int(2)}, {Str("a"), Int(3)}})
+	TestExpectRun(t, `a := [3, 1, 2]; v := view(a); sort(v; reverse); return [a, collect(v)]`,
+		nil, Array{Array{Int(3), Int(1), Int(2)}, Array{Int(3), Int(2), Int(1)}})
+	expectErrIs(t, `sort([1, 2]; less=func(a, b) => a < b, cmp=func(a, b) => 0)`, nil, ErrUnexpectedArgValue)
+	expectErrIs(t, `sort([1, 2]; cmp=func(a, b) => "x")`, nil, ErrType)
+	expectErrIs(t, `sort([1, 2]; key=1)`, nil, ErrType)
+	expectErrIs(t, `sort({}; reverse)`, nil, ErrType)
 
 	TestExpectRun(t, `return sortReverse(nil)`,
 		nil, Nil)
@@ -1171,6 +1544,69 @@ func TestVMBuiltinFunction(t *testing.T) {
 	expectErrIs(t, `sortReverse([], [])`, nil, ErrWrongNumArguments)
 	expectErrIs(t, `sortReverse({})`, nil, ErrType)
 
+	TestExpectRun(t, `a := [1, 3, 5, 7]; return [bisect(a, 5), bisect(a, 5; right), bisect(a, 0), bisect(a, 9), bisect([], 1)]`,
+		nil, Array{Int(2), Int(3), Int(0), Int(4), Int(0)})
+	TestExpectRun(t, `return bisect([{a: 3}, {a: 2}, {a: 1}], 2; key=func(d) => d.a, reverse)`,
+		nil, Int(1))
+	TestExpectRun(t, `return bisect(view([1, 3, 5, 7], 1), 5)`,
+		nil, Int(1))
+	TestExpectRun(t, `return [insertSorted([1, 3, 5], 4), insertSorted([], 1), insertSorted(["f1", "f10"], "f2"; natural)]`,
+		nil, Array{Array{Int(1), Int(3), Int(4), Int(5)}, Array{Int(1)}, Array{Str("f1"), Str("f2"), Str("f10")}})
+	TestExpectRun(t, `a := [1, 2, 3]; v := view(a, 0, 2); return [insertSorted(v, 0), a]`,
+		nil, Array{Array{Int(0), Int(1), Int(2)}, Array{Int(1), Int(2), Int(3)}})
+	TestExpectRun(t, `a := append([1, 3, 5], 7); b := insertSorted(a, 2); return [a, b]`,
+		nil, Array{Array{Int(1), Int(3), Int(5), Int(7)}, Array{Int(1), Int(2), Int(3), Int(5), Int(7)}})
+	expectErrIs(t, `bisect([1])`, nil, ErrWrongNumArguments)
+	expectErrIs(t, `bisect({}, 1)`, nil, ErrType)
+	expectErrIs(t, `insertSorted("abc", "b")`, nil, ErrType)
+
+	TestExpectRun(t, `s := sortedArray([3, 1, 2]); return [typeName(s), len(s), s[0], collect(s)]`,
+		nil, Array{Str("sortedArray"), Int(3), Int(1), Array{Int(1), Int(2), Int(3)}})
+	TestExpectRun(t, `s := sortedArray(; key=func(p) => p.score, reverse)
+	s.insert({n: "a", score: 10})
+	i := s.insert({n: "b", score: 30}, {n: "c", score: 20}, {n: "d", score: 20})
+	return [i, s.bisect(20), s.bisect(20; right), s[0].n, s[1].n, s[2].n, s[3].n]`,
+		nil, Array{Int(2), Int(1), Int(3), Str("b"), Str("c"), Str("d"), Str("a")})
+	TestExpectRun(t, `s := sortedArray([1, 2, 2, 3]); return [s.remove(2), s.remove(5), collect(s), s.pop(), s.pop(0), collect(s)]`,
+		nil, Array{True, False, Array{Int(1), Int(2), Int(3)}, Int(3), Int(1), Array{Int(2)}})
+	TestExpectRun(t, `s := sortedArray(["f10", "f2"]; natural); c := copy(s); c.insert("f1"); return [collect(s), collect(c)]`,
+		nil, Array{Array{Str("f2"), Str("f10")}, Array{Str("f1"), Str("f2"), Str("f10")}})
+	TestExpectRun(t, `return bool(sortedArray())`,
+		nil, False)
+	expectErrIs(t, `sortedArray([1]).pop(1)`, nil, ErrIndexOutOfBounds)
+	expectErrIs(t, `sortedArray([1]).x()`, nil, ErrInvalidIndex)
+	expectErrIs(t, `sortedArray(1)`, nil, ErrNotIterable)
+
+	TestExpectRun(t, `h := heap([5, 1, 4]); h.push(3, 2); r := [typeName(h), len(h), h.peek()]; for h { r = append(r, h.pop()) }; return r`,
+		nil, Array{Str("heap"), Int(5), Int(1), Int(1), Int(2), Int(3), Int(4), Int(5)})
+	TestExpectRun(t, `h := heap(; key=func(t) => t.p, max); h.push({n: "a", p: 1}, {n: "b", p: 3}, {n: "c", p: 2})
+	return [h.pop().n, h.pop().n, h.pop().n, bool(h)]`,
+		nil, Array{Str("b"), Str("c"), Str("a"), False})
+	TestExpectRun(t, `h := heap(["f10", "f2", "f1"]; natural); return [h.pop(), h.pop(), sort(collect(h))]`,
+		nil, Array{Str("f1"), Str("f2"), Array{Str("f10")}})
+	TestExpectRun(t, `h := heap([2, 1]); c := copy(h); c.pop(); return [len(h), len(c)]`,
+		nil, Array{Int(2), Int(1)})
+	expectErrIs(t, `heap().pop()`, nil, ErrIndexOutOfBounds)
+	expectErrIs(t, `heap().peek()`, nil, ErrIndexOutOfBounds)
+	expectErrIs(t, `heap().x()`, nil, ErrInvalidIndex)
+	expectErrIs(t, `heap(; less=func(a, b) => a < b, cmp=func(a, b) => 0)`, nil, ErrUnexpectedArgValue)
+
+	TestExpectRun(t, `d := deque([1, 2]); d.push(3); d.pushLeft(0, -1); return [typeName(d), len(d), d[0], d[-1], collect(d)]`,
+		nil, Array{Str("deque"), Int(5), Int(-1), Int(3), Array{Int(-1), Int(0), Int(1), Int(2), Int(3)}})
+	TestExpectRun(t, `d := deque([1, 2, 3]); return [d.popLeft(), d.pop(), d.peek(), d.peekLeft(), collect(d)]`,
+		nil, Array{Int(1), Int(3), Int(2), Int(2), Array{Int(2)}})
+	TestExpectRun(t, `w := deque(; maxLen=3); for i in [1, 2, 3, 4, 5] { w.push(i) }; r := collect(w); w.pushLeft(0); return [r, collect(w)]`,
+		nil, Array{Array{Int(3), Int(4), Int(5)}, Array{Int(0), Int(3), Int(4)}})
+	TestExpectRun(t, `d := deque(); for i in [1, 2, 3, 4, 5, 6, 7, 8, 9, 10] { d.pushLeft(i); d.push(i) }; for i in [1, 2, 3, 4, 5, 6, 7, 8, 9] { d.popLeft(); d.pop() }; d[1] = 0; return [collect(d), bool(d), copy(d) == d]`,
+		nil, Array{Array{Int(1), Int(0)}, True, True})
+	TestExpectRun(t, `d := deque([1]); d.clear(); return [len(d), bool(d)]`,
+		nil, Array{Int(0), False})
+	expectErrIs(t, `deque().pop()`, nil, ErrIndexOutOfBounds)
+	expectErrIs(t, `deque().popLeft()`, nil, ErrIndexOutOfBounds)
+	expectErrIs(t, `deque([1])[1]`, nil, ErrIndexOutOfBounds)
+	expectErrIs(t, `deque([1])["a"]`, nil, ErrType)
+	expectErrIs(t, `deque(; maxLen="a")`, nil, ErrType)
+
 	TestExpectRun(t, `return error("x")`, nil,
 		&Error{Name: "error", Message: "x"})
 	TestExpectRun(t, `return error(1)`, nil,
@@ -1721,6 +2157,46 @@ return str(close(o))`, nil, Str("Point{closed: true}"))
 
 	expectErrIs(t, `printf()`, nil, ErrWrongNumArguments)
 	expectErrIs(t, `sprintf()`, nil, ErrWrongNumArguments)
+	TestExpectRun(t, `return sprintf("%.2f|%8.3f|%-6.1f|%+d|%v|%e", 1.005d, 3.14159d, 1d, 5.7d, 1.5d, 2.5d)`,
+		nil, Str("1.01|   3.142|1.0   |+5|1.5|2.500000e+00"))
+	TestExpectRun(t, `return sprintf("%v|%s|%v", (;a=1, b="x"), (;a=1), [1, 2])`,
+		nil, Str(`(;a=1, b="x")|(;a=1)|[1, 2]`))
+	TestExpectRun(t, `Point := struct("Point", fields={x: 0, y: 0})
+		s := sprintf("%v|%12s", Point(x=1), Point(x=1))
+		func __format__(p Point, spec) { return "P<" + spec + ">" }
+		return [s, sprintf("%v|%-8.2f|%+05d|%q", Point(), Point(), Point(), Point())]`,
+		nil, Array{Str("Point{x: 1}| Point{x: 1}"), Str("P<>|P<<8.2f>|P<+05d>|P<r>")})
+	stdOut.Reset()
+	TestExpectRun(t, `Point := struct("Point", fields={x: 0, y: 0})
+		func __format__(p Point, spec) { return "P" }
+		printf("%v %d", Point(), 1)`,
+		NewTestOpts().Out(&stdOut).Skip2Pass(), Nil)
+	require.Equal(t, "P 1", stdOut.String())
+	expectErrIs(t, `Point := struct("Point", fields={x: 0, y: 0})
+		func __format__(p Point, spec) { throw "bad" }
+		sprintf("%v", Point())`, nil, &Error{Message: "bad"})
+
+	TestExpectRun(t, `return format("hello {name}, {n:03d} items", name="bob", n=7)`,
+		nil, Str("hello bob, 007 items"))
+	TestExpectRun(t, `return format("{} {} {0} {{x}}", 1, "a")`,
+		nil, Str("1 a 1 {x}"))
+	TestExpectRun(t, `return format("[{:>4}|{:<4}|{:*^7}|{:+d}|{:08.3f}|{:.1%}|{:x}|{:X}|{:b}|{:.2}|{:.2f}]",
+			"ab", "cd", "mid", 5, -3.14159, 0.256, 255, 255, 5, "hello", 1.005d)`,
+		nil, Str("[  ab|cd  |**mid**|+5|-003.142|25.6%|ff|FF|101|he|1.01]"))
+	TestExpectRun(t, `Point := struct("Point", fields={x: 0, y: 0})
+		func __format__(p Point, spec) {
+			return spec == "xy" ? format("({},{})", p.x, p.y) : str(p)
+		}
+		return format("{p:xy} {p}", p=Point(x=1, y=2)), __format__(1, "03")`,
+		nil, Array{Str("(1,2) Point{x: 1, y: 2}"), Str("001")})
+	expectErrIs(t, `format()`, nil, ErrWrongNumArguments)
+	expectErrIs(t, `format(1)`, nil, ErrType)
+	expectErrIs(t, `format("{x}")`, nil, ErrInvalidIndex)
+	expectErrIs(t, `format("{} {}", 1)`, nil, ErrIndexOutOfBounds)
+	expectErrIs(t, `format("{", 1)`, nil, ErrUnexpectedArgValue)
+	expectErrIs(t, `format("}", 1)`, nil, ErrUnexpectedArgValue)
+	expectErrIs(t, `format("{:q}", 1)`, nil, ErrUnexpectedArgValue)
+	expectErrIs(t, `format("{:d}", "s")`, nil, ErrType)
 }
 
 func TestObjectType(t *testing.T) {
@@ -1862,6 +2338,237 @@ return [int(Point(2, 8)), str(int)]
 			"  1. " + ReprQuote("compiledFunction #7(p Point)"))})
 }
 
+func TestObjectAttrProtocol(t *testing.T) {
+	TestExpectRun(t, `
+Row := struct("Row", fields={data: {}, id: 0})
+func __getattr__(r Row, name) => r.data[name]
+func __setattr__(r Row, name, value) {
+	r.data[name] = value
+}
+r := Row(data={a: 1})
+r.b = 2
+r.id = 3
+return [r.a, r.b, r.c, r.id, sort(collect(keys(r.data))), __getattr__(r, "a"), __getattr__({x: 1}, "x")]`,
+		nil, Array{Int(1), Int(2), Nil, Int(3), Array{Str("a"), Str("b")}, Int(1), Int(1)})
+
+	TestExpectRun(t, `
+Lazy := struct("Lazy", fields={loaded: {}})
+func __getattr__(l Lazy, name) {
+	l.loaded[name] = name + "!"
+	return l.loaded[name]
+}
+l := Lazy()
+return [l.x, l.x, len(l.loaded)]`,
+		nil, Array{Str("x!"), Str("x!"), Int(1)})
+
+	TestExpectRun(t, `
+Proxy := struct("Proxy", fields={target: nil})
+func __getattr__(p Proxy, name) => p.target[name]
+p := Proxy(target={hello: (who) => "hello " + who})
+return p.hello("gad")`,
+		nil, Str("hello gad"))
+
+	TestExpectRun(t, `
+Point := struct("Point", fields={x: 0})
+p := Point()
+p.y = 2
+__setattr__(p, "x", 1)
+return [p.x, p.y, p.z]`,
+		nil, Array{Int(1), Int(2), Nil})
+}
+
+func TestObjectCallMissing(t *testing.T) {
+	TestExpectRun(t, `
+Client := struct("Client", fields={base: ""}, methods={ping: (c) => "pong"})
+func __call_missing__(c Client, name, args, namedArgs) {
+	return [c.base + "/" + name, args, namedArgs]
+}
+c := Client(base="api")
+return [c.getUsers(1, id=3), c.ping(), c.base]`,
+		nil, Array{
+			Array{Str("api/getUsers"), Array{Int(1)}, Dict{"id": Int(3)}},
+			Str("pong"),
+			Str("api"),
+		})
+
+	expectErrIs(t, `
+Point := struct("Point", fields={x: 0})
+return Point().y()`, nil, ErrNotCallable)
+	expectErrIs(t, `
+Point := struct("Point", fields={x: 0})
+func __call_missing__(p Point, name, args, namedArgs) => name
+return Point().x()`, nil, ErrNotCallable)
+}
+
+func TestVMMock(t *testing.T) {
+	TestExpectRun(t, `
+log := []
+m := mock(returns={get: (id) => "user" + id, limit: 10}, calls=(c) => {log = append(log, c.name)})
+get := m.get
+return [m.get(1), get(2), m.limit(), m.missing(x=1), m(), m.__calls__, log]`,
+		nil, Array{
+			Str("user1"), Str("user2"), Int(10), Nil, Nil,
+			Array{
+				Dict{"name": Str("get"), "args": Array{Int(1)}, "namedArgs": Dict{}},
+				Dict{"name": Str("get"), "args": Array{Int(2)}, "namedArgs": Dict{}},
+				Dict{"name": Str("limit"), "args": Array{}, "namedArgs": Dict{}},
+				Dict{"name": Str("missing"), "args": Array{}, "namedArgs": Dict{"x": Int(1)}},
+				Dict{"name": Str("__call__"), "args": Array{}, "namedArgs": Dict{}},
+			},
+			Array{Str("get"), Str("get"), Str("limit"), Str("missing"), Str("__call__")},
+		})
+
+	expectErrIs(t, `mock(1)`, nil, ErrWrongNumArguments)
+	expectErrIs(t, `mock(returns=1)`, nil, ErrType)
+}
+
+func TestVMDeepEqualDiff(t *testing.T) {
+	TestExpectRun(t, `return [
+	deepEqual({a: [1, 2, {b: 3}]}, {a: [1, 2, {b: 3}]}),
+	deepEqual({a: [1, 2]}, {a: [1, 2, 3]}),
+	deepEqual({a: 1.0}, {a: 1.05}),
+	deepEqual({a: 1.0}, {a: 1.05}, tolerance=0.1),
+	deepEqual([1, "x"], [1, "x"], tolerance=1),
+	deepEqual([1, "x"], [1, "y"], tolerance=1),
+]`, nil, Array{True, False, False, True, True, False})
+
+	TestExpectRun(t, `return diff({a: [1, 2], b: {c: 1}, d: 1}, {a: [1, 3, 4], b: {c: 1, e: 2}})`,
+		nil, Array{
+			Dict{"path": Array{Str("a"), Int(1)}, "kind": Str("changed"), "a": Int(2), "b": Int(3)},
+			Dict{"path": Array{Str("a"), Int(2)}, "kind": Str("added"), "b": Int(4)},
+			Dict{"path": Array{Str("b"), Str("e")}, "kind": Str("added"), "b": Int(2)},
+			Dict{"path": Array{Str("d")}, "kind": Str("removed"), "a": Int(1)},
+		})
+	TestExpectRun(t, `return [diff([1.0], [1.05], tolerance=0.1), diff(1, "1")]`,
+		nil, Array{
+			Array{},
+			Array{Dict{"path": Array{}, "kind": Str("changed"), "a": Int(1), "b": Str("1")}},
+		})
+	TestExpectRun(t, `P := struct("P", fields={x: 0})
+return [deepEqual(P(x=1), P(x=1)), diff(P(x=1), P(x=2))]`,
+		nil, Array{True, Array{Dict{"path": Array{Str("x")}, "kind": Str("changed"), "a": Int(1), "b": Int(2)}}})
+
+	TestExpectRun(t, `a := {x: 1}; a.y = a; b := {x: 1}; b.y = b; c := {x: 2}; c.y = c
+l := [1, nil]; l[1] = l; m := [1, nil]; m[1] = m
+P := struct("P", fields={n: nil}); p := P(); p.n = p; q := P(); q.n = q
+return [deepEqual(a, b), deepEqual(a, c), diff(a, c)[0].path, len(diff(a, c)), deepEqual(l, m), deepEqual(p, q)]`,
+		nil, Array{True, False, Array{Str("x")}, Int(1), True, True})
+
+	expectErrIs(t, `deepEqual(1)`, nil, ErrWrongNumArguments)
+	expectErrIs(t, `diff(1, 2, tolerance="x")`, nil, ErrType)
+}
+
+func TestVMMergeAndPath(t *testing.T) {
+	TestExpectRun(t, `a := {x: 1, n: {a: 1, l: [1]}, l: [1]}
+b := {y: 2, n: {b: 2, l: [2]}, l: [2]}
+return [merge(a, b), merge(a, b, deep=true), merge(a, b, deep=true, arrays="concat"), a]`,
+		nil, Array{
+			Dict{"x": Int(1), "y": Int(2), "n": Dict{"b": Int(2), "l": Array{Int(2)}}, "l": Array{Int(2)}},
+			Dict{"x": Int(1), "y": Int(2), "n": Dict{"a": Int(1), "b": Int(2), "l": Array{Int(2)}}, "l": Array{Int(2)}},
+			Dict{"x": Int(1), "y": Int(2), "n": Dict{"a": Int(1), "b": Int(2), "l": Array{Int(1), Int(2)}}, "l": Array{Int(1), Int(2)}},
+			Dict{"x": Int(1), "n": Dict{"a": Int(1), "l": Array{Int(1)}}, "l": Array{Int(1)}},
+		})
+	expectErrIs(t, `merge({}, {}, arrays="x")`, nil, ErrUnexpectedArgValue)
+	expectErrIs(t, `merge({}, [])`, nil, ErrType)
+
+	TestExpectRun(t, `return [
+	mergePatch({a: "b", c: {d: "e", f: "g"}}, {a: "z", c: {f: nil}}),
+	mergePatch({a: [1]}, {a: {b: nil, c: 1}}),
+	mergePatch({a: 1}, [2]),
+]`, nil, Array{
+		Dict{"a": Str("z"), "c": Dict{"d": Str("e")}},
+		Dict{"a": Dict{"c": Int(1)}},
+		Array{Int(2)},
+	})
+
+	TestExpectRun(t, `doc := {a: {b: [10, {c: 3}]}, "x/y": 1, "m~n": 2}
+return [getPath(doc, "/a/b/1/c"), getPath(doc, "/a/b/0"), getPath(doc, "/x~1y"), getPath(doc, "/m~0n"),
+	getPath(doc, "/a/b/2"), getPath(doc, "/a/b/01"), getPath(doc, "/nope/x"), getPath(5, "")]`,
+		nil, Array{Int(3), Int(10), Int(1), Int(2), Nil, Nil, Nil, Int(5)})
+	TestExpectRun(t, `doc := {a: {b: [10]}}
+setPath(doc, "/a/b/-", 4)
+setPath(doc, "/a/b/0", 1)
+setPath(doc, "/q/r", 5)
+return [doc, setPath(nil, "/z", 1), setPath([1], "/1", 2)]`,
+		nil, Array{
+			Dict{"a": Dict{"b": Array{Int(1), Int(4)}}, "q": Dict{"r": Int(5)}},
+			Dict{"z": Int(1)},
+			Array{Int(1), Int(2)},
+		})
+	expectErrIs(t, `getPath({}, "a")`, nil, ErrInvalidIndex)
+	expectErrIs(t, `setPath({a: []}, "/a/1", 1)`, nil, ErrIndexOutOfBounds)
+	expectErrIs(t, `setPath({a: 1}, "/a/b", 1)`, nil, ErrNotIndexAssignable)
+}
+
+func TestVMPrintTable(t *testing.T) {
+	TestExpectRun(t, `rows := [{name: "alice", age: 30, city: "Paris"}, {name: "bob", age: 7}]
+b := buffer()
+printTable(rows, out=b)
+printTable(rows, columns=["name", "age"], align={age: "right"}, out=b)
+printTable([[1, "a"], [22, "bbb", true]], align="right", out=b)
+printTable([{name: "christopher"}], maxWidth=5, out=b)
+return str(b)`, nil, Str(`age  city   name
+---  -----  -----
+30   Paris  alice
+7           bob
+name   age
+-----  ---
+alice   30
+bob      7
+ 1    a
+22  bbb  true
+name
+-----
+chri…
+`))
+	expectErrIs(t, `printTable([], align="middle")`, nil, ErrUnexpectedArgValue)
+	expectErrIs(t, `printTable({})`, nil, ErrType)
+
+	TestExpectRun(t, `c := "bold red"; return [str("hi", color=c, bg="blue"), str(1, color="green"), str("x", color="")]`,
+		nil, Array{Str("\x1b[1;31;44mhi\x1b[0m"), Str("\x1b[32m1\x1b[0m"), Str("x")})
+	expectErrIs(t, `c := "pink"; str(1, color=c)`, nil, ErrUnexpectedArgValue)
+	expectErrIs(t, `c := "bold"; str(1, bg=c)`, nil, ErrUnexpectedArgValue)
+
+	TestExpectRun(t, `return str({b: (;y=1, x), a: ["s", 'c']}; sortKeys=true)`,
+		nil, Str(`{a: ["s", 'c'], b: (;x, y=1)}`))
+	TestExpectRun(t, `return str("s"; sortKeys=true), str("s"; canonical=true)`,
+		nil, Array{Str("s"), Str(`"s"`)})
+	TestExpectRun(t, `return str([1, 1u, 1.0, 1.5, 1d, 'c', nil, true, bytes(1, 2), {b: 1, "a b": (;y=1, x=2)}]; canonical=true)`,
+		nil, Str(`[1, 1u, 1.0, 1.5, 1d, 'c', nil, true, bytes(1, 2), {"a b": (;"x"=2, "y"=1), "b": 1}]`))
+	TestExpectRun(t, `a := {x: 1}; a.self = a; return str(a; canonical=true)`,
+		nil, Str(`{"self": ‹↶›, "x": 1}`))
+	TestExpectRun(t, `return str((;b=1, a=2); canonical=true) == str((;a=2, b=1); canonical=true)`,
+		nil, True)
+	expectErrIs(t, `str(1; canonical=1)`, nil, ErrType)
+}
+
+func TestVMProgress(t *testing.T) {
+	TestExpectRun(t, `b := buffer()
+p := progress(4, label="job", out=b)
+p.inc()
+p.inc(2)
+p.inc().done()
+p.inc()
+s := progress(out=b)
+s.inc(3)
+s.done()
+return [str(b), p.current, p.total, p.label]`,
+		nil, Array{Str("job 1/4  25%\njob 3/4  75%\njob 4/4 100%\n3\n"), Int(4), Int(4), Str("job")})
+
+	TestExpectRun(t, `b := buffer()
+sum := 0
+for v in withProgress([1, 2, 3], label="items", out=b) { sum += v }
+for v in withProgress(take(repeatIter(1), 2), total=4, out=b) { }
+for v in withProgress([], out=b) { }
+return [sum, str(b)]`,
+		nil, Array{Int(6), Str("items 1/3  33%\nitems 2/3  66%\nitems 3/3 100%\n1/4  25%\n2/4  50%\n0\n")})
+
+	expectErrIs(t, `progress("x")`, nil, ErrType)
+	expectErrIs(t, `progress(1, 2)`, nil, ErrWrongNumArguments)
+	expectErrIs(t, `progress().inc("x")`, nil, ErrType)
+	expectErrIs(t, `withProgress(1)`, nil, ErrType)
+}
+
 func TestCallerMethod(t *testing.T) {
 	TestExpectRun(t, `
 func f0() {
@@ -3243,22 +3950,48 @@ func TestVMSourceModules(t *testing.T) {
 
 	// cyclic imports
 	// (main) -> mod1 -> mod2 -> mod1
+	expectErrIs(t, `import("mod1")`,
+		NewTestOpts().Module("mod1", `import("mod2")`).
+			Module("mod2", `import("mod1")`), ErrCyclicImport)
 	expectErrHas(t, `import("mod1")`,
 		NewTestOpts().Module("mod1", `import("mod2")`).
-			Module("mod2", `import("mod1")`).CompilerError(),
-		"Compile Error: cyclic module import: mod1\n\tat mod2:1:1")
+			Module("mod2", `import("mod1")`),
+		"CyclicImportError: module 'mod1' is imported before it is initialized")
 	// (main) -> mod1 -> mod2 -> mod3 -> mod1
 	expectErrHas(t, `import("mod1")`,
 		NewTestOpts().Module("mod1", `import("mod2")`).
 			Module("mod2", `import("mod3")`).
-			Module("mod3", `import("mod1")`).CompilerError(),
-		"Compile Error: cyclic module import: mod1\n\tat mod3:1:1")
+			Module("mod3", `import("mod1")`),
+		"CyclicImportError: module 'mod1' is imported before it is initialized")
 	// (main) -> mod1 -> mod2 -> mod3 -> mod2
 	expectErrHas(t, `import("mod1")`,
 		NewTestOpts().Module("mod1", `import("mod2")`).
 			Module("mod2", `import("mod3")`).
-			Module("mod3", `import("mod2")`).CompilerError(),
-		"Compile Error: cyclic module import: mod2\n\tat mod3:1:1")
+			Module("mod3", `import("mod2")`),
+		"CyclicImportError: module 'mod2' is imported before it is initialized")
+	// mod1 -> mod1
+	expectErrIs(t, `import("mod1")`,
+		NewTestOpts().Module("mod1", `import("mod1")`), ErrCyclicImport)
+	// function level cycles are resolved lazily
+	TestExpectRun(t, `return import("even").isEven(10)`,
+		NewTestOpts().
+			Module("even", `
+odd := import("odd")
+return {isEven: func(n) { return n == 0 ? true : odd.isOdd(n-1) }}`).
+			Module("odd", `
+return {isOdd: func(n) { return n == 0 ? false : import("even").isEven(n-1) }}`),
+		True)
+	TestExpectRun(t, `return [import("mod1").f(), import("mod2").g()]`,
+		NewTestOpts().
+			Module("mod1", `return {f: func() { return import("mod2").name }, name: "mod1"}`).
+			Module("mod2", `return {g: func() { return import("mod1").name }, name: "mod2"}`),
+		Array{Str("mod2"), Str("mod1")})
+	// using a module while it is being initialized fails
+	expectErrIs(t, `import("mod1")`,
+		NewTestOpts().
+			Module("mod1", `m2 := import("mod2"); x := m2.f(); return {x: x}`).
+			Module("mod2", `return {f: func() { return import("mod1").x }}`),
+		ErrCyclicImport)
 
 	// unknown modules
 	expectErrHas(t, `import("mod0")`,
@@ -3632,6 +4365,12 @@ func TestVMStackOverflow(t *testing.T) {
 func TestVMString(t *testing.T) {
 	TestExpectRun(t, `return "Hello World!"`, nil, Str("Hello World!"))
 	TestExpectRun(t, `return "Hello" + " " + "World!"`, nil, Str("Hello World!"))
+	TestExpectRun(t, `s := ""; for i := 0; i < 1000; i++ { s += "part" }; return s`,
+		nil, Str(strings.Repeat("part", 1000)))
+	TestExpectRun(t, `s := ""; for i := 0; i < 100; i++ { s += "part" }
+	a := s + "a"; b := s + "b"; s += "c"; return [a, b, s]`,
+		nil, Array{Str(strings.Repeat("part", 100) + "a"), Str(strings.Repeat("part", 100) + "b"),
+			Str(strings.Repeat("part", 100) + "c")})
 
 	TestExpectRun(t, `return "Hello" == "Hello"`, nil, True)
 	TestExpectRun(t, `return "Hello" == "World"`, nil, False)
@@ -4637,3 +5376,993 @@ func (s *srwcloser) Close() error {
 	s.Closed = true
 	return nil
 }
+
+func TestVMSuspend(t *testing.T) {
+	bc, err := Compile([]byte(`
+total := 0
+add := func(n) { total += n; return total }
+f := func(x) {
+	try {
+		y := suspend(x * 2)
+		return add(y + 1)
+	} finally {
+		total += 100
+	}
+}
+a := f(1)
+b := suspend("second")
+return [a, b, total]`), CompileOptions{})
+	require.NoError(t, err)
+
+	vm := NewVM(bc)
+	_, err = vm.Run()
+	require.ErrorIs(t, err, ErrVMSuspended)
+	s := vm.Snapshot()
+	require.NotNil(t, s)
+	require.Equal(t, Int(2), s.Value)
+
+	vm = NewVM(bc)
+	_, err = vm.Resume(s, Int(10), nil)
+	require.ErrorIs(t, err, ErrVMSuspended)
+	s = vm.Snapshot()
+	require.Equal(t, Str("second"), s.Value)
+
+	ret, err := NewVM(bc).Resume(s, Str("x"), nil)
+	require.NoError(t, err)
+	require.Equal(t, Array{Int(11), Str("x"), Int(111)}, ret)
+
+	// malformed snapshots are not restored
+	frame := *s.Frames[0]
+	for _, bad := range []*Snapshot{
+		{Stack: make([]Object, 3000), Frames: s.Frames},
+		{Stack: s.Stack, Frames: make([]*SnapshotFrame, 2000)},
+		{Stack: s.Stack, Frames: []*SnapshotFrame{nil}},
+		{Stack: s.Stack, Frames: []*SnapshotFrame{{Fn: frame.Fn, IP: 100000}}},
+		{Stack: s.Stack, Frames: []*SnapshotFrame{{Fn: frame.Fn, BasePointer: 3000}}},
+		{Stack: s.Stack, Frames: []*SnapshotFrame{{Fn: frame.Fn, Handlers: []SnapshotErrHandler{{Catch: -1}}}}},
+	} {
+		_, err = NewVM(bc).Resume(bad, Nil, nil)
+		require.ErrorContains(t, err, "invalid Snapshot")
+	}
+
+	// suspend is not allowed in functions invoked by Go
+	expectErrHas(t, `return collect(map([1], func(v, _) { return suspend(v) }))`, nil,
+		"suspend is not allowed in a function invoked by Go")
+	expectErrIs(t, `suspend(1, 2)`, nil, ErrWrongNumArguments)
+}
+
+func TestVMSafepoint(t *testing.T) {
+	bc, err := Compile([]byte(`for {}`), CompileOptions{})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	vm := NewVM(bc).Setup(SetupOpts{Context: ctx, SafepointInterval: 100})
+	_, err = vm.Run()
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+
+	var calls int
+	errStop := errors.New("stop")
+	vm = NewVM(bc).Setup(SetupOpts{
+		SafepointInterval: 10,
+		Safepoint: func(vm *VM) error {
+			if calls++; calls == 3 {
+				return errStop
+			}
+			return SafepointYield(vm)
+		},
+	})
+	_, err = vm.Run()
+	require.ErrorIs(t, err, errStop)
+	require.Equal(t, 3, calls)
+
+	// safepoint errors cannot be caught
+	bc, err = Compile([]byte(`try { for {} } catch err { return err }`), CompileOptions{})
+	require.NoError(t, err)
+	vm = NewVM(bc).Setup(SetupOpts{
+		SafepointInterval: 1,
+		Safepoint:         func(vm *VM) error { return errStop },
+	})
+	_, err = vm.Run()
+	require.ErrorIs(t, err, errStop)
+
+	// safepoints apply to functions invoked by Go
+	bc, err = Compile([]byte(`return collect(map([1], func(v, _) { for {} }))`), CompileOptions{})
+	require.NoError(t, err)
+	calls = 0
+	vm = NewVM(bc).Setup(SetupOpts{
+		SafepointInterval: 5,
+		Safepoint: func(vm *VM) error {
+			if calls++; calls == 10 {
+				return errStop
+			}
+			return nil
+		},
+	})
+	_, err = vm.Run()
+	require.ErrorIs(t, err, errStop)
+}
+
+func TestVMBudget(t *testing.T) {
+	bc, err := Compile([]byte(`
+return {
+	loop: func() { for {} },
+	sum: func(n) { s := 0; for i := 0; i < n; i++ { s += i }; return s },
+}`), CompileOptions{})
+	require.NoError(t, err)
+
+	vm := NewVM(bc)
+	f, err := vm.Run()
+	require.NoError(t, err)
+	loop := f.(Dict)["loop"].(*CompiledFunction)
+	sum := f.(Dict)["sum"].(*CompiledFunction)
+
+	_, err = vm.RunCompiledFunctionOpts(loop, &RunOpts{Budget: &Budget{MaxOps: 1000}})
+	var budgetErr *BudgetExceededError
+	require.ErrorAs(t, err, &budgetErr)
+	require.False(t, budgetErr.Timeout)
+	require.Equal(t, 1000, budgetErr.Ops)
+	require.Equal(t, "BudgetExceededError: max ops 1000 exceeded", err.Error())
+
+	_, err = vm.RunCompiledFunctionOpts(loop, &RunOpts{Budget: &Budget{Timeout: 10 * time.Millisecond}})
+	require.ErrorAs(t, err, &budgetErr)
+	require.True(t, budgetErr.Timeout)
+	require.Equal(t, "BudgetExceededError: timeout 10ms exceeded", err.Error())
+
+	// budget is per run
+	b := &Budget{MaxOps: 1000}
+	for i := 0; i < 3; i++ {
+		ret, err := vm.RunCompiledFunctionOpts(sum, &RunOpts{Args: Args{Array{Int(10)}}, Budget: b})
+		require.NoError(t, err)
+		require.Equal(t, Int(45), ret)
+	}
+	_, err = vm.RunCompiledFunctionOpts(sum, &RunOpts{Args: Args{Array{Int(1000)}}, Budget: b})
+	require.ErrorAs(t, err, &budgetErr)
+
+	// budget errors cannot be caught
+	bc2, err := Compile([]byte(`try { for {} } catch err { return err }`), CompileOptions{})
+	require.NoError(t, err)
+	_, err = NewVM(bc2).RunOpts(&RunOpts{Budget: &Budget{MaxOps: 100}})
+	require.ErrorAs(t, err, &budgetErr)
+
+	inv := NewInvoker(vm, sum).Budget(b)
+	ret, err := inv.Invoke(Args{Array{Int(10)}}, nil)
+	require.NoError(t, err)
+	require.Equal(t, Int(45), ret)
+	_, err = inv.Invoke(Args{Array{Int(1000)}}, nil)
+	require.ErrorAs(t, err, &budgetErr)
+
+	caller, err := NewInvoker(vm, sum).Budget(b).Caller(Args{Array{Int(1000)}}, nil)
+	require.NoError(t, err)
+	_, err = caller.Call()
+	require.ErrorAs(t, err, &budgetErr)
+	// callbacks of builtins are counted in the budget of the run
+	for _, src := range []string{
+		`each([1], func(v, k) { for i := 0; i < 10000000; i++ {} })`,
+		`collect(map([1], func(v, k) { for {} }))`,
+		`each([1, 2, 3], func(v, k) { collect(map([1], func(v, k) { for i := 0; i < 10000000; i++ {} })) })`,
+	} {
+		bc3, err := Compile([]byte(src), CompileOptions{})
+		require.NoError(t, err)
+		_, err = NewVM(bc3).RunOpts(&RunOpts{Budget: &Budget{MaxOps: 1000}})
+		require.ErrorAs(t, err, &budgetErr, src)
+		require.False(t, budgetErr.Timeout, src)
+		_, err = NewVM(bc3).RunOpts(&RunOpts{Budget: &Budget{Timeout: 10 * time.Millisecond}})
+		require.ErrorAs(t, err, &budgetErr, src)
+		require.True(t, budgetErr.Timeout, src)
+	}
+}
+
+func TestVMAbortGracePeriod(t *testing.T) {
+	run := func(script string, grace time.Duration) (Dict, error) {
+		t.Helper()
+		bc, err := Compile([]byte(script), CompileOptions{})
+		require.NoError(t, err)
+		vm := NewVM(bc).Setup(SetupOpts{AbortGracePeriod: grace})
+		out := Dict{"log": Array{}}
+		time.AfterFunc(10*time.Millisecond, vm.Abort)
+		_, err = vm.RunOpts(&RunOpts{Globals: Dict{"out": out}})
+		return out, err
+	}
+
+	out, err := run(`global out
+	try { for {} } finally { out.closed = true }`, time.Second)
+	require.ErrorIs(t, err, ErrVMAborted)
+	require.Equal(t, True, out["closed"])
+
+	out, err = run(`global out
+	try { for {} } finally { out.closed = true }`, 0)
+	require.ErrorIs(t, err, ErrVMAborted)
+	require.Nil(t, out["closed"])
+
+	out, err = run(`global out
+	try { for {} } catch e { out.caught = true } finally { out.closed = true }`, time.Second)
+	require.ErrorIs(t, err, ErrVMAborted)
+	require.Nil(t, out["caught"])
+	require.Equal(t, True, out["closed"])
+
+	out, err = run(`global out
+	f := func() {
+		try { for {} } finally { out.log = append(out.log, "inner") }
+	}
+	try { f() } catch e { out.caught = true } finally { out.log = append(out.log, "outer") }`, time.Second)
+	require.ErrorIs(t, err, ErrVMAborted)
+	require.Nil(t, out["caught"])
+	require.Equal(t, Array{Str("inner"), Str("outer")}, out["log"])
+
+	// finally returning a value does not hide abort
+	_, err = run(`func() { try { for {} } finally { return 1 } }()`, time.Second)
+	require.ErrorIs(t, err, ErrVMAborted)
+
+	// hard deadline stops long running finally blocks
+	start := time.Now()
+	out, err = run(`global out
+	try { for {} } finally { out.started = true; for {} }`, 20*time.Millisecond)
+	require.ErrorIs(t, err, ErrVMAborted)
+	require.Equal(t, True, out["started"])
+	require.Less(t, time.Since(start), time.Second)
+}
+
+func TestVMRetry(t *testing.T) {
+	TestExpectRun(t, `n := 0; return [retry(func() { n++; return "ok" }), n]`,
+		nil, Array{Str("ok"), Int(1)})
+	TestExpectRun(t, `n := 0; return [retry(func() { n++; if n < 3 { throw "fail" }; return n }; backoff=1000), n]`,
+		nil, Array{Int(3), Int(3)})
+	TestExpectRun(t, `n := 0; try { retry(func() { n++; throw "fail" + n }; attempts=4, backoff=1000, jitter=0.5) } catch err { return [err.Message, n] }`,
+		nil, Array{Str("fail4"), Int(4)})
+	TestExpectRun(t, `n := 0; try { retry(func() { n++; [][n] }; retryIf=func(e) => e.Literal != "IndexOutOfBoundsError") } catch err { return [err.Literal, n] }`,
+		nil, Array{Str("IndexOutOfBoundsError"), Int(1)})
+	TestExpectRun(t, `n := 0; return retry(func() { n++; if n == 1 { throw "x" }; return n }; retryIf=func(e) => e.Message == "x")`,
+		nil, Int(2))
+	expectErrHas(t, `retry(func() {}; attempts=0)`, nil, `expected positive integer`)
+
+	var (
+		exit  *ExitError
+		calls int
+	)
+	globals := Dict{"count": &Function{Value: func(Call) (Object, error) {
+		calls++
+		return Nil, nil
+	}}}
+	c, err := Compile([]byte(`global count
+	retry(func() { count(); exit(3) }; backoff=int(1e9), retryIf=func(e) { count(); return true })`), CompileOptions{})
+	require.NoError(t, err)
+	_, err = NewVM(c).RunOpts(&RunOpts{Globals: globals})
+	require.ErrorAs(t, err, &exit)
+	require.Equal(t, 3, exit.Code)
+	require.Equal(t, 1, calls)
+	expectErrHas(t, `retry(1)`, nil, `expected callable`)
+
+	c, err = Compile([]byte(`retry(func() { throw "x" }; attempts=100, backoff=int(1e9))`), CompileOptions{})
+	require.NoError(t, err)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err = NewVM(c).Setup(SetupOpts{Context: ctx}).Run()
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	require.Less(t, time.Since(start), time.Second)
+}
+
+func TestVMTaskGroup(t *testing.T) {
+	TestExpectRun(t, `g := group(); return g.wait()`, nil, Array{})
+	TestExpectRun(t, `g := group()
+	for i := 0; i < 5; i++ { g.go(func(x) => x * 2, i) }
+	return g.wait()`, nil, Array{Int(0), Int(2), Int(4), Int(6), Int(8)})
+	TestExpectRun(t, `g := group(;limit=2)
+	for i := 0; i < 5; i++ { g.go(func(x; y=0) => x + y, i; y=10) }
+	return g.wait()`, nil, Array{Int(10), Int(11), Int(12), Int(13), Int(14)})
+	TestExpectRun(t, `g := group(); g.go(str, 1); return g.wait()`, nil, Array{Str("1")})
+	TestExpectRun(t, `g := group()
+	g.go(func() { for {} })
+	g.go(func() { throw "fail" })
+	try { g.wait() } catch err { return err.Message }`, nil, Str("fail"))
+	TestExpectRun(t, `g := group()
+	g.go(func() { [][1] })
+	try { g.wait() } catch err { return err.Literal }`, nil, Str("IndexOutOfBoundsError"))
+	expectErrHas(t, `group().go(1)`, nil, `expected callable`)
+	expectErrHas(t, `group(1)`, nil, `WrongNumberOfArgumentsError`)
+
+	c, err := Compile([]byte(`g := group(); g.go(func() { for {} }); g.wait()`), CompileOptions{})
+	require.NoError(t, err)
+	vm := NewVM(c)
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		vm.Abort()
+	}()
+	_, err = vm.Run()
+	require.ErrorIs(t, err, ErrVMAborted)
+}
+
+func TestVMSyncObjects(t *testing.T) {
+	TestExpectRun(t, `m := mutex(); n := 0; g := group()
+	for i := 0; i < 20; i++ { g.go(func() { m.lock(); n++; m.unlock() }) }
+	g.wait(); return n`, nil, Int(20))
+	TestExpectRun(t, `m := mutex(); return [m.tryLock(), m.tryLock(), m.unlock(), m.tryLock()]`,
+		nil, Array{True, False, Nil, True})
+	TestExpectRun(t, `m := mutex(); return [m.call(func(a; b=0) => a + b, 1; b=2), m.tryLock()]`,
+		nil, Array{Int(3), True})
+	TestExpectRun(t, `m := rwmutex(); m.rlock(); m.rlock(); r := [m.tryLock(), m.tryRLock()]
+	m.runlock(); m.runlock(); m.runlock(); return r + [m.tryLock(), m.tryRLock()]`,
+		nil, Array{False, True, True, False})
+	TestExpectRun(t, `m := rwmutex(); return [m.rcall(func() => 1), m.call(func() => 2)]`,
+		nil, Array{Int(1), Int(2)})
+	TestExpectRun(t, `wg := waitgroup(); n := 0; m := mutex(); g := group()
+	wg.add(3)
+	for i := 0; i < 3; i++ { g.go(func() { m.call(func() { n++ }); wg.done() }) }
+	wg.wait(); return n`, nil, Int(3))
+	TestExpectRun(t, `wg := waitgroup(); wg.wait(); return 1`, nil, Int(1))
+	TestExpectRun(t, `o := once(); n := 0; g := group()
+	for i := 0; i < 5; i++ { g.go(func() => o.call(func() { n++; return n })) }
+	return [g.wait(), n, o.done()]`, nil, Array{Array{Int(1), Int(1), Int(1), Int(1), Int(1)}, Int(1), True})
+	TestExpectRun(t, `o := once(); try { o.call(func() { throw "x" }) } catch {}
+	try { o.call(func() => 1) } catch err { return err.Message }`, nil, Str("x"))
+	expectErrHas(t, `mutex().unlock()`, nil, `unlock of unlocked mutex`)
+	expectErrHas(t, `waitgroup().done()`, nil, `negative waitgroup counter`)
+
+	run := func(src string) error {
+		c, err := Compile([]byte(src), CompileOptions{})
+		require.NoError(t, err)
+		_, err = NewVM(c).Setup(SetupOpts{DeadlockTimeout: 50 * time.Millisecond}).Run()
+		return err
+	}
+	err := run(`m := mutex(); m.lock(); m.lock()`)
+	require.ErrorIs(t, err, ErrDeadlock)
+	require.Contains(t, err.Error(), "already held by the same VM")
+	err = run(`m := mutex(); m.lock(); g := group(); g.go(func() { m.lock() }); g.wait()`)
+	require.ErrorIs(t, err, ErrDeadlock)
+	err = run(`wg := waitgroup(); wg.add(); wg.wait()`)
+	require.ErrorIs(t, err, ErrDeadlock)
+	err = run(`o := once(); o.call(func() { o.call(func() {}) })`)
+	require.ErrorIs(t, err, ErrDeadlock)
+	err = run(`try { m := mutex(); m.lock(); m.lock() } catch { if recover() == nil { throw "not a panic" } }`)
+	require.NoError(t, err)
+}
+
+func TestVMSleepTickerAfter(t *testing.T) {
+	TestExpectRun(t, `sleep(1000); sleep("1ms"); return 1`, nil, Int(1))
+	TestExpectRun(t, `return collect(keys(take(ticker("1ms"), 3)))`, nil, Array{Int(0), Int(1), Int(2)})
+	TestExpectRun(t, `return collect(map(take(ticker(1000000), 2), (v, _) => v >= 1000000))`, nil, Array{True, True})
+	TestExpectRun(t, `t := after("1ms", func(a; b=0) => a + b, 1; b=2); return t.wait()`, nil, Int(3))
+	TestExpectRun(t, `t := after("1h", func() => 1); return [t.stop(), t.stop(), t.wait()]`,
+		nil, Array{True, False, Nil})
+	TestExpectRun(t, `t := after(0, func() { throw "x" }); try { t.wait() } catch err { return [err.Message, t.stop()] }`,
+		nil, Array{Str("x"), False})
+	expectErrHas(t, `sleep([])`, nil, `expected int|str`)
+	expectErrHas(t, `sleep("x")`, nil, `expected int|str`)
+	expectErrHas(t, `ticker(0)`, nil, `expected positive duration`)
+	expectErrHas(t, `after(1, 2)`, nil, `expected callable`)
+
+	for _, src := range []string{
+		`sleep("1h")`,
+		`for _ in ticker("1h") {}`,
+		`after("1h", func() {}).wait()`,
+	} {
+		c, err := Compile([]byte(src), CompileOptions{})
+		require.NoError(t, err)
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		start := time.Now()
+		_, err = NewVM(c).Setup(SetupOpts{Context: ctx}).Run()
+		cancel()
+		require.ErrorIs(t, err, context.DeadlineExceeded, src)
+		require.Less(t, time.Since(start), time.Second, src)
+
+		vm := NewVM(c)
+		go func() {
+			time.Sleep(20 * time.Millisecond)
+			vm.Abort()
+		}()
+		_, err = vm.Run()
+		require.ErrorIs(t, err, ErrVMAborted, src)
+	}
+}
+
+func TestVMAfterRunEnd(t *testing.T) {
+	var calls atomic.Int32
+	globals := Dict{"count": &Function{Value: func(Call) (Object, error) {
+		calls.Add(1)
+		return Nil, nil
+	}}}
+	for _, src := range []string{
+		`global count; after("10ms", count); return 1`,
+		`global count; after("10ms", func() { count() }); throw "x"`,
+		`global count; each([1], func(v, k) { after("10ms", count) })`,
+		`global count; t := after("10ms", count); t.wait(); after("10ms", count)`,
+	} {
+		calls.Store(0)
+		c, err := Compile([]byte(src), CompileOptions{})
+		require.NoError(t, err)
+		vm := NewVM(c)
+		_, _ = vm.RunOpts(&RunOpts{Globals: globals})
+		n := calls.Load()
+		time.Sleep(50 * time.Millisecond)
+		require.Equal(t, n, calls.Load(), src)
+	}
+
+	// pending timers are stopped when VM is aborted
+	c, err := Compile([]byte(`global count; after("10ms", count); for {}`), CompileOptions{})
+	require.NoError(t, err)
+	calls.Store(0)
+	vm := NewVM(c)
+	time.AfterFunc(2*time.Millisecond, vm.Abort)
+	_, err = vm.RunOpts(&RunOpts{Globals: globals})
+	require.ErrorIs(t, err, ErrVMAborted)
+	time.Sleep(50 * time.Millisecond)
+	require.Equal(t, int32(0), calls.Load())
+}
+
+func TestVMEvents(t *testing.T) {
+	TestExpectRun(t, `e := events(); out := []
+	f := func(x; y=0) { out = append(out, x + y) }
+	e.on("a", f)
+	e.on("a", func(x; y=0) { out = append(out, -x) })
+	e.once("b", f)
+	r := [e.emit("a", 1; y=10), e.emit("b", 2), e.emit("b", 3), e.emit("c"), e.names()]
+	r = append(r, e.off("a", f), len(e.listeners("a")), e.off("a"), e.names())
+	return [r, out]`, nil, Array{
+		Array{Int(2), Int(1), Int(0), Int(0), Array{Str("a")},
+			Int(1), Int(1), Int(1), Array{}},
+		Array{Int(11), Int(-1), Int(2)},
+	})
+	TestExpectRun(t, `e := events(); m := mutex(); n := 0
+	for i := 0; i < 3; i++ { e.on("x", func(d) { m.call(func() { n += d }) }) }
+	e.emit("x", 2; async=true).wait()
+	return n`, nil, Int(6))
+	TestExpectRun(t, `e := events(); e.on("x", func() { throw "fail" }); e.on("x", func() { throw "other" })
+	try { e.emit("x") } catch err { return err.Message }`, nil, Str("fail"))
+	expectErrHas(t, `events().on(1, func() {})`, nil, `expected str`)
+	expectErrHas(t, `events().on("a", 1)`, nil, `expected callable`)
+}
+
+func TestVMOnCall(t *testing.T) {
+	src := `
+	func add(a, b) { return a + b }
+	func fail() { throw "x" }
+	func outer() { fail() }
+	for i := 0; i < 4; i++ { add(i, 1) }
+	try { outer() } catch _ {}
+	return len([1])`
+	c, err := Compile([]byte(src), CompileOptions{})
+	require.NoError(t, err)
+
+	var (
+		calls []string
+		errs  = map[string]error{}
+	)
+	onCall := func(info CallInfo, d time.Duration, err error) {
+		require.GreaterOrEqual(t, d, time.Duration(0))
+		calls = append(calls, info.Name)
+		if err != nil {
+			errs[info.Name] = err
+		}
+	}
+
+	ret, err := NewVM(c).Setup(SetupOpts{OnCall: onCall}).Run()
+	require.NoError(t, err)
+	require.Equal(t, Int(1), ret)
+	require.Equal(t, []string{"add", "add", "add", "add", "fail", "outer", "len"}, calls)
+	require.Len(t, errs, 2)
+	require.ErrorContains(t, errs["fail"], "x")
+	require.ErrorContains(t, errs["outer"], "x")
+
+	calls = nil
+	_, err = NewVM(c).Setup(SetupOpts{OnCall: onCall, OnCallSampleRate: 3}).Run()
+	require.NoError(t, err)
+	require.Equal(t, []string{"add", "fail"}, calls)
+}
+
+func TestVMAudit(t *testing.T) {
+	type point struct {
+		X int
+	}
+
+	mm := NewModuleMap()
+	mm.AddBuiltinModule("mod", map[string]Object{
+		"double": &Function{Name: "double", Value: func(c Call) (Object, error) {
+			return c.Args.GetOnly(0).(Int) * 2, nil
+		}},
+	})
+	mm.AddSourceModule("src", []byte(`return {one: func() => 1}`))
+
+	src := `global (g, p)
+	mod := import("mod")
+	s := import("src")
+	g = mod.double(s.one())
+	p.X = 5
+	func() { import("mod") }()
+	return [g, p.X]`
+	c, err := Compile([]byte(src), CompileOptions{CompilerOptions: CompilerOptions{ModuleMap: mm}})
+	require.NoError(t, err)
+
+	pt := &point{}
+	rv, err := NewReflectValue(pt)
+	require.NoError(t, err)
+
+	var log AuditLog
+	ret, err := NewVM(c).Setup(SetupOpts{Audit: log.Record}).RunOpts(&RunOpts{
+		Globals: Dict{"p": rv},
+	})
+	require.NoError(t, err)
+	require.Equal(t, Array{Int(2), Int(5)}, ret)
+	require.Equal(t, 5, pt.X)
+
+	var got []string
+	for _, e := range log.Events() {
+		s := fmt.Sprintf("%d:%s %s", e.Pos.Line, e.Kind, e.Name)
+		if e.Index != nil {
+			s += " " + e.Index.ToString()
+		}
+		if e.Value != nil {
+			s += " = " + e.Value.ToString()
+		}
+		got = append(got, s)
+	}
+	require.Equal(t, []string{
+		"2:import mod",
+		"3:import src",
+		"4:call mod.double = [1]",
+		"4:global g = 2",
+		"5:reflect  X = 5",
+		"6:import mod",
+	}, got)
+}
+
+func TestVMDeprecations(t *testing.T) {
+	mm := NewModuleMap()
+	mm.AddBuiltinModule("mod", map[string]Object{
+		"old": &Function{Name: "old", Value: func(c Call) (Object, error) {
+			return Int(1), nil
+		}},
+		"new": &Function{Name: "new", Value: func(c Call) (Object, error) {
+			return Int(2), nil
+		}},
+	})
+	deprecations := Deprecations{}.
+		Deprecate("len", "use size instead").
+		Deprecate("mod.old", "")
+
+	src := `mod := import("mod")
+	a := len([1]) + len([2])
+	b := mod.old() + mod.new()
+	f := func() { return import("mod").old() }
+	return [a, b, f()]`
+
+	var warnings []string
+	c, err := Compile([]byte(src), CompileOptions{CompilerOptions: CompilerOptions{
+		ModuleMap:    mm,
+		Deprecations: deprecations,
+		Warn: func(w *CompilerWarning) {
+			warnings = append(warnings, w.String())
+		},
+	}})
+	require.NoError(t, err)
+	require.Equal(t, []string{
+		"(main):2:7: 'len' is deprecated: use size instead",
+		"(main):2:18: 'len' is deprecated: use size instead",
+		"(main):3:11: 'mod.old' is deprecated",
+		"(main):4:37: 'mod.old' is deprecated",
+	}, warnings)
+
+	var events []string
+	ret, err := NewVM(c).Setup(SetupOpts{
+		Deprecations: deprecations,
+		OnDeprecated: func(e DeprecationEvent) {
+			events = append(events, fmt.Sprintf("%d:%s", e.Pos.Line, e.Message))
+		},
+	}).RunOpts(&RunOpts{})
+	require.NoError(t, err)
+	require.Equal(t, Array{Int(2), Int(3), Int(1)}, ret)
+	require.Equal(t, []string{
+		"2:'len' is deprecated: use size instead",
+		"3:'mod.old' is deprecated",
+	}, events)
+}
+
+func TestVMTaint(t *testing.T) {
+	mm := NewModuleMap()
+	mm.AddBuiltinModule("http", map[string]Object{
+		"post": &Function{Name: "post", Value: func(c Call) (Object, error) {
+			return c.Args.GetOnly(0), nil
+		}},
+	})
+
+	run := func(policy *TaintPolicy, src string) (Object, string, error) {
+		c, err := Compile([]byte(`global (email, names); http := import("http");`+src),
+			CompileOptions{CompilerOptions: CompilerOptions{ModuleMap: mm}})
+		require.NoError(t, err)
+		var out bytes.Buffer
+		ret, err := NewVM(c).Setup(SetupOpts{Taint: policy}).RunOpts(&RunOpts{
+			Globals: Dict{
+				"email": Taint(Str("a@b.c")),
+				"names": Taint(Array{Str("x"), Str("y")}),
+			},
+			StdOut: &out,
+		})
+		return ret, out.String(), err
+	}
+
+	ret, _, err := run(&TaintPolicy{}, `
+	values := []
+	for v in names {
+		values = append(values, v)
+	}
+	return [
+		"to: " + email,
+		sprintf("<%s>", email),
+		email[0:1],
+		{e: email}.e,
+		-len(email),
+		values[0],
+		http.post("x" + "y"),
+		email == "a@b.c",
+		"a@b.c" != email,
+		str(email) + "!",
+	]`)
+	require.NoError(t, err)
+	var tainted []bool
+	for _, v := range ret.(Array) {
+		tainted = append(tainted, IsTainted(v))
+	}
+	require.Equal(t, []bool{true, true, true, true, true, true, false, false, false, true}, tainted)
+	v, ok := Untaint(ret)
+	require.True(t, ok)
+	require.Equal(t, Array{Str("to: a@b.c"), Str("<a@b.c>"), Str("a"), Str("a@b.c"), Int(-5), Str("x"),
+		Str("xy"), True, False, Str("a@b.c!")}, v)
+
+	_, out, err := run(&TaintPolicy{}, `println("hi"); http.post("x")`)
+	require.NoError(t, err)
+	require.Equal(t, "hi\n", out)
+
+	for _, src := range []string{
+		`println("to: " + email)`,
+		`printf("%s", {e: email})`,
+		`http.post([email])`,
+		`http.post(1; body=email)`,
+		`println((;a=email))`,
+		`println(deque([email]))`,
+		`d := {}; d[email] = 1`,
+		`d := {}; d[names[0]] = 1`,
+		`each([email], func(v, k) { println(v) })`,
+		`each({e: email}, func(v, k) { http.post(v) })`,
+		`each(names, println)`,
+		`collect(map([email], func(v, k) { println(v) }))`,
+		`collect(filter(names, func(v, k, _) { println(v); return true }))`,
+		`reduce([email], func(a, v, k) { println(v) }, "")`,
+	} {
+		_, _, err = run(&TaintPolicy{}, src)
+		require.ErrorIs(t, err, ErrTaint, src)
+	}
+
+	_, _, err = run(&TaintPolicy{Approved: []string{"http.post"}}, `return http.post(email)`)
+	require.NoError(t, err)
+	_, _, err = run(&TaintPolicy{Sinks: []string{"http"}}, `println(email); try { http.post(email) } catch err { throw err }`)
+	require.ErrorIs(t, err, ErrTaint)
+
+	var violations []string
+	_, out, err = run(&TaintPolicy{Log: func(v TaintViolation) {
+		violations = append(violations, fmt.Sprintf("%d:%s", v.Pos.Line, v.Sink))
+	}}, `println(email)
+	http.post(email)`)
+	require.NoError(t, err)
+	require.Equal(t, "a@b.c\n", out)
+	require.Equal(t, []string{"1:println", "2:http.post"}, violations)
+
+	// methods of Go objects are sinks by their type names
+	_, _, err = run(&TaintPolicy{Sinks: []string{"deque.push"}}, `deque().push(email)`)
+	require.ErrorIs(t, err, ErrTaint)
+	_, _, err = run(&TaintPolicy{Sinks: []string{"deque.push"}}, `deque().pushLeft(email)`)
+	require.NoError(t, err)
+
+	violations = nil
+	ret, _, err = run(&TaintPolicy{Log: func(v TaintViolation) {
+		violations = append(violations, v.Sink)
+	}}, `d := {}; d[email] = 1; return d`)
+	require.NoError(t, err)
+	require.Equal(t, Dict{"a@b.c": Int(1)}, ret)
+	require.Equal(t, []string{"dict key"}, violations)
+
+	require.True(t, IsTainted(KeyValueArray{{K: Str("a"), V: Taint(Str("b"))}}))
+	v, ok = Untaint(KeyValueArray{{K: Taint(Str("a")), V: Str("b")}})
+	require.True(t, ok)
+	require.Equal(t, KeyValueArray{{K: Str("a"), V: Str("b")}}, v)
+	v, ok = Untaint(NewDeque(0, Taint(Str("a"))))
+	require.True(t, ok)
+	require.Equal(t, Array{Str("a")}, v.(*Deque).Values())
+}
+
+func TestVMExit(t *testing.T) {
+	run := func(src string) (Object, Array, error) {
+		var log Array
+		c, err := Compile([]byte(src), CompileOptions{})
+		require.NoError(t, err)
+		ret, err := NewVM(c).RunOpts(&RunOpts{Globals: Dict{"log": &Function{
+			Name: "log",
+			Value: func(c Call) (Object, error) {
+				log = append(log, c.Args.Get(0))
+				return Nil, nil
+			},
+		}}})
+		return ret, log, err
+	}
+
+	_, log, err := run(`global log
+	f := func() {
+		try {
+			exit(3)
+		} catch err {
+			log("catch")
+		} finally {
+			log("finally f")
+		}
+		log("after f")
+	}
+	try {
+		f()
+	} catch err {
+		log("catch main")
+	} finally {
+		log("finally main")
+	}
+	log("after main")`)
+	var exit *ExitError
+	require.ErrorAs(t, err, &exit)
+	require.Equal(t, 3, exit.Code)
+	require.ErrorIs(t, err, ErrExit)
+	require.Equal(t, "ExitError: exit status 3", err.Error())
+	require.Equal(t, Array{Str("finally f"), Str("finally main")}, log)
+
+	_, _, err = run(`exit()`)
+	require.ErrorAs(t, err, &exit)
+	require.Equal(t, 0, exit.Code)
+
+	_, _, err = run(`exit("a")`)
+	require.NotErrorIs(t, err, ErrExit)
+	require.ErrorIs(t, err, ErrType)
+
+	ret, _, err := run(`try { exit("a") } catch err { return "caught" }`)
+	require.NoError(t, err)
+	require.Equal(t, Str("caught"), ret)
+}
+
+type lineDebugger func(vm *VM, pos parser.SourceFilePos) error
+
+func (d lineDebugger) Line(vm *VM, pos parser.SourceFilePos) error {
+	return d(vm, pos)
+}
+
+func TestVMDebugger(t *testing.T) {
+	src := `global g
+	add := func(a, b) {
+		c := a + b
+		return c
+	}
+	x := add(1, 2)
+	return x + g`
+	c, err := Compile([]byte(src), CompileOptions{})
+	require.NoError(t, err)
+
+	var (
+		lines  []string
+		stop   = errors.New("stop")
+		stopAt int
+	)
+	debugger := lineDebugger(func(vm *VM, pos parser.SourceFilePos) error {
+		frames := vm.DebugFrames()
+		require.Equal(t, vm.CallDepth(), len(frames))
+		require.Equal(t, pos, frames[0].Pos)
+		lines = append(lines, fmt.Sprintf("%d:%d", pos.Line, len(frames)))
+		if pos.Line == 4 {
+			require.Equal(t, Dict{"a": Int(1), "b": Int(2), "c": Int(3)}, frames[0].Locals())
+			ret, err := vm.DebugEval(0, `c * 10 + g`)
+			require.NoError(t, err)
+			require.Equal(t, Int(35), ret)
+			require.Equal(t, 6, frames[1].Pos.Line)
+			_, err = vm.DebugEval(2, `c`)
+			require.ErrorIs(t, err, ErrIndexOutOfBounds)
+		}
+		if pos.Line == stopAt {
+			return stop
+		}
+		return nil
+	})
+
+	ret, err := NewVM(c).Setup(SetupOpts{Debugger: debugger}).RunOpts(&RunOpts{Globals: Dict{"g": Int(5)}})
+	require.NoError(t, err)
+	require.Equal(t, Int(8), ret)
+	require.Equal(t, []string{"2:1", "6:1", "3:2", "4:2", "7:1"}, lines)
+
+	lines, stopAt = nil, 3
+	_, err = NewVM(c).Setup(SetupOpts{Debugger: debugger}).RunOpts(&RunOpts{Globals: Dict{"g": Int(5)}})
+	require.ErrorIs(t, err, stop)
+	require.Equal(t, []string{"2:1", "6:1", "3:2"}, lines)
+}
+
+func TestVMFactoryModule(t *testing.T) {
+	type tenantKey struct{}
+
+	var created int
+	mm := NewModuleMap()
+	mm.AddFactoryModule("http", func(vm *VM) (map[string]Object, error) {
+		tenant, _ := vm.Context.Value(tenantKey{}).(string)
+		if tenant == "" {
+			return nil, errors.New("no tenant")
+		}
+		created++
+		return map[string]Object{
+			"host": Str(tenant + ".example.com"),
+		}, nil
+	})
+
+	c, err := Compile([]byte(`
+	h := import("http")
+	f := func() { return import("http") }
+	return [h.host, f().host, h.__module_name__]`),
+		CompileOptions{CompilerOptions: CompilerOptions{ModuleMap: mm}})
+	require.NoError(t, err)
+
+	for _, tenant := range []string{"a", "b"} {
+		ctx := context.WithValue(context.Background(), tenantKey{}, tenant)
+		ret, err := NewVM(c).Setup(SetupOpts{Context: ctx}).Run()
+		require.NoError(t, err)
+		host := Str(tenant + ".example.com")
+		require.Equal(t, Array{host, host, Str("http")}, ret)
+	}
+	require.Equal(t, 2, created)
+
+	_, err = NewVM(c).Run()
+	require.ErrorContains(t, err, "no tenant")
+
+	c, err = Compile([]byte(`try { import("http") } catch err { return err.Message }`),
+		CompileOptions{CompilerOptions: CompilerOptions{ModuleMap: mm}})
+	require.NoError(t, err)
+	ret, err := NewVM(c).Run()
+	require.NoError(t, err)
+	require.Equal(t, Str("no tenant"), ret)
+}
+
+func TestVMModuleHooks(t *testing.T) {
+	var calls []string
+	mm := NewModuleMap()
+	mm.AddBuiltinModule("rec", map[string]Object{
+		"add": &Function{Name: "add", Value: func(c Call) (Object, error) {
+			calls = append(calls, c.Args.GetOnly(0).ToString())
+			return Nil, nil
+		}},
+	})
+	mm.AddSourceModule("db", []byte(`
+	rec := import("rec")
+	conn := {open: false}
+	func __init__() { conn.open = true; rec.add("db init") }
+	func __cleanup__() { conn.open = false; rec.add("db cleanup") }
+	return conn`))
+	mm.AddSourceModule("cache", []byte(`
+	rec := import("rec")
+	func __cleanup__() { rec.add("cache cleanup") }`))
+	mm.AddSourceModule("bad", []byte(`func __init__() { throw "init failed" }; return 1`))
+
+	c, err := Compile([]byte(`
+	db := import("db")
+	import("cache")
+	f := func() { return import("db") }
+	return [db.open, f() == db]`),
+		CompileOptions{CompilerOptions: CompilerOptions{ModuleMap: mm}})
+	require.NoError(t, err)
+
+	vm := NewVM(c)
+	for i := 0; i < 2; i++ {
+		ret, err := vm.Run()
+		require.NoError(t, err)
+		require.Equal(t, Array{True, True}, ret)
+	}
+	require.Equal(t, []string{"db init"}, calls)
+	require.NoError(t, vm.Close())
+	require.Equal(t, []string{"db init", "cache cleanup", "db cleanup"}, calls)
+
+	calls = nil
+	_, err = vm.Run()
+	require.NoError(t, err)
+	require.Equal(t, []string{"db init"}, calls)
+
+	c, err = Compile([]byte(`try { import("bad") } catch err { return err.Message }`),
+		CompileOptions{CompilerOptions: CompilerOptions{ModuleMap: mm}})
+	require.NoError(t, err)
+	ret, err := NewVM(c).Run()
+	require.NoError(t, err)
+	require.Equal(t, Str("init failed"), ret)
+}
+
+func TestVMParallelImports(t *testing.T) {
+	mm := NewModuleMap()
+	mm.AddBuiltinModule("rec", map[string]Object{"x": Int(2)})
+	mm.AddSourceModule("shared", []byte(`n := 0; return {inc: func() { n++; return n }}`))
+	mm.AddSourceModule("a", []byte(`s := import("shared"); return {v: s.inc()}`))
+	mm.AddSourceModule("b", []byte(`
+	rec := import("rec")
+	x := 0
+	func __init__() { x = rec.x }
+	return {name: "b", x: func() => x}`))
+	mm.AddSourceModule("c", []byte(`const k = 3; return func(x) { return x * k }`))
+	mm.AddSourceModule("d", []byte(`return func() {
+		throw "boom"
+	}`))
+
+	for _, parallel := range []bool{false, true} {
+		c, err := Compile([]byte(`
+	a := import("a")
+	s := import("shared")
+	b := import("b")
+	rec := import("rec")
+	return [a.v, s.inc(), b.name, b.x(), rec.x, import("c")(2)]`),
+			CompileOptions{CompilerOptions: CompilerOptions{ModuleMap: mm, ParallelImports: parallel}})
+		require.NoError(t, err)
+		ret, err := NewVM(c).Run()
+		require.NoError(t, err)
+		require.Equal(t, Array{Int(1), Int(2), Str("b"), Int(2), Int(2), Int(6)}, ret)
+
+		c, err = Compile([]byte(`import("d")()`),
+			CompileOptions{CompilerOptions: CompilerOptions{ModuleMap: mm, ParallelImports: parallel}})
+		require.NoError(t, err)
+		_, err = NewVM(c).Run()
+		require.Error(t, err)
+		require.Contains(t, fmt.Sprintf("%+v", err), "d:2:3")
+	}
+
+	mm.AddSourceModule("e", []byte(`return x`))
+	_, err := Compile([]byte(`import("e")`),
+		CompileOptions{CompilerOptions: CompilerOptions{ModuleMap: mm, ParallelImports: true}})
+	require.ErrorContains(t, err, `unresolved reference "x"`)
+
+	// importers are called once for every module and the warnings of the
+	// precompiled modules are reported
+	imports := map[string]int{}
+	mm.Add("f", importerFunc(func(name string) (any, error) {
+		imports[name]++
+		return []byte(`rec := import("rec"); return rec.old()`), nil
+	}))
+	mm.Add("g", importerFunc(func(name string) (any, error) {
+		imports[name]++
+		return nil, errors.New("not found")
+	}))
+	mm.Add("rec", importerFunc(func(name string) (any, error) {
+		imports[name]++
+		return Dict{"old": &Function{Value: func(Call) (Object, error) { return Int(1), nil }}}, nil
+	}))
+	var warnings []string
+	opts := CompileOptions{CompilerOptions: CompilerOptions{
+		ModuleMap:       mm,
+		ParallelImports: true,
+		Deprecations:    Deprecations{"rec.old": "use new"},
+		Warn: func(w *CompilerWarning) {
+			warnings = append(warnings, w.String())
+		},
+	}}
+	c, err := Compile([]byte(`rec := import("rec"); return import("f")`), opts)
+	require.NoError(t, err)
+	ret, err := NewVM(c).Run()
+	require.NoError(t, err)
+	require.Equal(t, Int(1), ret)
+	require.Equal(t, map[string]int{"f": 1, "rec": 1}, imports)
+	require.Len(t, warnings, 1)
+	require.Contains(t, warnings[0], "use new")
+
+	_, err = Compile([]byte(`import("g")`), opts)
+	require.ErrorContains(t, err, "not found")
+	require.Equal(t, 1, imports["g"])
+
+	require.False(t, DefaultCompilerOptions.ParallelImports)
+}
+
+type importerFunc func(name string) (any, error)
+
+func (f importerFunc) Import(_ context.Context, name string) (any, string, error) {
+	v, err := f(name)
+	return v, name, err
+}
+
+var update = flag.Bool("update", false, "update golden files")
+
+func TestVMRenderGolden(t *testing.T) {
+	ExpectRenderGolden(t, "testdata/render.gad", Dict{
+		"title": Str("Fruits"),
+		"items": Array{Str("apple"), Str("banana")},
+	}, "testdata/render.golden")
+}